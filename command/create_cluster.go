@@ -2,17 +2,21 @@ package command
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/codegangsta/cli"
-	"github.com/docker/machine/commands/mcndirs"
-	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/kujtimiihoxha/go-brace-expansion"
 
 	"github.com/Spirals-Team/docker-g5k/libdockerg5k/cluster"
 	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/ingress"
 	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/volume"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/weave"
 )
 
 const (
@@ -22,8 +26,23 @@ const (
 	// regexReservation match the site (site) and the number of nodes (nbNodes) from a reservation
 	regexReservation = "^(?P<site>[[:alpha:]]+):(?P<nbNodes>[[:digit:]]+)$"
 
+	// regexCoreHoursQuota match the site (site) and the core-hours (hours) from a quota
+	regexCoreHoursQuota = "^(?P<site>[[:alpha:]]+):(?P<hours>[[:digit:]]+(?:\\.[[:digit:]]+)?)$"
+
 	// regexNodeParamFlag match the node site/ID and the parameter (param, paramName, paramValue) from a CLI flag using the format : {nodeName}:paramName=paramValue
 	regexNodeParamFlag = "^" + regexNodeName + ":(?P<param>(?P<paramName>[[:ascii:]]+)=(?P<paramValue>[[:ascii:]]+))$"
+
+	// regexNodeAliasFlag match the node site/ID and the alias (alias) from a CLI flag using the format : {nodeName}:alias
+	regexNodeAliasFlag = "^" + regexNodeName + ":(?P<alias>[^:=]+)$"
+
+	// regexNodePlatformFlag match the node site/ID and the images platform (platform) from a CLI flag using the format : {nodeName}:platform
+	regexNodePlatformFlag = "^" + regexNodeName + ":(?P<platform>[[:alnum:]_/]+)$"
+
+	// regexNodeFirewallFlag match the node site/ID and the firewall rule (rule) from a CLI flag using the format : {nodeName}:rule
+	regexNodeFirewallFlag = "^" + regexNodeName + ":(?P<rule>[^ ]+)$"
+
+	// regexNodeDeployModeFlag match the node site/ID and the deployment mode (mode) from a CLI flag using the format : {nodeName}:mode
+	regexNodeDeployModeFlag = "^" + regexNodeName + ":(?P<mode>[[:alpha:]]+)$"
 )
 
 var (
@@ -61,13 +80,165 @@ var (
 				Value:  "1:00:00",
 			},
 
+			cli.StringFlag{
+				EnvVar: "G5K_JOB_QUEUE",
+				Name:   "g5k-job-queue",
+				Usage:  "OAR queue used for the nodes reservation (ex: production, besteffort)",
+				Value:  "",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "G5K_SITE_FALLBACK",
+				Name:   "g5k-site-fallback",
+				Usage:  "Site tried (in the given order) when the reservation on a site fails or does not start before the reserve phase timeout (ex: nancy)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "G5K_CORE_HOURS_QUOTA",
+				Name:   "g5k-core-hours-quota",
+				Usage:  "Core-hours allowance of the user on a site, the reservation is refused if it does not fit in the remaining core-hours (ex: rennes:5000)",
+			},
+
+			cli.Float64Flag{
+				EnvVar: "G5K_MAX_CORE_HOURS",
+				Name:   "g5k-max-core-hours",
+				Usage:  "Maximum core-hours of the reservation, estimated with the largest nodes of the sites, the reservation is refused above it (not checked if 0)",
+				Value:  0,
+			},
+
+			cli.BoolFlag{
+				EnvVar: "G5K_ALLOW_CORE_HOURS_OVERRUN",
+				Name:   "g5k-allow-core-hours-overrun",
+				Usage:  "Allow the reservations above the maximum core-hours (only a warning)",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "G5K_DISABLE_SWAP",
+				Name:   "g5k-disable-swap",
+				Usage:  "Disable the swap on all nodes",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "G5K_JOB_FACTS",
+				Name:   "g5k-job-facts",
+				Usage:  "Write the job facts (job ID, start time, nodes) to /etc/docker-g5k/job.env on all nodes",
+			},
+
+			cli.DurationFlag{
+				EnvVar: "G5K_SSH_WAIT_TIMEOUT",
+				Name:   "g5k-ssh-wait-timeout",
+				Usage:  "Maximum time to wait for the SSH port of all nodes to be reachable before provisioning them (disabled if not set)",
+				Value:  0,
+			},
+
+			cli.IntFlag{
+				EnvVar: "HOST_CACHE_SIZE",
+				Name:   "host-cache-size",
+				Usage:  "Maximum number of nodes whose loaded machine and SSH client are cached and reused by the operations on the cluster (disabled if 0)",
+				Value:  0,
+			},
+
+			cli.DurationFlag{
+				EnvVar: "HOST_CACHE_IDLE_TIMEOUT",
+				Name:   "host-cache-idle-timeout",
+				Usage:  "Time an unused machine is kept in the host cache",
+				Value:  5 * time.Minute,
+			},
+
+			cli.IntFlag{
+				EnvVar: "FLEET_CONCURRENCY",
+				Name:   "fleet-concurrency",
+				Usage:  "Maximum number of nodes the operations on the whole cluster run on at the same time (provisioning excluded)",
+				Value:  64,
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "PHASE_TIMEOUT",
+				Name:   "phase-timeout",
+				Usage:  "Timeout of a provisioning phase (reserve, create, mapping, weave or swarm) (ex: reserve=30m)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "PROVISIONING_LOG_DIR",
+				Name:   "provisioning-log-dir",
+				Usage:  "Directory of the provisioning log file of each node (<machine name>.log)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "LOG_LEVEL",
+				Name:   "log-level",
+				Usage:  "Logging mode of the provisioning and driver output (quiet, normal, verbose, trace)",
+				Value:  "normal",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "ATOMIC",
+				Name:   "atomic",
+				Usage:  "Release all the reserved nodes if any node can't be reserved, deployed or provisioned (all-or-nothing)",
+			},
+
+			cli.IntFlag{
+				EnvVar: "MIN_SUCCESSFUL_NODES",
+				Name:   "min-successful-nodes",
+				Usage:  "Minimum number of provisioned nodes for the cluster creation to succeed, the other nodes (including the Swarm masters/managers except the first one) are optional",
+				Value:  0,
+			},
+
+			cli.DurationFlag{
+				EnvVar: "PROVISION_DEADLINE",
+				Name:   "provision-deadline",
+				Usage:  "Maximum total time of the nodes provisioning, all the reserved nodes are released if it is exceeded (disabled if not set)",
+				Value:  0,
+			},
+
+			cli.Int64Flag{
+				EnvVar: "PROVISIONING_SEED",
+				Name:   "provisioning-seed",
+				Usage:  "Seed of the allocation of the deployed nodes to the machines, to get the same cluster layout on each run (time-based if 0)",
+				Value:  0,
+			},
+
+			cli.StringFlag{
+				EnvVar: "CLUSTER_ID",
+				Name:   "cluster-id",
+				Usage:  "Identifier of the cluster, added as an Engine label on all nodes (ex: experiment-1)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "MACHINE_STORAGE_PATH",
+				Name:   "machine-storage-path",
+				Usage:  "Base directory of the Docker Machine storage of the cluster (certificates and machines), the Docker Machine default if empty",
+				Value:  "",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "ANNOTATION",
+				Name:   "annotation",
+				Usage:  "Metadata of the experiment added as an Engine label on all nodes (prefixed 'g5k.annotation.') and reported in the inventory (ex: git.commit=3f2a1c9)",
+			},
+
 			cli.StringFlag{
 				EnvVar: "G5K_IMAGE",
 				Name:   "g5k-image",
-				Usage:  "Name of the image to deploy",
+				Usage:  "Name (or alias) of the environment to deploy, or path/URL of an environment description",
 				Value:  "jessie-x64-min",
 			},
 
+			cli.StringFlag{
+				EnvVar: "G5K_DEPLOY_MODE",
+				Name:   "g5k-deploy-mode",
+				Usage:  "Deployment mode of the nodes: deploy the environment image (deploy) or use the default environment through SSH (classic)",
+				Value:  "deploy",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "G5K_NODE_DEPLOY_MODE",
+				Name:   "g5k-node-deploy-mode",
+				Usage:  "Deployment mode of the selected node(s) (site-id:mode), the nodes of a site share the same mode",
+			},
+
 			cli.StringFlag{
 				EnvVar: "G5K_RESOURCE_PROPERTIES",
 				Name:   "g5k-resource-properties",
@@ -75,6 +246,25 @@ var (
 				Value:  "",
 			},
 
+			cli.StringFlag{
+				EnvVar: "G5K_NETWORK_REQUIREMENT",
+				Name:   "g5k-network-requirement",
+				Usage:  "Network capability required on the nodes ('ib' for InfiniBand, 'eth{rate}g' for Ethernet, ex: 'eth25g')",
+				Value:  "",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "G5K_LOCAL_VOLUME",
+				Name:   "g5k-local-volume",
+				Usage:  "Create a Docker volume backed by the node local disk (site-id:volumename=[device:]path)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "G5K_SHARED_MOUNT",
+				Name:   "g5k-shared-mount",
+				Usage:  "Mount a NFS export on the node, optionally exposed as a Docker volume (site-id:path=server:export[:volumename])",
+			},
+
 			cli.StringFlag{
 				EnvVar: "ENGINE_INSTALL_URL",
 				Name:   "engine-install-url",
@@ -94,24 +284,487 @@ var (
 				Usage:  "Specify labels for the selected node(s) engine (site-id:labelname=labelvalue)",
 			},
 
+			cli.StringSliceFlag{
+				EnvVar: "G5K_NODE_ALIAS",
+				Name:   "g5k-node-alias",
+				Usage:  "Additional name of the selected node(s) in the static lookup table of the cluster hosts (site-id:alias)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "G5K_SYSCTL",
+				Name:   "g5k-sysctl",
+				Usage:  "Kernel parameter set on all nodes (ex: net.core.somaxconn=4096)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "G5K_NODE_SYSCTL",
+				Name:   "g5k-node-sysctl",
+				Usage:  "Kernel parameter set on the selected node(s) (site-id:name=value)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "G5K_NODE_FIREWALL",
+				Name:   "g5k-node-firewall",
+				Usage:  "Firewall rule applied on the incoming traffic of the selected node(s) (site-id:action[:protocol[/port]][@source])",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "ENGINE_ALIASES_LABEL",
+				Name:   "engine-aliases-label",
+				Usage:  "Add the aliases of the nodes as Engine label (g5k.aliases)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_LOG_MAX_SIZE",
+				Name:   "engine-log-max-size",
+				Usage:  "Maximum size of the containers log before it is rotated (ex: 10m)",
+				Value:  "",
+			},
+
+			cli.IntFlag{
+				EnvVar: "ENGINE_LOG_MAX_FILE",
+				Name:   "engine-log-max-file",
+				Usage:  "Maximum number of containers log files kept",
+				Value:  0,
+			},
+
+			cli.BoolFlag{
+				EnvVar: "ENGINE_DISABLE_G5K_LABELS",
+				Name:   "engine-disable-g5k-labels",
+				Usage:  "Do not add the Grid5000 site, job ID and node hostname as Engine labels",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_SECCOMP_PROFILE",
+				Name:   "engine-seccomp-profile",
+				Usage:  "Path of the seccomp profile (JSON) used as default by the Docker Engine of all nodes",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_APPARMOR_PROFILE",
+				Name:   "engine-apparmor-profile",
+				Usage:  "Path of an AppArmor profile to load on all nodes",
+				Value:  "",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "ENGINE_EXPERIMENTAL",
+				Name:   "engine-experimental",
+				Usage:  "Enable the Docker Engine experimental features on all nodes",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_LOG_LEVEL",
+				Name:   "engine-log-level",
+				Usage:  "Log level of the Docker Engine on all nodes (debug, info, warn, error, fatal)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_DEFAULT_SHM_SIZE",
+				Name:   "engine-default-shm-size",
+				Usage:  "Default size of the containers /dev/shm on all nodes (ex: 1g)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_PULL_PROXY",
+				Name:   "engine-pull-proxy",
+				Usage:  "HTTP(S) proxy used by the Docker Engines for their own requests, such as the image pulls, not by the containers nor the nodes (ex: http://proxy:3128)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_USERNS_REMAP",
+				Name:   "engine-userns-remap",
+				Usage:  "User namespace remapping of the Docker Engine on all nodes (default or user[:group])",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "ENGINE_DEBUG",
+				Name:   "engine-debug",
+				Usage:  "Run the Docker Engine of the selected node(s) in debug mode (site-id)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_DATA_ROOT",
+				Name:   "engine-data-root",
+				Usage:  "Data root directory of the Docker Engines (ex: /tmp/docker on the large local disk)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_DATA_ROOT_DEVICE",
+				Name:   "engine-data-root-device",
+				Usage:  "Device mounted (and formatted if it has no filesystem) on the Docker data root directory (ex: /dev/sdb)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_DATA_ROOT_MIN_FREE",
+				Name:   "engine-data-root-min-free",
+				Usage:  "Minimum free space of the Docker data root filesystem, the provisioning of the nodes with less space fails (ex: 50g)",
+				Value:  "",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "ENGINE_DATA_ROOT_PERSISTENT",
+				Name:   "engine-data-root-persistent",
+				Usage:  "Keep the Docker data root device across the jobs as an image cache of the re-provisioned nodes (needs a reserved disk)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_API_VERSION",
+				Name:   "engine-api-version",
+				Usage:  "Docker API version used by the clients on the nodes (ex: 1.24)",
+				Value:  "",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "ENGINE_RUNTIME",
+				Name:   "engine-runtime",
+				Usage:  "OCI runtime registered on the Docker Engine of all nodes (ex: crun=/usr/bin/crun)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_DEFAULT_RUNTIME",
+				Name:   "engine-default-runtime",
+				Usage:  "Default OCI runtime of the Docker Engine on all nodes (ex: crun)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_METRICS_ADDR",
+				Name:   "engine-metrics-addr",
+				Usage:  "Address of the Docker Engine Prometheus metrics endpoint on all nodes, requires --engine-experimental (ex: 0.0.0.0:9323)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_BRIDGE_SUBNET",
+				Name:   "engine-bridge-subnet",
+				Usage:  "Subnet of the Docker Engine default bridge (docker0) on all nodes (ex: 10.200.0.0/16)",
+				Value:  "",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "ENGINE_DNS_OPT",
+				Name:   "engine-dns-opt",
+				Usage:  "Default resolver option of the containers on all nodes (ex: ndots:1)",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "ENGINE_DISABLE_USERLAND_PROXY",
+				Name:   "engine-disable-userland-proxy",
+				Usage:  "Disable the Docker Engine userland proxy on all nodes (the published ports use iptables rules only)",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "ENGINE_DISABLE_IPTABLES",
+				Name:   "engine-disable-iptables",
+				Usage:  "Do not let the Docker Engine manage the iptables rules on all nodes",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "ENGINE_SYSTEMD_OVERRIDE",
+				Name:   "engine-systemd-override",
+				Usage:  "Setting of the Docker Engine systemd service on all nodes (ex: Service.LimitNOFILE=1048576)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "ENGINE_DEFAULT_NETWORK",
+				Name:   "engine-default-network",
+				Usage:  "Container network created on all nodes and advertised as default (ex: host, expnet)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "REGISTRY_NODE",
+				Name:   "registry-node",
+				Usage:  "Deploy a registry on the selected node and use it as registry mirror on all nodes",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "REGISTRY_PROXY_REMOTE_URL",
+				Name:   "registry-proxy-remote-url",
+				Usage:  "Use the registry as a pull-through cache of the given remote registry (ex: https://registry-1.docker.io)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "INGRESS_NODE",
+				Name:   "ingress-node",
+				Usage:  "Run an ingress controller (Traefik) routing the labeled containers/services on the selected node (a Swarm manager in Swarm mode)",
+				Value:  "",
+			},
+
+			cli.IntFlag{
+				EnvVar: "INGRESS_PORT",
+				Name:   "ingress-port",
+				Usage:  "Port published by the ingress controller on the ingress node",
+				Value:  80,
+			},
+
+			cli.StringFlag{
+				EnvVar: "BASTION_NODE",
+				Name:   "bastion-node",
+				Usage:  "Run a SOCKS proxy and allow the SSH forwarding on the selected node to reach the overlay-only services (a Swarm manager in Swarm mode)",
+				Value:  "",
+			},
+
+			cli.IntFlag{
+				EnvVar: "BASTION_PORT",
+				Name:   "bastion-port",
+				Usage:  "Port of the SOCKS proxy published on the bastion node",
+				Value:  1080,
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "REGISTRY_AUTH",
+				Name:   "registry-auth",
+				Usage:  "Credentials of a private registry used by the pulls on all nodes (ex: registry.example.com:5000=user:password)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "ENGINE_TRUSTED_CA",
+				Name:   "engine-trusted-ca",
+				Usage:  "Local path of a CA bundle (PEM) installed in the trust store of all nodes and trusted by the Engines for the private registries",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "ENGINE_REUSE_CERTS",
+				Name:   "engine-reuse-certs",
+				Usage:  "Reuse the existing Engine server certificates of the nodes (if still valid) instead of generating new ones",
+			},
+
+			cli.StringFlag{
+				EnvVar: "INFRA_RESTART_POLICY",
+				Name:   "infra-restart-policy",
+				Usage:  "Restart policy of the registry, Zookeeper and Weave Discovery containers (no, always, unless-stopped, on-failure[:max-retries])",
+				Value:  "always",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "INFRA_CONTAINER_CAP",
+				Name:   "infra-container-cap",
+				Usage:  "Linux capability added to the registry, Zookeeper, Weave Discovery and ingress containers (ex: NET_ADMIN)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "INFRA_STOP_SIGNAL",
+				Name:   "infra-stop-signal",
+				Usage:  "Signal used to stop the registry, Zookeeper, Weave Discovery and ingress containers (ex: SIGTERM)",
+			},
+
+			cli.DurationFlag{
+				EnvVar: "INFRA_STOP_TIMEOUT",
+				Name:   "infra-stop-timeout",
+				Usage:  "Time to wait for the registry, Zookeeper, Weave Discovery and ingress containers to stop before killing them (ex: 30s)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "DEFAULT_PLATFORM",
+				Name:   "default-platform",
+				Usage:  "Platform of the images pulled on the nodes and of the infrastructure containers (ex: linux/amd64, linux/arm64)",
+				Value:  "",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "G5K_NODE_PLATFORM",
+				Name:   "g5k-node-platform",
+				Usage:  "Platform of the images pulled on the selected node(s) (site-id:platform)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "CONTAINER_DEFAULT_CAP",
+				Name:   "container-default-cap",
+				Usage:  "Linux capability added by default to the workload containers (flags written to /etc/docker-g5k/container-caps on the nodes)",
+			},
+
 			cli.StringSliceFlag{
 				EnvVar: "SWARM_MASTER",
 				Name:   "swarm-master",
 				Usage:  "Select node(s) to be promoted to Swarm Master(standalone)/Manager(Mode)",
 			},
 
+			cli.StringFlag{
+				EnvVar: "SWARM_MANAGERS_ANTI_AFFINITY",
+				Name:   "swarm-managers-anti-affinity",
+				Usage:  "Spread the Swarm masters/managers of a site on distinct failure domains (cluster, switch)",
+				Value:  "",
+			},
+
 			cli.BoolFlag{
 				EnvVar: "SWARM_MODE_ENABLE",
 				Name:   "swarm-mode-enable",
 				Usage:  "Create a Swarm mode cluster",
 			},
 
+			cli.BoolFlag{
+				EnvVar: "SWARM_MODE_REQUIRE_QUORUM",
+				Name:   "swarm-mode-require-quorum",
+				Usage:  "Wait for a majority of the Swarm managers to join the cluster before returning",
+			},
+
+			cli.DurationFlag{
+				EnvVar: "SWARM_MODE_QUORUM_TIMEOUT",
+				Name:   "swarm-mode-quorum-timeout",
+				Usage:  "Maximum time to wait for the Swarm managers quorum",
+				Value:  5 * time.Minute,
+			},
+
+			cli.DurationFlag{
+				EnvVar: "SWARM_MODE_INIT_WAIT_TIMEOUT",
+				Name:   "swarm-mode-init-wait-timeout",
+				Usage:  "Maximum time a node wait for the Swarm mode cluster initialization before joining",
+				Value:  10 * time.Minute,
+			},
+
+			cli.IntFlag{
+				EnvVar: "SWARM_MODE_JOIN_RETRIES",
+				Name:   "swarm-mode-join-retries",
+				Usage:  "Number of retries of a Swarm mode join failing while the manager is not ready (-1 to disable)",
+				Value:  5,
+			},
+
+			cli.DurationFlag{
+				EnvVar: "SWARM_MODE_JOIN_BACKOFF",
+				Name:   "swarm-mode-join-backoff",
+				Usage:  "Delay before the first retry of a failed Swarm mode join (doubled at each retry)",
+				Value:  2 * time.Second,
+			},
+
+			cli.StringFlag{
+				EnvVar: "SWARM_MODE_ADVERTISE_ADDR",
+				Name:   "swarm-mode-advertise-addr",
+				Usage:  "Address advertised by the bootstrap manager and used by the nodes to join the Swarm mode cluster",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "SWARM_MODE_BOOTSTRAP_SITE",
+				Name:   "swarm-mode-bootstrap-site",
+				Usage:  "Site of the Swarm mode bootstrap manager ('auto' to select the site with the lowest latency to the other sites)",
+				Value:  "",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "SWARM_MODE_OVERLAY_ENCRYPTED",
+				Name:   "swarm-mode-overlay-encrypted",
+				Usage:  "Encrypt the application data of the overlay networks created by docker-g5k",
+			},
+
+			cli.IntFlag{
+				EnvVar: "SWARM_MODE_DATA_PATH_PORT",
+				Name:   "swarm-mode-data-path-port",
+				Usage:  "UDP port of the overlay networks VXLAN data path (Docker default if not set)",
+				Value:  0,
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "SWARM_MODE_DATA_PATH_OFFLOAD",
+				Name:   "swarm-mode-data-path-offload",
+				Usage:  "Offload feature of the data path interface set on all nodes (ex: gro=on, tx-udp_tnl-segmentation=off)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "SWARM_MODE_DATA_PATH_SYSCTL",
+				Name:   "swarm-mode-data-path-sysctl",
+				Usage:  "Kernel parameter of the overlay networks data path set on all nodes (ex: net.core.rmem_max=26214400)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "SWARM_MODE_SERVICE_DISCOVERY_NETWORK",
+				Name:   "swarm-mode-service-discovery-network",
+				Usage:  "Name of the encrypted overlay network connecting the standalone containers of the nodes for the name resolution (disabled if empty)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "SWARM_MODE_SERVICE_DISCOVERY_SUBNET",
+				Name:   "swarm-mode-service-discovery-subnet",
+				Usage:  "Subnet (CIDR) of the service discovery network (allocated by Docker if empty)",
+				Value:  "",
+			},
+
+			cli.IntFlag{
+				EnvVar: "SWARM_MODE_TASK_HISTORY_LIMIT",
+				Name:   "swarm-mode-task-history-limit",
+				Usage:  "Number of terminated tasks kept by service slot in the Swarm mode cluster (Docker default if not set)",
+				Value:  0,
+			},
+
+			cli.DurationFlag{
+				EnvVar: "SWARM_MODE_DISPATCHER_HEARTBEAT",
+				Name:   "swarm-mode-dispatcher-heartbeat",
+				Usage:  "Period of the nodes heartbeat to the Swarm mode managers (Docker default if not set)",
+				Value:  0,
+			},
+
+			cli.DurationFlag{
+				EnvVar: "SWARM_MODE_CERT_EXPIRY",
+				Name:   "swarm-mode-cert-expiry",
+				Usage:  "Validity of the Swarm mode nodes certificates (Docker default if not set)",
+				Value:  0,
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "SWARM_MODE_ADVERTISED_RESOURCES",
+				Name:   "swarm-mode-advertised-resources",
+				Usage:  "Resources advertised to the Swarm mode scheduler by the selected node(s) (ex: site-id:cpus=2.5, site-id:memory=8g)",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "SWARM_MODE_HARDWARE_LABELS",
+				Name:   "swarm-mode-hardware-labels",
+				Usage:  "Add the labels derived from the hardware description to the Swarm mode nodes once joined (ex: gpu=true, nic=25g)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "SWARM_MODE_HARDWARE_LABEL_RULE",
+				Name:   "swarm-mode-hardware-label-rule",
+				Usage:  "Rule deriving a Swarm mode node label from the hardware description, the first matching rule of a label wins (ex: nic=25g:nic_rate_gbps>=25)",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "SWARM_MODE_SMOKE_TEST",
+				Name:   "swarm-mode-smoke-test",
+				Usage:  "Deploy a smoke test service once the Swarm mode cluster is provisioned",
+			},
+
+			cli.StringFlag{
+				EnvVar: "SWARM_MODE_SMOKE_TEST_IMAGE",
+				Name:   "swarm-mode-smoke-test-image",
+				Usage:  "Image of the smoke test service (needs to serve HTTP on port 80 and provide wget)",
+				Value:  "nginx:alpine",
+			},
+
+			cli.IntFlag{
+				EnvVar: "SWARM_MODE_SMOKE_TEST_REPLICAS",
+				Name:   "swarm-mode-smoke-test-replicas",
+				Usage:  "Number of replicas of the smoke test service",
+				Value:  3,
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "SWARM_MODE_PLACEMENT_PREF",
+				Name:   "swarm-mode-placement-pref",
+				Usage:  "Placement preference of the replicated services created on the cluster (ex: spread=node.labels.nic or spread=engine.labels.g5k.site)",
+			},
+
 			cli.BoolFlag{
 				EnvVar: "SWARM_STANDALONE_ENABLE",
 				Name:   "swarm-standalone-enable",
 				Usage:  "Create a Swarm standalone cluster",
 			},
 
+			cli.StringFlag{
+				EnvVar: "SWARM_STANDALONE_DISCOVERY_BACKEND",
+				Name:   "swarm-standalone-discovery-backend",
+				Usage:  "Discovery backend to use with Swarm: token, zk, consul, etcd or nodes (Default: Inferred from the discovery URL)",
+				Value:  "",
+			},
+
 			cli.StringFlag{
 				EnvVar: "SWARM_STANDALONE_DISCOVERY",
 				Name:   "swarm-standalone-discovery",
@@ -119,6 +772,20 @@ var (
 				Value:  "",
 			},
 
+			cli.StringFlag{
+				EnvVar: "SWARM_STANDALONE_EXTERNAL_DISCOVERY",
+				Name:   "swarm-standalone-external-discovery",
+				Usage:  "External k/v store (zk, consul or etcd URL) used as Swarm discovery and Engines cluster store, not deployed by docker-g5k",
+				Value:  "",
+			},
+
+			cli.IntFlag{
+				EnvVar: "SWARM_STANDALONE_CLUSTER_ADVERTISE_PORT",
+				Name:   "swarm-standalone-cluster-advertise-port",
+				Usage:  "Port of the Engines advertised to the cluster store (Engine 'cluster-advertise' option, Docker Engine port if 0)",
+				Value:  0,
+			},
+
 			cli.StringFlag{
 				EnvVar: "SWARM_STANDALONE_IMAGE",
 				Name:   "swarm-standalone-image",
@@ -139,16 +806,49 @@ var (
 				Usage:  "Define arbitrary flags for Swarm master (can be provided multiple times)",
 			},
 
-			cli.StringSliceFlag{
-				EnvVar: "SWARM_STANDALONE_JOIN_OPT",
-				Name:   "swarm-standalone-join-opt",
-				Usage:  "Define arbitrary flags for Swarm join (can be provided multiple times)",
+			cli.StringSliceFlag{
+				EnvVar: "SWARM_STANDALONE_JOIN_OPT",
+				Name:   "swarm-standalone-join-opt",
+				Usage:  "Define arbitrary flags for Swarm join (can be provided multiple times)",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "WEAVE_NETWORKING",
+				Name:   "weave-networking",
+				Usage:  "Use Weave for networking (Only if Swarm standalone is enabled)",
+			},
+
+			cli.StringSliceFlag{
+				EnvVar: "WEAVE_CONNECTOR",
+				Name:   "weave-connector",
+				Usage:  "Select node(s) to be used as Weave hub, other nodes will only peer with them (Default: full mesh)",
+			},
+
+			cli.BoolFlag{
+				EnvVar: "WEAVE_STABLE_PEERS",
+				Name:   "weave-stable-peers",
+				Usage:  "Derive the Weave peer names from the machine names and seed the Weave IPAM with the Swarm masters (stable across restarts)",
+			},
+
+			cli.StringFlag{
+				EnvVar: "WEAVE_PASSWORD",
+				Name:   "weave-password",
+				Usage:  "Encryption password of the Weave network (Default: not encrypted)",
+				Value:  "",
 			},
 
-			cli.BoolFlag{
-				EnvVar: "WEAVE_NETWORKING",
-				Name:   "weave-networking",
-				Usage:  "Use Weave for networking (Only if Swarm standalone is enabled)",
+			cli.StringFlag{
+				EnvVar: "WEAVE_IMAGE",
+				Name:   "weave-image",
+				Usage:  "Weave Net router image, without tag (ex: myregistry:5000/weave, Default: weaveworks/weave)",
+				Value:  "",
+			},
+
+			cli.StringFlag{
+				EnvVar: "WEAVE_VERSION",
+				Name:   "weave-version",
+				Usage:  "Version (tag) of the Weave Net images (ex: 2.8.1, Default: latest)",
+				Value:  "",
 			},
 		},
 	}
@@ -208,6 +908,53 @@ func (c *CreateClusterCommand) parseSwarmMasterFlag(flag []string) (map[string]b
 	return swarmMasterNodes, nil
 }
 
+// parseEngineDebugFlag parse the Engine debug mode flag (site)-(id)
+func (c *CreateClusterCommand) parseEngineDebugFlag(flag []string) (map[string]bool, error) {
+	debugNodes := make(map[string]bool)
+
+	for _, paramValue := range flag {
+		// brace expansion support
+		for _, n := range gobrex.Expand(paramValue) {
+			// extract site and node ID
+			v, err := ParseCliFlag(regexNodeName, n)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in Engine debug parameter: '%s'", paramValue)
+			}
+
+			debugNodes[v["nodeName"]] = true
+		}
+	}
+
+	return debugNodes, nil
+}
+
+// parseWeaveConnectorFlag parse the Weave connector flag (site)-(id)
+func (c *CreateClusterCommand) parseWeaveConnectorFlag(flag []string) ([]string, error) {
+	// use a map to remove duplicates
+	connectors := make(map[string]bool)
+
+	for _, paramValue := range flag {
+		// brace expansion support
+		for _, n := range gobrex.Expand(paramValue) {
+			// extract site and node ID
+			v, err := ParseCliFlag(regexNodeName, n)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in Weave connector parameter: '%s'", paramValue)
+			}
+
+			connectors[v["nodeName"]] = true
+		}
+	}
+
+	weaveConnectors := []string{}
+	for n := range connectors {
+		weaveConnectors = append(weaveConnectors, n)
+	}
+	sort.Strings(weaveConnectors)
+
+	return weaveConnectors, nil
+}
+
 // parseEngineOptFlag parse the nodes Engine Opt flag {site}-{id}:optname=optvalue
 func (c *CreateClusterCommand) parseEngineOptFlag(flag []string) (map[string][]string, error) {
 	// initialize nodes Engine Opt map
@@ -250,6 +997,369 @@ func (c *CreateClusterCommand) parseEngineLabelFlag(flag []string) (map[string][
 	return nodesEngineLabel, nil
 }
 
+// parseLocalVolumeFlag parse the nodes local volume flag {site}-{id}:volumename=[device:]path
+func (c *CreateClusterCommand) parseLocalVolumeFlag(flag []string) (map[string][]volume.VolumeMount, error) {
+	// initialize nodes local volumes map
+	nodesLocalVolumes := make(map[string][]volume.VolumeMount)
+
+	for _, paramValue := range flag {
+		for _, f := range gobrex.Expand(paramValue) {
+			// extract node name and parameter
+			v, err := ParseCliFlag(regexNodeParamFlag, f)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in node local volume parameter: '%s'", paramValue)
+			}
+
+			// the device is optional
+			vol := volume.VolumeMount{Name: v["paramName"], Path: v["paramValue"]}
+			if s := strings.SplitN(v["paramValue"], ":", 2); len(s) == 2 {
+				vol.Device = s[0]
+				vol.Path = s[1]
+			}
+
+			// check local volume configuration
+			if err := vol.Validate(); err != nil {
+				return nil, err
+			}
+
+			// append the volume to the node's local volumes list
+			nodesLocalVolumes[v["nodeName"]] = append(nodesLocalVolumes[v["nodeName"]], vol)
+		}
+	}
+
+	return nodesLocalVolumes, nil
+}
+
+// parsePhaseTimeoutFlag parse the provisioning phases timeout flag (phase)=(duration)
+func (c *CreateClusterCommand) parsePhaseTimeoutFlag(flag []string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+
+	for _, f := range flag {
+		s := strings.SplitN(f, "=", 2)
+		if len(s) != 2 {
+			return nil, fmt.Errorf("Syntax error in provisioning phase timeout parameter: '%s'", f)
+		}
+
+		timeout, err := time.ParseDuration(s[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid timeout for provisioning phase '%s': '%s'", s[0], s[1])
+		}
+
+		timeouts[s[0]] = timeout
+	}
+
+	return timeouts, nil
+}
+
+// parseNodeAliasFlag parse the nodes alias flag {site}-{id}:alias
+func (c *CreateClusterCommand) parseNodeAliasFlag(flag []string) (map[string][]string, error) {
+	// initialize nodes aliases map
+	nodesAliases := make(map[string][]string)
+
+	for _, paramValue := range flag {
+		for _, f := range gobrex.Expand(paramValue) {
+			// extract node name and alias
+			v, err := ParseCliFlag(regexNodeAliasFlag, f)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in node alias parameter: '%s'", paramValue)
+			}
+
+			// append the alias to the node's aliases list
+			nodesAliases[v["nodeName"]] = append(nodesAliases[v["nodeName"]], v["alias"])
+		}
+	}
+
+	return nodesAliases, nil
+}
+
+// parseNodeDeployModeFlag parse the nodes deployment mode flag {site}-{id}:mode
+func (c *CreateClusterCommand) parseNodeDeployModeFlag(flag []string) (map[string]string, error) {
+	nodesModes := make(map[string]string)
+
+	for _, paramValue := range flag {
+		for _, f := range gobrex.Expand(paramValue) {
+			// extract node name and mode
+			v, err := ParseCliFlag(regexNodeDeployModeFlag, f)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in node deploy mode parameter: '%s'", paramValue)
+			}
+
+			nodesModes[v["nodeName"]] = v["mode"]
+		}
+	}
+
+	return nodesModes, nil
+}
+
+// parseNodePlatformFlag parse the nodes images platform flag {site}-{id}:platform
+func (c *CreateClusterCommand) parseNodePlatformFlag(flag []string) (map[string]string, error) {
+	nodesPlatforms := make(map[string]string)
+
+	for _, paramValue := range flag {
+		for _, f := range gobrex.Expand(paramValue) {
+			// extract node name and platform
+			v, err := ParseCliFlag(regexNodePlatformFlag, f)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in node platform parameter: '%s'", paramValue)
+			}
+
+			nodesPlatforms[v["nodeName"]] = v["platform"]
+		}
+	}
+
+	return nodesPlatforms, nil
+}
+
+// parseSysctlFlag parse the kernel parameters flag (name)=(value)
+func (c *CreateClusterCommand) parseSysctlFlag(flag []string) (map[string]string, error) {
+	sysctls := make(map[string]string)
+
+	for _, f := range flag {
+		s := strings.SplitN(f, "=", 2)
+		if len(s) != 2 || s[0] == "" || s[1] == "" {
+			return nil, fmt.Errorf("Syntax error in sysctl parameter: '%s'", f)
+		}
+
+		sysctls[s[0]] = s[1]
+	}
+
+	return sysctls, nil
+}
+
+// parseAnnotationFlag parse the annotations flag {key}={value} (nil if not set)
+func (c *CreateClusterCommand) parseAnnotationFlag(flag []string) (map[string]string, error) {
+	if len(flag) == 0 {
+		return nil, nil
+	}
+
+	annotations := make(map[string]string)
+	for _, f := range flag {
+		s := strings.SplitN(f, "=", 2)
+		if len(s) != 2 || s[0] == "" {
+			return nil, fmt.Errorf("Syntax error in annotation parameter: '%s'", f)
+		}
+
+		annotations[s[0]] = s[1]
+	}
+
+	return annotations, nil
+}
+
+// parseNodeFirewallFlag parse the nodes firewall rules flag {site}-{id}:action[:protocol[/port]][@source]
+func (c *CreateClusterCommand) parseNodeFirewallFlag(flag []string) (map[string][]cluster.FirewallRule, error) {
+	nodesRules := make(map[string][]cluster.FirewallRule)
+
+	for _, paramValue := range flag {
+		for _, f := range gobrex.Expand(paramValue) {
+			// extract node name and rule
+			v, err := ParseCliFlag(regexNodeFirewallFlag, f)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in node firewall parameter: '%s'", paramValue)
+			}
+
+			r, err := cluster.ParseFirewallRule(v["rule"])
+			if err != nil {
+				return nil, err
+			}
+			nodesRules[v["nodeName"]] = append(nodesRules[v["nodeName"]], r)
+		}
+	}
+
+	return nodesRules, nil
+}
+
+// parseOffloadFlag parse the offload features flag (feature)=on|off
+func (c *CreateClusterCommand) parseOffloadFlag(flag []string) (map[string]bool, error) {
+	offloads := make(map[string]bool)
+
+	for _, f := range flag {
+		s := strings.SplitN(f, "=", 2)
+		if len(s) != 2 || s[0] == "" || (s[1] != "on" && s[1] != "off") {
+			return nil, fmt.Errorf("Syntax error in offload feature parameter: '%s'", f)
+		}
+
+		offloads[s[0]] = s[1] == "on"
+	}
+
+	return offloads, nil
+}
+
+// parseNodeSysctlFlag parse the nodes kernel parameters flag {site}-{id}:name=value
+func (c *CreateClusterCommand) parseNodeSysctlFlag(flag []string) (map[string]map[string]string, error) {
+	nodesSysctls := make(map[string]map[string]string)
+
+	for _, paramValue := range flag {
+		for _, f := range gobrex.Expand(paramValue) {
+			// extract node name and parameter
+			v, err := ParseCliFlag(regexNodeParamFlag, f)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in node sysctl parameter: '%s'", paramValue)
+			}
+
+			if _, ok := nodesSysctls[v["nodeName"]]; !ok {
+				nodesSysctls[v["nodeName"]] = make(map[string]string)
+			}
+			nodesSysctls[v["nodeName"]][v["paramName"]] = v["paramValue"]
+		}
+	}
+
+	return nodesSysctls, nil
+}
+
+// parseSystemdOverrideFlag parse the Docker Engine systemd service overrides flag (Section.Key)=(value)
+func (c *CreateClusterCommand) parseSystemdOverrideFlag(flag []string) (map[string]string, error) {
+	overrides := make(map[string]string)
+
+	for _, f := range flag {
+		s := strings.SplitN(f, "=", 2)
+		if len(s) != 2 {
+			return nil, fmt.Errorf("Syntax error in Engine systemd override parameter: '%s'", f)
+		}
+
+		overrides[s[0]] = s[1]
+	}
+
+	return overrides, nil
+}
+
+// parseRuntimeFlag parse the OCI runtimes flag (name)=(binary path)
+func (c *CreateClusterCommand) parseRuntimeFlag(flag []string) (map[string]string, error) {
+	runtimes := make(map[string]string)
+
+	for _, f := range flag {
+		s := strings.SplitN(f, "=", 2)
+		if len(s) != 2 || s[0] == "" || s[1] == "" {
+			return nil, fmt.Errorf("Syntax error in Engine runtime parameter: '%s'", f)
+		}
+
+		runtimes[s[0]] = s[1]
+	}
+
+	return runtimes, nil
+}
+
+// parseHardwareLabelRuleFlag parse the hardware label rules flag (key)=(value):(condition)
+func (c *CreateClusterCommand) parseHardwareLabelRuleFlag(flag []string) ([]cluster.HardwareLabelRule, error) {
+	rules := []cluster.HardwareLabelRule{}
+
+	for _, f := range flag {
+		s := strings.SplitN(f, ":", 2)
+		if len(s) != 2 || s[0] == "" || s[1] == "" {
+			return nil, fmt.Errorf("Syntax error in hardware label rule parameter: '%s'", f)
+		}
+
+		rules = append(rules, cluster.HardwareLabelRule{Label: s[0], Condition: s[1]})
+	}
+
+	return rules, nil
+}
+
+// parseCoreHoursQuotaFlag parse the core-hours quota flag (site):(core-hours)
+func (c *CreateClusterCommand) parseCoreHoursQuotaFlag(flag []string) (map[string]float64, error) {
+	quota := make(map[string]float64)
+
+	for _, f := range flag {
+		v, err := ParseCliFlag(regexCoreHoursQuota, f)
+		if err != nil {
+			return nil, fmt.Errorf("Syntax error in core-hours quota parameter: '%s'", f)
+		}
+
+		hours, err := strconv.ParseFloat(v["hours"], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Error while converting core-hours in quota parameter: '%s'", f)
+		}
+
+		quota[v["site"]] = hours
+	}
+
+	return quota, nil
+}
+
+// parseRegistryAuthFlag parse the private registries credentials flag (registry)=(username):(password) or (registry)=(identity token)
+func (c *CreateClusterCommand) parseRegistryAuthFlag(flag []string) (map[string]cluster.RegistryAuth, error) {
+	auths := make(map[string]cluster.RegistryAuth)
+
+	for _, f := range flag {
+		// the credentials are not included in the error message
+		s := strings.SplitN(f, "=", 2)
+		if len(s) != 2 || s[0] == "" || s[1] == "" {
+			return nil, fmt.Errorf("Syntax error in registry credentials parameter (format: registry=username:password or registry=token)")
+		}
+
+		if creds := strings.SplitN(s[1], ":", 2); len(creds) == 2 {
+			auths[s[0]] = cluster.RegistryAuth{Username: creds[0], Password: creds[1]}
+		} else {
+			auths[s[0]] = cluster.RegistryAuth{IdentityToken: s[1]}
+		}
+	}
+
+	return auths, nil
+}
+
+// parseSharedMountFlag parse the nodes NFS shared mounts flag
+func (c *CreateClusterCommand) parseSharedMountFlag(flag []string) (map[string][]volume.SharedMount, error) {
+	// initialize nodes shared mounts map
+	nodesSharedMounts := make(map[string][]volume.SharedMount)
+
+	for _, paramValue := range flag {
+		for _, f := range gobrex.Expand(paramValue) {
+			// extract node name and parameter
+			v, err := ParseCliFlag(regexNodeParamFlag, f)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in node shared mount parameter: '%s'", paramValue)
+			}
+
+			// the volume name is optional
+			s := strings.Split(v["paramValue"], ":")
+			if len(s) < 2 || len(s) > 3 {
+				return nil, fmt.Errorf("Syntax error in node shared mount parameter: '%s'", paramValue)
+			}
+
+			m := volume.SharedMount{Server: s[0], Export: s[1], Path: v["paramName"]}
+			if len(s) == 3 {
+				m.VolumeName = s[2]
+			}
+
+			// check shared mount configuration
+			if err := m.Validate(); err != nil {
+				return nil, err
+			}
+
+			// append the mount to the node's shared mounts list
+			nodesSharedMounts[v["nodeName"]] = append(nodesSharedMounts[v["nodeName"]], m)
+		}
+	}
+
+	return nodesSharedMounts, nil
+}
+
+// parseAdvertisedResourcesFlag parse the nodes advertised resources flag
+func (c *CreateClusterCommand) parseAdvertisedResourcesFlag(flag []string) (map[string]*cluster.AdvertisedResources, error) {
+	// initialize nodes advertised resources map
+	nodesResources := make(map[string]*cluster.AdvertisedResources)
+
+	for _, paramValue := range flag {
+		for _, f := range gobrex.Expand(paramValue) {
+			// extract node name and parameter
+			v, err := ParseCliFlag(regexNodeParamFlag, f)
+			if err != nil {
+				return nil, fmt.Errorf("Syntax error in node advertised resources parameter: '%s'", paramValue)
+			}
+
+			if _, ok := nodesResources[v["nodeName"]]; !ok {
+				nodesResources[v["nodeName"]] = &cluster.AdvertisedResources{}
+			}
+
+			// set the resource of the node
+			if err := nodesResources[v["nodeName"]].Set(v["paramName"], v["paramValue"]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nodesResources, nil
+}
+
 // checkCliParameters perform checks on CLI parameters
 func (c *CreateClusterCommand) checkCliParameters() error {
 	// check username
@@ -282,6 +1392,34 @@ func (c *CreateClusterCommand) checkCliParameters() error {
 		return fmt.Errorf("You must provide a Docker Engine install URL")
 	}
 
+	// check registry node
+	if n := c.cli.String("registry-node"); n != "" {
+		if _, err := ParseCliFlag("^"+regexNodeName+"$", n); err != nil {
+			return fmt.Errorf("Syntax error in registry node parameter: '%s'", n)
+		}
+	} else if c.cli.String("registry-proxy-remote-url") != "" {
+		return fmt.Errorf("You need to select a registry node to use the registry as a pull-through cache")
+	}
+
+	// check ingress node
+	if n := c.cli.String("ingress-node"); n != "" {
+		if _, err := ParseCliFlag("^"+regexNodeName+"$", n); err != nil {
+			return fmt.Errorf("Syntax error in ingress node parameter: '%s'", n)
+		}
+	}
+
+	// check bastion node
+	if n := c.cli.String("bastion-node"); n != "" {
+		if _, err := ParseCliFlag("^"+regexNodeName+"$", n); err != nil {
+			return fmt.Errorf("Syntax error in bastion node parameter: '%s'", n)
+		}
+	}
+
+	// Swarm standalone and Swarm mode are mutually exclusive
+	if c.cli.Bool("swarm-standalone-enable") && c.cli.Bool("swarm-mode-enable") {
+		return fmt.Errorf("The --swarm-standalone-enable and --swarm-mode-enable flags are mutually exclusive")
+	}
+
 	// check if a Swarm master is defined (only if Swarm is enabled)
 	if c.cli.Bool("swarm-standalone-enable") || c.cli.Bool("swarm-mode-enable") {
 		if len(c.cli.StringSlice("swarm-master")) == 0 {
@@ -296,19 +1434,54 @@ func (c *CreateClusterCommand) checkCliParameters() error {
 			return fmt.Errorf("You must provide a Swarm image")
 		}
 
-		// check Docker Swarm strategy
-		if c.cli.String("swarm-standalone-strategy") == "" {
-			return fmt.Errorf("You must provide a Swarm strategy")
-		}
+		// check Docker Swarm strategy
+		if c.cli.String("swarm-standalone-strategy") == "" {
+			return fmt.Errorf("You must provide a Swarm strategy")
+		}
+
+		// check Docker Swarm discovery backend
+		if b := c.cli.String("swarm-standalone-discovery-backend"); b != "" {
+			if _, err := swarm.ParseDiscoveryBackend(b); err != nil {
+				return err
+			}
+		}
+
+		// check the external discovery replaces the discovery service
+		if c.cli.String("swarm-standalone-external-discovery") != "" && c.cli.String("swarm-standalone-discovery") != "" {
+			return fmt.Errorf("The --swarm-standalone-discovery and --swarm-standalone-external-discovery flags are mutually exclusive")
+		}
+	}
+
+	// check Weave connectors are only used with Weave networking
+	if len(c.cli.StringSlice("weave-connector")) > 0 && !c.cli.Bool("weave-networking") {
+		return fmt.Errorf("You need to enable Weave networking to select Weave connectors")
+	}
+
+	// check the minimum number of provisioned nodes is not used in atomic mode (all nodes are required)
+	if c.cli.Int("min-successful-nodes") < 0 {
+		return fmt.Errorf("The minimum number of successful nodes can't be negative")
+	}
+	if c.cli.Int("min-successful-nodes") > 0 && c.cli.Bool("atomic") {
+		return fmt.Errorf("You can't set a minimum number of successful nodes in atomic mode (all nodes are required)")
+	}
+
+	// check Weave password is only used with Weave networking
+	if c.cli.String("weave-password") != "" && !c.cli.Bool("weave-networking") {
+		return fmt.Errorf("You need to enable Weave networking to use a Weave password")
+	}
+
+	// check Weave stable peers are only used with Weave networking
+	if c.cli.Bool("weave-stable-peers") && !c.cli.Bool("weave-networking") {
+		return fmt.Errorf("You need to enable Weave networking to use Weave stable peers")
+	}
+
+	// check Weave images are only used with Weave networking
+	if (c.cli.String("weave-image") != "" || c.cli.String("weave-version") != "") && !c.cli.Bool("weave-networking") {
+		return fmt.Errorf("You need to enable Weave networking to use a Weave image or version")
 	}
 
 	// check Swarm Mode parameters
 	if c.cli.Bool("swarm-mode-enable") {
-		// block enabling Swarm mode and Swarm standalone at the same time
-		if c.cli.Bool("swarm-standalone-enable") {
-			return fmt.Errorf("You can't enable both swarm modes at the same time")
-		}
-
 		// block enabling Weave Networking (unsupported with Swarm Mode)
 		if c.cli.Bool("weave-networking") {
 			return fmt.Errorf("You can't enable Weave networking with Swarm Mode (Only Swarm Standalone is supported)")
@@ -322,33 +1495,270 @@ func (c *CreateClusterCommand) checkCliParameters() error {
 func (c *CreateClusterCommand) configureCluster() (*cluster.GlobalConfig, error) {
 	// create nodes global configuration
 	clusterConfig := &cluster.GlobalConfig{
-		LibMachineClient:       libmachine.NewClient(mcndirs.GetBaseDir(), mcndirs.GetMachineCertDir()),
+		LibMachineClient:       cluster.NewMachineStorageClient(c.cli.String("machine-storage-path")),
+		MachineStoragePath:     c.cli.String("machine-storage-path"),
 		EngineInstallURL:       c.cli.String("engine-install-url"),
+		DisableG5kLabels:       c.cli.Bool("engine-disable-g5k-labels"),
+		AliasesEngineLabel:     c.cli.Bool("engine-aliases-label"),
 		G5kUsername:            c.cli.String("g5k-username"),
 		G5kPassword:            c.cli.String("g5k-password"),
 		G5kImage:               c.cli.String("g5k-image"),
 		G5kWalltime:            c.cli.String("g5k-walltime"),
 		WeaveNetworkingEnabled: c.cli.Bool("weave-networking"),
+		WeaveStablePeers:       c.cli.Bool("weave-stable-peers"),
+		WeavePassword:          c.cli.String("weave-password"),
 		HostsLookupTable:       make(map[string]string),
+		LogRotation: cluster.LogRotation{
+			MaxSize: c.cli.String("engine-log-max-size"),
+			MaxFile: c.cli.Int("engine-log-max-file"),
+		},
+	}
+
+	// Weave Net images (upstream images if not set)
+	clusterConfig.WeaveConfig = weave.Config{Image: c.cli.String("weave-image"), Version: c.cli.String("weave-version")}
+
+	// Docker Engine bridge subnet
+	clusterConfig.BridgeSubnet = c.cli.String("engine-bridge-subnet")
+	clusterConfig.ContainerDNSOptions = c.cli.StringSlice("engine-dns-opt")
+
+	// Docker Engine userland proxy and iptables (Docker defaults if not disabled)
+	if c.cli.Bool("engine-disable-userland-proxy") {
+		userlandProxy := false
+		clusterConfig.UserlandProxy = &userlandProxy
+	}
+	if c.cli.Bool("engine-disable-iptables") {
+		manageIptables := false
+		clusterConfig.ManageIptables = &manageIptables
+	}
+
+	// Docker Engine systemd service overrides
+	systemdOverrides, err := c.parseSystemdOverrideFlag(c.cli.StringSlice("engine-systemd-override"))
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig.EngineSystemdOverrides = systemdOverrides
+
+	// kernel parameters
+	sysctls, err := c.parseSysctlFlag(c.cli.StringSlice("g5k-sysctl"))
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig.CommonSysctls = sysctls
+
+	// cluster ID
+	clusterConfig.ClusterID = c.cli.String("cluster-id")
+
+	// annotations
+	annotations, err := c.parseAnnotationFlag(c.cli.StringSlice("annotation"))
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig.Annotations = annotations
+
+	// minimum number of provisioned nodes
+	clusterConfig.MinSuccessfulNodes = c.cli.Int("min-successful-nodes")
+	clusterConfig.ProvisionDeadline = c.cli.Duration("provision-deadline")
+	clusterConfig.Seed = c.cli.Int64("provisioning-seed")
+
+	// default container network
+	clusterConfig.DefaultContainerNetwork = c.cli.String("engine-default-network")
+
+	// Docker data root
+	clusterConfig.DataRoot = c.cli.String("engine-data-root")
+	clusterConfig.DataRootDevice = c.cli.String("engine-data-root-device")
+	clusterConfig.DataRootMinFree = c.cli.String("engine-data-root-min-free")
+	clusterConfig.DataRootPersistent = c.cli.Bool("engine-data-root-persistent")
+
+	// Docker Engine experimental features and API version
+	clusterConfig.EngineExperimental = c.cli.Bool("engine-experimental")
+	clusterConfig.EngineAPIVersion = c.cli.String("engine-api-version")
+	clusterConfig.EngineMetricsAddr = c.cli.String("engine-metrics-addr")
+	clusterConfig.EngineLogLevel = c.cli.String("engine-log-level")
+	clusterConfig.DefaultShmSize = c.cli.String("engine-default-shm-size")
+	clusterConfig.EnginePullProxy = c.cli.String("engine-pull-proxy")
+	clusterConfig.UsernsRemap = c.cli.String("engine-userns-remap")
+
+	// OCI runtimes
+	runtimes, err := c.parseRuntimeFlag(c.cli.StringSlice("engine-runtime"))
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig.RuntimeBinaries = runtimes
+	clusterConfig.OCIRuntime = c.cli.String("engine-default-runtime")
+
+	// placement policy
+	clusterConfig.PlacementPolicy = cluster.PlacementPolicy{
+		ManagersAntiAffinity: c.cli.String("swarm-managers-anti-affinity"),
+	}
+
+	// provisioning phases timeout
+	phaseTimeouts, err := c.parsePhaseTimeoutFlag(c.cli.StringSlice("phase-timeout"))
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig.PhaseTimeouts = phaseTimeouts
+
+	// provisioning log files of the nodes
+	clusterConfig.LogDir = c.cli.String("provisioning-log-dir")
+	clusterConfig.LogLevel = c.cli.String("log-level")
+
+	// OAR queue
+	clusterConfig.OARQueue = c.cli.String("g5k-job-queue")
+
+	// deployment mode
+	clusterConfig.DeployMode = c.cli.String("g5k-deploy-mode")
+
+	// images platform
+	clusterConfig.DefaultPlatform = c.cli.String("default-platform")
+
+	// fallback sites
+	clusterConfig.SiteFallbacks = c.cli.StringSlice("g5k-site-fallback")
+
+	// core-hours quota
+	coreHoursQuota, err := c.parseCoreHoursQuotaFlag(c.cli.StringSlice("g5k-core-hours-quota"))
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig.CoreHoursQuota = coreHoursQuota
+	clusterConfig.MaxCoreHours = c.cli.Float64("g5k-max-core-hours")
+	clusterConfig.AllowCoreHoursOverrun = c.cli.Bool("g5k-allow-core-hours-overrun")
+
+	// swap
+	clusterConfig.DisableSwap = c.cli.Bool("g5k-disable-swap")
+	clusterConfig.JobFacts = c.cli.Bool("g5k-job-facts")
+	clusterConfig.SSHWaitTimeout = c.cli.Duration("g5k-ssh-wait-timeout")
+	clusterConfig.HostCacheSize = c.cli.Int("host-cache-size")
+	clusterConfig.HostCacheIdleTimeout = c.cli.Duration("host-cache-idle-timeout")
+	clusterConfig.FleetConcurrency = c.cli.Int("fleet-concurrency")
+
+	// Docker Engine security profiles
+	clusterConfig.SeccompProfilePath = c.cli.String("engine-seccomp-profile")
+	clusterConfig.AppArmorProfile = c.cli.String("engine-apparmor-profile")
+
+	// enable registry mirror
+	if c.cli.String("registry-node") != "" {
+		clusterConfig.DeployRegistry = true
+		clusterConfig.RegistryNode = c.cli.String("registry-node")
+		clusterConfig.RegistryProxyRemoteURL = c.cli.String("registry-proxy-remote-url")
+	}
+
+	// ingress controller
+	if c.cli.String("ingress-node") != "" {
+		clusterConfig.DeployIngress = &ingress.Spec{
+			Node: c.cli.String("ingress-node"),
+			Port: c.cli.Int("ingress-port"),
+		}
+	}
+
+	// bastion node
+	if c.cli.String("bastion-node") != "" {
+		clusterConfig.BastionNode = c.cli.String("bastion-node")
+		clusterConfig.BastionPort = c.cli.Int("bastion-port")
+	}
+
+	// private registries credentials
+	registryAuths, err := c.parseRegistryAuthFlag(c.cli.StringSlice("registry-auth"))
+	if err != nil {
+		return nil, err
+	}
+	clusterConfig.RegistryAuths = registryAuths
+	clusterConfig.TrustedCABundles = c.cli.StringSlice("engine-trusted-ca")
+	clusterConfig.ReuseExistingCerts = c.cli.Bool("engine-reuse-certs")
+
+	// infrastructure containers restart policy
+	clusterConfig.InfraRestartPolicy = c.cli.String("infra-restart-policy")
+	clusterConfig.InfraContainerCaps = c.cli.StringSlice("infra-container-cap")
+	clusterConfig.InfraStopSignal = c.cli.String("infra-stop-signal")
+	clusterConfig.InfraStopTimeout = c.cli.Duration("infra-stop-timeout")
+	clusterConfig.ContainerDefaultCaps = c.cli.StringSlice("container-default-cap")
+
+	// network requirement
+	if c.cli.String("g5k-network-requirement") != "" {
+		networkRequirement, err := g5k.ParseNetworkRequirement(c.cli.String("g5k-network-requirement"))
+		if err != nil {
+			return nil, err
+		}
+
+		clusterConfig.NetworkRequirement = networkRequirement
+	}
+
+	// enable Swarm mode managers quorum check
+	if c.cli.Bool("swarm-mode-require-quorum") {
+		clusterConfig.RequireQuorumOnProvision = true
+		clusterConfig.QuorumTimeout = c.cli.Duration("swarm-mode-quorum-timeout")
 	}
 
 	// Swarm Standalone config
 	if c.cli.Bool("swarm-standalone-enable") {
 		// enable Swarm Standalone
 		clusterConfig.SwarmStandaloneGlobalConfig = &swarm.SwarmStandaloneGlobalConfig{
-			Image:       c.cli.String("swarm-standalone-image"),
-			Discovery:   c.cli.String("swarm-standalone-discovery"),
-			Strategy:    c.cli.String("swarm-standalone-strategy"),
-			MasterFlags: c.cli.StringSlice("swarm-standalone-opt"),
-			JoinFlags:   c.cli.StringSlice("swarm-standalone-join-opt"),
+			Image:            c.cli.String("swarm-standalone-image"),
+			DiscoveryBackend: swarm.DiscoveryBackend(c.cli.String("swarm-standalone-discovery-backend")),
+			Discovery:        c.cli.String("swarm-standalone-discovery"),
+			Strategy:         c.cli.String("swarm-standalone-strategy"),
+			MasterFlags:      c.cli.StringSlice("swarm-standalone-opt"),
+			JoinFlags:        c.cli.StringSlice("swarm-standalone-join-opt"),
 		}
+		clusterConfig.SwarmStandaloneGlobalConfig.ExternalDiscoveryURL = c.cli.String("swarm-standalone-external-discovery")
+		clusterConfig.ClusterAdvertisePort = c.cli.Int("swarm-standalone-cluster-advertise-port")
 	}
 
 	// enable Swarm Mode
 	if c.cli.Bool("swarm-mode-enable") {
-		clusterConfig.SwarmModeGlobalConfig = &swarm.SwarmModeGlobalConfig{}
+		clusterConfig.SwarmModeGlobalConfig = &swarm.SwarmModeGlobalConfig{
+			InitWaitTimeout: c.cli.Duration("swarm-mode-init-wait-timeout"),
+			JoinRetries:     c.cli.Int("swarm-mode-join-retries"),
+			JoinBackoff:     c.cli.Duration("swarm-mode-join-backoff"),
+			AdvertiseAddr:   c.cli.String("swarm-mode-advertise-addr"),
+			BootstrapSite:   c.cli.String("swarm-mode-bootstrap-site"),
+			OverlayDefaults: swarm.OverlayDefaults{
+				Encrypted: c.cli.Bool("swarm-mode-overlay-encrypted"),
+				VXLANPort: c.cli.Int("swarm-mode-data-path-port"),
+			},
+			OrchestrationOpts: swarm.OrchestrationOpts{
+				TaskHistoryLimit:    c.cli.Int("swarm-mode-task-history-limit"),
+				DispatcherHeartbeat: c.cli.Duration("swarm-mode-dispatcher-heartbeat"),
+				NodeCertExpiry:      c.cli.Duration("swarm-mode-cert-expiry"),
+			},
+			SmokeTestImage:    c.cli.String("swarm-mode-smoke-test-image"),
+			SmokeTestReplicas: c.cli.Int("swarm-mode-smoke-test-replicas"),
+
+			DefaultPlacementPreferences: c.cli.StringSlice("swarm-mode-placement-pref"),
+		}
+		clusterConfig.SmokeTestOnProvision = c.cli.Bool("swarm-mode-smoke-test")
+
+		// data path tuning
+		offloads, err := c.parseOffloadFlag(c.cli.StringSlice("swarm-mode-data-path-offload"))
+		if err != nil {
+			return nil, err
+		}
+		dataPathSysctls, err := c.parseSysctlFlag(c.cli.StringSlice("swarm-mode-data-path-sysctl"))
+		if err != nil {
+			return nil, err
+		}
+		clusterConfig.SwarmModeGlobalConfig.DataPathTuning = swarm.DataPathTuning{Offloads: offloads, Sysctls: dataPathSysctls}
+
+		// service discovery network
+		clusterConfig.SwarmModeGlobalConfig.ServiceDiscoveryNetwork = c.cli.String("swarm-mode-service-discovery-network")
+		clusterConfig.SwarmModeGlobalConfig.ServiceDiscoverySubnet = c.cli.String("swarm-mode-service-discovery-subnet")
+
+		// hardware labels
+		rules, err := c.parseHardwareLabelRuleFlag(c.cli.StringSlice("swarm-mode-hardware-label-rule"))
+		if err != nil {
+			return nil, err
+		}
+		clusterConfig.HardwareLabels = c.cli.Bool("swarm-mode-hardware-labels")
+		clusterConfig.HardwareLabelRules = rules
+	}
+
+	// check cluster configuration
+	if err := clusterConfig.Validate(); err != nil {
+		return nil, err
 	}
 
+	// the reservation and deployment output also follow the logging mode
+	clusterConfig.ConfigureLogging()
+
 	// generate SSH key pair
 	if err := clusterConfig.GenerateSSHKeyPair(); err != nil {
 		return nil, fmt.Errorf("Error while generating cluster SSH key pair: '%s'", err)
@@ -358,7 +1768,7 @@ func (c *CreateClusterCommand) configureCluster() (*cluster.GlobalConfig, error)
 }
 
 // CreateCluster create nodes in docker-machine
-func (c *CreateClusterCommand) createCluster() error {
+func (c *CreateClusterCommand) createCluster() (err error) {
 	// create Grid5000 API client
 	g5kAPI := g5k.Init(c.cli.String("g5k-username"), c.cli.String("g5k-password"))
 
@@ -369,8 +1779,9 @@ func (c *CreateClusterCommand) createCluster() error {
 	}
 
 	// create new cluster
-	cluster := cluster.NewCluster(clusterConfig)
-	defer cluster.Config.LibMachineClient.Close()
+	g5kCluster := cluster.NewCluster(clusterConfig)
+	defer g5kCluster.Config.LibMachineClient.Close()
+	defer g5kCluster.Close()
 
 	// parse nodes reservation
 	nodesReservation, err := c.parseReserveNodesFlag(c.cli.StringSlice("g5k-reserve-nodes"))
@@ -384,7 +1795,7 @@ func (c *CreateClusterCommand) createCluster() error {
 	}
 
 	// create nodes in the cluster
-	cluster.CreateNodes(nodesReservation)
+	g5kCluster.CreateNodes(nodesReservation)
 
 	// parse engine opt
 	engineOpts, err := c.parseEngineOptFlag(c.cli.StringSlice("engine-opt"))
@@ -394,11 +1805,11 @@ func (c *CreateClusterCommand) createCluster() error {
 
 	// apply engine options to nodes
 	for node, opts := range engineOpts {
-		if _, ok := cluster.Nodes[node]; !ok {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
 			return fmt.Errorf("The node '%s' does not exist", node)
 		}
 
-		cluster.Nodes[node].EngineOpt = append(cluster.Nodes[node].EngineOpt, opts...)
+		g5kCluster.Nodes[node].EngineOpt = append(g5kCluster.Nodes[node].EngineOpt, opts...)
 	}
 
 	// parse engine label
@@ -409,11 +1820,146 @@ func (c *CreateClusterCommand) createCluster() error {
 
 	// apply engine labels to nodes
 	for node, labels := range engineLabels {
-		if _, ok := cluster.Nodes[node]; !ok {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].EngineLabel = append(g5kCluster.Nodes[node].EngineLabel, labels...)
+	}
+
+	// parse nodes aliases
+	nodesAliases, err := c.parseNodeAliasFlag(c.cli.StringSlice("g5k-node-alias"))
+	if err != nil {
+		return err
+	}
+
+	// apply aliases to nodes
+	for node, aliases := range nodesAliases {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].Aliases = append(g5kCluster.Nodes[node].Aliases, aliases...)
+	}
+
+	// parse nodes deployment mode
+	nodesModes, err := c.parseNodeDeployModeFlag(c.cli.StringSlice("g5k-node-deploy-mode"))
+	if err != nil {
+		return err
+	}
+
+	// apply deployment mode to nodes
+	for node, mode := range nodesModes {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].DeployMode = mode
+	}
+
+	// parse nodes images platform
+	nodesPlatforms, err := c.parseNodePlatformFlag(c.cli.StringSlice("g5k-node-platform"))
+	if err != nil {
+		return err
+	}
+
+	// apply images platform to nodes
+	for node, platform := range nodesPlatforms {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].Platform = platform
+	}
+
+	// parse Engine debug mode
+	debugNodes, err := c.parseEngineDebugFlag(c.cli.StringSlice("engine-debug"))
+	if err != nil {
+		return err
+	}
+
+	// enable debug mode on nodes
+	for node := range debugNodes {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].EngineDebug = true
+	}
+
+	// parse nodes kernel parameters
+	nodesSysctls, err := c.parseNodeSysctlFlag(c.cli.StringSlice("g5k-node-sysctl"))
+	if err != nil {
+		return err
+	}
+
+	// apply kernel parameters to nodes
+	for node, sysctls := range nodesSysctls {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].Sysctls = sysctls
+	}
+
+	// parse nodes firewall rules
+	nodesRules, err := c.parseNodeFirewallFlag(c.cli.StringSlice("g5k-node-firewall"))
+	if err != nil {
+		return err
+	}
+
+	// apply firewall rules to nodes
+	for node, rules := range nodesRules {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].FirewallRules = rules
+	}
+
+	// parse local volumes
+	localVolumes, err := c.parseLocalVolumeFlag(c.cli.StringSlice("g5k-local-volume"))
+	if err != nil {
+		return err
+	}
+
+	// apply local volumes to nodes
+	for node, volumes := range localVolumes {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].LocalVolumeMounts = append(g5kCluster.Nodes[node].LocalVolumeMounts, volumes...)
+	}
+
+	// parse shared mounts
+	sharedMounts, err := c.parseSharedMountFlag(c.cli.StringSlice("g5k-shared-mount"))
+	if err != nil {
+		return err
+	}
+
+	// apply shared mounts to nodes
+	for node, mounts := range sharedMounts {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].SharedMounts = append(g5kCluster.Nodes[node].SharedMounts, mounts...)
+	}
+
+	// parse advertised resources
+	advertisedResources, err := c.parseAdvertisedResourcesFlag(c.cli.StringSlice("swarm-mode-advertised-resources"))
+	if err != nil {
+		return err
+	}
+
+	// apply advertised resources to nodes
+	for node, resources := range advertisedResources {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
 			return fmt.Errorf("The node '%s' does not exist", node)
 		}
 
-		cluster.Nodes[node].EngineLabel = append(cluster.Nodes[node].EngineLabel, labels...)
+		g5kCluster.Nodes[node].AdvertisedResources = *resources
 	}
 
 	// parse Swarm master flag
@@ -422,45 +1968,288 @@ func (c *CreateClusterCommand) createCluster() error {
 		return err
 	}
 
-	// store swarm master nodes
+	// set swarm master nodes role
 	for node := range swarmMaster {
-		if _, ok := cluster.Nodes[node]; !ok {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", node)
+		}
+
+		g5kCluster.Nodes[node].Role = cluster.NodeRoleManager
+	}
+	g5kCluster.SyncNodeRoles()
+
+	// parse Weave connector flag
+	weaveConnectors, err := c.parseWeaveConnectorFlag(c.cli.StringSlice("weave-connector"))
+	if err != nil {
+		return err
+	}
+
+	// store Weave connectors
+	for _, node := range weaveConnectors {
+		if _, ok := g5kCluster.Nodes[node]; !ok {
 			return fmt.Errorf("The node '%s' does not exist", node)
 		}
+	}
+	g5kCluster.Config.WeaveConnectors = weaveConnectors
+
+	// check registry node exist
+	if g5kCluster.Config.DeployRegistry {
+		if _, ok := g5kCluster.Nodes[g5kCluster.Config.RegistryNode]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", g5kCluster.Config.RegistryNode)
+		}
+	}
+
+	// add network requirement to the resource properties
+	resourceProperties := c.cli.String("g5k-resource-properties")
+	if g5kCluster.Config.NetworkRequirement != nil {
+		// check the sites have nodes satisfying the network requirement
+		for site := range nodesReservation {
+			if err := g5kAPI.CheckNetworkRequirement(site, g5kCluster.Config.NetworkRequirement); err != nil {
+				return err
+			}
+		}
+
+		resourceProperties = g5k.CombineResourceProperties(resourceProperties, g5kCluster.Config.NetworkRequirement.OARProperties())
+	}
+
+	// check the sites have enough failure domains to spread the Swarm managers
+	antiAffinity := g5kCluster.Config.PlacementPolicy.ManagersAntiAffinity
+	if antiAffinity != "" {
+		for site := range nodesReservation {
+			nbManagers := g5kCluster.SiteManagersCount(site)
+			if nbManagers < 2 {
+				continue
+			}
+
+			nbDomains, err := g5kAPI.CountFailureDomains(site, antiAffinity)
+			if err != nil {
+				return err
+			}
+
+			if nbDomains < nbManagers {
+				return fmt.Errorf("The %d Swarm managers of site '%s' can't be spread on distinct failure domains ('%s'): only %d available", nbManagers, site, antiAffinity, nbDomains)
+			}
+		}
+	}
+
+	// check the deployment mode of each site before any reservation (the nodes of a site are reserved in a single job)
+	modes := make(map[string]string)
+	for site := range nodesReservation {
+		mode, err := g5kCluster.SiteDeployMode(site)
+		if err != nil {
+			return err
+		}
+
+		modes[site] = mode
+	}
+
+	// resolve the environment to deploy on each site before any reservation (a path or an URL is used as is, no environment in classic mode)
+	images := make(map[string]string)
+	for site := range nodesReservation {
+		if modes[site] == cluster.DeployModeClassic {
+			continue
+		}
+
+		image, err := g5kAPI.ResolveEnvironment(site, c.cli.String("g5k-image"))
+		if err != nil {
+			return err
+		}
+
+		images[site] = image
+	}
+
+	// check the estimated core-hours of the reservations does not exceed the maximum
+	if _, err := g5kCluster.Config.CheckMaxCoreHours(nodesReservation); err != nil {
+		return err
+	}
+
+	// check the reservations fit in the remaining core-hours of the sites
+	if len(g5kCluster.Config.CoreHoursQuota) > 0 {
+		sites := []string{}
+		for site := range nodesReservation {
+			sites = append(sites, site)
+		}
+
+		report, err := g5kCluster.Config.QuotaUsage(sites)
+		if err != nil {
+			return err
+		}
+
+		for site, nb := range nodesReservation {
+			if err := report.CheckReservation(site, nb, c.cli.String("g5k-walltime")); err != nil {
+				return err
+			}
+		}
+	}
+
+	// release the reserved jobs if the cluster can't be entirely reserved and deployed (atomic mode)
+	reservedJobs := make(map[string]int)
+	if c.cli.Bool("atomic") {
+		defer func() {
+			if err != nil && len(reservedJobs) > 0 {
+				log.Warn("The cluster creation failed, releasing the reserved jobs...")
+				if releaseErr := releaseReservedJobs(reservedJobs, g5kAPI.CancelJob); releaseErr != nil {
+					log.Error(releaseErr)
+				}
+			}
+		}()
+	}
 
-		cluster.Config.SwarmMasterNode = append(cluster.Config.SwarmMasterNode, node)
+	// the fallback sites can't be sites of the reservation (a site can only have one job per cluster)
+	excludedSites := make(map[string]bool)
+	for site := range nodesReservation {
+		excludedSites[site] = true
 	}
 
-	// process nodes reservations by sites
-	for site, nb := range nodesReservation {
-		log.Infof("Reserving %d nodes on '%s' site...", nb, site)
+	// process nodes reservations by sites (tried on the fallback sites if the reservation fails), the reserved site of each requested site is recorded
+	reservedSites := make(map[string]string)
+	for requestedSite, nb := range nodesReservation {
+		resources := g5k.GenerateResources(nb, c.cli.String("g5k-walltime"), antiAffinity, g5kCluster.SiteManagersCount(requestedSite))
+
+		site, jobID, err := cluster.ReserveWithFallback(g5kCluster.Config.ReservationSites(requestedSite, excludedSites), func(site string) (int, error) {
+			// the checks of the requested sites are done before any reservation
+			if site != requestedSite {
+				image, err := c.checkFallbackSite(g5kAPI, g5kCluster, site, nb, antiAffinity, g5kCluster.SiteManagersCount(requestedSite), modes[requestedSite])
+				if err != nil {
+					return 0, fmt.Errorf("The fallback site '%s' can't be used: %w: %w", site, cluster.ErrReservation, err)
+				}
+				images[site] = image
+			}
+
+			if g5kCluster.Config.OARQueue != "" {
+				log.Infof("Reserving %d nodes on '%s' site (queue '%s', %s mode)...", nb, site, g5kCluster.Config.OARQueue, modes[requestedSite])
+			} else {
+				log.Infof("Reserving %d nodes on '%s' site (%s mode)...", nb, site, modes[requestedSite])
+			}
 
-		// reserve nodes
-		jobID, err := g5kAPI.ReserveNodes(site, nb, c.cli.String("g5k-resource-properties"), c.cli.String("g5k-walltime"))
+			return cluster.ReserveJob(g5kAPI, site, resources, resourceProperties, g5kCluster.Config.OARQueue, cluster.DeployModeJobType(modes[requestedSite]), g5kCluster.Config.PhaseTimeout(cluster.PhaseReserve))
+		})
 		if err != nil {
-			return fmt.Errorf("Job reservation for site '%s' failed: '%s'", site, err)
+			return err
 		}
+		excludedSites[site] = true
+		reservedJobs[site] = jobID
+		reservedSites[requestedSite] = site
+
+		// the nodes keep the machine names of the requested site
+		g5kCluster.RelocateSiteNodes(requestedSite, site)
+	}
+
+	// deploy the nodes once all the sites are reserved (nothing is deployed if a reservation fails)
+	for requestedSite := range nodesReservation {
+		site := reservedSites[requestedSite]
+		jobID := reservedJobs[site]
 
-		// deploy nodes
-		deployedNodes, err := g5kAPI.DeployNodes(site, string(cluster.Config.SSHKeyPair.PublicKey), jobID, c.cli.String("g5k-image"))
+		// deploy nodes (not in classic mode)
+		deployedNodes, err := cluster.DeployJobNodes(g5kAPI, site, modes[requestedSite], string(g5kCluster.Config.SSHKeyPair.PublicKey), jobID, images[site])
 		if err != nil {
-			return fmt.Errorf("Nodes deployment for site '%s' failed: '%s'", site, err)
+			return fmt.Errorf("Nodes deployment for site '%s' failed: %w: %w", site, cluster.ErrDeployment, err)
+		}
+
+		// order the deployed nodes using the seed (the Grid5000 API order is not stable)
+		deployedNodes = g5kCluster.OrderDeployedNodes(requestedSite, deployedNodes)
+
+		// order the deployed nodes to allocate the Swarm managers on distinct failure domains
+		var domains map[string]string
+		if antiAffinity != "" {
+			domains, err = g5kAPI.GetNodesFailureDomain(site, deployedNodes, antiAffinity)
+			if err != nil {
+				return fmt.Errorf("Unable to get the failure domain of the nodes for site '%s' : '%s'", site, err)
+			}
+
+			deployedNodes = g5kCluster.PlaceManagers(requestedSite, deployedNodes, domains)
 		}
 
 		// allocate deployed nodes to machines
-		if err := cluster.AllocateDeployedNodesToMachines(site, jobID, deployedNodes); err != nil {
+		if err := g5kCluster.AllocateDeployedNodesToMachines(requestedSite, jobID, deployedNodes); err != nil {
 			return fmt.Errorf("Unable to allocate deployed nodes to machines for site '%s' : '%s'", site, err)
 		}
+
+		// store the failure domain of the nodes
+		g5kCluster.SetFailureDomains(domains)
+
+		// select the nodes network interface satisfying the network requirement
+		if err := g5kCluster.ResolveAdvertiseInterfaces(g5kAPI, site); err != nil {
+			return fmt.Errorf("Unable to select the network interface of the nodes for site '%s' : '%s'", site, err)
+		}
+	}
+
+	// provision deployed nodes (the cluster jobs are released on failure in atomic mode)
+	if c.cli.Bool("atomic") {
+		reservedJobs = map[string]int{}
+		return g5kCluster.ProvisionAtomic()
 	}
 
-	// provision deployed nodes
-	if err := cluster.ProvisionNodes(); err != nil {
+	if err := g5kCluster.ProvisionNodes(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// checkFallbackSite run the checks of the requested sites on the fallback site (VPN, network requirement, failure domains, quota) and returns the environment to deploy on it (none in classic mode)
+func (c *CreateClusterCommand) checkFallbackSite(g5kAPI *g5k.G5K, g5kCluster *cluster.Cluster, site string, nb int, antiAffinity string, nbManagers int, mode string) (string, error) {
+	if err := g5kAPI.CheckVpnConnection(map[string]int{site: nb}); err != nil {
+		return "", err
+	}
+
+	if g5kCluster.Config.NetworkRequirement != nil {
+		if err := g5kAPI.CheckNetworkRequirement(site, g5kCluster.Config.NetworkRequirement); err != nil {
+			return "", err
+		}
+	}
+
+	if antiAffinity != "" && nbManagers >= 2 {
+		nbDomains, err := g5kAPI.CountFailureDomains(site, antiAffinity)
+		if err != nil {
+			return "", err
+		}
+
+		if nbDomains < nbManagers {
+			return "", fmt.Errorf("The %d Swarm managers can't be spread on distinct failure domains ('%s'): only %d available", nbManagers, antiAffinity, nbDomains)
+		}
+	}
+
+	if _, err := g5kCluster.Config.CheckMaxCoreHours(map[string]int{site: nb}); err != nil {
+		return "", err
+	}
+
+	if len(g5kCluster.Config.CoreHoursQuota) > 0 {
+		report, err := g5kCluster.Config.QuotaUsage([]string{site})
+		if err != nil {
+			return "", err
+		}
+
+		if err := report.CheckReservation(site, nb, c.cli.String("g5k-walltime")); err != nil {
+			return "", err
+		}
+	}
+
+	if mode == cluster.DeployModeClassic {
+		return "", nil
+	}
+
+	return g5kAPI.ResolveEnvironment(site, c.cli.String("g5k-image"))
+}
+
+// releaseReservedJobs cancel the reserved jobs (site => job ID) using the given function
+func releaseReservedJobs(reservedJobs map[string]int, cancel func(site string, jobID int) error) error {
+	failed := []string{}
+	for site, jobID := range reservedJobs {
+		if err := cancel(site, jobID); err != nil {
+			log.Errorf("Unable to cancel the job '%d' on site '%s': '%s'", jobID, site, err)
+			failed = append(failed, fmt.Sprintf("%s/%d", site, jobID))
+		}
+	}
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("Unable to release the job(s): %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
 // RunCreateClusterCommand create a new cluster using cli flags
 func RunCreateClusterCommand(cli *cli.Context) error {
 	c := CreateClusterCommand{cli: cli}
@@ -1,10 +1,15 @@
 package command
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/cluster"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/volume"
 )
 
 // Test ParseReserveNodes flag
@@ -170,3 +175,338 @@ func TestParseEngineLabelFlagCorrectNodeNameMultipleOptMultipleValue(t *testing.
 		"site-2": []string{"key=val"},
 	}))
 }
+
+// Test ParseLocalVolume flag
+func TestParseLocalVolumeFlagEmpty(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseLocalVolumeFlag([]string{})
+	assert.NoError(t, err)
+}
+
+func TestParseLocalVolumeFlagIncorrectNodeName(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseLocalVolumeFlag([]string{"incorrect:data=/tmp/data"})
+	assert.Error(t, err)
+}
+
+func TestParseLocalVolumeFlagIncorrectPath(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseLocalVolumeFlag([]string{"site-1:data=tmp/data"})
+	assert.Error(t, err)
+}
+
+func TestParseLocalVolumeFlagCorrectPath(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseLocalVolumeFlag([]string{"site-1:data=/tmp/data"})
+	assert.NoError(t, err)
+	assert.True(t, reflect.DeepEqual(val, map[string][]volume.VolumeMount{
+		"site-1": []volume.VolumeMount{{Name: "data", Path: "/tmp/data"}},
+	}))
+}
+
+func TestParseLocalVolumeFlagCorrectDeviceAndPath(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseLocalVolumeFlag([]string{"site-1:data=/dev/sdb:/tmp/data", "site-2:data=/tmp/data"})
+	assert.NoError(t, err)
+	assert.True(t, reflect.DeepEqual(val, map[string][]volume.VolumeMount{
+		"site-1": []volume.VolumeMount{{Name: "data", Path: "/tmp/data", Device: "/dev/sdb"}},
+		"site-2": []volume.VolumeMount{{Name: "data", Path: "/tmp/data"}},
+	}))
+}
+
+// Test ParseWeaveConnector flag
+func TestParseWeaveConnectorFlagEmpty(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseWeaveConnectorFlag([]string{})
+	assert.NoError(t, err)
+	assert.Empty(t, val)
+}
+
+func TestParseWeaveConnectorFlagIncorrectNodeName(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseWeaveConnectorFlag([]string{"test-1", "incorrect"})
+	assert.Error(t, err)
+}
+
+func TestParseWeaveConnectorFlagCorrectMultipleNodeName(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseWeaveConnectorFlag([]string{"test-2", "test-1", "test-2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test-1", "test-2"}, val)
+}
+
+func TestParseAdvertisedResourcesFlagIncorrectResource(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseAdvertisedResourcesFlag([]string{"site-1:gpus=1"})
+	assert.Error(t, err)
+}
+
+func TestParseAdvertisedResourcesFlagCorrectMultipleValue(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseAdvertisedResourcesFlag([]string{"site-1:cpus=2", "site-2:cpus=2", "site-1:memory=1g"})
+	assert.NoError(t, err)
+	assert.True(t, reflect.DeepEqual(val, map[string]*cluster.AdvertisedResources{
+		"site-1": {NanoCPUs: 2000000000, MemoryBytes: 1 << 30},
+		"site-2": {NanoCPUs: 2000000000},
+	}))
+}
+
+func TestParseSharedMountFlagIncorrectValue(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseSharedMountFlag([]string{"site-1:/home/user=nfs"})
+	assert.Error(t, err)
+}
+
+func TestParseSharedMountFlagCorrectValue(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseSharedMountFlag([]string{"site-1:/home/user=nfs:/export/home/user", "site-1:/data=nfs:/export/data:data"})
+	assert.NoError(t, err)
+	assert.True(t, reflect.DeepEqual(val, map[string][]volume.SharedMount{
+		"site-1": {
+			{Server: "nfs", Export: "/export/home/user", Path: "/home/user"},
+			{Server: "nfs", Export: "/export/data", Path: "/data", VolumeName: "data"},
+		},
+	}))
+}
+
+func TestParsePhaseTimeoutFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parsePhaseTimeoutFlag([]string{"reserve"})
+	assert.Error(t, err)
+
+	_, err = c.parsePhaseTimeoutFlag([]string{"reserve=10"})
+	assert.Error(t, err)
+}
+
+func TestParsePhaseTimeoutFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parsePhaseTimeoutFlag([]string{"reserve=30m", "mapping=30s"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"reserve": 30 * time.Minute, "mapping": 30 * time.Second}, val)
+}
+
+func TestParseSystemdOverrideFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseSystemdOverrideFlag([]string{"Service.LimitNOFILE"})
+	assert.Error(t, err)
+}
+
+func TestParseSystemdOverrideFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseSystemdOverrideFlag([]string{"Service.LimitNOFILE=1048576", "Service.Environment=A=B"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"Service.LimitNOFILE": "1048576", "Service.Environment": "A=B"}, val)
+}
+
+func TestParseEngineDebugFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseEngineDebugFlag([]string{"lille"})
+	assert.Error(t, err)
+}
+
+func TestParseEngineDebugFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseEngineDebugFlag([]string{"lille-0", "nancy-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"lille-0": true, "nancy-1": true}, val)
+}
+
+func TestParseSysctlFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseSysctlFlag([]string{"net.core.somaxconn"})
+	assert.Error(t, err)
+
+	_, err = c.parseSysctlFlag([]string{"net.core.somaxconn="})
+	assert.Error(t, err)
+}
+
+func TestParseSysctlFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseSysctlFlag([]string{"net.core.somaxconn=4096", "net.ipv4.tcp_rmem=4096 87380 6291456"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"net.core.somaxconn": "4096", "net.ipv4.tcp_rmem": "4096 87380 6291456"}, val)
+}
+
+func TestParseAnnotationFlag(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseAnnotationFlag([]string{"experiment.id=exp-42", "params=nodes=4 rate=10"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"experiment.id": "exp-42", "params": "nodes=4 rate=10"}, val)
+
+	val, err = c.parseAnnotationFlag(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+
+	_, err = c.parseAnnotationFlag([]string{"experiment.id"})
+	assert.Error(t, err)
+}
+
+func TestParseOffloadFlag(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseOffloadFlag([]string{"gro=on", "tx-udp_tnl-segmentation=off"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"gro": true, "tx-udp_tnl-segmentation": false}, val)
+
+	_, err = c.parseOffloadFlag([]string{"gro=true"})
+	assert.Error(t, err)
+}
+
+func TestParseNodeSysctlFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseNodeSysctlFlag([]string{"net.core.somaxconn=4096"})
+	assert.Error(t, err)
+}
+
+func TestParseNodeSysctlFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseNodeSysctlFlag([]string{"lille-0:net.core.somaxconn=4096", "lille-1:net.core.somaxconn=4096", "lille-1:vm.swappiness=10"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"lille-0": {"net.core.somaxconn": "4096"},
+		"lille-1": {"net.core.somaxconn": "4096", "vm.swappiness": "10"},
+	}, val)
+}
+
+func TestParseNodeFirewallFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseNodeFirewallFlag([]string{"drop:tcp/2377"})
+	assert.Error(t, err)
+
+	_, err = c.parseNodeFirewallFlag([]string{"lille-0:block:tcp/2377"})
+	assert.Error(t, err)
+}
+
+func TestParseNodeFirewallFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseNodeFirewallFlag([]string{"lille-0:drop:tcp/2377@lille-1", "lille-0:reject@10.0.0.0/8"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]cluster.FirewallRule{
+		"lille-0": {{Action: "drop", Protocol: "tcp", Port: 2377, Source: "lille-1"}, {Action: "reject", Source: "10.0.0.0/8"}},
+	}, val)
+}
+
+func TestParseRuntimeFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseRuntimeFlag([]string{"crun"})
+	assert.Error(t, err)
+
+	_, err = c.parseRuntimeFlag([]string{"crun="})
+	assert.Error(t, err)
+}
+
+func TestParseRuntimeFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseRuntimeFlag([]string{"crun=/usr/bin/crun", "kata=/usr/bin/kata-runtime"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"crun": "/usr/bin/crun", "kata": "/usr/bin/kata-runtime"}, val)
+}
+
+func TestParseHardwareLabelRuleFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseHardwareLabelRuleFlag([]string{"gpu=true"})
+	assert.Error(t, err)
+
+	_, err = c.parseHardwareLabelRuleFlag([]string{":gpus>=1"})
+	assert.Error(t, err)
+}
+
+func TestParseHardwareLabelRuleFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseHardwareLabelRuleFlag([]string{"nic=25g:nic_rate_gbps>=25", "cpu=xeon:cpu_model~Xeon Gold"})
+	assert.NoError(t, err)
+	assert.Equal(t, []cluster.HardwareLabelRule{
+		{Label: "nic=25g", Condition: "nic_rate_gbps>=25"},
+		{Label: "cpu=xeon", Condition: "cpu_model~Xeon Gold"},
+	}, val)
+}
+
+func TestParseRegistryAuthFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseRegistryAuthFlag([]string{"user:s3cret"})
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cret")
+
+	_, err = c.parseRegistryAuthFlag([]string{"registry.example.com="})
+	assert.Error(t, err)
+}
+
+func TestParseRegistryAuthFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseRegistryAuthFlag([]string{"registry.example.com:5000=user:pass:word", "docker.io=token"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]cluster.RegistryAuth{
+		"registry.example.com:5000": {Username: "user", Password: "pass:word"},
+		"docker.io":                 {IdentityToken: "token"},
+	}, val)
+}
+
+func TestParseNodeAliasFlagIncorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	_, err := c.parseNodeAliasFlag([]string{"db0"})
+	assert.Error(t, err)
+
+	_, err = c.parseNodeAliasFlag([]string{"lille-0:db=0"})
+	assert.Error(t, err)
+}
+
+func TestParseNodeAliasFlagCorrectFormat(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseNodeAliasFlag([]string{"lille-0:db0", "lille-0:cache0", "lille-1:web0"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"lille-0": {"db0", "cache0"}, "lille-1": {"web0"}}, val)
+}
+
+func TestParseNodeDeployModeFlag(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseNodeDeployModeFlag([]string{"lille-0:classic", "lille-1:classic", "nancy-0:deploy"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lille-0": "classic", "lille-1": "classic", "nancy-0": "deploy"}, val)
+
+	_, err = c.parseNodeDeployModeFlag([]string{"classic"})
+	assert.Error(t, err)
+}
+
+func TestParseNodePlatformFlag(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseNodePlatformFlag([]string{"lille-0:linux/arm64", "lille-1:linux/arm/v7"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lille-0": "linux/arm64", "lille-1": "linux/arm/v7"}, val)
+
+	_, err = c.parseNodePlatformFlag([]string{"linux/arm64"})
+	assert.Error(t, err)
+}
+
+func TestReleaseReservedJobs(t *testing.T) {
+	cancelled := map[string]int{}
+	err := releaseReservedJobs(map[string]int{"lille": 42, "nancy": 7}, func(site string, jobID int) error {
+		cancelled[site] = jobID
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"lille": 42, "nancy": 7}, cancelled)
+}
+
+func TestReleaseReservedJobsFailure(t *testing.T) {
+	err := releaseReservedJobs(map[string]int{"lille": 42, "nancy": 7}, func(site string, jobID int) error {
+		if site == "nancy" {
+			return fmt.Errorf("job not found")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "Unable to release the job(s): nancy/7")
+}
+
+func TestParseCoreHoursQuotaFlag(t *testing.T) {
+	c := CreateClusterCommand{}
+	val, err := c.parseCoreHoursQuotaFlag([]string{"rennes:5000", "lille:12.5"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"rennes": 5000, "lille": 12.5}, val)
+}
+
+func TestParseCoreHoursQuotaFlagIncorrect(t *testing.T) {
+	c := CreateClusterCommand{}
+	for _, f := range []string{"rennes", "rennes:", "rennes:-1", "rennes:1.", "site1:10"} {
+		_, err := c.parseCoreHoursQuotaFlag([]string{f})
+		assert.Error(t, err, f)
+	}
+}
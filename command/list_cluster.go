@@ -7,9 +7,8 @@ import (
 
 	"strings"
 
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/cluster"
 	"github.com/codegangsta/cli"
-	"github.com/docker/machine/commands/mcndirs"
-	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/persist"
 )
 
@@ -20,6 +19,14 @@ var (
 		Aliases: []string{"ls-cluster", "ls", "l"},
 		Usage:   "List all clusters and their number of nodes",
 		Action:  RunListClusterCommand,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				EnvVar: "MACHINE_STORAGE_PATH",
+				Name:   "machine-storage-path",
+				Usage:  "Base directory of the Docker Machine storage of the cluster (certificates and machines), the Docker Machine default if empty",
+				Value:  "",
+			},
+		},
 	}
 )
 
@@ -31,7 +38,7 @@ type ListClusterCommand struct {
 // ListCluster list all clusters
 func (c *ListClusterCommand) ListCluster() error {
 	// create a new libmachine client
-	client := libmachine.NewClient(mcndirs.GetBaseDir(), mcndirs.GetMachineCertDir())
+	client := cluster.NewMachineStorageClient(c.cli.String("machine-storage-path"))
 	defer client.Close()
 
 	// load hosts from libmachine storage
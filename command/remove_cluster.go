@@ -8,9 +8,8 @@ import (
 	"strings"
 
 	"github.com/Songmu/prompter"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/cluster"
 	"github.com/codegangsta/cli"
-	"github.com/docker/machine/commands/mcndirs"
-	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/persist"
 )
@@ -28,6 +27,20 @@ var (
 				Name:   "no-confirm",
 				Usage:  "Disable confirmation before removing machines",
 			},
+
+			cli.DurationFlag{
+				EnvVar: "G5K_RM_GRACEFUL_CONTAINER_STOP",
+				Name:   "graceful-container-stop",
+				Usage:  "Grace period of the running containers stopped before removing machines (not stopped if not set)",
+				Value:  0,
+			},
+
+			cli.StringFlag{
+				EnvVar: "MACHINE_STORAGE_PATH",
+				Name:   "machine-storage-path",
+				Usage:  "Base directory of the Docker Machine storage of the cluster (certificates and machines), the Docker Machine default if empty",
+				Value:  "",
+			},
 		},
 	}
 )
@@ -49,7 +62,7 @@ func (c *RemoveClusterCommand) checkCliParameters() error {
 // RemoveCluster remove all nodes
 func (c *RemoveClusterCommand) RemoveCluster() error {
 	// create a new libmachine client
-	client := libmachine.NewClient(mcndirs.GetBaseDir(), mcndirs.GetMachineCertDir())
+	client := cluster.NewMachineStorageClient(c.cli.String("machine-storage-path"))
 	defer client.Close()
 
 	// store jobs ID to kill
@@ -102,6 +115,13 @@ func (c *RemoveClusterCommand) RemoveCluster() error {
 				continue
 			}
 
+			// stop the running containers before killing the job
+			if grace := c.cli.Duration("graceful-container-stop"); grace > 0 {
+				if _, err := cluster.StopHostContainers(h, grace); err != nil {
+					log.Warnf("The containers of node '%s' were not stopped: %s", h.Name, err)
+				}
+			}
+
 			// check the job is already in the list of deleted jobs
 			if _, exist := killedJobs[driverConfig.G5kJobID]; !exist {
 				// send API call to kill job
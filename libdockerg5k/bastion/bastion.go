@@ -0,0 +1,111 @@
+package bastion
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// Network is the attachable overlay network joined by the SOCKS proxy in Swarm mode (the Swarm mode services reachable through the bastion need to join it)
+	Network = "docker-g5k-bastion"
+
+	// DefaultPort is the default port of the SOCKS proxy published on the bastion node
+	DefaultPort = 1080
+
+	// default SOCKS5 proxy image
+	defaultImage = "serjs/go-socks5-proxy"
+
+	// ReachableTimeout is the time to wait for the SOCKS proxy to accept connections once started
+	ReachableTimeout = 30 * time.Second
+
+	// interval between two connection attempts to the SOCKS proxy
+	reachableRetryInterval = 1 * time.Second
+
+	// enableForwardingCommand allow the TCP forwarding of the SSH server (ex: 'ssh -D' or 'ssh -J' through the bastion node)
+	enableForwardingCommand = "sed -i -E 's/^#?AllowTcpForwarding.*/AllowTcpForwarding yes/' /etc/ssh/sshd_config && " +
+		"(grep -q '^AllowTcpForwarding yes' /etc/ssh/sshd_config || echo 'AllowTcpForwarding yes' >> /etc/ssh/sshd_config) && " +
+		"(systemctl reload ssh || service ssh reload)"
+)
+
+// ValidatePort check the port of the SOCKS proxy (default if zero)
+func ValidatePort(port int) error {
+	if port < 0 || port > 65535 {
+		return fmt.Errorf("Invalid bastion port: %d (must be between 1 and 65535)", port)
+	}
+
+	return nil
+}
+
+// proxyPort returns the port of the SOCKS proxy published on the bastion node
+func proxyPort(port int) int {
+	if port == 0 {
+		return DefaultPort
+	}
+
+	return port
+}
+
+// Endpoint returns the address (host:port) of the SOCKS proxy on the given host
+func Endpoint(hostname string, port int) string {
+	return net.JoinHostPort(hostname, strconv.Itoa(proxyPort(port)))
+}
+
+// generateNetworkCommand returns the command used to create the attachable overlay network of the bastion (if it does not exist)
+func generateNetworkCommand() string {
+	return fmt.Sprintf("docker network inspect %[1]s >/dev/null 2>&1 || docker network create --driver overlay --attachable %[1]s", Network)
+}
+
+// generateRunCommand returns the command used to run the SOCKS proxy on the given container network (the default bridge if empty) with the published port, restart policy, added capabilities, stop signal/timeout and image platform
+func generateRunCommand(network string, port int, restartPolicy string, caps []string, stop container.StopConfig, platform string) string {
+	if network != "" {
+		network = fmt.Sprintf("--network %s ", network)
+	}
+
+	return fmt.Sprintf("docker run -d %s --name docker-g5k-bastion %s %s-p %d:1080 -e REQUIRE_AUTH=false %s", container.RestartFlag(restartPolicy)+container.CapAddFlags(caps)+stop.Flags()+container.PlatformFlag(platform), container.LabelFlag, network, proxyPort(port), defaultImage)
+}
+
+// StartBastion allow the SSH forwarding on the given host and start the SOCKS proxy container with the added capabilities, stop signal/timeout and image platform
+// the proxy joins the bastion overlay network in Swarm mode (needs to be a Swarm manager), the Weave network if enabled, the default bridge otherwise (the default restart policy is used if empty)
+func StartBastion(h *host.Host, port int, swarmMode bool, weaveNetworking bool, restartPolicy string, caps []string, stop container.StopConfig, platform string) error {
+	if _, err := h.RunSSHCommand(enableForwardingCommand); err != nil {
+		return fmt.Errorf("Bastion SSH forwarding configuration failed: '%s'", err)
+	}
+
+	network := ""
+	if swarmMode {
+		if _, err := h.RunSSHCommand(generateNetworkCommand()); err != nil {
+			return fmt.Errorf("Bastion network creation failed: '%s'", err)
+		}
+		network = Network
+	} else if weaveNetworking {
+		network = "weave"
+	}
+
+	if _, err := h.RunSSHCommand(generateRunCommand(network, port, restartPolicy, caps, stop, platform)); err != nil {
+		return fmt.Errorf("Bastion run command failed: '%s'", err)
+	}
+
+	return nil
+}
+
+// WaitReachable wait until the SOCKS proxy accept connections on the given endpoint, or returns an error after the timeout
+func WaitReachable(endpoint string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", endpoint, reachableRetryInterval)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		if time.Now().Add(reachableRetryInterval).After(deadline) {
+			return fmt.Errorf("The bastion endpoint '%s' is not reachable: '%s'", endpoint, err)
+		}
+		time.Sleep(reachableRetryInterval)
+	}
+}
@@ -0,0 +1,39 @@
+package bastion
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePort(t *testing.T) {
+	assert.NoError(t, ValidatePort(0))
+	assert.NoError(t, ValidatePort(1080))
+	assert.Error(t, ValidatePort(-1))
+	assert.Error(t, ValidatePort(70000))
+}
+
+func TestEndpoint(t *testing.T) {
+	assert.Equal(t, "chetemi-1.lille.grid5000.fr:1080", Endpoint("chetemi-1.lille.grid5000.fr", 0))
+	assert.Equal(t, "chetemi-1.lille.grid5000.fr:9050", Endpoint("chetemi-1.lille.grid5000.fr", 9050))
+}
+
+func TestGenerateRunCommand(t *testing.T) {
+	assert.Equal(t, "docker run -d --restart=always --name docker-g5k-bastion --label managed-by=docker-g5k -p 1080:1080 -e REQUIRE_AUTH=false serjs/go-socks5-proxy", generateRunCommand("", 0, "", nil, container.StopConfig{}, ""))
+	assert.Equal(t, "docker run -d --restart=unless-stopped --stop-timeout=10 --name docker-g5k-bastion --label managed-by=docker-g5k --network docker-g5k-bastion -p 9050:1080 -e REQUIRE_AUTH=false serjs/go-socks5-proxy", generateRunCommand(Network, 9050, "unless-stopped", nil, container.StopConfig{Timeout: 10 * time.Second}, ""))
+}
+
+func TestWaitReachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	endpoint := l.Addr().String()
+
+	assert.NoError(t, WaitReachable(endpoint, time.Second))
+
+	// no retry is possible within the timeout
+	l.Close()
+	assert.Error(t, WaitReachable(endpoint, 0))
+}
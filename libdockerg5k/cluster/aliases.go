@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/ingress"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/registry"
+)
+
+const (
+	// aliasesLabel is the Engine label listing the aliases of a node
+	aliasesLabel = "g5k.aliases"
+)
+
+var (
+	// regexHostAlias match a valid hostname alias
+	regexHostAlias = regexp.MustCompile("^[[:alnum:]]([[:alnum:]-]*[[:alnum:]])?$")
+)
+
+// validateNodesAliases check the aliases of the nodes are valid hostnames and unique in the cluster (and not used as machine name)
+func (c *Cluster) validateNodesAliases() error {
+	owners := make(map[string]string)
+	for _, n := range c.Nodes {
+		for _, alias := range n.Aliases {
+			if !regexHostAlias.MatchString(alias) {
+				return fmt.Errorf("Invalid alias '%s' of node '%s' (only letters, digits and '-' are allowed)", alias, n.MachineName)
+			}
+
+			if _, ok := c.Nodes[alias]; ok || alias == registry.Hostname || alias == ingress.Hostname {
+				return fmt.Errorf("The alias '%s' of node '%s' is already the name of a cluster host", alias, n.MachineName)
+			}
+
+			if owner, ok := owners[alias]; ok {
+				return fmt.Errorf("The alias '%s' is used by both nodes '%s' and '%s'", alias, owner, n.MachineName)
+			}
+			owners[alias] = n.MachineName
+		}
+	}
+
+	return nil
+}
+
+// configureHostsAliases check and set the aliases of the nodes in the static lookup table
+func (c *Cluster) configureHostsAliases() error {
+	if err := c.validateNodesAliases(); err != nil {
+		return err
+	}
+
+	c.Config.hostsAliases = make(map[string][]string)
+	for machineName, n := range c.Nodes {
+		if len(n.Aliases) > 0 {
+			c.Config.hostsAliases[machineName] = n.Aliases
+		}
+	}
+
+	return nil
+}
+
+// aliasesLabels returns the Engine label listing the aliases of the node (if enabled)
+func (n *Node) aliasesLabels() []string {
+	if !n.clusterConfig.AliasesEngineLabel || len(n.Aliases) == 0 {
+		return []string{}
+	}
+
+	return []string{fmt.Sprintf("%s=%s", aliasesLabel, strings.Join(n.Aliases, ","))}
+}
@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNodesAliases(t *testing.T) {
+	c := &Cluster{Nodes: map[string]*Node{
+		"lille-0": {MachineName: "lille-0", Aliases: []string{"db0", "cache0"}},
+		"lille-1": {MachineName: "lille-1", Aliases: []string{"web0"}},
+	}}
+	assert.NoError(t, c.validateNodesAliases())
+
+	c.Nodes["lille-1"].Aliases = []string{"db0"}
+	assert.Error(t, c.validateNodesAliases())
+
+	c.Nodes["lille-1"].Aliases = []string{"lille-0"}
+	assert.Error(t, c.validateNodesAliases())
+
+	c.Nodes["lille-1"].Aliases = []string{"web_0"}
+	assert.Error(t, c.validateNodesAliases())
+}
+
+func TestConfigureHostsAliases(t *testing.T) {
+	c := &Cluster{Config: &GlobalConfig{}, Nodes: map[string]*Node{
+		"lille-0": {MachineName: "lille-0", Aliases: []string{"db0"}},
+		"lille-1": {MachineName: "lille-1"},
+	}}
+	assert.NoError(t, c.configureHostsAliases())
+	assert.Equal(t, map[string][]string{"lille-0": {"db0"}}, c.Config.hostsAliases)
+}
+
+func TestEngineLabelsAliases(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{DisableG5kLabels: true}, Aliases: []string{"db0", "cache0"}}
+	assert.Empty(t, n.engineLabels())
+
+	n.clusterConfig.AliasesEngineLabel = true
+	assert.Equal(t, []string{"g5k.aliases=db0,cache0"}, n.engineLabels())
+}
@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// annotationLabelPrefix is the prefix of the Engine labels storing the annotations of the cluster
+const annotationLabelPrefix = "g5k.annotation."
+
+var (
+	// regexAnnotationKey match an annotation key (Docker label key syntax: lowercase letters, digits, '.' and '-', ex: experiment.id, git-commit)
+	regexAnnotationKey = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+)
+
+// validateAnnotations check the annotations keys and values
+func validateAnnotations(annotations map[string]string) error {
+	for key, value := range annotations {
+		if !regexAnnotationKey.MatchString(key) || strings.Contains(key, "..") {
+			return fmt.Errorf("Invalid annotation key: '%s' (lowercase letters, digits, '.' and '-' are allowed, ex: experiment.id)", key)
+		}
+
+		if strings.ContainsAny(value, "\n\r") {
+			return fmt.Errorf("The value of the annotation '%s' must be on a single line", key)
+		}
+	}
+
+	return nil
+}
+
+// annotationLabels returns the Engine labels of the annotations (sorted by key)
+func annotationLabels(annotations map[string]string) []string {
+	labels := []string{}
+	for key, value := range annotations {
+		labels = append(labels, fmt.Sprintf("%s%s=%s", annotationLabelPrefix, key, value))
+	}
+	sort.Strings(labels)
+
+	return labels
+}
+
+// parseAnnotationLabels returns the annotations stored in the Engine labels (nil if none)
+func parseAnnotationLabels(labels []string) map[string]string {
+	var annotations map[string]string
+	for _, l := range labels {
+		// label format: g5k.annotation.{key}={value}
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], annotationLabelPrefix) {
+			continue
+		}
+
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[strings.TrimPrefix(kv[0], annotationLabelPrefix)] = kv[1]
+	}
+
+	return annotations
+}
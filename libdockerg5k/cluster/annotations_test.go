@@ -0,0 +1,40 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	assert.NoError(t, validateAnnotations(nil))
+	assert.NoError(t, validateAnnotations(map[string]string{"experiment.id": "exp-42", "git-commit": "3f2a1c9", "params": ""}))
+
+	for _, key := range []string{"", "Experiment", "experiment_id", ".id", "id.", "experiment..id", "git commit"} {
+		assert.Error(t, validateAnnotations(map[string]string{key: "v"}), key)
+	}
+	assert.Error(t, validateAnnotations(map[string]string{"params": "a\nb"}))
+}
+
+func TestAnnotationLabels(t *testing.T) {
+	labels := annotationLabels(map[string]string{"git.commit": "3f2a1c9", "experiment.id": "exp-42"})
+	assert.Equal(t, []string{"g5k.annotation.experiment.id=exp-42", "g5k.annotation.git.commit=3f2a1c9"}, labels)
+
+	assert.Equal(t, map[string]string{"experiment.id": "exp-42", "git.commit": "3f2a1c9"}, parseAnnotationLabels(append(labels, "g5k.site=lille")))
+	assert.Nil(t, parseAnnotationLabels([]string{"g5k.site=lille"}))
+}
+
+func TestEngineLabelsAnnotations(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager})
+	c.Config.Annotations = map[string]string{"experiment.id": "exp-42"}
+	c.Config.DisableG5kLabels = true
+	n := c.Nodes["lille-0"]
+
+	assert.Contains(t, n.engineLabels(), "g5k.annotation.experiment.id=exp-42")
+
+	// a user label with the same key takes precedence
+	n.EngineLabel = []string{"g5k.annotation.experiment.id=exp-43"}
+	assert.Equal(t, []string{"g5k.annotation.experiment.id=exp-43"}, n.engineLabels())
+
+	assert.Equal(t, map[string]string{"experiment.id": "exp-42"}, c.Inventory().Annotations)
+}
@@ -0,0 +1,180 @@
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// Ansible inventory formats
+	AnsibleFormatINI  = "ini"
+	AnsibleFormatYAML = "yaml"
+
+	// Ansible groups of the nodes
+	ansibleGroupManagers = "managers"
+	ansibleGroupWorkers  = "workers"
+)
+
+// AnsibleInventoryOptions contain the options of the generated Ansible inventory
+type AnsibleInventoryOptions struct {
+	Format  string // ini (default) or yaml
+	Bastion string // SSH bastion used to reach the nodes (ex: access.grid5000.fr), nodes are reached directly if empty
+}
+
+// ansibleHost contain a node and its variables in the Ansible inventory
+type ansibleHost struct {
+	name string
+	vars map[string]string
+}
+
+// ansibleHostVars returns the Ansible variables of the node inventory entry
+func (c *Cluster) ansibleHostVars(ni *NodeInventory) map[string]string {
+	vars := map[string]string{
+		"ansible_host":                 ni.NodeName,
+		"ansible_user":                 "root",
+		"ansible_ssh_private_key_file": filepath.Join(c.Config.machineDir(ni.MachineName), "id_rsa"),
+		"g5k_site":                     ni.G5kSite,
+		"g5k_job_id":                   fmt.Sprintf("%d", ni.G5kJobID),
+		"g5k_node":                     ni.NodeName,
+	}
+
+	// use the node IP address if known
+	if ip, ok := c.Config.HostsLookupTable[ni.MachineName]; ok {
+		vars["ansible_host"] = ip
+	}
+
+	return vars
+}
+
+// ansibleGroups returns the nodes of the inventory by Ansible group (managers/workers), sorted by machine name
+func (c *Cluster) ansibleGroups(inv *Inventory) map[string][]ansibleHost {
+	groups := map[string][]ansibleHost{ansibleGroupManagers: {}, ansibleGroupWorkers: {}}
+
+	machineNames := []string{}
+	for machineName := range inv.Nodes {
+		machineNames = append(machineNames, machineName)
+	}
+	sort.Strings(machineNames)
+
+	for _, machineName := range machineNames {
+		ni := inv.Nodes[machineName]
+
+		group := ansibleGroupWorkers
+		if ni.SwarmMaster {
+			group = ansibleGroupManagers
+		}
+
+		groups[group] = append(groups[group], ansibleHost{machineName, c.ansibleHostVars(ni)})
+	}
+
+	return groups
+}
+
+// ansibleGlobalVars returns the Ansible variables of all nodes
+func ansibleGlobalVars(opts AnsibleInventoryOptions, username string) map[string]string {
+	vars := map[string]string{}
+	if opts.Bastion != "" {
+		vars["ansible_ssh_common_args"] = fmt.Sprintf("-o ProxyJump=%s@%s -o StrictHostKeyChecking=no", username, opts.Bastion)
+	}
+
+	return vars
+}
+
+// sortedKeys returns the keys of the map, sorted
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// renderAnsibleINI returns the inventory in the Ansible INI format
+func renderAnsibleINI(groups map[string][]ansibleHost, globalVars map[string]string) string {
+	var b strings.Builder
+
+	for _, group := range []string{ansibleGroupManagers, ansibleGroupWorkers} {
+		fmt.Fprintf(&b, "[%s]\n", group)
+		for _, h := range groups[group] {
+			b.WriteString(h.name)
+			for _, k := range sortedKeys(h.vars) {
+				fmt.Fprintf(&b, " %s=%s", k, h.vars[k])
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if len(globalVars) > 0 {
+		b.WriteString("[all:vars]\n")
+		for _, k := range sortedKeys(globalVars) {
+			fmt.Fprintf(&b, "%s='%s'\n", k, globalVars[k])
+		}
+	}
+
+	return b.String()
+}
+
+// renderAnsibleYAML returns the inventory in the Ansible YAML format
+func renderAnsibleYAML(groups map[string][]ansibleHost, globalVars map[string]string) string {
+	var b strings.Builder
+
+	b.WriteString("all:\n")
+	if len(globalVars) > 0 {
+		b.WriteString("  vars:\n")
+		for _, k := range sortedKeys(globalVars) {
+			fmt.Fprintf(&b, "    %s: %q\n", k, globalVars[k])
+		}
+	}
+
+	b.WriteString("  children:\n")
+	for _, group := range []string{ansibleGroupManagers, ansibleGroupWorkers} {
+		fmt.Fprintf(&b, "    %s:\n", group)
+		if len(groups[group]) == 0 {
+			b.WriteString("      hosts: {}\n")
+			continue
+		}
+
+		b.WriteString("      hosts:\n")
+		for _, h := range groups[group] {
+			fmt.Fprintf(&b, "        %s:\n", h.name)
+			for _, k := range sortedKeys(h.vars) {
+				fmt.Fprintf(&b, "          %s: %q\n", k, h.vars[k])
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// WriteAnsibleInventory write the cluster inventory as an Ansible inventory (managers and workers groups) in the given file
+func (c *Cluster) WriteAnsibleInventory(path string, opts AnsibleInventoryOptions) error {
+	groups := c.ansibleGroups(c.Inventory())
+	user, _, err := c.Config.credentials()
+	if err != nil {
+		return err
+	}
+
+	globalVars := ansibleGlobalVars(opts, user)
+
+	var content string
+	switch opts.Format {
+	case AnsibleFormatINI, "":
+		content = renderAnsibleINI(groups, globalVars)
+	case AnsibleFormatYAML:
+		content = renderAnsibleYAML(groups, globalVars)
+	default:
+		return fmt.Errorf("Unsupported Ansible inventory format: '%s' (supported: '%s', '%s')", opts.Format, AnsibleFormatINI, AnsibleFormatYAML)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("Unable to write the Ansible inventory '%s': '%s'", path, err)
+	}
+
+	return nil
+}
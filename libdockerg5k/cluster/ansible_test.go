@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAnsibleGroups() map[string][]ansibleHost {
+	return map[string][]ansibleHost{
+		ansibleGroupManagers: {{"lille-0", map[string]string{"ansible_host": "172.16.0.1", "g5k_site": "lille"}}},
+		ansibleGroupWorkers:  {{"lille-1", map[string]string{"ansible_host": "172.16.0.2", "g5k_site": "lille"}}},
+	}
+}
+
+func TestAnsibleGroups(t *testing.T) {
+	c := NewCluster(&GlobalConfig{HostsLookupTable: map[string]string{"lille-0": "172.16.0.1"}})
+	inv := &Inventory{Nodes: map[string]*NodeInventory{
+		"lille-1": {MachineName: "lille-1", NodeName: "chifflet-2.lille.grid5000.fr", G5kSite: "lille", G5kJobID: 42},
+		"lille-0": {MachineName: "lille-0", NodeName: "chifflet-1.lille.grid5000.fr", G5kSite: "lille", G5kJobID: 42, SwarmMaster: true},
+	}}
+
+	groups := c.ansibleGroups(inv)
+	assert.Len(t, groups[ansibleGroupManagers], 1)
+	assert.Equal(t, "lille-0", groups[ansibleGroupManagers][0].name)
+	assert.Equal(t, "172.16.0.1", groups[ansibleGroupManagers][0].vars["ansible_host"])
+	assert.Equal(t, "42", groups[ansibleGroupManagers][0].vars["g5k_job_id"])
+
+	assert.Len(t, groups[ansibleGroupWorkers], 1)
+	assert.Equal(t, "chifflet-2.lille.grid5000.fr", groups[ansibleGroupWorkers][0].vars["ansible_host"])
+}
+
+func TestRenderAnsibleINI(t *testing.T) {
+	expected := "[managers]\nlille-0 ansible_host=172.16.0.1 g5k_site=lille\n\n" +
+		"[workers]\nlille-1 ansible_host=172.16.0.2 g5k_site=lille\n\n" +
+		"[all:vars]\nansible_ssh_common_args='-o ProxyJump=jdoe@access.grid5000.fr'\n"
+
+	assert.Equal(t, expected, renderAnsibleINI(newAnsibleGroups(), map[string]string{"ansible_ssh_common_args": "-o ProxyJump=jdoe@access.grid5000.fr"}))
+}
+
+func TestRenderAnsibleYAML(t *testing.T) {
+	expected := "all:\n  children:\n" +
+		"    managers:\n      hosts:\n        lille-0:\n          ansible_host: \"172.16.0.1\"\n          g5k_site: \"lille\"\n" +
+		"    workers:\n      hosts: {}\n"
+
+	groups := newAnsibleGroups()
+	groups[ansibleGroupWorkers] = []ansibleHost{}
+	assert.Equal(t, expected, renderAnsibleYAML(groups, map[string]string{}))
+}
@@ -0,0 +1,271 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// defaultDeprovisionTimeout is the default maximum time of the graceful teardown of the nodes when the cluster is deprovisioned
+	defaultDeprovisionTimeout = 10 * time.Minute
+
+	// cancelJobTimeout is the maximum time of the cancellation of a Grid5000 job when the cluster is deprovisioned
+	cancelJobTimeout = 2 * time.Minute
+)
+
+// releaseJobs cancel the Grid5000 jobs of the cluster nodes using the given function (each cancellation within the given timeout) and returns the errors by machine name
+// the jobs not reserved by docker-g5k (imported) are kept running, the jobs whose cancellation timed out are logged (they may still be running)
+func (c *Cluster) releaseJobs(cancel func(site string, jobID int) error, timeout time.Duration) map[string]error {
+	errs := make(map[string]error)
+	timedOut := []string{}
+	for k, machineNames := range c.nodesByJob() {
+		if !c.ownsJob(machineNames) {
+			log.Infof("The job '%d' on site '%s' was not reserved by docker-g5k, it is kept running", k.jobID, k.site)
+			continue
+		}
+
+		site, jobID := k.site, k.jobID
+		if err := WithTimeout(timeout, func() error { return cancel(site, jobID) }); err != nil {
+			if errors.Is(err, ErrTimeout) {
+				timedOut = append(timedOut, fmt.Sprintf("%s/%d", site, jobID))
+			}
+			for _, machineName := range machineNames {
+				errs[machineName] = fmt.Errorf("Unable to cancel the job '%d' on site '%s': '%s'", jobID, site, err)
+			}
+		}
+	}
+
+	if len(timedOut) > 0 {
+		sort.Strings(timedOut)
+		log.Warnf("The cancellation of %d job(s) timed out, they may still be running: %s", len(timedOut), strings.Join(timedOut, ", "))
+	}
+
+	return errs
+}
+
+// deprovisionTimeout returns the maximum time of the graceful teardown of the nodes when the cluster is deprovisioned
+func (c *GlobalConfig) deprovisionTimeout() time.Duration {
+	if c.DeprovisionTimeout == 0 {
+		return defaultDeprovisionTimeout
+	}
+
+	return c.DeprovisionTimeout
+}
+
+// teardownNodes run the graceful teardown function on the nodes (in parallel, within the deprovision timeout), then remove the machines of all the nodes using the given function
+// it returns the errors of the removals by machine name, and the machine names (sorted) of the nodes whose teardown failed or timed out. The nodes still tearing down after the timeout are not waited for
+func (c *Cluster) teardownNodes(machineNames []string, teardown func(n *Node, h *host.Host) error, remove func(machineName string) error) (map[string]error, []string) {
+	errs := make(map[string]error)
+	forced := []string{}
+	if len(machineNames) == 0 {
+		return errs, forced
+	}
+
+	if teardown != nil {
+		for machineName, err := range c.runOnSelectedNodes(&NodeSelector{Names: machineNames}, c.Config.deprovisionTimeout(), teardown) {
+			log.Warnf("The graceful teardown of node '%s' failed, its machine is removed anyway: %s", machineName, err)
+			forced = append(forced, machineName)
+		}
+		sort.Strings(forced)
+	}
+
+	for _, machineName := range machineNames {
+		if err := remove(machineName); err != nil {
+			errs[machineName] = err
+		}
+	}
+
+	return errs, forced
+}
+
+// removeMachineStorage delete the directory of the machine from the storage
+// like the Docker Machine client Remove, the driver is not called: the node is released with its Grid5000 job
+func (c *GlobalConfig) removeMachineStorage(machineName string) error {
+	if err := os.RemoveAll(c.machineDir(machineName)); err != nil {
+		return fmt.Errorf("Unable to remove the machine: '%s'", err)
+	}
+
+	return nil
+}
+
+// removeMachines stop the containers of the cluster nodes (in parallel and within the deprovision timeout, see GracefulContainerStop) and remove their Docker Machines from the storage (if they exist)
+// it returns the errors by machine name and the machine names of the nodes whose containers were not stopped
+func (c *Cluster) removeMachines() (map[string]error, []string) {
+	machineNames := []string{}
+	for machineName := range c.Nodes {
+		if exists, err := c.Config.LibMachineClient.Exists(machineName); err == nil && exists {
+			machineNames = append(machineNames, machineName)
+		}
+	}
+
+	// the machines are only removed from the storage if the containers are not stopped
+	var teardown func(n *Node, h *host.Host) error
+	if c.Config.GracefulContainerStop > 0 {
+		teardown = func(n *Node, h *host.Host) error {
+			log.Infof("Stopping the containers of node '%s' ('%s')...", n.NodeName, n.MachineName)
+			_, err := StopHostContainers(h, n.clusterConfig.GracefulContainerStop)
+			return err
+		}
+	}
+
+	return c.teardownNodes(machineNames, teardown, c.Config.removeMachineStorage)
+}
+
+// DeprovisionAll stop the containers of the cluster nodes (in parallel, within the deprovision timeout) and remove their Docker Machines, then cancel the Grid5000 jobs reserved by docker-g5k
+// the machines are removed even if their containers were not stopped in time, and each job cancellation is bounded, so the resources are reclaimed in a bounded time. The machine names (sorted) of the nodes not torn down gracefully are returned
+func (c *Cluster) DeprovisionAll() ([]string, error) {
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	errs, forced := c.removeMachines()
+	for machineName, err := range c.releaseJobs(g5kAPI.CancelJob, cancelJobTimeout) {
+		errs[machineName] = err
+	}
+
+	if len(forced) > 0 {
+		log.Warnf("%d node(s) not torn down gracefully: %s", len(forced), strings.Join(forced, ", "))
+	}
+
+	return forced, fleetError("Release", errs)
+}
+
+// ReleaseNodes cancel the Grid5000 jobs of the cluster nodes reserved by docker-g5k and remove their Docker Machines (see DeprovisionAll)
+func (c *Cluster) ReleaseNodes() error {
+	_, err := c.DeprovisionAll()
+	return err
+}
+
+// ProvisionAtomic provision all nodes of the cluster (already reserved and deployed) with all-or-nothing semantics:
+// if any node fails to provision (or the provisioning deadline is exceeded), all the jobs of the cluster are released and the machines removed (unlike ProvisionNodes which keeps the provisioned nodes)
+func (c *Cluster) ProvisionAtomic() error {
+	err := c.provisionWithDeadline(true)
+	if err == nil {
+		return nil
+	}
+
+	// the nodes are already released when the deadline is exceeded
+	if errors.Is(err, ErrDeadline) {
+		return err
+	}
+
+	log.Warn("The cluster provisioning failed, releasing all nodes...")
+	if releaseErr := c.ReleaseNodes(); releaseErr != nil {
+		return fmt.Errorf("%s (%s)", err, releaseErr)
+	}
+
+	return err
+}
+
+// reserveThenDeploy reserve the jobs of all the sites (number of nodes by site) before deploying any of them, and returns the job ID and the deployed nodes hostname by site
+// all the reserved jobs are canceled if any reservation or deployment fails, so the cluster is either entirely reserved and deployed or not at all
+func reserveThenDeploy(reservations map[string]int, reserve func(site string, nb int) (int, error), deploy func(site string, jobID int, nb int) ([]string, error), cancel func(site string, jobID int) error) (map[string]int, map[string][]string, error) {
+	// sort sites by name
+	sites := []string{}
+	for site := range reservations {
+		sites = append(sites, site)
+	}
+	sort.Strings(sites)
+
+	jobs := make(map[string]int)
+	release := func(err error) (map[string]int, map[string][]string, error) {
+		for site, jobID := range jobs {
+			if cancelErr := cancel(site, jobID); cancelErr != nil {
+				log.Errorf("Unable to cancel the job '%d' on site '%s': '%s'", jobID, site, cancelErr)
+			}
+		}
+		return nil, nil, err
+	}
+
+	// reserve all the sites first
+	for _, site := range sites {
+		jobID, err := reserve(site, reservations[site])
+		if err != nil {
+			return release(err)
+		}
+		jobs[site] = jobID
+	}
+
+	// deploy the nodes once all the reservations succeeded
+	deployed := make(map[string][]string)
+	for _, site := range sites {
+		hostnames, err := deploy(site, jobs[site], reservations[site])
+		if err != nil {
+			return release(err)
+		}
+		deployed[site] = hostnames
+	}
+
+	return jobs, deployed, nil
+}
+
+// ReserveAndProvisionAtomic reserve the given number of nodes by site (one job per site) and provision them with all-or-nothing semantics: all the sites are reserved before
+// deploying any node, and the jobs are released if any reservation or deployment fails. The nodes (machine name format: {site}-{id}, roles from the Swarm master nodes
+// of the cluster configuration) are then added to the cluster and provisioned by ProvisionAtomic
+func (c *Cluster) ReserveAndProvisionAtomic(reservations map[string]int) error {
+	for site, nb := range reservations {
+		if nb <= 0 {
+			return fmt.Errorf("Invalid number of nodes to reserve on site '%s': %d", site, nb)
+		}
+	}
+
+	if err := validateDeployMode(c.Config.DeployMode); err != nil {
+		return err
+	}
+
+	if _, err := c.Config.CheckMaxCoreHours(reservations); err != nil {
+		return err
+	}
+
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		return err
+	}
+
+	mode := (&Node{clusterConfig: c.Config}).deployMode()
+	images := make(map[string]string)
+	jobs, deployed, err := reserveThenDeploy(reservations, func(site string, nb int) (int, error) {
+		jobID, image, err := c.Config.reserveJob(g5kAPI, site, mode, nb)
+		images[site] = image
+		return jobID, err
+	}, func(site string, jobID int, nb int) ([]string, error) {
+		return c.Config.deployJob(g5kAPI, site, mode, jobID, images[site], nb)
+	}, g5kAPI.CancelJob)
+	if err != nil {
+		return err
+	}
+
+	nodes := []*Node{}
+	for site, hostnames := range deployed {
+		for _, n := range c.Config.newJobNodes(site, jobs[site], hostnames) {
+			n.DeployMode = mode
+			n.OwnsJob = true
+			nodes = append(nodes, n)
+		}
+	}
+
+	// the jobs are released if the nodes can't be resolved
+	if err := c.Config.lookupNodesIP(nodes); err != nil {
+		log.Warn("The cluster reservation failed, releasing the reserved jobs...")
+		for site, jobID := range jobs {
+			if cancelErr := g5kAPI.CancelJob(site, jobID); cancelErr != nil {
+				log.Errorf("Unable to cancel the job '%d' on site '%s': '%s'", jobID, site, cancelErr)
+			}
+		}
+		return err
+	}
+
+	for _, n := range nodes {
+		c.Nodes[n.MachineName] = n
+	}
+
+	return c.ProvisionAtomic()
+}
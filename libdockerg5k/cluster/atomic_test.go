@@ -0,0 +1,217 @@
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseJobs(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.Nodes["lille-0"] = &Node{MachineName: "lille-0", G5kSite: "lille", G5kJobID: 42, OwnsJob: true}
+	c.Nodes["lille-1"] = &Node{MachineName: "lille-1", G5kSite: "lille", G5kJobID: 42, OwnsJob: true}
+	c.Nodes["nancy-0"] = &Node{MachineName: "nancy-0", G5kSite: "nancy", G5kJobID: 7, OwnsJob: true}
+	c.Nodes["nancy-1"] = &Node{MachineName: "nancy-1", G5kSite: "nancy"}
+
+	cancelled := []string{}
+	errs := c.releaseJobs(func(site string, jobID int) error {
+		cancelled = append(cancelled, fmt.Sprintf("%s/%d", site, jobID))
+		if site == "nancy" {
+			return fmt.Errorf("job not found")
+		}
+		return nil
+	}, time.Second)
+
+	assert.ElementsMatch(t, []string{"lille/42", "nancy/7"}, cancelled)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs, "nancy-0")
+}
+
+func TestReleaseJobsImported(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.Nodes["lille-0"] = &Node{MachineName: "lille-0", G5kSite: "lille", G5kJobID: 42, OwnsJob: true}
+	c.Nodes["lille-1"] = &Node{MachineName: "lille-1", G5kSite: "lille", G5kJobID: 42}
+	c.Nodes["nancy-0"] = &Node{MachineName: "nancy-0", G5kSite: "nancy", G5kJobID: 7}
+	c.Nodes["rennes-0"] = &Node{MachineName: "rennes-0", G5kSite: "rennes", G5kJobID: 3, OwnsJob: true}
+
+	// the jobs imported by a node are kept running
+	cancelled := []string{}
+	errs := c.releaseJobs(func(site string, jobID int) error {
+		cancelled = append(cancelled, fmt.Sprintf("%s/%d", site, jobID))
+		return nil
+	}, time.Second)
+
+	assert.Equal(t, []string{"rennes/3"}, cancelled)
+	assert.Empty(t, errs)
+}
+
+func TestReleaseJobsTimeout(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.Nodes["lille-0"] = &Node{MachineName: "lille-0", G5kSite: "lille", G5kJobID: 42, OwnsJob: true}
+	c.Nodes["nancy-0"] = &Node{MachineName: "nancy-0", G5kSite: "nancy", G5kJobID: 7, OwnsJob: true}
+
+	// a stalled cancellation doesn't block the release of the other jobs
+	errs := c.releaseJobs(func(site string, jobID int) error {
+		if site == "lille" {
+			time.Sleep(time.Second)
+		}
+		return nil
+	}, 50*time.Millisecond)
+
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs["lille-0"].Error(), "timeout")
+}
+
+func TestDeprovisionTimeout(t *testing.T) {
+	assert.Equal(t, defaultDeprovisionTimeout, (&GlobalConfig{}).deprovisionTimeout())
+	assert.Equal(t, time.Minute, (&GlobalConfig{DeprovisionTimeout: time.Minute}).deprovisionTimeout())
+}
+
+func TestTeardownNodes(t *testing.T) {
+	c := newFleetCluster(4, 0)
+	c.Config.DeprovisionTimeout = 50 * time.Millisecond
+
+	// the machines of all the nodes are removed, including the stuck and failing ones, the nodes without machine are not torn down
+	var mu sync.Mutex
+	tornDown := []string{}
+	teardown := func(n *Node, h *host.Host) error {
+		mu.Lock()
+		tornDown = append(tornDown, n.MachineName)
+		mu.Unlock()
+
+		switch n.MachineName {
+		case "lille-1":
+			time.Sleep(time.Second)
+		case "lille-2":
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	}
+
+	removed := []string{}
+	remove := func(machineName string) error {
+		removed = append(removed, machineName)
+		if machineName == "lille-2" {
+			return fmt.Errorf("permission denied")
+		}
+		return nil
+	}
+
+	errs, forced := c.teardownNodes([]string{"lille-0", "lille-1", "lille-2"}, teardown, remove)
+	assert.Equal(t, []string{"lille-1", "lille-2"}, forced)
+	assert.ElementsMatch(t, []string{"lille-0", "lille-1", "lille-2"}, removed)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs, "lille-2")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, []string{"lille-0", "lille-1", "lille-2"}, tornDown)
+
+	errs, forced = c.teardownNodes([]string{}, nil, nil)
+	assert.Empty(t, errs)
+	assert.Empty(t, forced)
+}
+
+func TestTeardownNodesWithoutTeardown(t *testing.T) {
+	c := newFleetCluster(2, 0)
+
+	// the machines are only removed if the containers are not stopped
+	removed := []string{}
+	errs, forced := c.teardownNodes([]string{"lille-0", "lille-1"}, nil, func(machineName string) error {
+		removed = append(removed, machineName)
+		return nil
+	})
+	assert.Empty(t, errs)
+	assert.Empty(t, forced)
+	assert.ElementsMatch(t, []string{"lille-0", "lille-1"}, removed)
+}
+
+func TestRemoveMachineStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-g5k-storage")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := &GlobalConfig{MachineStoragePath: dir}
+	assert.NoError(t, os.MkdirAll(config.machineDir("lille-0"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(config.machineDir("lille-0"), "config.json"), []byte("{}"), 0600))
+
+	assert.NoError(t, config.removeMachineStorage("lille-0"))
+	_, err = os.Stat(config.machineDir("lille-0"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the machine was already removed
+	assert.NoError(t, config.removeMachineStorage("lille-0"))
+}
+
+func TestReserveThenDeploy(t *testing.T) {
+	calls := []string{}
+	jobs, deployed, err := reserveThenDeploy(map[string]int{"nancy": 2, "lille": 1}, func(site string, nb int) (int, error) {
+		calls = append(calls, "reserve "+site)
+		return len(calls), nil
+	}, func(site string, jobID int, nb int) ([]string, error) {
+		calls = append(calls, "deploy "+site)
+		return []string{fmt.Sprintf("%s-%d.%s.grid5000.fr", site, jobID, site)}, nil
+	}, func(site string, jobID int) error {
+		calls = append(calls, "cancel "+site)
+		return nil
+	})
+
+	// all the sites are reserved before any deployment
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"reserve lille", "reserve nancy", "deploy lille", "deploy nancy"}, calls)
+	assert.Equal(t, map[string]int{"lille": 1, "nancy": 2}, jobs)
+	assert.Equal(t, []string{"lille-1.lille.grid5000.fr"}, deployed["lille"])
+}
+
+func TestReserveThenDeployReservationFailure(t *testing.T) {
+	calls := []string{}
+	_, _, err := reserveThenDeploy(map[string]int{"lille": 1, "nancy": 2, "rennes": 3}, func(site string, nb int) (int, error) {
+		calls = append(calls, "reserve "+site)
+		if site == "nancy" {
+			return 0, fmt.Errorf("not enough resources")
+		}
+		return 42, nil
+	}, func(site string, jobID int, nb int) ([]string, error) {
+		calls = append(calls, "deploy "+site)
+		return nil, nil
+	}, func(site string, jobID int) error {
+		calls = append(calls, "cancel "+site)
+		return nil
+	})
+
+	// no node is deployed and the reserved job is released
+	assert.Error(t, err)
+	assert.Equal(t, []string{"reserve lille", "reserve nancy", "cancel lille"}, calls)
+}
+
+func TestReserveThenDeployDeploymentFailure(t *testing.T) {
+	cancelled := []string{}
+	_, _, err := reserveThenDeploy(map[string]int{"lille": 1, "nancy": 2}, func(site string, nb int) (int, error) {
+		return 42, nil
+	}, func(site string, jobID int, nb int) ([]string, error) {
+		if site == "nancy" {
+			return nil, fmt.Errorf("1/2 nodes deployed")
+		}
+		return []string{"chifflet-1.lille.grid5000.fr"}, nil
+	}, func(site string, jobID int) error {
+		cancelled = append(cancelled, site)
+		return fmt.Errorf("job not found")
+	})
+
+	// all the jobs are released, even the ones already deployed
+	assert.Error(t, err)
+	assert.ElementsMatch(t, []string{"lille", "nancy"}, cancelled)
+}
+
+func TestReserveAndProvisionAtomicInvalid(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	assert.Error(t, c.ReserveAndProvisionAtomic(map[string]int{"nancy": 0}))
+	assert.Empty(t, c.Nodes)
+}
@@ -0,0 +1,26 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+)
+
+// RunJobContainer run a one-shot job container to completion on the node and returns its exit code and logs
+func (c *Cluster) RunJobContainer(machineName string, spec container.ContainerSpec) (*container.JobResult, error) {
+	n, ok := c.Nodes[machineName]
+	if !ok {
+		return nil, fmt.Errorf("The node '%s' is not part of the cluster", machineName)
+	}
+
+	h, err := n.loadHost()
+	if err != nil {
+		return nil, err
+	}
+
+	// unique container name on the node
+	name := fmt.Sprintf("docker-g5k-job-%d", time.Now().UnixNano())
+
+	return container.RunJob(h, name, spec)
+}
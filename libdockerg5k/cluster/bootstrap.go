@@ -0,0 +1,279 @@
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// BootstrapSiteAuto select the bootstrap site with the lowest latency to the other sites
+	BootstrapSiteAuto = "auto"
+
+	// number of ICMP echo requests sent for each latency measurement
+	latencyPingCount = 3
+
+	// maximum time allowed to connect to a node for a latency measurement
+	latencySSHConnectTimeout = 10 * time.Second
+)
+
+// regexPingAverage extract the average round-trip time (in ms) of the 'ping -q' summary (iputils, BSD or BusyBox)
+var regexPingAverage = regexp.MustCompile(`(?m)^(?:rtt|round-trip) min/avg/max(?:/(?:mdev|stddev))? = [0-9.]+/([0-9.]+)/`)
+
+// BootstrapSelection contain the site of the Swarm mode bootstrap manager and the inter-site latencies it was selected from
+type BootstrapSelection struct {
+	Site string `json:"site"`
+
+	// true if the site was selected from the latencies, false if it was set by the user or used as a fallback
+	Auto bool `json:"auto"`
+
+	// round-trip time (in ms) between the sites (only set if the latencies were measured)
+	Latencies map[string]map[string]float64 `json:"latencies_ms,omitempty"`
+}
+
+// latencyProbe returns the round-trip time from a node to another node
+type latencyProbe func(from *Node, to *Node) (time.Duration, error)
+
+// parsePingAverage returns the average round-trip time of the 'ping -q' output
+func parsePingAverage(output string) (time.Duration, error) {
+	m := regexPingAverage.FindStringSubmatch(output)
+	if m == nil {
+		return 0, fmt.Errorf("No round-trip time in ping output: '%s'", output)
+	}
+
+	ms, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid round-trip time '%s': '%s'", m[1], err)
+	}
+
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
+
+// sshPingProbe returns a probe pinging the destination node from the source node over SSH (the Docker machines are not created yet)
+func (c *Cluster) sshPingProbe(keyPath string) latencyProbe {
+	return func(from *Node, to *Node) (time.Duration, error) {
+		cmd := exec.Command("ssh",
+			"-i", keyPath,
+			"-o", "BatchMode=yes",
+			"-o", "StrictHostKeyChecking=no",
+			"-o", "UserKnownHostsFile=/dev/null",
+			"-o", fmt.Sprintf("ConnectTimeout=%d", int(latencySSHConnectTimeout.Seconds())),
+			"root@"+from.NodeName,
+			fmt.Sprintf("ping -q -c %d %s", latencyPingCount, to.NodeName),
+		)
+
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return 0, fmt.Errorf("Failed to ping '%s' from '%s': '%s' (%s)", to.NodeName, from.NodeName, err, out)
+		}
+
+		return parsePingAverage(string(out))
+	}
+}
+
+// siteRepresentatives returns the first node (Swarm managers first) of each site of the cluster
+func (c *Cluster) siteRepresentatives() map[string]*Node {
+	sites := make(map[string]*Node)
+	for _, m := range c.Config.SwarmMasterNode {
+		if n := c.Nodes[m]; n != nil && sites[n.G5kSite] == nil {
+			sites[n.G5kSite] = n
+		}
+	}
+
+	for _, machineName := range c.selectNodes(nil) {
+		if n := c.Nodes[machineName]; sites[n.G5kSite] == nil {
+			sites[n.G5kSite] = n
+		}
+	}
+
+	return sites
+}
+
+// measureSiteLatencies returns the round-trip time between each pair of sites, measured (in parallel) from a node of each site
+func measureSiteLatencies(nodes map[string]*Node, probe latencyProbe) (map[string]map[string]time.Duration, error) {
+	latencies := make(map[string]map[string]time.Duration)
+	for site := range nodes {
+		latencies[site] = make(map[string]time.Duration)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(map[string]error)
+
+	for from, src := range nodes {
+		for to, dst := range nodes {
+			if from == to {
+				continue
+			}
+
+			wg.Add(1)
+			go func(from, to string, src, dst *Node) {
+				defer wg.Done()
+
+				rtt, err := probe(src, dst)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs[from+"->"+to] = err
+					return
+				}
+				latencies[from][to] = rtt
+			}(from, to, src, dst)
+		}
+	}
+	wg.Wait()
+
+	if err := fleetError("Latency measurement", errs); err != nil {
+		return nil, err
+	}
+
+	return latencies, nil
+}
+
+// selectBootstrapSite returns the candidate site with the lowest mean latency to the other sites (ties broken by name)
+func selectBootstrapSite(candidates []string, latencies map[string]map[string]time.Duration) string {
+	sorted := append([]string{}, candidates...)
+	sort.Strings(sorted)
+
+	best := ""
+	var bestCost time.Duration
+	for _, site := range sorted {
+		// the round-trip time of a pair can differ between the two directions
+		var cost time.Duration
+		for other := range latencies {
+			if other != site {
+				cost += latencies[site][other] + latencies[other][site]
+			}
+		}
+
+		if best == "" || cost < bestCost {
+			best, bestCost = site, cost
+		}
+	}
+
+	return best
+}
+
+// managerSites returns the sites of the Swarm managers, in the managers order
+func (c *Cluster) managerSites() []string {
+	sites := []string{}
+	seen := make(map[string]bool)
+	for _, m := range c.Config.SwarmMasterNode {
+		if n := c.Nodes[m]; n != nil && !seen[n.G5kSite] {
+			seen[n.G5kSite] = true
+			sites = append(sites, n.G5kSite)
+		}
+	}
+
+	return sites
+}
+
+// useBootstrapSite move the first Swarm manager of the site in first position of the managers (making it the bootstrap node)
+func (c *Cluster) useBootstrapSite(site string) {
+	for i, m := range c.Config.SwarmMasterNode {
+		if c.Nodes[m].G5kSite == site {
+			managers := append([]string{m}, c.Config.SwarmMasterNode[:i]...)
+			c.Config.SwarmMasterNode = append(managers, c.Config.SwarmMasterNode[i+1:]...)
+			return
+		}
+	}
+}
+
+// autoBootstrapSite measure the latencies between the sites and returns the selected bootstrap site (the first manager site if the measurement fails)
+func (c *Cluster) autoBootstrapSite(candidates []string, probe latencyProbe) *BootstrapSelection {
+	latencies, err := measureSiteLatencies(c.siteRepresentatives(), probe)
+	if err != nil {
+		log.Warnf("Failed to measure the latencies between the sites, using the site of the first Swarm manager '%s': '%s'", candidates[0], err)
+		return &BootstrapSelection{Site: candidates[0]}
+	}
+
+	sel := &BootstrapSelection{
+		Site:      selectBootstrapSite(candidates, latencies),
+		Auto:      true,
+		Latencies: make(map[string]map[string]float64),
+	}
+
+	for from, to := range latencies {
+		sel.Latencies[from] = make(map[string]float64)
+		for site, rtt := range to {
+			sel.Latencies[from][site] = float64(rtt) / float64(time.Millisecond)
+		}
+	}
+
+	return sel
+}
+
+// configureBootstrapSite select the site of the Swarm mode bootstrap manager and move one of its managers in first position
+func (c *Cluster) configureBootstrapSite(probe latencyProbe) error {
+	site := c.Config.SwarmModeGlobalConfig.BootstrapSite
+	candidates := c.managerSites()
+	if site == "" || len(candidates) == 0 {
+		return nil
+	}
+
+	// site set by the user
+	if site != BootstrapSiteAuto {
+		for _, s := range candidates {
+			if s == site {
+				c.useBootstrapSite(site)
+				c.Config.bootstrapSelection = &BootstrapSelection{Site: site}
+				return nil
+			}
+		}
+
+		return fmt.Errorf("No Swarm manager on the bootstrap site '%s'", site)
+	}
+
+	// nothing to measure on a single site cluster
+	if len(c.siteRepresentatives()) < 2 {
+		c.Config.bootstrapSelection = &BootstrapSelection{Site: candidates[0]}
+		return nil
+	}
+
+	if probe == nil {
+		keyPath, cleanup, err := c.writeSSHPrivateKey()
+		if err != nil {
+			log.Warnf("Failed to measure the latencies between the sites, using the site of the first Swarm manager '%s': '%s'", candidates[0], err)
+			c.Config.bootstrapSelection = &BootstrapSelection{Site: candidates[0]}
+			return nil
+		}
+		defer cleanup()
+
+		probe = c.sshPingProbe(keyPath)
+	}
+
+	c.Config.bootstrapSelection = c.autoBootstrapSite(candidates, probe)
+	c.useBootstrapSite(c.Config.bootstrapSelection.Site)
+	log.Infof("Using site '%s' for the Swarm mode bootstrap manager", c.Config.bootstrapSelection.Site)
+
+	return nil
+}
+
+// writeSSHPrivateKey write the cluster SSH private key to a temporary file, returning its path and a function removing it
+func (c *Cluster) writeSSHPrivateKey() (string, func(), error) {
+	if c.Config.SSHKeyPair == nil {
+		return "", nil, fmt.Errorf("No SSH key pair")
+	}
+
+	f, err := ioutil.TempFile("", "docker-g5k-ssh-key")
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to create the SSH private key file: '%s'", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(c.Config.SSHKeyPair.PrivateKey); err != nil {
+		os.Remove(f.Name())
+		return "", nil, fmt.Errorf("Failed to write the SSH private key file: '%s'", err)
+	}
+
+	return f.Name(), func() { os.Remove(f.Name()) }, nil
+}
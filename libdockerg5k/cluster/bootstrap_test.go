@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+)
+
+// newBootstrapTestCluster returns a Swarm mode cluster with managers on three sites
+func newBootstrapTestCluster(bootstrapSite string) *Cluster {
+	return &Cluster{
+		Config: &GlobalConfig{
+			SwarmMasterNode:       []string{"lille-0", "nancy-0", "rennes-0"},
+			SwarmModeGlobalConfig: &swarm.SwarmModeGlobalConfig{BootstrapSite: bootstrapSite},
+		},
+		Nodes: map[string]*Node{
+			"lille-0":  {MachineName: "lille-0", NodeName: "chetemi-1.lille.grid5000.fr", G5kSite: "lille"},
+			"nancy-0":  {MachineName: "nancy-0", NodeName: "graphene-1.nancy.grid5000.fr", G5kSite: "nancy"},
+			"nancy-1":  {MachineName: "nancy-1", NodeName: "graphene-2.nancy.grid5000.fr", G5kSite: "nancy"},
+			"rennes-0": {MachineName: "rennes-0", NodeName: "paravance-1.rennes.grid5000.fr", G5kSite: "rennes"},
+		},
+	}
+}
+
+// siteLatencyProbe returns a probe using the given round-trip times between sites (in ms, symmetric)
+func siteLatencyProbe(rtts map[string]int) latencyProbe {
+	return func(from *Node, to *Node) (time.Duration, error) {
+		if rtt, ok := rtts[from.G5kSite+"-"+to.G5kSite]; ok {
+			return time.Duration(rtt) * time.Millisecond, nil
+		}
+		if rtt, ok := rtts[to.G5kSite+"-"+from.G5kSite]; ok {
+			return time.Duration(rtt) * time.Millisecond, nil
+		}
+		return 0, fmt.Errorf("unreachable")
+	}
+}
+
+func TestParsePingAverage(t *testing.T) {
+	out := "PING graphene-1.nancy.grid5000.fr (172.16.64.1) 56(84) bytes of data.\n\n--- graphene-1.nancy.grid5000.fr ping statistics ---\n3 packets transmitted, 3 received, 0% packet loss, time 2003ms\nrtt min/avg/max/mdev = 9.812/10.250/10.731/0.375 ms\n"
+	rtt, err := parsePingAverage(out)
+	assert.NoError(t, err)
+	assert.Equal(t, 10250*time.Microsecond, rtt)
+
+	_, err = parsePingAverage("3 packets transmitted, 0 received, 100% packet loss, time 2003ms")
+	assert.Error(t, err)
+}
+
+func TestSelectBootstrapSite(t *testing.T) {
+	latencies := map[string]map[string]time.Duration{
+		"lille":  {"nancy": 10 * time.Millisecond, "rennes": 20 * time.Millisecond},
+		"nancy":  {"lille": 10 * time.Millisecond, "rennes": 15 * time.Millisecond},
+		"rennes": {"lille": 20 * time.Millisecond, "nancy": 15 * time.Millisecond},
+	}
+
+	assert.Equal(t, "nancy", selectBootstrapSite([]string{"lille", "nancy", "rennes"}, latencies))
+
+	// only the sites of the managers are candidates
+	assert.Equal(t, "lille", selectBootstrapSite([]string{"rennes", "lille"}, latencies))
+}
+
+func TestConfigureBootstrapSiteAuto(t *testing.T) {
+	c := newBootstrapTestCluster(BootstrapSiteAuto)
+	assert.NoError(t, c.configureBootstrapSite(siteLatencyProbe(map[string]int{"lille-nancy": 10, "lille-rennes": 20, "nancy-rennes": 15})))
+
+	assert.Equal(t, []string{"nancy-0", "lille-0", "rennes-0"}, c.Config.SwarmMasterNode)
+	assert.Equal(t, "nancy", c.Config.bootstrapSelection.Site)
+	assert.True(t, c.Config.bootstrapSelection.Auto)
+	assert.Equal(t, 15.0, c.Config.bootstrapSelection.Latencies["rennes"]["nancy"])
+}
+
+func TestConfigureBootstrapSiteFallback(t *testing.T) {
+	c := newBootstrapTestCluster(BootstrapSiteAuto)
+	assert.NoError(t, c.configureBootstrapSite(siteLatencyProbe(map[string]int{"lille-nancy": 10, "nancy-rennes": 15})))
+
+	assert.Equal(t, []string{"lille-0", "nancy-0", "rennes-0"}, c.Config.SwarmMasterNode)
+	assert.Equal(t, &BootstrapSelection{Site: "lille"}, c.Config.bootstrapSelection)
+}
+
+func TestConfigureBootstrapSiteUser(t *testing.T) {
+	c := newBootstrapTestCluster("rennes")
+	assert.NoError(t, c.configureBootstrapSite(nil))
+	assert.Equal(t, []string{"rennes-0", "lille-0", "nancy-0"}, c.Config.SwarmMasterNode)
+	assert.Equal(t, &BootstrapSelection{Site: "rennes"}, c.Config.bootstrapSelection)
+
+	c = newBootstrapTestCluster("grenoble")
+	assert.Error(t, c.configureBootstrapSite(nil))
+
+	// unchanged without bootstrap site
+	c = newBootstrapTestCluster("")
+	assert.NoError(t, c.configureBootstrapSite(nil))
+	assert.Equal(t, []string{"lille-0", "nancy-0", "rennes-0"}, c.Config.SwarmMasterNode)
+	assert.Nil(t, c.Config.bootstrapSelection)
+}
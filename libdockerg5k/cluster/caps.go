@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// containerCapsPath is the file containing the flags adding the default capabilities to the workload containers on the nodes
+	containerCapsPath = "/etc/docker-g5k/container-caps"
+)
+
+// normalizeCapabilities returns the (valid) capabilities names in the Docker format
+func normalizeCapabilities(caps []string) []string {
+	normalized := []string{}
+	for _, c := range caps {
+		if name, err := container.NormalizeCapability(c); err == nil {
+			normalized = append(normalized, name)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil
+	}
+
+	return normalized
+}
+
+// ContainerCapFlags returns the 'docker run' flags adding the default capabilities to a workload container (empty if there is no default capability)
+func (c *GlobalConfig) ContainerCapFlags() string {
+	return strings.TrimSpace(container.CapAddFlags(c.ContainerDefaultCaps))
+}
+
+// writeContainerCaps write the flags of the default capabilities of the workload containers on the node (if any) and log the capabilities of the containers
+func (n *Node) writeContainerCaps(h *host.Host) error {
+	if caps := normalizeCapabilities(n.clusterConfig.InfraContainerCaps); caps != nil {
+		n.logf("Infrastructure containers capabilities: %s", strings.Join(caps, ","))
+	}
+
+	caps := normalizeCapabilities(n.clusterConfig.ContainerDefaultCaps)
+	if caps == nil {
+		return nil
+	}
+
+	if _, err := h.RunSSHCommand(fmt.Sprintf("mkdir -p %s && printf '%%s\\n' '%s' >%s", path.Dir(containerCapsPath), n.clusterConfig.ContainerCapFlags(), containerCapsPath)); err != nil {
+		return fmt.Errorf("Failed to write the containers default capabilities to '%s': '%s'", containerCapsPath, err)
+	}
+
+	n.logf("Containers default capabilities: %s", strings.Join(caps, ","))
+	return nil
+}
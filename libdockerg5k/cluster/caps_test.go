@@ -0,0 +1,23 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCapabilities(t *testing.T) {
+	assert.Nil(t, normalizeCapabilities(nil))
+	assert.Equal(t, []string{"NET_ADMIN", "SYS_PTRACE"}, normalizeCapabilities([]string{"cap_net_admin", "SYS_PTRACE"}))
+}
+
+func TestContainerCapFlags(t *testing.T) {
+	assert.Equal(t, "", (&GlobalConfig{}).ContainerCapFlags())
+	assert.Equal(t, "--cap-add=NET_ADMIN", (&GlobalConfig{ContainerDefaultCaps: []string{"NET_ADMIN"}}).ContainerCapFlags())
+}
+
+func TestValidateContainerCaps(t *testing.T) {
+	assert.NoError(t, (&GlobalConfig{InfraContainerCaps: []string{"NET_ADMIN"}, ContainerDefaultCaps: []string{"SYS_PTRACE"}}).Validate())
+	assert.Error(t, (&GlobalConfig{InfraContainerCaps: []string{"NET_MAGIC"}}).Validate())
+	assert.Error(t, (&GlobalConfig{ContainerDefaultCaps: []string{"NET_MAGIC"}}).Validate())
+}
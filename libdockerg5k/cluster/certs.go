@@ -0,0 +1,126 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// minServerCertValidity is the minimum remaining validity of an existing server certificate to be reused
+	minServerCertValidity = 24 * time.Hour
+
+	// remote paths of the Docker Engine server certificate and key on the nodes (Docker Machine defaults)
+	remoteServerCertPath = "/etc/docker/server.pem"
+	remoteServerKeyPath  = "/etc/docker/server-key.pem"
+)
+
+// serverCert contain the PEM encoded server certificate and key of a node
+type serverCert struct {
+	cert []byte
+	key  []byte
+}
+
+// parsePEMCertificate returns the first certificate of the PEM data
+func parsePEMCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("No PEM certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// validateServerCert check the server certificate matches its key, is signed by the CA, is still valid at the given time and covers the node address
+func validateServerCert(sc *serverCert, caPEM []byte, addr string, now time.Time) error {
+	if _, err := tls.X509KeyPair(sc.cert, sc.key); err != nil {
+		return fmt.Errorf("The server certificate does not match its key: '%s'", err)
+	}
+
+	cert, err := parsePEMCertificate(sc.cert)
+	if err != nil {
+		return fmt.Errorf("Invalid server certificate: '%s'", err)
+	}
+
+	ca, err := parsePEMCertificate(caPEM)
+	if err != nil {
+		return fmt.Errorf("Invalid CA certificate: '%s'", err)
+	}
+
+	if err := cert.CheckSignatureFrom(ca); err != nil {
+		return fmt.Errorf("The server certificate is not signed by the current CA: '%s'", err)
+	}
+
+	if now.Add(minServerCertValidity).After(cert.NotAfter) {
+		return fmt.Errorf("The server certificate is expired or expires soon (%s)", cert.NotAfter.Format(time.RFC3339))
+	}
+
+	if err := cert.VerifyHostname(addr); err != nil {
+		return fmt.Errorf("The server certificate does not cover the node address: '%s'", err)
+	}
+
+	return nil
+}
+
+// reusableServerCert returns the existing server certificate and key of the node if they can be reused (nil if disabled, missing or not valid for the node)
+func (n *Node) reusableServerCert() *serverCert {
+	if !n.clusterConfig.ReuseExistingCerts {
+		return nil
+	}
+
+	authOptions := n.createHostAuthOptions()
+	cert, certErr := ioutil.ReadFile(authOptions.ServerCertPath)
+	key, keyErr := ioutil.ReadFile(authOptions.ServerKeyPath)
+	ca, caErr := ioutil.ReadFile(authOptions.CaCertPath)
+	if certErr != nil || keyErr != nil || caErr != nil {
+		log.Infof("No existing server certificate for node '%s' ('%s'), a new one will be generated", n.NodeName, n.MachineName)
+		return nil
+	}
+
+	sc := &serverCert{cert: cert, key: key}
+	if err := validateServerCert(sc, ca, n.clusterConfig.HostsLookupTable[n.MachineName], time.Now()); err != nil {
+		log.Warnf("The existing server certificate of node '%s' ('%s') can't be reused, a new one will be generated: %s", n.NodeName, n.MachineName, err)
+		return nil
+	}
+
+	return sc
+}
+
+// generateServerCertCommand returns the command used to write the (base64 encoded) server certificate and key on the node
+func generateServerCertCommand(sc *serverCert) string {
+	return fmt.Sprintf("echo '%s' | base64 -d >%s && echo '%s' | base64 -d >%s && chmod 600 %s",
+		base64.StdEncoding.EncodeToString(sc.cert), remoteServerCertPath, base64.StdEncoding.EncodeToString(sc.key), remoteServerKeyPath, remoteServerKeyPath)
+}
+
+// restoreServerCert replace the server certificate and key generated by the machine creation by the reused ones (in the local machine storage and on the node)
+func (n *Node) restoreServerCert(h *host.Host, sc *serverCert) error {
+	if sc == nil {
+		return nil
+	}
+
+	authOptions := n.createHostAuthOptions()
+	if err := ioutil.WriteFile(authOptions.ServerCertPath, sc.cert, 0644); err != nil {
+		return fmt.Errorf("Unable to restore the server certificate: '%s'", err)
+	}
+	if err := ioutil.WriteFile(authOptions.ServerKeyPath, sc.key, 0600); err != nil {
+		return fmt.Errorf("Unable to restore the server key: '%s'", err)
+	}
+
+	if _, err := h.RunSSHCommand(generateServerCertCommand(sc)); err != nil {
+		return fmt.Errorf("Failed to install the server certificate on the node: '%s'", err)
+	}
+
+	if err := n.restartEngine(h); err != nil {
+		return err
+	}
+
+	log.Infof("Existing server certificate of node '%s' ('%s') reused", n.NodeName, n.MachineName)
+	return nil
+}
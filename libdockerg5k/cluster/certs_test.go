@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCA is a CA used to sign the test server certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newTestCA returns a new self-signed test CA
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// serverCert returns a server certificate signed by the CA for the address, valid until the given time
+func (ca *testCA) serverCert(t *testing.T, addr string, notAfter time.Time) *serverCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "node"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		IPAddresses:  []net.IP{net.ParseIP(addr)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	return &serverCert{
+		cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+}
+
+func TestValidateServerCert(t *testing.T) {
+	ca := newTestCA(t)
+	sc := ca.serverCert(t, "172.16.0.1", time.Now().Add(30*24*time.Hour))
+	assert.NoError(t, validateServerCert(sc, ca.pem, "172.16.0.1", time.Now()))
+}
+
+func TestValidateServerCertIncorrect(t *testing.T) {
+	ca := newTestCA(t)
+	valid := ca.serverCert(t, "172.16.0.1", time.Now().Add(30*24*time.Hour))
+
+	// expired (or expiring soon)
+	assert.Error(t, validateServerCert(valid, ca.pem, "172.16.0.1", time.Now().Add(30*24*time.Hour)))
+	assert.Error(t, validateServerCert(ca.serverCert(t, "172.16.0.1", time.Now().Add(time.Hour)), ca.pem, "172.16.0.1", time.Now()))
+
+	// node address not covered
+	assert.Error(t, validateServerCert(valid, ca.pem, "172.16.0.2", time.Now()))
+
+	// signed by another CA
+	assert.Error(t, validateServerCert(valid, newTestCA(t).pem, "172.16.0.1", time.Now()))
+
+	// key of another certificate
+	other := ca.serverCert(t, "172.16.0.1", time.Now().Add(30*24*time.Hour))
+	assert.Error(t, validateServerCert(&serverCert{cert: valid.cert, key: other.key}, ca.pem, "172.16.0.1", time.Now()))
+
+	// not a certificate
+	assert.Error(t, validateServerCert(&serverCert{cert: []byte("garbage"), key: valid.key}, ca.pem, "172.16.0.1", time.Now()))
+}
+
+func TestGenerateServerCertCommand(t *testing.T) {
+	cmd := generateServerCertCommand(&serverCert{cert: []byte("a"), key: []byte("b")})
+	assert.True(t, strings.HasPrefix(cmd, "echo 'YQ==' | base64 -d >/etc/docker/server.pem && "))
+	assert.Contains(t, cmd, "echo 'Yg==' | base64 -d >/etc/docker/server-key.pem")
+	assert.Contains(t, cmd, "chmod 600 /etc/docker/server-key.pem")
+}
@@ -0,0 +1,277 @@
+package cluster
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// checkpointDir is the directory of the containers checkpoints on the nodes (a sub-directory by container)
+	checkpointDir = "/var/lib/docker-g5k/checkpoints"
+
+	// checkpointLookupTimeout is the maximum time allowed to find the node of a checkpoint
+	checkpointLookupTimeout = 2 * time.Minute
+
+	// criuInstallCommand install CRIU on the node if it is missing (needed by the Docker checkpoints)
+	criuInstallCommand = "command -v criu >/dev/null 2>&1 || { apt-get update -q && DEBIAN_FRONTEND=noninteractive apt-get install -q -y criu; }"
+
+	// engineExperimentalCommand returns true if the running Engine has the experimental features enabled
+	engineExperimentalCommand = "docker version --format '{{.Server.Experimental}}'"
+)
+
+// regexCheckpointName match a container or checkpoint name (ex: web, web.1, cp-0)
+var regexCheckpointName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// CheckpointReport contain the timings of a container checkpoint or restore
+type CheckpointReport struct {
+	Container  string `json:"container"`
+	Checkpoint string `json:"checkpoint"`
+	Source     string `json:"source"`           // machine name of the node where the checkpoint was created
+	Target     string `json:"target,omitempty"` // machine name of the node where the container was restored (restore only)
+
+	CheckpointDuration time.Duration `json:"checkpoint_duration,omitempty"`
+	TransferDuration   time.Duration `json:"transfer_duration,omitempty"` // zero if the container is restored on the node of the checkpoint
+	RestoreDuration    time.Duration `json:"restore_duration,omitempty"`
+}
+
+// validateCheckpointNames check the container and checkpoint names (used in the commands and paths of the nodes)
+func validateCheckpointNames(container string, checkpointName string) error {
+	if !regexCheckpointName.MatchString(container) {
+		return fmt.Errorf("Invalid container name: '%s'", container)
+	}
+	if !regexCheckpointName.MatchString(checkpointName) {
+		return fmt.Errorf("Invalid checkpoint name: '%s'", checkpointName)
+	}
+
+	return nil
+}
+
+// containerCheckpointDir returns the checkpoint directory of the container on the nodes
+func containerCheckpointDir(container string) string {
+	return fmt.Sprintf("%s/%s", checkpointDir, container)
+}
+
+// checkpointImageFile returns the file storing the image of the checkpointed container (used to create the container on the target node)
+func checkpointImageFile(container string, checkpointName string) string {
+	return fmt.Sprintf("%s/%s.image", containerCheckpointDir(container), checkpointName)
+}
+
+// generateCheckpointCommand returns the command checkpointing the running container (the container is stopped) and storing its image
+func generateCheckpointCommand(container string, checkpointName string) string {
+	dir := containerCheckpointDir(container)
+	return fmt.Sprintf("mkdir -p %s && docker inspect --format '{{.Config.Image}}' %s > %s && docker checkpoint create --checkpoint-dir %s %s %s",
+		dir, container, checkpointImageFile(container, checkpointName), dir, container, checkpointName)
+}
+
+// generateRestoreCommand returns the command restoring the container from the checkpoint (the container is created from the checkpointed image if it does not exist on the node)
+func generateRestoreCommand(container string, checkpointName string) string {
+	return fmt.Sprintf("{ docker inspect --format '{{.Id}}' %s >/dev/null 2>&1 || docker create --name %s $(cat %s); } && docker start --checkpoint %s --checkpoint-dir %s %s",
+		container, container, checkpointImageFile(container, checkpointName), checkpointName, containerCheckpointDir(container), container)
+}
+
+// generateCheckpointExistsCommand returns the command checking the checkpoint is stored on the node
+func generateCheckpointExistsCommand(container string, checkpointName string) string {
+	return fmt.Sprintf("test -d %s/%s && test -f %s", containerCheckpointDir(container), checkpointName, checkpointImageFile(container, checkpointName))
+}
+
+// sshCommandArgs returns the arguments of the OpenSSH client running the command on the node
+func (info *SSHConnInfo) sshCommandArgs(command string) []string {
+	args := []string{
+		"-i", info.KeyPath,
+		"-p", fmt.Sprintf("%d", info.Port),
+		"-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+	}
+	if info.Bastion != "" {
+		args = append(args, "-o", fmt.Sprintf("ProxyJump=%s", info.Bastion))
+	}
+
+	return append(args, fmt.Sprintf("%s@%s", info.User, info.Host), command)
+}
+
+// checkCheckpointSupport check the experimental features are enabled on the cluster and the running Engine of the node, and install CRIU if it is missing
+func (n *Node) checkCheckpointSupport(h *host.Host) error {
+	if !n.clusterConfig.EngineExperimental {
+		return fmt.Errorf("The containers checkpoints need the experimental features of the Engines")
+	}
+
+	out, err := h.RunSSHCommand(engineExperimentalCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the experimental mode of the Engine: '%s'", err)
+	}
+	if strings.TrimSpace(out) != "true" {
+		return fmt.Errorf("The experimental features are not enabled on the running Engine")
+	}
+
+	if _, err := h.RunSSHCommand(criuInstallCommand); err != nil {
+		return fmt.Errorf("Failed to install CRIU: '%s'", err)
+	}
+
+	return nil
+}
+
+// checkpointNode returns the loaded host of the node for a checkpoint operation, after checking the checkpoints are supported
+func (c *Cluster) checkpointNode(machineName string) (*Node, *host.Host, error) {
+	n, ok := c.Nodes[machineName]
+	if !ok {
+		return nil, nil, fmt.Errorf("The node '%s' does not exist", machineName)
+	}
+
+	h, err := n.loadHost()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := n.checkCheckpointSupport(h); err != nil {
+		return nil, nil, fmt.Errorf("Node '%s': %s", machineName, err)
+	}
+
+	return n, h, nil
+}
+
+// CheckpointContainer checkpoint the running container of the node (machine name) with Docker and CRIU for a migration, the container is stopped
+// the checkpoint is stored on the node and can be restored on any node of the cluster with RestoreContainer
+func (c *Cluster) CheckpointContainer(machineName string, container string, checkpointName string) (*CheckpointReport, error) {
+	if err := validateCheckpointNames(container, checkpointName); err != nil {
+		return nil, err
+	}
+
+	n, h, err := c.checkpointNode(machineName)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	if out, err := h.RunSSHCommand(generateCheckpointCommand(container, checkpointName)); err != nil {
+		return nil, fmt.Errorf("Failed to checkpoint the container '%s' on node '%s': '%s' (%s)", container, machineName, err, strings.TrimSpace(out))
+	}
+
+	report := &CheckpointReport{
+		Container:          container,
+		Checkpoint:         checkpointName,
+		Source:             machineName,
+		CheckpointDuration: time.Since(start),
+	}
+
+	log.Infof("Container '%s' checkpointed as '%s' on node '%s' ('%s') in %s", container, checkpointName, n.NodeName, machineName, report.CheckpointDuration)
+	return report, nil
+}
+
+// checkpointSource returns the machine name of the node storing the checkpoint (the target node first, to avoid a transfer)
+func (c *Cluster) checkpointSource(targetMachineName string, container string, checkpointName string) (string, error) {
+	var mu sync.Mutex
+	found := []string{}
+	c.runOnNodes(checkpointLookupTimeout, func(n *Node, h *host.Host) error {
+		if _, err := h.RunSSHCommand(generateCheckpointExistsCommand(container, checkpointName)); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		found = append(found, n.MachineName)
+		mu.Unlock()
+		return nil
+	})
+
+	if len(found) == 0 {
+		return "", fmt.Errorf("The checkpoint '%s' of the container '%s' was not found on the nodes", checkpointName, container)
+	}
+
+	sort.Strings(found)
+	for _, machineName := range found {
+		if machineName == targetMachineName {
+			return machineName, nil
+		}
+	}
+
+	return found[0], nil
+}
+
+// transferCheckpoint copy the checkpoint data from the source node to the target node over SSH (the data is streamed through the local host, the nodes can't connect to each other)
+func (c *Cluster) transferCheckpoint(source *Node, target *Node, container string, checkpointName string) error {
+	src, err := source.SSHConnection()
+	if err != nil {
+		return err
+	}
+	dst, err := target.SSHConnection()
+	if err != nil {
+		return err
+	}
+
+	dir := containerCheckpointDir(container)
+	send := exec.Command("ssh", src.sshCommandArgs(fmt.Sprintf("tar -C %s -cz %s %s.image", dir, checkpointName, checkpointName))...)
+	receive := exec.Command("ssh", dst.sshCommandArgs(fmt.Sprintf("mkdir -p %s && tar -C %s -xz", dir, dir))...)
+
+	pipe, err := send.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("Failed to transfer the checkpoint: '%s'", err)
+	}
+	receive.Stdin = pipe
+
+	var sendErr, receiveErr strings.Builder
+	send.Stderr = &sendErr
+	receive.Stderr = &receiveErr
+
+	if err := receive.Start(); err != nil {
+		return fmt.Errorf("Failed to transfer the checkpoint: '%s'", err)
+	}
+	if err := send.Run(); err != nil {
+		receive.Wait()
+		return fmt.Errorf("Failed to read the checkpoint from node '%s': '%s' (%s)", source.MachineName, err, strings.TrimSpace(sendErr.String()))
+	}
+	if err := receive.Wait(); err != nil {
+		return fmt.Errorf("Failed to write the checkpoint on node '%s': '%s' (%s)", target.MachineName, err, strings.TrimSpace(receiveErr.String()))
+	}
+
+	return nil
+}
+
+// RestoreContainer restore the container from its checkpoint on the target node (machine name), the checkpoint data is transferred over SSH from the node where it was created
+// the container is created on the target node from the checkpointed image if it does not exist
+func (c *Cluster) RestoreContainer(targetMachineName string, container string, checkpointName string) (*CheckpointReport, error) {
+	if err := validateCheckpointNames(container, checkpointName); err != nil {
+		return nil, err
+	}
+
+	target, h, err := c.checkpointNode(targetMachineName)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceMachineName, err := c.checkpointSource(targetMachineName, container, checkpointName)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CheckpointReport{
+		Container:  container,
+		Checkpoint: checkpointName,
+		Source:     sourceMachineName,
+		Target:     targetMachineName,
+	}
+
+	if sourceMachineName != targetMachineName {
+		start := time.Now()
+		if err := c.transferCheckpoint(c.Nodes[sourceMachineName], target, container, checkpointName); err != nil {
+			return nil, err
+		}
+		report.TransferDuration = time.Since(start)
+	}
+
+	start := time.Now()
+	if out, err := h.RunSSHCommand(generateRestoreCommand(container, checkpointName)); err != nil {
+		return nil, fmt.Errorf("Failed to restore the container '%s' on node '%s': '%s' (%s)", container, targetMachineName, err, strings.TrimSpace(out))
+	}
+	report.RestoreDuration = time.Since(start)
+
+	log.Infof("Container '%s' restored from '%s' on node '%s' ('%s'): transfer %s, restore %s", container, checkpointName, target.NodeName, targetMachineName, report.TransferDuration, report.RestoreDuration)
+	return report, nil
+}
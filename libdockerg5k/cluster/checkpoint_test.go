@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCheckpointNames(t *testing.T) {
+	assert.NoError(t, validateCheckpointNames("web.1", "cp-0"))
+
+	assert.Error(t, validateCheckpointNames("", "cp-0"))
+	assert.Error(t, validateCheckpointNames("web", "cp 0"))
+	assert.Error(t, validateCheckpointNames("web;ls", "cp-0"))
+}
+
+func TestGenerateCheckpointCommands(t *testing.T) {
+	assert.Equal(t, "mkdir -p /var/lib/docker-g5k/checkpoints/web && docker inspect --format '{{.Config.Image}}' web > /var/lib/docker-g5k/checkpoints/web/cp0.image && docker checkpoint create --checkpoint-dir /var/lib/docker-g5k/checkpoints/web web cp0",
+		generateCheckpointCommand("web", "cp0"))
+	assert.Equal(t, "{ docker inspect --format '{{.Id}}' web >/dev/null 2>&1 || docker create --name web $(cat /var/lib/docker-g5k/checkpoints/web/cp0.image); } && docker start --checkpoint cp0 --checkpoint-dir /var/lib/docker-g5k/checkpoints/web web",
+		generateRestoreCommand("web", "cp0"))
+	assert.Equal(t, "test -d /var/lib/docker-g5k/checkpoints/web/cp0 && test -f /var/lib/docker-g5k/checkpoints/web/cp0.image",
+		generateCheckpointExistsCommand("web", "cp0"))
+}
+
+func TestSSHCommandArgs(t *testing.T) {
+	info := &SSHConnInfo{Host: "lille-0.lille.grid5000.fr", Port: 22, User: "root", KeyPath: "/tmp/id_rsa"}
+	args := info.sshCommandArgs("hostname")
+	assert.Equal(t, []string{"-i", "/tmp/id_rsa", "-p", "22"}, args[:4])
+	assert.Equal(t, []string{"root@lille-0.lille.grid5000.fr", "hostname"}, args[len(args)-2:])
+	assert.NotContains(t, args, "ProxyJump=user@access.grid5000.fr")
+
+	info.Bastion = "user@access.grid5000.fr"
+	assert.Contains(t, info.sshCommandArgs("hostname"), "ProxyJump=user@access.grid5000.fr")
+}
+
+func TestCheckpointContainerIncorrect(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager})
+
+	_, err := c.CheckpointContainer("lille-0", "web;ls", "cp0")
+	assert.Error(t, err)
+	_, err = c.CheckpointContainer("nancy-0", "web", "cp0")
+	assert.Error(t, err)
+	_, err = c.RestoreContainer("nancy-0", "web", "cp0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,24 @@
+package cluster
+
+import (
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/weave"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// cleanupContainers remove the containers started by docker-g5k on the node after a failed provisioning (best effort, errors are only logged)
+func (n *Node) cleanupContainers(h *host.Host) {
+	log.Infof("Removing the docker-g5k containers of node '%s' ('%s')...", n.NodeName, n.MachineName)
+
+	if err := container.RemoveManagedContainers(h); err != nil {
+		log.Errorf("Cleanup failed on node '%s': '%s'", n.MachineName, err)
+	}
+
+	// the Weave Net router is started by the Weave script and can't be labeled
+	if n.clusterConfig.SwarmStandaloneGlobalConfig != nil && n.clusterConfig.WeaveNetworkingEnabled && weave.IsWeaveNetRunning(h, n.clusterConfig.WeaveConfig) {
+		if err := weave.StopWeave(h, n.clusterConfig.WeaveConfig); err != nil {
+			log.Errorf("Cleanup failed on node '%s': '%s'", n.MachineName, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// deepCopy copy the exported fields of the value to the destination (through JSON, the fields excluded from JSON are not copied)
+func deepCopy(v interface{}, dst interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, dst)
+}
+
+// cloneConfig returns a copy of the global configuration using the machine storage, without the state of the cluster (ID, join tokens, static lookup table)
+// the secrets, hooks and plugins are shared with the configuration
+func (c *GlobalConfig) cloneConfig(storagePath string) (*GlobalConfig, error) {
+	clone := &GlobalConfig{}
+	if err := deepCopy(c, clone); err != nil {
+		return nil, err
+	}
+
+	clone.LibMachineClient = NewMachineStorageClient(storagePath)
+	if c.LibMachineClient != nil {
+		clone.LibMachineClient.IsDebug = c.LibMachineClient.IsDebug
+	}
+	clone.MachineStoragePath = storagePath
+	clone.ClusterID = ""
+	clone.HostsLookupTable = make(map[string]string)
+
+	clone.PreEngineHook = c.PreEngineHook
+	clone.NodePlugins = c.NodePlugins
+	clone.ClusterPlugins = c.ClusterPlugins
+	clone.Credentials = c.Credentials
+	clone.G5kPassword = c.G5kPassword
+	clone.passwordEnv = c.passwordEnv
+	clone.SSHKeyPair = c.SSHKeyPair
+	clone.WeavePassword = c.WeavePassword
+
+	for registry, a := range c.RegistryAuths {
+		clone.RegistryAuths[registry] = a
+	}
+
+	// the join tokens and bootstrap manager are not copied (json:"-")
+	if c.SwarmModeGlobalConfig != nil {
+		clone.SwarmModeGlobalConfig.CAOptions.ExternalCACert = c.SwarmModeGlobalConfig.CAOptions.ExternalCACert
+	}
+
+	return clone, nil
+}
+
+// cloneNode returns a copy of the node configuration for the global configuration, without the reservation results (node hostname, job ID and ownership, failure domain)
+// a node reserved on a fallback site is cloned on its requested site
+func (n *Node) cloneNode(config *GlobalConfig) (*Node, error) {
+	clone := &Node{}
+	if err := deepCopy(n, clone); err != nil {
+		return nil, err
+	}
+
+	clone.clusterConfig = config
+	clone.NodeName = ""
+	clone.G5kJobID = 0
+	clone.OwnsJob = false
+	clone.FailureDomain = ""
+	if n.requestedSite != "" {
+		clone.G5kSite = n.requestedSite
+	}
+
+	return clone, nil
+}
+
+// Clone returns a copy of the cluster configuration and nodes (sorted by machine name) with the same roles, labels and options, ready to be reserved and provisioned
+// as another cluster (ex: control and treatment clusters of an experiment). The reservation results and the state of the cluster (ID, job IDs, join tokens, static lookup table) are not copied
+// the machine names are generated from the sites on reservation ({site}-{id}) and kept, the clone needs its own machine storage to not overwrite the machines of the cluster
+func (c *Cluster) Clone(storagePath string) (*GlobalConfig, []*Node, error) {
+	if storagePath == "" || !filepath.IsAbs(storagePath) {
+		return nil, nil, fmt.Errorf("The machine storage of the cloned cluster must be an absolute path: '%s'", storagePath)
+	}
+
+	if filepath.Clean(storagePath) == c.Config.machineStorageDir() {
+		return nil, nil, fmt.Errorf("The cloned cluster can't use the machine storage of the cluster: '%s'", storagePath)
+	}
+
+	config, err := c.Config.cloneConfig(storagePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to clone the cluster configuration: '%s'", err)
+	}
+
+	nodes := []*Node{}
+	for machineName, n := range c.Nodes {
+		node, err := n.cloneNode(config)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unable to clone the configuration of node '%s': '%s'", machineName, err)
+		}
+
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].MachineName < nodes[j].MachineName })
+
+	return config, nodes, nil
+}
@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClone(t *testing.T) {
+	c := newTestSnapshotCluster()
+	c.Config.ClusterID = "control"
+	c.Config.SwarmModeGlobalConfig.CAOptions = swarm.CAOptions{ExternalCAURL: "https://ca.example.org:8888/api/v1/cfssl/sign", ExternalCACert: "-----BEGIN CERTIFICATE-----"}
+	c.Config.RegistryAuths = map[string]RegistryAuth{"registry.example.com": {Username: "jdoe", Password: "secret"}}
+	c.Nodes["lille-0"].Role = NodeRoleManager
+	c.Nodes["lille-0"].FailureDomain = "chimint"
+	c.Nodes["lille-1"].requestedSite = "nancy"
+
+	config, nodes, err := c.Clone("/tmp/treatment")
+	assert.NoError(t, err)
+
+	// state and reservation results are not copied
+	assert.Equal(t, "", config.ClusterID)
+	assert.Empty(t, config.HostsLookupTable)
+	assert.Equal(t, "", config.SwarmModeGlobalConfig.WorkerToken)
+	assert.Equal(t, "/tmp/treatment", config.LibMachineClient.Filestore.Path)
+	assert.NoError(t, config.validateMachineStorage())
+
+	// secrets and hooks are shared
+	assert.Equal(t, "secret", config.G5kPassword)
+	assert.Equal(t, "secret", config.RegistryAuths["registry.example.com"].Password)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----", config.SwarmModeGlobalConfig.CAOptions.ExternalCACert)
+	assert.NotNil(t, config.PreEngineHook)
+
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "lille-0", nodes[0].MachineName)
+	assert.Equal(t, NodeRoleManager, nodes[0].Role)
+	assert.Equal(t, []string{"log-level=debug"}, nodes[0].EngineOpt)
+	assert.Equal(t, "", nodes[0].NodeName)
+	assert.Equal(t, 0, nodes[0].G5kJobID)
+	assert.Equal(t, "", nodes[0].FailureDomain)
+	assert.Equal(t, "nancy", nodes[1].G5kSite)
+	assert.True(t, nodes[0].clusterConfig == config)
+
+	// the clone does not modify the cluster
+	config.PhaseTimeouts[PhaseCreate] = 0
+	nodes[0].EngineOpt[0] = "log-level=info"
+	assert.Equal(t, "control", c.Config.ClusterID)
+	assert.Equal(t, []string{"log-level=debug"}, c.Nodes["lille-0"].EngineOpt)
+	assert.NotEqual(t, config.PhaseTimeouts[PhaseCreate], c.Config.PhaseTimeouts[PhaseCreate])
+	assert.Equal(t, "chimint-1.lille.grid5000.fr", c.Nodes["lille-0"].NodeName)
+}
+
+func TestCloneSnapshot(t *testing.T) {
+	c := newTestSnapshotCluster()
+	a, err := c.Snapshot()
+	assert.NoError(t, err)
+
+	config, nodes, err := c.Clone("/tmp/treatment")
+	assert.NoError(t, err)
+
+	clone := NewCluster(config)
+	for _, n := range nodes {
+		clone.Nodes[n.MachineName] = n
+	}
+	b, err := clone.Snapshot()
+	assert.NoError(t, err)
+
+	// only the machine storage differ
+	assert.Equal(t, `+ config.MachineStoragePath: "/tmp/treatment"`, CompareSnapshots(a, b))
+}
+
+func TestCloneStorage(t *testing.T) {
+	c := newTestSnapshotCluster()
+	c.Config.MachineStoragePath = "/tmp/control"
+
+	_, _, err := c.Clone("")
+	assert.Error(t, err)
+	_, _, err = c.Clone("treatment")
+	assert.Error(t, err)
+	_, _, err = c.Clone("/tmp/control/")
+	assert.Error(t, err)
+}
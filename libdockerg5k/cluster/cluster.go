@@ -2,45 +2,289 @@ package cluster
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"net"
 
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/bastion"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/ingress"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/registry"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/security"
 	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/weave"
 	"github.com/Spirals-Team/docker-g5k/libdockerg5k/zookeeper"
 	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/ssh"
 )
 
+const (
+	// defaultQuorumTimeout is the default maximum time to wait for the Swarm mode managers quorum
+	defaultQuorumTimeout = 5 * time.Minute
+
+	// engineTLSPort is the port of the Docker Engines API (TLS) configured by Docker Machine
+	engineTLSPort = 2376
+)
+
 // GlobalConfig contains the cluster global configuration
 type GlobalConfig struct {
 	// Docker Machine
-	LibMachineClient *libmachine.Client
+	LibMachineClient *libmachine.Client `json:"-"`
+
+	// base directory of the Docker Machine storage of the cluster (certificates and machines), the Docker Machine default if empty
+	// the libmachine client needs to use the same storage (see NewMachineStorageClient), clusters using distinct storages can be provisioned concurrently
+	MachineStoragePath string
+
+	// identifier of the cluster, added as an Engine label on all nodes to find them back in the machine storage (optional)
+	ClusterID string
+
+	// metadata of the experiment (ex: experiment.id, git.commit), added as Engine labels on all nodes (prefixed 'g5k.annotation.') and reported in the inventory (optional)
+	Annotations map[string]string
+
+	// hook run on each node right after the machine creation, before any other configuration (optional)
+	// the cluster nodes hostname are not resolvable yet (the hosts mapping is done after), an error aborts the node provisioning
+	PreEngineHook func(h *host.Host) error `json:"-"`
+
+	// plugins applied (in order) on each node once provisioned, and once on a manager after all nodes are provisioned (optional)
+	NodePlugins    []ProvisionPlugin `json:"-"`
+	ClusterPlugins []ProvisionPlugin `json:"-"`
 
 	// Docker Engine
 	EngineInstallURL string
+	DisableG5kLabels bool // do not add the Grid'5000 site, job ID and node hostname as Engine labels
+	LogRotation      LogRotation
+
+	// subnet of the Docker Engine default bridge (docker0), Docker default if empty
+	BridgeSubnet string
+
+	// default resolver options of the containers (ex: ndots:1), Docker defaults if empty
+	ContainerDNSOptions []string
+
+	// settings of the Docker Engine systemd service (Section.Key => value), written as a drop-in on all nodes
+	EngineSystemdOverrides map[string]string
+
+	// network created on all nodes and advertised as the default container network (optional)
+	DefaultContainerNetwork string
+
+	// default platform of the images pulled on the nodes and of the infrastructure containers (ex: linux/amd64, the variant of each node if empty)
+	DefaultPlatform string
+
+	// Docker Engine experimental features and API version used by the clients on the nodes (optional)
+	EngineExperimental bool
+	EngineAPIVersion   string
+
+	// log level of the Docker Engines (debug, info, warn, error or fatal, Docker default if empty, the nodes in debug mode use 'debug')
+	EngineLogLevel string
+
+	// default size of the containers '/dev/shm' on the Docker Engines (ex: 1g, Docker default if empty)
+	DefaultShmSize string
+
+	// HTTP(S) proxy used by the Docker Engines for their own requests (ex: image pulls from the external registries), not by the containers nor the hosts (disabled if empty)
+	EnginePullProxy string
+
+	// Docker Engine userland proxy and iptables rules management (Docker defaults if nil)
+	UserlandProxy  *bool
+	ManageIptables *bool
+
+	// OCI runtimes registered on the Engines (name => binary path) and default runtime (runc if empty)
+	RuntimeBinaries map[string]string
+	OCIRuntime      string
+
+	// address of the Docker Engine Prometheus metrics endpoint (format: [ip]:port, requires the experimental features, optional)
+	EngineMetricsAddr string
+
+	// Docker Engine security profiles (optional, Docker defaults are used if empty)
+	SeccompProfilePath string // local path of the seccomp profile used as default by the Engine
+	AppArmorProfile    string // local path of the AppArmor profile loaded on the nodes
+
+	// Grid'5000 credentials provider (optional, G5kUsername and G5kPassword are used if not set)
+	Credentials CredentialProvider `json:"-"`
 
 	// Grid'5000 driver config (needed or some Docker Machine operations will not work afterwards)
+	// the secrets and the live state fields are excluded from the configuration snapshots (json:"-")
 	G5kUsername string
-	G5kPassword string
+	G5kPassword string `json:"-"`
 	G5kImage    string
 	G5kWalltime string
-	SSHKeyPair  *ssh.KeyPair
+	SSHKeyPair  *ssh.KeyPair `json:"-"`
+
+	// default deployment mode of the nodes: deploy the G5kImage environment or use the default environment (DeployModeDeploy if empty)
+	DeployMode string
+
+	// environment variable of the password (only set for the configurations loaded from a cluster definition file)
+	passwordEnv string
+
+	// disable the swap on the nodes (ex: required by kubelet)
+	DisableSwap bool
+
+	// data root directory of the Docker Engines (ex: on the large local disk, Docker default if empty)
+	DataRoot        string
+	DataRootDevice  string // device mounted (and formatted if needed) on the data root directory (optional)
+	DataRootMinFree string // minimum free space of the data root filesystem (ex: 50g, not checked if empty)
+
+	// keep the data root device across the jobs as an image cache of the re-provisioned nodes (needs a data root device on a reserved disk, not on the system disk)
+	DataRootPersistent bool
+
+	// write the Grid'5000 job facts (job ID, start time, nodes) as an env file on the nodes
+	JobFacts bool
+
+	// maximum time to wait for the SSH port of the nodes to be reachable before provisioning them (disabled if zero)
+	SSHWaitTimeout time.Duration
+
+	// maximum number of nodes whose loaded host and SSH client are cached and reused by the operations on the cluster (disabled if zero)
+	// the SSH connections are not kept open: each command opens its own connection
+	HostCacheSize        int
+	HostCacheIdleTimeout time.Duration // time an unused host is kept in the cache (5 minutes if zero)
+	hostCache            hostCache
+
+	// maximum number of nodes the operations on the whole cluster (commands, facts gathering, containers stats, logs collection...) run on at the same time (default if zero)
+	// the provisioning of the nodes is not bounded by it
+	FleetConcurrency int
+
+	// SSH bastion used by the external tools to reach the nodes (ex: access.grid5000.fr, nodes are reached directly if empty)
+	SSHBastion string
+
+	// kernel parameters set on all nodes (the parameters of a node take precedence)
+	CommonSysctls map[string]string
+
+	// grace period of the running containers stopped before the removal of a node (the containers are not stopped if zero)
+	GracefulContainerStop time.Duration
+
+	// maximum time of the graceful teardown of the nodes (containers stop) when the cluster is deprovisioned (default if not set)
+	// the machines of the nodes not torn down in time are removed anyway and their jobs canceled
+	DeprovisionTimeout time.Duration
+
+	// OAR queue used for the reservations (default queue if empty)
+	OARQueue string
+
+	// replace the nodes lost by the supervisor (ex: preempted besteffort jobs), bounded by the maximum number of replacement attempts (default if not set)
+	AutoRepair     bool
+	MaxRepairs     int
+	RepairInterval time.Duration
+
+	// sites tried in order when the reservation of the nodes of a site fails or does not start before the 'reserve' phase timeout (the nodes keep their machine names)
+	SiteFallbacks []string
+
+	// core-hours allowance of the user by site, used to check the reservations fit (not checked for the missing sites)
+	CoreHoursQuota map[string]float64
+
+	// maximum core-hours of a reservation, estimated with the largest nodes of the sites before reserving (not checked if zero), and allow the reservations above it (with a warning)
+	MaxCoreHours          float64
+	AllowCoreHoursOverrun bool
+
+	// placement rules of the nodes
+	PlacementPolicy PlacementPolicy
+
+	// seed of the nondeterministic choices (allocation of the deployed nodes to the machines, time-based if zero)
+	Seed int64
+
+	// timeout of the provisioning phases (default timeout is used for the missing phases)
+	PhaseTimeouts map[string]time.Duration
+
+	// network capability required on the nodes (nil to use the default network)
+	NetworkRequirement *g5k.NetworkRequirement
+
+	// hardware description of the nodes (from the Reference API)
+	hardware hardwareCache
+
+	// add the labels derived from the hardware description to the Swarm mode nodes once joined (default rules are used if empty)
+	HardwareLabels     bool
+	HardwareLabelRules []HardwareLabelRule
+
+	// directory of the provisioning log files of the nodes (<machineName>.log, disabled if empty)
+	LogDir string
+
+	// logging mode of the libmachine and driver output ('quiet', 'normal', 'verbose' or 'trace', the logger is not changed if empty)
+	LogLevel string
 
 	// Associates nodes IP address with Machine name
-	HostsLookupTable map[string]string
+	HostsLookupTable map[string]string `json:"-"`
+
+	// aliases of the nodes in the static lookup table (by machine name, set at provisioning)
+	hostsAliases map[string][]string
+
+	// add the aliases of the nodes as Engine label (g5k.aliases)
+	AliasesEngineLabel bool
+
+	// site of the Swarm mode bootstrap manager and the latencies it was selected from (set at provisioning)
+	bootstrapSelection *BootstrapSelection
+
+	// machine names (sorted) of the cluster nodes, without the other hosts of the static lookup table (set at provisioning)
+	clusterNodes []string
 
 	// Swarm configuration
 	SwarmStandaloneGlobalConfig *swarm.SwarmStandaloneGlobalConfig
 	SwarmModeGlobalConfig       *swarm.SwarmModeGlobalConfig
-	SwarmMasterNode             []string
+	SwarmMasterNode             []string // deprecated: use the nodes Role (kept in sync by Cluster.SyncNodeRoles)
+
+	// Swarm mode managers quorum
+	RequireQuorumOnProvision bool
+	QuorumTimeout            time.Duration
+
+	// the provisioning succeeds if at least this number of nodes are provisioned, the others (including the Swarm masters except the first one) are optional (disabled if zero)
+	MinSuccessfulNodes int
+
+	// maximum total time of the nodes provisioning, all the nodes are released if it is exceeded (disabled if zero)
+	ProvisionDeadline time.Duration
+
+	// deploy a smoke test service once the Swarm mode cluster is provisioned
+	SmokeTestOnProvision bool
 
 	// Weave networking
 	WeaveNetworkingEnabled bool
+	WeaveConnectors        []string     // hub nodes of the Weave network (full mesh if empty)
+	WeaveStablePeers       bool         // peer names derived from the machine names and IPAM seeded by the Swarm masters
+	WeavePassword          string       `json:"-"` // encryption password of the Weave network (not encrypted if empty)
+	WeaveConfig            weave.Config // Weave Net router image and version (upstream images if not set)
 
 	// Cluster storage
 	UseZookeeperClusterStorage bool
+
+	// port of the Engines advertised to the cluster storage ('cluster-advertise' option), the Docker Engine port (2376) if zero
+	ClusterAdvertisePort int
+
+	// restart policy of the infrastructure containers started by docker-g5k (registry, Zookeeper, Weave Discovery), 'always' if empty
+	InfraRestartPolicy string
+
+	// user namespace remapping of the Docker Engines ('default' or user[:group], disabled if empty)
+	UsernsRemap string
+
+	// Linux capabilities added to the infrastructure containers started by docker-g5k (registry, Zookeeper, Weave Discovery, ingress)
+	InfraContainerCaps []string
+
+	// signal and grace period used to stop the infrastructure containers (image and Docker default if not set)
+	InfraStopSignal  string
+	InfraStopTimeout time.Duration
+
+	// Linux capabilities added by default to the workload containers (written on the nodes for the containers launch)
+	ContainerDefaultCaps []string
+
+	// credentials of the private registries (by registry hostname), written to the Docker client configuration of the nodes
+	RegistryAuths map[string]RegistryAuth
+
+	// reuse the existing server certificate of the nodes (if still valid for the node) instead of the one generated at each machine creation
+	ReuseExistingCerts bool
+
+	// local paths of the CA bundles (PEM) installed in the system trust store of the nodes and trusted by the Engine for the private registries
+	TrustedCABundles []string
+
+	// Registry mirror
+	DeployRegistry         bool
+	RegistryNode           string
+	RegistryProxyRemoteURL string // run the registry as a pull-through cache of this remote registry (optional)
+
+	// ingress controller routing the labeled containers/services, run on one node (disabled if nil)
+	DeployIngress *ingress.Spec
+
+	// bastion node running a SOCKS proxy and allowing the SSH forwarding to reach the overlay-only services (disabled if empty)
+	BastionNode string
+	BastionPort int // port of the SOCKS proxy published on the bastion node (default if zero)
 }
 
 // GenerateSSHKeyPair generate a new global SSH key
@@ -54,17 +298,380 @@ func (c *GlobalConfig) GenerateSSHKeyPair() error {
 	return nil
 }
 
+// InfraStopConfig returns the stop signal and timeout of the infrastructure containers
+func (c *GlobalConfig) InfraStopConfig() container.StopConfig {
+	return container.StopConfig{Signal: c.InfraStopSignal, Timeout: c.InfraStopTimeout}
+}
+
+// engineClusterStore returns the k/v store of the Engines 'cluster-store' option (the deployed Zookeeper or the external Swarm discovery, empty if none)
+func (c *GlobalConfig) engineClusterStore() string {
+	if c.SwarmStandaloneGlobalConfig == nil {
+		return ""
+	}
+
+	if c.SwarmStandaloneGlobalConfig.ExternalDiscoveryURL != "" {
+		return c.SwarmStandaloneGlobalConfig.ExternalDiscoveryURL
+	}
+
+	if c.UseZookeeperClusterStorage {
+		return c.SwarmStandaloneGlobalConfig.Discovery
+	}
+
+	return ""
+}
+
+// clusterAdvertisePort returns the port of the Engines 'cluster-advertise' option
+// the option advertise the Engine (not the k/v store) to the other Engines, so the port is the same for all the storage backends
+func (c *GlobalConfig) clusterAdvertisePort() int {
+	if c.ClusterAdvertisePort != 0 {
+		return c.ClusterAdvertisePort
+	}
+
+	return engineTLSPort
+}
+
+// validateSwarmParadigm check Swarm standalone and Swarm mode are not both enabled (the nodes would run both)
+func (c *GlobalConfig) validateSwarmParadigm() error {
+	if c.SwarmStandaloneGlobalConfig != nil && c.SwarmModeGlobalConfig != nil {
+		return ErrSwarmConflict
+	}
+
+	return nil
+}
+
+// Validate check the cluster global configuration
+func (c *GlobalConfig) Validate() error {
+	// check only one Swarm paradigm is enabled
+	if err := c.validateSwarmParadigm(); err != nil {
+		return err
+	}
+
+	// check log rotation
+	if err := c.LogRotation.Validate(); err != nil {
+		return err
+	}
+
+	// check placement policy
+	if err := c.PlacementPolicy.Validate(); err != nil {
+		return err
+	}
+
+	// check provisioning phases timeout
+	if err := validatePhaseTimeouts(c.PhaseTimeouts); err != nil {
+		return err
+	}
+
+	// check minimum number of provisioned nodes
+	if c.MinSuccessfulNodes < 0 {
+		return fmt.Errorf("Invalid minimum number of successful nodes: %d", c.MinSuccessfulNodes)
+	}
+
+	// check provisioning deadline
+	if c.ProvisionDeadline < 0 {
+		return fmt.Errorf("The provisioning deadline must be positive: '%s'", c.ProvisionDeadline)
+	}
+
+	// check host cache
+	if c.HostCacheSize < 0 {
+		return fmt.Errorf("Invalid host cache size: %d", c.HostCacheSize)
+	}
+	if c.HostCacheIdleTimeout < 0 {
+		return fmt.Errorf("Invalid host cache idle timeout: %s", c.HostCacheIdleTimeout)
+	}
+
+	// check fleet operations concurrency
+	if c.FleetConcurrency < 0 {
+		return fmt.Errorf("Invalid fleet operations concurrency: %d", c.FleetConcurrency)
+	}
+
+	// check provisioning plugins
+	if err := validatePlugins(c.NodePlugins); err != nil {
+		return err
+	}
+	if err := validatePlugins(c.ClusterPlugins); err != nil {
+		return err
+	}
+
+	// check kernel parameters
+	if err := validateSysctls(c.CommonSysctls); err != nil {
+		return err
+	}
+
+	// check Swarm mode data path kernel parameters
+	if c.SwarmModeGlobalConfig != nil {
+		if err := validateSysctls(c.SwarmModeGlobalConfig.DataPathTuning.Sysctls); err != nil {
+			return err
+		}
+	}
+
+	// check supervisor options
+	if c.MaxRepairs < 0 || c.RepairInterval < 0 {
+		return fmt.Errorf("The maximum number of repairs and the repair interval need to be positive")
+	}
+
+	// check containers stop grace period
+	if c.GracefulContainerStop < 0 {
+		return fmt.Errorf("Invalid containers stop grace period: %s", c.GracefulContainerStop)
+	}
+
+	// check deprovision timeout
+	if c.DeprovisionTimeout < 0 {
+		return fmt.Errorf("The deprovision timeout needs to be positive: '%s'", c.DeprovisionTimeout)
+	}
+
+	// check Docker data root
+	if err := validateDataRoot(c.DataRoot, c.DataRootDevice, c.DataRootMinFree); err != nil {
+		return err
+	}
+	if err := c.validateDataRootPersistence(); err != nil {
+		return err
+	}
+
+	// check bridge subnet
+	if c.BridgeSubnet != "" {
+		if err := validateBridgeSubnet(c.BridgeSubnet); err != nil {
+			return err
+		}
+	}
+
+	// check the service discovery subnet does not overlap the bridge subnet
+	if err := c.validateServiceDiscoverySubnet(); err != nil {
+		return err
+	}
+
+	// check fallback sites
+	if err := validateSiteFallbacks(c.SiteFallbacks); err != nil {
+		return err
+	}
+
+	// check core-hours quota
+	if err := validateCoreHoursQuota(c.CoreHoursQuota); err != nil {
+		return err
+	}
+	if c.MaxCoreHours < 0 {
+		return fmt.Errorf("The maximum core-hours must be positive: '%v'", c.MaxCoreHours)
+	}
+
+	// check containers DNS options
+	if err := validateDNSOptions(c.ContainerDNSOptions); err != nil {
+		return err
+	}
+
+	// check Docker Engine service overrides
+	if err := validateSystemdOverrides(c.EngineSystemdOverrides); err != nil {
+		return err
+	}
+
+	// check Docker Engine pull proxy
+	if c.EnginePullProxy != "" {
+		if err := validatePullProxy(c.EnginePullProxy); err != nil {
+			return err
+		}
+	}
+
+	// check cluster ID
+	if c.ClusterID != "" {
+		if err := validateClusterID(c.ClusterID); err != nil {
+			return err
+		}
+	}
+
+	// check annotations
+	if err := validateAnnotations(c.Annotations); err != nil {
+		return err
+	}
+
+	// check Engines cluster advertise port
+	if c.ClusterAdvertisePort < 0 || c.ClusterAdvertisePort > 65535 {
+		return fmt.Errorf("Invalid cluster advertise port: %d", c.ClusterAdvertisePort)
+	}
+
+	// check machine storage
+	if err := c.validateMachineStorage(); err != nil {
+		return err
+	}
+
+	// check userland proxy and iptables configuration
+	for _, w := range c.networkWarnings() {
+		log.Warn(w)
+	}
+
+	// check infrastructure containers restart policy
+	if c.InfraRestartPolicy != "" {
+		if err := container.ValidateRestartPolicy(c.InfraRestartPolicy); err != nil {
+			return err
+		}
+	}
+
+	// check infrastructure containers stop signal and timeout
+	if err := c.InfraStopConfig().Validate(); err != nil {
+		return fmt.Errorf("Invalid infrastructure containers stop configuration: %s", err)
+	}
+
+	// check user namespace remapping
+	if c.UsernsRemap != "" {
+		if err := validateUsernsRemap(c.UsernsRemap); err != nil {
+			return err
+		}
+
+		for _, w := range c.usernsWarnings() {
+			log.Warn(w)
+		}
+	}
+
+	// check containers capabilities
+	if err := container.ValidateCapabilities(c.InfraContainerCaps); err != nil {
+		return fmt.Errorf("Invalid infrastructure containers capability: %s", err)
+	}
+	if err := container.ValidateCapabilities(c.ContainerDefaultCaps); err != nil {
+		return fmt.Errorf("Invalid containers default capability: %s", err)
+	}
+
+	// check OCI runtimes
+	if err := validateOCIRuntimes(c.OCIRuntime, c.RuntimeBinaries); err != nil {
+		return err
+	}
+
+	// check hardware labels rules
+	if c.HardwareLabels {
+		if c.SwarmModeGlobalConfig == nil {
+			return fmt.Errorf("The hardware labels are only supported with Swarm mode")
+		}
+
+		for _, r := range c.HardwareLabelRules {
+			if err := r.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// check ingress configuration
+	if c.DeployIngress != nil {
+		if err := c.DeployIngress.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// check bastion configuration
+	if c.BastionNode != "" {
+		if err := bastion.ValidatePort(c.BastionPort); err != nil {
+			return err
+		}
+	}
+
+	// check Weave Net images
+	if err := c.WeaveConfig.Validate(); err != nil {
+		return err
+	}
+
+	// check Weave encryption password
+	if err := weave.ValidatePassword(c.WeavePassword); err != nil {
+		return err
+	}
+
+	// check private registries credentials
+	for _, registry := range c.sortedRegistries() {
+		a := c.RegistryAuths[registry]
+		if err := a.Validate(registry); err != nil {
+			return err
+		}
+	}
+
+	// check Engine log level
+	if err := validateEngineLogLevel(c.EngineLogLevel); err != nil {
+		return err
+	}
+
+	// check default shm size
+	if err := validateDefaultShmSize(c.DefaultShmSize); err != nil {
+		return err
+	}
+
+	// check logging mode
+	if err := validateLogLevel(c.LogLevel); err != nil {
+		return err
+	}
+
+	// check Engine metrics address
+	if c.EngineMetricsAddr != "" {
+		if err := validateMetricsAddr(c.EngineMetricsAddr, c.EngineExperimental); err != nil {
+			return err
+		}
+	}
+
+	// check default images platform
+	if c.DefaultPlatform != "" {
+		if err := container.ValidatePlatform(c.DefaultPlatform); err != nil {
+			return err
+		}
+	}
+
+	// check default container network
+	if c.DefaultContainerNetwork != "" {
+		if err := validateContainerNetwork(c.DefaultContainerNetwork); err != nil {
+			return err
+		}
+	}
+
+	// check Swarm mode configuration
+	if c.SwarmModeGlobalConfig != nil {
+		if err := c.SwarmModeGlobalConfig.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// check Docker API version
+	if c.EngineAPIVersion != "" {
+		if err := validateEngineAPIVersion(c.EngineAPIVersion); err != nil {
+			return err
+		}
+	}
+
+	// check OAR queue
+	if c.OARQueue != "" {
+		if err := g5k.ValidateJobQueue(c.OARQueue); err != nil {
+			return err
+		}
+	}
+
+	// check deployment mode
+	if err := validateDeployMode(c.DeployMode); err != nil {
+		return err
+	}
+
+	// check trusted CA bundles
+	if _, err := readCABundles(c.TrustedCABundles); err != nil {
+		return err
+	}
+
+	// check security profiles
+	if c.SeccompProfilePath != "" {
+		if _, err := security.ReadSeccompProfile(c.SeccompProfilePath); err != nil {
+			return err
+		}
+	}
+	if c.AppArmorProfile != "" {
+		if _, err := security.ReadAppArmorProfile(c.AppArmorProfile); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Cluster represents the cluster
 type Cluster struct {
-	Config *GlobalConfig
-	Nodes  map[string]*Node
+	Config        *GlobalConfig
+	Nodes         map[string]*Node
+	ExternalHosts map[string]*ExternalHost
 }
 
 // NewCluster create a new cluster using the given configuration
 func NewCluster(config *GlobalConfig) *Cluster {
 	return &Cluster{
-		Config: config,
-		Nodes:  make(map[string]*Node),
+		Config:        config,
+		Nodes:         make(map[string]*Node),
+		ExternalHosts: make(map[string]*ExternalHost),
 	}
 }
 
@@ -95,6 +702,7 @@ func (c *Cluster) AllocateDeployedNodesToMachines(site string, jobID int, deploy
 		// set driver parameters
 		c.Nodes[machineName].NodeName = n
 		c.Nodes[machineName].G5kJobID = jobID
+		c.Nodes[machineName].OwnsJob = true
 
 		// lookup IP address of the node for static lookup table
 		ip, err := net.LookupIP(n)
@@ -109,41 +717,376 @@ func (c *Cluster) AllocateDeployedNodesToMachines(site string, jobID int, deploy
 	return nil
 }
 
-// ProvisionNodes provision the nodes in the cluster (in parallel)
+// ResolveAdvertiseInterfaces set the advertised interface of the site nodes to their network adapter satisfying the network requirement
+func (c *Cluster) ResolveAdvertiseInterfaces(g5kAPI *g5k.G5K, site string) error {
+	// keep the default interface if there is no network requirement
+	if c.Config.NetworkRequirement == nil {
+		return nil
+	}
+
+	for _, n := range c.Nodes {
+		if n.G5kSite != site {
+			continue
+		}
+
+		// get node description from the Reference API
+		refNode, err := g5kAPI.GetReferenceNode(site, n.NodeName)
+		if err != nil {
+			return fmt.Errorf("Unable to get the description of node '%s': '%s'", n.NodeName, err)
+		}
+
+		device, err := c.Config.NetworkRequirement.MatchingDevice(refNode)
+		if err != nil {
+			return err
+		}
+
+		n.AdvertiseInterface = device
+	}
+
+	return nil
+}
+
+// configureSwarmStandaloneDiscovery generate the Swarm standalone discovery URL for the selected backend (deploying the needed k/v store if no address is given)
+func (c *Cluster) configureSwarmStandaloneDiscovery() error {
+	gc := c.Config.SwarmStandaloneGlobalConfig
+
+	backend, err := gc.GetDiscoveryBackend()
+	if err != nil {
+		return err
+	}
+
+	// the external k/v store is never deployed
+	if gc.ExternalDiscoveryURL != "" {
+		log.Infof("The external Swarm discovery '%s' is used as cluster storage", gc.ExternalDiscoveryURL)
+	}
+
+	// generate discovery URL for the backends that can be managed by docker-g5k
+	if gc.Discovery == "" && gc.ExternalDiscoveryURL == "" {
+		switch backend {
+		case swarm.DiscoveryBackendZookeeper:
+			log.Info("No Swarm cluster storage defined, Zookeeper will be deployed on each master nodes")
+
+			// enable Zookeeper
+			c.Config.UseZookeeperClusterStorage = true
+
+			// set discovery string with zookeeper url
+			gc.Discovery = zookeeper.GenerateClusterStorageURL(c.Config.SwarmMasterNode, c.Config.HostsLookupTable)
+
+		case swarm.DiscoveryBackendNodes:
+			// set discovery string with all nodes address
+			nodes := []string{}
+			for machineName := range c.Nodes {
+				nodes = append(nodes, machineName)
+			}
+			sort.Strings(nodes)
+			gc.Discovery = swarm.GenerateNodesDiscoveryURL(nodes, c.Config.HostsLookupTable)
+		}
+	}
+
+	return gc.ConfigureDiscovery()
+}
+
+// waitForClusterStorage wait for the Zookeeper servers of the provisioned Swarm masters (not in the errors) to be part of an ensemble with a leader, in parallel and within the Zookeeper phase timeout
+// the servers are waited for once all the masters started theirs: the ensemble can't elect a leader before a quorum of servers is running
+func (c *Cluster) waitForClusterStorage(errs map[string]error, waitForLeader func(h *host.Host, timeout time.Duration) error) error {
+	masters := []string{}
+	for _, machineName := range c.Config.SwarmMasterNode {
+		if _, failed := errs[machineName]; !failed {
+			masters = append(masters, machineName)
+		}
+	}
+
+	if len(masters) == 0 {
+		return nil
+	}
+
+	log.Info("Waiting for the Zookeeper ensemble to elect a leader...")
+	timeout := c.Config.PhaseTimeout(PhaseZookeeper)
+	zkErrs := c.runOnSelectedNodes(&NodeSelector{Names: masters}, timeout, func(n *Node, h *host.Host) error {
+		if err := waitForLeader(h, timeout); err != nil {
+			return n.wrapError(ErrZookeeper, err)
+		}
+		return nil
+	})
+
+	for machineName, err := range zkErrs {
+		errs[machineName] = err
+	}
+
+	return fleetError("Zookeeper leader election", zkErrs)
+}
+
+// validateWeaveConnectors check the Weave connectors are nodes of the cluster with a known IP address
+func (c *Cluster) validateWeaveConnectors() error {
+	for _, connector := range c.Config.WeaveConnectors {
+		if _, ok := c.Nodes[connector]; !ok {
+			return fmt.Errorf("The Weave connector '%s' is not a node of the cluster", connector)
+		}
+
+		if _, ok := c.Config.HostsLookupTable[connector]; !ok {
+			return fmt.Errorf("The Weave connector '%s' has no known IP address", connector)
+		}
+	}
+
+	return nil
+}
+
+// configureRegistry check the registry node and add it to the static lookup table
+func (c *Cluster) configureRegistry() error {
+	if _, ok := c.Nodes[c.Config.RegistryNode]; !ok {
+		return fmt.Errorf("The registry node '%s' is not a node of the cluster", c.Config.RegistryNode)
+	}
+
+	ip, ok := c.Config.HostsLookupTable[c.Config.RegistryNode]
+	if !ok {
+		return fmt.Errorf("The registry node '%s' has no known IP address", c.Config.RegistryNode)
+	}
+
+	// set IP address of the registry in the static lookup table
+	c.Config.HostsLookupTable[registry.Hostname] = ip
+
+	return nil
+}
+
+// configureIngress check the ingress node (a Swarm manager in Swarm mode) and add it to the static lookup table
+func (c *Cluster) configureIngress() error {
+	n, ok := c.Nodes[c.Config.DeployIngress.Node]
+	if !ok {
+		return fmt.Errorf("The ingress node '%s' is not a node of the cluster", c.Config.DeployIngress.Node)
+	}
+
+	// the ingress controller needs the manager API to route the Swarm mode services
+	if c.Config.SwarmModeGlobalConfig != nil && !n.isSwarmMaster() {
+		return fmt.Errorf("The ingress node '%s' must be a Swarm manager", n.MachineName)
+	}
+
+	ip, ok := c.Config.HostsLookupTable[n.MachineName]
+	if !ok {
+		return fmt.Errorf("The ingress node '%s' has no known IP address", n.MachineName)
+	}
+
+	// set IP address of the ingress in the static lookup table
+	c.Config.HostsLookupTable[ingress.Hostname] = ip
+
+	return nil
+}
+
+// configureBastion check the bastion node (a Swarm manager in Swarm mode) is a reserved node of the cluster
+func (c *Cluster) configureBastion() error {
+	n, ok := c.Nodes[c.Config.BastionNode]
+	if !ok {
+		return fmt.Errorf("The bastion node '%s' is not a node of the cluster", c.Config.BastionNode)
+	}
+
+	// the bastion overlay network can only be created by a manager
+	if c.Config.SwarmModeGlobalConfig != nil && !n.isSwarmMaster() {
+		return fmt.Errorf("The bastion node '%s' must be a Swarm manager", n.MachineName)
+	}
+
+	// the clients reach the bastion by its Grid5000 hostname
+	if n.NodeName == "" {
+		return fmt.Errorf("The bastion node '%s' has no known hostname", n.MachineName)
+	}
+
+	if _, ok := c.Config.HostsLookupTable[n.MachineName]; !ok {
+		return fmt.Errorf("The bastion node '%s' has no known IP address", n.MachineName)
+	}
+
+	return nil
+}
+
+// ProvisionNodes provision the nodes in the cluster (in parallel), the nodes failing to provision (except Swarm masters/managers) are only logged
+// all the nodes are released if the provisioning deadline is exceeded
 func (c *Cluster) ProvisionNodes() error {
-	// if Swarm standalone is enabled, and no discovery method provided, deploy a Zookeeper instance for the cluster
-	if (c.Config.SwarmStandaloneGlobalConfig != nil) && (c.Config.SwarmStandaloneGlobalConfig.Discovery == "") {
-		log.Info("No Swarm cluster storage defined, Zookeeper will be deployed on each master nodes")
+	return c.provisionWithDeadline(false)
+}
+
+// provisionNodes provision the nodes in the cluster (in parallel), returning an error if any node fails when strict is set
+// the result of each node provisioning is recorded in the progress (if not nil), the provisioning stops between its phases when the progress is canceled
+func (c *Cluster) provisionNodes(strict bool, progress *provisionProgress) error {
+	// check cluster configuration
+	if err := c.Config.Validate(); err != nil {
+		return err
+	}
+
+	// set the libmachine/driver output to the logging mode
+	c.Config.ConfigureLogging()
+
+	// create the machine storage directories of the cluster
+	if err := c.Config.prepareMachineStorage(); err != nil {
+		return err
+	}
+
+	// check nodes role
+	c.SyncNodeRoles()
+	c.syncClusterNodes()
+	if err := c.validateNodeRoles(); err != nil {
+		return err
+	}
+
+	// check minimum number of provisioned nodes
+	if err := c.validateMinSuccessfulNodes(); err != nil {
+		return err
+	}
+
+	// check nodes kernel parameters
+	for _, n := range c.Nodes {
+		if err := validateSysctls(n.Sysctls); err != nil {
+			return fmt.Errorf("Node '%s': %s", n.MachineName, err)
+		}
+	}
+
+	// check nodes firewall rules
+	for _, n := range c.Nodes {
+		if err := validateFirewallRules(n.FirewallRules); err != nil {
+			return fmt.Errorf("Node '%s': %s", n.MachineName, err)
+		}
+	}
+
+	// check nodes deployment mode
+	if err := c.validateDeployModes(); err != nil {
+		return err
+	}
+
+	// check the labels of the Swarm mode services placement preferences
+	if err := c.validatePlacementPreferences(); err != nil {
+		return err
+	}
 
-		// enable Zookeeper
-		c.Config.UseZookeeperClusterStorage = true
+	// check nodes images platform
+	if err := c.validateNodePlatforms(); err != nil {
+		return err
+	}
+
+	// only the minimum number of nodes is required in degraded mode (never in strict mode)
+	degraded := !strict && c.Config.MinSuccessfulNodes > 0
+
+	// configure nodes aliases
+	if err := c.configureHostsAliases(); err != nil {
+		return err
+	}
 
-		// set discovery string with zookeeper url
-		c.Config.SwarmStandaloneGlobalConfig.Discovery = zookeeper.GenerateClusterStorageURL(c.Config.SwarmMasterNode, c.Config.HostsLookupTable)
+	// wait for the nodes SSH (unreachable nodes are only logged, their provisioning will fail, unless strict is set)
+	if c.Config.SSHWaitTimeout > 0 {
+		if err := c.waitForNodesSSH(); err != nil && strict {
+			return err
+		}
+	}
+
+	// configure registry mirror
+	if c.Config.DeployRegistry {
+		if err := c.configureRegistry(); err != nil {
+			return err
+		}
+	}
+
+	// configure ingress
+	if c.Config.DeployIngress != nil {
+		if err := c.configureIngress(); err != nil {
+			return err
+		}
+	}
+
+	// configure bastion
+	if c.Config.BastionNode != "" {
+		if err := c.configureBastion(); err != nil {
+			return err
+		}
+	}
+
+	// check Weave connectors
+	if c.Config.WeaveNetworkingEnabled {
+		if err := c.validateWeaveConnectors(); err != nil {
+			return err
+		}
+	}
+
+	// configure Swarm standalone discovery
+	if c.Config.SwarmStandaloneGlobalConfig != nil {
+		if err := c.configureSwarmStandaloneDiscovery(); err != nil {
+			return err
+		}
 	}
 
 	// provision Swarm master/manager nodes (sequential)
-	for _, k := range c.Config.SwarmMasterNode {
+	errs := make(map[string]error)
+
+	// release the jobs of the nodes whose creation is stuck (all the jobs are released by the caller in strict mode, or when the deadline is exceeded)
+	if !strict {
+		defer func() {
+			if !progress.isCanceled() {
+				c.releaseStuckJobs(errs)
+			}
+		}()
+	}
+
+	// select the site of the Swarm mode bootstrap manager
+	if c.Config.SwarmModeGlobalConfig != nil {
+		if err := c.configureBootstrapSite(nil); err != nil {
+			return err
+		}
+	}
+
+	for i, k := range c.Config.SwarmMasterNode {
+		// stop before the next node when the deadline is exceeded
+		if progress.isCanceled() {
+			return ErrDeadline
+		}
+
 		log.Infof("Provisionning Swarm master/manager node '%s' ('%s')...", c.Nodes[k].NodeName, c.Nodes[k].MachineName)
 
-		// error in Swarm master provisionning is fatal
-		if err := c.Nodes[k].Provision(); err != nil {
-			return fmt.Errorf("Error while provisionning Swarm master/manager node '%s' ('%s'): '%s'", c.Nodes[k].NodeName, c.Nodes[k].MachineName, err)
+		// error in Swarm master provisionning is fatal (except for the non-bootstrap masters in degraded mode)
+		err := c.Nodes[k].Provision()
+		progress.record(k, err)
+		if err != nil {
+			if !degraded || i == 0 {
+				errs[k] = err
+				return fmt.Errorf("Error while provisionning Swarm master/manager node '%s': %w", c.Nodes[k].NodeName, err)
+			}
+
+			log.Errorf("Error while provisionning Swarm master/manager node '%s': '%s'\n", c.Nodes[k].NodeName, err)
+			errs[k] = err
+		}
+	}
+
+	// wait for the Zookeeper ensemble to elect a leader, once all the Swarm masters started their server
+	if c.Config.SwarmStandaloneGlobalConfig != nil && c.Config.UseZookeeperClusterStorage {
+		if err := c.waitForClusterStorage(errs, zookeeper.WaitForLeader); err != nil {
+			return err
+		}
+	}
+
+	// the successful Swarm mode managers needs to reach the quorum
+	if degraded && c.Config.SwarmModeGlobalConfig != nil {
+		managers := len(c.Config.SwarmMasterNode)
+		if succeeded := managers - len(errs); succeeded < managers/2+1 {
+			return fmt.Errorf("Only %d/%d Swarm managers provisioned, the quorum (%d) is not reached: %w", succeeded, managers, managers/2+1, fleetError("Provisioning", errs))
 		}
 	}
 
+	if progress.isCanceled() {
+		return ErrDeadline
+	}
+
 	log.Info("Provisionning nodes, it will take a few minutes...")
 
 	// provision all deployed nodes (parallel)
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 	for _, n := range c.Nodes {
 		// skip already provisionned Swarm master/manager
 		if !n.isSwarmMaster() {
 			wg.Add(1)
 			go func(n *Node) {
 				defer wg.Done()
-				if err := n.Provision(); err != nil {
-					log.Errorf("Error while provisionning node '%s' ('%s'): '%s'\n", n.NodeName, n.MachineName, err)
+				err := n.Provision()
+				progress.record(n.MachineName, err)
+				if err != nil {
+					log.Errorf("Error while provisionning node '%s': '%s'\n", n.NodeName, err)
+
+					mu.Lock()
+					errs[n.MachineName] = err
+					mu.Unlock()
 				}
 			}(n)
 		}
@@ -152,5 +1095,226 @@ func (c *Cluster) ProvisionNodes() error {
 	// wait nodes provisionning to finish
 	wg.Wait()
 
+	if progress.isCanceled() {
+		return ErrDeadline
+	}
+
+	// all nodes are required in strict mode
+	if strict && len(errs) > 0 {
+		return fleetError("Provisioning", errs)
+	}
+
+	// the minimum number of nodes is required in degraded mode
+	if degraded {
+		succeeded := c.provisioningSummary(errs)
+		if succeeded < c.Config.MinSuccessfulNodes {
+			return fmt.Errorf("Only %d/%d nodes provisioned (minimum: %d): %w", succeeded, len(c.Nodes), c.Config.MinSuccessfulNodes, fleetError("Provisioning", errs))
+		}
+	}
+
+	// wait for the Swarm mode managers to reach the quorum
+	if c.Config.SwarmModeGlobalConfig != nil && c.Config.RequireQuorumOnProvision {
+		if err := c.waitForManagersQuorum(); err != nil {
+			return err
+		}
+	}
+
+	// check the Swarm mode cluster can run workloads
+	if c.Config.SwarmModeGlobalConfig != nil && c.Config.SmokeTestOnProvision {
+		if _, err := c.SmokeTest(); err != nil {
+			return err
+		}
+	}
+
+	// apply the cluster plugins
+	if err := c.applyClusterPlugins(errs); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateMinSuccessfulNodes check the minimum number of provisioned nodes is reachable and keeps the Swarm mode managers quorum
+func (c *Cluster) validateMinSuccessfulNodes() error {
+	if c.Config.MinSuccessfulNodes == 0 {
+		return nil
+	}
+
+	if c.Config.MinSuccessfulNodes > len(c.Nodes) {
+		return fmt.Errorf("The minimum number of successful nodes (%d) exceeds the number of nodes of the cluster (%d)", c.Config.MinSuccessfulNodes, len(c.Nodes))
+	}
+
+	if c.Config.SwarmModeGlobalConfig != nil {
+		if quorum := len(c.Config.SwarmMasterNode)/2 + 1; c.Config.MinSuccessfulNodes < quorum {
+			return fmt.Errorf("The minimum number of successful nodes (%d) is lower than the Swarm managers quorum (%d)", c.Config.MinSuccessfulNodes, quorum)
+		}
+	}
+
 	return nil
 }
+
+// provisioningSummary log the provisioned and failed nodes (by machine name) and returns the number of provisioned nodes
+func (c *Cluster) provisioningSummary(errs map[string]error) int {
+	succeeded := []string{}
+	failed := []string{}
+	for machineName := range c.Nodes {
+		if _, ok := errs[machineName]; ok {
+			failed = append(failed, machineName)
+		} else {
+			succeeded = append(succeeded, machineName)
+		}
+	}
+	sort.Strings(succeeded)
+	sort.Strings(failed)
+
+	log.Infof("%d/%d nodes provisioned: %s", len(succeeded), len(c.Nodes), strings.Join(succeeded, ", "))
+	if len(failed) > 0 {
+		log.Warnf("%d/%d nodes failed to provision: %s", len(failed), len(c.Nodes), strings.Join(failed, ", "))
+	}
+
+	return len(succeeded)
+}
+
+// waitForManagersQuorum wait until a majority of the Swarm mode managers joined the cluster
+func (c *Cluster) waitForManagersQuorum() error {
+	log.Info("Waiting for the Swarm managers to reach the quorum...")
+
+	// use default timeout if none is given
+	timeout := c.Config.QuorumTimeout
+	if timeout <= 0 {
+		timeout = defaultQuorumTimeout
+	}
+
+	// the first Swarm master is the bootstrap manager
+	h, err := c.Nodes[c.Config.SwarmMasterNode[0]].loadHost()
+	if err != nil {
+		return err
+	}
+
+	return c.Config.SwarmModeGlobalConfig.WaitForManagersQuorum(h, len(c.Config.SwarmMasterNode), timeout)
+}
+
+// SmokeTest run the smoke test service on the Swarm mode cluster and log the tasks placement
+func (c *Cluster) SmokeTest() (*swarm.SmokeTestResult, error) {
+	log.Info("Running the Swarm mode smoke test service...")
+
+	h, err := c.swarmManager()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.Config.SwarmModeGlobalConfig.SmokeTest(h)
+	if result != nil {
+		for _, p := range result.Placements {
+			if p.Reachable {
+				log.Infof("Smoke test task '%s' running on node '%s' is reachable at '%s'", p.TaskID, p.Node, p.Address)
+			} else {
+				log.Errorf("Smoke test task '%s' running on node '%s' failed: %s", p.TaskID, p.Node, p.Error)
+			}
+		}
+	}
+
+	return result, err
+}
+
+// VerifyOverlayConnectivity run the overlay connectivity check between the nodes of the Swarm mode cluster and log the unreachable links
+func (c *Cluster) VerifyOverlayConnectivity() (*swarm.ConnectivityMatrix, error) {
+	log.Info("Checking the overlay connectivity between the Swarm mode nodes...")
+
+	h, err := c.swarmManager()
+	if err != nil {
+		return nil, err
+	}
+
+	matrix, err := c.Config.SwarmModeGlobalConfig.VerifyOverlayConnectivity(h)
+	if matrix != nil {
+		for _, p := range matrix.UnreachablePairs() {
+			log.Errorf("Overlay link '%s' is unreachable", p)
+		}
+	}
+
+	return matrix, err
+}
+
+// UpdateService run a rolling update of the service image through a reachable manager and log the observed tasks timeline
+func (c *Cluster) UpdateService(name string, image string, opts swarm.UpdateOpts) (*swarm.UpdateResult, error) {
+	h, err := c.swarmManager()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Updating the service '%s' to the image '%s'...", name, image)
+
+	result, err := c.Config.SwarmModeGlobalConfig.UpdateService(h, name, image, opts)
+	if result != nil {
+		for _, e := range result.Events {
+			log.Debugf("Service '%s' task '%s' on node '%s' (%s): %s (desired: %s) %s", name, e.TaskID, e.Node, e.Image, e.State, e.DesiredState, e.Error)
+		}
+		log.Infof("Update of the service '%s' finished in %s (state: '%s', %d task event(s))", name, result.Finished.Sub(result.Started), result.State, len(result.Events))
+	}
+
+	return result, err
+}
+
+// UpdateSwarmConfig apply the orchestration options to the live Swarm mode cluster through a reachable manager (the unset options are not modified)
+func (c *Cluster) UpdateSwarmConfig(opts swarm.OrchestrationOpts) error {
+	h, err := c.swarmManager()
+	if err != nil {
+		return err
+	}
+
+	if err := c.Config.SwarmModeGlobalConfig.UpdateSwarmConfig(h, opts); err != nil {
+		return err
+	}
+
+	log.Infof("Swarm mode orchestration options updated:%s", opts.String())
+	return nil
+}
+
+// RotateSwarmCA rotate the root CA of the Swarm mode cluster through a reachable manager and wait for all the nodes to get their new certificate
+func (c *Cluster) RotateSwarmCA() (*swarm.CARotationReport, error) {
+	h, err := c.swarmManager()
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := c.Config.SwarmModeGlobalConfig.RotateCA(h)
+	if err != nil {
+		return report, err
+	}
+
+	log.Infof("Swarm mode root CA rotated in %s, %d node(s) with a new certificate", report.Duration, len(report.Nodes))
+	return report, nil
+}
+
+// CreateSecret create the secret on the Swarm mode cluster through a reachable manager (kept as is if it already exists) and returns its ID
+func (c *Cluster) CreateSecret(name string, data []byte) (string, error) {
+	h, err := c.swarmManager()
+	if err != nil {
+		return "", err
+	}
+
+	id, err := c.Config.SwarmModeGlobalConfig.CreateSecret(h, name, data)
+	if err != nil {
+		return "", err
+	}
+
+	log.Infof("Swarm secret '%s' is available (ID: '%s')", name, id)
+	return id, nil
+}
+
+// CreateConfig create the config on the Swarm mode cluster through a reachable manager (kept as is if it already exists) and returns its ID
+func (c *Cluster) CreateConfig(name string, data []byte) (string, error) {
+	h, err := c.swarmManager()
+	if err != nil {
+		return "", err
+	}
+
+	id, err := c.Config.SwarmModeGlobalConfig.CreateConfig(h, name, data)
+	if err != nil {
+		return "", err
+	}
+
+	log.Infof("Swarm config '%s' is available (ID: '%s')", name, id)
+	return id, nil
+}
@@ -0,0 +1,166 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+)
+
+func TestValidateSwarmParadigm(t *testing.T) {
+	assert.NoError(t, (&GlobalConfig{}).validateSwarmParadigm())
+	assert.NoError(t, (&GlobalConfig{SwarmModeGlobalConfig: &swarm.SwarmModeGlobalConfig{}}).validateSwarmParadigm())
+	assert.NoError(t, (&GlobalConfig{SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{}}).validateSwarmParadigm())
+}
+
+func TestValidateSwarmParadigmConflict(t *testing.T) {
+	c := &GlobalConfig{SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{}, SwarmModeGlobalConfig: &swarm.SwarmModeGlobalConfig{}}
+	assert.Equal(t, ErrSwarmConflict, c.Validate())
+
+	// the cluster provisioning is refused before provisioning any node
+	assert.True(t, errors.Is(NewCluster(c).ProvisionNodes(), ErrSwarmConflict))
+}
+
+func TestEngineClusterStore(t *testing.T) {
+	assert.Equal(t, "", (&GlobalConfig{}).engineClusterStore())
+	assert.Equal(t, "", (&GlobalConfig{SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{Discovery: "consul://10.0.0.1:8500"}}).engineClusterStore())
+
+	config := &GlobalConfig{SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{Discovery: "zk://10.0.0.1:2181"}, UseZookeeperClusterStorage: true}
+	assert.Equal(t, "zk://10.0.0.1:2181", config.engineClusterStore())
+
+	config = &GlobalConfig{SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{ExternalDiscoveryURL: "etcd://10.0.0.1:2379/swarm"}}
+	assert.Equal(t, "etcd://10.0.0.1:2379/swarm", config.engineClusterStore())
+}
+
+func TestClusterAdvertiseFlag(t *testing.T) {
+	// the Engine port is advertised whatever the storage backend
+	for _, store := range []string{"zk://10.0.0.1:2181", "etcd://10.0.0.1:2379/swarm", "consul://10.0.0.1:8500"} {
+		config := &GlobalConfig{SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{ExternalDiscoveryURL: store}}
+		assert.Equal(t, 2376, config.clusterAdvertisePort())
+	}
+
+	config := &GlobalConfig{SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{Discovery: "zk://10.0.0.1:2181"}, UseZookeeperClusterStorage: true}
+	n := &Node{clusterConfig: config, MachineName: "lille-0"}
+	assert.Contains(t, n.engineClusterStoreFlags(), "cluster-advertise=eth0:2376")
+
+	config.ClusterAdvertisePort = 12376
+	assert.Equal(t, []string{"cluster-advertise=eth0:12376", "cluster-store=zk://10.0.0.1:2181"}, n.engineClusterStoreFlags())
+
+	assert.Empty(t, (&Node{clusterConfig: &GlobalConfig{}}).engineClusterStoreFlags())
+}
+
+func TestValidateClusterAdvertisePort(t *testing.T) {
+	assert.NoError(t, (&GlobalConfig{ClusterAdvertisePort: 12376}).Validate())
+	assert.Error(t, (&GlobalConfig{ClusterAdvertisePort: -1}).Validate())
+	assert.Error(t, (&GlobalConfig{ClusterAdvertisePort: 65536}).Validate())
+}
+
+func TestConfigureSwarmStandaloneExternalDiscovery(t *testing.T) {
+	c := NewCluster(&GlobalConfig{SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{ExternalDiscoveryURL: "zk://10.0.0.1:2181/swarm"}, SwarmMasterNode: []string{"lille-0"}})
+	assert.NoError(t, c.configureSwarmStandaloneDiscovery())
+	assert.False(t, c.Config.UseZookeeperClusterStorage)
+	assert.Equal(t, "zk://10.0.0.1:2181/swarm", c.Config.SwarmStandaloneGlobalConfig.Discovery)
+}
+
+func TestValidateMinSuccessfulNodes(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0", "lille-1", "lille-2"}, map[string]string{"lille-0": "", "lille-1": "", "lille-2": "", "lille-3": "", "lille-4": ""})
+	assert.NoError(t, c.validateMinSuccessfulNodes())
+
+	c.Config.MinSuccessfulNodes = 2
+	assert.NoError(t, c.validateMinSuccessfulNodes())
+
+	// lower than the managers quorum
+	c.Config.MinSuccessfulNodes = 1
+	assert.Error(t, c.validateMinSuccessfulNodes())
+
+	// more than the cluster nodes
+	c.Config.MinSuccessfulNodes = 6
+	assert.Error(t, c.validateMinSuccessfulNodes())
+
+	c.Config.MinSuccessfulNodes = -1
+	assert.Error(t, c.Config.Validate())
+}
+
+func TestProvisioningSummary(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": "", "lille-1": "", "lille-2": ""})
+	assert.Equal(t, 3, c.provisioningSummary(nil))
+	assert.Equal(t, 1, c.provisioningSummary(map[string]error{"lille-1": errors.New("failed"), "lille-2": errors.New("failed")}))
+}
+
+func TestConfigureBastion(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleWorker})
+	c.Config.HostsLookupTable = map[string]string{"lille-0": "1.2.3.4"}
+
+	c.Config.BastionNode = "lille-2"
+	assert.Error(t, c.configureBastion())
+
+	// the bastion needs to be a manager in Swarm mode
+	c.Config.BastionNode = "lille-1"
+	assert.Error(t, c.configureBastion())
+
+	c.Config.BastionNode = "lille-0"
+	assert.Error(t, c.configureBastion())
+
+	c.Nodes["lille-0"].NodeName = "chetemi-1.lille.grid5000.fr"
+	assert.NoError(t, c.configureBastion())
+
+	assert.Equal(t, "chetemi-1.lille.grid5000.fr:1080", c.Nodes["lille-0"].inventory().BastionEndpoint)
+	assert.Equal(t, "", c.Nodes["lille-1"].inventory().BastionEndpoint)
+	assert.True(t, c.isProtected(c.Nodes["lille-0"]))
+	assert.False(t, c.isReplaceable(c.Nodes["lille-0"]))
+}
+
+func TestWaitForClusterStorage(t *testing.T) {
+	c := newFleetCluster(4, 0)
+	c.Config.SwarmMasterNode = []string{"lille-0", "lille-1", "lille-2"}
+
+	// the ensemble elects a leader once a quorum of masters is waiting (all their servers are started before)
+	var mu sync.Mutex
+	waiting := make(map[string]bool)
+	elected := make(chan struct{})
+	waitForLeader := func(h *host.Host, timeout time.Duration) error {
+		mu.Lock()
+		waiting[h.Name] = true
+		if len(waiting) == 2 {
+			close(elected)
+		}
+		mu.Unlock()
+
+		select {
+		case <-elected:
+			return nil
+		case <-time.After(timeout):
+			return fmt.Errorf("no leader")
+		}
+	}
+
+	// the failed masters are not waited for
+	errs := map[string]error{"lille-2": fmt.Errorf("provisioning failed")}
+	assert.NoError(t, c.waitForClusterStorage(errs, waitForLeader))
+	assert.Equal(t, map[string]bool{"lille-0": true, "lille-1": true}, waiting)
+	assert.Len(t, errs, 1)
+}
+
+func TestWaitForClusterStorageTimeout(t *testing.T) {
+	c := newFleetCluster(2, 0)
+	c.Config.SwarmMasterNode = []string{"lille-0", "lille-1"}
+	c.Config.PhaseTimeouts = map[string]time.Duration{PhaseZookeeper: 50 * time.Millisecond}
+
+	errs := make(map[string]error)
+	err := c.waitForClusterStorage(errs, func(h *host.Host, timeout time.Duration) error {
+		if h.Name == "lille-1" {
+			return fmt.Errorf("The Zookeeper ensemble has no leader after %s", timeout)
+		}
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, errs, "lille-1")
+	assert.True(t, errors.Is(errs["lille-1"], ErrZookeeper))
+}
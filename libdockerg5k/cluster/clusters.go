@@ -0,0 +1,206 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	g5kdriver "github.com/Spirals-Team/docker-machine-driver-g5k/driver"
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/persist"
+)
+
+const (
+	// clusterIDLabel is the Engine label identifying the cluster of a node
+	clusterIDLabel = "g5k.cluster"
+
+	// clusterRoleLabel is the Engine label storing the role of a node in its cluster
+	clusterRoleLabel = "g5k.cluster.role"
+
+	// clusterJobOwnedLabel is the Engine label marking the nodes whose Grid'5000 job was reserved by docker-g5k
+	clusterJobOwnedLabel = "g5k.cluster.job-owned"
+)
+
+var (
+	// regexClusterID match a valid cluster ID
+	regexClusterID = regexp.MustCompile("^[[:alnum:]][[:alnum:]_.-]*$")
+)
+
+// validateClusterID check the cluster ID
+func validateClusterID(id string) error {
+	if !regexClusterID.MatchString(id) {
+		return fmt.Errorf("Invalid cluster ID: '%s' (only letters, digits, '_', '.' and '-' are allowed)", id)
+	}
+
+	return nil
+}
+
+// clusterLabels returns the Engine labels identifying the cluster, the role of the node and the ownership of its job (if a cluster ID is configured)
+func (n *Node) clusterLabels() []string {
+	if n.clusterConfig.ClusterID == "" {
+		return []string{}
+	}
+
+	labels := []string{fmt.Sprintf("%s=%s", clusterIDLabel, n.clusterConfig.ClusterID)}
+	if n.Role != "" {
+		labels = append(labels, fmt.Sprintf("%s=%s", clusterRoleLabel, n.Role))
+	}
+	if n.OwnsJob {
+		labels = append(labels, fmt.Sprintf("%s=true", clusterJobOwnedLabel))
+	}
+
+	return labels
+}
+
+// labelValue returns the value of the label with the given key (empty if not found)
+func labelValue(labels []string, key string) string {
+	for _, l := range labels {
+		// label format: key=value
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) == 2 && kv[0] == key {
+			return kv[1]
+		}
+	}
+
+	return ""
+}
+
+// hostEngineLabels returns the Engine labels stored in the machine configuration
+func hostEngineLabels(h *host.Host) []string {
+	if h.HostOptions == nil || h.HostOptions.EngineOptions == nil {
+		return nil
+	}
+
+	return h.HostOptions.EngineOptions.Labels
+}
+
+// clusterHost contain a machine of the local Docker Machine storage belonging to a cluster
+type clusterHost struct {
+	host   *host.Host
+	driver g5kdriver.Driver
+	id     string
+}
+
+// loadLabeledHosts returns the Grid'5000 machines of the Docker Machine storage labeled with a cluster ID
+func loadLabeledHosts(client *libmachine.Client) ([]clusterHost, error) {
+	lst, _, err := persist.LoadAllHosts(client)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load the machines: '%s'", err)
+	}
+
+	hosts := []clusterHost{}
+	for _, h := range lst {
+		// only catch Grid'5000 nodes
+		if h.DriverName != "g5k" {
+			continue
+		}
+
+		// only catch nodes labeled with a cluster ID
+		id := labelValue(hostEngineLabels(h), clusterIDLabel)
+		if id == "" {
+			continue
+		}
+
+		// get machine driver configuration
+		var drv g5kdriver.Driver
+		if err := json.Unmarshal(h.RawDriver, &drv); err != nil {
+			continue
+		}
+
+		hosts = append(hosts, clusterHost{h, drv, id})
+	}
+
+	return hosts, nil
+}
+
+// ListClusters returns the IDs (sorted) of the clusters found in the local Docker Machine storage (the Docker Machine default if the storage path is empty)
+func ListClusters(storagePath string) ([]string, error) {
+	// create a new libmachine client
+	client := NewMachineStorageClient(storagePath)
+	defer client.Close()
+
+	hosts, err := loadLabeledHosts(client)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	found := make(map[string]bool)
+	for _, ch := range hosts {
+		if !found[ch.id] {
+			found[ch.id] = true
+			ids = append(ids, ch.id)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// LoadCluster returns the global configuration and the nodes (sorted by machine name) of the cluster from the local Docker Machine storage (the Docker Machine default if the storage path is empty)
+// The returned configuration only contain the Grid'5000 settings and the annotations, its libmachine client must be closed by the caller
+func LoadCluster(storagePath string, id string) (*GlobalConfig, []*Node, error) {
+	// create a new libmachine client
+	client := NewMachineStorageClient(storagePath)
+
+	hosts, err := loadLabeledHosts(client)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+
+	config := &GlobalConfig{
+		LibMachineClient:   client,
+		MachineStoragePath: storagePath,
+		ClusterID:          id,
+		HostsLookupTable:   make(map[string]string),
+	}
+
+	nodes := []*Node{}
+	for _, ch := range hosts {
+		if ch.id != id {
+			continue
+		}
+
+		// Grid'5000 settings are the same for all nodes of the cluster
+		config.G5kUsername = ch.driver.G5kUsername
+		config.G5kPassword = ch.driver.G5kPassword
+		config.G5kImage = ch.driver.G5kImage
+		config.G5kWalltime = ch.driver.G5kWalltime
+		config.OARQueue = ch.driver.G5kJobQueue
+		config.Annotations = parseAnnotationLabels(hostEngineLabels(ch.host))
+
+		n := &Node{
+			clusterConfig: config,
+			MachineName:   ch.host.Name,
+			NodeName:      ch.driver.G5kHostToProvision,
+			Role:          labelValue(hostEngineLabels(ch.host), clusterRoleLabel),
+			G5kSite:       ch.driver.G5kSite,
+			G5kJobID:      ch.driver.G5kJobID,
+			OwnsJob:       labelValue(hostEngineLabels(ch.host), clusterJobOwnedLabel) == "true",
+		}
+
+		// lookup IP address of the node for static lookup table
+		ip, err := net.LookupIP(n.NodeName)
+		if err != nil || len(ip) < 1 {
+			client.Close()
+			return nil, nil, fmt.Errorf("Unable to lookup IP address for '%s' node: '%s'", n.NodeName, err)
+		}
+		config.HostsLookupTable[n.MachineName] = ip[0].String()
+
+		nodes = append(nodes, n)
+	}
+
+	if len(nodes) == 0 {
+		client.Close()
+		return nil, nil, fmt.Errorf("No machine found for the cluster '%s'", id)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].MachineName < nodes[j].MachineName })
+
+	return config, nodes, nil
+}
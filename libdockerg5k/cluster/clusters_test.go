@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateClusterID(t *testing.T) {
+	assert.NoError(t, validateClusterID("exp-1.run_2"))
+	assert.Error(t, validateClusterID(""))
+	assert.Error(t, validateClusterID(".exp"))
+	assert.Error(t, validateClusterID("my exp"))
+}
+
+func TestEngineLabelsClusterID(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{ClusterID: "exp1", DisableG5kLabels: true}, Role: NodeRoleManager, EngineLabel: []string{"mykey=myval"}}
+	assert.Equal(t, []string{"mykey=myval", "g5k.cluster=exp1", "g5k.cluster.role=Manager"}, n.engineLabels())
+
+	n = &Node{clusterConfig: &GlobalConfig{}, Role: NodeRoleManager}
+	assert.Empty(t, n.clusterLabels())
+
+	// ownership of the job reserved by docker-g5k
+	n = &Node{clusterConfig: &GlobalConfig{ClusterID: "exp1"}, OwnsJob: true}
+	assert.Equal(t, []string{"g5k.cluster=exp1", "g5k.cluster.job-owned=true"}, n.clusterLabels())
+}
+
+func TestLabelValue(t *testing.T) {
+	labels := []string{"g5k.cluster=exp1", "g5k.cluster.role=Worker", "invalid"}
+	assert.Equal(t, "exp1", labelValue(labels, "g5k.cluster"))
+	assert.Equal(t, "Worker", labelValue(labels, "g5k.cluster.role"))
+	assert.Equal(t, "", labelValue(labels, "invalid"))
+	assert.Equal(t, "", labelValue(nil, "g5k.cluster"))
+}
@@ -0,0 +1,364 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+)
+
+const (
+	// defaultPasswordEnv is the environment variable of the Grid5000 password used if none is given in the cluster definition file
+	defaultPasswordEnv = "G5K_PASSWORD"
+
+	// defaults of the cluster definition file (same as the command line)
+	defaultConfigG5kImage    = "jessie-x64-min"
+	defaultConfigG5kWalltime = "1:00:00"
+)
+
+// ClusterFile contain the cluster definition file (JSON format)
+type ClusterFile struct {
+	G5k       G5kFile        `json:"g5k"`
+	Engine    EngineFile     `json:"engine"`
+	Registry  *RegistryFile  `json:"registry,omitempty"`
+	SwarmMode *SwarmModeFile `json:"swarm_mode,omitempty"`
+	Nodes     []NodeFile     `json:"nodes"`
+
+	// metadata of the experiment
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// G5kFile contain the Grid5000 configuration of the cluster definition file
+type G5kFile struct {
+	Username    string `json:"username"`
+	PasswordEnv string `json:"password_env,omitempty"` // environment variable containing the password (G5K_PASSWORD if empty)
+	Image       string `json:"image,omitempty"`
+	Walltime    string `json:"walltime,omitempty"`
+	Queue       string `json:"queue,omitempty"`
+}
+
+// EngineFile contain the Docker Engine configuration of the cluster definition file
+type EngineFile struct {
+	InstallURL       string            `json:"install_url,omitempty"`
+	DisableG5kLabels bool              `json:"disable_g5k_labels,omitempty"`
+	AliasesLabel     bool              `json:"aliases_label,omitempty"`
+	LogMaxSize       string            `json:"log_max_size,omitempty"`
+	LogMaxFile       int               `json:"log_max_file,omitempty"`
+	BridgeSubnet     string            `json:"bridge_subnet,omitempty"`
+	SystemdOverrides map[string]string `json:"systemd_overrides,omitempty"`
+	DefaultNetwork   string            `json:"default_network,omitempty"`
+	Experimental     bool              `json:"experimental,omitempty"`
+	APIVersion       string            `json:"api_version,omitempty"`
+	MetricsAddr      string            `json:"metrics_addr,omitempty"`
+	SeccompProfile   string            `json:"seccomp_profile,omitempty"`
+	AppArmorProfile  string            `json:"apparmor_profile,omitempty"`
+	PullProxy        string            `json:"pull_proxy,omitempty"`
+}
+
+// RegistryFile contain the registry mirror configuration of the cluster definition file
+type RegistryFile struct {
+	Node           string `json:"node"`
+	ProxyRemoteURL string `json:"proxy_remote_url,omitempty"`
+}
+
+// SwarmModeFile contain the Swarm mode configuration of the cluster definition file
+type SwarmModeFile struct {
+	RequireQuorum    bool   `json:"require_quorum,omitempty"`
+	QuorumTimeout    string `json:"quorum_timeout,omitempty"`
+	InitWaitTimeout  string `json:"init_wait_timeout,omitempty"`
+	OverlayEncrypted bool   `json:"overlay_encrypted,omitempty"`
+	DataPathPort     int    `json:"data_path_port,omitempty"`
+	SmokeTest        bool   `json:"smoke_test,omitempty"`
+}
+
+// NodeFile contain a node of the cluster definition file
+type NodeFile struct {
+	MachineName        string   `json:"machine_name"`
+	Site               string   `json:"site"`
+	Role               string   `json:"role,omitempty"` // Manager or Worker (default)
+	NodeName           string   `json:"node_name,omitempty"`
+	JobID              int      `json:"job_id,omitempty"`
+	OwnsJob            bool     `json:"owns_job,omitempty"` // the job was reserved by docker-g5k (canceled on release)
+	EngineOpt          []string `json:"engine_opt,omitempty"`
+	EngineLabel        []string `json:"engine_label,omitempty"`
+	Aliases            []string `json:"aliases,omitempty"`
+	AdvertiseInterface string   `json:"advertise_interface,omitempty"`
+}
+
+// parseOptionalDuration parse the duration (zero if empty)
+func parseOptionalDuration(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid duration for '%s': '%s'", name, value)
+	}
+
+	return d, nil
+}
+
+// formatOptionalDuration returns the duration as string (empty if zero)
+func formatOptionalDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+
+	return d.String()
+}
+
+// globalConfig returns the cluster global configuration of the cluster definition file (the password is read from the environment)
+func (f *ClusterFile) globalConfig() (*GlobalConfig, error) {
+	config := &GlobalConfig{
+		EngineInstallURL: f.Engine.InstallURL,
+		DisableG5kLabels: f.Engine.DisableG5kLabels,
+		LogRotation:      LogRotation{MaxSize: f.Engine.LogMaxSize, MaxFile: f.Engine.LogMaxFile},
+
+		AliasesEngineLabel: f.Engine.AliasesLabel,
+
+		BridgeSubnet:            f.Engine.BridgeSubnet,
+		EngineSystemdOverrides:  f.Engine.SystemdOverrides,
+		DefaultContainerNetwork: f.Engine.DefaultNetwork,
+		EngineExperimental:      f.Engine.Experimental,
+		EngineAPIVersion:        f.Engine.APIVersion,
+		EngineMetricsAddr:       f.Engine.MetricsAddr,
+		SeccompProfilePath:      f.Engine.SeccompProfile,
+		AppArmorProfile:         f.Engine.AppArmorProfile,
+		EnginePullProxy:         f.Engine.PullProxy,
+
+		G5kUsername: f.G5k.Username,
+		G5kImage:    f.G5k.Image,
+		G5kWalltime: f.G5k.Walltime,
+		OARQueue:    f.G5k.Queue,
+
+		Annotations: f.Annotations,
+
+		HostsLookupTable: make(map[string]string),
+
+		passwordEnv: f.G5k.PasswordEnv,
+	}
+
+	// defaults
+	if config.G5kImage == "" {
+		config.G5kImage = defaultConfigG5kImage
+	}
+	if config.G5kWalltime == "" {
+		config.G5kWalltime = defaultConfigG5kWalltime
+	}
+	if config.passwordEnv == "" {
+		config.passwordEnv = defaultPasswordEnv
+	}
+
+	// Grid5000 credentials
+	if config.G5kUsername == "" {
+		return nil, fmt.Errorf("The Grid5000 username is required")
+	}
+	config.G5kPassword = os.Getenv(config.passwordEnv)
+	if config.G5kPassword == "" {
+		return nil, fmt.Errorf("The Grid5000 password environment variable '%s' is not set", config.passwordEnv)
+	}
+
+	// registry mirror
+	if f.Registry != nil {
+		config.DeployRegistry = true
+		config.RegistryNode = f.Registry.Node
+		config.RegistryProxyRemoteURL = f.Registry.ProxyRemoteURL
+	}
+
+	// Swarm mode
+	if f.SwarmMode != nil {
+		initWaitTimeout, err := parseOptionalDuration("init_wait_timeout", f.SwarmMode.InitWaitTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		quorumTimeout, err := parseOptionalDuration("quorum_timeout", f.SwarmMode.QuorumTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		config.SwarmModeGlobalConfig = &swarm.SwarmModeGlobalConfig{
+			InitWaitTimeout: initWaitTimeout,
+			OverlayDefaults: swarm.OverlayDefaults{
+				Encrypted: f.SwarmMode.OverlayEncrypted,
+				VXLANPort: f.SwarmMode.DataPathPort,
+			},
+		}
+		config.RequireQuorumOnProvision = f.SwarmMode.RequireQuorum
+		config.QuorumTimeout = quorumTimeout
+		config.SmokeTestOnProvision = f.SwarmMode.SmokeTest
+	}
+
+	return config, nil
+}
+
+// nodes returns the nodes of the cluster definition file
+func (f *ClusterFile) nodes(config *GlobalConfig) ([]*Node, error) {
+	c := NewCluster(config)
+
+	nodes := []*Node{}
+	for _, nf := range f.Nodes {
+		if nf.MachineName == "" || nf.Site == "" {
+			return nil, fmt.Errorf("The machine name and the site are required for all nodes")
+		}
+
+		if _, ok := c.Nodes[nf.MachineName]; ok {
+			return nil, fmt.Errorf("The node '%s' is defined more than once", nf.MachineName)
+		}
+
+		n := &Node{
+			clusterConfig:      config,
+			MachineName:        nf.MachineName,
+			NodeName:           nf.NodeName,
+			Role:               nf.Role,
+			G5kSite:            nf.Site,
+			G5kJobID:           nf.JobID,
+			OwnsJob:            nf.OwnsJob,
+			EngineOpt:          nf.EngineOpt,
+			EngineLabel:        nf.EngineLabel,
+			Aliases:            nf.Aliases,
+			AdvertiseInterface: nf.AdvertiseInterface,
+		}
+
+		c.Nodes[n.MachineName] = n
+		nodes = append(nodes, n)
+	}
+
+	if config.DeployRegistry {
+		if _, ok := c.Nodes[config.RegistryNode]; !ok {
+			return nil, fmt.Errorf("The registry node '%s' does not exist", config.RegistryNode)
+		}
+	}
+
+	// check nodes role and build the Swarm master/manager list
+	c.SyncNodeRoles()
+	if err := c.validateNodeRoles(); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// LoadClusterConfig read the cluster definition file (JSON format) and returns the validated cluster global configuration and nodes
+// The Grid5000 password is read from the environment variable given in the file (G5K_PASSWORD by default)
+func LoadClusterConfig(path string) (*GlobalConfig, []*Node, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to read the cluster definition file '%s': '%s'", path, err)
+	}
+
+	var f ClusterFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, nil, fmt.Errorf("Unable to parse the cluster definition file '%s': '%s'", path, err)
+	}
+
+	config, err := f.globalConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nodes, err := f.nodes(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return config, nodes, nil
+}
+
+// newClusterFile returns the cluster definition file of the cluster global configuration and nodes (sorted by machine name)
+func newClusterFile(config *GlobalConfig, nodes []*Node) *ClusterFile {
+	f := &ClusterFile{
+		G5k: G5kFile{
+			Username:    config.G5kUsername,
+			PasswordEnv: config.passwordEnv,
+			Image:       config.G5kImage,
+			Walltime:    config.G5kWalltime,
+			Queue:       config.OARQueue,
+		},
+		Engine: EngineFile{
+			InstallURL:       config.EngineInstallURL,
+			DisableG5kLabels: config.DisableG5kLabels,
+			AliasesLabel:     config.AliasesEngineLabel,
+			LogMaxSize:       config.LogRotation.MaxSize,
+			LogMaxFile:       config.LogRotation.MaxFile,
+			BridgeSubnet:     config.BridgeSubnet,
+			SystemdOverrides: config.EngineSystemdOverrides,
+			DefaultNetwork:   config.DefaultContainerNetwork,
+			Experimental:     config.EngineExperimental,
+			APIVersion:       config.EngineAPIVersion,
+			MetricsAddr:      config.EngineMetricsAddr,
+			SeccompProfile:   config.SeccompProfilePath,
+			AppArmorProfile:  config.AppArmorProfile,
+			PullProxy:        config.EnginePullProxy,
+		},
+		Nodes:       []NodeFile{},
+		Annotations: config.Annotations,
+	}
+
+	if f.G5k.PasswordEnv == "" {
+		f.G5k.PasswordEnv = defaultPasswordEnv
+	}
+
+	if config.DeployRegistry {
+		f.Registry = &RegistryFile{Node: config.RegistryNode, ProxyRemoteURL: config.RegistryProxyRemoteURL}
+	}
+
+	if gc := config.SwarmModeGlobalConfig; gc != nil {
+		f.SwarmMode = &SwarmModeFile{
+			RequireQuorum:    config.RequireQuorumOnProvision,
+			QuorumTimeout:    formatOptionalDuration(config.QuorumTimeout),
+			InitWaitTimeout:  formatOptionalDuration(gc.InitWaitTimeout),
+			OverlayEncrypted: gc.OverlayDefaults.Encrypted,
+			DataPathPort:     gc.OverlayDefaults.VXLANPort,
+			SmokeTest:        config.SmokeTestOnProvision,
+		}
+	}
+
+	sorted := append([]*Node{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MachineName < sorted[j].MachineName })
+
+	for _, n := range sorted {
+		role := n.Role
+		if role == "" && n.isSwarmMaster() {
+			role = NodeRoleManager
+		}
+
+		f.Nodes = append(f.Nodes, NodeFile{
+			MachineName:        n.MachineName,
+			Site:               n.G5kSite,
+			Role:               role,
+			NodeName:           n.NodeName,
+			JobID:              n.G5kJobID,
+			OwnsJob:            n.OwnsJob,
+			EngineOpt:          n.EngineOpt,
+			EngineLabel:        n.EngineLabel,
+			Aliases:            n.Aliases,
+			AdvertiseInterface: n.AdvertiseInterface,
+		})
+	}
+
+	return f
+}
+
+// WriteClusterConfig write the cluster global configuration and nodes in a cluster definition file (JSON format), the Grid5000 password is never written
+func WriteClusterConfig(path string, config *GlobalConfig, nodes []*Node) error {
+	data, err := json.MarshalIndent(newClusterFile(config, nodes), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("Unable to write the cluster definition file '%s': '%s'", path, err)
+	}
+
+	return nil
+}
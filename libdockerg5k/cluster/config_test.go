@@ -0,0 +1,91 @@
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testClusterFile = `{
+  "g5k": {"username": "jdoe", "password_env": "TEST_G5K_PASSWORD", "queue": "production"},
+  "engine": {"bridge_subnet": "10.200.0.0/16", "systemd_overrides": {"Service.LimitNOFILE": "1048576"}},
+  "swarm_mode": {"init_wait_timeout": "5m", "data_path_port": 4790},
+  "nodes": [
+    {"machine_name": "lille-1", "site": "lille"},
+    {"machine_name": "lille-0", "site": "lille", "role": "Manager", "engine_label": ["mykey=myval"]}
+  ]
+}`
+
+func writeTestClusterFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "docker-g5k-config")
+	assert.NoError(t, err)
+
+	path := filepath.Join(dir, "cluster.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadClusterConfig(t *testing.T) {
+	os.Setenv("TEST_G5K_PASSWORD", "secret")
+	defer os.Unsetenv("TEST_G5K_PASSWORD")
+
+	path := writeTestClusterFile(t, testClusterFile)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	config, nodes, err := LoadClusterConfig(path)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "secret", config.G5kPassword)
+	assert.Equal(t, defaultConfigG5kImage, config.G5kImage)
+	assert.Equal(t, defaultConfigG5kWalltime, config.G5kWalltime)
+	assert.False(t, config.DisableG5kLabels)
+	assert.Equal(t, 5*time.Minute, config.SwarmModeGlobalConfig.InitWaitTimeout)
+	assert.Equal(t, 4790, config.SwarmModeGlobalConfig.OverlayDefaults.VXLANPort)
+	assert.Equal(t, []string{"lille-0"}, config.SwarmMasterNode)
+
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, NodeRoleWorker, nodes[0].Role)
+	assert.Equal(t, []string{"mykey=myval"}, nodes[1].EngineLabel)
+}
+
+func TestLoadClusterConfigIncorrect(t *testing.T) {
+	os.Setenv("TEST_G5K_PASSWORD", "secret")
+	defer os.Unsetenv("TEST_G5K_PASSWORD")
+
+	for _, content := range []string{
+		`{"g5k": {"username": "jdoe"}, "nodes": []}`,
+		`{"g5k": {"username": "jdoe", "password_env": "TEST_G5K_PASSWORD"}, "swarm_mode": {}, "nodes": [{"machine_name": "lille-0", "site": "lille"}]}`,
+		`{"g5k": {"username": "jdoe", "password_env": "TEST_G5K_PASSWORD"}, "nodes": [{"machine_name": "lille-0", "site": "lille"}, {"machine_name": "lille-0", "site": "lille"}]}`,
+		`{"g5k": {"username": "jdoe", "password_env": "TEST_G5K_PASSWORD"}, "engine": {"bridge_subnet": "10.200.0.0"}, "nodes": []}`,
+	} {
+		path := writeTestClusterFile(t, content)
+		_, _, err := LoadClusterConfig(path)
+		assert.Error(t, err)
+		os.RemoveAll(filepath.Dir(path))
+	}
+}
+
+func TestWriteClusterConfigRoundTrip(t *testing.T) {
+	os.Setenv("TEST_G5K_PASSWORD", "secret")
+	defer os.Unsetenv("TEST_G5K_PASSWORD")
+
+	path := writeTestClusterFile(t, testClusterFile)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	config, nodes, err := LoadClusterConfig(path)
+	assert.NoError(t, err)
+
+	assert.NoError(t, WriteClusterConfig(path, config, nodes))
+
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	reloaded, reloadedNodes, err := LoadClusterConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, newClusterFile(config, nodes), newClusterFile(reloaded, reloadedNodes))
+}
@@ -0,0 +1,176 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// containerStatsTimeout is the maximum time allowed to collect the containers stats of all nodes (each stats request waits for a second sample)
+	containerStatsTimeout = 1 * time.Minute
+
+	// containerStatsCommand query the Docker API stats endpoint once for each running container (in parallel) and print the results (one by line)
+	containerStatsCommand = "d=$(mktemp -d) && " +
+		"docker ps -q | xargs -r -P 16 -I{} curl -s -o \"$d/{}\" --unix-socket /var/run/docker.sock 'http://localhost/containers/{}/stats?stream=false'; " +
+		"for f in \"$d\"/*; do [ -s \"$f\" ] && cat \"$f\" && echo; done; rm -rf \"$d\""
+)
+
+// ContainerStat contain the resource usage of a running container, and the role and Engine labels of its node (to group the containers)
+type ContainerStat struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	CPUPercent       float64 `json:"cpu_percent"` // of one CPU (up to 100% by online CPU)
+	MemoryUsageBytes uint64  `json:"memory_usage_bytes"`
+	MemoryLimitBytes uint64  `json:"memory_limit_bytes"`
+	MemoryPercent    float64 `json:"memory_percent"`
+	NetworkRxBytes   uint64  `json:"network_rx_bytes"` // all networks of the container
+	NetworkTxBytes   uint64  `json:"network_tx_bytes"`
+
+	NodeRole   string   `json:"node_role,omitempty"`
+	NodeLabels []string `json:"node_labels,omitempty"`
+}
+
+// dockerCPUStats contain the CPU usage of the Docker API stats
+type dockerCPUStats struct {
+	CPUUsage struct {
+		TotalUsage uint64 `json:"total_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  uint64 `json:"online_cpus"`
+}
+
+// dockerStats contain the fields used from the Docker API stats of a container
+type dockerStats struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	CPUStats    dockerCPUStats `json:"cpu_stats"`
+	PreCPUStats dockerCPUStats `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+}
+
+// containerStat returns the resource usage like the docker stats command (the page cache is not counted in the memory usage)
+func (s *dockerStats) containerStat() ContainerStat {
+	cs := ContainerStat{
+		ID:               s.ID,
+		Name:             strings.TrimPrefix(s.Name, "/"),
+		MemoryUsageBytes: s.MemoryStats.Usage,
+		MemoryLimitBytes: s.MemoryStats.Limit,
+	}
+
+	// cgroup v1 ('cache') or v2 ('inactive_file') page cache
+	cache := s.MemoryStats.Stats["cache"]
+	if v, ok := s.MemoryStats.Stats["inactive_file"]; ok {
+		cache = v
+	}
+	if cache < cs.MemoryUsageBytes {
+		cs.MemoryUsageBytes -= cache
+	}
+
+	if cs.MemoryLimitBytes > 0 {
+		cs.MemoryPercent = float64(cs.MemoryUsageBytes) / float64(cs.MemoryLimitBytes) * 100
+	}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		cs.CPUPercent = cpuDelta / systemDelta * float64(s.CPUStats.OnlineCPUs) * 100
+	}
+
+	for _, n := range s.Networks {
+		cs.NetworkRxBytes += n.RxBytes
+		cs.NetworkTxBytes += n.TxBytes
+	}
+
+	return cs
+}
+
+// parseContainerStats returns the containers stats (sorted by name) from the output of the stats command
+func parseContainerStats(out string) ([]ContainerStat, error) {
+	stats := []ContainerStat{}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var s dockerStats
+		if err := json.Unmarshal([]byte(line), &s); err != nil {
+			return nil, fmt.Errorf("Unable to parse the container stats: '%s'", err)
+		}
+		stats = append(stats, s.containerStat())
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+
+	return stats, nil
+}
+
+// containerStats returns the resource usage of the running containers of the node, with the node role and Engine labels
+func (n *Node) containerStats() ([]ContainerStat, error) {
+	out, err := n.runSSHCommand(containerStatsCommand)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to collect the containers stats: '%s'", err)
+	}
+
+	stats, err := parseContainerStats(out)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := n.engineLabels()
+	for i := range stats {
+		stats[i].NodeRole = n.Role
+		stats[i].NodeLabels = labels
+	}
+
+	return stats, nil
+}
+
+// ContainerStats collect (in parallel) a snapshot of the CPU, memory and network usage of the running containers of the selected nodes (all nodes if the selector is empty),
+// from the Docker API stats endpoint. The stats of the reachable nodes are returned by machine name, the unreachable nodes are reported in the returned error
+func (c *Cluster) ContainerStats(sel *NodeSelector) (map[string][]ContainerStat, error) {
+	if err := c.checkSelection(sel); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	results := make(map[string][]ContainerStat)
+
+	errs := c.runOnSelectedNodes(sel, containerStatsTimeout, func(n *Node, h *host.Host) error {
+		stats, err := n.containerStats()
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		results[n.MachineName] = stats
+		mu.Unlock()
+
+		return nil
+	})
+
+	// copy the results to not race with the nodes still running after a timeout
+	mu.Lock()
+	defer mu.Unlock()
+
+	stats := make(map[string][]ContainerStat)
+	for machineName, s := range results {
+		stats[machineName] = s
+	}
+
+	return stats, fleetError("Containers stats collection", errs)
+}
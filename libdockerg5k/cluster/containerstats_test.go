@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContainerStats(t *testing.T) {
+	out := `{"id":"b2c3","name":"/web","cpu_stats":{"cpu_usage":{"total_usage":400000000},"system_cpu_usage":2000000000,"online_cpus":4},"precpu_stats":{"cpu_usage":{"total_usage":300000000},"system_cpu_usage":1000000000},"memory_stats":{"usage":2097152,"limit":8388608,"stats":{"inactive_file":1048576}},"networks":{"eth0":{"rx_bytes":100,"tx_bytes":10},"eth1":{"rx_bytes":50,"tx_bytes":5}}}
+
+{"id":"a1b2","name":"/db","cpu_stats":{"cpu_usage":{"total_usage":100},"system_cpu_usage":1000,"online_cpus":2},"precpu_stats":{},"memory_stats":{"usage":4096,"limit":0,"stats":{"cache":1024}}}
+`
+	stats, err := parseContainerStats(out)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+
+	assert.Equal(t, ContainerStat{ID: "a1b2", Name: "db", CPUPercent: 20, MemoryUsageBytes: 3072}, stats[0])
+	assert.Equal(t, ContainerStat{
+		ID:               "b2c3",
+		Name:             "web",
+		CPUPercent:       40,
+		MemoryUsageBytes: 1048576,
+		MemoryLimitBytes: 8388608,
+		MemoryPercent:    12.5,
+		NetworkRxBytes:   150,
+		NetworkTxBytes:   15,
+	}, stats[1])
+}
+
+func TestParseContainerStatsIncorrect(t *testing.T) {
+	stats, err := parseContainerStats("")
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+
+	_, err = parseContainerStats("{\"id\":")
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+)
+
+const (
+	// default environment variables of the Grid5000 credentials
+	defaultUsernameEnv = "G5K_USERNAME"
+	defaultTokenEnv    = "G5K_TOKEN"
+)
+
+// CredentialProvider returns the Grid5000 credentials, it is called each time the credentials are needed (API requests, machines creation)
+// The Grid5000 API only support the basic authentication, a token is used as password if no password is returned
+type CredentialProvider interface {
+	Credentials() (user, pass, token string, err error)
+}
+
+// EnvCredentials read the Grid5000 credentials from environment variables (defaults: G5K_USERNAME, G5K_PASSWORD and G5K_TOKEN)
+type EnvCredentials struct {
+	UsernameEnv string
+	PasswordEnv string
+	TokenEnv    string
+}
+
+// envOrDefault returns the value of the environment variable, or of the default variable if the name is empty
+func envOrDefault(name string, defaultName string) string {
+	if name == "" {
+		name = defaultName
+	}
+
+	return os.Getenv(name)
+}
+
+// Credentials returns the Grid5000 credentials from the environment
+func (e EnvCredentials) Credentials() (string, string, string, error) {
+	return envOrDefault(e.UsernameEnv, defaultUsernameEnv), envOrDefault(e.PasswordEnv, defaultPasswordEnv), envOrDefault(e.TokenEnv, defaultTokenEnv), nil
+}
+
+// FileCredentials read the Grid5000 credentials from a JSON file (format: {"username": "...", "password": "...", "token": "..."})
+// The file must not be readable by the group or other users
+type FileCredentials struct {
+	Path string
+}
+
+// credentialsFile contain the Grid5000 credentials of a credentials file
+type credentialsFile struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// Credentials returns the Grid5000 credentials from the file
+func (f FileCredentials) Credentials() (string, string, string, error) {
+	fi, err := os.Stat(f.Path)
+	if err != nil {
+		return "", "", "", fmt.Errorf("Unable to read the credentials file '%s': '%s'", f.Path, err)
+	}
+
+	// check file permissions
+	if fi.Mode().Perm()&0077 != 0 {
+		return "", "", "", fmt.Errorf("The credentials file '%s' must not be accessible by the group or other users (permissions: %s)", f.Path, fi.Mode().Perm())
+	}
+
+	data, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return "", "", "", fmt.Errorf("Unable to read the credentials file '%s': '%s'", f.Path, err)
+	}
+
+	var cf credentialsFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		return "", "", "", fmt.Errorf("Unable to parse the credentials file '%s': '%s'", f.Path, err)
+	}
+
+	return cf.Username, cf.Password, cf.Token, nil
+}
+
+// credentials returns the Grid5000 username and password from the credential provider (if set) or the configuration
+func (c *GlobalConfig) credentials() (string, string, error) {
+	if c.Credentials == nil {
+		return c.G5kUsername, c.G5kPassword, nil
+	}
+
+	user, pass, token, err := c.Credentials.Credentials()
+	if err != nil {
+		return "", "", fmt.Errorf("Unable to get the Grid5000 credentials: '%s'", err)
+	}
+
+	// the token is sent as password (basic authentication)
+	if pass == "" {
+		pass = token
+	}
+
+	if user == "" || pass == "" {
+		return "", "", fmt.Errorf("The credential provider returned an empty Grid5000 username or password")
+	}
+
+	return user, pass, nil
+}
+
+// g5kAPI returns a Grid5000 API client using the cluster credentials
+func (c *GlobalConfig) g5kAPI() (*g5k.G5K, error) {
+	user, pass, err := c.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	return g5k.Init(user, pass), nil
+}
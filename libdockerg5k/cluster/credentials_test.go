@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvCredentials(t *testing.T) {
+	os.Setenv("TEST_G5K_USERNAME", "jdoe")
+	os.Setenv("TEST_G5K_PASSWORD", "secret")
+	defer os.Unsetenv("TEST_G5K_USERNAME")
+	defer os.Unsetenv("TEST_G5K_PASSWORD")
+
+	user, pass, token, err := EnvCredentials{UsernameEnv: "TEST_G5K_USERNAME", PasswordEnv: "TEST_G5K_PASSWORD", TokenEnv: "TEST_G5K_TOKEN"}.Credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "jdoe", user)
+	assert.Equal(t, "secret", pass)
+	assert.Equal(t, "", token)
+}
+
+func TestFileCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-g5k-credentials")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "credentials.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"username": "jdoe", "token": "abc"}`), 0600))
+
+	user, pass, token, err := FileCredentials{Path: path}.Credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "jdoe", user)
+	assert.Equal(t, "", pass)
+	assert.Equal(t, "abc", token)
+
+	// readable by other users
+	assert.NoError(t, os.Chmod(path, 0644))
+	_, _, _, err = FileCredentials{Path: path}.Credentials()
+	assert.Error(t, err)
+
+	// missing file
+	_, _, _, err = FileCredentials{Path: filepath.Join(dir, "missing.json")}.Credentials()
+	assert.Error(t, err)
+}
+
+type testCredentials struct {
+	user, pass, token string
+}
+
+func (c testCredentials) Credentials() (string, string, string, error) {
+	return c.user, c.pass, c.token, nil
+}
+
+func TestGlobalConfigCredentials(t *testing.T) {
+	// configuration fields
+	user, pass, err := (&GlobalConfig{G5kUsername: "jdoe", G5kPassword: "secret"}).credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "jdoe", user)
+	assert.Equal(t, "secret", pass)
+
+	// provider takes precedence, token used as password
+	user, pass, err = (&GlobalConfig{G5kUsername: "other", Credentials: testCredentials{"jdoe", "", "abc"}}).credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "jdoe", user)
+	assert.Equal(t, "abc", pass)
+
+	// empty credentials
+	_, _, err = (&GlobalConfig{Credentials: testCredentials{"jdoe", "", ""}}).credentials()
+	assert.Error(t, err)
+}
@@ -0,0 +1,23 @@
+package cluster
+
+import (
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// applyDataPathOffloads set the offload features of the Swarm mode data path on the advertise interface of the node (nothing is done if none is configured)
+func (n *Node) applyDataPathOffloads(h *host.Host) error {
+	gc := n.clusterConfig.SwarmModeGlobalConfig
+	if gc == nil || len(gc.DataPathTuning.Offloads) == 0 {
+		return nil
+	}
+
+	iface := n.clusterAdvertiseInterface()
+	if err := gc.DataPathTuning.ApplyOffloads(h, iface); err != nil {
+		return err
+	}
+
+	log.Infof("%d data path offload feature(s) set on interface '%s' of node '%s' ('%s')", len(gc.DataPathTuning.Offloads), iface, n.NodeName, n.MachineName)
+	n.appliedDataPathOffloads = gc.DataPathTuning.Offloads
+	return nil
+}
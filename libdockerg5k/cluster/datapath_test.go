@@ -0,0 +1,14 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyDataPathOffloadsNotConfigured(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{SwarmModeGlobalConfig: &swarm.SwarmModeGlobalConfig{}}}
+	assert.NoError(t, n.applyDataPathOffloads(nil))
+	assert.Nil(t, n.appliedDataPathOffloads)
+}
@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// defaultDataRoot is the data root directory of the Docker Engine if none is configured
+	defaultDataRoot = "/var/lib/docker"
+
+	// imageCacheMarker is the file of the persistent data root storing the date (Unix time) of its first provisioning
+	imageCacheMarker = ".docker-g5k-image-cache"
+)
+
+// validateDataRoot check the Docker data root path, device and minimum free space
+func validateDataRoot(path string, device string, minFree string) error {
+	if path != "" && (!filepath.IsAbs(path) || filepath.Clean(path) == "/") {
+		return fmt.Errorf("The Docker data root needs to be an absolute path (not the root directory): '%s'", path)
+	}
+
+	if device != "" {
+		if path == "" {
+			return fmt.Errorf("The Docker data root is needed to mount the device '%s'", device)
+		}
+
+		if !strings.HasPrefix(device, "/dev/") {
+			return fmt.Errorf("The Docker data root device is not a device path: '%s'", device)
+		}
+	}
+
+	if minFree != "" {
+		if _, err := parseMemorySize(minFree); err != nil {
+			return fmt.Errorf("The Docker data root minimum free space is not a valid size: '%s'", minFree)
+		}
+	}
+
+	return nil
+}
+
+// validateDataRootPersistence check the persistent data root has a dedicated device (the deployment of the node wipes its system disk)
+func (c *GlobalConfig) validateDataRootPersistence() error {
+	if c.DataRootPersistent && c.DataRootDevice == "" {
+		return fmt.Errorf("The persistent Docker data root needs a data root device (ex: a reserved disk)")
+	}
+
+	return nil
+}
+
+// dataRoot returns the data root directory of the Docker Engine of the node (the node flag takes precedence)
+func (n *Node) dataRoot() string {
+	if d := getEngineFlagValue(n.EngineOpt, "data-root"); d != "" {
+		return d
+	}
+
+	if n.clusterConfig.DataRoot != "" {
+		return n.clusterConfig.DataRoot
+	}
+
+	return defaultDataRoot
+}
+
+// generateMountDataRootCommand returns the command used to mount the device (formatted if it has no filesystem) on the data root directory while the Engine is stopped
+// the mount is added to the fstab to be kept after a reboot
+func generateMountDataRootCommand(path string, device string) string {
+	return fmt.Sprintf("systemctl stop docker.service && mkdir -p %[1]s && (blkid %[2]s || mkfs.ext4 -q %[2]s) && (mountpoint -q %[1]s || mount %[2]s %[1]s) && (grep -q '^%[2]s ' /etc/fstab || echo '%[2]s %[1]s ext4 defaults 0 2' >>/etc/fstab) && systemctl start docker.service", path, device)
+}
+
+// generateFreeSpaceCommand returns the command used to get the available space (in bytes) of the filesystem of the path
+func generateFreeSpaceCommand(path string) string {
+	return fmt.Sprintf("df --output=avail -B1 %s | tail -n 1", path)
+}
+
+// prepareDataRoot mount the data root device (if configured) and check the free space of the data root of the node (if configured)
+func (n *Node) prepareDataRoot(h *host.Host) error {
+	path := n.dataRoot()
+
+	if n.clusterConfig.DataRootDevice != "" {
+		if _, err := h.RunSSHCommand(generateMountDataRootCommand(path, n.clusterConfig.DataRootDevice)); err != nil {
+			return fmt.Errorf("Failed to mount the device '%s' on the Docker data root '%s': '%s'", n.clusterConfig.DataRootDevice, path, err)
+		}
+	}
+
+	if n.clusterConfig.DataRootPersistent {
+		if err := n.prepareImageCache(h, path); err != nil {
+			return err
+		}
+	}
+
+	if n.clusterConfig.DataRootMinFree == "" {
+		return nil
+	}
+
+	minFree, err := parseMemorySize(n.clusterConfig.DataRootMinFree)
+	if err != nil {
+		return err
+	}
+
+	out, err := h.RunSSHCommand(generateFreeSpaceCommand(path))
+	if err != nil {
+		return fmt.Errorf("Failed to get the free space of the Docker data root '%s': '%s'", path, err)
+	}
+
+	free, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the free space of the Docker data root: '%s'", strings.TrimSpace(out))
+	}
+
+	if free < minFree {
+		return fmt.Errorf("The Docker data root '%s' has only %d bytes available (minimum: %s)", path, free, n.clusterConfig.DataRootMinFree)
+	}
+
+	return nil
+}
+
+// generateDisksCommand returns the command listing the disk of the device and the disk of the root filesystem (one per line)
+func generateDisksCommand(device string) string {
+	return fmt.Sprintf(`for d in $(readlink -f %s) $(findmnt -no SOURCE /); do p=$(lsblk -ndo PKNAME $d); if [ -n "$p" ]; then echo $p; else basename $d; fi; done`, device)
+}
+
+// generateImageCacheCommand returns the command printing the date of the first provisioning of the data root (empty if new) and recording it if new
+func generateImageCacheCommand(path string) string {
+	return fmt.Sprintf("cat %[1]s/%[2]s 2>/dev/null || date +%%s >%[1]s/%[2]s", path, imageCacheMarker)
+}
+
+// cleanReusedDataRootCommand remove the state of the previous cluster kept in the reused data root (Swarm membership, containers, volumes and networks), only the images are kept
+const cleanReusedDataRootCommand = "docker swarm leave --force >/dev/null 2>&1; docker ps -aq | xargs -r docker rm -f >/dev/null && docker volume prune -f >/dev/null && docker network prune -f >/dev/null && docker images -q | sort -u | wc -l"
+
+// checkDataRootPersistence check the device of the data root is not on the disk of the root filesystem, wiped by the deployment of the node, from the generateDisksCommand output
+func checkDataRootPersistence(device string, out string) error {
+	disks := strings.Fields(out)
+	if len(disks) != 2 {
+		return fmt.Errorf("Unable to find the disks of the device '%s' and of the root filesystem: '%s'", device, strings.TrimSpace(out))
+	}
+
+	if disks[0] == disks[1] {
+		return fmt.Errorf("The persistent Docker data root device '%s' is on the system disk '%s', wiped by the deployment of the node", device, disks[0])
+	}
+
+	return nil
+}
+
+// prepareImageCache check the data root device is persistent and clean the previous cluster state if the data root is reused, its images are kept as cache
+func (n *Node) prepareImageCache(h *host.Host, path string) error {
+	device := n.clusterConfig.DataRootDevice
+
+	out, err := h.RunSSHCommand(generateDisksCommand(device))
+	if err != nil {
+		return fmt.Errorf("Failed to get the disks of the node: '%s'", err)
+	}
+	if err := checkDataRootPersistence(device, out); err != nil {
+		return err
+	}
+
+	out, err = h.RunSSHCommand(generateImageCacheCommand(path))
+	if err != nil {
+		return fmt.Errorf("Failed to read the image cache marker of the Docker data root '%s': '%s'", path, err)
+	}
+
+	created, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		n.logf("Persistent Docker data root '%s' initialized on device '%s'", path, device)
+		return nil
+	}
+
+	out, err = h.RunSSHCommand(cleanReusedDataRootCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to clean the reused Docker data root '%s': '%s'", path, err)
+	}
+
+	n.cachedImages, _ = strconv.Atoi(strings.TrimSpace(out))
+	n.logf("Persistent Docker data root '%s' reused (first provisioned at %s): %d cached image(s)", path, time.Unix(created, 0).Format(time.RFC3339), n.cachedImages)
+	return nil
+}
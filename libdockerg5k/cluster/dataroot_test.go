@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDataRoot(t *testing.T) {
+	assert.NoError(t, validateDataRoot("", "", ""))
+	assert.NoError(t, validateDataRoot("/tmp/docker", "", "50g"))
+	assert.NoError(t, validateDataRoot("/mnt/docker", "/dev/sdb", ""))
+	assert.NoError(t, validateDataRoot("", "", "10g"))
+
+	assert.Error(t, validateDataRoot("tmp/docker", "", ""))
+	assert.Error(t, validateDataRoot("/", "", ""))
+	assert.Error(t, validateDataRoot("", "/dev/sdb", ""))
+	assert.Error(t, validateDataRoot("/mnt/docker", "sdb", ""))
+	assert.Error(t, validateDataRoot("/tmp/docker", "", "50 GB"))
+}
+
+func TestDataRoot(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{}}
+	assert.Equal(t, "/var/lib/docker", n.dataRoot())
+
+	n.clusterConfig.DataRoot = "/tmp/docker"
+	assert.Equal(t, "/tmp/docker", n.dataRoot())
+	assert.Contains(t, n.engineFlags(), "data-root=/tmp/docker")
+
+	// the node flag takes precedence
+	n.EngineOpt = []string{"data-root=/mnt/docker"}
+	assert.Equal(t, "/mnt/docker", n.dataRoot())
+	assert.Equal(t, []string{"data-root=/mnt/docker"}, n.engineFlags())
+}
+
+func TestGenerateMountDataRootCommand(t *testing.T) {
+	cmd := generateMountDataRootCommand("/mnt/docker", "/dev/sdb")
+	assert.Contains(t, cmd, "mkfs.ext4 -q /dev/sdb")
+	assert.Contains(t, cmd, "mount /dev/sdb /mnt/docker")
+	assert.Contains(t, cmd, "echo '/dev/sdb /mnt/docker ext4 defaults 0 2' >>/etc/fstab")
+}
+
+func TestValidateDataRootPersistence(t *testing.T) {
+	assert.NoError(t, (&GlobalConfig{}).validateDataRootPersistence())
+	assert.NoError(t, (&GlobalConfig{DataRoot: "/mnt/docker", DataRootDevice: "/dev/sdb", DataRootPersistent: true}).validateDataRootPersistence())
+	assert.Error(t, (&GlobalConfig{DataRoot: "/tmp/docker", DataRootPersistent: true}).validateDataRootPersistence())
+	assert.Error(t, (&GlobalConfig{DataRootPersistent: true}).Validate())
+}
+
+func TestCheckDataRootPersistence(t *testing.T) {
+	assert.NoError(t, checkDataRootPersistence("/dev/sdb", "sdb\nsda\n"))
+	assert.NoError(t, checkDataRootPersistence("/dev/disk/by-id/wwn-0x5000c500a1b2c3d4", "sdc\nsda\n"))
+
+	// the device is a partition of the system disk
+	assert.Error(t, checkDataRootPersistence("/dev/sda5", "sda\nsda\n"))
+	assert.Error(t, checkDataRootPersistence("/dev/sdb", ""))
+}
+
+func TestGenerateImageCacheCommand(t *testing.T) {
+	assert.Equal(t, "cat /mnt/docker/.docker-g5k-image-cache 2>/dev/null || date +%s >/mnt/docker/.docker-g5k-image-cache", generateImageCacheCommand("/mnt/docker"))
+	assert.Contains(t, generateDisksCommand("/dev/sdb"), "readlink -f /dev/sdb")
+}
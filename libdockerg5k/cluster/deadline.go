@@ -0,0 +1,155 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// provisionProgress record the nodes whose provisioning is finished (the provisionings still running after the deadline keep recording)
+// it is canceled when the deadline expires, so the provisioning stops before its next phase
+type provisionProgress struct {
+	mu       sync.Mutex
+	finished map[string]error
+	canceled bool
+}
+
+// newProvisionProgress returns an empty provisioning progress
+func newProvisionProgress() *provisionProgress {
+	return &provisionProgress{finished: make(map[string]error)}
+}
+
+// record the result of the node provisioning (nothing is recorded without progress)
+func (p *provisionProgress) record(machineName string, err error) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.finished[machineName] = err
+}
+
+// cancel the provisioning (the nodes already provisioning are not interrupted)
+func (p *provisionProgress) cancel() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.canceled = true
+}
+
+// isCanceled returns true if the provisioning was canceled (never without progress)
+func (p *provisionProgress) isCanceled() bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.canceled
+}
+
+// DeadlineError is returned when the provisioning of the cluster exceeds its deadline, with the nodes (by machine name) provisioned, failed and still provisioning when it expired
+type DeadlineError struct {
+	Deadline  time.Duration
+	Completed []string
+	Failed    []string
+	Pending   []string
+}
+
+// Error returns the deadline and the nodes provisioned within it
+func (e *DeadlineError) Error() string {
+	return fmt.Sprintf("%s after %s, %d/%d nodes provisioned within the deadline (%s), %d failed, %d still provisioning", ErrDeadline, e.Deadline, len(e.Completed), len(e.Completed)+len(e.Failed)+len(e.Pending), strings.Join(e.Completed, ", "), len(e.Failed), len(e.Pending))
+}
+
+// Unwrap returns ErrDeadline, to test the error with errors.Is
+func (e *DeadlineError) Unwrap() error {
+	return ErrDeadline
+}
+
+// deadlineError returns the deadline error with the state of each node of the cluster (sorted by machine name)
+func (p *provisionProgress) deadlineError(nodes map[string]*Node, deadline time.Duration) *DeadlineError {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := &DeadlineError{Deadline: deadline, Completed: []string{}, Failed: []string{}, Pending: []string{}}
+	for machineName := range nodes {
+		err, finished := p.finished[machineName]
+		switch {
+		case !finished:
+			e.Pending = append(e.Pending, machineName)
+		case err != nil:
+			e.Failed = append(e.Failed, machineName)
+		default:
+			e.Completed = append(e.Completed, machineName)
+		}
+	}
+	sort.Strings(e.Completed)
+	sort.Strings(e.Failed)
+	sort.Strings(e.Pending)
+
+	return e
+}
+
+// removeReappearedMachines wait (within the timeout) for the canceled provisioning to finish and force the removal of the machines saved again by the creations still running when the nodes were released
+// the machines created after the timeout are not removed, it returns the errors by machine name
+func (c *Cluster) removeReappearedMachines(done <-chan error, timeout time.Duration, exists func(machineName string) (bool, error), force func(machineName string) error) map[string]error {
+	errs := make(map[string]error)
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		log.Warnf("The canceled provisioning did not finish within %s, the machines created later will not be removed", timeout)
+	}
+
+	for machineName := range c.Nodes {
+		if found, err := exists(machineName); err != nil || !found {
+			continue
+		}
+
+		log.Warnf("The machine '%s' was created after the nodes release, removing it...", machineName)
+		if err := force(machineName); err != nil {
+			errs[machineName] = err
+		}
+	}
+
+	return errs
+}
+
+// provisionWithDeadline provision the nodes of the cluster under the provisioning deadline (if set), a DeadlineError is returned if it is exceeded
+// libmachine operations can't be canceled: the provisioning is canceled before its next phase, all the jobs of the cluster are released to stop the running provisionings and the machines are removed (including the ones saved by the creations finishing after the release)
+func (c *Cluster) provisionWithDeadline(strict bool) error {
+	if c.Config.ProvisionDeadline <= 0 {
+		return c.provisionNodes(strict, nil)
+	}
+
+	progress := newProvisionProgress()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.provisionNodes(strict, progress)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(c.Config.ProvisionDeadline):
+	}
+
+	progress.cancel()
+	err := progress.deadlineError(c.Nodes, c.Config.ProvisionDeadline)
+	log.Errorf("The cluster provisioning exceeded its deadline of %s (provisioned nodes: %s, still provisioning: %s)", err.Deadline, strings.Join(err.Completed, ", "), strings.Join(err.Pending, ", "))
+
+	log.Warn("Releasing all nodes...")
+	releaseErr := c.ReleaseNodes()
+	if errs := c.removeReappearedMachines(done, c.Config.deprovisionTimeout(), c.Config.LibMachineClient.Exists, c.Config.removeMachineStorage); len(errs) > 0 && releaseErr == nil {
+		releaseErr = fleetError("Release", errs)
+	}
+	if releaseErr != nil {
+		return fmt.Errorf("%w (%s)", err, releaseErr)
+	}
+
+	return err
+}
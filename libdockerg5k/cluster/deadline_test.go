@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvisionProgressDeadlineError(t *testing.T) {
+	nodes := map[string]*Node{"lille-0": {}, "lille-1": {}, "nancy-0": {}, "nancy-1": {}}
+
+	p := newProvisionProgress()
+	p.record("nancy-0", nil)
+	p.record("lille-0", nil)
+	p.record("lille-1", fmt.Errorf("node lille-1: swarm join: error"))
+
+	err := p.deadlineError(nodes, 20*time.Minute)
+	assert.Equal(t, []string{"lille-0", "nancy-0"}, err.Completed)
+	assert.Equal(t, []string{"lille-1"}, err.Failed)
+	assert.Equal(t, []string{"nancy-1"}, err.Pending)
+	assert.True(t, errors.Is(err, ErrDeadline))
+	assert.Equal(t, "provisioning deadline exceeded after 20m0s, 2/4 nodes provisioned within the deadline (lille-0, nancy-0), 1 failed, 1 still provisioning", err.Error())
+}
+
+func TestProvisionProgressNil(t *testing.T) {
+	// nothing is recorded without deadline
+	var p *provisionProgress
+	p.record("lille-0", nil)
+}
+
+func TestProvisionProgressCancel(t *testing.T) {
+	var nilProgress *provisionProgress
+	assert.False(t, nilProgress.isCanceled())
+
+	p := newProvisionProgress()
+	assert.False(t, p.isCanceled())
+	p.cancel()
+	assert.True(t, p.isCanceled())
+}
+
+func TestRemoveReappearedMachines(t *testing.T) {
+	c := &Cluster{Nodes: map[string]*Node{"lille-0": {}, "lille-1": {}, "nancy-0": {}}}
+
+	// the creation of lille-1 finishes after the release and saves its machine again
+	saved := map[string]bool{}
+	done := make(chan error, 1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		saved["lille-1"] = true
+		saved["nancy-0"] = true
+		done <- ErrDeadline
+	}()
+
+	removed := []string{}
+	errs := c.removeReappearedMachines(done, time.Minute, func(machineName string) (bool, error) {
+		return saved[machineName], nil
+	}, func(machineName string) error {
+		removed = append(removed, machineName)
+		if machineName == "nancy-0" {
+			return fmt.Errorf("permission denied")
+		}
+		return nil
+	})
+
+	sort.Strings(removed)
+	assert.Equal(t, []string{"lille-1", "nancy-0"}, removed)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs, "nancy-0")
+}
+
+func TestRemoveReappearedMachinesTimeout(t *testing.T) {
+	c := &Cluster{Nodes: map[string]*Node{"lille-0": {}}}
+
+	// the canceled provisioning never finishes
+	start := time.Now()
+	errs := c.removeReappearedMachines(make(chan error), 20*time.Millisecond, func(machineName string) (bool, error) {
+		return true, nil
+	}, func(machineName string) error {
+		return nil
+	})
+
+	assert.Empty(t, errs)
+	assert.True(t, time.Since(start) < time.Second)
+}
@@ -0,0 +1,110 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+)
+
+const (
+	// deployment modes of the nodes
+	DeployModeDeploy  = "deploy"  // deploy the environment image on the node (kadeploy, reproducible, default)
+	DeployModeClassic = "classic" // use the default environment of the node through SSH (no deployment, faster)
+)
+
+// validateDeployMode check the deployment mode (empty for the default mode)
+func validateDeployMode(mode string) error {
+	switch mode {
+	case "", DeployModeDeploy, DeployModeClassic:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid deployment mode: '%s' (supported: deploy, classic)", mode)
+}
+
+// deployMode returns the deployment mode of the node (the cluster mode if not set, deploy by default)
+func (n *Node) deployMode() string {
+	if n.DeployMode != "" {
+		return n.DeployMode
+	}
+
+	if n.clusterConfig.DeployMode != "" {
+		return n.clusterConfig.DeployMode
+	}
+
+	return DeployModeDeploy
+}
+
+// isClassicMode returns true if the node is used in its default environment (no deployment), false otherwise
+func (n *Node) isClassicMode() bool {
+	return n.deployMode() == DeployModeClassic
+}
+
+// DeployModeJobType returns the OAR job type of the nodes reserved in the deployment mode
+func DeployModeJobType(mode string) string {
+	if mode == DeployModeClassic {
+		return g5k.JobTypeClassicSSH
+	}
+
+	return g5k.JobTypeDeploy
+}
+
+// SiteDeployMode returns the deployment mode of the nodes of the site (the cluster mode if the site has no nodes)
+// the nodes of a site are reserved in a single job, whose type (deploy or classic SSH) can't be mixed
+func (c *Cluster) SiteDeployMode(site string) (string, error) {
+	modes := make(map[string][]string)
+	for _, n := range c.Nodes {
+		if n.G5kSite != site {
+			continue
+		}
+
+		if err := validateDeployMode(n.DeployMode); err != nil {
+			return "", fmt.Errorf("Node '%s': %s", n.MachineName, err)
+		}
+		modes[n.deployMode()] = append(modes[n.deployMode()], n.MachineName)
+	}
+
+	switch len(modes) {
+	case 0:
+		return (&Node{clusterConfig: c.Config}).deployMode(), nil
+	case 1:
+		for mode := range modes {
+			return mode, nil
+		}
+	}
+
+	classic := modes[DeployModeClassic]
+	sort.Strings(classic)
+	return "", fmt.Errorf("The nodes of site '%s' can't mix the deploy and classic modes, they are reserved in a single job (classic nodes: %v)", site, classic)
+}
+
+// validateDeployModes check the deployment mode of the nodes and that each site uses a single mode
+func (c *Cluster) validateDeployModes() error {
+	sites := make(map[string]bool)
+	for _, n := range c.Nodes {
+		sites[n.G5kSite] = true
+	}
+
+	for site := range sites {
+		if _, err := c.SiteDeployMode(site); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeployJobNodes deploy the image on the nodes of the job in deploy mode, and returns the nodes hostname (the nodes are used as is in classic mode)
+func DeployJobNodes(g5kAPI JobDeployer, site string, mode string, sshPublicKey string, jobID int, image string) ([]string, error) {
+	if mode != DeployModeClassic {
+		return g5kAPI.DeployNodes(site, sshPublicKey, jobID, image)
+	}
+
+	job, err := g5kAPI.GetJob(site, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return job.Nodes, nil
+}
@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDeployMode(t *testing.T) {
+	assert.NoError(t, validateDeployMode(""))
+	assert.NoError(t, validateDeployMode(DeployModeDeploy))
+	assert.NoError(t, validateDeployMode(DeployModeClassic))
+	assert.Error(t, validateDeployMode("kadeploy"))
+	assert.Error(t, (&GlobalConfig{DeployMode: "Classic"}).Validate())
+}
+
+func TestDeployModeJobType(t *testing.T) {
+	assert.Equal(t, g5k.JobTypeDeploy, DeployModeJobType(""))
+	assert.Equal(t, g5k.JobTypeDeploy, DeployModeJobType(DeployModeDeploy))
+	assert.Equal(t, g5k.JobTypeClassicSSH, DeployModeJobType(DeployModeClassic))
+}
+
+func TestSiteDeployMode(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.CreateNodes(map[string]int{"lille": 2, "nancy": 1})
+
+	mode, err := c.SiteDeployMode("lille")
+	assert.NoError(t, err)
+	assert.Equal(t, DeployModeDeploy, mode)
+
+	// the cluster mode is used by the nodes without mode and the sites without nodes
+	c.Config.DeployMode = DeployModeClassic
+	mode, err = c.SiteDeployMode("rennes")
+	assert.NoError(t, err)
+	assert.Equal(t, DeployModeClassic, mode)
+
+	c.Nodes["nancy-0"].DeployMode = DeployModeDeploy
+	mode, err = c.SiteDeployMode("nancy")
+	assert.NoError(t, err)
+	assert.Equal(t, DeployModeDeploy, mode)
+	assert.Equal(t, DeployModeClassic, c.Nodes["lille-0"].inventory().DeployMode)
+	assert.NoError(t, c.validateDeployModes())
+
+	// the nodes of a site are reserved in a single job
+	c.Nodes["lille-1"].DeployMode = DeployModeDeploy
+	_, err = c.SiteDeployMode("lille")
+	assert.EqualError(t, err, "The nodes of site 'lille' can't mix the deploy and classic modes, they are reserved in a single job (classic nodes: [lille-0])")
+	assert.Error(t, c.validateDeployModes())
+
+	c.Nodes["lille-1"].DeployMode = "quick"
+	assert.Error(t, c.validateDeployModes())
+}
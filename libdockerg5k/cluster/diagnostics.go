@@ -0,0 +1,256 @@
+package cluster
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// diagnosticsTimeout is the maximum time allowed to collect the logs and facts of all nodes
+	diagnosticsTimeout = 5 * time.Minute
+
+	// diagnosticsManifest is the name of the manifest file of the diagnostics bundle
+	diagnosticsManifest = "manifest.json"
+)
+
+// DiagnosticsManifest describe the content of a diagnostics bundle and the nodes or resources that could not be collected
+type DiagnosticsManifest struct {
+	Created          time.Time         `json:"created"`
+	ClusterID        string            `json:"cluster_id,omitempty"`
+	Annotations      map[string]string `json:"annotations,omitempty"` // metadata of the experiment
+	Files            []string          `json:"files"`
+	UnreachableNodes map[string]string `json:"unreachable_nodes,omitempty"` // machine name => error
+	Errors           []string          `json:"errors,omitempty"`            // configuration, inventory or jobs collection errors
+}
+
+// diagnosticsBundle contain the files of a diagnostics bundle (the nodes are collected in parallel, the writes are synchronized)
+type diagnosticsBundle struct {
+	mu       sync.Mutex
+	files    map[string][]byte
+	manifest DiagnosticsManifest
+}
+
+// newDiagnosticsBundle returns an empty diagnostics bundle of the cluster
+func newDiagnosticsBundle(clusterID string) *diagnosticsBundle {
+	return &diagnosticsBundle{
+		files:    make(map[string][]byte),
+		manifest: DiagnosticsManifest{Created: time.Now(), ClusterID: clusterID, UnreachableNodes: make(map[string]string)},
+	}
+}
+
+// add add the file to the bundle
+func (b *diagnosticsBundle) add(name string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.files[name] = data
+}
+
+// addJSON add the value to the bundle as an indented JSON file
+func (b *diagnosticsBundle) addJSON(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to encode '%s': '%s'", name, err)
+	}
+
+	b.add(name, data)
+	return nil
+}
+
+// addError record a collection error in the manifest
+func (b *diagnosticsBundle) addError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.manifest.Errors = append(b.manifest.Errors, err.Error())
+}
+
+// addUnreachableNode record a node that could not be collected in the manifest
+func (b *diagnosticsBundle) addUnreachableNode(machineName string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.manifest.UnreachableNodes[machineName] = err.Error()
+}
+
+// write write the bundle as a gzipped tarball (sorted files, followed by the manifest)
+func (b *diagnosticsBundle) write(w io.Writer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	names := []string{}
+	for name := range b.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	manifest := b.manifest
+	manifest.Files = names
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to encode the diagnostics manifest: '%s'", err)
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	writeFile := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: manifest.Created}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	for _, name := range names {
+		if err := writeFile(name, b.files[name]); err != nil {
+			return fmt.Errorf("Unable to write '%s' to the diagnostics bundle: '%s'", name, err)
+		}
+	}
+
+	if err := writeFile(diagnosticsManifest, manifestData); err != nil {
+		return fmt.Errorf("Unable to write the manifest to the diagnostics bundle: '%s'", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// diagnosticsLogName returns the name of a node log in the bundle from its collected logs file suffix (the Engine logs have no suffix)
+func diagnosticsLogName(machineName string, suffix string) string {
+	name := strings.TrimPrefix(suffix, "-")
+	if name == "" {
+		name = "engine"
+	}
+
+	return fmt.Sprintf("nodes/%s/%s.log", machineName, name)
+}
+
+// collectNodeDiagnostics add the Engine (and Weave/Zookeeper containers) logs and the live facts of the node to the bundle, all or nothing
+func (n *Node) collectNodeDiagnostics(b *diagnosticsBundle) error {
+	logs := make(map[string][]byte)
+	for suffix, cmd := range n.logsToCollect() {
+		out, err := n.runSSHCommand(cmd)
+		if err != nil {
+			return fmt.Errorf("Failed to get logs with command '%s': '%s'", cmd, err)
+		}
+		logs[diagnosticsLogName(n.MachineName, suffix)] = []byte(out)
+	}
+
+	facts, err := n.gatherFacts()
+	if err != nil {
+		return err
+	}
+
+	for name, data := range logs {
+		b.add(name, data)
+	}
+
+	return b.addJSON(fmt.Sprintf("nodes/%s/facts.json", n.MachineName), facts)
+}
+
+// collectProvisioningLogs add the provisioning log files of the nodes to the bundle (if the logs directory is set, the nodes without log file are skipped)
+func (c *Cluster) collectProvisioningLogs(b *diagnosticsBundle) {
+	if c.Config.LogDir == "" {
+		return
+	}
+
+	for machineName := range c.Nodes {
+		data, err := ioutil.ReadFile(filepath.Join(c.Config.LogDir, fmt.Sprintf("%s.log", machineName)))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				b.addError(fmt.Errorf("Unable to read the provisioning log of node '%s': '%s'", machineName, err))
+			}
+			continue
+		}
+
+		b.add(fmt.Sprintf("nodes/%s/provisioning.log", machineName), data)
+	}
+}
+
+// collectJobStates add the state of the Grid5000 jobs of the cluster to the bundle
+func (c *Cluster) collectJobStates(b *diagnosticsBundle) {
+	jobs := c.nodesByJob()
+	if len(jobs) == 0 {
+		return
+	}
+
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		b.addError(fmt.Errorf("Unable to get the jobs state: '%s'", err))
+		return
+	}
+
+	for k := range jobs {
+		job, err := g5kAPI.GetJob(k.site, k.jobID)
+		if err != nil {
+			b.addError(fmt.Errorf("Unable to get the job '%d' on site '%s': '%s'", k.jobID, k.site, err))
+			continue
+		}
+
+		if err := b.addJSON(fmt.Sprintf("jobs/%s-%d.json", k.site, k.jobID), job); err != nil {
+			b.addError(err)
+		}
+	}
+}
+
+// DiagnosticBundle write a diagnostics bundle of the cluster (gzipped tarball) to the destination path for a bug report: the configuration snapshot (without the secrets),
+// the inventory (with the provisioning errors), the provisioning log of each node (if the logs directory is set), the Engine (and Weave/Zookeeper containers) logs and the facts of each node,
+// and the state of the Grid5000 jobs. The unreachable nodes and the resources that could not be collected are reported in the bundle manifest, only the bundle write errors are returned
+func (c *Cluster) DiagnosticBundle(destPath string) error {
+	b := newDiagnosticsBundle(c.Config.ClusterID)
+	b.manifest.Annotations = c.Config.Annotations
+
+	// configuration and inventory
+	if s, err := c.Snapshot(); err != nil {
+		b.addError(err)
+	} else if err := b.addJSON("config.json", s); err != nil {
+		b.addError(err)
+	}
+
+	if err := b.addJSON("inventory.json", c.Inventory()); err != nil {
+		b.addError(err)
+	}
+
+	c.collectProvisioningLogs(b)
+
+	// live logs and facts of the nodes
+	errs := c.runOnNodes(diagnosticsTimeout, func(n *Node, h *host.Host) error {
+		return n.collectNodeDiagnostics(b)
+	})
+	for machineName, err := range errs {
+		log.Warnf("The diagnostics of node '%s' can't be collected: '%s'", machineName, err)
+		b.addUnreachableNode(machineName, err)
+	}
+
+	c.collectJobStates(b)
+
+	// write the bundle
+	f, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("Unable to create the diagnostics bundle '%s': '%s'", destPath, err)
+	}
+	defer f.Close()
+
+	if err := b.write(f); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readDiagnosticsBundle returns the files of a diagnostics bundle by name
+func readDiagnosticsBundle(t *testing.T, r io.Reader) map[string][]byte {
+	gr, err := gzip.NewReader(r)
+	assert.NoError(t, err)
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+
+		data, err := ioutil.ReadAll(tr)
+		assert.NoError(t, err)
+		files[hdr.Name] = data
+	}
+
+	return files
+}
+
+func TestDiagnosticsLogName(t *testing.T) {
+	assert.Equal(t, "nodes/lille-0/engine.log", diagnosticsLogName("lille-0", ""))
+	assert.Equal(t, "nodes/lille-0/zookeeper.log", diagnosticsLogName("lille-0", "-zookeeper"))
+}
+
+func TestDiagnosticsBundleWrite(t *testing.T) {
+	b := newDiagnosticsBundle("exp1")
+	b.add("nodes/lille-0/engine.log", []byte("started"))
+	assert.NoError(t, b.addJSON("config.json", map[string]string{"site": "lille"}))
+	b.addUnreachableNode("lille-1", errors.New("connection refused"))
+	b.addError(errors.New("no credentials"))
+
+	f, err := ioutil.TempFile("", "diagnostics")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	assert.NoError(t, b.write(f))
+	f.Seek(0, 0)
+
+	files := readDiagnosticsBundle(t, f)
+	assert.Equal(t, "started", string(files["nodes/lille-0/engine.log"]))
+
+	manifest := DiagnosticsManifest{}
+	assert.NoError(t, json.Unmarshal(files[diagnosticsManifest], &manifest))
+	assert.Equal(t, "exp1", manifest.ClusterID)
+	assert.Equal(t, []string{"config.json", "nodes/lille-0/engine.log"}, manifest.Files)
+	assert.Equal(t, map[string]string{"lille-1": "connection refused"}, manifest.UnreachableNodes)
+	assert.Equal(t, []string{"no credentials"}, manifest.Errors)
+}
+
+func TestCollectProvisioningLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "lille-0.log"), []byte("provisioned"), 0644))
+
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleWorker})
+	c.Config.LogDir = dir
+
+	b := newDiagnosticsBundle("")
+	c.collectProvisioningLogs(b)
+	assert.Equal(t, map[string][]byte{"nodes/lille-0/provisioning.log": []byte("provisioned")}, b.files)
+	assert.Empty(t, b.manifest.Errors)
+}
+
+func TestDiagnosticBundleWithoutNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diagnostics")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(dir, "bundle.tar.gz")
+	assert.NoError(t, NewCluster(&GlobalConfig{ClusterID: "exp1"}).DiagnosticBundle(dest))
+
+	f, err := os.Open(dest)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	files := readDiagnosticsBundle(t, f)
+	assert.Contains(t, files, "config.json")
+	assert.Contains(t, files, "inventory.json")
+	assert.Contains(t, files, diagnosticsManifest)
+
+	assert.Error(t, NewCluster(&GlobalConfig{}).DiagnosticBundle(filepath.Join(dir, "missing", "bundle.tar.gz")))
+}
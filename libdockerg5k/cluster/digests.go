@@ -0,0 +1,141 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// verifyDigestsTimeout is the maximum time allowed to inspect the images of all nodes
+	verifyDigestsTimeout = 2 * time.Minute
+
+	// missingImageDigest is the digest reported by the inspect command if the image is not present on the node
+	missingImageDigest = "-"
+)
+
+// DigestReport contain the digests (image ID) of an image on the nodes
+type DigestReport struct {
+	Image string `json:"image"`
+
+	// machine names of the nodes by image digest
+	Digests map[string][]string `json:"digests"`
+
+	// digest of the image on most nodes (empty if the image is on no node)
+	Reference string `json:"reference,omitempty"`
+
+	// nodes with another digest than the reference digest
+	Diverging []string `json:"diverging,omitempty"`
+
+	// nodes without the image
+	Missing []string `json:"missing,omitempty"`
+}
+
+// IsConsistent returns true if all nodes have the same digest of the image
+func (r *DigestReport) IsConsistent() bool {
+	return len(r.Diverging) == 0 && len(r.Missing) == 0
+}
+
+// generateInspectDigestsCommand returns the command printing the digest of each image on the node (one line by image)
+func generateInspectDigestsCommand(images []string) string {
+	cmds := []string{}
+	for _, i := range images {
+		cmds = append(cmds, fmt.Sprintf("{ docker image inspect --format '%s {{.Id}}' '%s' 2>/dev/null || echo '%s %s'; }", i, i, i, missingImageDigest))
+	}
+
+	return strings.Join(cmds, "; ")
+}
+
+// parseImageDigests returns the digest of the images by image name from the inspect command output
+func parseImageDigests(out string) map[string]string {
+	digests := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		digests[fields[0]] = fields[1]
+	}
+
+	return digests
+}
+
+// newDigestReport returns the report of the image from the digests of the nodes by machine name
+func newDigestReport(image string, nodes map[string]string) *DigestReport {
+	r := &DigestReport{Image: image, Digests: make(map[string][]string)}
+
+	for machineName, digest := range nodes {
+		if digest == missingImageDigest || digest == "" {
+			r.Missing = append(r.Missing, machineName)
+			continue
+		}
+		r.Digests[digest] = append(r.Digests[digest], machineName)
+	}
+	sort.Strings(r.Missing)
+
+	// the reference is the digest of most nodes (the smallest digest if tied, to be deterministic)
+	for digest, machineNames := range r.Digests {
+		sort.Strings(machineNames)
+		if r.Reference == "" || len(machineNames) > len(r.Digests[r.Reference]) || (len(machineNames) == len(r.Digests[r.Reference]) && digest < r.Reference) {
+			r.Reference = digest
+		}
+	}
+
+	for digest, machineNames := range r.Digests {
+		if digest != r.Reference {
+			r.Diverging = append(r.Diverging, machineNames...)
+		}
+	}
+	sort.Strings(r.Diverging)
+
+	return r
+}
+
+// VerifyImageDigests inspect the images on the selected nodes (all nodes if the selector is empty) and returns the digests report by image
+// The nodes whose images can't be inspected are reported in the returned error, the reports only contain the inspected nodes
+func (c *Cluster) VerifyImageDigests(images []string, sel *NodeSelector) (map[string]*DigestReport, error) {
+	if err := c.checkSelection(sel); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	results := make(map[string]map[string]string)
+
+	errs := c.runOnSelectedNodes(sel, verifyDigestsTimeout, func(n *Node, h *host.Host) error {
+		out, err := n.runSSHCommand(generateInspectDigestsCommand(images))
+		if err != nil {
+			return fmt.Errorf("Failed to inspect the images: '%s'", err)
+		}
+
+		mu.Lock()
+		results[n.MachineName] = parseImageDigests(out)
+		mu.Unlock()
+
+		return nil
+	})
+
+	// build the reports (locked to not race with the nodes still running after a timeout)
+	mu.Lock()
+	defer mu.Unlock()
+
+	reports := make(map[string]*DigestReport)
+	for _, i := range images {
+		nodes := make(map[string]string)
+		for machineName, digests := range results {
+			nodes[machineName] = digests[i]
+		}
+
+		r := newDigestReport(i, nodes)
+		if !r.IsConsistent() {
+			log.Warnf("The image '%s' differs on the nodes: %d node(s) diverging from digest '%s' (%s), %d node(s) without the image (%s)", i, len(r.Diverging), r.Reference, strings.Join(r.Diverging, ", "), len(r.Missing), strings.Join(r.Missing, ", "))
+		}
+		reports[i] = r
+	}
+
+	return reports, fleetError("Image digests verification", errs)
+}
@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateInspectDigestsCommand(t *testing.T) {
+	assert.Equal(t, "{ docker image inspect --format 'nginx:1.25 {{.Id}}' 'nginx:1.25' 2>/dev/null || echo 'nginx:1.25 -'; }; { docker image inspect --format 'redis {{.Id}}' 'redis' 2>/dev/null || echo 'redis -'; }",
+		generateInspectDigestsCommand([]string{"nginx:1.25", "redis"}))
+}
+
+func TestParseImageDigests(t *testing.T) {
+	assert.Equal(t, map[string]string{"nginx:1.25": "sha256:aaa", "redis": "-"}, parseImageDigests("nginx:1.25 sha256:aaa\nredis -\n\n"))
+}
+
+func TestNewDigestReport(t *testing.T) {
+	r := newDigestReport("nginx:1.25", map[string]string{
+		"nancy-0":  "sha256:aaa",
+		"nancy-1":  "sha256:aaa",
+		"nancy-2":  "sha256:bbb",
+		"rennes-0": "-",
+		"rennes-1": "sha256:aaa",
+	})
+
+	assert.Equal(t, "sha256:aaa", r.Reference)
+	assert.Equal(t, map[string][]string{"sha256:aaa": {"nancy-0", "nancy-1", "rennes-1"}, "sha256:bbb": {"nancy-2"}}, r.Digests)
+	assert.Equal(t, []string{"nancy-2"}, r.Diverging)
+	assert.Equal(t, []string{"rennes-0"}, r.Missing)
+	assert.False(t, r.IsConsistent())
+}
+
+func TestNewDigestReportConsistent(t *testing.T) {
+	r := newDigestReport("redis", map[string]string{"nancy-0": "sha256:aaa", "nancy-1": "sha256:aaa"})
+	assert.True(t, r.IsConsistent())
+
+	// tied digests
+	r = newDigestReport("redis", map[string]string{"nancy-0": "sha256:bbb", "nancy-1": "sha256:aaa"})
+	assert.Equal(t, "sha256:aaa", r.Reference)
+	assert.Equal(t, []string{"nancy-0"}, r.Diverging)
+
+	// image missing on all nodes
+	r = newDigestReport("redis", map[string]string{"nancy-0": "-"})
+	assert.Equal(t, "", r.Reference)
+	assert.Equal(t, []string{"nancy-0"}, r.Missing)
+}
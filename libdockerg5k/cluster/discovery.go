@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+// subnetsOverlap returns true if the two subnets (CIDR) overlap, false otherwise (or if a subnet is invalid)
+func subnetsOverlap(a string, b string) bool {
+	_, netA, err := net.ParseCIDR(a)
+	if err != nil {
+		return false
+	}
+	_, netB, err := net.ParseCIDR(b)
+	if err != nil {
+		return false
+	}
+
+	return netA.Contains(netB.IP) || netB.Contains(netA.IP)
+}
+
+// serviceDiscoverySubnet returns the subnet of the Swarm mode service discovery network (empty if not set or without Swarm mode)
+func (c *GlobalConfig) serviceDiscoverySubnet() string {
+	if c.SwarmModeGlobalConfig == nil || c.SwarmModeGlobalConfig.ServiceDiscoveryNetwork == "" {
+		return ""
+	}
+
+	return c.SwarmModeGlobalConfig.ServiceDiscoverySubnet
+}
+
+// validateServiceDiscoverySubnet check the service discovery subnet does not overlap the bridge subnet (the subnet itself is checked with the Swarm mode configuration)
+func (c *GlobalConfig) validateServiceDiscoverySubnet() error {
+	subnet := c.serviceDiscoverySubnet()
+	if subnet != "" && c.BridgeSubnet != "" && subnetsOverlap(subnet, c.BridgeSubnet) {
+		return fmt.Errorf("The service discovery subnet '%s' overlaps the bridge subnet '%s'", subnet, c.BridgeSubnet)
+	}
+
+	return nil
+}
+
+// checkServiceDiscoverySubnet check the service discovery subnet does not overlap the subnets of the node's host, including the bridge (if configured)
+func (n *Node) checkServiceDiscoverySubnet(h *host.Host) error {
+	subnet := n.clusterConfig.serviceDiscoverySubnet()
+	if subnet == "" {
+		return nil
+	}
+
+	out, err := h.RunSSHCommand("ip -o -4 addr show")
+	if err != nil {
+		return fmt.Errorf("Failed to get the node addresses: '%s'", err)
+	}
+
+	if addr := findOverlappingInterfaceAddress(subnet, out, ""); addr != "" {
+		return fmt.Errorf("The service discovery subnet '%s' overlaps the node address %s", subnet, addr)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubnetsOverlap(t *testing.T) {
+	assert.True(t, subnetsOverlap("10.20.0.0/16", "10.20.1.0/24"))
+	assert.True(t, subnetsOverlap("10.0.0.0/8", "10.20.0.0/16"))
+	assert.False(t, subnetsOverlap("10.20.0.0/16", "10.21.0.0/16"))
+	assert.False(t, subnetsOverlap("10.20.0.0/16", "invalid"))
+}
+
+func TestValidateServiceDiscoverySubnet(t *testing.T) {
+	c := &GlobalConfig{BridgeSubnet: "10.20.0.0/16", SwarmModeGlobalConfig: &swarm.SwarmModeGlobalConfig{ServiceDiscoveryNetwork: "discovery", ServiceDiscoverySubnet: "10.20.128.0/24"}}
+	assert.Error(t, c.validateServiceDiscoverySubnet())
+
+	c.SwarmModeGlobalConfig.ServiceDiscoverySubnet = "10.30.0.0/24"
+	assert.NoError(t, c.validateServiceDiscoverySubnet())
+	assert.Equal(t, "10.30.0.0/24", c.serviceDiscoverySubnet())
+
+	// the subnet is ignored without the service discovery network
+	c.SwarmModeGlobalConfig.ServiceDiscoveryNetwork = ""
+	assert.Equal(t, "", c.serviceDiscoverySubnet())
+}
+
+func TestFindOverlappingInterfaceAddress(t *testing.T) {
+	out := "3: docker0    inet 172.17.0.1/16 brd 172.17.255.255 scope global docker0\n"
+	assert.Equal(t, "172.17.0.1/16 (docker0)", findOverlappingInterfaceAddress("172.17.5.0/24", out, ""))
+	assert.Equal(t, "", findOverlappingAddress("172.17.5.0/24", out))
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// dnsFlagOptions are the resolver options without value (resolv.conf 'options')
+	dnsFlagOptions = map[string]bool{
+		"rotate":                true,
+		"debug":                 true,
+		"edns0":                 true,
+		"inet6":                 true,
+		"no-tld-query":          true,
+		"no-reload":             true,
+		"single-request":        true,
+		"single-request-reopen": true,
+		"trust-ad":              true,
+		"use-vc":                true,
+	}
+
+	// dnsValueOptions are the resolver options with a numeric value (option:n) and their maximum value
+	dnsValueOptions = map[string]int{
+		"ndots":    15,
+		"timeout":  30,
+		"attempts": 5,
+	}
+)
+
+// validateDNSOption check the container resolver option (format: option or option:n, ex: ndots:1)
+func validateDNSOption(opt string) error {
+	s := strings.SplitN(opt, ":", 2)
+	name := s[0]
+	hasValue := len(s) == 2
+
+	if dnsFlagOptions[name] && !hasValue {
+		return nil
+	}
+
+	max, ok := dnsValueOptions[name]
+	if !ok {
+		return fmt.Errorf("Unknown container DNS option: '%s' (supported: ndots:n, timeout:n, attempts:n, rotate, edns0, single-request, ...)", opt)
+	}
+
+	if !hasValue {
+		return fmt.Errorf("Invalid container DNS option: '%s' (format: %s:n, with n between 0 and %d)", opt, name, max)
+	}
+
+	n, err := strconv.Atoi(s[1])
+	if err != nil || n < 0 || n > max {
+		return fmt.Errorf("Invalid container DNS option: '%s' (format: %s:n, with n between 0 and %d)", opt, name, max)
+	}
+
+	return nil
+}
+
+// validateDNSOptions check the container resolver options
+func validateDNSOptions(opts []string) error {
+	for _, opt := range opts {
+		if err := validateDNSOption(opt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dnsOptions returns the resolver options of the node containers (the node 'dns-opt' flags take precedence, empty for the Docker defaults)
+func (n *Node) dnsOptions() []string {
+	if getEngineFlagValue(n.EngineOpt, "dns-opt") != "" {
+		opts := []string{}
+		for _, f := range n.EngineOpt {
+			if s := strings.SplitN(f, "=", 2); len(s) == 2 && s[0] == "dns-opt" {
+				opts = append(opts, s[1])
+			}
+		}
+		return opts
+	}
+
+	return n.clusterConfig.ContainerDNSOptions
+}
+
+// dnsEngineFlags returns the 'dns-opt' Engine flags of the cluster container resolver options (none if the node sets its own options)
+func (n *Node) dnsEngineFlags() []string {
+	flags := []string{}
+	if getEngineFlagValue(n.EngineOpt, "dns-opt") != "" {
+		return flags
+	}
+
+	for _, opt := range n.clusterConfig.ContainerDNSOptions {
+		flags = append(flags, fmt.Sprintf("dns-opt=%s", opt))
+	}
+
+	return flags
+}
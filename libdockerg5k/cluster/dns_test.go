@@ -0,0 +1,39 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDNSOptions(t *testing.T) {
+	assert.NoError(t, validateDNSOptions(nil))
+	assert.NoError(t, validateDNSOptions([]string{"ndots:1", "timeout:2", "attempts:3", "rotate", "single-request-reopen"}))
+}
+
+func TestValidateDNSOptionsIncorrect(t *testing.T) {
+	assert.Error(t, validateDNSOptions([]string{"ndots"}))
+	assert.Error(t, validateDNSOptions([]string{"ndots:x"}))
+	assert.Error(t, validateDNSOptions([]string{"ndots:16"}))
+	assert.Error(t, validateDNSOptions([]string{"ndots:-1"}))
+	assert.Error(t, validateDNSOptions([]string{"rotate:1"}))
+	assert.Error(t, validateDNSOptions([]string{"unknown"}))
+}
+
+func TestDNSEngineFlags(t *testing.T) {
+	config := &GlobalConfig{ContainerDNSOptions: []string{"ndots:1", "timeout:2"}}
+
+	n := &Node{clusterConfig: config}
+	assert.Equal(t, []string{"dns-opt=ndots:1", "dns-opt=timeout:2"}, n.dnsEngineFlags())
+	assert.Equal(t, []string{"ndots:1", "timeout:2"}, n.dnsOptions())
+
+	// the node options take precedence
+	n = &Node{clusterConfig: config, EngineOpt: []string{"dns-opt=ndots:2", "dns-opt=rotate"}}
+	assert.Empty(t, n.dnsEngineFlags())
+	assert.Equal(t, []string{"ndots:2", "rotate"}, n.dnsOptions())
+
+	// Docker defaults
+	n = &Node{clusterConfig: &GlobalConfig{}}
+	assert.Empty(t, n.dnsEngineFlags())
+	assert.Empty(t, n.dnsOptions())
+}
@@ -0,0 +1,188 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// drainSiteTimeout is the default maximum time allowed for the tasks of a drained site to be stopped
+	drainSiteTimeout = 10 * time.Minute
+
+	// runningTasksCommand returns the ID and current state of the tasks of the Swarm mode nodes (followed by the nodes ID)
+	runningTasksCommand = "docker node ps --format '{{.ID}} {{.CurrentState}}'"
+)
+
+// countRunningTasks returns the number of tasks still running in the output of the running tasks command (the tasks already shut down are ignored)
+func countRunningTasks(out string) int {
+	count := 0
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == "Running" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// waitForTasksDrained wait until no task is running on the Swarm mode nodes (the manager host is used to get the tasks status)
+func waitForTasksDrained(manager *host.Host, nodeIDs []string, timeout time.Duration) error {
+	count := 0
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(5 * time.Second) {
+		out, err := manager.RunSSHCommand(fmt.Sprintf("%s %s", runningTasksCommand, strings.Join(nodeIDs, " ")))
+		if err != nil {
+			continue
+		}
+
+		if count = countRunningTasks(out); count == 0 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%d tasks are still running on the drained nodes after %s", count, timeout)
+}
+
+// checkDrainQuorum check the Swarm managers outside of the drained nodes (machine names) are enough to keep the Raft quorum while the drained nodes are unavailable
+func (c *Cluster) checkDrainQuorum(status []swarm.ManagerStatus, drained map[string]bool) error {
+	remaining := 0
+	for _, n := range c.reachableManagerNodes(status) {
+		if !drained[n.MachineName] {
+			remaining++
+		}
+	}
+
+	if quorum := len(status)/2 + 1; remaining < quorum {
+		return fmt.Errorf("Draining the nodes would break the Swarm quorum: %d reachable managers left, %d required (%d managers)", remaining, quorum, len(status))
+	}
+
+	return nil
+}
+
+// siteSwarmNodes returns the machine names (sorted) and the Swarm mode node ID of the cluster nodes of the given site
+func (c *Cluster) siteSwarmNodes(site string) ([]string, map[string]string, error) {
+	sel := &NodeSelector{Sites: []string{site}}
+	if err := c.checkSelection(sel); err != nil {
+		return nil, nil, fmt.Errorf("Site '%s': %s", site, err)
+	}
+
+	machineNames := c.selectNodes(sel)
+	nodeIDs := make(map[string]string)
+	errs := make(map[string]error)
+	for _, machineName := range machineNames {
+		h, err := c.Nodes[machineName].loadHost()
+		if err != nil {
+			errs[machineName] = err
+			continue
+		}
+
+		out, err := h.RunSSHCommand(swarmNodeIDCommand)
+		if err != nil {
+			errs[machineName] = fmt.Errorf("Failed to get the Swarm node ID: '%s'", err)
+			continue
+		}
+		nodeIDs[machineName] = strings.TrimSpace(out)
+	}
+
+	return machineNames, nodeIDs, fleetError("Swarm node ID", errs)
+}
+
+// DrainSite drain the Swarm mode tasks of all the nodes of the site (for a site maintenance) and wait until the tasks are stopped on the site and rescheduled on the other sites
+// or the timeout expire (default timeout if 0). The drain is refused if the Swarm managers of the other sites can't keep the quorum while the site is unavailable
+func (c *Cluster) DrainSite(site string, timeout time.Duration) error {
+	if c.Config.SwarmModeGlobalConfig == nil {
+		return fmt.Errorf("Swarm mode is not enabled for this cluster")
+	}
+
+	if timeout < 0 {
+		return fmt.Errorf("Invalid drain timeout: %s", timeout)
+	}
+	if timeout == 0 {
+		timeout = drainSiteTimeout
+	}
+
+	machineNames, nodeIDs, err := c.siteSwarmNodes(site)
+	if err != nil {
+		return err
+	}
+
+	if len(machineNames) == len(c.Nodes) {
+		return fmt.Errorf("All the nodes of the cluster are on site '%s', the tasks can't be rescheduled on other sites", site)
+	}
+
+	drained := make(map[string]bool)
+	for _, machineName := range machineNames {
+		drained[machineName] = true
+	}
+
+	// refuse to drain the site if the managers of the other sites don't keep the quorum
+	status, err := c.managersStatus()
+	if err != nil {
+		return err
+	}
+	if err := c.checkDrainQuorum(status, drained); err != nil {
+		return fmt.Errorf("Site '%s': %s", site, err)
+	}
+
+	// a manager of another site is used to drain the nodes
+	manager, err := c.swarmManager(machineNames...)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Draining the %d nodes of site '%s'...", len(machineNames), site)
+
+	ids := []string{}
+	for _, machineName := range machineNames {
+		if err := setSwarmNodeAvailability(manager, nodeIDs[machineName], "drain"); err != nil {
+			return fleetError("Site drain", map[string]error{machineName: err})
+		}
+		ids = append(ids, nodeIDs[machineName])
+	}
+
+	// wait for the tasks to be rescheduled on the other sites
+	if err := waitForTasksDrained(manager, ids, timeout); err != nil {
+		return fmt.Errorf("Site '%s' drain failed: '%s'", site, err)
+	}
+
+	log.Infof("The %d nodes of site '%s' are drained", len(machineNames), site)
+
+	return nil
+}
+
+// ActivateSite schedule the Swarm mode tasks on all the nodes of the site again (after a site maintenance)
+func (c *Cluster) ActivateSite(site string) error {
+	if c.Config.SwarmModeGlobalConfig == nil {
+		return fmt.Errorf("Swarm mode is not enabled for this cluster")
+	}
+
+	machineNames, nodeIDs, err := c.siteSwarmNodes(site)
+	if err != nil {
+		return err
+	}
+
+	manager, err := c.swarmManager()
+	if err != nil {
+		return err
+	}
+
+	errs := make(map[string]error)
+	for _, machineName := range machineNames {
+		if err := setSwarmNodeAvailability(manager, nodeIDs[machineName], "active"); err != nil {
+			errs[machineName] = err
+		}
+	}
+
+	if err := fleetError("Site activation", errs); err != nil {
+		return err
+	}
+
+	log.Infof("The %d nodes of site '%s' are active", len(machineNames), site)
+
+	return nil
+}
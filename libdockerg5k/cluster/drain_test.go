@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountRunningTasks(t *testing.T) {
+	out := "x1 Running 2 hours ago\nx2 Shutdown 3 minutes ago\nx3 Running 5 seconds ago\nx4 Failed 1 hour ago\n\n"
+
+	assert.Equal(t, 2, countRunningTasks(out))
+	assert.Equal(t, 0, countRunningTasks(""))
+}
+
+func TestCheckDrainQuorum(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0", "lille-1", "nancy-0"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleManager, "nancy-0": NodeRoleManager, "rennes-0": NodeRoleManager})
+
+	status := []swarm.ManagerStatus{
+		{Hostname: "lille-0", Leader: true, Reachability: "reachable"},
+		{Hostname: "lille-1", Reachability: "reachable"},
+		{Hostname: "nancy-0", Reachability: "reachable"},
+	}
+
+	// 2 of 3 managers on the drained site
+	assert.Error(t, c.checkDrainQuorum(status, map[string]bool{"lille-0": true, "lille-1": true}))
+	assert.NoError(t, c.checkDrainQuorum(status, map[string]bool{"nancy-0": true}))
+
+	// an unreachable manager does not count in the remaining managers
+	status = append(status, swarm.ManagerStatus{Hostname: "rennes-0", Reachability: "unreachable"})
+	assert.Error(t, c.checkDrainQuorum(status, map[string]bool{"nancy-0": true}))
+}
+
+func TestDrainSiteRequireSwarmMode(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	assert.Error(t, c.DrainSite("lille", 0))
+	assert.Error(t, c.ActivateSite("lille"))
+}
+
+func TestDrainSiteIncorrect(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager})
+	c.Nodes["lille-0"].G5kSite = "lille"
+
+	assert.Error(t, c.DrainSite("nancy", 0))
+	assert.Error(t, c.DrainSite("lille", -1))
+	assert.Error(t, c.ActivateSite("nancy"))
+}
@@ -0,0 +1,239 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	// regexSize match a size with an optional unit (b, k, m or g)
+	regexSize = regexp.MustCompile("^[[:digit:]]+[bkmgBKMG]?$")
+
+	// regexAPIVersion match a Docker API version (ex: 1.24)
+	regexAPIVersion = regexp.MustCompile("^[[:digit:]]+\\.[[:digit:]]+$")
+)
+
+// validateEngineAPIVersion check the Docker API version format
+func validateEngineAPIVersion(version string) error {
+	if !regexAPIVersion.MatchString(version) {
+		return fmt.Errorf("The Docker API version is not valid: '%s' (format: major.minor, ex: 1.24)", version)
+	}
+
+	return nil
+}
+
+// generateAPIVersionCommand returns the command used to pin the Docker API version of the clients running on the node
+func generateAPIVersionCommand(version string) string {
+	return fmt.Sprintf("sed -i '/^DOCKER_API_VERSION=/d' /etc/environment && echo 'DOCKER_API_VERSION=%s' >>/etc/environment", version)
+}
+
+// configureAPIVersion pin the Docker API version used by the clients running on the node's host (if configured)
+func (n *Node) configureAPIVersion(h *host.Host) error {
+	if n.clusterConfig.EngineAPIVersion == "" {
+		return nil
+	}
+
+	if _, err := h.RunSSHCommand(generateAPIVersionCommand(n.clusterConfig.EngineAPIVersion)); err != nil {
+		return fmt.Errorf("Failed to pin the Docker API version: '%s'", err)
+	}
+
+	return nil
+}
+
+// LogRotation contain the rotation configuration of the 'json-file' log driver
+type LogRotation struct {
+	MaxSize string // maximum size of the log before it is rotated (ex: 10m)
+	MaxFile int    // maximum number of log files kept
+}
+
+// IsSet returns true if log rotation is configured, false otherwise
+func (l *LogRotation) IsSet() bool {
+	return l.MaxSize != "" || l.MaxFile != 0
+}
+
+// Validate check the log rotation configuration
+func (l *LogRotation) Validate() error {
+	if l.MaxSize != "" && !regexSize.MatchString(l.MaxSize) {
+		return fmt.Errorf("The log rotation max size is not a valid size: '%s'", l.MaxSize)
+	}
+
+	if l.MaxFile < 0 {
+		return fmt.Errorf("The log rotation max file must be a positive number: '%d'", l.MaxFile)
+	}
+
+	if l.MaxFile > 1 && l.MaxSize == "" {
+		return fmt.Errorf("The log rotation max size is needed to keep more than one log file")
+	}
+
+	return nil
+}
+
+// engineFlags returns the Engine flags for the log rotation
+func (l *LogRotation) engineFlags() []string {
+	flags := []string{}
+
+	if l.MaxSize != "" {
+		flags = append(flags, fmt.Sprintf("log-opt=max-size=%s", l.MaxSize))
+	}
+
+	if l.MaxFile != 0 {
+		flags = append(flags, fmt.Sprintf("log-opt=max-file=%d", l.MaxFile))
+	}
+
+	return flags
+}
+
+// getEngineFlagValue returns the value of the flag in the given Engine flags (empty if not found)
+func getEngineFlagValue(flags []string, name string) string {
+	for _, f := range flags {
+		if s := strings.SplitN(f, "=", 2); len(s) == 2 && s[0] == name {
+			return s[1]
+		}
+	}
+
+	return ""
+}
+
+// validateBridgeSubnet check the bridge subnet is a valid IPv4 CIDR
+func validateBridgeSubnet(subnet string) error {
+	ip, _, err := net.ParseCIDR(subnet)
+	if err != nil || ip.To4() == nil {
+		return fmt.Errorf("The bridge subnet is not a valid IPv4 CIDR: '%s'", subnet)
+	}
+
+	return nil
+}
+
+// bridgeIP returns the IP address (with the prefix length) of the bridge in the subnet (first address if the network address is given)
+func bridgeIP(subnet string) string {
+	ip, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return subnet
+	}
+
+	// use the first address of the subnet instead of the network address
+	ip = ip.To4()
+	if ip.Equal(ipNet.IP) {
+		ip = net.IPv4(ip[0], ip[1], ip[2], ip[3]+1)
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	return fmt.Sprintf("%s/%d", ip, ones)
+}
+
+// findOverlappingAddress returns the address (from the 'ip -o -4 addr show' output) overlapping the bridge subnet, excluding the bridge itself (empty if none)
+func findOverlappingAddress(subnet string, ipAddrOutput string) string {
+	return findOverlappingInterfaceAddress(subnet, ipAddrOutput, "docker0")
+}
+
+// findOverlappingInterfaceAddress returns the address (from the 'ip -o -4 addr show' output) overlapping the subnet, excluding the given interface (empty if none)
+func findOverlappingInterfaceAddress(subnet string, ipAddrOutput string, excludedInterface string) string {
+	_, bridgeNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(ipAddrOutput, "\n") {
+		// line format: {index}: {interface} inet {address}/{prefix} ...
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[2] != "inet" || fields[1] == excludedInterface {
+			continue
+		}
+
+		_, addrNet, err := net.ParseCIDR(fields[3])
+		if err != nil {
+			continue
+		}
+
+		if bridgeNet.Contains(addrNet.IP) || addrNet.Contains(bridgeNet.IP) {
+			return fmt.Sprintf("%s (%s)", fields[3], fields[1])
+		}
+	}
+
+	return ""
+}
+
+// checkBridgeSubnet check the bridge subnet does not overlap the subnets of the node's host (if configured)
+func (n *Node) checkBridgeSubnet(h *host.Host) error {
+	if n.clusterConfig.BridgeSubnet == "" {
+		return nil
+	}
+
+	out, err := h.RunSSHCommand("ip -o -4 addr show")
+	if err != nil {
+		return fmt.Errorf("Failed to get the node addresses: '%s'", err)
+	}
+
+	if addr := findOverlappingAddress(n.clusterConfig.BridgeSubnet, out); addr != "" {
+		return fmt.Errorf("The bridge subnet '%s' overlaps the node address %s", n.clusterConfig.BridgeSubnet, addr)
+	}
+
+	return nil
+}
+
+// engineFlags returns the Engine flags of the node (cluster flags followed by the node flags)
+func (n *Node) engineFlags() []string {
+	flags := []string{}
+
+	// log rotation (only for the 'json-file' log driver)
+	if n.clusterConfig.LogRotation.IsSet() {
+		if d := getEngineFlagValue(n.EngineOpt, "log-driver"); d == "" || d == "json-file" {
+			flags = append(flags, n.clusterConfig.LogRotation.engineFlags()...)
+		} else {
+			log.Warnf("Log rotation is not applied on node '%s' ('%s') because it uses the '%s' log driver", n.NodeName, n.MachineName, d)
+		}
+	}
+
+	// bridge subnet (the node flag takes precedence)
+	if n.clusterConfig.BridgeSubnet != "" {
+		if b := getEngineFlagValue(n.EngineOpt, "bip"); b == "" {
+			flags = append(flags, fmt.Sprintf("bip=%s", bridgeIP(n.clusterConfig.BridgeSubnet)))
+		} else {
+			log.Warnf("The bridge subnet is not applied on node '%s' ('%s') because it uses the '%s' bridge IP", n.NodeName, n.MachineName, b)
+		}
+	}
+
+	// containers resolver options (the node flags take precedence)
+	flags = append(flags, n.dnsEngineFlags()...)
+
+	// data root directory (the node flag takes precedence)
+	if n.clusterConfig.DataRoot != "" && getEngineFlagValue(n.EngineOpt, "data-root") == "" {
+		flags = append(flags, fmt.Sprintf("data-root=%s", n.clusterConfig.DataRoot))
+	}
+
+	// debug mode and log level (the node flag takes precedence)
+	flags = append(flags, n.logLevelEngineFlags()...)
+
+	// default shm size of the containers (the node flag takes precedence)
+	flags = append(flags, n.shmSizeEngineFlags()...)
+
+	// experimental features
+	if n.clusterConfig.EngineExperimental {
+		flags = append(flags, "experimental")
+	}
+
+	// userland proxy and iptables (the node flags take precedence)
+	for _, f := range n.clusterConfig.networkEngineFlags() {
+		if getEngineFlagValue(n.EngineOpt, strings.SplitN(f, "=", 2)[0]) == "" {
+			flags = append(flags, f)
+		}
+	}
+
+	// OCI runtimes
+	flags = append(flags, n.clusterConfig.runtimeEngineFlags()...)
+
+	// Prometheus metrics endpoint
+	if n.clusterConfig.EngineMetricsAddr != "" {
+		flags = append(flags, fmt.Sprintf("metrics-addr=%s", n.clusterConfig.EngineMetricsAddr))
+	}
+
+	// resources advertised to the Swarm mode scheduler
+	flags = append(flags, n.AdvertisedResources.engineFlags()...)
+
+	return append(flags, n.EngineOpt...)
+}
@@ -0,0 +1,89 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogRotationValidate(t *testing.T) {
+	assert.NoError(t, (&LogRotation{}).Validate())
+	assert.NoError(t, (&LogRotation{MaxSize: "10m", MaxFile: 3}).Validate())
+	assert.Error(t, (&LogRotation{MaxSize: "10mb"}).Validate())
+	assert.Error(t, (&LogRotation{MaxSize: "10m", MaxFile: -1}).Validate())
+	assert.Error(t, (&LogRotation{MaxFile: 3}).Validate())
+}
+
+func TestEngineFlagsLogRotation(t *testing.T) {
+	n := &Node{
+		clusterConfig: &GlobalConfig{LogRotation: LogRotation{MaxSize: "10m", MaxFile: 3}},
+		EngineOpt:     []string{"graph=/tmp"},
+	}
+	assert.Equal(t, []string{"log-opt=max-size=10m", "log-opt=max-file=3", "graph=/tmp"}, n.engineFlags())
+}
+
+func TestEngineFlagsLogRotationOtherLogDriver(t *testing.T) {
+	n := &Node{
+		clusterConfig: &GlobalConfig{LogRotation: LogRotation{MaxSize: "10m"}},
+		EngineOpt:     []string{"log-driver=syslog"},
+	}
+	assert.Equal(t, []string{"log-driver=syslog"}, n.engineFlags())
+}
+
+func TestEngineFlagsAdvertisedResources(t *testing.T) {
+	n := &Node{
+		clusterConfig:       &GlobalConfig{},
+		EngineOpt:           []string{"graph=/tmp"},
+		AdvertisedResources: AdvertisedResources{NanoCPUs: 2000000000},
+	}
+	assert.Equal(t, []string{"node-generic-resource=cpu=2000000000", "graph=/tmp"}, n.engineFlags())
+}
+
+func TestEngineFlagsExperimental(t *testing.T) {
+	n := &Node{
+		clusterConfig: &GlobalConfig{EngineExperimental: true},
+		EngineOpt:     []string{"graph=/tmp"},
+	}
+	assert.Equal(t, []string{"experimental", "graph=/tmp"}, n.engineFlags())
+}
+
+func TestValidateEngineAPIVersion(t *testing.T) {
+	assert.NoError(t, validateEngineAPIVersion("1.24"))
+	assert.Error(t, validateEngineAPIVersion("1"))
+	assert.Error(t, validateEngineAPIVersion("v1.24"))
+	assert.Error(t, validateEngineAPIVersion("1.24; rm -rf /"))
+}
+
+func TestGenerateAPIVersionCommand(t *testing.T) {
+	assert.Equal(t, "sed -i '/^DOCKER_API_VERSION=/d' /etc/environment && echo 'DOCKER_API_VERSION=1.24' >>/etc/environment", generateAPIVersionCommand("1.24"))
+}
+
+func TestValidateBridgeSubnet(t *testing.T) {
+	assert.NoError(t, validateBridgeSubnet("10.200.0.0/16"))
+	assert.NoError(t, validateBridgeSubnet("192.168.5.1/24"))
+	assert.Error(t, validateBridgeSubnet("10.200.0.0"))
+	assert.Error(t, validateBridgeSubnet("fd00::/64"))
+}
+
+func TestBridgeIP(t *testing.T) {
+	assert.Equal(t, "10.200.0.1/16", bridgeIP("10.200.0.0/16"))
+	assert.Equal(t, "192.168.5.254/24", bridgeIP("192.168.5.254/24"))
+}
+
+func TestFindOverlappingAddress(t *testing.T) {
+	out := "1: lo    inet 127.0.0.1/8 scope host lo\n" +
+		"2: eth0    inet 172.16.20.5/20 brd 172.16.31.255 scope global eth0\n" +
+		"3: docker0    inet 172.17.0.1/16 scope global docker0\n"
+
+	assert.Equal(t, "172.16.20.5/20 (eth0)", findOverlappingAddress("172.16.0.0/12", out))
+	assert.Equal(t, "", findOverlappingAddress("172.17.0.0/16", out))
+	assert.Equal(t, "", findOverlappingAddress("10.200.0.0/16", out))
+}
+
+func TestEngineFlagsBridgeSubnet(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{BridgeSubnet: "10.200.0.0/16"}}
+	assert.Equal(t, []string{"bip=10.200.0.1/16"}, n.engineFlags())
+
+	n = &Node{clusterConfig: &GlobalConfig{BridgeSubnet: "10.200.0.0/16"}, EngineOpt: []string{"bip=10.100.0.1/24"}}
+	assert.Equal(t, []string{"bip=10.100.0.1/24"}, n.engineFlags())
+}
@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrReservation is returned when the Grid'5000 job reservation fails
+	ErrReservation = errors.New("reservation")
+	// ErrDeployment is returned when the deployment of the nodes fails
+	ErrDeployment = errors.New("deployment")
+	// ErrDriverConfig is returned when the Docker Machine driver can't be configured
+	ErrDriverConfig = errors.New("driver configuration")
+	// ErrMachineCreate is returned when the Docker Machine creation fails
+	ErrMachineCreate = errors.New("machine creation")
+	// ErrHook is returned when the user hook fails on the node
+	ErrHook = errors.New("hook")
+	// ErrSwap is returned when the swap can't be disabled on the node
+	ErrSwap = errors.New("swap")
+	// ErrSysctl is returned when the kernel parameters can't be set on the node
+	ErrSysctl = errors.New("sysctl")
+	// ErrFirewall is returned when the firewall rules can't be applied on the node
+	ErrFirewall = errors.New("firewall")
+	// ErrDataPath is returned when the offload features of the Swarm mode data path can't be set on the node
+	ErrDataPath = errors.New("data path tuning")
+	// ErrDataRoot is returned when the Docker data root can't be mounted or has not enough free space
+	ErrDataRoot = errors.New("data root")
+	// ErrEngineConfig is returned when the Docker Engine of the node can't be configured
+	ErrEngineConfig = errors.New("engine configuration")
+	// ErrAdvertisedResources is returned when the advertised resources exceed the node physical resources
+	ErrAdvertisedResources = errors.New("advertised resources")
+	// ErrSecurityProfile is returned when the security profiles can't be applied on the node
+	ErrSecurityProfile = errors.New("security profile")
+	// ErrHostsMapping is returned when the static lookup table of the node can't be updated
+	ErrHostsMapping = errors.New("hosts mapping")
+	// ErrJobFacts is returned when the Grid'5000 job facts can't be written on the node
+	ErrJobFacts = errors.New("job facts")
+	// ErrContainerCaps is returned when the default capabilities of the containers can't be written on the node
+	ErrContainerCaps = errors.New("container capabilities")
+	// ErrRegistry is returned when the registry can't be started
+	ErrRegistry = errors.New("registry")
+	// ErrLocalVolume is returned when a local volume can't be created
+	ErrLocalVolume = errors.New("local volume")
+	// ErrSharedMount is returned when a NFS shared directory can't be mounted
+	ErrSharedMount = errors.New("shared mount")
+	// ErrContainerNetwork is returned when the default container network can't be created
+	ErrContainerNetwork = errors.New("container network")
+	// ErrZookeeper is returned when the Zookeeper cluster storage can't be started or its ensemble has no leader
+	ErrZookeeper = errors.New("zookeeper")
+	// ErrWeave is returned when Weave Net/Discovery can't be started
+	ErrWeave = errors.New("weave")
+	// ErrSwarmInit is returned when the Swarm mode cluster initialization fails
+	ErrSwarmInit = errors.New("swarm init")
+	// ErrSwarmJoin is returned when the node can't join the Swarm mode cluster
+	ErrSwarmJoin = errors.New("swarm join")
+	// ErrHardwareLabels is returned when the hardware labels can't be added to the Swarm mode node
+	ErrHardwareLabels = errors.New("hardware labels")
+	// ErrServiceDiscovery is returned when the service discovery network can't be created or the registrator can't be started
+	ErrServiceDiscovery = errors.New("service discovery")
+	// ErrIngress is returned when the ingress controller can't be started
+	ErrIngress = errors.New("ingress")
+	// ErrBastion is returned when the bastion SOCKS proxy can't be started or is not reachable
+	ErrBastion = errors.New("bastion")
+	// ErrPlugin is returned when a provisioning plugin fails on the node
+	ErrPlugin = errors.New("plugin")
+
+	// ErrTimeout is returned when a provisioning phase does not finish before its timeout
+	ErrTimeout = errors.New("timeout")
+	// ErrDeadline is returned when the provisioning of the cluster does not finish before its deadline (all the nodes are released)
+	ErrDeadline = errors.New("provisioning deadline exceeded")
+	// ErrCreateStuck is returned when the Docker Machine creation does not return before the create phase timeout (the half-created machine is removed)
+	ErrCreateStuck = errors.New("machine creation stuck")
+
+	// ErrSwarmConflict is returned when both Swarm standalone and Swarm mode are enabled
+	ErrSwarmConflict = errors.New("Swarm standalone and Swarm mode are mutually exclusive, only one of them can be enabled")
+)
+
+// wrapError returns an error with the node name and the failed provisioning phase (both the phase and the error can be tested with errors.Is)
+func (n *Node) wrapError(phase error, err error) error {
+	return fmt.Errorf("node %s: %w: %w", n.MachineName, phase, err)
+}
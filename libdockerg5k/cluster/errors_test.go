@@ -0,0 +1,23 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapErrorMessage(t *testing.T) {
+	n := &Node{MachineName: "lille-0"}
+	err := n.wrapError(ErrWeave, errors.New("Weave Net run command failed"))
+	assert.EqualError(t, err, "node lille-0: weave: Weave Net run command failed")
+}
+
+func TestWrapErrorIs(t *testing.T) {
+	n := &Node{MachineName: "lille-0"}
+	cause := errors.New("connection refused")
+	err := n.wrapError(ErrSwarmJoin, cause)
+	assert.True(t, errors.Is(err, ErrSwarmJoin))
+	assert.True(t, errors.Is(err, cause))
+	assert.False(t, errors.Is(err, ErrSwarmInit))
+}
@@ -0,0 +1,213 @@
+package cluster
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// scopes of the Docker events
+	EventScopeLocal = "local" // events of the node Engine (containers, images, networks, volumes...)
+	EventScopeSwarm = "swarm" // events of the Swarm mode cluster (services, nodes, secrets, configs), received from each manager
+
+	// eventsCommand stream the Docker events of the given scope (JSON format, one event by line)
+	eventsCommand = "docker events --format '{{json .}}' --filter scope=%s"
+
+	// eventsRetryDelay is the time to wait before watching again the events of a node after its stream stopped (ex: Engine restart, SSH connection lost)
+	eventsRetryDelay = 5 * time.Second
+
+	// eventsDedupWindow is the time a Swarm event is remembered to drop its duplicates received from the other managers
+	eventsDedupWindow = 1 * time.Minute
+)
+
+// ClusterEvent contain a Docker event of the cluster and the node it was received from
+type ClusterEvent struct {
+	Node       string            `json:"node"`  // machine name of the source node (the first manager it was received from for the Swarm events)
+	Scope      string            `json:"scope"` // local or swarm
+	Type       string            `json:"type"`  // container, image, network, volume, daemon, plugin, service, node, secret or config
+	Action     string            `json:"action"`
+	ActorID    string            `json:"actor_id"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Time       time.Time         `json:"time"`
+}
+
+// dockerEvent contain a Docker event of the Engine API
+type dockerEvent struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Scope    string `json:"scope"`
+	TimeNano int64  `json:"timeNano"`
+}
+
+// parseEvent returns the cluster event of the Docker event (JSON format) received from the node
+func parseEvent(machineName string, line string) (ClusterEvent, error) {
+	var e dockerEvent
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		return ClusterEvent{}, fmt.Errorf("Unable to parse the Docker event: '%s'", err)
+	}
+
+	if e.Scope == "" {
+		e.Scope = EventScopeLocal
+	}
+
+	return ClusterEvent{
+		Node:       machineName,
+		Scope:      e.Scope,
+		Type:       e.Type,
+		Action:     e.Action,
+		ActorID:    e.Actor.ID,
+		Attributes: e.Actor.Attributes,
+		Time:       time.Unix(0, e.TimeNano),
+	}, nil
+}
+
+// eventsDedup drop the Swarm events already received from another manager
+type eventsDedup struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// isDuplicate returns true if the Swarm event was already received (within the dedup window), false otherwise
+// the managers timestamp the created and updated objects events with the object update time, but the removals with their own receive time (not part of the key)
+func (d *eventsDedup) isDuplicate(e ClusterEvent, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.seen == nil {
+		d.seen = make(map[string]time.Time)
+	}
+
+	for key, t := range d.seen {
+		if now.Sub(t) > eventsDedupWindow {
+			delete(d.seen, key)
+		}
+	}
+
+	key := fmt.Sprintf("%s/%s/%s", e.Type, e.Action, e.ActorID)
+	if e.Action != "remove" {
+		key = fmt.Sprintf("%s/%d", key, e.Time.UnixNano())
+	}
+
+	if _, ok := d.seen[key]; ok {
+		return true
+	}
+
+	d.seen[key] = now
+	return false
+}
+
+// eventStream run the events command on a node and give each line of its output to the function, until the command stops or the context is done
+type eventStream func(ctx context.Context, command string, line func(string)) error
+
+// sshEventStream returns the event stream of the node over SSH (the ssh process is killed when the context is done)
+func sshEventStream(info *SSHConnInfo) eventStream {
+	return func(ctx context.Context, command string, line func(string)) error {
+		cmd := exec.CommandContext(ctx, "ssh", info.sshCommandArgs(command)...)
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			return fmt.Errorf("Failed to start the events stream: '%s'", err)
+		}
+
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("Failed to start the events stream: '%s'", err)
+		}
+
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			line(scanner.Text())
+		}
+
+		return cmd.Wait()
+	}
+}
+
+// watchEvents multiplex the events of the given scopes of the nodes streams into the returned channel, closed once the context is done
+// the streams stopping before are started again after a delay, the Swarm events received from several managers are de-duplicated
+func watchEvents(ctx context.Context, streams map[string]eventStream, scopes map[string][]string, retryDelay time.Duration) <-chan ClusterEvent {
+	events := make(chan ClusterEvent)
+	dedup := &eventsDedup{}
+
+	var wg sync.WaitGroup
+	for machineName, stream := range streams {
+		for _, scope := range scopes[machineName] {
+			wg.Add(1)
+			go func(machineName string, stream eventStream, scope string) {
+				defer wg.Done()
+
+				for {
+					err := stream(ctx, fmt.Sprintf(eventsCommand, scope), func(line string) {
+						e, err := parseEvent(machineName, line)
+						if err != nil {
+							log.Debugf("Ignoring an event of node '%s': %s", machineName, err)
+							return
+						}
+
+						if e.Scope == EventScopeSwarm && dedup.isDuplicate(e, time.Now()) {
+							return
+						}
+
+						select {
+						case events <- e:
+						case <-ctx.Done():
+						}
+					})
+
+					if ctx.Err() != nil {
+						return
+					}
+					log.Warnf("The %s events stream of node '%s' stopped (%v), watching again in %s", scope, machineName, err, retryDelay)
+
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(retryDelay):
+					}
+				}
+			}(machineName, stream, scope)
+		}
+	}
+
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	return events
+}
+
+// WatchEvents stream the Docker events of the cluster until the context is done: the Engine events of each node, and the Swarm mode events (services, nodes,
+// secrets, configs) of the managers, de-duplicated. Each event is tagged with its source node, the channel is closed once the context is done (and the streams stopped)
+// the events are received over SSH, the streams of the nodes stopping before (ex: Engine restart) are started again, the events sent meanwhile are lost
+func (c *Cluster) WatchEvents(ctx context.Context) (<-chan ClusterEvent, error) {
+	streams := make(map[string]eventStream)
+	scopes := make(map[string][]string)
+	for machineName, n := range c.Nodes {
+		info, err := n.SSHConnection()
+		if err != nil {
+			return nil, err
+		}
+
+		streams[machineName] = sshEventStream(info)
+		scopes[machineName] = []string{EventScopeLocal}
+		if c.Config.SwarmModeGlobalConfig != nil && n.isSwarmMaster() {
+			scopes[machineName] = append(scopes[machineName], EventScopeSwarm)
+		}
+	}
+
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("The cluster has no node to watch")
+	}
+
+	return watchEvents(ctx, streams, scopes, eventsRetryDelay), nil
+}
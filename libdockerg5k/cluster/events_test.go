@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testContainerEvent = `{"status":"start","id":"c1","from":"nginx","Type":"container","Action":"start","Actor":{"ID":"c1","Attributes":{"image":"nginx","name":"web"}},"scope":"local","time":1600000000,"timeNano":1600000000000000001}`
+	testServiceEvent   = `{"Type":"service","Action":"update","Actor":{"ID":"s1","Attributes":{"name":"web"}},"scope":"swarm","time":1600000000,"timeNano":1600000000000000002}`
+)
+
+func TestParseEvent(t *testing.T) {
+	e, err := parseEvent("lille-0", testContainerEvent)
+	assert.NoError(t, err)
+	assert.Equal(t, ClusterEvent{
+		Node:       "lille-0",
+		Scope:      EventScopeLocal,
+		Type:       "container",
+		Action:     "start",
+		ActorID:    "c1",
+		Attributes: map[string]string{"image": "nginx", "name": "web"},
+		Time:       time.Unix(0, 1600000000000000001),
+	}, e)
+
+	_, err = parseEvent("lille-0", "Error response from daemon")
+	assert.Error(t, err)
+}
+
+func TestEventsDedup(t *testing.T) {
+	d := &eventsDedup{}
+	now := time.Now()
+
+	update := ClusterEvent{Node: "lille-0", Type: "service", Action: "update", ActorID: "s1", Time: time.Unix(0, 2)}
+	assert.False(t, d.isDuplicate(update, now))
+	update.Node = "lille-1"
+	assert.True(t, d.isDuplicate(update, now))
+
+	// another update of the same service
+	update.Time = time.Unix(0, 3)
+	assert.False(t, d.isDuplicate(update, now))
+
+	// the managers timestamp the removals with their receive time
+	assert.False(t, d.isDuplicate(ClusterEvent{Type: "service", Action: "remove", ActorID: "s1", Time: time.Unix(0, 4)}, now))
+	assert.True(t, d.isDuplicate(ClusterEvent{Type: "service", Action: "remove", ActorID: "s1", Time: time.Unix(0, 5)}, now))
+
+	// forgotten after the dedup window
+	assert.False(t, d.isDuplicate(ClusterEvent{Type: "service", Action: "remove", ActorID: "s1", Time: time.Unix(0, 6)}, now.Add(2*eventsDedupWindow)))
+}
+
+// testEventStream returns a stream giving the lines of the command (scope) once, then waiting for the end of the context
+func testEventStream(lines map[string][]string) eventStream {
+	return func(ctx context.Context, command string, line func(string)) error {
+		for scope, l := range lines {
+			if command != fmt.Sprintf(eventsCommand, scope) {
+				continue
+			}
+
+			for _, s := range l {
+				line(s)
+			}
+		}
+
+		<-ctx.Done()
+		return ctx.Err()
+	}
+}
+
+func TestWatchEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	streams := map[string]eventStream{
+		"lille-0": testEventStream(map[string][]string{EventScopeLocal: {testContainerEvent}, EventScopeSwarm: {testServiceEvent}}),
+		"lille-1": testEventStream(map[string][]string{EventScopeLocal: {testContainerEvent, "invalid"}, EventScopeSwarm: {testServiceEvent}}),
+		"lille-2": testEventStream(map[string][]string{EventScopeLocal: {testContainerEvent}}),
+	}
+	scopes := map[string][]string{
+		"lille-0": {EventScopeLocal, EventScopeSwarm},
+		"lille-1": {EventScopeLocal, EventScopeSwarm},
+		"lille-2": {EventScopeLocal},
+	}
+	events := watchEvents(ctx, streams, scopes, time.Millisecond)
+
+	received := []string{}
+	for i := 0; i < 4; i++ {
+		e := <-events
+		received = append(received, fmt.Sprintf("%s %s", e.Scope, e.Node))
+	}
+	sort.Strings(received)
+
+	// the local events of each node and the Swarm event once
+	assert.Equal(t, "local lille-0", received[0])
+	assert.Equal(t, "local lille-1", received[1])
+	assert.Equal(t, "local lille-2", received[2])
+	assert.Contains(t, []string{"swarm lille-0", "swarm lille-1"}, received[3])
+
+	// the channel is closed once the context is done
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchEventsRetry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the stream stops after each event (ex: Engine restart)
+	stream := func(ctx context.Context, command string, line func(string)) error {
+		line(testContainerEvent)
+		return fmt.Errorf("connection lost")
+	}
+	events := watchEvents(ctx, map[string]eventStream{"lille-0": stream}, map[string][]string{"lille-0": {EventScopeLocal}}, time.Millisecond)
+
+	// an event of each start of the stream
+	for i := 0; i < 2; i++ {
+		e := <-events
+		assert.Equal(t, "lille-0", e.Node)
+	}
+}
@@ -0,0 +1,311 @@
+package cluster
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/hostsmapping"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/ingress"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/registry"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// externalEngineTimeout is the maximum time allowed for a request to the Docker Engine of an external host
+	externalEngineTimeout = 30 * time.Second
+
+	// minExternalAPIVersion is the minimum Docker API version of the external hosts (Swarm mode)
+	minExternalAPIVersion = "1.24"
+)
+
+// AuthPaths contain the paths of the TLS files used to connect to the Docker Engine of an external host
+type AuthPaths struct {
+	CACert     string `json:"ca_cert"`
+	ClientCert string `json:"client_cert"`
+	ClientKey  string `json:"client_key"`
+}
+
+// ExternalHost contain an existing Docker host (not reserved on Grid'5000) added to the cluster as a Swarm mode worker
+type ExternalHost struct {
+	Name          string    `json:"name"`
+	DockerHost    string    `json:"docker_host"` // format: tcp://host:port
+	Address       string    `json:"address"`     // IP address in the static lookup table of the cluster nodes
+	Certs         AuthPaths `json:"certs"`
+	EngineVersion string    `json:"engine_version"`
+}
+
+// externalEngineVersion contain the version of a Docker Engine (Engine API '/version')
+type externalEngineVersion struct {
+	Version    string `json:"Version"`
+	APIVersion string `json:"ApiVersion"`
+	Os         string `json:"Os"`
+}
+
+// externalEngineInfo contain the Swarm state of a Docker Engine (Engine API '/info')
+type externalEngineInfo struct {
+	Swarm struct {
+		LocalNodeState string `json:"LocalNodeState"`
+	} `json:"Swarm"`
+}
+
+// swarmJoinRequest contain the body of a Swarm join request (Engine API '/swarm/join')
+type swarmJoinRequest struct {
+	ListenAddr    string   `json:"ListenAddr"`
+	AdvertiseAddr string   `json:"AdvertiseAddr"`
+	RemoteAddrs   []string `json:"RemoteAddrs"`
+	JoinToken     string   `json:"JoinToken"`
+}
+
+// engineClient is a minimal client of the Docker Engine API of an external host (TCP with TLS client authentication)
+type engineClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// parseDockerHost returns the hostname and the port of a Docker host URL (format: tcp://host:port)
+func parseDockerHost(dockerHost string) (string, string, error) {
+	u, err := url.Parse(dockerHost)
+	if err != nil || u.Scheme != "tcp" || u.Hostname() == "" || u.Port() == "" {
+		return "", "", fmt.Errorf("Invalid Docker host: '%s' (format: tcp://host:port)", dockerHost)
+	}
+
+	return u.Hostname(), u.Port(), nil
+}
+
+// newEngineClient returns a client of the Docker Engine API of the host using the TLS files
+func newEngineClient(dockerHost string, certs AuthPaths) (*engineClient, error) {
+	hostname, port, err := parseDockerHost(dockerHost)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := ioutil.ReadFile(certs.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the CA certificate '%s': '%s'", certs.CACert, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("Invalid CA certificate: '%s'", certs.CACert)
+	}
+
+	cert, err := tls.LoadX509KeyPair(certs.ClientCert, certs.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load the client certificate '%s': '%s'", certs.ClientCert, err)
+	}
+
+	return &engineClient{
+		baseURL: fmt.Sprintf("https://%s", net.JoinHostPort(hostname, port)),
+		client: &http.Client{
+			Timeout: externalEngineTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{cert}},
+			},
+		},
+	}, nil
+}
+
+// do send a request with the given JSON body (if not nil) to the Engine API and unmarshal the JSON response (if v is not nil)
+func (e *engineClient) do(method string, path string, body interface{}, v interface{}) error {
+	var reqBody *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	} else {
+		reqBody = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, e.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("The Docker Engine returned an error for '%s': '%s' (%s)", path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// apiVersionLess returns true if the Docker API version a (format: major.minor) is lower than b, false otherwise
+func apiVersionLess(a string, b string) bool {
+	pa := strings.SplitN(a, ".", 2)
+	pb := strings.SplitN(b, ".", 2)
+	for i := 0; i < 2; i++ {
+		var va, vb int
+		if i < len(pa) {
+			va, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			vb, _ = strconv.Atoi(pb[i])
+		}
+
+		if va != vb {
+			return va < vb
+		}
+	}
+
+	return false
+}
+
+// checkExternalEngine check the Docker Engine of the external host is compatible with the cluster: Linux, minimum API version and not already in a Swarm
+func (c *Cluster) checkExternalEngine(version *externalEngineVersion, info *externalEngineInfo) error {
+	if version.Os != "linux" {
+		return fmt.Errorf("The Docker Engine of the external host does not run on Linux: '%s'", version.Os)
+	}
+
+	minVersion := minExternalAPIVersion
+	if c.Config.EngineAPIVersion != "" && apiVersionLess(minVersion, c.Config.EngineAPIVersion) {
+		minVersion = c.Config.EngineAPIVersion
+	}
+	if apiVersionLess(version.APIVersion, minVersion) {
+		return fmt.Errorf("The Docker API version of the external host is too old: '%s' (minimum: '%s')", version.APIVersion, minVersion)
+	}
+
+	if info.Swarm.LocalNodeState != "inactive" {
+		return fmt.Errorf("The Docker Engine of the external host is already part of a Swarm (state: '%s')", info.Swarm.LocalNodeState)
+	}
+
+	return nil
+}
+
+// validateExternalHostName check the name of the external host is a valid hostname not used in the cluster
+func (c *Cluster) validateExternalHostName(name string) error {
+	if !regexHostAlias.MatchString(name) {
+		return fmt.Errorf("Invalid external host name: '%s' (only letters, digits and '-' are allowed)", name)
+	}
+
+	if _, ok := c.Nodes[name]; ok || name == registry.Hostname || name == ingress.Hostname {
+		return fmt.Errorf("The external host name '%s' is already the name of a cluster host", name)
+	}
+
+	if _, ok := c.ExternalHosts[name]; ok {
+		return fmt.Errorf("The external host '%s' is already part of the cluster", name)
+	}
+
+	for _, n := range c.Nodes {
+		for _, alias := range n.Aliases {
+			if alias == name {
+				return fmt.Errorf("The external host name '%s' is already an alias of node '%s'", name, n.MachineName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// AddExternalHost add an existing Docker host (not reserved on Grid'5000, ex: a local server or a cloud VM) to the Swarm mode cluster as a worker.
+// The Docker Engine of the host is reached through its API (TCP with TLS client authentication) as SSH is not available: the host is added to
+// the static lookup table of the cluster nodes, but its own static lookup table is not modified.
+func (c *Cluster) AddExternalHost(name string, dockerHost string, certs AuthPaths) error {
+	// the external hosts only join the Swarm mode clusters (through the Engine API)
+	if c.Config.SwarmModeGlobalConfig == nil {
+		return fmt.Errorf("The external hosts can only be added to a Swarm mode cluster (Swarm standalone and Weave need SSH access to the host)")
+	}
+	if len(c.Config.SwarmMasterNode) == 0 {
+		return fmt.Errorf("At least one Swarm master/manager node is required")
+	}
+
+	if err := c.validateExternalHostName(name); err != nil {
+		return err
+	}
+
+	// resolve the IP address of the host
+	hostname, _, err := parseDockerHost(dockerHost)
+	if err != nil {
+		return err
+	}
+	ip, err := net.LookupIP(hostname)
+	if err != nil || len(ip) < 1 {
+		return fmt.Errorf("Unable to lookup IP address for '%s' external host: '%s'", hostname, err)
+	}
+
+	// check the Engine is reachable and compatible
+	engine, err := newEngineClient(dockerHost, certs)
+	if err != nil {
+		return err
+	}
+
+	var version externalEngineVersion
+	if err := engine.do("GET", "/version", nil, &version); err != nil {
+		return fmt.Errorf("The Docker Engine of the external host '%s' is not reachable: '%s'", name, err)
+	}
+
+	var info externalEngineInfo
+	if err := engine.do("GET", "/info", nil, &info); err != nil {
+		return fmt.Errorf("The Docker Engine of the external host '%s' is not reachable: '%s'", name, err)
+	}
+
+	if err := c.checkExternalEngine(&version, &info); err != nil {
+		return err
+	}
+
+	// get the join tokens from a reachable manager
+	manager, err := c.swarmManager()
+	if err != nil {
+		return err
+	}
+	if err := c.Config.SwarmModeGlobalConfig.LoadJoinTokens(manager); err != nil {
+		return err
+	}
+
+	// add the external host to the static lookup table of the cluster nodes
+	newHosts := map[string]string{name: ip[0].String()}
+	errs := c.runOnNodes(c.Config.PhaseTimeout(PhaseMapping), func(n *Node, h *host.Host) error {
+		return hostsmapping.AddClusterHostsMapping(h, newHosts, nil)
+	})
+	if err := fleetError("Hosts mapping", errs); err != nil {
+		return err
+	}
+	c.Config.HostsLookupTable[name] = newHosts[name]
+
+	// join the Swarm mode cluster as worker
+	join := swarmJoinRequest{
+		ListenAddr:    "0.0.0.0:2377",
+		AdvertiseAddr: newHosts[name],
+		RemoteAddrs:   []string{c.Config.SwarmModeGlobalConfig.BootstrapManagerURL},
+		JoinToken:     c.Config.SwarmModeGlobalConfig.WorkerToken,
+	}
+	if err := engine.do("POST", "/swarm/join", join, nil); err != nil {
+		return fmt.Errorf("The external host '%s' can't join the Swarm mode cluster: '%s'", name, err)
+	}
+
+	c.ExternalHosts[name] = &ExternalHost{
+		Name:          name,
+		DockerHost:    dockerHost,
+		Address:       newHosts[name],
+		Certs:         certs,
+		EngineVersion: version.Version,
+	}
+
+	log.Infof("External host '%s' ('%s', Docker Engine %s) added to the cluster", name, dockerHost, version.Version)
+	return nil
+}
@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDockerHost(t *testing.T) {
+	hostname, port, err := parseDockerHost("tcp://example.org:2376")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.org", hostname)
+	assert.Equal(t, "2376", port)
+
+	_, _, err = parseDockerHost("unix:///var/run/docker.sock")
+	assert.Error(t, err)
+
+	_, _, err = parseDockerHost("tcp://example.org")
+	assert.Error(t, err)
+
+	_, _, err = parseDockerHost("example.org:2376")
+	assert.Error(t, err)
+}
+
+func TestAPIVersionLess(t *testing.T) {
+	assert.True(t, apiVersionLess("1.23", "1.24"))
+	assert.True(t, apiVersionLess("1.9", "1.24"))
+	assert.False(t, apiVersionLess("1.24", "1.24"))
+	assert.False(t, apiVersionLess("1.30", "1.24"))
+	assert.False(t, apiVersionLess("2.0", "1.40"))
+}
+
+func TestCheckExternalEngine(t *testing.T) {
+	c := NewCluster(&GlobalConfig{EngineAPIVersion: "1.30"})
+
+	info := &externalEngineInfo{}
+	info.Swarm.LocalNodeState = "inactive"
+
+	assert.NoError(t, c.checkExternalEngine(&externalEngineVersion{APIVersion: "1.30", Os: "linux"}, info))
+	assert.Error(t, c.checkExternalEngine(&externalEngineVersion{APIVersion: "1.29", Os: "linux"}, info))
+	assert.Error(t, c.checkExternalEngine(&externalEngineVersion{APIVersion: "1.30", Os: "windows"}, info))
+
+	info.Swarm.LocalNodeState = "active"
+	assert.Error(t, c.checkExternalEngine(&externalEngineVersion{APIVersion: "1.30", Os: "linux"}, info))
+}
+
+func TestValidateExternalHostName(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.Nodes["node-0"] = &Node{MachineName: "node-0", Aliases: []string{"db"}}
+	c.ExternalHosts["ext-0"] = &ExternalHost{Name: "ext-0"}
+
+	assert.NoError(t, c.validateExternalHostName("ext-1"))
+	assert.Error(t, c.validateExternalHostName("ext_1"))
+	assert.Error(t, c.validateExternalHostName("node-0"))
+	assert.Error(t, c.validateExternalHostName("db"))
+	assert.Error(t, c.validateExternalHostName("ext-0"))
+}
+
+func TestAddExternalHostRequireSwarmMode(t *testing.T) {
+	c := NewCluster(&GlobalConfig{SwarmMasterNode: []string{"node-0"}})
+	assert.Error(t, c.AddExternalHost("ext-0", "tcp://127.0.0.1:2376", AuthPaths{}))
+
+	c.Config.SwarmModeGlobalConfig = &swarm.SwarmModeGlobalConfig{}
+	assert.Error(t, c.AddExternalHost("ext_0", "tcp://127.0.0.1:2376", AuthPaths{}))
+	assert.Error(t, c.AddExternalHost("ext-0", "127.0.0.1:2376", AuthPaths{}))
+}
+
+func TestSwarmJoinRequestJSON(t *testing.T) {
+	data, err := json.Marshal(swarmJoinRequest{
+		ListenAddr:    "0.0.0.0:2377",
+		AdvertiseAddr: "10.0.0.2",
+		RemoteAddrs:   []string{"10.0.0.1:2377"},
+		JoinToken:     "token",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ListenAddr":"0.0.0.0:2377","AdvertiseAddr":"10.0.0.2","RemoteAddrs":["10.0.0.1:2377"],"JoinToken":"token"}`, string(data))
+}
@@ -0,0 +1,111 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// gatherFactsTimeout is the maximum time allowed to gather the facts of all nodes
+	gatherFactsTimeout = 1 * time.Minute
+
+	// factsCommand returns the kernel version, uptime, load average, available memory and Docker version of the node (one by line)
+	factsCommand = "uname -r && cut -d ' ' -f 1 /proc/uptime && cut -d ' ' -f 1-3 /proc/loadavg && awk '/^MemAvailable:/ {print $2}' /proc/meminfo && docker version --format '{{.Server.Version}}'"
+)
+
+// NodeFacts contain the live state of a node (unlike HardwareInfo describing its specification)
+type NodeFacts struct {
+	KernelVersion   string        `json:"kernel_version"`
+	Uptime          time.Duration `json:"uptime"`
+	LoadAverage     [3]float64    `json:"load_average"` // 1, 5 and 15 minutes
+	FreeMemoryBytes int64         `json:"free_memory_bytes"`
+	DockerVersion   string        `json:"docker_version"`
+}
+
+// parseNodeFacts returns the node facts from the output of the facts command
+func parseNodeFacts(out string) (*NodeFacts, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 5 {
+		return nil, fmt.Errorf("Unable to parse the node facts: %d lines instead of 5", len(lines))
+	}
+
+	f := &NodeFacts{
+		KernelVersion: strings.TrimSpace(lines[0]),
+		DockerVersion: strings.TrimSpace(lines[4]),
+	}
+
+	uptime, err := strconv.ParseFloat(strings.TrimSpace(lines[1]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse the node uptime: '%s'", lines[1])
+	}
+	f.Uptime = time.Duration(uptime * float64(time.Second))
+
+	loads := strings.Fields(lines[2])
+	if len(loads) != 3 {
+		return nil, fmt.Errorf("Unable to parse the node load average: '%s'", lines[2])
+	}
+	for i, l := range loads {
+		if f.LoadAverage[i], err = strconv.ParseFloat(l, 64); err != nil {
+			return nil, fmt.Errorf("Unable to parse the node load average: '%s'", lines[2])
+		}
+	}
+
+	// available memory is given in kB
+	mem, err := strconv.ParseInt(strings.TrimSpace(lines[3]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse the node available memory: '%s'", lines[3])
+	}
+	f.FreeMemoryBytes = mem * 1024
+
+	return f, nil
+}
+
+// gatherFacts returns the live facts of the node
+func (n *Node) gatherFacts() (*NodeFacts, error) {
+	out, err := n.runSSHCommand(factsCommand)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to gather the node facts: '%s'", err)
+	}
+
+	return parseNodeFacts(out)
+}
+
+// GatherFacts collect (in parallel) the kernel version, uptime, load average, available memory and Docker version of the selected nodes (all nodes if the selector is empty)
+// The facts of the reachable nodes are returned by machine name, the unreachable nodes are reported in the returned error
+func (c *Cluster) GatherFacts(sel *NodeSelector) (map[string]*NodeFacts, error) {
+	if err := c.checkSelection(sel); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	results := make(map[string]*NodeFacts)
+
+	errs := c.runOnSelectedNodes(sel, gatherFactsTimeout, func(n *Node, h *host.Host) error {
+		f, err := n.gatherFacts()
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		results[n.MachineName] = f
+		mu.Unlock()
+
+		return nil
+	})
+
+	// copy the results to not race with the nodes still running after a timeout
+	mu.Lock()
+	defer mu.Unlock()
+
+	facts := make(map[string]*NodeFacts)
+	for machineName, f := range results {
+		facts[machineName] = f
+	}
+
+	return facts, fleetError("Facts gathering", errs)
+}
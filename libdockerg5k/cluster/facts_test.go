@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNodeFacts(t *testing.T) {
+	f, err := parseNodeFacts("4.9.0-3-amd64\n3725.52\n0.52 0.58 0.59\n16229376\n17.06.0-ce\n")
+	assert.NoError(t, err)
+	assert.Equal(t, &NodeFacts{
+		KernelVersion:   "4.9.0-3-amd64",
+		Uptime:          3725*time.Second + 520*time.Millisecond,
+		LoadAverage:     [3]float64{0.52, 0.58, 0.59},
+		FreeMemoryBytes: 16229376 * 1024,
+		DockerVersion:   "17.06.0-ce",
+	}, f)
+}
+
+func TestParseNodeFactsIncorrect(t *testing.T) {
+	_, err := parseNodeFacts("4.9.0-3-amd64\n3725.52\n")
+	assert.Error(t, err)
+
+	_, err = parseNodeFacts("4.9.0-3-amd64\nup\n0.52 0.58 0.59\n16229376\n17.06.0-ce")
+	assert.Error(t, err)
+
+	_, err = parseNodeFacts("4.9.0-3-amd64\n3725.52\n0.52 0.58\n16229376\n17.06.0-ce")
+	assert.Error(t, err)
+}
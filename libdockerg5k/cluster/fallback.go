@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	// regexSiteName match a Grid5000 site name
+	regexSiteName = regexp.MustCompile("^[a-z][a-z0-9-]*$")
+)
+
+// validateSiteFallbacks check the fallback sites names and that a site is not given twice
+func validateSiteFallbacks(sites []string) error {
+	seen := make(map[string]bool)
+	for _, site := range sites {
+		if !regexSiteName.MatchString(site) {
+			return fmt.Errorf("Invalid fallback site name: '%s'", site)
+		}
+
+		if seen[site] {
+			return fmt.Errorf("The fallback site '%s' is given more than once", site)
+		}
+		seen[site] = true
+	}
+
+	return nil
+}
+
+// ReservationSites returns the sites to try in order for the reservation of the site nodes: the site, then the fallback sites not excluded
+// (the sites already holding a job of the cluster need to be excluded, a site can only have one job per cluster)
+func (c *GlobalConfig) ReservationSites(site string, excluded map[string]bool) []string {
+	sites := []string{site}
+	for _, fallback := range c.SiteFallbacks {
+		if fallback != site && !excluded[fallback] {
+			sites = append(sites, fallback)
+		}
+	}
+
+	return sites
+}
+
+// ReserveWithFallback run the reservation on each site in order until one succeeds, and returns the site and its job ID
+// the reservation is not retried on the following sites if it fails with another error than ErrReservation (ex: deployment error)
+func ReserveWithFallback(sites []string, reserve func(site string) (int, error)) (string, int, error) {
+	errs := []string{}
+	for i, site := range sites {
+		jobID, err := reserve(site)
+		if err == nil {
+			return site, jobID, nil
+		}
+
+		if !errors.Is(err, ErrReservation) {
+			return "", 0, err
+		}
+
+		errs = append(errs, fmt.Sprintf("%s: %s", site, err))
+		if i < len(sites)-1 {
+			log.Warnf("The reservation on site '%s' failed, trying the fallback site '%s'...", site, sites[i+1])
+		}
+	}
+
+	return "", 0, fmt.Errorf("Job reservation failed on all the sites: %w (%s)", ErrReservation, strings.Join(errs, "; "))
+}
+
+// RelocateSiteNodes move the nodes of the requested site to the site where they were reserved (the machine names are kept)
+func (c *Cluster) RelocateSiteNodes(requested string, site string) {
+	if requested == site {
+		return
+	}
+
+	relocated := []string{}
+	for _, n := range c.Nodes {
+		if n.G5kSite == requested && n.requestedSite == "" {
+			n.G5kSite = site
+			n.requestedSite = requested
+			relocated = append(relocated, n.MachineName)
+		}
+	}
+	sort.Strings(relocated)
+
+	log.Infof("The nodes of site '%s' are reserved on the fallback site '%s': %s", requested, site, strings.Join(relocated, ", "))
+}
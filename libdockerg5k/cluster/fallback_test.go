@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSiteFallbacks(t *testing.T) {
+	assert.NoError(t, validateSiteFallbacks(nil))
+	assert.NoError(t, validateSiteFallbacks([]string{"nancy", "rennes"}))
+	assert.Error(t, validateSiteFallbacks([]string{"nancy", "nancy"}))
+	assert.Error(t, validateSiteFallbacks([]string{"Nancy"}))
+	assert.Error(t, validateSiteFallbacks([]string{""}))
+}
+
+func TestReservationSites(t *testing.T) {
+	c := &GlobalConfig{}
+	assert.Equal(t, []string{"lille"}, c.ReservationSites("lille", nil))
+
+	c.SiteFallbacks = []string{"nancy", "lille", "rennes", "lyon"}
+	assert.Equal(t, []string{"lille", "nancy", "rennes", "lyon"}, c.ReservationSites("lille", nil))
+	assert.Equal(t, []string{"lille", "nancy", "lyon"}, c.ReservationSites("lille", map[string]bool{"lille": true, "rennes": true}))
+}
+
+func TestReserveWithFallback(t *testing.T) {
+	tried := []string{}
+	site, jobID, err := ReserveWithFallback([]string{"lille", "nancy", "rennes"}, func(site string) (int, error) {
+		tried = append(tried, site)
+		if site == "lille" {
+			return 0, fmt.Errorf("%w: timeout", ErrReservation)
+		}
+		return 42, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "nancy", site)
+	assert.Equal(t, 42, jobID)
+	assert.Equal(t, []string{"lille", "nancy"}, tried)
+}
+
+func TestReserveWithFallbackFailure(t *testing.T) {
+	_, _, err := ReserveWithFallback([]string{"lille", "nancy"}, func(site string) (int, error) {
+		return 0, fmt.Errorf("%w: no resources on %s", ErrReservation, site)
+	})
+	assert.True(t, errors.Is(err, ErrReservation))
+	assert.Contains(t, err.Error(), "no resources on lille")
+	assert.Contains(t, err.Error(), "no resources on nancy")
+
+	// the other errors are not retried on the fallback sites
+	tried := 0
+	_, _, err = ReserveWithFallback([]string{"lille", "nancy"}, func(site string) (int, error) {
+		tried++
+		return 0, fmt.Errorf("%w: deploy error", ErrDeployment)
+	})
+	assert.True(t, errors.Is(err, ErrDeployment))
+	assert.Equal(t, 1, tried)
+}
+
+func TestRelocateSiteNodes(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.CreateNodes(map[string]int{"lille": 2, "nancy": 1})
+
+	c.RelocateSiteNodes("lille", "lille")
+	assert.Equal(t, "", c.Nodes["lille-0"].requestedSite)
+
+	c.RelocateSiteNodes("lille", "rennes")
+	assert.Equal(t, "rennes", c.Nodes["lille-0"].G5kSite)
+	assert.Equal(t, "rennes", c.Nodes["lille-1"].G5kSite)
+	assert.Equal(t, "lille", c.Nodes["lille-1"].requestedSite)
+	assert.Equal(t, "nancy", c.Nodes["nancy-0"].G5kSite)
+	assert.Equal(t, "", c.Nodes["nancy-0"].requestedSite)
+
+	// the managers of the requested site are still found by machine name
+	c.Config.SwarmMasterNode = []string{"lille-0"}
+	assert.Equal(t, 1, c.SiteManagersCount("lille"))
+
+	assert.Equal(t, "lille", c.Nodes["lille-0"].inventory().RequestedSite)
+	assert.Equal(t, "", c.Nodes["nancy-0"].inventory().RequestedSite)
+}
@@ -0,0 +1,313 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// firewallChain is the iptables chain of the node firewall rules (jumped from the INPUT chain)
+	firewallChain = "DOCKER-G5K-FIREWALL"
+	// partitionChain is the iptables chain of the network partitions rules (jumped from the INPUT chain, flushed when the partitions are healed)
+	partitionChain = "DOCKER-G5K-PARTITION"
+
+	// partitionTimeout is the maximum time allowed to apply or heal the network partitions on all nodes
+	partitionTimeout = 2 * time.Minute
+)
+
+var (
+	// firewallActions are the iptables targets of the firewall rules
+	firewallActions = map[string]string{"drop": "DROP", "reject": "REJECT", "accept": "ACCEPT"}
+
+	// firewallProtocols are the protocols of the firewall rules (all if empty)
+	firewallProtocols = []string{"all", "tcp", "udp", "icmp"}
+)
+
+// FirewallRule is an iptables rule filtering the incoming traffic of a node (format: action[:protocol[/port]][@source], ex: drop:tcp/2377@lille-1)
+type FirewallRule struct {
+	Action   string // drop, reject or accept
+	Protocol string // all, tcp, udp or icmp (all if empty)
+	Port     int    // destination port (tcp and udp only, all ports if 0)
+	Source   string // source address, network (CIDR) or machine name of a cluster node (any source if empty)
+}
+
+// ParseFirewallRule parse a firewall rule (format: action[:protocol[/port]][@source])
+func ParseFirewallRule(s string) (FirewallRule, error) {
+	r := FirewallRule{}
+
+	spec := s
+	if i := strings.Index(spec, "@"); i != -1 {
+		r.Source = spec[i+1:]
+		spec = spec[:i]
+	}
+
+	parts := strings.SplitN(spec, ":", 2)
+	r.Action = parts[0]
+	if len(parts) == 2 {
+		proto := strings.SplitN(parts[1], "/", 2)
+		r.Protocol = proto[0]
+		if len(proto) == 2 {
+			port, err := strconv.Atoi(proto[1])
+			if err != nil {
+				return r, fmt.Errorf("Invalid port in firewall rule: '%s'", s)
+			}
+			r.Port = port
+		}
+	}
+
+	return r, r.Validate()
+}
+
+// Validate check the action, protocol, port and source of the firewall rule (the source machine name is resolved at provisioning)
+func (r *FirewallRule) Validate() error {
+	if _, ok := firewallActions[r.Action]; !ok {
+		return fmt.Errorf("Invalid firewall rule action: '%s' (supported: drop, reject, accept)", r.Action)
+	}
+
+	if r.Protocol != "" {
+		supported := false
+		for _, p := range firewallProtocols {
+			supported = supported || r.Protocol == p
+		}
+		if !supported {
+			return fmt.Errorf("Invalid firewall rule protocol: '%s' (supported: %s)", r.Protocol, strings.Join(firewallProtocols, ", "))
+		}
+	}
+
+	if r.Port < 0 || r.Port > 65535 {
+		return fmt.Errorf("Invalid firewall rule port: %d", r.Port)
+	}
+	if r.Port != 0 && r.Protocol != "tcp" && r.Protocol != "udp" {
+		return fmt.Errorf("A firewall rule port needs the tcp or udp protocol")
+	}
+
+	if strings.ContainsAny(r.Source, " '\"") {
+		return fmt.Errorf("Invalid firewall rule source: '%s'", r.Source)
+	}
+
+	return nil
+}
+
+// String returns the firewall rule in its parsed format
+func (r FirewallRule) String() string {
+	s := r.Action
+	if r.Protocol != "" {
+		s = fmt.Sprintf("%s:%s", s, r.Protocol)
+		if r.Port != 0 {
+			s = fmt.Sprintf("%s/%d", s, r.Port)
+		}
+	}
+	if r.Source != "" {
+		s = fmt.Sprintf("%s@%s", s, r.Source)
+	}
+
+	return s
+}
+
+// resolveSource returns the source address of the rule (the machine names are resolved with the static lookup table, empty for any source)
+func (r *FirewallRule) resolveSource(hostsLookupTable map[string]string) (string, error) {
+	if r.Source == "" {
+		return "", nil
+	}
+
+	if ip := net.ParseIP(r.Source); ip != nil {
+		return r.Source, nil
+	}
+	if _, _, err := net.ParseCIDR(r.Source); err == nil {
+		return r.Source, nil
+	}
+
+	if ip, ok := hostsLookupTable[r.Source]; ok {
+		return ip, nil
+	}
+
+	return "", fmt.Errorf("The firewall rule source '%s' is not an address, a network or a node of the cluster", r.Source)
+}
+
+// iptablesArgs returns the iptables arguments of the rule appended to the chain (the source is already resolved)
+func (r *FirewallRule) iptablesArgs(chain string, source string) string {
+	args := fmt.Sprintf("-A %s", chain)
+	if r.Protocol != "" && r.Protocol != "all" {
+		args = fmt.Sprintf("%s -p %s", args, r.Protocol)
+	}
+	if source != "" {
+		args = fmt.Sprintf("%s -s %s", args, source)
+	}
+	if r.Port != 0 {
+		args = fmt.Sprintf("%s --dport %d", args, r.Port)
+	}
+
+	return fmt.Sprintf("%s -j %s", args, firewallActions[r.Action])
+}
+
+// generateChainRulesCommand returns the command (re)creating the iptables chain jumped from the INPUT chain with the given rules (an empty list only flush the chain)
+func generateChainRulesCommand(chain string, rules []string) string {
+	cmds := []string{
+		fmt.Sprintf("{ iptables -N %s 2>/dev/null || true; }", chain),
+		fmt.Sprintf("iptables -F %s", chain),
+		fmt.Sprintf("{ iptables -C INPUT -j %s 2>/dev/null || iptables -I INPUT -j %s; }", chain, chain),
+	}
+	for _, r := range rules {
+		cmds = append(cmds, fmt.Sprintf("iptables %s", r))
+	}
+
+	return strings.Join(cmds, " && ")
+}
+
+// validateFirewallRules check the firewall rules of the node
+func validateFirewallRules(rules []FirewallRule) error {
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyFirewallRules apply the firewall rules of the node in its own iptables chain (nothing is done if the node has no rules)
+func (n *Node) applyFirewallRules(h *host.Host) error {
+	if len(n.FirewallRules) == 0 {
+		return nil
+	}
+
+	args := []string{}
+	for _, r := range n.FirewallRules {
+		source, err := r.resolveSource(n.clusterConfig.HostsLookupTable)
+		if err != nil {
+			return err
+		}
+		args = append(args, r.iptablesArgs(firewallChain, source))
+	}
+
+	if _, err := h.RunSSHCommand(generateChainRulesCommand(firewallChain, args)); err != nil {
+		return fmt.Errorf("Failed to apply the firewall rules: '%s'", err)
+	}
+
+	applied := []string{}
+	for _, r := range n.FirewallRules {
+		applied = append(applied, r.String())
+	}
+
+	log.Infof("%d firewall rule(s) applied on node '%s' ('%s')", len(applied), n.NodeName, n.MachineName)
+	n.appliedFirewallRules = applied
+	return nil
+}
+
+// partitionSources returns the machine names (sorted) of the nodes rejected by each node of a partition between the two groups of nodes (each side drops the traffic of the other side)
+func partitionSources(fromNodes []string, toNodes []string) map[string][]string {
+	sources := make(map[string]map[string]bool)
+	add := func(machineName string, source string) {
+		if machineName == source {
+			return
+		}
+		if _, ok := sources[machineName]; !ok {
+			sources[machineName] = make(map[string]bool)
+		}
+		sources[machineName][source] = true
+	}
+
+	for _, from := range fromNodes {
+		for _, to := range toNodes {
+			add(from, to)
+			add(to, from)
+		}
+	}
+
+	result := make(map[string][]string)
+	for machineName, s := range sources {
+		for source := range s {
+			result[machineName] = append(result[machineName], source)
+		}
+		sort.Strings(result[machineName])
+	}
+
+	return result
+}
+
+// ApplyPartition create a network partition between the two groups of nodes (machine names) of the live cluster for fault injection, each side drops the incoming traffic of the other side
+// the partition rules are added to the existing partitions of the nodes and reported in the inventory, HealPartition remove all the partitions
+func (c *Cluster) ApplyPartition(fromNodes []string, toNodes []string) error {
+	if len(fromNodes) == 0 || len(toNodes) == 0 {
+		return fmt.Errorf("A network partition needs nodes on both sides")
+	}
+
+	for _, machineName := range append(append([]string{}, fromNodes...), toNodes...) {
+		if _, ok := c.Nodes[machineName]; !ok {
+			return fmt.Errorf("The node '%s' does not exist", machineName)
+		}
+		if _, ok := c.Config.HostsLookupTable[machineName]; !ok {
+			return fmt.Errorf("The node '%s' has no known IP address", machineName)
+		}
+	}
+
+	sources := partitionSources(fromNodes, toNodes)
+	machineNames := []string{}
+	for machineName := range sources {
+		machineNames = append(machineNames, machineName)
+	}
+
+	var mu sync.Mutex
+	errs := c.runOnSelectedNodes(&NodeSelector{Names: machineNames}, partitionTimeout, func(n *Node, h *host.Host) error {
+		mu.Lock()
+		partitioned := append([]string{}, n.partitionedFrom...)
+		mu.Unlock()
+
+		// keep the existing partitions of the node
+		for _, source := range sources[n.MachineName] {
+			found := false
+			for _, p := range partitioned {
+				found = found || p == source
+			}
+			if !found {
+				partitioned = append(partitioned, source)
+			}
+		}
+		sort.Strings(partitioned)
+
+		args := []string{}
+		for _, source := range partitioned {
+			r := FirewallRule{Action: "drop"}
+			args = append(args, r.iptablesArgs(partitionChain, c.Config.HostsLookupTable[source]))
+		}
+
+		if _, err := h.RunSSHCommand(generateChainRulesCommand(partitionChain, args)); err != nil {
+			return fmt.Errorf("Failed to apply the partition rules: '%s'", err)
+		}
+
+		mu.Lock()
+		n.partitionedFrom = partitioned
+		mu.Unlock()
+
+		log.Infof("Node '%s' ('%s') is partitioned from %s", n.NodeName, n.MachineName, strings.Join(partitioned, ", "))
+		return nil
+	})
+
+	return fleetError("Network partition", errs)
+}
+
+// HealPartition remove all the network partitions of the live cluster (the firewall rules of the nodes are kept)
+func (c *Cluster) HealPartition() error {
+	var mu sync.Mutex
+	errs := c.runOnNodes(partitionTimeout, func(n *Node, h *host.Host) error {
+		if _, err := h.RunSSHCommand(generateChainRulesCommand(partitionChain, nil)); err != nil {
+			return fmt.Errorf("Failed to remove the partition rules: '%s'", err)
+		}
+
+		mu.Lock()
+		n.partitionedFrom = nil
+		mu.Unlock()
+
+		return nil
+	})
+
+	return fleetError("Network partition healing", errs)
+}
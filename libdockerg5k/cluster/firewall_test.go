@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFirewallRule(t *testing.T) {
+	r, err := ParseFirewallRule("drop:tcp/2377@lille-1")
+	assert.NoError(t, err)
+	assert.Equal(t, FirewallRule{Action: "drop", Protocol: "tcp", Port: 2377, Source: "lille-1"}, r)
+	assert.Equal(t, "drop:tcp/2377@lille-1", r.String())
+
+	r, err = ParseFirewallRule("reject@10.0.0.0/8")
+	assert.NoError(t, err)
+	assert.Equal(t, FirewallRule{Action: "reject", Source: "10.0.0.0/8"}, r)
+	assert.Equal(t, "reject@10.0.0.0/8", r.String())
+
+	r, err = ParseFirewallRule("accept:icmp")
+	assert.NoError(t, err)
+	assert.Equal(t, FirewallRule{Action: "accept", Protocol: "icmp"}, r)
+}
+
+func TestParseFirewallRuleIncorrect(t *testing.T) {
+	for _, s := range []string{"", "block", "drop:sctp", "drop:tcp/http", "drop:tcp/70000", "drop:icmp/22", "drop:all/22", "drop@lille 1"} {
+		_, err := ParseFirewallRule(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFirewallRuleResolveSource(t *testing.T) {
+	hostsLookup := map[string]string{"lille-1": "10.0.0.1"}
+
+	for source, expected := range map[string]string{"": "", "10.0.0.2": "10.0.0.2", "10.0.0.0/8": "10.0.0.0/8", "lille-1": "10.0.0.1"} {
+		r := FirewallRule{Action: "drop", Source: source}
+		s, err := r.resolveSource(hostsLookup)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, s)
+	}
+
+	r := FirewallRule{Action: "drop", Source: "lille-2"}
+	_, err := r.resolveSource(hostsLookup)
+	assert.Error(t, err)
+}
+
+func TestFirewallRuleIptablesArgs(t *testing.T) {
+	r := FirewallRule{Action: "drop", Protocol: "tcp", Port: 2377}
+	assert.Equal(t, "-A DOCKER-G5K-FIREWALL -p tcp -s 10.0.0.1 --dport 2377 -j DROP", r.iptablesArgs(firewallChain, "10.0.0.1"))
+
+	r = FirewallRule{Action: "reject", Protocol: "all"}
+	assert.Equal(t, "-A DOCKER-G5K-FIREWALL -j REJECT", r.iptablesArgs(firewallChain, ""))
+}
+
+func TestGenerateChainRulesCommand(t *testing.T) {
+	assert.Equal(t, "{ iptables -N DOCKER-G5K-PARTITION 2>/dev/null || true; } && iptables -F DOCKER-G5K-PARTITION && { iptables -C INPUT -j DOCKER-G5K-PARTITION 2>/dev/null || iptables -I INPUT -j DOCKER-G5K-PARTITION; }", generateChainRulesCommand(partitionChain, nil))
+	assert.Contains(t, generateChainRulesCommand(partitionChain, []string{"-A DOCKER-G5K-PARTITION -s 10.0.0.1 -j DROP"}), "&& iptables -A DOCKER-G5K-PARTITION -s 10.0.0.1 -j DROP")
+}
+
+func TestPartitionSources(t *testing.T) {
+	sources := partitionSources([]string{"lille-0", "lille-1"}, []string{"nancy-0", "lille-1"})
+
+	assert.Equal(t, map[string][]string{
+		"lille-0": {"lille-1", "nancy-0"},
+		"lille-1": {"lille-0", "nancy-0"},
+		"nancy-0": {"lille-0", "lille-1"},
+	}, sources)
+}
+
+func TestApplyPartitionIncorrect(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleWorker})
+	c.Config.HostsLookupTable = map[string]string{"lille-0": "10.0.0.0"}
+
+	assert.Error(t, c.ApplyPartition([]string{"lille-0"}, nil))
+	assert.Error(t, c.ApplyPartition([]string{"lille-0"}, []string{"nancy-0"}))
+	assert.Error(t, c.ApplyPartition([]string{"lille-0"}, []string{"lille-1"}))
+}
@@ -0,0 +1,161 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// defaultFleetConcurrency is the maximum number of nodes an operation on the cluster runs on at the same time if no concurrency is set
+	defaultFleetConcurrency = 64
+)
+
+// nodeResult contain the result of an operation on a node
+type nodeResult struct {
+	machineName string
+	err         error
+}
+
+// loadHost load the node's host from the Docker Machine storage
+func (n *Node) loadHost() (*host.Host, error) {
+	h, err := n.clusterConfig.LibMachineClient.Load(n.MachineName)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load machine '%s': '%s'", n.MachineName, err)
+	}
+
+	return h, nil
+}
+
+// fleetConcurrency returns the maximum number of nodes an operation on the cluster runs on at the same time
+func (c *GlobalConfig) fleetConcurrency() int {
+	if c.FleetConcurrency == 0 {
+		return defaultFleetConcurrency
+	}
+
+	return c.FleetConcurrency
+}
+
+// runOnNodes run the given function on all nodes of the cluster (in parallel) and returns the errors by machine name
+func (c *Cluster) runOnNodes(timeout time.Duration, fn func(n *Node, h *host.Host) error) map[string]error {
+	return c.runOnSelectedNodes(nil, timeout, fn)
+}
+
+// runOnSelectedNodes run the given function on the nodes selected by the selector (in parallel, bounded by the fleet concurrency) and returns the errors by machine name
+// the timeout covers the whole operation, the nodes still waiting for their turn when it expires are not run
+func (c *Cluster) runOnSelectedNodes(sel *NodeSelector, timeout time.Duration, fn func(n *Node, h *host.Host) error) map[string]error {
+	results := make(chan nodeResult, len(c.Nodes))
+	slots := make(chan struct{}, c.Config.fleetConcurrency())
+	done := make(chan struct{})
+	defer close(done)
+
+	// store nodes to wait for
+	pending := make(map[string]bool)
+
+	for _, machineName := range c.selectNodes(sel) {
+		n := c.Nodes[machineName]
+		pending[n.MachineName] = true
+
+		go func(n *Node) {
+			// wait for a free slot (or the end of the operation)
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-done:
+				return
+			}
+
+			// the operation timed out while the slot was acquired
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			// load node's host (from the host cache if enabled)
+			h, _, err := n.sshConnection()
+			if err != nil {
+				results <- nodeResult{n.MachineName, err}
+				return
+			}
+
+			results <- nodeResult{n.MachineName, fn(n, h)}
+		}(n)
+	}
+
+	// wait for all nodes to finish or the timeout to expire
+	errs := make(map[string]error)
+	timeoutChan := time.After(timeout)
+	for len(pending) > 0 {
+		select {
+		case r := <-results:
+			delete(pending, r.machineName)
+			if r.err != nil {
+				errs[r.machineName] = r.err
+			}
+		case <-timeoutChan:
+			for machineName := range pending {
+				errs[machineName] = fmt.Errorf("Operation timed out after %s", timeout)
+			}
+			return errs
+		}
+	}
+
+	return errs
+}
+
+// fleetError log the errors of an operation on the nodes and returns an error listing the failed nodes (nil if no error)
+func fleetError(operation string, errs map[string]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	// sort failed nodes by name
+	failedNodes := []string{}
+	for machineName, err := range errs {
+		log.Errorf("%s failed on node '%s': '%s'", operation, machineName, err)
+		failedNodes = append(failedNodes, machineName)
+	}
+	sort.Strings(failedNodes)
+
+	return fmt.Errorf("%s failed on node(s): %s", operation, strings.Join(failedNodes, ", "))
+}
+
+// RunCommand run the shell command on the selected nodes (all nodes if the selector is empty) in parallel and returns the output of the successful nodes by machine name
+func (c *Cluster) RunCommand(command string, sel *NodeSelector, timeout time.Duration) (map[string]string, error) {
+	if err := c.checkSelection(sel); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	results := make(map[string]string)
+
+	errs := c.runOnSelectedNodes(sel, timeout, func(n *Node, h *host.Host) error {
+		out, err := n.runSSHCommand(command)
+		if err != nil {
+			return fmt.Errorf("Command failed: '%s'", err)
+		}
+
+		mu.Lock()
+		results[n.MachineName] = out
+		mu.Unlock()
+
+		return nil
+	})
+
+	// copy the results to not race with the nodes still running after a timeout
+	mu.Lock()
+	defer mu.Unlock()
+
+	outputs := make(map[string]string)
+	for machineName, out := range results {
+		outputs[machineName] = out
+	}
+
+	return outputs, fleetError("Command", errs)
+}
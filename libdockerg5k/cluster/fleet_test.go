@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFleetCluster returns a cluster of the given number of nodes whose hosts are cached (the operations don't load any machine)
+func newFleetCluster(nodes int, concurrency int) *Cluster {
+	c := NewCluster(&GlobalConfig{FleetConcurrency: concurrency, HostCacheSize: nodes})
+	c.CreateNodes(map[string]int{"lille": nodes})
+
+	c.Config.hostCache.entries = make(map[string]*hostCacheEntry)
+	for machineName := range c.Nodes {
+		c.Config.hostCache.entries[machineName] = &hostCacheEntry{host: &host.Host{Name: machineName}, lastUsed: time.Now()}
+	}
+
+	return c
+}
+
+func TestFleetConcurrency(t *testing.T) {
+	assert.Equal(t, defaultFleetConcurrency, (&GlobalConfig{}).fleetConcurrency())
+	assert.Equal(t, 4, (&GlobalConfig{FleetConcurrency: 4}).fleetConcurrency())
+}
+
+func TestRunOnNodesConcurrency(t *testing.T) {
+	c := newFleetCluster(10, 3)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	errs := c.runOnNodes(time.Minute, func(n *Node, h *host.Host) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if n.MachineName == "lille-4" {
+			return fmt.Errorf("failed")
+		}
+		return nil
+	})
+
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs, "lille-4")
+	assert.Equal(t, 3, maxRunning)
+}
+
+func TestRunOnNodesConcurrencyTimeout(t *testing.T) {
+	c := newFleetCluster(4, 1)
+
+	var mu sync.Mutex
+	started := 0
+	errs := c.runOnNodes(50*time.Millisecond, func(n *Node, h *host.Host) error {
+		mu.Lock()
+		started++
+		mu.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+
+	// the nodes waiting for their turn are not run after the timeout
+	assert.Len(t, errs, 4)
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 1, started)
+	mu.Unlock()
+}
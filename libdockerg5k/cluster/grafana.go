@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+const (
+	// grafanaDatasource is the Prometheus datasource of the dashboard panels (selected at import with the dashboard variable)
+	grafanaDatasource = "${datasource}"
+
+	// size of the dashboard panels in the Grafana grid (24 columns wide)
+	grafanaPanelWidth  = 8
+	grafanaPanelHeight = 8
+)
+
+// grafanaTarget is a Prometheus query of a Grafana panel
+type grafanaTarget struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+	RefID        string `json:"refId"`
+}
+
+// grafanaGridPos is the position of a panel in the dashboard grid
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// grafanaFieldConfig contain the unit of the values of a panel
+type grafanaFieldConfig struct {
+	Defaults struct {
+		Unit string `json:"unit"`
+	} `json:"defaults"`
+}
+
+// grafanaPanel is a row or time series panel of the dashboard
+type grafanaPanel struct {
+	ID          int                 `json:"id"`
+	Type        string              `json:"type"`
+	Title       string              `json:"title"`
+	GridPos     grafanaGridPos      `json:"gridPos"`
+	Datasource  string              `json:"datasource,omitempty"`
+	FieldConfig *grafanaFieldConfig `json:"fieldConfig,omitempty"`
+	Targets     []grafanaTarget     `json:"targets,omitempty"`
+}
+
+// grafanaVariable is a template variable of the dashboard
+type grafanaVariable struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+// grafanaDashboard is the Grafana dashboard definition (JSON model)
+type grafanaDashboard struct {
+	UID           string   `json:"uid,omitempty"`
+	Title         string   `json:"title"`
+	Tags          []string `json:"tags"`
+	SchemaVersion int      `json:"schemaVersion"`
+	Refresh       string   `json:"refresh"`
+	Time          struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	} `json:"time"`
+	Templating struct {
+		List []grafanaVariable `json:"list"`
+	} `json:"templating"`
+	Panels []grafanaPanel `json:"panels"`
+}
+
+// newGrafanaPanel returns a time series panel of the Engine metrics of the node with the given unit
+func newGrafanaPanel(id int, title string, unit string, x int, y int, targets ...grafanaTarget) grafanaPanel {
+	p := grafanaPanel{
+		ID:         id,
+		Type:       "timeseries",
+		Title:      title,
+		GridPos:    grafanaGridPos{H: grafanaPanelHeight, W: grafanaPanelWidth, X: x, Y: y},
+		Datasource: grafanaDatasource,
+		Targets:    targets,
+	}
+	p.FieldConfig = &grafanaFieldConfig{}
+	p.FieldConfig.Defaults.Unit = unit
+
+	return p
+}
+
+// nodeGrafanaPanels returns the row and the CPU, memory and containers panels of the node (selected by the 'instance' label of its Engine metrics endpoint)
+func nodeGrafanaPanels(ni *NodeInventory, id int, y int) []grafanaPanel {
+	instance := fmt.Sprintf("instance=\"%s\"", ni.EngineMetricsEndpoint)
+
+	title := fmt.Sprintf("%s (%s, site %s)", ni.MachineName, ni.NodeName, ni.G5kSite)
+	if ni.Role != "" {
+		title = fmt.Sprintf("%s (%s, %s, site %s)", ni.MachineName, ni.NodeName, ni.Role, ni.G5kSite)
+	}
+
+	return []grafanaPanel{
+		{ID: id, Type: "row", Title: title, GridPos: grafanaGridPos{H: 1, W: 24, X: 0, Y: y}},
+		newGrafanaPanel(id+1, "CPU", "short", 0, y+1,
+			grafanaTarget{Expr: fmt.Sprintf("engine_daemon_engine_cpus_cpus{%s}", instance), LegendFormat: "CPUs", RefID: "A"},
+			grafanaTarget{Expr: fmt.Sprintf("rate(process_cpu_seconds_total{%s}[1m])", instance), LegendFormat: "Docker Engine", RefID: "B"},
+		),
+		newGrafanaPanel(id+2, "Memory", "bytes", grafanaPanelWidth, y+1,
+			grafanaTarget{Expr: fmt.Sprintf("engine_daemon_engine_memory_bytes{%s}", instance), LegendFormat: "Total", RefID: "A"},
+			grafanaTarget{Expr: fmt.Sprintf("process_resident_memory_bytes{%s}", instance), LegendFormat: "Docker Engine", RefID: "B"},
+		),
+		newGrafanaPanel(id+3, "Containers", "short", 2*grafanaPanelWidth, y+1,
+			grafanaTarget{Expr: fmt.Sprintf("engine_daemon_container_states_containers{%s}", instance), LegendFormat: "{{state}}", RefID: "A"},
+		),
+	}
+}
+
+// GrafanaDashboard returns the definition (JSON model) of a Grafana dashboard with the CPU, memory and containers panels of each node of the current inventory (by machine name),
+// from the Docker Engine Prometheus metrics (the Prometheus 'instance' label of a node is its Engine metrics endpoint), the nodes without metrics endpoint are skipped
+// the dashboard is generated from the live inventory and needs to be generated again when the cluster nodes change
+func (c *Cluster) GrafanaDashboard() ([]byte, error) {
+	inv := c.Inventory()
+
+	machineNames := []string{}
+	for machineName, ni := range inv.Nodes {
+		if ni.EngineMetricsEndpoint != "" {
+			machineNames = append(machineNames, machineName)
+		}
+	}
+	sort.Strings(machineNames)
+
+	if len(machineNames) == 0 {
+		return nil, fmt.Errorf("No node exposes the Docker Engine metrics, the Engine metrics address needs to be set")
+	}
+
+	d := grafanaDashboard{
+		Title:         "docker-g5k cluster",
+		Tags:          []string{"docker-g5k"},
+		SchemaVersion: 27,
+		Refresh:       "30s",
+		Panels:        []grafanaPanel{},
+	}
+	if c.Config.ClusterID != "" {
+		d.UID = fmt.Sprintf("docker-g5k-%s", c.Config.ClusterID)
+		d.Title = fmt.Sprintf("docker-g5k cluster %s", c.Config.ClusterID)
+	}
+	d.Time.From = "now-1h"
+	d.Time.To = "now"
+	d.Templating.List = []grafanaVariable{{Name: "datasource", Label: "Prometheus", Type: "datasource", Query: "prometheus"}}
+
+	// one row of panels by node
+	for i, machineName := range machineNames {
+		d.Panels = append(d.Panels, nodeGrafanaPanels(inv.Nodes[machineName], i*4+1, i*(grafanaPanelHeight+1))...)
+	}
+
+	return json.MarshalIndent(d, "", "  ")
+}
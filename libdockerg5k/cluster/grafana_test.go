@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrafanaDashboard(t *testing.T) {
+	c := NewCluster(&GlobalConfig{ClusterID: "exp1", EngineMetricsAddr: "0.0.0.0:9323"})
+	c.Nodes["lille-1"] = &Node{clusterConfig: c.Config, MachineName: "lille-1", NodeName: "chetemi-2.lille.grid5000.fr", G5kSite: "lille", Role: NodeRoleWorker}
+	c.Nodes["lille-0"] = &Node{clusterConfig: c.Config, MachineName: "lille-0", NodeName: "chetemi-1.lille.grid5000.fr", G5kSite: "lille", Role: NodeRoleManager}
+
+	out, err := c.GrafanaDashboard()
+	assert.NoError(t, err)
+
+	var d grafanaDashboard
+	assert.NoError(t, json.Unmarshal(out, &d))
+	assert.Equal(t, "docker-g5k-exp1", d.UID)
+	assert.Equal(t, "docker-g5k cluster exp1", d.Title)
+	assert.Len(t, d.Panels, 8)
+
+	// one row of panels by node, sorted by machine name
+	assert.Equal(t, "row", d.Panels[0].Type)
+	assert.Equal(t, "lille-0 (chetemi-1.lille.grid5000.fr, Manager, site lille)", d.Panels[0].Title)
+	assert.Equal(t, "lille-1 (chetemi-2.lille.grid5000.fr, Worker, site lille)", d.Panels[4].Title)
+	assert.Equal(t, grafanaGridPos{H: 8, W: 8, X: 8, Y: 10}, d.Panels[6].GridPos)
+	assert.Equal(t, "engine_daemon_engine_memory_bytes{instance=\"chetemi-2.lille.grid5000.fr:9323\"}", d.Panels[6].Targets[0].Expr)
+	assert.Equal(t, "bytes", d.Panels[6].FieldConfig.Defaults.Unit)
+
+	// the panel IDs are unique
+	ids := map[int]bool{}
+	for _, p := range d.Panels {
+		ids[p.ID] = true
+	}
+	assert.Len(t, ids, 8)
+}
+
+func TestGrafanaDashboardWithoutMetrics(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.Nodes["lille-0"] = &Node{clusterConfig: c.Config, MachineName: "lille-0", NodeName: "chetemi-1.lille.grid5000.fr", G5kSite: "lille"}
+
+	_, err := c.GrafanaDashboard()
+	assert.Error(t, err)
+}
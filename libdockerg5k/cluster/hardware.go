@@ -0,0 +1,116 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+)
+
+// HardwareInfo contain the hardware description of a node (from the Grid5000 Reference API)
+type HardwareInfo struct {
+	CPUModel    string   `json:"cpu_model"`
+	CPUs        int      `json:"cpus"`
+	Cores       int      `json:"cores"`
+	Threads     int      `json:"threads"`
+	MemoryBytes int64    `json:"memory_bytes"`
+	NICRateGbps float64  `json:"nic_rate_gbps"` // rate of the fastest enabled network adapter
+	GPUs        []string `json:"gpus,omitempty"`
+}
+
+// hardwareCache store the hardware description of the nodes by node hostname
+type hardwareCache struct {
+	mu    sync.Mutex
+	nodes map[string]*HardwareInfo
+}
+
+// get returns the hardware description of the node from the cache (nil if not cached)
+func (c *hardwareCache) get(nodeName string) *HardwareInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.nodes[nodeName]
+}
+
+// set store the hardware description of the node in the cache
+func (c *hardwareCache) set(nodeName string, hw *HardwareInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.nodes == nil {
+		c.nodes = make(map[string]*HardwareInfo)
+	}
+	c.nodes[nodeName] = hw
+}
+
+// newHardwareInfo returns the hardware description of the Reference API node
+func newHardwareInfo(ref *g5k.ReferenceNode) *HardwareInfo {
+	hw := &HardwareInfo{
+		CPUModel:    strings.TrimSpace(fmt.Sprintf("%s %s", ref.Processor.Model, ref.Processor.Version)),
+		CPUs:        ref.Architecture.NbProcs,
+		Cores:       ref.Architecture.NbCores,
+		Threads:     ref.Architecture.NbThreads,
+		MemoryBytes: ref.MainMemory.RAMSize,
+	}
+
+	// fastest enabled network adapter
+	for _, a := range ref.NetworkAdapters {
+		if a.Enabled && a.Rate/1e9 > hw.NICRateGbps {
+			hw.NICRateGbps = a.Rate / 1e9
+		}
+	}
+
+	// GPUs (sorted by device ID)
+	ids := []string{}
+	for id := range ref.GPUDevices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		gpu := ref.GPUDevices[id]
+		hw.GPUs = append(hw.GPUs, strings.TrimSpace(fmt.Sprintf("%s %s", gpu.Vendor, gpu.Model)))
+	}
+
+	return hw
+}
+
+// HardwareFacts returns the hardware description of the reserved node from the Grid5000 Reference API (cached for the cluster)
+func (n *Node) HardwareFacts() (*HardwareInfo, error) {
+	if n.NodeName == "" {
+		return nil, fmt.Errorf("The node '%s' is not reserved", n.MachineName)
+	}
+
+	if hw := n.clusterConfig.hardware.get(n.NodeName); hw != nil {
+		return hw, nil
+	}
+
+	g5kAPI, err := n.clusterConfig.g5kAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := g5kAPI.GetReferenceNode(n.G5kSite, n.NodeName)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the description of node '%s': '%s'", n.NodeName, err)
+	}
+
+	hw := newHardwareInfo(ref)
+	n.clusterConfig.hardware.set(n.NodeName, hw)
+
+	return hw, nil
+}
+
+// CollectHardwareFacts get the hardware description of all nodes (included in the cluster inventory)
+func (c *Cluster) CollectHardwareFacts() error {
+	errs := make(map[string]error)
+	for machineName, n := range c.Nodes {
+		if _, err := n.HardwareFacts(); err != nil {
+			errs[machineName] = err
+		}
+	}
+
+	return fleetError("Hardware facts collection", errs)
+}
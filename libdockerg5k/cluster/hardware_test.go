@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+)
+
+func TestNewHardwareInfo(t *testing.T) {
+	ref := &g5k.ReferenceNode{
+		UID: "chifflot-1",
+		NetworkAdapters: []g5k.ReferenceNetworkAdapter{
+			{Device: "eth0", Rate: 25e9, Enabled: true},
+			{Device: "eth1", Rate: 100e9, Enabled: false},
+			{Device: "eth2", Rate: 10e9, Enabled: true},
+		},
+		Processor:    g5k.ReferenceProcessor{Model: "Intel Xeon", Version: "Gold 6126"},
+		Architecture: g5k.ReferenceArchitecture{NbProcs: 2, NbCores: 24, NbThreads: 48},
+		MainMemory:   g5k.ReferenceMainMemory{RAMSize: 206158430208},
+		GPUDevices: map[string]g5k.ReferenceGPUDevice{
+			"nvidia1": {Vendor: "Nvidia", Model: "Tesla V100"},
+			"nvidia0": {Vendor: "Nvidia", Model: "Tesla P100"},
+		},
+	}
+
+	assert.Equal(t, &HardwareInfo{
+		CPUModel:    "Intel Xeon Gold 6126",
+		CPUs:        2,
+		Cores:       24,
+		Threads:     48,
+		MemoryBytes: 206158430208,
+		NICRateGbps: 25,
+		GPUs:        []string{"Nvidia Tesla P100", "Nvidia Tesla V100"},
+	}, newHardwareInfo(ref))
+}
+
+func TestInventoryHardwareFromCache(t *testing.T) {
+	config := &GlobalConfig{}
+	n := &Node{clusterConfig: config, MachineName: "lille-0", NodeName: "chifflot-1.lille.grid5000.fr"}
+	assert.Nil(t, n.inventory().Hardware)
+
+	hw := &HardwareInfo{CPUModel: "Intel Xeon Gold 6126"}
+	config.hardware.set(n.NodeName, hw)
+
+	facts, err := n.HardwareFacts()
+	assert.NoError(t, err)
+	assert.Equal(t, hw, facts)
+	assert.Equal(t, hw, n.inventory().Hardware)
+}
@@ -0,0 +1,203 @@
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/ssh"
+)
+
+const (
+	// defaultHostCacheIdleTimeout is the time an unused cached host is kept if no idle timeout is set
+	defaultHostCacheIdleTimeout = 5 * time.Minute
+)
+
+// HostCacheStats contain the usage statistics of the host cache (loaded hosts of the nodes)
+type HostCacheStats struct {
+	Open      int `json:"open"`      // cached hosts
+	Hits      int `json:"hits"`      // operations reusing a cached host
+	Misses    int `json:"misses"`    // operations loading the host from the machine storage
+	Evictions int `json:"evictions"` // hosts dropped because the cache was full
+	Expired   int `json:"expired"`   // hosts dropped after the idle timeout
+}
+
+// hostCacheEntry contain a cached host of a node and its SSH client
+// the libmachine SSH clients hold no connection: each command still opens its own connection (native client) or runs its own ssh process (external client)
+type hostCacheEntry struct {
+	host     *host.Host
+	client   ssh.Client
+	lastUsed time.Time
+}
+
+// hostCache store the loaded hosts and SSH clients of the nodes by machine name, to not load the machine from the storage at each operation
+type hostCache struct {
+	mu      sync.Mutex
+	entries map[string]*hostCacheEntry
+	stats   HostCacheStats
+}
+
+// hostLoader load the host of a node and create its SSH client
+type hostLoader func() (*host.Host, ssh.Client, error)
+
+// acquire returns the cached host of the machine, or load it with the loader (the least recently used host is evicted if the cache is full)
+// the loader is called without the cache lock, so the hosts of several nodes are loaded in parallel
+func (p *hostCache) acquire(machineName string, size int, idleTimeout time.Duration, now time.Time, load hostLoader) (*hostCacheEntry, error) {
+	p.mu.Lock()
+	if p.entries == nil {
+		p.entries = make(map[string]*hostCacheEntry)
+	}
+
+	p.expire(idleTimeout, now)
+
+	if e, ok := p.entries[machineName]; ok {
+		p.stats.Hits++
+		e.lastUsed = now
+		p.mu.Unlock()
+		return e, nil
+	}
+
+	p.stats.Misses++
+	p.mu.Unlock()
+
+	h, client, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// the host was loaded meanwhile by another operation
+	if e, ok := p.entries[machineName]; ok {
+		e.lastUsed = now
+		return e, nil
+	}
+
+	// evict the least recently used host
+	if p.entries == nil {
+		p.entries = make(map[string]*hostCacheEntry)
+	}
+	if len(p.entries) >= size {
+		var lru string
+		for name, e := range p.entries {
+			if lru == "" || e.lastUsed.Before(p.entries[lru].lastUsed) {
+				lru = name
+			}
+		}
+		delete(p.entries, lru)
+		p.stats.Evictions++
+	}
+
+	e := &hostCacheEntry{host: h, client: client, lastUsed: now}
+	p.entries[machineName] = e
+	return e, nil
+}
+
+// expire drop the hosts unused since the idle timeout (the cache lock must be held)
+func (p *hostCache) expire(idleTimeout time.Duration, now time.Time) {
+	for name, e := range p.entries {
+		if now.Sub(e.lastUsed) > idleTimeout {
+			delete(p.entries, name)
+			p.stats.Expired++
+		}
+	}
+}
+
+// drop drop the cached host of the machine (ex: after a failed command, the machine may have changed)
+func (p *hostCache) drop(machineName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.entries, machineName)
+}
+
+// close drop all the cached hosts
+func (p *hostCache) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.entries = nil
+}
+
+// snapshot returns the current statistics of the cache
+func (p *hostCache) snapshot() HostCacheStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.Open = len(p.entries)
+	return stats
+}
+
+// hostCacheIdleTimeout returns the idle timeout of the cached hosts
+func (c *GlobalConfig) hostCacheIdleTimeout() time.Duration {
+	if c.HostCacheIdleTimeout == 0 {
+		return defaultHostCacheIdleTimeout
+	}
+
+	return c.HostCacheIdleTimeout
+}
+
+// loadSSHClient load the node's host and create its SSH client
+func (n *Node) loadSSHClient() (*host.Host, ssh.Client, error) {
+	h, err := n.loadHost()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := h.CreateSSHClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to create the SSH client of machine '%s': '%s'", n.MachineName, err)
+	}
+
+	return h, client, nil
+}
+
+// sshConnection returns the host and SSH client of the node (from the host cache if enabled), each command run with them opens its own SSH connection
+func (n *Node) sshConnection() (*host.Host, ssh.Client, error) {
+	if n.clusterConfig.HostCacheSize == 0 {
+		return n.loadSSHClient()
+	}
+
+	e, err := n.clusterConfig.hostCache.acquire(n.MachineName, n.clusterConfig.HostCacheSize, n.clusterConfig.hostCacheIdleTimeout(), time.Now(), n.loadSSHClient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e.host, e.client, nil
+}
+
+// runSSHCommand run the command on the node using its (cached) SSH client
+func (n *Node) runSSHCommand(command string) (string, error) {
+	_, client, err := n.sshConnection()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := client.Output(command)
+	if err != nil {
+		// the machine may have changed, its host will be loaded again by the next operation
+		n.clusterConfig.hostCache.drop(n.MachineName)
+		return out, err
+	}
+
+	return out, nil
+}
+
+// HostCacheStats returns the usage statistics of the host cache
+func (c *Cluster) HostCacheStats() HostCacheStats {
+	return c.Config.hostCache.snapshot()
+}
+
+// Close release the resources of the cluster (cached hosts)
+func (c *Cluster) Close() {
+	stats := c.Config.hostCache.snapshot()
+	c.Config.hostCache.close()
+
+	if c.Config.HostCacheSize > 0 {
+		log.Debugf("Host cache closed (%d cached, %d hits, %d misses, %d evictions, %d expired)", stats.Open, stats.Hits, stats.Misses, stats.Evictions, stats.Expired)
+	}
+}
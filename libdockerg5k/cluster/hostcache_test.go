@@ -0,0 +1,117 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/stretchr/testify/assert"
+)
+
+// testLoader returns a loader creating a new host with the given name and counting the loads
+func testLoader(name string, loads *int) hostLoader {
+	return func() (*host.Host, ssh.Client, error) {
+		*loads++
+		return &host.Host{Name: name}, nil, nil
+	}
+}
+
+func TestHostCacheAcquire(t *testing.T) {
+	var p hostCache
+	loads := 0
+	now := time.Now()
+
+	e, err := p.acquire("rennes-0", 2, time.Minute, now, testLoader("rennes-0", &loads))
+	assert.NoError(t, err)
+	assert.Equal(t, "rennes-0", e.host.Name)
+
+	// reused connection
+	e2, err := p.acquire("rennes-0", 2, time.Minute, now.Add(time.Second), testLoader("rennes-0", &loads))
+	assert.NoError(t, err)
+	assert.True(t, e == e2)
+	assert.Equal(t, 1, loads)
+
+	assert.Equal(t, HostCacheStats{Open: 1, Hits: 1, Misses: 1}, p.snapshot())
+}
+
+func TestHostCacheEviction(t *testing.T) {
+	var p hostCache
+	loads := 0
+	now := time.Now()
+
+	p.acquire("rennes-0", 2, time.Minute, now, testLoader("rennes-0", &loads))
+	p.acquire("rennes-1", 2, time.Minute, now.Add(time.Second), testLoader("rennes-1", &loads))
+	p.acquire("rennes-0", 2, time.Minute, now.Add(2*time.Second), testLoader("rennes-0", &loads))
+
+	// the least recently used connection (rennes-1) is evicted
+	p.acquire("rennes-2", 2, time.Minute, now.Add(3*time.Second), testLoader("rennes-2", &loads))
+	assert.Contains(t, p.entries, "rennes-0")
+	assert.NotContains(t, p.entries, "rennes-1")
+	assert.Contains(t, p.entries, "rennes-2")
+
+	assert.Equal(t, HostCacheStats{Open: 2, Hits: 1, Misses: 3, Evictions: 1}, p.snapshot())
+}
+
+func TestHostCacheIdleTimeout(t *testing.T) {
+	var p hostCache
+	loads := 0
+	now := time.Now()
+
+	p.acquire("rennes-0", 2, time.Minute, now, testLoader("rennes-0", &loads))
+	p.acquire("rennes-0", 2, time.Minute, now.Add(2*time.Minute), testLoader("rennes-0", &loads))
+	assert.Equal(t, 2, loads)
+
+	assert.Equal(t, HostCacheStats{Open: 1, Misses: 2, Expired: 1}, p.snapshot())
+}
+
+func TestHostCacheLoadError(t *testing.T) {
+	var p hostCache
+	_, err := p.acquire("rennes-0", 2, time.Minute, time.Now(), func() (*host.Host, ssh.Client, error) {
+		return nil, nil, errors.New("unreachable")
+	})
+	assert.EqualError(t, err, "unreachable")
+
+	assert.Equal(t, HostCacheStats{Misses: 1}, p.snapshot())
+}
+
+func TestHostCacheClose(t *testing.T) {
+	var p hostCache
+	loads := 0
+
+	p.acquire("rennes-0", 2, time.Minute, time.Now(), testLoader("rennes-0", &loads))
+	p.acquire("rennes-1", 2, time.Minute, time.Now(), testLoader("rennes-1", &loads))
+	p.drop("rennes-0")
+	assert.Equal(t, 1, p.snapshot().Open)
+
+	p.close()
+	assert.Equal(t, 0, p.snapshot().Open)
+}
+
+func TestHostCacheParallelLoad(t *testing.T) {
+	var p hostCache
+	now := time.Now()
+
+	// the host of a node is loaded while another one is loading (the loader is not called with the cache lock held)
+	loading := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := p.acquire("rennes-0", 2, time.Minute, now, func() (*host.Host, ssh.Client, error) {
+			close(loading)
+			<-done
+			return &host.Host{Name: "rennes-0"}, nil, nil
+		})
+		done <- err
+	}()
+
+	<-loading
+	loads := 0
+	_, err := p.acquire("rennes-1", 2, time.Minute, now, testLoader("rennes-1", &loads))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, loads)
+
+	done <- nil
+	assert.NoError(t, <-done)
+	assert.Equal(t, HostCacheStats{Open: 2, Misses: 2}, p.snapshot())
+}
@@ -0,0 +1,117 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/hostsmapping"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/ingress"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/registry"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// HostsMappingChange contain an entry of the static lookup table whose address changed (empty if the entry is new)
+type HostsMappingChange struct {
+	Name     string `json:"name"`
+	Previous string `json:"previous,omitempty"`
+	Current  string `json:"current"`
+}
+
+// HostsMappingReport contain the result of a refresh of the static lookup table of the nodes
+type HostsMappingReport struct {
+	Changes      []HostsMappingChange `json:"changes"`
+	UpdatedNodes []string             `json:"updated_nodes"` // nodes whose static lookup table was rewritten (sorted)
+}
+
+// resolveHostsLookupTable returns the static lookup table of the cluster with the current IP address of the nodes
+// the registry and ingress entries follow the address of their node, the other entries (ex: external hosts) are kept
+func (c *Cluster) resolveHostsLookupTable() (map[string]string, error) {
+	table := make(map[string]string)
+	for name, ip := range c.Config.HostsLookupTable {
+		table[name] = ip
+	}
+
+	for _, n := range c.Nodes {
+		// the node is not allocated to a Grid5000 node
+		if n.NodeName == "" {
+			continue
+		}
+
+		ip, err := net.LookupIP(n.NodeName)
+		if err != nil || len(ip) < 1 {
+			return nil, fmt.Errorf("Unable to lookup IP address for '%s' node: '%s'", n.NodeName, err)
+		}
+		table[n.MachineName] = ip[0].String()
+	}
+
+	if c.Config.DeployRegistry {
+		if ip, ok := table[c.Config.RegistryNode]; ok {
+			table[registry.Hostname] = ip
+		}
+	}
+
+	if c.Config.DeployIngress != nil {
+		if ip, ok := table[c.Config.DeployIngress.Node]; ok {
+			table[ingress.Hostname] = ip
+		}
+	}
+
+	return table, nil
+}
+
+// hostsMappingChanges returns the entries (sorted by name) whose address differ between the two static lookup tables
+func hostsMappingChanges(previous map[string]string, current map[string]string) []HostsMappingChange {
+	changes := []HostsMappingChange{}
+	for name, ip := range current {
+		if previous[name] != ip {
+			changes = append(changes, HostsMappingChange{Name: name, Previous: previous[name], Current: ip})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+
+	return changes
+}
+
+// RefreshHostsMapping lookup the current IP address of the nodes (ex: after a re-reservation or a KaVLAN change) and rewrite the cluster entries of the static lookup table
+// of the nodes not matching it, the other entries of the nodes are kept. It can be run routinely: nothing is rewritten if the addresses did not change
+// the Engines and Swarm configurations using the previous addresses are not updated
+func (c *Cluster) RefreshHostsMapping() (*HostsMappingReport, error) {
+	table, err := c.resolveHostsLookupTable()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &HostsMappingReport{Changes: hostsMappingChanges(c.Config.HostsLookupTable, table), UpdatedNodes: []string{}}
+	for _, change := range report.Changes {
+		log.Infof("The address of '%s' in the static lookup table changed: '%s' => '%s'", change.Name, change.Previous, change.Current)
+	}
+	c.Config.HostsLookupTable = table
+
+	// rewrite the static lookup table of the nodes with stale entries
+	var mu sync.Mutex
+	errs := c.runOnNodes(c.Config.PhaseTimeout(PhaseMapping), func(n *Node, h *host.Host) error {
+		out, err := h.RunSSHCommand("cat /etc/hosts")
+		if err != nil {
+			return fmt.Errorf("Failed to read the static lookup table: '%s'", err)
+		}
+
+		if hostsmapping.IsClusterHostsMappingUpToDate(out, table, c.Config.hostsAliases) {
+			return nil
+		}
+
+		if err := hostsmapping.ReplaceClusterHostsMapping(h, table, c.Config.hostsAliases); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		report.UpdatedNodes = append(report.UpdatedNodes, n.MachineName)
+		mu.Unlock()
+		return nil
+	})
+	sort.Strings(report.UpdatedNodes)
+
+	return report, fleetError("Hosts mapping refresh", errs)
+}
@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveHostsLookupTable(t *testing.T) {
+	c := NewCluster(&GlobalConfig{
+		DeployRegistry:   true,
+		RegistryNode:     "lille-0",
+		HostsLookupTable: map[string]string{"lille-0": "10.0.0.1", "lille-1": "10.0.0.2", registry.Hostname: "10.0.0.1", "ext-0": "10.0.0.9"},
+	})
+	c.Nodes["lille-0"] = &Node{clusterConfig: c.Config, MachineName: "lille-0", NodeName: "127.0.0.2"}
+	c.Nodes["lille-1"] = &Node{clusterConfig: c.Config, MachineName: "lille-1"}
+
+	table, err := c.resolveHostsLookupTable()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lille-0": "127.0.0.2", "lille-1": "10.0.0.2", registry.Hostname: "127.0.0.2", "ext-0": "10.0.0.9"}, table)
+
+	// the current table is not modified
+	assert.Equal(t, "10.0.0.1", c.Config.HostsLookupTable["lille-0"])
+}
+
+func TestHostsMappingChanges(t *testing.T) {
+	previous := map[string]string{"lille-0": "10.0.0.1", "lille-1": "10.0.0.2", "g5k-registry": "10.0.0.1"}
+	current := map[string]string{"lille-0": "10.0.1.1", "lille-1": "10.0.0.2", "g5k-registry": "10.0.1.1", "lille-2": "10.0.0.3"}
+
+	assert.Equal(t, []HostsMappingChange{
+		{Name: "g5k-registry", Previous: "10.0.0.1", Current: "10.0.1.1"},
+		{Name: "lille-0", Previous: "10.0.0.1", Current: "10.0.1.1"},
+		{Name: "lille-2", Current: "10.0.0.3"},
+	}, hostsMappingChanges(previous, current))
+
+	assert.Empty(t, hostsMappingChanges(current, current))
+}
+
+func TestRefreshHostsMappingNoNodes(t *testing.T) {
+	c := NewCluster(&GlobalConfig{HostsLookupTable: map[string]string{"ext-0": "10.0.0.9"}})
+
+	// idempotent: nothing changed and no node to update
+	report, err := c.RefreshHostsMapping()
+	assert.NoError(t, err)
+	assert.Empty(t, report.Changes)
+	assert.Empty(t, report.UpdatedNodes)
+}
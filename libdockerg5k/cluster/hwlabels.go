@@ -0,0 +1,177 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+var (
+	// regexHardwareCondition match a hardware condition (format: field operator value, ex: nic_rate_gbps>=25 or cpu_model~Xeon)
+	regexHardwareCondition = regexp.MustCompile("^([a-z_]+)(>=|<=|==|>|<|~)(.+)$")
+
+	// regexSwarmNodeLabel match a Swarm node label (format: key=value)
+	regexSwarmNodeLabel = regexp.MustCompile("^[[:alnum:]][[:alnum:]._-]*=[[:alnum:]._-]+$")
+
+	// defaultHardwareLabelRules are the rules used if none is configured (the first matching rule of a label key wins)
+	defaultHardwareLabelRules = []HardwareLabelRule{
+		{Label: "gpu=true", Condition: "gpus>=1"},
+		{Label: "nic=100g", Condition: "nic_rate_gbps>=100"},
+		{Label: "nic=25g", Condition: "nic_rate_gbps>=25"},
+		{Label: "nic=10g", Condition: "nic_rate_gbps>=10"},
+		{Label: "nic=1g", Condition: "nic_rate_gbps>=1"},
+	}
+)
+
+// HardwareLabelRule contain a Swarm node label applied to the nodes whose hardware satisfies the condition
+type HardwareLabelRule struct {
+	Label     string `json:"label"`     // format: key=value
+	Condition string `json:"condition"` // format: field operator value (fields: cpus, cores, threads, memory_bytes, nic_rate_gbps, gpus, cpu_model)
+}
+
+// Validate check the label and the condition of the rule
+func (r HardwareLabelRule) Validate() error {
+	if !regexSwarmNodeLabel.MatchString(r.Label) {
+		return fmt.Errorf("Invalid hardware label: '%s' (format: key=value)", r.Label)
+	}
+
+	// evaluate the condition on an empty hardware description to check its syntax
+	if _, err := r.matches(&HardwareInfo{}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// matches returns true if the hardware satisfies the condition of the rule, false otherwise
+func (r HardwareLabelRule) matches(hw *HardwareInfo) (bool, error) {
+	m := regexHardwareCondition.FindStringSubmatch(r.Condition)
+	if m == nil {
+		return false, fmt.Errorf("Invalid hardware condition: '%s' (format: field operator value)", r.Condition)
+	}
+	field, op, value := m[1], m[2], m[3]
+
+	// the CPU model is only matched by substring
+	if field == "cpu_model" || op == "~" {
+		if field != "cpu_model" || op != "~" {
+			return false, fmt.Errorf("Invalid hardware condition: '%s' (only 'cpu_model~value' is supported for the CPU model)", r.Condition)
+		}
+
+		return strings.Contains(hw.CPUModel, value), nil
+	}
+
+	var actual float64
+	switch field {
+	case "cpus":
+		actual = float64(hw.CPUs)
+	case "cores":
+		actual = float64(hw.Cores)
+	case "threads":
+		actual = float64(hw.Threads)
+	case "memory_bytes":
+		actual = float64(hw.MemoryBytes)
+	case "nic_rate_gbps":
+		actual = hw.NICRateGbps
+	case "gpus":
+		actual = float64(len(hw.GPUs))
+	default:
+		return false, fmt.Errorf("Unknown hardware field in condition: '%s' (supported: cpus, cores, threads, memory_bytes, nic_rate_gbps, gpus, cpu_model)", r.Condition)
+	}
+
+	expected, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false, fmt.Errorf("Invalid value in hardware condition: '%s'", r.Condition)
+	}
+
+	switch op {
+	case ">=":
+		return actual >= expected, nil
+	case "<=":
+		return actual <= expected, nil
+	case ">":
+		return actual > expected, nil
+	case "<":
+		return actual < expected, nil
+	}
+
+	return actual == expected, nil
+}
+
+// hardwareLabels returns the labels of the rules satisfied by the hardware (the first matching rule of a label key wins)
+func hardwareLabels(hw *HardwareInfo, rules []HardwareLabelRule) ([]string, error) {
+	if len(rules) == 0 {
+		rules = defaultHardwareLabelRules
+	}
+
+	labels := []string{}
+	keys := make(map[string]bool)
+	for _, r := range rules {
+		key := strings.SplitN(r.Label, "=", 2)[0]
+		if keys[key] {
+			continue
+		}
+
+		ok, err := r.matches(hw)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			keys[key] = true
+			labels = append(labels, r.Label)
+		}
+	}
+
+	return labels, nil
+}
+
+// generateNodeLabelsCommand returns the command used to add the labels to the Swarm mode node
+func generateNodeLabelsCommand(nodeID string, labels []string) string {
+	cmd := "docker node update"
+	for _, l := range labels {
+		cmd = fmt.Sprintf("%s --label-add %s", cmd, l)
+	}
+
+	return fmt.Sprintf("%s %s", cmd, nodeID)
+}
+
+// applyHardwareLabels add the labels derived from the hardware facts of the node to its Swarm mode node (once joined)
+func (n *Node) applyHardwareLabels(h *host.Host) error {
+	hw, err := n.HardwareFacts()
+	if err != nil {
+		return err
+	}
+
+	labels, err := hardwareLabels(hw, n.clusterConfig.HardwareLabelRules)
+	if err != nil {
+		return err
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+
+	// the node labels itself if it is a Swarm manager, the bootstrap manager is used otherwise
+	manager := h
+	if !n.isSwarmMaster() {
+		bootstrap := &Node{clusterConfig: n.clusterConfig, MachineName: n.clusterConfig.SwarmMasterNode[0]}
+		if manager, err = bootstrap.loadHost(); err != nil {
+			return err
+		}
+	}
+
+	out, err := h.RunSSHCommand(swarmNodeIDCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the Swarm node ID: '%s'", err)
+	}
+
+	if _, err := manager.RunSSHCommand(generateNodeLabelsCommand(strings.TrimSpace(out), labels)); err != nil {
+		return fmt.Errorf("Failed to add the hardware labels to the Swarm node: '%s'", err)
+	}
+
+	n.appliedHardwareLabels = labels
+	return nil
+}
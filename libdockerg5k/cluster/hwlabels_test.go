@@ -0,0 +1,44 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHardwareLabelRuleValidate(t *testing.T) {
+	assert.NoError(t, HardwareLabelRule{Label: "gpu=true", Condition: "gpus>=1"}.Validate())
+	assert.NoError(t, HardwareLabelRule{Label: "cpu=xeon", Condition: "cpu_model~Xeon"}.Validate())
+	assert.Error(t, HardwareLabelRule{Label: "gpu", Condition: "gpus>=1"}.Validate())
+	assert.Error(t, HardwareLabelRule{Label: "gpu=true", Condition: "gpus"}.Validate())
+	assert.Error(t, HardwareLabelRule{Label: "gpu=true", Condition: "disks>=1"}.Validate())
+	assert.Error(t, HardwareLabelRule{Label: "gpu=true", Condition: "gpus>=one"}.Validate())
+	assert.Error(t, HardwareLabelRule{Label: "cpu=xeon", Condition: "cpu_model>=1"}.Validate())
+	assert.Error(t, HardwareLabelRule{Label: "cpu=xeon", Condition: "cores~8"}.Validate())
+}
+
+func TestHardwareLabelsDefaultRules(t *testing.T) {
+	labels, err := hardwareLabels(&HardwareInfo{NICRateGbps: 25, GPUs: []string{"Nvidia GTX 1080 Ti"}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gpu=true", "nic=25g"}, labels)
+
+	labels, err = hardwareLabels(&HardwareInfo{NICRateGbps: 10}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nic=10g"}, labels)
+}
+
+func TestHardwareLabelsCustomRules(t *testing.T) {
+	rules := []HardwareLabelRule{
+		{Label: "mem=large", Condition: "memory_bytes>=274877906944"},
+		{Label: "mem=small", Condition: "memory_bytes<274877906944"},
+		{Label: "cpu=xeon", Condition: "cpu_model~Xeon"},
+	}
+
+	labels, err := hardwareLabels(&HardwareInfo{CPUModel: "Intel Xeon E5-2630 v4", MemoryBytes: 137438953472}, rules)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"mem=small", "cpu=xeon"}, labels)
+}
+
+func TestGenerateNodeLabelsCommand(t *testing.T) {
+	assert.Equal(t, "docker node update --label-add gpu=true --label-add nic=25g abc123", generateNodeLabelsCommand("abc123", []string{"gpu=true", "nic=25g"}))
+}
@@ -0,0 +1,182 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClusterStatus contain the live state of the cluster nodes and the state described by the cluster configuration (by machine name), checked by the invariants
+type ClusterStatus struct {
+	Nodes   map[string]*NodeStatus
+	Desired map[string]*NodeStatus
+}
+
+// Invariant is a property of the live cluster, its check returns the violations of the property (empty if it holds)
+type Invariant struct {
+	Name  string
+	Check func(s *ClusterStatus) []string
+}
+
+// InvariantViolation contain a violation of an invariant
+type InvariantViolation struct {
+	Invariant string `json:"invariant"`
+	Reason    string `json:"reason"`
+}
+
+// InvariantError contain all the violations of the asserted invariants
+type InvariantError struct {
+	Violations []InvariantViolation
+}
+
+// Error returns the list of the violations
+func (e *InvariantError) Error() string {
+	violations := []string{}
+	for _, v := range e.Violations {
+		violations = append(violations, fmt.Sprintf("%s (%s)", v.Invariant, v.Reason))
+	}
+
+	return fmt.Sprintf("%d cluster invariant violation(s): %s", len(e.Violations), strings.Join(violations, ", "))
+}
+
+// sortedNodes returns the machine names (sorted) of the nodes satisfying the predicate
+func (s *ClusterStatus) sortedNodes(predicate func(ns *NodeStatus) bool) []string {
+	nodes := []string{}
+	for machineName, ns := range s.Nodes {
+		if predicate(ns) {
+			nodes = append(nodes, machineName)
+		}
+	}
+	sort.Strings(nodes)
+
+	return nodes
+}
+
+// NodesCount check the cluster has exactly the given number of live nodes
+func NodesCount(count int) Invariant {
+	return Invariant{
+		Name: fmt.Sprintf("nodes-count=%d", count),
+		Check: func(s *ClusterStatus) []string {
+			if len(s.Nodes) != count {
+				return []string{fmt.Sprintf("%d node(s)", len(s.Nodes))}
+			}
+			return nil
+		},
+	}
+}
+
+// ManagersCount check the cluster has exactly the given number of Swarm managers (Swarm mode) or masters (Swarm standalone)
+func ManagersCount(count int) Invariant {
+	return Invariant{
+		Name: fmt.Sprintf("managers-count=%d", count),
+		Check: func(s *ClusterStatus) []string {
+			managers := s.sortedNodes(func(ns *NodeStatus) bool {
+				return ns.SwarmRole == SwarmRoleManager || ns.SwarmRole == SwarmRoleMaster
+			})
+
+			if len(managers) != count {
+				return []string{fmt.Sprintf("%d manager(s): %s", len(managers), strings.Join(managers, ", "))}
+			}
+			return nil
+		},
+	}
+}
+
+// NodesJoined check all the live nodes are part of the Swarm cluster
+func NodesJoined() Invariant {
+	return Invariant{
+		Name: "nodes-joined",
+		Check: func(s *ClusterStatus) []string {
+			violations := []string{}
+			for _, machineName := range s.sortedNodes(func(ns *NodeStatus) bool { return ns.SwarmRole == "" }) {
+				violations = append(violations, fmt.Sprintf("node '%s' is not part of the Swarm cluster", machineName))
+			}
+			return violations
+		},
+	}
+}
+
+// NodesLabel check all the live nodes have the Engine label (format: key=value, or key for any value)
+func NodesLabel(label string) Invariant {
+	hasLabel := func(ns *NodeStatus) bool {
+		for _, l := range ns.EngineLabels {
+			if l == label || (!strings.Contains(label, "=") && strings.HasPrefix(l, label+"=")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return Invariant{
+		Name: fmt.Sprintf("nodes-label=%s", label),
+		Check: func(s *ClusterStatus) []string {
+			violations := []string{}
+			for _, machineName := range s.sortedNodes(func(ns *NodeStatus) bool { return !hasLabel(ns) }) {
+				violations = append(violations, fmt.Sprintf("node '%s' does not have the label", machineName))
+			}
+			return violations
+		},
+	}
+}
+
+// NodesMatchConfig check the live nodes are the nodes of the cluster configuration, with their Swarm role and Engine labels (see Plan)
+func NodesMatchConfig() Invariant {
+	return Invariant{
+		Name: "nodes-match-config",
+		Check: func(s *ClusterStatus) []string {
+			diff := computeDiff(s.Desired, s.Nodes)
+
+			violations := []string{}
+			for _, machineName := range diff.Add {
+				violations = append(violations, fmt.Sprintf("node '%s' is missing", machineName))
+			}
+			for _, machineName := range diff.Remove {
+				violations = append(violations, fmt.Sprintf("node '%s' is not in the configuration", machineName))
+			}
+			for _, update := range diff.Update {
+				if update.SwarmRole != nil {
+					violations = append(violations, fmt.Sprintf("node '%s' has Swarm role '%s' instead of '%s'", update.MachineName, update.SwarmRole.From, update.SwarmRole.To))
+				}
+				if len(update.AddedLabels) > 0 {
+					violations = append(violations, fmt.Sprintf("node '%s' is missing label(s): %s", update.MachineName, strings.Join(update.AddedLabels, ", ")))
+				}
+				if len(update.RemovedLabels) > 0 {
+					violations = append(violations, fmt.Sprintf("node '%s' has unexpected label(s): %s", update.MachineName, strings.Join(update.RemovedLabels, ", ")))
+				}
+			}
+			return violations
+		},
+	}
+}
+
+// checkInvariants returns an error containing all the violations of the invariants (nil if they all hold)
+func checkInvariants(s *ClusterStatus, invariants []Invariant) error {
+	e := &InvariantError{Violations: []InvariantViolation{}}
+	for _, inv := range invariants {
+		for _, reason := range inv.Check(s) {
+			e.Violations = append(e.Violations, InvariantViolation{Invariant: inv.Name, Reason: reason})
+		}
+	}
+
+	if len(e.Violations) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// Assert check the invariants (built-in or custom) on the live state of the cluster, typically as an acceptance test after the provisioning
+// all the invariants are checked, the returned error (*InvariantError) contain all their violations
+func (c *Cluster) Assert(invariants []Invariant) error {
+	actual, err := c.Status()
+	if err != nil {
+		return err
+	}
+
+	desired := make(map[string]*NodeStatus)
+	for machineName, n := range c.Nodes {
+		desired[machineName] = n.desiredStatus()
+	}
+
+	return checkInvariants(&ClusterStatus{Nodes: actual, Desired: desired}, invariants)
+}
@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClusterStatus() *ClusterStatus {
+	return &ClusterStatus{
+		Nodes: map[string]*NodeStatus{
+			"lille-0": {MachineName: "lille-0", SwarmRole: SwarmRoleManager, EngineLabels: []string{"g5k.site=lille", "exp=a"}},
+			"lille-1": {MachineName: "lille-1", SwarmRole: SwarmRoleWorker, EngineLabels: []string{"g5k.site=lille"}},
+			"lille-2": {MachineName: "lille-2", EngineLabels: []string{"g5k.site=lille"}},
+		},
+		Desired: map[string]*NodeStatus{
+			"lille-0": {MachineName: "lille-0", SwarmRole: SwarmRoleManager, EngineLabels: []string{"g5k.site=lille", "exp=a"}},
+			"lille-1": {MachineName: "lille-1", SwarmRole: SwarmRoleWorker, EngineLabels: []string{"g5k.site=lille"}},
+			"lille-2": {MachineName: "lille-2", SwarmRole: SwarmRoleWorker, EngineLabels: []string{"g5k.site=lille"}},
+		},
+	}
+}
+
+func TestCheckInvariants(t *testing.T) {
+	s := newTestClusterStatus()
+
+	assert.NoError(t, checkInvariants(s, []Invariant{NodesCount(3), ManagersCount(1), NodesLabel("g5k.site"), NodesLabel("g5k.site=lille")}))
+	assert.NoError(t, checkInvariants(s, nil))
+}
+
+func TestCheckInvariantsViolations(t *testing.T) {
+	s := newTestClusterStatus()
+
+	// custom invariant
+	noDebug := Invariant{Name: "no-debug", Check: func(s *ClusterStatus) []string { return []string{"debug enabled"} }}
+
+	err := checkInvariants(s, []Invariant{ManagersCount(3), NodesJoined(), NodesLabel("exp"), NodesMatchConfig(), noDebug})
+	assert.Error(t, err)
+
+	// all the violations are reported
+	e, ok := err.(*InvariantError)
+	assert.True(t, ok)
+	assert.Equal(t, []InvariantViolation{
+		{Invariant: "managers-count=3", Reason: "1 manager(s): lille-0"},
+		{Invariant: "nodes-joined", Reason: "node 'lille-2' is not part of the Swarm cluster"},
+		{Invariant: "nodes-label=exp", Reason: "node 'lille-1' does not have the label"},
+		{Invariant: "nodes-label=exp", Reason: "node 'lille-2' does not have the label"},
+		{Invariant: "nodes-match-config", Reason: "node 'lille-2' has Swarm role '' instead of 'worker'"},
+		{Invariant: "no-debug", Reason: "debug enabled"},
+	}, e.Violations)
+	assert.Contains(t, err.Error(), "6 cluster invariant violation(s): managers-count=3 (1 manager(s): lille-0)")
+}
+
+func TestNodesMatchConfig(t *testing.T) {
+	s := newTestClusterStatus()
+	s.Nodes["lille-2"].SwarmRole = SwarmRoleWorker
+	delete(s.Nodes, "lille-1")
+	s.Nodes["lille-3"] = &NodeStatus{MachineName: "lille-3"}
+	s.Nodes["lille-0"].EngineLabels = []string{"g5k.site=lille", "exp=b"}
+
+	assert.Equal(t, []string{
+		"node 'lille-1' is missing",
+		"node 'lille-3' is not in the configuration",
+		"node 'lille-0' is missing label(s): exp=a",
+		"node 'lille-0' has unexpected label(s): exp=b",
+	}, NodesMatchConfig().Check(s))
+}
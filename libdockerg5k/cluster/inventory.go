@@ -0,0 +1,286 @@
+package cluster
+
+import (
+	"encoding/json"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/bastion"
+)
+
+// NodeInventory contain the description of a node in the cluster inventory
+type NodeInventory struct {
+	MachineName string `json:"machine_name"`
+	NodeName    string `json:"node_name"`
+	G5kSite     string `json:"g5k_site"`
+	G5kJobID    int    `json:"g5k_job_id"`
+	G5kJobQueue string `json:"g5k_job_queue,omitempty"`
+	SwarmMaster bool   `json:"swarm_master"`
+	Role        string `json:"role,omitempty"`
+
+	// deployment mode of the node (deploy or classic)
+	DeployMode string `json:"deploy_mode"`
+
+	// platform of the images pulled on the node and its detected architecture (only set once provisioned)
+	Platform     string `json:"platform,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+
+	// site requested for the node (only set if it was reserved on a fallback site)
+	RequestedSite string `json:"requested_site,omitempty"`
+
+	// additional names of the node in the static lookup table
+	Aliases []string `json:"aliases,omitempty"`
+
+	// failure domain of the node (only set with a placement policy)
+	FailureDomain string `json:"failure_domain,omitempty"`
+
+	// error of the node provisioning (only set if it failed)
+	ProvisioningError string `json:"provisioning_error,omitempty"`
+
+	// Grid'5000 job facts written on the node (only set once provisioned)
+	JobFacts map[string]string `json:"job_facts,omitempty"`
+
+	// Docker Engine
+	EngineExperimental bool   `json:"engine_experimental"`
+	EngineAPIVersion   string `json:"engine_api_version,omitempty"`
+	EngineDebug        bool   `json:"engine_debug,omitempty"`
+	EngineLogLevel     string `json:"engine_log_level,omitempty"`
+	EngineUsernsRemap  string `json:"engine_userns_remap,omitempty"`
+
+	// default size of the containers '/dev/shm' (Docker default if empty)
+	EngineDefaultShmSize string `json:"engine_default_shm_size,omitempty"`
+
+	// proxy of the Docker Engine requests, without its password (only set if configured)
+	EnginePullProxy string `json:"engine_pull_proxy,omitempty"`
+
+	// data root directory of the Docker Engine
+	EngineDataRoot string `json:"engine_data_root"`
+
+	// number of images kept in the reused persistent data root (only set if reused)
+	CachedImages int `json:"cached_images,omitempty"`
+
+	// default OCI runtime of the Docker Engine
+	EngineRuntime string `json:"engine_runtime"`
+
+	// Docker Engine Prometheus metrics endpoint (host:port)
+	EngineMetricsEndpoint string `json:"engine_metrics_endpoint,omitempty"`
+
+	// Docker Engine systemd service overrides
+	EngineSystemdOverrides map[string]string `json:"engine_systemd_overrides,omitempty"`
+
+	// default container network
+	DefaultContainerNetwork string `json:"default_container_network,omitempty"`
+
+	// default resolver options of the containers
+	ContainerDNSOptions []string `json:"container_dns_options,omitempty"`
+
+	// local volumes
+	LocalVolumes []string `json:"local_volumes,omitempty"`
+
+	// NFS shared directories
+	SharedMounts []string `json:"shared_mounts,omitempty"`
+
+	// stable Weave Net router identity (only set with Weave stable peers)
+	WeavePeerName string `json:"weave_peer_name,omitempty"`
+	WeaveNickname string `json:"weave_nickname,omitempty"`
+
+	// address of the ingress controller (only set on the ingress node)
+	IngressEndpoint string `json:"ingress_endpoint,omitempty"`
+
+	// address of the SOCKS proxy (only set on the bastion node)
+	BastionEndpoint string `json:"bastion_endpoint,omitempty"`
+
+	// network interface used for cluster communications
+	AdvertiseInterface string `json:"advertise_interface"`
+
+	// kernel parameters applied on the node (only set once provisioned)
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+
+	// firewall rules applied on the node (only set once provisioned) and nodes it is partitioned from (only set by a network partition)
+	FirewallRules   []string `json:"firewall_rules,omitempty"`
+	PartitionedFrom []string `json:"partitioned_from,omitempty"`
+
+	// offload features of the Swarm mode data path interface (only set once provisioned, the data path kernel parameters are reported with the sysctls)
+	DataPathOffloads map[string]bool `json:"data_path_offloads,omitempty"`
+
+	// hardware description of the node (only set once collected)
+	Hardware *HardwareInfo `json:"hardware,omitempty"`
+
+	// Swarm mode node labels derived from the hardware description (only set once provisioned)
+	HardwareLabels []string `json:"hardware_labels,omitempty"`
+
+	// resources advertised to the Swarm mode scheduler
+	AdvertisedNanoCPUs    int64 `json:"advertised_nano_cpus,omitempty"`
+	AdvertisedMemoryBytes int64 `json:"advertised_memory_bytes,omitempty"`
+}
+
+// Inventory contain the description of all nodes in the cluster
+type Inventory struct {
+	Nodes map[string]*NodeInventory `json:"nodes"`
+
+	// existing Docker hosts added to the cluster as Swarm mode workers
+	ExternalHosts map[string]*ExternalHost `json:"external_hosts,omitempty"`
+
+	// orchestration options of the Swarm mode cluster (only set with Swarm mode)
+	SwarmOrchestration *SwarmOrchestrationInventory `json:"swarm_orchestration,omitempty"`
+
+	// Linux capabilities added to the infrastructure containers and by default to the workload containers
+	InfraContainerCaps   []string `json:"infra_container_caps,omitempty"`
+	ContainerDefaultCaps []string `json:"container_default_caps,omitempty"`
+
+	// site of the Swarm mode bootstrap manager (only set if a bootstrap site was configured)
+	SwarmBootstrap *BootstrapSelection `json:"swarm_bootstrap,omitempty"`
+
+	// metadata of the experiment
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// service discovery network of the standalone containers and its subnet (only set with Swarm mode)
+	ServiceDiscoveryNetwork string `json:"service_discovery_network,omitempty"`
+	ServiceDiscoverySubnet  string `json:"service_discovery_subnet,omitempty"`
+
+	// placement preferences of the replicated services created on the cluster (only set with Swarm mode)
+	SwarmPlacementPreferences []string `json:"swarm_placement_preferences,omitempty"`
+}
+
+// SwarmOrchestrationInventory contain the orchestration options of the Swarm mode cluster in the inventory (empty if the Docker default is used)
+type SwarmOrchestrationInventory struct {
+	TaskHistoryLimit    int    `json:"task_history_limit,omitempty"`
+	DispatcherHeartbeat string `json:"dispatcher_heartbeat,omitempty"`
+	NodeCertExpiry      string `json:"node_cert_expiry,omitempty"`
+}
+
+// inventory returns the inventory entry of the node
+func (n *Node) inventory() *NodeInventory {
+	ni := &NodeInventory{
+		MachineName: n.MachineName,
+		NodeName:    n.NodeName,
+		G5kSite:     n.G5kSite,
+		G5kJobID:    n.G5kJobID,
+		G5kJobQueue: n.clusterConfig.OARQueue,
+		SwarmMaster: n.isSwarmMaster(),
+		Role:        n.Role,
+
+		DeployMode:    n.deployMode(),
+		RequestedSite: n.requestedSite,
+
+		Platform:     n.platform(),
+		Architecture: n.detectedArch,
+
+		Aliases: n.Aliases,
+
+		FailureDomain: n.FailureDomain,
+
+		JobFacts: n.injectedJobFacts,
+
+		AdvertiseInterface: n.clusterAdvertiseInterface(),
+
+		Sysctls: n.appliedSysctls,
+
+		FirewallRules:   n.appliedFirewallRules,
+		PartitionedFrom: n.partitionedFrom,
+
+		DataPathOffloads: n.appliedDataPathOffloads,
+
+		EngineExperimental: n.clusterConfig.EngineExperimental,
+		EngineAPIVersion:   n.clusterConfig.EngineAPIVersion,
+		EngineDebug:        n.EngineDebug,
+		EngineLogLevel:     n.engineLogLevel(),
+		EngineUsernsRemap:  n.usernsRemapSpec(),
+
+		EngineDefaultShmSize: n.defaultShmSize(),
+
+		EnginePullProxy: redactedPullProxy(n.clusterConfig.EnginePullProxy),
+
+		EngineDataRoot: n.dataRoot(),
+		CachedImages:   n.cachedImages,
+
+		EngineRuntime:         n.clusterConfig.activeRuntime(),
+		EngineMetricsEndpoint: n.metricsEndpoint(),
+
+		EngineSystemdOverrides: n.clusterConfig.EngineSystemdOverrides,
+
+		DefaultContainerNetwork: n.clusterConfig.DefaultContainerNetwork,
+		ContainerDNSOptions:     n.dnsOptions(),
+
+		Hardware:       n.clusterConfig.hardware.get(n.NodeName),
+		HardwareLabels: n.appliedHardwareLabels,
+
+		AdvertisedNanoCPUs:    n.AdvertisedResources.NanoCPUs,
+		AdvertisedMemoryBytes: n.AdvertisedResources.MemoryBytes,
+	}
+
+	// provisioning failure
+	if n.provisionErr != nil {
+		ni.ProvisioningError = n.provisionErr.Error()
+	}
+
+	// Weave Net router identity
+	if n.clusterConfig.WeaveNetworkingEnabled {
+		if id := n.weaveIdentity(); id != nil {
+			ni.WeavePeerName = id.Name
+			ni.WeaveNickname = id.Nickname
+		}
+	}
+
+	// ingress controller
+	if n.isIngressNode() {
+		ni.IngressEndpoint = n.clusterConfig.DeployIngress.Endpoint(n.NodeName)
+	}
+
+	// bastion SOCKS proxy
+	if n.isBastionNode() {
+		ni.BastionEndpoint = bastion.Endpoint(n.NodeName, n.clusterConfig.BastionPort)
+	}
+
+	// local volumes
+	for _, v := range n.LocalVolumeMounts {
+		ni.LocalVolumes = append(ni.LocalVolumes, v.Name)
+	}
+
+	// NFS shared directories
+	for _, m := range n.SharedMounts {
+		ni.SharedMounts = append(ni.SharedMounts, m.String())
+	}
+
+	return ni
+}
+
+// Inventory returns the inventory of the cluster nodes
+func (c *Cluster) Inventory() *Inventory {
+	inv := &Inventory{
+		Nodes:         make(map[string]*NodeInventory),
+		ExternalHosts: c.ExternalHosts,
+
+		InfraContainerCaps:   normalizeCapabilities(c.Config.InfraContainerCaps),
+		ContainerDefaultCaps: normalizeCapabilities(c.Config.ContainerDefaultCaps),
+
+		SwarmBootstrap: c.Config.bootstrapSelection,
+
+		Annotations: c.Config.Annotations,
+	}
+
+	for machineName, n := range c.Nodes {
+		inv.Nodes[machineName] = n.inventory()
+	}
+
+	// Swarm mode orchestration options
+	if gc := c.Config.SwarmModeGlobalConfig; gc != nil {
+		inv.SwarmOrchestration = &SwarmOrchestrationInventory{TaskHistoryLimit: gc.OrchestrationOpts.TaskHistoryLimit}
+		if gc.OrchestrationOpts.DispatcherHeartbeat != 0 {
+			inv.SwarmOrchestration.DispatcherHeartbeat = gc.OrchestrationOpts.DispatcherHeartbeat.String()
+		}
+		if gc.OrchestrationOpts.NodeCertExpiry != 0 {
+			inv.SwarmOrchestration.NodeCertExpiry = gc.OrchestrationOpts.NodeCertExpiry.String()
+		}
+
+		inv.ServiceDiscoveryNetwork = gc.ServiceDiscoveryNetwork
+		inv.ServiceDiscoverySubnet = c.Config.serviceDiscoverySubnet()
+
+		inv.SwarmPlacementPreferences = gc.PlacementPreferences()
+	}
+
+	return inv
+}
+
+// JSON returns the inventory as an indented JSON document
+func (i *Inventory) JSON() ([]byte, error) {
+	return json.MarshalIndent(i, "", "  ")
+}
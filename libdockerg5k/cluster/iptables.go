@@ -0,0 +1,42 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// networkEngineFlags returns the Engine flags of the userland proxy and iptables rules management (only if configured)
+func (c *GlobalConfig) networkEngineFlags() []string {
+	flags := []string{}
+
+	if c.UserlandProxy != nil {
+		flags = append(flags, fmt.Sprintf("userland-proxy=%s", strconv.FormatBool(*c.UserlandProxy)))
+	}
+
+	if c.ManageIptables != nil {
+		flags = append(flags, fmt.Sprintf("iptables=%s", strconv.FormatBool(*c.ManageIptables)))
+	}
+
+	return flags
+}
+
+// networkWarnings returns the consequences of the userland proxy and iptables configuration on the cluster networking
+func (c *GlobalConfig) networkWarnings() []string {
+	warnings := []string{}
+
+	if c.ManageIptables == nil || *c.ManageIptables {
+		return warnings
+	}
+
+	warnings = append(warnings, "The Docker Engine will not manage the iptables rules: the containers will not have outbound connectivity without manual NAT rules")
+
+	if c.SwarmModeGlobalConfig != nil {
+		warnings = append(warnings, "The Swarm mode routing mesh and the overlay networks rely on iptables rules and may not work without the Docker Engine managing them")
+	}
+
+	if c.UserlandProxy != nil && !*c.UserlandProxy {
+		warnings = append(warnings, "The published ports of the containers will not be reachable without both the userland proxy and the iptables rules")
+	}
+
+	return warnings
+}
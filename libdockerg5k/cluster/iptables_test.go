@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkEngineFlags(t *testing.T) {
+	assert.Empty(t, (&GlobalConfig{}).networkEngineFlags())
+
+	enabled, disabled := true, false
+	c := &GlobalConfig{UserlandProxy: &disabled, ManageIptables: &enabled}
+	assert.Equal(t, []string{"userland-proxy=false", "iptables=true"}, c.networkEngineFlags())
+}
+
+func TestEngineFlagsNetworkNodePrecedence(t *testing.T) {
+	disabled := false
+	n := &Node{clusterConfig: &GlobalConfig{UserlandProxy: &disabled, ManageIptables: &disabled}, EngineOpt: []string{"iptables=true"}}
+	assert.Equal(t, []string{"userland-proxy=false", "iptables=true"}, n.engineFlags())
+}
+
+func TestNetworkWarnings(t *testing.T) {
+	enabled, disabled := true, false
+	assert.Empty(t, (&GlobalConfig{}).networkWarnings())
+	assert.Empty(t, (&GlobalConfig{UserlandProxy: &disabled, ManageIptables: &enabled}).networkWarnings())
+	assert.Len(t, (&GlobalConfig{ManageIptables: &disabled}).networkWarnings(), 1)
+	assert.Len(t, (&GlobalConfig{ManageIptables: &disabled, SwarmModeGlobalConfig: &swarm.SwarmModeGlobalConfig{}}).networkWarnings(), 2)
+	assert.Len(t, (&GlobalConfig{ManageIptables: &disabled, UserlandProxy: &disabled}).networkWarnings(), 2)
+}
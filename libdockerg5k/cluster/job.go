@@ -0,0 +1,257 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+)
+
+// JobStatus returns the state of the Grid5000 job of the node
+func (n *Node) JobStatus() (*g5k.JobState, error) {
+	if n.G5kJobID == 0 {
+		return nil, fmt.Errorf("The node '%s' is not reserved", n.MachineName)
+	}
+
+	g5kAPI, err := n.clusterConfig.g5kAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := g5kAPI.GetJob(n.G5kSite, n.G5kJobID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the job '%d' of node '%s': '%s'", n.G5kJobID, n.MachineName, err)
+	}
+
+	return job, nil
+}
+
+// CancelJob kill the Grid5000 job of the node (the Docker Machine is kept, other nodes of the same job are released too)
+func (n *Node) CancelJob() error {
+	if n.G5kJobID == 0 {
+		return fmt.Errorf("The node '%s' is not reserved", n.MachineName)
+	}
+
+	g5kAPI, err := n.clusterConfig.g5kAPI()
+	if err != nil {
+		return err
+	}
+
+	if err := g5kAPI.CancelJob(n.G5kSite, n.G5kJobID); err != nil {
+		return fmt.Errorf("Unable to cancel the job '%d' of node '%s': '%s'", n.G5kJobID, n.MachineName, err)
+	}
+
+	return nil
+}
+
+// newJobNodes returns the nodes (machine name format: {site}-{id}) of the given deployed Grid5000 nodes hostname, sorted by hostname
+func (c *GlobalConfig) newJobNodes(site string, jobID int, hostnames []string) []*Node {
+	sorted := append([]string{}, hostnames...)
+	sort.Strings(sorted)
+
+	nodes := []*Node{}
+	for i, hostname := range sorted {
+		nodes = append(nodes, &Node{
+			clusterConfig: c,
+			MachineName:   fmt.Sprintf("%s-%d", site, i),
+			NodeName:      hostname,
+			G5kSite:       site,
+			G5kJobID:      jobID,
+		})
+	}
+
+	return nodes
+}
+
+// ImportJob returns the nodes of an existing Grid5000 job (reserved outside of docker-g5k) owned by the cluster user
+// The job must be running and its nodes deployed with the cluster SSH key before provisioning them
+func (c *GlobalConfig) ImportJob(site string, jobID int) ([]*Node, error) {
+	user, pass, err := c.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := g5k.Init(user, pass).GetJob(site, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the job '%d' on site '%s': '%s'", jobID, site, err)
+	}
+
+	// check job owner and state
+	if err := job.ValidateImport(user); err != nil {
+		return nil, err
+	}
+
+	nodes := c.newJobNodes(site, jobID, job.Nodes)
+	if err := c.lookupNodesIP(nodes); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// lookupNodesIP set the IP address of the nodes in the static lookup table
+func (c *GlobalConfig) lookupNodesIP(nodes []*Node) error {
+	if c.HostsLookupTable == nil {
+		c.HostsLookupTable = make(map[string]string)
+	}
+
+	for _, n := range nodes {
+		// lookup IP address of the node for static lookup table
+		ip, err := net.LookupIP(n.NodeName)
+		if err != nil || len(ip) < 1 {
+			return fmt.Errorf("Unable to lookup IP address for '%s' node: '%s'", n.NodeName, err)
+		}
+
+		// set IP address of the machine in the static lookup table
+		c.HostsLookupTable[n.MachineName] = ip[0].String()
+	}
+
+	return nil
+}
+
+// assignHostnames returns the hostnames of the job (sorted) assigned to the nodes without hostname, in the given order (machine name => hostname)
+// the hostnames already set on the nodes must belong to the job, and the number of nodes without hostname must match the remaining job nodes
+func assignHostnames(nodes []*Node, hostnames []string) (map[string]string, error) {
+	free := make(map[string]bool)
+	for _, h := range hostnames {
+		free[h] = true
+	}
+
+	unnamed := []*Node{}
+	for _, n := range nodes {
+		if n.NodeName == "" {
+			unnamed = append(unnamed, n)
+			continue
+		}
+
+		if !free[n.NodeName] {
+			return nil, fmt.Errorf("The node '%s' of machine '%s' is not a node of the job or is used more than once", n.NodeName, n.MachineName)
+		}
+		delete(free, n.NodeName)
+	}
+
+	if len(unnamed) != len(free) {
+		return nil, fmt.Errorf("The job has %d nodes to assign but %d nodes are defined without hostname", len(free), len(unnamed))
+	}
+
+	remaining := []string{}
+	for h := range free {
+		remaining = append(remaining, h)
+	}
+	sort.Strings(remaining)
+
+	assigned := make(map[string]string)
+	for i, n := range unnamed {
+		assigned[n.MachineName] = remaining[i]
+	}
+
+	return assigned, nil
+}
+
+// AssignJobNodes set the hostname of the nodes of the Grid5000 job defined without hostname (ex: from a cluster definition file) using the reserved nodes of the job
+// The nodes of the given list reserved in the job (site and job ID) get the remaining job hostnames (sorted) in the list order, the resolved mapping (machine name => hostname) is returned
+func (c *GlobalConfig) AssignJobNodes(nodes []*Node, site string, jobID int) (map[string]string, error) {
+	jobNodes := []*Node{}
+	for _, n := range nodes {
+		if n.G5kSite == site && n.G5kJobID == jobID {
+			jobNodes = append(jobNodes, n)
+		}
+	}
+
+	g5kAPI, err := c.g5kAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := g5kAPI.GetJob(site, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the job '%d' on site '%s': '%s'", jobID, site, err)
+	}
+
+	if !job.IsRunning() {
+		return nil, fmt.Errorf("The job '%d' is not running (state: '%s')", job.UID, job.State)
+	}
+
+	assigned, err := assignHostnames(jobNodes, job.Nodes)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to assign the nodes of job '%d' on site '%s': %s", jobID, site, err)
+	}
+
+	for _, n := range jobNodes {
+		if h, ok := assigned[n.MachineName]; ok {
+			n.NodeName = h
+		}
+	}
+
+	if err := c.lookupNodesIP(jobNodes); err != nil {
+		return nil, err
+	}
+
+	return assigned, nil
+}
+
+// jobKey identify a Grid5000 job (job IDs are unique by site)
+type jobKey struct {
+	site  string
+	jobID int
+}
+
+// nodesByJob returns the machine names (sorted) of the reserved nodes by Grid5000 job
+func (c *Cluster) nodesByJob() map[jobKey][]string {
+	jobs := make(map[jobKey][]string)
+	for machineName, n := range c.Nodes {
+		if n.G5kJobID == 0 {
+			continue
+		}
+
+		k := jobKey{n.G5kSite, n.G5kJobID}
+		jobs[k] = append(jobs[k], machineName)
+	}
+
+	for _, machineNames := range jobs {
+		sort.Strings(machineNames)
+	}
+
+	return jobs
+}
+
+// ownsJob returns true if the job was reserved by docker-g5k for all its nodes, false if any node imported it (reserved outside of docker-g5k)
+func (c *Cluster) ownsJob(machineNames []string) bool {
+	for _, machineName := range machineNames {
+		if n, ok := c.Nodes[machineName]; ok && !n.OwnsJob {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ExtendWalltime request a walltime extension (format: [+]hours[:minutes[:seconds]]) of the Grid5000 jobs of the cluster and returns the machine names (sorted) of the extended nodes
+// The jobs whose extension is refused (depending on the site and queue policies) are reported in the returned error, the other jobs are still extended
+func (c *Cluster) ExtendWalltime(additional string) ([]string, error) {
+	if _, err := g5k.ValidateWalltimeExtension(additional); err != nil {
+		return nil, err
+	}
+
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	extended := []string{}
+	errs := make(map[string]error)
+	for k, machineNames := range c.nodesByJob() {
+		if err := g5kAPI.ExtendJobWalltime(k.site, k.jobID, additional); err != nil {
+			for _, machineName := range machineNames {
+				errs[machineName] = fmt.Errorf("Unable to extend the walltime of job '%d' on site '%s': '%s'", k.jobID, k.site, err)
+			}
+			continue
+		}
+
+		extended = append(extended, machineNames...)
+	}
+	sort.Strings(extended)
+
+	return extended, fleetError("Walltime extension", errs)
+}
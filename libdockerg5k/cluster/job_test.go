@@ -0,0 +1,65 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJobNodes(t *testing.T) {
+	config := &GlobalConfig{}
+	nodes := config.newJobNodes("nancy", 42, []string{"graphene-2.nancy.grid5000.fr", "graphene-1.nancy.grid5000.fr"})
+
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "nancy-0", nodes[0].MachineName)
+	assert.Equal(t, "graphene-1.nancy.grid5000.fr", nodes[0].NodeName)
+	assert.Equal(t, "nancy-1", nodes[1].MachineName)
+	assert.Equal(t, "graphene-2.nancy.grid5000.fr", nodes[1].NodeName)
+
+	for _, n := range nodes {
+		assert.Equal(t, "nancy", n.G5kSite)
+		assert.Equal(t, 42, n.G5kJobID)
+		assert.Equal(t, config, n.clusterConfig)
+	}
+}
+
+func TestNodesByJob(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.Nodes["lille-1"] = &Node{MachineName: "lille-1", G5kSite: "lille", G5kJobID: 42}
+	c.Nodes["lille-0"] = &Node{MachineName: "lille-0", G5kSite: "lille", G5kJobID: 42}
+	c.Nodes["nancy-0"] = &Node{MachineName: "nancy-0", G5kSite: "nancy", G5kJobID: 42}
+	c.Nodes["nancy-1"] = &Node{MachineName: "nancy-1", G5kSite: "nancy"}
+
+	assert.Equal(t, map[jobKey][]string{
+		{"lille", 42}: {"lille-0", "lille-1"},
+		{"nancy", 42}: {"nancy-0"},
+	}, c.nodesByJob())
+}
+
+func TestAssignHostnames(t *testing.T) {
+	nodes := []*Node{
+		{MachineName: "web"},
+		{MachineName: "db", NodeName: "graphene-1.nancy.grid5000.fr"},
+		{MachineName: "cache"},
+	}
+
+	assigned, err := assignHostnames(nodes, []string{"graphene-3.nancy.grid5000.fr", "graphene-2.nancy.grid5000.fr", "graphene-1.nancy.grid5000.fr"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"web": "graphene-2.nancy.grid5000.fr", "cache": "graphene-3.nancy.grid5000.fr"}, assigned)
+}
+
+func TestAssignHostnamesIncorrect(t *testing.T) {
+	hostnames := []string{"graphene-1.nancy.grid5000.fr", "graphene-2.nancy.grid5000.fr"}
+
+	// count mismatch
+	_, err := assignHostnames([]*Node{{MachineName: "web"}}, hostnames)
+	assert.Error(t, err)
+
+	// hostname not in the job
+	_, err = assignHostnames([]*Node{{MachineName: "web"}, {MachineName: "db", NodeName: "griffon-1.nancy.grid5000.fr"}}, hostnames)
+	assert.Error(t, err)
+
+	// hostname used more than once
+	_, err = assignHostnames([]*Node{{MachineName: "web", NodeName: "graphene-1.nancy.grid5000.fr"}, {MachineName: "db", NodeName: "graphene-1.nancy.grid5000.fr"}}, hostnames)
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// jobFactsPath is the env file (KEY=value) containing the Grid5000 job facts on the nodes
+	jobFactsPath = "/etc/docker-g5k/job.env"
+)
+
+// syncClusterNodes store the machine names (sorted) of the cluster nodes in the configuration, for the job facts of the nodes
+func (c *Cluster) syncClusterNodes() {
+	nodes := []string{}
+	for machineName := range c.Nodes {
+		nodes = append(nodes, machineName)
+	}
+	sort.Strings(nodes)
+
+	c.Config.clusterNodes = nodes
+}
+
+// jobFacts returns the Grid5000 job facts of the node (job ID, start time, nodes of the job and of the cluster) as environment variables
+func (n *Node) jobFacts(job *g5k.JobState) map[string]string {
+	jobNodes := append([]string{}, job.Nodes...)
+	sort.Strings(jobNodes)
+
+	// the cluster nodes are resolvable by their machine name (the registry, ingress and external hosts of the static lookup table are not cluster nodes)
+	clusterNodes := append([]string{}, n.clusterConfig.clusterNodes...)
+
+	facts := map[string]string{
+		"G5K_SITE":          n.G5kSite,
+		"G5K_JOB_ID":        strconv.Itoa(n.G5kJobID),
+		"G5K_JOB_NODES":     strings.Join(jobNodes, ","),
+		"G5K_NODE_NAME":     n.NodeName,
+		"G5K_MACHINE_NAME":  n.MachineName,
+		"G5K_CLUSTER_NODES": strings.Join(clusterNodes, ","),
+	}
+
+	if job.StartedAt != 0 {
+		facts["G5K_JOB_START"] = time.Unix(job.StartedAt, 0).UTC().Format(time.RFC3339)
+	}
+
+	return facts
+}
+
+// generateEnvFile returns the env file (one KEY=value line by variable, sorted) of the variables
+func generateEnvFile(vars map[string]string) string {
+	keys := []string{}
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buffer bytes.Buffer
+	for _, k := range keys {
+		buffer.WriteString(fmt.Sprintf("%s=%s\n", k, vars[k]))
+	}
+
+	return buffer.String()
+}
+
+// writeJobFacts write the Grid5000 job facts of the node to the job env file (if enabled) and keep them for the inventory
+func (n *Node) writeJobFacts(h *host.Host) error {
+	if !n.clusterConfig.JobFacts {
+		return nil
+	}
+
+	job, err := n.JobStatus()
+	if err != nil {
+		return err
+	}
+
+	facts := n.jobFacts(job)
+	if _, err := h.RunSSHCommand(fmt.Sprintf("mkdir -p %s && printf '%%s' '%s' >%s", path.Dir(jobFactsPath), generateEnvFile(facts), jobFactsPath)); err != nil {
+		return fmt.Errorf("Failed to write the job facts to '%s': '%s'", jobFactsPath, err)
+	}
+
+	n.injectedJobFacts = facts
+	return nil
+}
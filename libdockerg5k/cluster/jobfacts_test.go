@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+)
+
+func TestNodeJobFacts(t *testing.T) {
+	c := NewCluster(&GlobalConfig{HostsLookupTable: map[string]string{"lille-1": "172.16.20.2", "lille-0": "172.16.20.1", "registry.g5k.local": "172.16.20.1", "ext-0": "10.0.0.1"}})
+	c.Nodes["lille-1"] = &Node{clusterConfig: c.Config, MachineName: "lille-1"}
+	n := &Node{clusterConfig: c.Config, MachineName: "lille-0", NodeName: "chetemi-1.lille.grid5000.fr", G5kSite: "lille", G5kJobID: 42}
+	c.Nodes["lille-0"] = n
+
+	// the service aliases and the external hosts of the static lookup table are not cluster nodes
+	c.syncClusterNodes()
+
+	facts := n.jobFacts(&g5k.JobState{StartedAt: 1500000000, Nodes: []string{"chetemi-2.lille.grid5000.fr", "chetemi-1.lille.grid5000.fr"}})
+	assert.Equal(t, map[string]string{
+		"G5K_SITE":          "lille",
+		"G5K_JOB_ID":        "42",
+		"G5K_JOB_START":     "2017-07-14T02:40:00Z",
+		"G5K_JOB_NODES":     "chetemi-1.lille.grid5000.fr,chetemi-2.lille.grid5000.fr",
+		"G5K_NODE_NAME":     "chetemi-1.lille.grid5000.fr",
+		"G5K_MACHINE_NAME":  "lille-0",
+		"G5K_CLUSTER_NODES": "lille-0,lille-1",
+	}, facts)
+
+	// the start time is not set if the job is not started
+	_, ok := n.jobFacts(&g5k.JobState{})["G5K_JOB_START"]
+	assert.False(t, ok)
+}
+
+func TestGenerateEnvFile(t *testing.T) {
+	assert.Equal(t, "A=1\nB=x,y\n", generateEnvFile(map[string]string{"B": "x,y", "A": "1"}))
+}
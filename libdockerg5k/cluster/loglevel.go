@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// engineDebugCommand returns 'true' if the Docker Engine runs in debug mode
+	engineDebugCommand = "docker info --format '{{.Debug}}'"
+)
+
+var (
+	// engineLogLevels are the log levels supported by the Docker Engine
+	engineLogLevels = []string{"debug", "info", "warn", "error", "fatal"}
+)
+
+// validateEngineLogLevel check the Docker Engine log level (empty for the Docker default)
+func validateEngineLogLevel(level string) error {
+	if level == "" {
+		return nil
+	}
+
+	for _, l := range engineLogLevels {
+		if level == l {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Invalid Engine log level: '%s' (supported: %s)", level, strings.Join(engineLogLevels, ", "))
+}
+
+// engineLogLevel returns the Docker Engine log level of the node (debug mode and the node 'log-level' flag take precedence, empty for the Docker default)
+func (n *Node) engineLogLevel() string {
+	if n.EngineDebug {
+		return "debug"
+	}
+
+	if l := getEngineFlagValue(n.EngineOpt, "log-level"); l != "" {
+		return l
+	}
+
+	return n.clusterConfig.EngineLogLevel
+}
+
+// logLevelEngineFlags returns the debug and log level Engine flags of the node (the node 'log-level' flag is kept if set)
+func (n *Node) logLevelEngineFlags() []string {
+	flags := []string{}
+
+	if n.EngineDebug {
+		flags = append(flags, "debug")
+	}
+
+	if getEngineFlagValue(n.EngineOpt, "log-level") != "" {
+		if n.EngineDebug {
+			log.Warnf("The debug mode of node '%s' ('%s') overrides its log level flag", n.NodeName, n.MachineName)
+		}
+		return flags
+	}
+
+	if l := n.engineLogLevel(); l != "" {
+		flags = append(flags, fmt.Sprintf("log-level=%s", l))
+	}
+
+	return flags
+}
+
+// checkEngineDebug check the Engine of the node runs in debug mode (if enabled), restarting it if it still runs with a previous configuration
+func (n *Node) checkEngineDebug(h *host.Host) error {
+	if !n.EngineDebug {
+		return nil
+	}
+
+	out, err := h.RunSSHCommand(engineDebugCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the Engine debug mode: '%s'", err)
+	}
+
+	if strings.TrimSpace(out) == "true" {
+		return nil
+	}
+
+	log.Infof("Restarting the Docker Engine of node '%s' ('%s') to enable the debug mode...", n.NodeName, n.MachineName)
+	if err := n.restartEngine(h); err != nil {
+		return err
+	}
+
+	out, err = h.RunSSHCommand(engineDebugCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the Engine debug mode: '%s'", err)
+	}
+
+	if strings.TrimSpace(out) != "true" {
+		return fmt.Errorf("The Docker Engine does not run in debug mode after its restart")
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEngineLogLevel(t *testing.T) {
+	assert.NoError(t, validateEngineLogLevel(""))
+	assert.NoError(t, validateEngineLogLevel("debug"))
+	assert.NoError(t, validateEngineLogLevel("warn"))
+
+	assert.Error(t, validateEngineLogLevel("verbose"))
+	assert.Error(t, validateEngineLogLevel("DEBUG"))
+}
+
+func TestLogLevelEngineFlags(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{}}
+	assert.Empty(t, n.logLevelEngineFlags())
+	assert.Equal(t, "", n.engineLogLevel())
+
+	n = &Node{clusterConfig: &GlobalConfig{EngineLogLevel: "warn"}}
+	assert.Equal(t, []string{"log-level=warn"}, n.logLevelEngineFlags())
+	assert.Equal(t, "warn", n.engineLogLevel())
+
+	// debug mode of the node
+	n = &Node{clusterConfig: &GlobalConfig{EngineLogLevel: "warn"}, EngineDebug: true}
+	assert.Equal(t, []string{"debug", "log-level=debug"}, n.logLevelEngineFlags())
+	assert.Equal(t, "debug", n.engineLogLevel())
+
+	// the node flag takes precedence
+	n = &Node{clusterConfig: &GlobalConfig{EngineLogLevel: "warn"}, EngineOpt: []string{"log-level=error"}}
+	assert.Empty(t, n.logLevelEngineFlags())
+	assert.Equal(t, "error", n.engineLogLevel())
+}
@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// logging modes of the libmachine/driver output
+	LogLevelQuiet   = "quiet"   // only the errors
+	LogLevelNormal  = "normal"  // informations, warnings and errors (default)
+	LogLevelVerbose = "verbose" // libmachine debug messages (SSH commands, provisioning steps), without the raw driver output
+	LogLevelTrace   = "trace"   // everything, including the raw output of the driver plugin
+)
+
+var (
+	// regexDriverOutput match a line of raw driver output relayed by libmachine (format: '(machine name) output' or '(machine name) DBG | output')
+	regexDriverOutput = regexp.MustCompile(`^\([[:alnum:]._-]+\) `)
+)
+
+// validateLogLevel check the logging mode (empty for the normal mode)
+func validateLogLevel(level string) error {
+	switch level {
+	case "", LogLevelQuiet, LogLevelNormal, LogLevelVerbose, LogLevelTrace:
+		return nil
+	}
+
+	return fmt.Errorf("Invalid log level: '%s' (supported: quiet, normal, verbose, trace)", level)
+}
+
+// lineFilterWriter write the complete lines that are not dropped by the filter to the underlying writer
+type lineFilterWriter struct {
+	mu   sync.Mutex
+	w    io.Writer
+	drop func(line []byte) bool
+	buf  []byte
+}
+
+func (f *lineFilterWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.buf = append(f.buf, p...)
+	for {
+		i := bytes.IndexByte(f.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := f.buf[:i+1]
+		if !f.drop(line) {
+			if _, err := f.w.Write(line); err != nil {
+				return len(p), err
+			}
+		}
+		f.buf = f.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// logWriters returns whether the debug messages are enabled and the writers of the libmachine logger for the logging mode (to the given output and error writers)
+// (libmachine write the informations and warnings to the output writer, the debug messages and errors to the error writer)
+func logWriters(level string, out io.Writer, errw io.Writer) (bool, io.Writer, io.Writer) {
+	switch level {
+	case LogLevelQuiet:
+		return false, ioutil.Discard, errw
+	case LogLevelVerbose:
+		return true, out, &lineFilterWriter{w: errw, drop: regexDriverOutput.Match}
+	case LogLevelTrace:
+		return true, out, errw
+	}
+
+	return false, out, errw
+}
+
+// ConfigureLogging set the libmachine logger (used by docker-g5k and the driver) to the logging mode of the cluster, writing to stdout and stderr
+// the logger is left unchanged if the logging mode is not set (ex: writers set by the caller)
+func (c *GlobalConfig) ConfigureLogging() {
+	if c.LogLevel == "" {
+		return
+	}
+
+	debug, out, errw := logWriters(c.LogLevel, os.Stdout, os.Stderr)
+
+	log.SetDebug(debug)
+	log.SetOutWriter(out)
+	log.SetErrWriter(errw)
+}
@@ -0,0 +1,54 @@
+package cluster
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateLogLevel(t *testing.T) {
+	assert.NoError(t, validateLogLevel(""))
+	assert.NoError(t, validateLogLevel(LogLevelQuiet))
+	assert.NoError(t, validateLogLevel(LogLevelTrace))
+	assert.Error(t, validateLogLevel("debug"))
+}
+
+func TestLineFilterWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := &lineFilterWriter{w: &b, drop: regexDriverOutput.Match}
+
+	w.Write([]byte("Running SSH command\n(lille-0) DBG | Getting job"))
+	assert.Equal(t, "Running SSH command\n", b.String())
+
+	// the dropped line is completed by the next write
+	w.Write([]byte(" state\n(lille-0) Waiting for the deployment\nProvisioning done\n"))
+	assert.Equal(t, "Running SSH command\nProvisioning done\n", b.String())
+}
+
+func TestLogWriters(t *testing.T) {
+	var out, errw bytes.Buffer
+
+	debug, o, e := logWriters(LogLevelQuiet, &out, &errw)
+	assert.False(t, debug)
+	assert.Equal(t, ioutil.Discard, o)
+	assert.Equal(t, &errw, e)
+
+	debug, o, e = logWriters(LogLevelNormal, &out, &errw)
+	assert.False(t, debug)
+	assert.Equal(t, &out, o)
+	assert.Equal(t, &errw, e)
+
+	debug, o, e = logWriters(LogLevelVerbose, &out, &errw)
+	assert.True(t, debug)
+	assert.Equal(t, &out, o)
+	e.Write([]byte("(lille-0) raw output\nSSH cmd err, output: exit status 1\n"))
+	assert.Equal(t, "SSH cmd err, output: exit status 1\n", errw.String())
+
+	errw.Reset()
+	debug, _, e = logWriters(LogLevelTrace, &out, &errw)
+	assert.True(t, debug)
+	e.Write([]byte("(lille-0) raw output\n"))
+	assert.Equal(t, "(lille-0) raw output\n", errw.String())
+}
@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// collectLogsTimeout is the maximum time allowed to collect the logs of all nodes
+	collectLogsTimeout = 5 * time.Minute
+
+	// engineLogsCommand returns the Docker Engine logs (from the journal, or the log file for non-systemd hosts)
+	engineLogsCommand = "journalctl -u docker.service --no-pager 2>/dev/null || cat /var/log/docker.log"
+)
+
+// containerLogsCommand returns the command used to get the logs of a container
+func containerLogsCommand(containerName string) string {
+	return fmt.Sprintf("docker logs %s 2>&1", containerName)
+}
+
+// logsToCollect returns the logs to collect on the node (file suffix => command)
+func (n *Node) logsToCollect() map[string]string {
+	logs := map[string]string{"": engineLogsCommand}
+
+	// Weave Net / Discovery containers
+	if n.clusterConfig.SwarmStandaloneGlobalConfig != nil && n.clusterConfig.WeaveNetworkingEnabled {
+		logs["-weave"] = containerLogsCommand("weave")
+		logs["-weavediscovery"] = containerLogsCommand("weavediscovery")
+	}
+
+	// Zookeeper cluster storage container (master nodes only)
+	if n.clusterConfig.UseZookeeperClusterStorage && n.isSwarmMaster() {
+		logs["-zookeeper"] = containerLogsCommand("docker-g5k-zookeeper")
+	}
+
+	return logs
+}
+
+// collectLogs save the logs of the node in the destination directory
+func (n *Node) collectLogs(destDir string) error {
+	for suffix, cmd := range n.logsToCollect() {
+		out, err := n.runSSHCommand(cmd)
+		if err != nil {
+			return fmt.Errorf("Failed to get logs with command '%s': '%s'", cmd, err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(destDir, fmt.Sprintf("%s%s.log", n.MachineName, suffix)), []byte(out), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CollectEngineLogs save the Docker Engine logs (and the Weave/Zookeeper containers logs if enabled) of the selected nodes (all nodes if the selector is empty) in the destination directory
+func (c *Cluster) CollectEngineLogs(destDir string, sel *NodeSelector) error {
+	if err := c.checkSelection(sel); err != nil {
+		return err
+	}
+
+	// create destination directory
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("Unable to create logs directory '%s': '%s'", destDir, err)
+	}
+
+	errs := c.runOnSelectedNodes(sel, collectLogsTimeout, func(n *Node, h *host.Host) error {
+		return n.collectLogs(destDir)
+	})
+
+	return fleetError("Logs collection", errs)
+}
@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+)
+
+func TestLogsToCollectEngineOnly(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{}, MachineName: "lille-0"}
+	assert.Equal(t, map[string]string{"": engineLogsCommand}, n.logsToCollect())
+}
+
+func TestLogsToCollectWeaveAndZookeeper(t *testing.T) {
+	config := &GlobalConfig{
+		SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{},
+		SwarmMasterNode:             []string{"lille-0"},
+		WeaveNetworkingEnabled:      true,
+		UseZookeeperClusterStorage:  true,
+	}
+
+	master := &Node{clusterConfig: config, MachineName: "lille-0"}
+	assert.Len(t, master.logsToCollect(), 4)
+	assert.Contains(t, master.logsToCollect(), "-zookeeper")
+
+	worker := &Node{clusterConfig: config, MachineName: "lille-1"}
+	assert.Len(t, worker.logsToCollect(), 3)
+	assert.NotContains(t, worker.logsToCollect(), "-zookeeper")
+}
@@ -0,0 +1,146 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/docker/machine/libmachine/host"
+)
+
+// managersStatus returns the Raft status of the Swarm mode managers, queried from the first responding manager (in the Swarm master/manager list order)
+func (c *Cluster) managersStatus() ([]swarm.ManagerStatus, error) {
+	if c.Config.SwarmModeGlobalConfig == nil {
+		return nil, fmt.Errorf("Swarm mode is not enabled for this cluster")
+	}
+	if len(c.Config.SwarmMasterNode) == 0 {
+		return nil, fmt.Errorf("At least one Swarm master/manager node is required")
+	}
+
+	errs := []string{}
+	for _, machineName := range c.Config.SwarmMasterNode {
+		n, ok := c.Nodes[machineName]
+		if !ok {
+			continue
+		}
+
+		h, err := n.loadHost()
+		if err == nil {
+			var status []swarm.ManagerStatus
+			if status, err = c.Config.SwarmModeGlobalConfig.ManagersStatus(h); err == nil {
+				return status, nil
+			}
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", machineName, err))
+	}
+
+	return nil, fmt.Errorf("No Swarm manager is reachable (%s)", strings.Join(errs, ", "))
+}
+
+// nodeByHostname returns the cluster node with the given Swarm node hostname (machine name or Grid5000 hostname), nil if none
+func (c *Cluster) nodeByHostname(hostname string) *Node {
+	if n, ok := c.Nodes[hostname]; ok {
+		return n
+	}
+
+	for _, n := range c.Nodes {
+		if n.NodeName == hostname || strings.SplitN(n.NodeName, ".", 2)[0] == hostname {
+			return n
+		}
+	}
+
+	return nil
+}
+
+// reachableManagerNodes returns the cluster nodes of the reachable managers (the leader first, then sorted by machine name)
+func (c *Cluster) reachableManagerNodes(status []swarm.ManagerStatus) []*Node {
+	nodes := []*Node{}
+	leader := make(map[string]bool)
+	for _, m := range status {
+		if !m.IsReachable() {
+			continue
+		}
+
+		if n := c.nodeByHostname(m.Hostname); n != nil {
+			nodes = append(nodes, n)
+			leader[n.MachineName] = m.Leader
+		}
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		if leader[nodes[i].MachineName] != leader[nodes[j].MachineName] {
+			return leader[nodes[i].MachineName]
+		}
+		return nodes[i].MachineName < nodes[j].MachineName
+	})
+
+	return nodes
+}
+
+// Leader returns the host of the Swarm mode manager currently Raft leader
+func (c *Cluster) Leader() (*host.Host, error) {
+	status, err := c.managersStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range status {
+		if !m.Leader {
+			continue
+		}
+
+		n := c.nodeByHostname(m.Hostname)
+		if n == nil {
+			return nil, fmt.Errorf("The Swarm leader '%s' is not a node of the cluster", m.Hostname)
+		}
+
+		return n.loadHost()
+	}
+
+	return nil, fmt.Errorf("The Swarm mode cluster has no leader")
+}
+
+// ReachableManagers returns the hosts of the reachable Swarm mode managers (the leader first)
+func (c *Cluster) ReachableManagers() ([]*host.Host, error) {
+	status, err := c.managersStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []*host.Host{}
+	for _, n := range c.reachableManagerNodes(status) {
+		h, err := n.loadHost()
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, h)
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("No Swarm manager is reachable")
+	}
+
+	return hosts, nil
+}
+
+// swarmManager returns the host of a reachable Swarm mode manager (the leader if possible) to run a cluster operation, excluding the given machines
+func (c *Cluster) swarmManager(exclude ...string) (*host.Host, error) {
+	status, err := c.managersStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[string]bool)
+	for _, machineName := range exclude {
+		excluded[machineName] = true
+	}
+
+	for _, n := range c.reachableManagerNodes(status) {
+		if !excluded[n.MachineName] {
+			return n.loadHost()
+		}
+	}
+
+	return nil, fmt.Errorf("No Swarm manager is reachable")
+}
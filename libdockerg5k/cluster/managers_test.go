@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeByHostname(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.Nodes["lille-0"] = &Node{MachineName: "lille-0", NodeName: "chetemi-1.lille.grid5000.fr"}
+	c.Nodes["lille-1"] = &Node{MachineName: "lille-1", NodeName: "chetemi-2.lille.grid5000.fr"}
+
+	assert.Equal(t, "lille-0", c.nodeByHostname("lille-0").MachineName)
+	assert.Equal(t, "lille-1", c.nodeByHostname("chetemi-2.lille.grid5000.fr").MachineName)
+	assert.Equal(t, "lille-1", c.nodeByHostname("chetemi-2").MachineName)
+	assert.Nil(t, c.nodeByHostname("chetemi-3"))
+}
+
+func TestReachableManagerNodes(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0", "lille-1", "lille-2", "lille-3"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleManager, "lille-2": NodeRoleManager, "lille-3": NodeRoleManager})
+
+	status := []swarm.ManagerStatus{
+		{Hostname: "lille-0", Reachability: "reachable"},
+		{Hostname: "lille-1", Reachability: "unreachable"},
+		{Hostname: "lille-3", Reachability: "reachable"},
+		{Hostname: "lille-2", Leader: true, Reachability: "reachable"},
+		{Hostname: "unknown", Reachability: "reachable"},
+	}
+
+	machineNames := []string{}
+	for _, n := range c.reachableManagerNodes(status) {
+		machineNames = append(machineNames, n.MachineName)
+	}
+	assert.Equal(t, []string{"lille-2", "lille-0", "lille-3"}, machineNames)
+
+	assert.Empty(t, c.reachableManagerNodes([]swarm.ManagerStatus{{Hostname: "lille-0", Reachability: "unreachable"}}))
+}
+
+func TestManagersStatusRequireSwarmMode(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	_, err := c.Leader()
+	assert.Error(t, err)
+
+	_, err = c.ReachableManagers()
+	assert.Error(t, err)
+}
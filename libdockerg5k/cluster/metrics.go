@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// validateMetricsAddr check the Docker Engine metrics address (format: [ip]:port), the metrics endpoint requires the experimental features
+func validateMetricsAddr(addr string, experimental bool) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("Invalid Engine metrics address: '%s' (format: [ip]:port)", addr)
+	}
+
+	if host != "" && net.ParseIP(host) == nil {
+		return fmt.Errorf("Invalid Engine metrics address: '%s' (the host must be an IP address)", addr)
+	}
+
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("Invalid Engine metrics address: '%s' (the port must be between 1 and 65535)", addr)
+	}
+
+	if !experimental {
+		return fmt.Errorf("The Engine metrics address requires the Docker Engine experimental features to be enabled")
+	}
+
+	return nil
+}
+
+// metricsEndpoint returns the address of the Engine metrics endpoint of the node for scraping (empty if not configured)
+// the node hostname is used when the metrics address listen on all interfaces
+func (n *Node) metricsEndpoint() string {
+	addr := n.clusterConfig.EngineMetricsAddr
+	if addr == "" {
+		return ""
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		host = n.NodeName
+	}
+
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,36 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMetricsAddr(t *testing.T) {
+	assert.NoError(t, validateMetricsAddr("0.0.0.0:9323", true))
+	assert.NoError(t, validateMetricsAddr(":9323", true))
+	assert.NoError(t, validateMetricsAddr("[::]:9323", true))
+	assert.Error(t, validateMetricsAddr("0.0.0.0:9323", false))
+	assert.Error(t, validateMetricsAddr("9323", true))
+	assert.Error(t, validateMetricsAddr("localhost:9323", true))
+	assert.Error(t, validateMetricsAddr("0.0.0.0:0", true))
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{}, NodeName: "chimint-1.lille.grid5000.fr"}
+	assert.Equal(t, "", n.metricsEndpoint())
+
+	n.clusterConfig.EngineMetricsAddr = "0.0.0.0:9323"
+	assert.Equal(t, "chimint-1.lille.grid5000.fr:9323", n.metricsEndpoint())
+
+	n.clusterConfig.EngineMetricsAddr = ":9323"
+	assert.Equal(t, "chimint-1.lille.grid5000.fr:9323", n.metricsEndpoint())
+
+	n.clusterConfig.EngineMetricsAddr = "172.16.0.1:9323"
+	assert.Equal(t, "172.16.0.1:9323", n.metricsEndpoint())
+}
+
+func TestEngineFlagsMetricsAddr(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{EngineExperimental: true, EngineMetricsAddr: "0.0.0.0:9323"}}
+	assert.Equal(t, []string{"experimental", "metrics-addr=0.0.0.0:9323"}, n.engineFlags())
+}
@@ -0,0 +1,318 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// defaultIperfImage is the default image of the temporary iperf3 containers (entrypoint: iperf3)
+	defaultIperfImage = "networkstatic/iperf3"
+
+	// netMatrixPingImage is the image of the temporary containers pinging the other nodes on the overlay network
+	netMatrixPingImage = "busybox"
+
+	// defaultNetMatrixDuration is the default duration of each bandwidth measurement
+	defaultNetMatrixDuration = 5 * time.Second
+
+	// number of ICMP echo requests sent for each latency measurement of the network matrix
+	netMatrixPingCount = 5
+
+	// maximum time allowed to start or stop the iperf3 servers (including the image pull)
+	iperfServersTimeout = 5 * time.Minute
+
+	// name of the iperf3 server containers on the underlay and overlay networks
+	iperfUnderlayServer = "docker-g5k-iperf"
+	iperfOverlayServer  = "docker-g5k-iperf-weave"
+
+	// weaveNetwork is the Docker network of the Weave Net plugin
+	weaveNetwork = "weave"
+)
+
+// NetMatrixOpts contain the options of the network measurements between the nodes
+type NetMatrixOpts struct {
+	Concurrency int           // maximum number of pairs measured at the same time, a node is never in two concurrent measurements (1 if <= 0)
+	Duration    time.Duration // duration of each bandwidth measurement (default if not set)
+	IperfImage  string        // image of the iperf3 containers (default if empty)
+	Selector    *NodeSelector // measured nodes (all nodes if empty)
+}
+
+// NetMeasurement contain the latency and bandwidth measured from a node to another node
+type NetMeasurement struct {
+	LatencyMs     float64 `json:"latency_ms"`
+	BandwidthMbps float64 `json:"bandwidth_mbps"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// NetMatrix contain the measurements between each (ordered) pair of nodes (source machine name => destination machine name => measurement)
+type NetMatrix struct {
+	Nodes    []string                              `json:"nodes"`
+	Underlay map[string]map[string]*NetMeasurement `json:"underlay"`
+
+	// measurements between the containers on the Weave network (only set with Weave networking)
+	Overlay map[string]map[string]*NetMeasurement `json:"overlay,omitempty"`
+}
+
+// nodePair is an ordered pair of nodes (by machine name) measured by the network matrix
+type nodePair struct {
+	src string
+	dst string
+}
+
+func (p nodePair) String() string {
+	return p.src + "->" + p.dst
+}
+
+// iperfImage returns the image of the iperf3 containers
+func (o *NetMatrixOpts) iperfImage() string {
+	if o.IperfImage != "" {
+		return o.IperfImage
+	}
+
+	return defaultIperfImage
+}
+
+// duration returns the duration of each bandwidth measurement
+func (o *NetMatrixOpts) duration() time.Duration {
+	if o.Duration > 0 {
+		return o.Duration
+	}
+
+	return defaultNetMatrixDuration
+}
+
+// concurrency returns the maximum number of pairs measured at the same time
+func (o *NetMatrixOpts) concurrency() int {
+	if o.Concurrency > 0 {
+		return o.Concurrency
+	}
+
+	return 1
+}
+
+// scheduleNodePairs returns the ordered pairs of the nodes grouped in rounds of at most the given number of pairs, without any node in two pairs of a round
+func scheduleNodePairs(nodes []string, concurrency int) [][]nodePair {
+	pending := []nodePair{}
+	for _, src := range nodes {
+		for _, dst := range nodes {
+			if src != dst {
+				pending = append(pending, nodePair{src, dst})
+			}
+		}
+	}
+
+	rounds := [][]nodePair{}
+	for len(pending) > 0 {
+		round := []nodePair{}
+		busy := make(map[string]bool)
+		remaining := []nodePair{}
+
+		for _, p := range pending {
+			if len(round) < concurrency && !busy[p.src] && !busy[p.dst] {
+				round = append(round, p)
+				busy[p.src], busy[p.dst] = true, true
+			} else {
+				remaining = append(remaining, p)
+			}
+		}
+
+		rounds = append(rounds, round)
+		pending = remaining
+	}
+
+	return rounds
+}
+
+// parseIperfBandwidth returns the received bandwidth (in Mbit/s) of the 'iperf3 -J' output
+func parseIperfBandwidth(out string) (float64, error) {
+	var report struct {
+		End struct {
+			SumReceived struct {
+				BitsPerSecond float64 `json:"bits_per_second"`
+			} `json:"sum_received"`
+		} `json:"end"`
+		Error string `json:"error"`
+	}
+
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		return 0, fmt.Errorf("Failed to decode the iperf3 report: '%s'", err)
+	}
+
+	if report.Error != "" {
+		return 0, fmt.Errorf("iperf3 failed: '%s'", report.Error)
+	}
+
+	return report.End.SumReceived.BitsPerSecond / 1e6, nil
+}
+
+// iperfServerCommand returns the command starting the temporary iperf3 server container on the network (removed when stopped)
+func (o *NetMatrixOpts) iperfServerCommand(name string, network string) string {
+	return fmt.Sprintf("docker rm -f %[1]s >/dev/null 2>&1; docker run -d --rm --name %[1]s %[2]s --net=%[3]s %[4]s -s", name, container.LabelFlag, network, o.iperfImage())
+}
+
+// iperfClientCommand returns the command measuring the bandwidth to the server address from a temporary container on the network
+func (o *NetMatrixOpts) iperfClientCommand(network string, address string) string {
+	return fmt.Sprintf("docker run --rm %s --net=%s %s -c %s -t %d -J", container.LabelFlag, network, o.iperfImage(), address, int(o.duration().Seconds()))
+}
+
+// measurePair returns the latency and bandwidth measured from the source node to the destination address with the commands
+func measurePair(src *Node, pingCmd string, iperfCmd string) *NetMeasurement {
+	m := &NetMeasurement{}
+
+	out, err := src.runSSHCommand(pingCmd)
+	if err != nil {
+		m.Error = fmt.Sprintf("Ping failed: '%s'", err)
+		return m
+	}
+
+	rtt, err := parsePingAverage(out)
+	if err != nil {
+		m.Error = err.Error()
+		return m
+	}
+	m.LatencyMs = float64(rtt) / float64(time.Millisecond)
+
+	out, err = src.runSSHCommand(iperfCmd)
+	if err != nil {
+		m.Error = fmt.Sprintf("Bandwidth measurement failed: '%s'", err)
+		return m
+	}
+
+	if m.BandwidthMbps, err = parseIperfBandwidth(out); err != nil {
+		m.Error = err.Error()
+	}
+
+	return m
+}
+
+// startIperfServers start the iperf3 servers on the nodes and returns the address of the servers on the network (machine name => address)
+func (c *Cluster) startIperfServers(opts *NetMatrixOpts, overlay bool) (map[string]string, error) {
+	var mu sync.Mutex
+	addresses := make(map[string]string)
+
+	name, network := iperfUnderlayServer, "host"
+	if overlay {
+		name, network = iperfOverlayServer, weaveNetwork
+	}
+
+	errs := c.runOnSelectedNodes(opts.Selector, iperfServersTimeout, func(n *Node, _ *host.Host) error {
+		if _, err := n.runSSHCommand(opts.iperfServerCommand(name, network)); err != nil {
+			return fmt.Errorf("Failed to start the iperf3 server: '%s'", err)
+		}
+
+		// the underlay measurements use the node hostname
+		address := n.NodeName
+		if overlay {
+			out, err := n.runSSHCommand(fmt.Sprintf("docker inspect --format '{{range .NetworkSettings.Networks}}{{.IPAddress}}{{end}}' %s", name))
+			if err != nil || strings.TrimSpace(out) == "" {
+				return fmt.Errorf("Failed to get the address of the iperf3 server on the Weave network: '%v'", err)
+			}
+			address = strings.TrimSpace(out)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		addresses[n.MachineName] = address
+		return nil
+	})
+
+	if err := fleetError("iperf3 servers start", errs); err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+// stopIperfServers stop the iperf3 servers of the nodes (the containers are removed once stopped)
+func (c *Cluster) stopIperfServers(sel *NodeSelector) {
+	c.runOnSelectedNodes(sel, iperfServersTimeout, func(n *Node, _ *host.Host) error {
+		_, err := n.runSSHCommand(fmt.Sprintf("docker rm -f %s %s >/dev/null 2>&1 || true", iperfUnderlayServer, iperfOverlayServer))
+		return err
+	})
+}
+
+// measureNetwork measure the latency and bandwidth between each pair of nodes on the network, running the rounds of pairs one after the other
+func (c *Cluster) measureNetwork(nodes []string, opts *NetMatrixOpts, overlay bool) (map[string]map[string]*NetMeasurement, map[string]error) {
+	results := make(map[string]map[string]*NetMeasurement)
+	for _, m := range nodes {
+		results[m] = make(map[string]*NetMeasurement)
+	}
+
+	addresses, err := c.startIperfServers(opts, overlay)
+	if err != nil {
+		return results, map[string]error{"servers": err}
+	}
+
+	var mu sync.Mutex
+	errs := make(map[string]error)
+
+	for _, round := range scheduleNodePairs(nodes, opts.concurrency()) {
+		var wg sync.WaitGroup
+		for _, p := range round {
+			wg.Add(1)
+			go func(p nodePair) {
+				defer wg.Done()
+
+				// the overlay measurements run from a container attached to the Weave network
+				address := addresses[p.dst]
+				pingCmd := fmt.Sprintf("ping -q -c %d %s", netMatrixPingCount, address)
+				network := "host"
+				if overlay {
+					pingCmd = fmt.Sprintf("docker run --rm %s --net=%s %s ping -q -c %d %s", container.LabelFlag, weaveNetwork, netMatrixPingImage, netMatrixPingCount, address)
+					network = weaveNetwork
+				}
+
+				m := measurePair(c.Nodes[p.src], pingCmd, opts.iperfClientCommand(network, address))
+
+				mu.Lock()
+				defer mu.Unlock()
+				results[p.src][p.dst] = m
+				if m.Error != "" {
+					errs[p.String()] = fmt.Errorf("%s", m.Error)
+				}
+			}(p)
+		}
+		wg.Wait()
+	}
+
+	return results, errs
+}
+
+// NetworkMatrix measure the latency (ping) and bandwidth (iperf3 in temporary containers) between each pair of the selected nodes, on the underlay network
+// and on the Weave network if enabled. The failed measurements are reported in the matrix and in the returned error.
+func (c *Cluster) NetworkMatrix(opts NetMatrixOpts) (*NetMatrix, error) {
+	if err := c.checkSelection(opts.Selector); err != nil {
+		return nil, err
+	}
+
+	nodes := c.selectNodes(opts.Selector)
+	if len(nodes) < 2 {
+		return nil, fmt.Errorf("At least two nodes are needed to measure the network")
+	}
+	defer c.stopIperfServers(opts.Selector)
+
+	matrix := &NetMatrix{Nodes: nodes}
+
+	log.Infof("Measuring the network between %d nodes (%d pairs)...", len(nodes), len(nodes)*(len(nodes)-1))
+	underlay, errs := c.measureNetwork(nodes, &opts, false)
+	matrix.Underlay = underlay
+
+	if c.Config.WeaveNetworkingEnabled {
+		log.Info("Measuring the Weave network between the nodes...")
+		overlay, overlayErrs := c.measureNetwork(nodes, &opts, true)
+		matrix.Overlay = overlay
+
+		for k, err := range overlayErrs {
+			errs["weave "+k] = err
+		}
+	}
+
+	return matrix, fleetError("Network measurement", errs)
+}
@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleNodePairs(t *testing.T) {
+	nodes := []string{"lille-0", "lille-1", "lille-2", "lille-3"}
+
+	// sequential measurements
+	rounds := scheduleNodePairs(nodes, 1)
+	assert.Len(t, rounds, 12)
+	assert.Equal(t, []nodePair{{"lille-0", "lille-1"}}, rounds[0])
+
+	// every pair is measured once, a node is never in two pairs of a round
+	rounds = scheduleNodePairs(nodes, 4)
+	seen := make(map[nodePair]bool)
+	for _, round := range rounds {
+		assert.True(t, len(round) <= 2)
+
+		busy := make(map[string]bool)
+		for _, p := range round {
+			assert.False(t, busy[p.src] || busy[p.dst], p.String())
+			busy[p.src], busy[p.dst] = true, true
+			seen[p] = true
+		}
+	}
+	assert.Len(t, seen, 12)
+}
+
+func TestParseIperfBandwidth(t *testing.T) {
+	bw, err := parseIperfBandwidth(`{"start": {}, "end": {"sum_sent": {"bits_per_second": 9.5e9}, "sum_received": {"bits_per_second": 9.4e9}}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, 9400.0, bw)
+
+	_, err = parseIperfBandwidth(`{"start": {}, "end": {}, "error": "unable to connect to server: Connection refused"}`)
+	assert.Error(t, err)
+
+	_, err = parseIperfBandwidth("iperf3: error")
+	assert.Error(t, err)
+}
+
+func TestParsePingAverageBusyBox(t *testing.T) {
+	rtt, err := parsePingAverage("--- 10.32.0.2 ping statistics ---\n5 packets transmitted, 5 packets received, 0% packet loss\nround-trip min/avg/max = 0.301/0.412/0.590 ms\n")
+	assert.NoError(t, err)
+	assert.Equal(t, 412*time.Microsecond, rtt)
+}
+
+func TestNetMatrixCommands(t *testing.T) {
+	opts := &NetMatrixOpts{}
+	assert.Equal(t, 1, opts.concurrency())
+	assert.Equal(t, "docker rm -f docker-g5k-iperf >/dev/null 2>&1; docker run -d --rm --name docker-g5k-iperf --label managed-by=docker-g5k --net=host networkstatic/iperf3 -s", opts.iperfServerCommand(iperfUnderlayServer, "host"))
+	assert.Equal(t, "docker run --rm --label managed-by=docker-g5k --net=weave networkstatic/iperf3 -c 10.32.0.2 -t 5 -J", opts.iperfClientCommand(weaveNetwork, "10.32.0.2"))
+
+	opts = &NetMatrixOpts{Concurrency: 3, Duration: 10 * time.Second, IperfImage: "iperf3:local"}
+	assert.Equal(t, 3, opts.concurrency())
+	assert.Equal(t, "docker run --rm --label managed-by=docker-g5k --net=host iperf3:local -c graphene-2.nancy.grid5000.fr -t 10 -J", opts.iperfClientCommand("host", "graphene-2.nancy.grid5000.fr"))
+}
+
+func TestNetworkMatrixSingleNode(t *testing.T) {
+	c := &Cluster{Config: &GlobalConfig{}, Nodes: map[string]*Node{"lille-0": {MachineName: "lille-0"}}}
+	_, err := c.NetworkMatrix(NetMatrixOpts{})
+	assert.Error(t, err)
+}
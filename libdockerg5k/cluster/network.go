@@ -0,0 +1,60 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// defaultNetworkLabel is the Engine label advertising the default container network of the cluster
+	defaultNetworkLabel = "g5k.default-network"
+)
+
+var (
+	// regexNetworkName match a valid Docker network name
+	regexNetworkName = regexp.MustCompile("^[[:alnum:]][[:alnum:]_.-]*$")
+)
+
+// validateContainerNetwork check the default container network name
+func validateContainerNetwork(name string) error {
+	if !regexNetworkName.MatchString(name) {
+		return fmt.Errorf("Invalid container network name: '%s' (only letters, digits, '_', '.' and '-' are allowed)", name)
+	}
+
+	return nil
+}
+
+// isPredefinedNetwork returns true if the network is created by the Docker Engine (bridge, host or none), false otherwise
+func isPredefinedNetwork(name string) bool {
+	return name == "bridge" || name == "host" || name == "none"
+}
+
+// generateNetworkCreateCommand returns the command used to create the user-defined bridge network (if it does not exist)
+func generateNetworkCreateCommand(name string) string {
+	return fmt.Sprintf("docker network inspect %[1]s >/dev/null 2>&1 || docker network create --driver bridge %[1]s", name)
+}
+
+// defaultNetworkLabels returns the Engine label advertising the default container network (if configured)
+func (n *Node) defaultNetworkLabels() []string {
+	if n.clusterConfig.DefaultContainerNetwork == "" {
+		return []string{}
+	}
+
+	return []string{fmt.Sprintf("%s=%s", defaultNetworkLabel, n.clusterConfig.DefaultContainerNetwork)}
+}
+
+// createDefaultNetwork create the default container network on the node (if configured and not predefined by the Engine)
+func (n *Node) createDefaultNetwork(h *host.Host) error {
+	name := n.clusterConfig.DefaultContainerNetwork
+	if name == "" || isPredefinedNetwork(name) {
+		return nil
+	}
+
+	if _, err := h.RunSSHCommand(generateNetworkCreateCommand(name)); err != nil {
+		return fmt.Errorf("Failed to create the container network '%s': '%s'", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateContainerNetwork(t *testing.T) {
+	assert.NoError(t, validateContainerNetwork("host"))
+	assert.NoError(t, validateContainerNetwork("experiment_net-1.2"))
+	assert.Error(t, validateContainerNetwork(""))
+	assert.Error(t, validateContainerNetwork("-net"))
+	assert.Error(t, validateContainerNetwork("my net"))
+}
+
+func TestGenerateNetworkCreateCommand(t *testing.T) {
+	assert.Equal(t, "docker network inspect expnet >/dev/null 2>&1 || docker network create --driver bridge expnet", generateNetworkCreateCommand("expnet"))
+}
+
+func TestEngineLabelsDefaultNetwork(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{DefaultContainerNetwork: "expnet", DisableG5kLabels: true}, EngineLabel: []string{"mykey=myval"}}
+	assert.Equal(t, []string{"mykey=myval", "g5k.default-network=expnet"}, n.engineLabels())
+}
@@ -4,13 +4,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/bastion"
 	"github.com/Spirals-Team/docker-g5k/libdockerg5k/hostsmapping"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/ingress"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/registry"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/security"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/volume"
 	"github.com/Spirals-Team/docker-g5k/libdockerg5k/weave"
 	"github.com/Spirals-Team/docker-g5k/libdockerg5k/zookeeper"
 	g5kdriver "github.com/Spirals-Team/docker-machine-driver-g5k/driver"
-	"github.com/docker/machine/commands/mcndirs"
 	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/host"
 )
 
 // Node contain node specific informations
@@ -20,32 +28,111 @@ type Node struct {
 	NodeName    string // Grid'5000 node hostname
 	MachineName string // Docker Machine name
 
+	// role of the node in the cluster (Manager or Worker)
+	Role string
+
 	// g5k driver
 	G5kSite  string
 	G5kJobID int
 
+	// the Grid'5000 job of the node was reserved by docker-g5k and is canceled when the node is released, false for an imported job (reserved outside, kept running)
+	OwnsJob bool
+
+	// deployment mode of the node (the cluster mode if empty), the nodes of a site share the same mode
+	DeployMode string
+
+	// platform of the images pulled on the node (ex: linux/arm64), the cluster default platform if empty
+	Platform string
+
+	// failure domain of the node (only set with a placement policy)
+	FailureDomain string
+
 	// Docker Engine
 	EngineOpt   []string
 	EngineLabel []string
+	EngineDebug bool // run the Engine of the node in debug mode
+
+	// local volumes
+	LocalVolumeMounts []volume.VolumeMount
+
+	// NFS shared directories
+	SharedMounts []volume.SharedMount
+
+	// additional names of the node in the static lookup table of the cluster hosts (ex: db0)
+	Aliases []string
+
+	// kernel parameters of the node (ex: net.core.somaxconn=4096), merged with the common parameters of the cluster
+	Sysctls map[string]string
+
+	// iptables rules filtering the incoming traffic of the node (ex: drop:tcp/2377@lille-1)
+	FirewallRules []FirewallRule
+
+	// network interface used for cluster communications (empty for the default interface)
+	AdvertiseInterface string
+
+	// resources advertised to the Swarm mode scheduler
+	AdvertisedResources AdvertisedResources
+
+	// Grid'5000 job facts written on the node (set at provisioning)
+	injectedJobFacts map[string]string
+
+	// Swarm mode node labels derived from the hardware description (set at provisioning)
+	appliedHardwareLabels []string
+
+	// kernel parameters applied on the node (set at provisioning)
+	appliedSysctls map[string]string
+
+	// firewall rules applied on the node (set at provisioning) and nodes it is partitioned from (set by the network partitions)
+	appliedFirewallRules []string
+	partitionedFrom      []string
+
+	// offload features of the Swarm mode data path applied on the node (set at provisioning)
+	appliedDataPathOffloads map[string]bool
+
+	// site requested for the node when it was reserved on a fallback site (empty otherwise)
+	requestedSite string
+
+	// provisioning log file of the node (only open while provisioning)
+	provisionLog *nodeLog
+
+	// error of the last provisioning of the node (nil if it succeeded)
+	provisionErr error
+
+	// provisioning phase that failed during the last provisioning of the node (empty if none)
+	failedPhase string
+
+	// architecture of the images matching the node hardware (set at provisioning)
+	detectedArch string
+
+	// number of images kept in the reused persistent data root (set at provisioning)
+	cachedImages int
 }
 
 // createHostAuthOptions returns a configured AuthOptions for HostOptions struct
 func (n *Node) createHostAuthOptions() *auth.Options {
+	certDir := n.clusterConfig.machineCertDir()
+	machineDir := n.clusterConfig.machineDir(n.MachineName)
+
 	return &auth.Options{
-		CertDir:          mcndirs.GetMachineCertDir(),
-		CaCertPath:       filepath.Join(mcndirs.GetMachineCertDir(), "ca.pem"),
-		CaPrivateKeyPath: filepath.Join(mcndirs.GetMachineCertDir(), "ca-key.pem"),
-		ClientCertPath:   filepath.Join(mcndirs.GetMachineCertDir(), "cert.pem"),
-		ClientKeyPath:    filepath.Join(mcndirs.GetMachineCertDir(), "key.pem"),
-		ServerCertPath:   filepath.Join(mcndirs.GetMachineDir(), n.MachineName, "server.pem"),
-		ServerKeyPath:    filepath.Join(mcndirs.GetMachineDir(), n.MachineName, "server-key.pem"),
-		StorePath:        filepath.Join(mcndirs.GetMachineDir(), n.MachineName),
+		CertDir:          certDir,
+		CaCertPath:       filepath.Join(certDir, "ca.pem"),
+		CaPrivateKeyPath: filepath.Join(certDir, "ca-key.pem"),
+		ClientCertPath:   filepath.Join(certDir, "cert.pem"),
+		ClientKeyPath:    filepath.Join(certDir, "key.pem"),
+		ServerCertPath:   filepath.Join(machineDir, "server.pem"),
+		ServerKeyPath:    filepath.Join(machineDir, "server-key.pem"),
+		StorePath:        machineDir,
 		ServerCertSANs:   nil,
 	}
 }
 
 // isSwarmMaster returns true if this node is a Swarm master/manager, false otherwise
 func (n *Node) isSwarmMaster() bool {
+	if n.Role != "" {
+		return n.Role == NodeRoleManager
+	}
+
+	// fallback on the Swarm master/manager list if the role is not set
 	for _, v := range n.clusterConfig.SwarmMasterNode {
 		if v == n.MachineName {
 			return true
@@ -55,48 +142,278 @@ func (n *Node) isSwarmMaster() bool {
 	return false
 }
 
+// isIngressNode returns true if this node runs the ingress controller, false otherwise
+func (n *Node) isIngressNode() bool {
+	return n.clusterConfig.DeployIngress != nil && n.clusterConfig.DeployIngress.Node == n.MachineName
+}
+
+// isBastionNode returns true if this node is the bastion of the cluster, false otherwise
+func (n *Node) isBastionNode() bool {
+	return n.clusterConfig.BastionNode != "" && n.clusterConfig.BastionNode == n.MachineName
+}
+
+// isSwarmModeBootstrapNode returns true if this node initialize the Swarm mode cluster (first Swarm manager), false otherwise
+func (n *Node) isSwarmModeBootstrapNode() bool {
+	return len(n.clusterConfig.SwarmMasterNode) > 0 && n.clusterConfig.SwarmMasterNode[0] == n.MachineName
+}
+
+// clusterAdvertiseInterface returns the network interface advertised to the cluster (eth0 by default)
+func (n *Node) clusterAdvertiseInterface() string {
+	if n.AdvertiseInterface != "" {
+		return n.AdvertiseInterface
+	}
+
+	return "eth0"
+}
+
+// engineClusterStoreFlags returns the 'cluster-advertise' & 'cluster-store' Docker Engine options (empty if the Engines don't use a cluster storage)
+func (n *Node) engineClusterStoreFlags() []string {
+	store := n.clusterConfig.engineClusterStore()
+	if store == "" {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("cluster-advertise=%s:%d", n.clusterAdvertiseInterface(), n.clusterConfig.clusterAdvertisePort()), fmt.Sprintf("cluster-store=%s", store)}
+}
+
+// applySecurityProfiles load the AppArmor profile and set the default seccomp profile of the Docker Engine (if configured)
+func (n *Node) applySecurityProfiles(h *host.Host) error {
+	// AppArmor profile
+	if n.clusterConfig.AppArmorProfile != "" {
+		profile, err := security.ReadAppArmorProfile(n.clusterConfig.AppArmorProfile)
+		if err != nil {
+			return err
+		}
+
+		if err := security.LoadAppArmorProfile(h, profile); err != nil {
+			return err
+		}
+	}
+
+	// seccomp profile
+	if n.clusterConfig.SeccompProfilePath != "" {
+		profile, err := security.ReadSeccompProfile(n.clusterConfig.SeccompProfilePath)
+		if err != nil {
+			return err
+		}
+
+		if err := security.UploadSeccompProfile(h, profile); err != nil {
+			return err
+		}
+
+		// set the profile as default in the Engine configuration (the other settings are kept) and wait for the Engine to be back
+		if err := updateDaemonConfig(h, map[string]interface{}{"seccomp-profile": security.SeccompProfilePath}); err != nil {
+			return err
+		}
+		if err := n.restartEngine(h); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// weavePeers returns the IP address of the Weave connectors the node should peer with (other connectors for a connector, all connectors otherwise)
+func (n *Node) weavePeers() []string {
+	peers := []string{}
+	for _, connector := range n.clusterConfig.WeaveConnectors {
+		if connector != n.MachineName {
+			peers = append(peers, n.clusterConfig.HostsLookupTable[connector])
+		}
+	}
+
+	return peers
+}
+
+// weaveIdentity returns the stable Weave identity of the node (nil if not enabled)
+// The IPAM seed is the same on all nodes of the cluster: the Swarm masters peer names
+func (n *Node) weaveIdentity() *weave.Identity {
+	if !n.clusterConfig.WeaveStablePeers {
+		return nil
+	}
+
+	seed := []string{}
+	for _, m := range n.clusterConfig.SwarmMasterNode {
+		seed = append(seed, weave.PeerName(m))
+	}
+	sort.Strings(seed)
+
+	return &weave.Identity{
+		Name:     weave.PeerName(n.MachineName),
+		Nickname: n.MachineName,
+		IPAMSeed: seed,
+	}
+}
+
+// startClusterStorage run the Zookeeper cluster storage on the node's host
+// the ensemble can only elect a leader once all the Swarm masters started their server, it is waited for by the cluster (see waitForClusterStorage)
+func (n *Node) startClusterStorage(h *host.Host) error {
+	return zookeeper.StartClusterStorage(h, n.clusterConfig.SwarmMasterNode, n.clusterConfig.InfraRestartPolicy, n.clusterConfig.InfraContainerCaps, n.clusterConfig.InfraStopConfig(), n.platform())
+}
+
+// runWeave run Weave Net and Weave Discovery on the node's host
+func (n *Node) runWeave(h *host.Host) error {
+	return n.startWeave(h, n.clusterConfig.WeavePassword)
+}
+
+// startWeave run Weave Net (with the given encryption password) and Weave Discovery on the node's host
+func (n *Node) startWeave(h *host.Host, password string) error {
+	// run Weave Net
+	if err := weave.RunWeaveNet(h, n.clusterConfig.WeaveConfig, n.weavePeers(), n.weaveIdentity(), password); err != nil {
+		return err
+	}
+
+	// run Weave Discovery (only for full mesh, or it will connect all nodes together)
+	if len(n.clusterConfig.WeaveConnectors) == 0 {
+		if err := weave.RunWeaveDiscovery(h, n.clusterConfig.SwarmStandaloneGlobalConfig.Discovery, n.clusterConfig.InfraRestartPolicy, n.clusterConfig.InfraContainerCaps, n.clusterConfig.InfraStopConfig(), n.platform()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// g5kLabels returns the Engine labels describing the Grid'5000 job of the node
+func (n *Node) g5kLabels() []string {
+	return []string{
+		fmt.Sprintf("g5k.site=%s", n.G5kSite),
+		fmt.Sprintf("g5k.jobid=%d", n.G5kJobID),
+		fmt.Sprintf("g5k.node=%s", n.NodeName),
+	}
+}
+
+// mergeEngineLabels returns the user labels followed by the additional labels not already defined (by key) by the user, without duplicates
+func mergeEngineLabels(userLabels []string, additionalLabels []string) []string {
+	labels := []string{}
+	keys := make(map[string]bool)
+
+	for _, lst := range [][]string{userLabels, additionalLabels} {
+		for _, l := range lst {
+			// label format: key=value
+			key := strings.SplitN(l, "=", 2)[0]
+			if keys[key] {
+				continue
+			}
+
+			keys[key] = true
+			labels = append(labels, l)
+		}
+	}
+
+	return labels
+}
+
+// engineLabels returns the Engine labels of the node
+func (n *Node) engineLabels() []string {
+	labels := append(n.clusterLabels(), n.aliasesLabels()...)
+	labels = append(labels, n.defaultNetworkLabels()...)
+	labels = append(labels, annotationLabels(n.clusterConfig.Annotations)...)
+
+	// add Grid'5000 job labels (unless disabled)
+	if !n.clusterConfig.DisableG5kLabels {
+		labels = append(n.g5kLabels(), labels...)
+	}
+
+	if len(labels) == 0 {
+		return n.EngineLabel
+	}
+
+	return mergeEngineLabels(n.EngineLabel, labels)
+}
+
 // Provision will install Docker Engine/Swarm and perform some configurations on the node
+// the provisioning steps are also logged to the node log file (LogDir/<machineName>.log, truncated) if enabled
 func (n *Node) Provision() error {
-	// disable driver logs
-	//log.SetErrWriter(ioutil.Discard)
-	//log.SetOutWriter(ioutil.Discard)
+	if n.clusterConfig.LogDir != "" {
+		l, err := openNodeLog(n.clusterConfig.LogDir, n.MachineName)
+		if err != nil {
+			return n.wrapError(ErrDriverConfig, err)
+		}
+
+		n.provisionLog = l
+		defer l.close()
+	}
+
+	start := time.Now()
+	n.logf("Provisioning node '%s' ('%s') on site '%s' (job %d)", n.NodeName, n.MachineName, n.G5kSite, n.G5kJobID)
+
+	n.failedPhase = ""
+	metrics := activeProvisionMetrics()
+	if metrics != nil {
+		metrics.observeAttempt(n.G5kSite)
+	}
+
+	n.provisionErr = n.provision()
+	if metrics != nil {
+		metrics.observeResult(n.G5kSite, n.failedPhase, n.provisionErr)
+	}
+
+	if n.provisionErr != nil {
+		n.logf("Provisioning failed after %s: %s", time.Since(start), n.provisionErr)
+		return n.provisionErr
+	}
+
+	n.logf("Provisioning done in %s", time.Since(start))
+	return nil
+}
+
+// provision create the machine of the node and configure it
+func (n *Node) provision() error {
+	// get Grid'5000 credentials (the driver needs them for the machine operations)
+	user, pass, err := n.clusterConfig.credentials()
+	if err != nil {
+		return n.wrapError(ErrDriverConfig, err)
+	}
 
 	// create driver instance for libmachine
 	driver := g5kdriver.NewDriver()
 
 	// set g5k driver parameters
-	driver.G5kUsername = n.clusterConfig.G5kUsername
-	driver.G5kPassword = n.clusterConfig.G5kPassword
+	driver.G5kUsername = user
+	driver.G5kPassword = pass
 	driver.G5kSite = n.G5kSite
 	driver.G5kImage = n.clusterConfig.G5kImage
 	driver.G5kWalltime = n.clusterConfig.G5kWalltime
 	driver.G5kJobID = n.G5kJobID
+	driver.G5kJobQueue = n.clusterConfig.OARQueue
 	driver.G5kHostToProvision = n.NodeName
 	driver.SSHKeyPair = n.clusterConfig.SSHKeyPair
 	driver.G5kSkipVpnChecks = true
 
+	// the node keeps its default environment in classic mode (no image to deploy)
+	if n.isClassicMode() {
+		driver.G5kImage = ""
+		driver.G5kReuseRefEnvironment = true
+	}
+
 	// set base driver parameters
 	driver.BaseDriver.MachineName = n.MachineName
-	driver.BaseDriver.StorePath = mcndirs.GetBaseDir()
+	driver.BaseDriver.StorePath = n.clusterConfig.machineStorageDir()
 	driver.BaseDriver.SSHKeyPath = driver.GetSSHKeyPath()
 
 	// marshal configured driver
 	data, err := json.Marshal(driver)
 	if err != nil {
-		return err
+		return n.wrapError(ErrDriverConfig, err)
 	}
 
 	// create a new host config
 	h, err := n.clusterConfig.LibMachineClient.NewHost("g5k", data)
 	if err != nil {
-		return err
+		return n.wrapError(ErrDriverConfig, err)
 	}
 
 	// set Docker Engine parameters
-	h.HostOptions.EngineOptions.ArbitraryFlags = n.EngineOpt
-	h.HostOptions.EngineOptions.Labels = n.EngineLabel
+	h.HostOptions.EngineOptions.ArbitraryFlags = n.engineFlags()
+	h.HostOptions.EngineOptions.Labels = n.engineLabels()
 	h.HostOptions.EngineOptions.InstallURL = n.clusterConfig.EngineInstallURL
 
+	// use the cluster registry as mirror (insecure, the registry does not use TLS)
+	if n.clusterConfig.DeployRegistry {
+		h.HostOptions.EngineOptions.RegistryMirror = append(h.HostOptions.EngineOptions.RegistryMirror, registry.MirrorURL())
+		h.HostOptions.EngineOptions.InsecureRegistry = append(h.HostOptions.EngineOptions.InsecureRegistry, registry.Address())
+	}
+
 	// mandatory, or driver will use bad paths for certificates
 	h.HostOptions.AuthOptions = n.createHostAuthOptions()
 
@@ -106,56 +423,254 @@ func (n *Node) Provision() error {
 	}
 
 	// Engine cluster storage
-	if n.clusterConfig.UseZookeeperClusterStorage {
-		// set 'cluster-advertise' & 'cluster-store' Docker Engine options
-		h.HostOptions.EngineOptions.ArbitraryFlags = append(h.HostOptions.EngineOptions.ArbitraryFlags, "cluster-advertise=eth0:2379", fmt.Sprintf("cluster-store=%s", n.clusterConfig.SwarmStandaloneGlobalConfig.Discovery))
+	h.HostOptions.EngineOptions.ArbitraryFlags = append(h.HostOptions.EngineOptions.ArbitraryFlags, n.engineClusterStoreFlags()...)
+
+	// keep the existing server certificate (if reusable), as the machine creation always generates a new one
+	reused := n.reusableServerCert()
+
+	// provision the new machine (the half-created machine is removed if the creation is stuck)
+	if err := n.createMachine(h); err != nil {
+		return n.wrapError(ErrMachineCreate, err)
 	}
 
-	// provision the new machine
-	if err := n.clusterConfig.LibMachineClient.Create(h); err != nil {
+	// restore the reused server certificate
+	if err := n.restoreServerCert(h, reused); err != nil {
+		return n.wrapError(ErrMachineCreate, err)
+	}
+
+	// configure the machine (the containers started by docker-g5k are removed if it fails)
+	if err := n.configureHost(h); err != nil {
+		n.cleanupContainers(h)
 		return err
 	}
 
+	return nil
+}
+
+// configureHost configure the Docker Engine and run the cluster services on the created machine of the node
+func (n *Node) configureHost(h *host.Host) error {
+	// run the user hook before any other configuration
+	if n.clusterConfig.PreEngineHook != nil {
+		if err := n.clusterConfig.PreEngineHook(h); err != nil {
+			return n.wrapError(ErrHook, err)
+		}
+	}
+
+	// detect the node architecture (only a warning if it does not match the images platform)
+	n.detectArchitecture(h)
+
+	// disable the swap
+	if n.clusterConfig.DisableSwap {
+		if err := n.disableSwap(h); err != nil {
+			return n.wrapError(ErrSwap, err)
+		}
+	}
+
+	// set the kernel parameters
+	if err := n.applySysctls(h); err != nil {
+		return n.wrapError(ErrSysctl, err)
+	}
+
+	// apply the firewall rules
+	if err := n.applyFirewallRules(h); err != nil {
+		return n.wrapError(ErrFirewall, err)
+	}
+
+	// set the offload features of the Swarm mode data path
+	if err := n.applyDataPathOffloads(h); err != nil {
+		return n.wrapError(ErrDataPath, err)
+	}
+
+	// prepare the Docker data root
+	if err := n.prepareDataRoot(h); err != nil {
+		return n.wrapError(ErrDataRoot, err)
+	}
+
+	// check the bridge subnet does not overlap the node subnets
+	if err := n.checkBridgeSubnet(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
+	// check the service discovery subnet does not overlap the node subnets
+	if err := n.checkServiceDiscoverySubnet(h); err != nil {
+		return n.wrapError(ErrServiceDiscovery, err)
+	}
+
+	// apply the Docker Engine service overrides
+	if err := n.applySystemdOverrides(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
+	// check the Engine runs in debug mode
+	if err := n.checkEngineDebug(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
+	// check the Engine runs with the default shm size
+	if err := n.checkEngineShmSize(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
+	// pin the Docker API version of the node clients
+	if err := n.configureAPIVersion(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
+	// write the private registries credentials
+	if err := n.configureRegistryAuths(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
+	// install the trusted CA certificates
+	if err := n.installTrustedCAs(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
+	// install the OCI runtimes
+	if err := n.installRuntimes(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
+	// check the advertised resources do not exceed the physical resources
+	if n.AdvertisedResources.IsSet() {
+		if err := n.AdvertisedResources.checkPhysicalResources(h); err != nil {
+			return n.wrapError(ErrAdvertisedResources, err)
+		}
+	}
+
+	// apply the Docker Engine security profiles
+	if err := n.applySecurityProfiles(h); err != nil {
+		return n.wrapError(ErrSecurityProfile, err)
+	}
+
+	// enable the user namespace remapping (after the security profiles, the Engine configuration file is merged)
+	if err := n.applyUsernsRemap(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
+	// set the Engine pull proxy (the Engine configuration file is merged)
+	if err := n.applyPullProxy(h); err != nil {
+		return n.wrapError(ErrEngineConfig, err)
+	}
+
 	// add all cluster nodes to the static lookup table of the host
-	if err := hostsmapping.AddClusterHostsMapping(h, n.clusterConfig.HostsLookupTable); err != nil {
-		return err
+	if err := n.runPhase(PhaseMapping, func() error {
+		return hostsmapping.AddClusterHostsMapping(h, n.clusterConfig.HostsLookupTable, n.clusterConfig.hostsAliases)
+	}); err != nil {
+		return n.wrapError(ErrHostsMapping, err)
+	}
+
+	// write the Grid'5000 job facts (after the hosts mapping, the cluster nodes are resolvable)
+	if err := n.writeJobFacts(h); err != nil {
+		return n.wrapError(ErrJobFacts, err)
+	}
+
+	// write the default capabilities of the workload containers
+	if err := n.writeContainerCaps(h); err != nil {
+		return n.wrapError(ErrContainerCaps, err)
+	}
+
+	// run the registry on the registry node
+	if n.clusterConfig.DeployRegistry && n.MachineName == n.clusterConfig.RegistryNode {
+		if err := registry.StartRegistry(h, n.clusterConfig.RegistryProxyRemoteURL, n.clusterConfig.InfraRestartPolicy, n.clusterConfig.InfraContainerCaps, n.clusterConfig.InfraStopConfig(), n.platform()); err != nil {
+			return n.wrapError(ErrRegistry, err)
+		}
+	}
+
+	// create Docker volumes backed by the node local disk
+	for _, v := range n.LocalVolumeMounts {
+		if err := volume.CreateLocalVolume(h, v); err != nil {
+			return n.wrapError(ErrLocalVolume, err)
+		}
+	}
+
+	// mount the NFS shared directories
+	for _, m := range n.SharedMounts {
+		if err := volume.MountSharedDirectory(h, m); err != nil {
+			return n.wrapError(ErrSharedMount, err)
+		}
+	}
+
+	// create the default container network
+	if err := n.createDefaultNetwork(h); err != nil {
+		return n.wrapError(ErrContainerNetwork, err)
 	}
 
 	// Swarm standalone (post-creation)
 	if n.clusterConfig.SwarmStandaloneGlobalConfig != nil {
 		// run Zookeeper cluster storage on Swarm master nodes only
 		if n.isSwarmMaster() && n.clusterConfig.UseZookeeperClusterStorage {
-			zookeeper.StartClusterStorage(h, n.clusterConfig.SwarmMasterNode)
+			if err := n.runPhase(PhaseZookeeper, func() error { return n.startClusterStorage(h) }); err != nil {
+				return n.wrapError(ErrZookeeper, err)
+			}
 		}
 
 		// run Weave Net / Discovery if enabled
 		if n.clusterConfig.WeaveNetworkingEnabled {
-			// run Weave Net
-			if err := weave.RunWeaveNet(h); err != nil {
-				return err
-			}
-
-			// run Weave Discovery
-			if err := weave.RunWeaveDiscovery(h, n.clusterConfig.SwarmStandaloneGlobalConfig.Discovery); err != nil {
-				return err
+			if err := n.runPhase(PhaseWeave, func() error { return n.runWeave(h) }); err != nil {
+				return n.wrapError(ErrWeave, err)
 			}
 		}
 	}
 
 	// Swarm mode
 	if n.clusterConfig.SwarmModeGlobalConfig != nil {
-		// check if cluster is already initialized
-		if !n.clusterConfig.SwarmModeGlobalConfig.IsSwarmModeClusterInitialized() {
+		// the bootstrap node initialize the cluster, the others wait for it before joining
+		if n.isSwarmModeBootstrapNode() {
 			// initialize Swarm mode cluster (only for bootstrap node)
-			if err := n.clusterConfig.SwarmModeGlobalConfig.InitSwarmModeCluster(h); err != nil {
-				return err
+			if err := n.runPhase(PhaseSwarm, func() error {
+				return n.clusterConfig.SwarmModeGlobalConfig.InitSwarmModeCluster(h, n.AdvertiseInterface)
+			}); err != nil {
+				return n.wrapError(ErrSwarmInit, err)
 			}
 		} else {
 			// join the Swarm mode cluster
-			if err := n.clusterConfig.SwarmModeGlobalConfig.JoinSwarmModeCluster(h, n.isSwarmMaster()); err != nil {
-				return err
+			if err := n.runPhase(PhaseSwarm, func() error {
+				return n.clusterConfig.SwarmModeGlobalConfig.JoinSwarmModeCluster(h, n.isSwarmMaster(), n.AdvertiseInterface)
+			}); err != nil {
+				return n.wrapError(ErrSwarmJoin, err)
+			}
+		}
+
+		// create the service discovery network (bootstrap manager only) and connect the standalone containers of the node to it
+		if n.isSwarmModeBootstrapNode() {
+			if err := n.clusterConfig.SwarmModeGlobalConfig.CreateServiceDiscoveryNetwork(h); err != nil {
+				return n.wrapError(ErrServiceDiscovery, err)
 			}
 		}
+		if err := n.clusterConfig.SwarmModeGlobalConfig.StartRegistrator(h, n.clusterConfig.InfraRestartPolicy, n.clusterConfig.InfraStopConfig(), n.platform()); err != nil {
+			return n.wrapError(ErrServiceDiscovery, err)
+		}
+
+		// label the node with its hardware class
+		if n.clusterConfig.HardwareLabels {
+			if err := n.applyHardwareLabels(h); err != nil {
+				return n.wrapError(ErrHardwareLabels, err)
+			}
+		}
+	}
+
+	// run the ingress controller on the ingress node (once in the Swarm mode cluster)
+	if n.isIngressNode() {
+		if err := ingress.StartIngress(h, n.clusterConfig.DeployIngress, n.clusterConfig.SwarmModeGlobalConfig != nil, n.clusterConfig.InfraRestartPolicy, n.clusterConfig.InfraContainerCaps, n.clusterConfig.InfraStopConfig(), n.platform()); err != nil {
+			return n.wrapError(ErrIngress, err)
+		}
+	}
+
+	// run the SOCKS proxy on the bastion node (once in the Swarm mode cluster) and check it is reachable from docker-g5k
+	if n.isBastionNode() {
+		if err := bastion.StartBastion(h, n.clusterConfig.BastionPort, n.clusterConfig.SwarmModeGlobalConfig != nil, n.clusterConfig.WeaveNetworkingEnabled, n.clusterConfig.InfraRestartPolicy, n.clusterConfig.InfraContainerCaps, n.clusterConfig.InfraStopConfig(), n.platform()); err != nil {
+			return n.wrapError(ErrBastion, err)
+		}
+
+		if err := bastion.WaitReachable(bastion.Endpoint(n.NodeName, n.clusterConfig.BastionPort), bastion.ReachableTimeout); err != nil {
+			return n.wrapError(ErrBastion, err)
+		}
+	}
+
+	// apply the node plugins (once in the Swarm cluster)
+	if err := n.applyNodePlugins(h); err != nil {
+		return n.wrapError(ErrPlugin, err)
 	}
 
 	return nil
@@ -0,0 +1,96 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/weave"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeEngineLabelsNoUserLabels(t *testing.T) {
+	labels := mergeEngineLabels(nil, []string{"g5k.site=lille", "g5k.jobid=42"})
+	assert.Equal(t, []string{"g5k.site=lille", "g5k.jobid=42"}, labels)
+}
+
+func TestMergeEngineLabelsUserLabelOverride(t *testing.T) {
+	labels := mergeEngineLabels([]string{"g5k.site=custom", "mykey=myval"}, []string{"g5k.site=lille", "g5k.jobid=42"})
+	assert.Equal(t, []string{"g5k.site=custom", "mykey=myval", "g5k.jobid=42"}, labels)
+}
+
+func TestMergeEngineLabelsDuplicates(t *testing.T) {
+	labels := mergeEngineLabels([]string{"mykey=myval", "mykey=myval"}, []string{"g5k.jobid=42", "g5k.jobid=42"})
+	assert.Equal(t, []string{"mykey=myval", "g5k.jobid=42"}, labels)
+}
+
+func TestEngineLabelsG5kLabels(t *testing.T) {
+	n := &Node{
+		clusterConfig: &GlobalConfig{},
+		NodeName:      "chifflet-1.lille.grid5000.fr",
+		MachineName:   "lille-0",
+		G5kSite:       "lille",
+		G5kJobID:      42,
+		EngineLabel:   []string{"mykey=myval"},
+	}
+	assert.Equal(t, []string{"mykey=myval", "g5k.site=lille", "g5k.jobid=42", "g5k.node=chifflet-1.lille.grid5000.fr"}, n.engineLabels())
+}
+
+func TestEngineLabelsDisableG5kLabels(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{DisableG5kLabels: true}, EngineLabel: []string{"mykey=myval"}}
+	assert.Equal(t, []string{"mykey=myval"}, n.engineLabels())
+}
+
+func TestWeavePeersSpoke(t *testing.T) {
+	config := &GlobalConfig{
+		WeaveConnectors:  []string{"lille-0", "lille-1"},
+		HostsLookupTable: map[string]string{"lille-0": "10.0.0.0", "lille-1": "10.0.0.1", "lille-2": "10.0.0.2"},
+	}
+	n := &Node{clusterConfig: config, MachineName: "lille-2"}
+	assert.Equal(t, []string{"10.0.0.0", "10.0.0.1"}, n.weavePeers())
+}
+
+func TestWeavePeersConnector(t *testing.T) {
+	config := &GlobalConfig{
+		WeaveConnectors:  []string{"lille-0", "lille-1"},
+		HostsLookupTable: map[string]string{"lille-0": "10.0.0.0", "lille-1": "10.0.0.1", "lille-2": "10.0.0.2"},
+	}
+	n := &Node{clusterConfig: config, MachineName: "lille-0"}
+	assert.Equal(t, []string{"10.0.0.1"}, n.weavePeers())
+}
+
+func TestWeaveIdentityDisabled(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{}, MachineName: "lille-0"}
+	assert.Nil(t, n.weaveIdentity())
+}
+
+func TestWeaveIdentitySameSeed(t *testing.T) {
+	config := &GlobalConfig{WeaveStablePeers: true, SwarmMasterNode: []string{"lille-1", "lille-0"}}
+	n0 := &Node{clusterConfig: config, MachineName: "lille-0"}
+	n2 := &Node{clusterConfig: config, MachineName: "lille-2"}
+
+	id := n2.weaveIdentity()
+	assert.Equal(t, "lille-2", id.Nickname)
+	assert.Equal(t, weave.PeerName("lille-2"), id.Name)
+	assert.Len(t, id.IPAMSeed, 2)
+	assert.Contains(t, id.IPAMSeed, n0.weaveIdentity().Name)
+	assert.Equal(t, n0.weaveIdentity().IPAMSeed, id.IPAMSeed)
+}
+
+func TestStartClusterStorageFailure(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{SwarmMasterNode: []string{"lille-0", "sophia-1"}}, MachineName: "lyon-2"}
+
+	// the start failure of the Zookeeper container fails the phase
+	err := n.runPhase(PhaseZookeeper, func() error { return n.startClusterStorage(&host.Host{Name: "lyon-2"}) })
+	assert.Error(t, err)
+	assert.Equal(t, PhaseZookeeper, n.failedPhase)
+	assert.True(t, errors.Is(n.wrapError(ErrZookeeper, err), ErrZookeeper))
+}
+
+func TestStartClusterStorageNoWait(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{SwarmMasterNode: []string{"lille-0", "sophia-1"}, PhaseTimeouts: map[string]time.Duration{PhaseZookeeper: 100 * time.Millisecond}}, MachineName: "lille-0"}
+
+	// the master does not wait for the ensemble leader (the other masters did not start their server yet)
+	assert.NoError(t, n.runPhase(PhaseZookeeper, func() error { return n.startClusterStorage(&host.Host{Name: "lille-0"}) }))
+}
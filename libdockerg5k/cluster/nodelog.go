@@ -0,0 +1,63 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// nodeLog write the provisioning messages of a node to its own log file (the phases keep running in background after a timeout, the writes are synchronized)
+type nodeLog struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// openNodeLog create (or truncate) the log file of the machine in the directory
+func openNodeLog(dir string, machineName string) (*nodeLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Unable to create logs directory '%s': '%s'", dir, err)
+	}
+
+	f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%s.log", machineName)))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create the log file of machine '%s': '%s'", machineName, err)
+	}
+
+	return &nodeLog{f: f}, nil
+}
+
+// printf write a timestamped line to the log file (nothing is done if the log is not open)
+func (l *nodeLog) printf(format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f != nil {
+		fmt.Fprintf(l.f, "%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+	}
+}
+
+// close close the log file, the later writes are ignored
+func (l *nodeLog) close() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f != nil {
+		l.f.Close()
+		l.f = nil
+	}
+}
+
+// logf write the message to the provisioning log file of the node (if enabled)
+func (n *Node) logf(format string, args ...interface{}) {
+	n.provisionLog.printf(format, args...)
+}
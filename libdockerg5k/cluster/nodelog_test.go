@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeLogTruncate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-g5k-logs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := openNodeLog(filepath.Join(dir, "provisioning"), "lille-0")
+	assert.NoError(t, err)
+	l.printf("first provisioning")
+	l.close()
+
+	l, err = openNodeLog(filepath.Join(dir, "provisioning"), "lille-0")
+	assert.NoError(t, err)
+	l.printf("Phase '%s' started", PhaseCreate)
+	l.close()
+
+	// writes after close are ignored
+	l.printf("ignored")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "provisioning", "lille-0.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+	assert.Contains(t, string(data), "Phase 'create' started")
+}
+
+func TestNodeLogDisabled(t *testing.T) {
+	n := &Node{MachineName: "lille-0"}
+	n.logf("not written")
+	n.provisionLog.close()
+}
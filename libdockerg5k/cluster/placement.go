@@ -0,0 +1,109 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// PlacementPolicy contain the placement rules of the nodes
+type PlacementPolicy struct {
+	ManagersAntiAffinity string // spread the Swarm managers of a site on distinct failure domains ('cluster' or 'switch', disabled if empty)
+}
+
+// IsSet returns true if a placement rule is configured, false otherwise
+func (p *PlacementPolicy) IsSet() bool {
+	return p.ManagersAntiAffinity != ""
+}
+
+// Validate check the placement policy
+func (p *PlacementPolicy) Validate() error {
+	if p.ManagersAntiAffinity != "" {
+		return g5k.ValidateFailureDomain(p.ManagersAntiAffinity)
+	}
+
+	return nil
+}
+
+// siteManagersIndex returns the index (machine name : {site}-{index}) of the Swarm managers of the site
+func (c *Cluster) siteManagersIndex(site string) []int {
+	managers := []int{}
+	for _, m := range c.Config.SwarmMasterNode {
+		var index int
+		if _, err := fmt.Sscanf(m, site+"-%d", &index); err == nil && fmt.Sprintf("%s-%d", site, index) == m {
+			managers = append(managers, index)
+		}
+	}
+	sort.Ints(managers)
+
+	return managers
+}
+
+// SiteManagersCount returns the number of Swarm managers on the site
+func (c *Cluster) SiteManagersCount(site string) int {
+	return len(c.siteManagersIndex(site))
+}
+
+// placeManagers returns the deployed nodes ordered so the nodes allocated to the managers index are on distinct failure domains (if possible), and the number of managers on distinct domains
+func placeManagers(deployedNodes []string, managersIndex []int, domains map[string]string) ([]string, int) {
+	ordered := make([]string, len(deployedNodes))
+	taken := make(map[string]bool)
+	usedDomains := make(map[string]bool)
+	spread := 0
+
+	// allocate a node of an unused failure domain to each manager
+	for _, i := range managersIndex {
+		if i >= len(deployedNodes) {
+			continue
+		}
+
+		for _, n := range deployedNodes {
+			if d := domains[n]; !taken[n] && d != "" && !usedDomains[d] {
+				ordered[i] = n
+				taken[n] = true
+				usedDomains[d] = true
+				spread++
+				break
+			}
+		}
+	}
+
+	// allocate the remaining nodes in their deployment order
+	next := 0
+	for i := range ordered {
+		if ordered[i] != "" {
+			continue
+		}
+
+		for taken[deployedNodes[next]] {
+			next++
+		}
+		ordered[i] = deployedNodes[next]
+		taken[deployedNodes[next]] = true
+	}
+
+	return ordered, spread
+}
+
+// PlaceManagers returns the deployed nodes of the site ordered so the Swarm managers are allocated on distinct failure domains (where possible)
+func (c *Cluster) PlaceManagers(site string, deployedNodes []string, domains map[string]string) []string {
+	managersIndex := c.siteManagersIndex(site)
+
+	ordered, spread := placeManagers(deployedNodes, managersIndex, domains)
+	if spread < len(managersIndex) {
+		log.Warnf("Only %d of the %d Swarm managers of site '%s' are on distinct failure domains ('%s')", spread, len(managersIndex), site, c.Config.PlacementPolicy.ManagersAntiAffinity)
+	}
+
+	return ordered
+}
+
+// SetFailureDomains store the failure domain of the nodes (hostname => domain)
+func (c *Cluster) SetFailureDomains(domains map[string]string) {
+	for _, n := range c.Nodes {
+		if d, ok := domains[n.NodeName]; ok {
+			n.FailureDomain = d
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaceManagersDistinctDomains(t *testing.T) {
+	deployed := []string{"a-1", "a-2", "b-1", "c-1"}
+	domains := map[string]string{"a-1": "a", "a-2": "a", "b-1": "b", "c-1": "c"}
+
+	ordered, spread := placeManagers(deployed, []int{0, 1, 2}, domains)
+	assert.Equal(t, []string{"a-1", "b-1", "c-1", "a-2"}, ordered)
+	assert.Equal(t, 3, spread)
+}
+
+func TestPlaceManagersNotEnoughDomains(t *testing.T) {
+	deployed := []string{"a-1", "a-2", "a-3", "b-1"}
+	domains := map[string]string{"a-1": "a", "a-2": "a", "a-3": "a", "b-1": "b"}
+
+	ordered, spread := placeManagers(deployed, []int{1, 2, 3}, domains)
+	assert.ElementsMatch(t, deployed, ordered)
+	assert.Equal(t, "a-1", ordered[1])
+	assert.Equal(t, "b-1", ordered[2])
+	assert.Equal(t, 2, spread)
+}
+
+func TestSiteManagersIndex(t *testing.T) {
+	c := &Cluster{Config: &GlobalConfig{SwarmMasterNode: []string{"lille-2", "nancy-0", "lille-0", "lille-1a"}}}
+	assert.Equal(t, []int{0, 2}, c.siteManagersIndex("lille"))
+	assert.Equal(t, 1, c.SiteManagersCount("nancy"))
+}
+
+func TestPlacementPolicyValidate(t *testing.T) {
+	assert.NoError(t, (&PlacementPolicy{}).Validate())
+	assert.NoError(t, (&PlacementPolicy{ManagersAntiAffinity: "cluster"}).Validate())
+	assert.Error(t, (&PlacementPolicy{ManagersAntiAffinity: "rack"}).Validate())
+}
@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hardwareLabelKeys returns the keys of the Swarm node labels derived from the hardware description (none if the hardware labels are disabled)
+func (c *GlobalConfig) hardwareLabelKeys() map[string]bool {
+	keys := make(map[string]bool)
+	if !c.HardwareLabels {
+		return keys
+	}
+
+	rules := c.HardwareLabelRules
+	if len(rules) == 0 {
+		rules = defaultHardwareLabelRules
+	}
+
+	for _, r := range rules {
+		keys[strings.SplitN(r.Label, "=", 2)[0]] = true
+	}
+
+	return keys
+}
+
+// validatePlacementPreferences check the labels referenced by the default placement preferences of the Swarm mode services exist:
+// the Swarm node labels need to be derived from the hardware description, the Engine labels to be set on at least one node
+func (c *Cluster) validatePlacementPreferences() error {
+	if c.Config.SwarmModeGlobalConfig == nil {
+		return nil
+	}
+
+	nodeLabels, engineLabels := c.Config.SwarmModeGlobalConfig.PlacementLabels()
+
+	hwKeys := c.Config.hardwareLabelKeys()
+	for _, key := range nodeLabels {
+		if !hwKeys[key] {
+			return fmt.Errorf("The placement preference node label '%s' is not derived from the hardware description (see the hardware label rules)", key)
+		}
+	}
+
+	engineKeys := make(map[string]bool)
+	for _, n := range c.Nodes {
+		for _, l := range n.engineLabels() {
+			engineKeys[strings.SplitN(l, "=", 2)[0]] = true
+		}
+	}
+	for _, key := range engineLabels {
+		if !engineKeys[key] {
+			return fmt.Errorf("The placement preference Engine label '%s' is not set on any node", key)
+		}
+	}
+
+	return nil
+}
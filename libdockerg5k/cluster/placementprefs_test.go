@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePlacementPreferences(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleWorker})
+	assert.NoError(t, c.validatePlacementPreferences())
+
+	// node labels derived from the hardware description (default rules)
+	c.Config.SwarmModeGlobalConfig.DefaultPlacementPreferences = []string{"spread=node.labels.nic"}
+	assert.Error(t, c.validatePlacementPreferences())
+	c.Config.HardwareLabels = true
+	assert.NoError(t, c.validatePlacementPreferences())
+
+	c.Config.HardwareLabelRules = []HardwareLabelRule{{Label: "gpu=true", Condition: "gpus>=1"}}
+	assert.Error(t, c.validatePlacementPreferences())
+
+	// Engine labels of the nodes
+	c.Config.SwarmModeGlobalConfig.DefaultPlacementPreferences = []string{"spread=engine.labels.g5k.site"}
+	c.Config.DisableG5kLabels = true
+	assert.Error(t, c.validatePlacementPreferences())
+	c.Config.DisableG5kLabels = false
+	assert.NoError(t, c.validatePlacementPreferences())
+
+	c.Config.SwarmModeGlobalConfig.DefaultPlacementPreferences = []string{"spread=engine.labels.rack"}
+	assert.Error(t, c.validatePlacementPreferences())
+	c.Nodes["lille-1"].EngineLabel = []string{"rack=r1"}
+	assert.NoError(t, c.validatePlacementPreferences())
+}
+
+func TestInventoryPlacementPreferences(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager})
+	assert.Equal(t, []string{"spread=node.id"}, c.Inventory().SwarmPlacementPreferences)
+
+	c.Config.SwarmModeGlobalConfig.DefaultPlacementPreferences = []string{"spread=node.labels.nic"}
+	assert.Equal(t, []string{"spread=node.labels.nic"}, c.Inventory().SwarmPlacementPreferences)
+}
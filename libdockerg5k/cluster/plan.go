@@ -0,0 +1,146 @@
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// RoleChange contain a change of the Swarm role of a node
+type RoleChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NodeUpdate contain the in-place changes to apply on an existing node
+type NodeUpdate struct {
+	MachineName   string      `json:"machine_name"`
+	SwarmRole     *RoleChange `json:"swarm_role,omitempty"`
+	AddedLabels   []string    `json:"added_labels,omitempty"`
+	RemovedLabels []string    `json:"removed_labels,omitempty"`
+}
+
+// ClusterDiff contain the changes needed to go from the actual cluster state to the desired one
+type ClusterDiff struct {
+	Add    []string      `json:"add"`
+	Remove []string      `json:"remove"`
+	Update []*NodeUpdate `json:"update"`
+}
+
+// IsEmpty returns true if there is no change, false otherwise
+func (d *ClusterDiff) IsEmpty() bool {
+	return len(d.Add) == 0 && len(d.Remove) == 0 && len(d.Update) == 0
+}
+
+// JSON returns the diff as an indented JSON document
+func (d *ClusterDiff) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// diffLabels returns the labels to add and to remove to go from the actual labels to the desired ones
+func diffLabels(desired []string, actual []string) ([]string, []string) {
+	desiredSet := make(map[string]bool)
+	for _, l := range desired {
+		desiredSet[l] = true
+	}
+
+	actualSet := make(map[string]bool)
+	for _, l := range actual {
+		actualSet[l] = true
+	}
+
+	added := []string{}
+	for l := range desiredSet {
+		if !actualSet[l] {
+			added = append(added, l)
+		}
+	}
+	sort.Strings(added)
+
+	removed := []string{}
+	for l := range actualSet {
+		if !desiredSet[l] {
+			removed = append(removed, l)
+		}
+	}
+	sort.Strings(removed)
+
+	return added, removed
+}
+
+// diffNode returns the in-place changes to apply on the node (nil if there is no change)
+func diffNode(desired *NodeStatus, actual *NodeStatus) *NodeUpdate {
+	update := &NodeUpdate{MachineName: desired.MachineName}
+
+	// Swarm role
+	if desired.SwarmRole != actual.SwarmRole {
+		update.SwarmRole = &RoleChange{From: actual.SwarmRole, To: desired.SwarmRole}
+	}
+
+	// Engine labels
+	added, removed := diffLabels(desired.EngineLabels, actual.EngineLabels)
+	if len(added) > 0 {
+		update.AddedLabels = added
+	}
+	if len(removed) > 0 {
+		update.RemovedLabels = removed
+	}
+
+	if update.SwarmRole == nil && update.AddedLabels == nil && update.RemovedLabels == nil {
+		return nil
+	}
+
+	return update
+}
+
+// computeDiff returns the changes needed to go from the actual nodes state to the desired one
+func computeDiff(desired map[string]*NodeStatus, actual map[string]*NodeStatus) *ClusterDiff {
+	diff := &ClusterDiff{
+		Add:    []string{},
+		Remove: []string{},
+		Update: []*NodeUpdate{},
+	}
+
+	// nodes to add or update
+	for machineName, d := range desired {
+		a, ok := actual[machineName]
+		if !ok {
+			diff.Add = append(diff.Add, machineName)
+			continue
+		}
+
+		if update := diffNode(d, a); update != nil {
+			diff.Update = append(diff.Update, update)
+		}
+	}
+
+	// nodes to remove
+	for machineName := range actual {
+		if _, ok := desired[machineName]; !ok {
+			diff.Remove = append(diff.Remove, machineName)
+		}
+	}
+
+	// sort by machine name for a stable output
+	sort.Strings(diff.Add)
+	sort.Strings(diff.Remove)
+	sort.Slice(diff.Update, func(i, j int) bool { return diff.Update[i].MachineName < diff.Update[j].MachineName })
+
+	return diff
+}
+
+// Plan returns the changes needed to apply the cluster configuration on the running cluster (nothing is modified)
+func (c *Cluster) Plan() (*ClusterDiff, error) {
+	// get live state of the cluster
+	actual, err := c.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	// get desired state from the configuration
+	desired := make(map[string]*NodeStatus)
+	for machineName, n := range c.Nodes {
+		desired[machineName] = n.desiredStatus()
+	}
+
+	return computeDiff(desired, actual), nil
+}
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeDiffNoChange(t *testing.T) {
+	desired := map[string]*NodeStatus{
+		"lille-0": {MachineName: "lille-0", SwarmRole: SwarmRoleManager, EngineLabels: []string{"a=1"}},
+	}
+	actual := map[string]*NodeStatus{
+		"lille-0": {MachineName: "lille-0", SwarmRole: SwarmRoleManager, EngineLabels: []string{"a=1"}},
+	}
+
+	assert.True(t, computeDiff(desired, actual).IsEmpty())
+}
+
+func TestComputeDiffAddRemove(t *testing.T) {
+	desired := map[string]*NodeStatus{
+		"lille-0": {MachineName: "lille-0"},
+		"lille-2": {MachineName: "lille-2"},
+		"lille-1": {MachineName: "lille-1"},
+	}
+	actual := map[string]*NodeStatus{
+		"lille-0": {MachineName: "lille-0"},
+		"lille-3": {MachineName: "lille-3"},
+	}
+
+	diff := computeDiff(desired, actual)
+	assert.Equal(t, []string{"lille-1", "lille-2"}, diff.Add)
+	assert.Equal(t, []string{"lille-3"}, diff.Remove)
+	assert.Empty(t, diff.Update)
+}
+
+func TestComputeDiffUpdate(t *testing.T) {
+	desired := map[string]*NodeStatus{
+		"lille-0": {MachineName: "lille-0", SwarmRole: SwarmRoleManager, EngineLabels: []string{"a=1", "b=2"}},
+	}
+	actual := map[string]*NodeStatus{
+		"lille-0": {MachineName: "lille-0", SwarmRole: SwarmRoleWorker, EngineLabels: []string{"a=1", "c=3"}},
+	}
+
+	diff := computeDiff(desired, actual)
+	assert.Len(t, diff.Update, 1)
+	assert.Equal(t, &RoleChange{From: SwarmRoleWorker, To: SwarmRoleManager}, diff.Update[0].SwarmRole)
+	assert.Equal(t, []string{"b=2"}, diff.Update[0].AddedLabels)
+	assert.Equal(t, []string{"c=3"}, diff.Update[0].RemovedLabels)
+}
+
+func TestClusterDiffJSON(t *testing.T) {
+	diff := computeDiff(map[string]*NodeStatus{"lille-0": {MachineName: "lille-0"}}, map[string]*NodeStatus{})
+
+	data, err := diff.JSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"add": ["lille-0"], "remove": [], "update": []}`, string(data))
+}
+
+func TestParseSwarmModeRole(t *testing.T) {
+	assert.Equal(t, SwarmRoleManager, parseSwarmModeRole("active true\n"))
+	assert.Equal(t, SwarmRoleWorker, parseSwarmModeRole("active false"))
+	assert.Equal(t, "", parseSwarmModeRole("inactive false"))
+}
+
+func TestParseEngineLabels(t *testing.T) {
+	labels, err := parseEngineLabels(`["a=1","b=2"]` + "\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a=1", "b=2"}, labels)
+
+	labels, err = parseEngineLabels("null")
+	assert.NoError(t, err)
+	assert.Empty(t, labels)
+
+	_, err = parseEngineLabels("not json")
+	assert.Error(t, err)
+}
+
+func TestComputeDiffMachineLabels(t *testing.T) {
+	// the provider label added by Docker Machine is not a change
+	labels, err := parseEngineLabels(`["g5k.site=lille","provider=g5k"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"g5k.site=lille"}, labels)
+
+	desired := map[string]*NodeStatus{
+		"lille-0": {MachineName: "lille-0", SwarmRole: SwarmRoleManager, EngineLabels: []string{"g5k.site=lille"}},
+	}
+	actual := map[string]*NodeStatus{
+		"lille-0": {MachineName: "lille-0", SwarmRole: SwarmRoleManager, EngineLabels: labels},
+	}
+
+	assert.True(t, computeDiff(desired, actual).IsEmpty())
+}
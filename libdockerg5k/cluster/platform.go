@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// platform returns the platform of the images pulled on the node (the cluster default platform if not set, empty for the variant of the node)
+func (n *Node) platform() string {
+	if n.Platform != "" {
+		return n.Platform
+	}
+
+	return n.clusterConfig.DefaultPlatform
+}
+
+// isPlatformMismatch returns true if the architecture of the platform differs from the detected architecture, false otherwise (or if one of them is unknown)
+func isPlatformMismatch(platform string, arch string) bool {
+	if platform == "" || arch == "" {
+		return false
+	}
+
+	return container.PlatformArchitecture(platform) != arch
+}
+
+// detectArchitecture store the architecture of the node (images architecture matching its hardware) and warn if the images platform of the node does not match it
+// the images of a mismatching platform are only run with an emulation (ex: binfmt/QEMU)
+func (n *Node) detectArchitecture(h *host.Host) {
+	out, err := h.RunSSHCommand("uname -m")
+	if err != nil {
+		log.Warnf("Unable to detect the architecture of the node '%s': '%s'", n.MachineName, err)
+		return
+	}
+
+	n.detectedArch = container.MachineArchitecture(out)
+	n.logf("Detected node architecture: %s", n.detectedArch)
+
+	if isPlatformMismatch(n.platform(), n.detectedArch) {
+		log.Warnf("The images platform '%s' of the node '%s' does not match its architecture '%s'", n.platform(), n.MachineName, n.detectedArch)
+	}
+}
+
+// validateNodePlatforms check the images platform of the nodes
+func (c *Cluster) validateNodePlatforms() error {
+	for _, n := range c.Nodes {
+		if n.Platform == "" {
+			continue
+		}
+
+		if err := container.ValidatePlatform(n.Platform); err != nil {
+			return fmt.Errorf("Node '%s': %s", n.MachineName, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodePlatform(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.CreateNodes(map[string]int{"lille": 2})
+	assert.Equal(t, "", c.Nodes["lille-0"].platform())
+
+	c.Config.DefaultPlatform = "linux/amd64"
+	c.Nodes["lille-1"].Platform = "linux/arm64"
+	assert.Equal(t, "linux/amd64", c.Nodes["lille-0"].platform())
+	assert.Equal(t, "linux/arm64", c.Nodes["lille-1"].platform())
+	assert.NoError(t, c.validateNodePlatforms())
+
+	c.Nodes["lille-1"].detectedArch = "arm64"
+	assert.Equal(t, "linux/arm64", c.Nodes["lille-1"].inventory().Platform)
+	assert.Equal(t, "arm64", c.Nodes["lille-1"].inventory().Architecture)
+
+	c.Nodes["lille-1"].Platform = "arm64"
+	assert.Error(t, c.validateNodePlatforms())
+
+	c.Config.DefaultPlatform = "linux/sparc"
+	assert.Error(t, c.Config.Validate())
+}
+
+func TestIsPlatformMismatch(t *testing.T) {
+	assert.False(t, isPlatformMismatch("linux/arm64/v8", "arm64"))
+	assert.True(t, isPlatformMismatch("linux/amd64", "arm64"))
+
+	// the variant of the node is pulled without platform
+	assert.False(t, isPlatformMismatch("", "arm64"))
+	assert.False(t, isPlatformMismatch("linux/amd64", ""))
+}
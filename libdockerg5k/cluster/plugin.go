@@ -0,0 +1,116 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// ProvisionPlugin is a user integration applied on the hosts once provisioned (ex: monitoring agents, lab-specific setup)
+// The context expire at the end of the 'plugins' phase timeout, an error aborts the provisioning of the node (node plugins) or of the cluster (cluster plugins)
+type ProvisionPlugin interface {
+	Name() string
+	Apply(ctx context.Context, h *host.Host, cfg *GlobalConfig) error
+}
+
+// validatePlugins check the plugins are set and their names are unique
+func validatePlugins(plugins []ProvisionPlugin) error {
+	names := make(map[string]bool)
+	for i, p := range plugins {
+		if p == nil {
+			return fmt.Errorf("The provisioning plugin %d is not set", i)
+		}
+
+		if p.Name() == "" {
+			return fmt.Errorf("The provisioning plugin %d has no name", i)
+		}
+
+		if names[p.Name()] {
+			return fmt.Errorf("The provisioning plugin '%s' is registered more than once", p.Name())
+		}
+		names[p.Name()] = true
+	}
+
+	return nil
+}
+
+// applyPlugins apply the plugins on the host in the declared order, stopping at the first failure
+func applyPlugins(plugins []ProvisionPlugin, h *host.Host, cfg *GlobalConfig) error {
+	if len(plugins) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.PhaseTimeout(PhasePlugins))
+	defer cancel()
+
+	for _, p := range plugins {
+		if err := p.Apply(ctx, h, cfg); err != nil {
+			return fmt.Errorf("Plugin '%s' failed: %w", p.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// applyNodePlugins apply the node plugins on the node (once it joined the Swarm cluster)
+func (n *Node) applyNodePlugins(h *host.Host) error {
+	if len(n.clusterConfig.NodePlugins) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	n.logf("Phase '%s' started", PhasePlugins)
+
+	err := applyPlugins(n.clusterConfig.NodePlugins, h, n.clusterConfig)
+	n.observePhase(PhasePlugins, start, err)
+
+	if err != nil {
+		n.logf("Phase '%s' failed: %s", PhasePlugins, err)
+		return err
+	}
+
+	n.logf("Phase '%s' done", PhasePlugins)
+	return nil
+}
+
+// clusterPluginsHost returns the host the cluster plugins are applied on: a reachable Swarm mode manager, or the first provisioned Swarm master (or node)
+func (c *Cluster) clusterPluginsHost(errs map[string]error) (*host.Host, error) {
+	if c.Config.SwarmModeGlobalConfig != nil {
+		return c.swarmManager()
+	}
+
+	candidates := append([]string{}, c.Config.SwarmMasterNode...)
+	machineNames := []string{}
+	for machineName := range c.Nodes {
+		machineNames = append(machineNames, machineName)
+	}
+	sort.Strings(machineNames)
+	candidates = append(candidates, machineNames...)
+
+	for _, machineName := range candidates {
+		if n, ok := c.Nodes[machineName]; ok && errs[machineName] == nil {
+			return n.loadHost()
+		}
+	}
+
+	return nil, fmt.Errorf("No provisioned node to apply the cluster plugins on")
+}
+
+// applyClusterPlugins apply the cluster plugins once, after all nodes are provisioned
+func (c *Cluster) applyClusterPlugins(errs map[string]error) error {
+	if len(c.Config.ClusterPlugins) == 0 {
+		return nil
+	}
+
+	h, err := c.clusterPluginsHost(errs)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Applying %d cluster plugin(s) on machine '%s'...", len(c.Config.ClusterPlugins), h.Name)
+	return applyPlugins(c.Config.ClusterPlugins, h, c.Config)
+}
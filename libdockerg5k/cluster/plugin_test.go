@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPlugin is a provisioning plugin recording its calls
+type testPlugin struct {
+	name  string
+	err   error
+	calls *[]string
+}
+
+func (p *testPlugin) Name() string { return p.name }
+
+func (p *testPlugin) Apply(ctx context.Context, h *host.Host, cfg *GlobalConfig) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("no deadline")
+	}
+
+	*p.calls = append(*p.calls, p.name)
+	return p.err
+}
+
+func TestValidatePlugins(t *testing.T) {
+	calls := []string{}
+	assert.NoError(t, validatePlugins(nil))
+	assert.NoError(t, validatePlugins([]ProvisionPlugin{&testPlugin{name: "monitoring", calls: &calls}, &testPlugin{name: "lab", calls: &calls}}))
+
+	assert.Error(t, validatePlugins([]ProvisionPlugin{nil}))
+	assert.Error(t, validatePlugins([]ProvisionPlugin{&testPlugin{calls: &calls}}))
+	assert.Error(t, validatePlugins([]ProvisionPlugin{&testPlugin{name: "lab", calls: &calls}, &testPlugin{name: "lab", calls: &calls}}))
+}
+
+func TestApplyPlugins(t *testing.T) {
+	calls := []string{}
+	plugins := []ProvisionPlugin{&testPlugin{name: "monitoring", calls: &calls}, &testPlugin{name: "lab", calls: &calls}}
+
+	assert.NoError(t, applyPlugins(plugins, &host.Host{}, &GlobalConfig{}))
+	assert.Equal(t, []string{"monitoring", "lab"}, calls)
+}
+
+func TestApplyPluginsFailure(t *testing.T) {
+	calls := []string{}
+	errLab := errors.New("lab setup failed")
+	plugins := []ProvisionPlugin{
+		&testPlugin{name: "monitoring", calls: &calls},
+		&testPlugin{name: "lab", err: errLab, calls: &calls},
+		&testPlugin{name: "never", calls: &calls},
+	}
+
+	err := applyPlugins(plugins, &host.Host{}, &GlobalConfig{})
+	assert.EqualError(t, err, "Plugin 'lab' failed: lab setup failed")
+	assert.True(t, errors.Is(err, errLab))
+	assert.Equal(t, []string{"monitoring", "lab"}, calls)
+}
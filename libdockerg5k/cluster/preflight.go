@@ -0,0 +1,173 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+)
+
+// names of the preflight checks
+const (
+	PreflightAPI         = "api"         // the Grid5000 API is reachable
+	PreflightCredentials = "credentials" // the Grid5000 credentials are valid and the site exists
+	PreflightFreeNodes   = "free-nodes"  // enough free nodes satisfy the resource filter (network requirement)
+	PreflightImage       = "image"       // the environment to deploy exists on the site
+)
+
+// PreflightCheck contain the result of a preflight check and the reason of its failure (or details if passed)
+type PreflightCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// PreflightReport contain the results of the preflight checks of a site
+type PreflightReport struct {
+	Site      string           `json:"site"`
+	Passed    bool             `json:"passed"`
+	Checks    []PreflightCheck `json:"checks"`
+	FreeNodes int              `json:"free_nodes"` // free nodes satisfying the resource filter
+}
+
+// addCheck append the result of a check to the report
+func (r *PreflightReport) addCheck(name string, err error, details string) {
+	check := PreflightCheck{Name: name, Passed: err == nil, Reason: details}
+	if err != nil {
+		check.Reason = err.Error()
+		r.Passed = false
+	}
+
+	r.Checks = append(r.Checks, check)
+}
+
+// err returns an error listing the failed checks (nil if all passed)
+func (r *PreflightReport) err() error {
+	failed := []string{}
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, fmt.Sprintf("%s (%s)", check.Name, check.Reason))
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("The preflight of site '%s' failed: %s", r.Site, strings.Join(failed, ", "))
+}
+
+// siteAccessErrors returns the errors of the API reachability and credentials checks from the site access result
+func siteAccessErrors(site string, err error) (error, error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	var apiErr *g5k.APIError
+	if !errors.As(err, &apiErr) {
+		return fmt.Errorf("The Grid5000 API is not reachable: '%s'", err), fmt.Errorf("Not checked, the Grid5000 API is not reachable")
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("The Grid5000 credentials are rejected: '%s'", apiErr.Status)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("Unknown site '%s'", site)
+	}
+
+	return fmt.Errorf("The Grid5000 API returned an error: '%s'", apiErr.Status), fmt.Errorf("Not checked, the Grid5000 API returned an error")
+}
+
+// countFreeNodes returns the number of free nodes (by the nodes status) satisfying the network requirement (all the nodes if nil)
+func countFreeNodes(nodes []g5k.ReferenceNode, status map[string]g5k.NodeStatus, requirement *g5k.NetworkRequirement) int {
+	count := 0
+	for i := range nodes {
+		s, ok := status[nodes[i].UID]
+		if !ok || !s.IsFree() {
+			continue
+		}
+
+		if requirement != nil {
+			if _, err := requirement.MatchingDevice(&nodes[i]); err != nil {
+				continue
+			}
+		}
+
+		count++
+	}
+
+	return count
+}
+
+// checkFreeNodes returns the number of free nodes of the site satisfying the network requirement and an error if there are less than needed
+func (c *GlobalConfig) checkFreeNodes(g5kAPI *g5k.G5K, site string, needed int) (int, error) {
+	nodes, err := g5kAPI.GetSiteReferenceNodes(site)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to get the description of the nodes of site '%s': '%s'", site, err)
+	}
+
+	status, err := g5kAPI.GetSiteNodesStatus(site)
+	if err != nil {
+		return 0, err
+	}
+
+	free := countFreeNodes(nodes, status, c.NetworkRequirement)
+	if free < needed {
+		return free, fmt.Errorf("%d free node(s) satisfying the resource filter, %d needed", free, needed)
+	}
+
+	return free, nil
+}
+
+// checkImage check the environment of the cluster exists on the site (not checked in classic mode or for a path/URL to an environment description) and returns its name
+func (c *GlobalConfig) checkImage(g5kAPI *g5k.G5K, site string) (string, error) {
+	if (&Node{clusterConfig: c}).deployMode() != DeployModeDeploy {
+		return "not needed in classic mode", nil
+	}
+
+	if g5k.IsEnvironmentPath(c.G5kImage) {
+		return fmt.Sprintf("environment description '%s' not checked", c.G5kImage), nil
+	}
+
+	name, err := g5kAPI.ResolveEnvironment(site, c.G5kImage)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("environment '%s'", name), nil
+}
+
+// Preflight check the site can satisfy a reservation of the given number of nodes before reserving: the Grid5000 API is reachable, the credentials are valid,
+// enough nodes satisfying the resource filter are free and the environment to deploy exists. It returns the report of all the checks and an error listing the failed ones
+// the checks depending on the API are not done if it is not reachable, the free nodes may be reserved by another user before the reservation
+func (c *GlobalConfig) Preflight(site string, nodes int) (*PreflightReport, error) {
+	if nodes <= 0 {
+		return nil, fmt.Errorf("Invalid number of nodes to check: %d", nodes)
+	}
+
+	report := &PreflightReport{Site: site, Passed: true}
+
+	g5kAPI, err := c.g5kAPI()
+	if err != nil {
+		report.addCheck(PreflightCredentials, err, "")
+		return report, report.err()
+	}
+
+	apiErr, credentialsErr := siteAccessErrors(site, g5kAPI.CheckSiteAccess(site))
+	report.addCheck(PreflightAPI, apiErr, "")
+	report.addCheck(PreflightCredentials, credentialsErr, "")
+	if apiErr != nil || credentialsErr != nil {
+		return report, report.err()
+	}
+
+	free, err := c.checkFreeNodes(g5kAPI, site, nodes)
+	report.FreeNodes = free
+	report.addCheck(PreflightFreeNodes, err, fmt.Sprintf("%d free node(s) satisfying the resource filter", free))
+
+	image, err := c.checkImage(g5kAPI, site)
+	report.addCheck(PreflightImage, err, image)
+
+	return report, report.err()
+}
@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSiteAccessErrors(t *testing.T) {
+	apiErr, credentialsErr := siteAccessErrors("lille", nil)
+	assert.NoError(t, apiErr)
+	assert.NoError(t, credentialsErr)
+
+	apiErr, credentialsErr = siteAccessErrors("lille", fmt.Errorf("dial tcp: i/o timeout"))
+	assert.Error(t, apiErr)
+	assert.Error(t, credentialsErr)
+
+	apiErr, credentialsErr = siteAccessErrors("lille", &g5k.APIError{Path: "sites/lille", StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"})
+	assert.NoError(t, apiErr)
+	assert.EqualError(t, credentialsErr, "The Grid5000 credentials are rejected: '401 Unauthorized'")
+
+	apiErr, credentialsErr = siteAccessErrors("lile", &g5k.APIError{Path: "sites/lile", StatusCode: http.StatusNotFound, Status: "404 Not Found"})
+	assert.NoError(t, apiErr)
+	assert.EqualError(t, credentialsErr, "Unknown site 'lile'")
+
+	apiErr, _ = siteAccessErrors("lille", &g5k.APIError{Path: "sites/lille", StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"})
+	assert.Error(t, apiErr)
+}
+
+func TestCountFreeNodes(t *testing.T) {
+	eth := g5k.ReferenceNetworkAdapter{Device: "eth0", Interface: "Ethernet", Rate: 10e9, Enabled: true, Mountable: true}
+	ib := g5k.ReferenceNetworkAdapter{Device: "ib0", Interface: "InfiniBand", Rate: 56e9, Enabled: true, Mountable: true}
+	nodes := []g5k.ReferenceNode{
+		{UID: "grisou-1", NetworkAdapters: []g5k.ReferenceNetworkAdapter{eth, ib}},
+		{UID: "grisou-2", NetworkAdapters: []g5k.ReferenceNetworkAdapter{eth}},
+		{UID: "grisou-3", NetworkAdapters: []g5k.ReferenceNetworkAdapter{eth, ib}},
+		{UID: "grisou-4", NetworkAdapters: []g5k.ReferenceNetworkAdapter{eth}},
+	}
+	status := map[string]g5k.NodeStatus{
+		"grisou-1": {Hard: "alive", Soft: "free"},
+		"grisou-2": {Hard: "alive", Soft: "free"},
+		"grisou-3": {Hard: "alive", Soft: "busy"},
+	}
+
+	assert.Equal(t, 2, countFreeNodes(nodes, status, nil))
+	assert.Equal(t, 1, countFreeNodes(nodes, status, &g5k.NetworkRequirement{Interface: "InfiniBand"}))
+	assert.Equal(t, 0, countFreeNodes(nodes, nil, nil))
+}
+
+func TestPreflightReport(t *testing.T) {
+	r := &PreflightReport{Site: "lille", Passed: true}
+	r.addCheck(PreflightAPI, nil, "")
+	r.addCheck(PreflightImage, nil, "environment 'debian11-min'")
+	assert.True(t, r.Passed)
+	assert.NoError(t, r.err())
+
+	r.addCheck(PreflightFreeNodes, fmt.Errorf("1 free node(s) satisfying the resource filter, 3 needed"), "1 free node(s) satisfying the resource filter")
+	assert.False(t, r.Passed)
+	assert.Equal(t, "1 free node(s) satisfying the resource filter, 3 needed", r.Checks[2].Reason)
+	assert.EqualError(t, r.err(), "The preflight of site 'lille' failed: free-nodes (1 free node(s) satisfying the resource filter, 3 needed)")
+}
+
+func TestCheckImageClassicMode(t *testing.T) {
+	c := &GlobalConfig{DeployMode: DeployModeClassic}
+	_, err := c.checkImage(nil, "lille")
+	assert.NoError(t, err)
+
+	c = &GlobalConfig{G5kImage: "http://public.lille.grid5000.fr/~jdoe/env.yaml"}
+	details, err := c.checkImage(nil, "lille")
+	assert.NoError(t, err)
+	assert.Contains(t, details, "not checked")
+}
+
+func TestPreflightIncorrect(t *testing.T) {
+	_, err := (&GlobalConfig{}).Preflight("lille", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,250 @@
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// provisionMetricsPrefix is the prefix of the provisioning metrics names
+	provisionMetricsPrefix = "docker_g5k_provision"
+
+	// phaseOther is the phase label of the failures outside of a provisioning phase (ex: Engine configuration, Swarm standalone)
+	phaseOther = "other"
+
+	// provisionMetricsContentType is the content type of the Prometheus text exposition format
+	provisionMetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+var (
+	// phaseDurationBuckets are the upper bounds (in seconds) of the phase duration histogram buckets
+	phaseDurationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800}
+
+	// escaper of the label values in the text exposition format
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	// provisioningMetrics contain the provisioning metrics of the process (nil until enabled)
+	provisioningMetrics   *provisionMetrics
+	provisioningMetricsMu sync.Mutex
+)
+
+// metricKey is the site and phase labels of a metric
+type metricKey struct {
+	site  string
+	phase string
+}
+
+// durationHistogram is a cumulative histogram of durations (in seconds)
+type durationHistogram struct {
+	buckets []uint64 // observations count of each bucket of phaseDurationBuckets (not cumulative)
+	sum     float64
+	count   uint64
+}
+
+// provisionMetrics contain the counters and histograms of the nodes provisioning
+type provisionMetrics struct {
+	mu        sync.Mutex
+	attempts  map[string]uint64 // site => provisioning attempts
+	successes map[string]uint64 // site => successful provisionings
+	failures  map[metricKey]uint64
+	durations map[metricKey]*durationHistogram
+}
+
+func newProvisionMetrics() *provisionMetrics {
+	return &provisionMetrics{
+		attempts:  make(map[string]uint64),
+		successes: make(map[string]uint64),
+		failures:  make(map[metricKey]uint64),
+		durations: make(map[metricKey]*durationHistogram),
+	}
+}
+
+// EnableProvisioningMetrics start collecting the provisioning metrics of the nodes (attempts, successes, failures by phase and phase durations, labeled by site)
+// the metrics are collected for all the clusters of the process, calling it again keeps the collected metrics
+func EnableProvisioningMetrics() {
+	provisioningMetricsMu.Lock()
+	defer provisioningMetricsMu.Unlock()
+
+	if provisioningMetrics == nil {
+		provisioningMetrics = newProvisionMetrics()
+	}
+}
+
+// activeProvisionMetrics returns the provisioning metrics if enabled, nil otherwise
+func activeProvisionMetrics() *provisionMetrics {
+	provisioningMetricsMu.Lock()
+	defer provisioningMetricsMu.Unlock()
+
+	return provisioningMetrics
+}
+
+// observeAttempt count a provisioning attempt on the site
+func (m *provisionMetrics) observeAttempt(site string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.attempts[site]++
+}
+
+// observeResult count the provisioning success or failure (in the phase) on the site
+func (m *provisionMetrics) observeResult(site string, phase string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		m.successes[site]++
+		return
+	}
+
+	if phase == "" {
+		phase = phaseOther
+	}
+	m.failures[metricKey{site, phase}]++
+}
+
+// observePhase add the duration of the provisioning phase on the site to its histogram
+func (m *provisionMetrics) observePhase(site string, phase string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := metricKey{site, phase}
+	h, ok := m.durations[k]
+	if !ok {
+		h = &durationHistogram{buckets: make([]uint64, len(phaseDurationBuckets))}
+		m.durations[k] = h
+	}
+
+	s := d.Seconds()
+	for i, b := range phaseDurationBuckets {
+		if s <= b {
+			h.buckets[i]++
+			break
+		}
+	}
+	h.sum += s
+	h.count++
+}
+
+// formatLabels returns the labels in the text exposition format (sorted by name)
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, labelValueEscaper.Replace(labels[name])))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sortedMetricKeys returns the keys sorted by site then phase
+func sortedMetricKeys(keys []metricKey) []metricKey {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].site != keys[j].site {
+			return keys[i].site < keys[j].site
+		}
+		return keys[i].phase < keys[j].phase
+	})
+
+	return keys
+}
+
+// writeSiteCounter write the counter of each site in the text exposition format
+func writeSiteCounter(w *bufio.Writer, name string, help string, values map[string]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+	sites := make([]string, 0, len(values))
+	for site := range values {
+		sites = append(sites, site)
+	}
+	sort.Strings(sites)
+
+	for _, site := range sites {
+		fmt.Fprintf(w, "%s%s %d\n", name, formatLabels(map[string]string{"site": site}), values[site])
+	}
+}
+
+// write write the metrics in the Prometheus text exposition format
+func (m *provisionMetrics) write(out io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := bufio.NewWriter(out)
+
+	writeSiteCounter(w, provisionMetricsPrefix+"_attempts_total", "Number of node provisioning attempts.", m.attempts)
+	writeSiteCounter(w, provisionMetricsPrefix+"_successes_total", "Number of successful node provisionings.", m.successes)
+
+	name := provisionMetricsPrefix + "_failures_total"
+	fmt.Fprintf(w, "# HELP %s Number of failed node provisionings by failed phase.\n# TYPE %s counter\n", name, name)
+	keys := make([]metricKey, 0, len(m.failures))
+	for k := range m.failures {
+		keys = append(keys, k)
+	}
+	for _, k := range sortedMetricKeys(keys) {
+		fmt.Fprintf(w, "%s%s %d\n", name, formatLabels(map[string]string{"site": k.site, "phase": k.phase}), m.failures[k])
+	}
+
+	name = provisionMetricsPrefix + "_phase_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Duration of the node provisioning phases.\n# TYPE %s histogram\n", name, name)
+	keys = make([]metricKey, 0, len(m.durations))
+	for k := range m.durations {
+		keys = append(keys, k)
+	}
+	for _, k := range sortedMetricKeys(keys) {
+		h := m.durations[k]
+
+		cumulative := uint64(0)
+		for i, b := range phaseDurationBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(map[string]string{"site": k.site, "phase": k.phase, "le": fmt.Sprint(b)}), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(map[string]string{"site": k.site, "phase": k.phase, "le": "+Inf"}), h.count)
+		fmt.Fprintf(w, "%s_sum%s %g\n", name, formatLabels(map[string]string{"site": k.site, "phase": k.phase}), h.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(map[string]string{"site": k.site, "phase": k.phase}), h.count)
+	}
+
+	return w.Flush()
+}
+
+// WriteProvisioningMetrics write the provisioning metrics in the Prometheus text exposition format
+func WriteProvisioningMetrics(w io.Writer) error {
+	m := activeProvisionMetrics()
+	if m == nil {
+		return fmt.Errorf("The provisioning metrics are not enabled")
+	}
+
+	return m.write(w)
+}
+
+// ProvisioningMetricsHandler returns the HTTP handler exposing the provisioning metrics to Prometheus (enable the metrics collection)
+func ProvisioningMetricsHandler() http.Handler {
+	EnableProvisioningMetrics()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", provisionMetricsContentType)
+		if err := WriteProvisioningMetrics(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// observePhase record the duration of the provisioning phase of the node, and the phase as the failed one on error
+func (n *Node) observePhase(phase string, start time.Time, err error) {
+	if err != nil {
+		n.failedPhase = phase
+	}
+
+	if m := activeProvisionMetrics(); m != nil {
+		m.observePhase(n.G5kSite, phase, time.Since(start))
+	}
+}
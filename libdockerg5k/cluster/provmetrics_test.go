@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvisionMetricsWrite(t *testing.T) {
+	m := newProvisionMetrics()
+	m.observeAttempt("lille")
+	m.observeAttempt("lille")
+	m.observeAttempt("nancy")
+	m.observeResult("lille", "", nil)
+	m.observeResult("lille", PhaseCreate, fmt.Errorf("create error"))
+	m.observeResult("nancy", "", fmt.Errorf("engine error"))
+	m.observePhase("lille", PhaseCreate, 3*time.Second)
+	m.observePhase("lille", PhaseCreate, 90*time.Second)
+	m.observePhase("lille", PhaseCreate, time.Hour)
+
+	var b bytes.Buffer
+	assert.NoError(t, m.write(&b))
+	out := b.String()
+
+	assert.Contains(t, out, "# TYPE docker_g5k_provision_attempts_total counter\n")
+	assert.Contains(t, out, "docker_g5k_provision_attempts_total{site=\"lille\"} 2\n")
+	assert.Contains(t, out, "docker_g5k_provision_attempts_total{site=\"nancy\"} 1\n")
+	assert.Contains(t, out, "docker_g5k_provision_successes_total{site=\"lille\"} 1\n")
+	assert.Contains(t, out, "docker_g5k_provision_failures_total{phase=\"create\",site=\"lille\"} 1\n")
+	assert.Contains(t, out, "docker_g5k_provision_failures_total{phase=\"other\",site=\"nancy\"} 1\n")
+
+	// cumulative buckets
+	assert.Contains(t, out, "# TYPE docker_g5k_provision_phase_duration_seconds histogram\n")
+	assert.Contains(t, out, "docker_g5k_provision_phase_duration_seconds_bucket{le=\"1\",phase=\"create\",site=\"lille\"} 0\n")
+	assert.Contains(t, out, "docker_g5k_provision_phase_duration_seconds_bucket{le=\"5\",phase=\"create\",site=\"lille\"} 1\n")
+	assert.Contains(t, out, "docker_g5k_provision_phase_duration_seconds_bucket{le=\"120\",phase=\"create\",site=\"lille\"} 2\n")
+	assert.Contains(t, out, "docker_g5k_provision_phase_duration_seconds_bucket{le=\"1800\",phase=\"create\",site=\"lille\"} 2\n")
+	assert.Contains(t, out, "docker_g5k_provision_phase_duration_seconds_bucket{le=\"+Inf\",phase=\"create\",site=\"lille\"} 3\n")
+	assert.Contains(t, out, "docker_g5k_provision_phase_duration_seconds_sum{phase=\"create\",site=\"lille\"} 3693\n")
+	assert.Contains(t, out, "docker_g5k_provision_phase_duration_seconds_count{phase=\"create\",site=\"lille\"} 3\n")
+}
+
+func TestFormatLabels(t *testing.T) {
+	assert.Equal(t, "{phase=\"swarm\",site=\"lille\"}", formatLabels(map[string]string{"site": "lille", "phase": "swarm"}))
+	assert.Equal(t, "{site=\"a\\\"b\\\\c\\n\"}", formatLabels(map[string]string{"site": "a\"b\\c\n"}))
+}
+
+func TestRunPhaseMetrics(t *testing.T) {
+	m := newProvisionMetrics()
+	provisioningMetricsMu.Lock()
+	previous := provisioningMetrics
+	provisioningMetrics = m
+	provisioningMetricsMu.Unlock()
+	defer func() {
+		provisioningMetricsMu.Lock()
+		provisioningMetrics = previous
+		provisioningMetricsMu.Unlock()
+	}()
+
+	n := &Node{clusterConfig: &GlobalConfig{}, G5kSite: "lille"}
+	assert.NoError(t, n.runPhase(PhaseMapping, func() error { return nil }))
+	assert.Equal(t, "", n.failedPhase)
+
+	assert.Error(t, n.runPhase(PhaseWeave, func() error { return fmt.Errorf("weave error") }))
+	assert.Equal(t, PhaseWeave, n.failedPhase)
+
+	assert.Equal(t, uint64(1), m.durations[metricKey{"lille", PhaseMapping}].count)
+	assert.Equal(t, uint64(1), m.durations[metricKey{"lille", PhaseWeave}].count)
+}
+
+func TestProvisioningMetricsHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ProvisioningMetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, 200, rec.Code)
+	assert.Equal(t, provisionMetricsContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "# TYPE docker_g5k_provision_attempts_total counter")
+}
@@ -0,0 +1,274 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// pruneImagesTimeout is the maximum time allowed to prune the images on all nodes
+	pruneImagesTimeout = 30 * time.Minute
+
+	// pruneImagesListCommand returns the ID, tags (comma separated) and creation time of the images of the node (one image by line, intermediate images excluded)
+	pruneImagesListCommand = "docker image ls -q --no-trunc | sort -u | xargs -r docker image inspect --format '{{.Id}}|{{join .RepoTags \",\"}}|{{.Created}}'"
+
+	// pruneImagesInUseCommand returns the image ID of all the containers of the node (running or stopped)
+	pruneImagesInUseCommand = "docker ps -aq --no-trunc | xargs -r docker container inspect --format '{{.Image}}'"
+
+	// pruneDiskUsedCommand returns the used space (bytes) of the file system of the Docker data root
+	pruneDiskUsedCommand = "df -B1 --output=used \"$(docker info --format '{{.DockerRootDir}}')\" | tail -n 1"
+)
+
+var (
+	// regexImageID match a full or short (at least 12 hexadecimal characters) image ID
+	regexImageID = regexp.MustCompile("^(sha256:)?[[:xdigit:]]{12,64}$")
+)
+
+// PruneOptions contain the options of the images garbage collection on the nodes
+type PruneOptions struct {
+	DanglingOnly bool          // only remove the dangling images (untagged), all the unused images otherwise
+	Until        time.Duration // only remove the images created before this age (all ages if not set)
+	Keep         []string      // images never removed: reference (ex: nginx:1.19), repository for all its tags (ex: nginx) or image ID
+	System       bool          // also remove the stopped containers, the unused networks and the build cache before the images (volumes are kept)
+	Selector     *NodeSelector // nodes where the images are pruned (all nodes if empty)
+}
+
+// PruneReport contain the result of the images garbage collection on a node
+type PruneReport struct {
+	UntaggedImages []string `json:"untagged_images"` // removed references
+	DeletedImages  []string `json:"deleted_images"`  // IDs of the removed images
+	SkippedImages  []string `json:"skipped_images"`  // images that could not be removed (ex: used by a container created meanwhile) and the reason
+	ReclaimedBytes int64    `json:"reclaimed_bytes"` // space freed on the file system of the Docker data root
+}
+
+// pruneImage contain an image of a node, candidate to the garbage collection
+type pruneImage struct {
+	ID      string
+	Tags    []string
+	Created time.Time
+}
+
+// Validate check the prune options
+func (o *PruneOptions) Validate() error {
+	if o.Until < 0 {
+		return fmt.Errorf("The age of the pruned images must be positive: '%s'", o.Until)
+	}
+
+	for _, k := range o.Keep {
+		if strings.TrimSpace(k) == "" || strings.ContainsAny(k, " '\"") {
+			return fmt.Errorf("Invalid image to keep: '%s'", k)
+		}
+	}
+
+	return nil
+}
+
+// isKept returns true if the image matches an image of the keep list (by reference, repository or ID), false otherwise
+func (o *PruneOptions) isKept(img pruneImage) bool {
+	for _, k := range o.Keep {
+		if regexImageID.MatchString(k) && strings.HasPrefix(strings.TrimPrefix(img.ID, "sha256:"), strings.TrimPrefix(k, "sha256:")) {
+			return true
+		}
+
+		for _, tag := range img.Tags {
+			// a reference without tag (ex: nginx, registry:5000/app) match all the tags of the repository
+			if tag == k || (!strings.Contains(k[strings.LastIndex(k, "/")+1:], ":") && strings.HasPrefix(tag, k+":")) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// generatePruneSystemCommand returns the command removing the stopped containers, the unused networks and the build cache (created before the age if set)
+func (o *PruneOptions) generatePruneSystemCommand() string {
+	filter := ""
+	if o.Until > 0 {
+		filter = fmt.Sprintf(" --filter until=%s", o.Until)
+	}
+
+	return fmt.Sprintf("docker container prune -f%s && docker network prune -f%s && docker builder prune -f%s", filter, filter, filter)
+}
+
+// parsePruneImages returns the images from the output of the images list command
+func parsePruneImages(out string) ([]pruneImage, error) {
+	images := []pruneImage{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "|", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Unable to parse the image: '%s'", line)
+		}
+
+		created, err := time.Parse(time.RFC3339Nano, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse the creation time of image '%s': '%s'", fields[0], err)
+		}
+
+		img := pruneImage{ID: fields[0], Tags: []string{}, Created: created}
+		if fields[1] != "" {
+			img.Tags = strings.Split(fields[1], ",")
+		}
+		images = append(images, img)
+	}
+
+	return images, nil
+}
+
+// selectPruneImages returns the references to remove (the tags of the tagged images, the ID of the dangling images) of the images not in use, not kept and matching the options
+func selectPruneImages(images []pruneImage, inUse map[string]bool, opts PruneOptions, now time.Time) []string {
+	refs := []string{}
+	for _, img := range images {
+		switch {
+		case inUse[img.ID]:
+		case opts.isKept(img):
+		case opts.DanglingOnly && len(img.Tags) > 0:
+		case opts.Until > 0 && now.Sub(img.Created) < opts.Until:
+		case len(img.Tags) == 0:
+			refs = append(refs, img.ID)
+		default:
+			refs = append(refs, img.Tags...)
+		}
+	}
+	sort.Strings(refs)
+
+	return refs
+}
+
+// parseImagesRemoval returns the report of the images removal command output (the errors of the images that could not be removed are skipped)
+func parseImagesRemoval(out string) *PruneReport {
+	report := &PruneReport{UntaggedImages: []string{}, DeletedImages: []string{}, SkippedImages: []string{}}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "Untagged: "):
+			report.UntaggedImages = append(report.UntaggedImages, strings.TrimPrefix(line, "Untagged: "))
+		case strings.HasPrefix(line, "Deleted: "):
+			report.DeletedImages = append(report.DeletedImages, strings.TrimPrefix(line, "Deleted: "))
+		case strings.HasPrefix(line, "Error"):
+			report.SkippedImages = append(report.SkippedImages, line)
+		}
+	}
+
+	return report
+}
+
+// diskUsed returns the used space of the file system of the Docker data root of the node
+func diskUsed(h *host.Host) (int64, error) {
+	out, err := h.RunSSHCommand(pruneDiskUsedCommand)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get the disk usage: '%s'", err)
+	}
+
+	used, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to parse the disk usage: '%s'", err)
+	}
+
+	return used, nil
+}
+
+// pruneImages remove the images of the node matching the options, that are not in use nor kept
+// the images are removed without force: Docker refuses to remove an image used by a container (ex: created since the images were listed)
+func pruneImages(h *host.Host, opts PruneOptions) (*PruneReport, error) {
+	before, err := diskUsed(h)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.System {
+		if _, err := h.RunSSHCommand(opts.generatePruneSystemCommand()); err != nil {
+			return nil, fmt.Errorf("Failed to prune the containers, networks and build cache: '%s'", err)
+		}
+	}
+
+	out, err := h.RunSSHCommand(pruneImagesListCommand)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the images: '%s'", err)
+	}
+	images, err := parsePruneImages(out)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err = h.RunSSHCommand(pruneImagesInUseCommand)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the images in use: '%s'", err)
+	}
+	inUse := make(map[string]bool)
+	for _, id := range strings.Fields(out) {
+		inUse[id] = true
+	}
+
+	report := &PruneReport{UntaggedImages: []string{}, DeletedImages: []string{}, SkippedImages: []string{}}
+	if refs := selectPruneImages(images, inUse, opts, time.Now()); len(refs) > 0 {
+		out, _ := h.RunSSHCommand(fmt.Sprintf("docker image rm %s 2>&1", strings.Join(refs, " ")))
+		report = parseImagesRemoval(out)
+	}
+
+	after, err := diskUsed(h)
+	if err != nil {
+		return nil, err
+	}
+	if before > after {
+		report.ReclaimedBytes = before - after
+	}
+
+	return report, nil
+}
+
+// PruneImages remove the unused images of the selected nodes (all nodes if the selector is empty) matching the options (dangling only, older than an age), during long experiments
+// The images used by a container (running or stopped) and the images of the keep list are never removed. The removed images and the reclaimed space are returned by machine name
+func (c *Cluster) PruneImages(opts PruneOptions) (map[string]*PruneReport, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := c.checkSelection(opts.Selector); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	results := make(map[string]*PruneReport)
+
+	errs := c.runOnSelectedNodes(opts.Selector, pruneImagesTimeout, func(n *Node, h *host.Host) error {
+		report, err := pruneImages(h, opts)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("%d image(s) removed on node '%s', %d bytes reclaimed", len(report.DeletedImages), n.MachineName, report.ReclaimedBytes)
+		for _, skipped := range report.SkippedImages {
+			log.Warnf("Image not removed on node '%s': %s", n.MachineName, skipped)
+		}
+
+		mu.Lock()
+		results[n.MachineName] = report
+		mu.Unlock()
+
+		return nil
+	})
+
+	// copy the results to not race with the nodes still pruning after a timeout
+	mu.Lock()
+	defer mu.Unlock()
+
+	reports := make(map[string]*PruneReport)
+	for machineName, r := range results {
+		reports[machineName] = r
+	}
+
+	return reports, fleetError("Images prune", errs)
+}
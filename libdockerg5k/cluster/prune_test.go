@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPruneImages = `sha256:aaaaaaaaaaaaaaaa|nginx:1.19,nginx:latest|2020-09-01T10:00:00.123456789Z
+sha256:bbbbbbbbbbbbbbbb||2020-09-10T10:00:00Z
+sha256:cccccccccccccccc|registry:5000/app:v1|2020-09-02T10:00:00Z
+sha256:dddddddddddddddd|busybox:latest|2020-09-10T10:00:00Z
+sha256:eeeeeeeeeeeeeeee||2020-09-01T10:00:00Z
+`
+
+func TestPruneOptionsValidate(t *testing.T) {
+	assert.NoError(t, (&PruneOptions{}).Validate())
+	assert.NoError(t, (&PruneOptions{Until: 24 * time.Hour, Keep: []string{"nginx", "registry:5000/app:v1"}}).Validate())
+	assert.Error(t, (&PruneOptions{Until: -time.Hour}).Validate())
+	assert.Error(t, (&PruneOptions{Keep: []string{""}}).Validate())
+	assert.Error(t, (&PruneOptions{Keep: []string{"nginx; rm -rf /"}}).Validate())
+}
+
+func TestParsePruneImages(t *testing.T) {
+	images, err := parsePruneImages(testPruneImages)
+	assert.NoError(t, err)
+	assert.Len(t, images, 5)
+	assert.Equal(t, []string{"nginx:1.19", "nginx:latest"}, images[0].Tags)
+	assert.Empty(t, images[1].Tags)
+	assert.Equal(t, 2020, images[0].Created.Year())
+
+	images, err = parsePruneImages("")
+	assert.NoError(t, err)
+	assert.Empty(t, images)
+
+	_, err = parsePruneImages("sha256:aaaa|nginx:latest")
+	assert.Error(t, err)
+}
+
+func TestSelectPruneImages(t *testing.T) {
+	images, err := parsePruneImages(testPruneImages)
+	assert.NoError(t, err)
+	now := time.Date(2020, 9, 11, 10, 0, 0, 0, time.UTC)
+	inUse := map[string]bool{"sha256:dddddddddddddddd": true}
+
+	// all the unused images
+	assert.Equal(t, []string{"nginx:1.19", "nginx:latest", "registry:5000/app:v1", "sha256:bbbbbbbbbbbbbbbb", "sha256:eeeeeeeeeeeeeeee"}, selectPruneImages(images, inUse, PruneOptions{}, now))
+
+	// dangling images older than 2 days
+	assert.Equal(t, []string{"sha256:eeeeeeeeeeeeeeee"}, selectPruneImages(images, inUse, PruneOptions{DanglingOnly: true, Until: 48 * time.Hour}, now))
+
+	// the kept images (repository, reference, ID) and the images in use are never removed
+	opts := PruneOptions{Keep: []string{"nginx", "registry:5000/app:v1", "eeeeeeeeeeee"}}
+	assert.Equal(t, []string{"sha256:bbbbbbbbbbbbbbbb"}, selectPruneImages(images, inUse, opts, now))
+
+	// the tag of a reference only keep this tag (and the image if it has other tags)
+	opts = PruneOptions{Keep: []string{"nginx:1.19", "registry:5000/app"}}
+	assert.Equal(t, []string{"sha256:bbbbbbbbbbbbbbbb", "sha256:eeeeeeeeeeeeeeee"}, selectPruneImages(images, inUse, opts, now))
+}
+
+func TestParseImagesRemoval(t *testing.T) {
+	report := parseImagesRemoval(`Untagged: nginx:1.19
+Untagged: nginx@sha256:0123
+Deleted: sha256:aaaaaaaaaaaaaaaa
+Deleted: sha256:ffffffffffffffff
+Error response from daemon: conflict: unable to remove repository reference "busybox:latest" (must force) - container 0123 is using its referenced image dddd
+`)
+
+	assert.Equal(t, []string{"nginx:1.19", "nginx@sha256:0123"}, report.UntaggedImages)
+	assert.Equal(t, []string{"sha256:aaaaaaaaaaaaaaaa", "sha256:ffffffffffffffff"}, report.DeletedImages)
+	assert.Len(t, report.SkippedImages, 1)
+}
+
+func TestGeneratePruneSystemCommand(t *testing.T) {
+	assert.Equal(t, "docker container prune -f && docker network prune -f && docker builder prune -f", (&PruneOptions{}).generatePruneSystemCommand())
+	assert.Equal(t, "docker container prune -f --filter until=24h0m0s && docker network prune -f --filter until=24h0m0s && docker builder prune -f --filter until=24h0m0s",
+		(&PruneOptions{Until: 24 * time.Hour}).generatePruneSystemCommand())
+}
@@ -0,0 +1,202 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// pullImagesTimeout is the maximum time allowed to pull the images on all nodes
+	pullImagesTimeout = 1 * time.Hour
+
+	// defaultPullBackoff is the default delay before the first retry of a failed pull (doubled at each retry)
+	defaultPullBackoff = 5 * time.Second
+)
+
+var (
+	// permanentPullErrors are the messages of the pull errors that will not be fixed by a retry (authentication, unknown image)
+	permanentPullErrors = []string{"unauthorized", "authentication required", "pull access denied", "not found", "manifest unknown", "invalid reference format"}
+)
+
+// PullOptions contain the options of the images pull on the nodes
+type PullOptions struct {
+	MaxConcurrentNodes int           // maximum number of nodes pulling at the same time (unlimited if <= 0)
+	Stagger            time.Duration // delay between the start of two nodes
+	Timeout            time.Duration // maximum time allowed for each pull attempt (no timeout if not set)
+	Retries            int           // number of retries of a failed pull (network errors only)
+	Backoff            time.Duration // delay before the first retry, doubled at each retry (default if not set)
+	Selector           *NodeSelector // nodes pulling the images (all nodes if empty)
+}
+
+// ImagePull contain the result of an image pull on a node
+type ImagePull struct {
+	Image    string
+	Duration time.Duration
+	Attempts int
+	Err      error
+}
+
+// isPermanentPullError returns true if the pull error will not be fixed by a retry (authentication or unknown image), false otherwise
+func isPermanentPullError(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, e := range permanentPullErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// generatePullCommand returns the command used to pull the image variant of the platform (stopped after the timeout if set, the variant of the node if the platform is empty)
+func generatePullCommand(image string, timeout time.Duration, platform string) string {
+	if timeout <= 0 {
+		return fmt.Sprintf("docker pull%s %s", container.PlatformFlag(platform), image)
+	}
+
+	return fmt.Sprintf("timeout %d docker pull%s %s", int(timeout.Seconds()), container.PlatformFlag(platform), image)
+}
+
+// retryDelay returns the delay before the given retry (starting at 1)
+func (o *PullOptions) retryDelay(retry int) time.Duration {
+	backoff := o.Backoff
+	if backoff <= 0 {
+		backoff = defaultPullBackoff
+	}
+
+	return backoff * time.Duration(1<<uint(retry-1))
+}
+
+// pullLimiter limit the number of nodes pulling at the same time and the delay between the start of two nodes
+type pullLimiter struct {
+	slots   chan struct{}
+	stagger time.Duration
+
+	mu        sync.Mutex
+	nextStart time.Time
+}
+
+// newPullLimiter returns a limiter allowing the given number of concurrent nodes (unlimited if <= 0), started with the given delay
+func newPullLimiter(maxConcurrentNodes int, nbNodes int, stagger time.Duration) *pullLimiter {
+	if maxConcurrentNodes <= 0 || maxConcurrentNodes > nbNodes {
+		maxConcurrentNodes = nbNodes
+	}
+
+	return &pullLimiter{
+		slots:   make(chan struct{}, maxConcurrentNodes),
+		stagger: stagger,
+	}
+}
+
+// acquire wait for a free slot and for the stagger delay since the previous start
+func (l *pullLimiter) acquire() {
+	l.slots <- struct{}{}
+
+	l.mu.Lock()
+	start := l.nextStart
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+	l.nextStart = start.Add(l.stagger)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(start))
+}
+
+// release free the slot of a node
+func (l *pullLimiter) release() {
+	<-l.slots
+}
+
+// pullImage pull the image variant of the platform on the node, retrying on network errors
+func pullImage(h *host.Host, image string, platform string, opts PullOptions) ImagePull {
+	p := ImagePull{Image: image}
+	start := time.Now()
+
+	for {
+		p.Attempts++
+		out, err := h.RunSSHCommand(generatePullCommand(image, opts.Timeout, platform))
+		if err == nil {
+			p.Err = nil
+			break
+		}
+		p.Err = fmt.Errorf("Failed to pull image '%s' (attempt %d): '%s'", image, p.Attempts, err)
+
+		// stop on permanent errors or when the retries are exhausted
+		if isPermanentPullError(err.Error()+out) || p.Attempts > opts.Retries {
+			break
+		}
+
+		time.Sleep(opts.retryDelay(p.Attempts))
+	}
+
+	p.Duration = time.Since(start)
+	return p
+}
+
+// pullImages pull the images variant of the platform on the node and returns the result of each pull
+func pullImages(h *host.Host, images []string, platform string, opts PullOptions) []ImagePull {
+	pulls := []ImagePull{}
+	for _, image := range images {
+		pulls = append(pulls, pullImage(h, image, platform, opts))
+	}
+
+	return pulls
+}
+
+// PullImages pull the images on the selected nodes (all nodes if the selector is empty) for the platform of each node, limiting the number of nodes pulling at the same time and retrying the pulls failing with network errors
+// The result of each pull (time taken, attempts and error) is returned by machine name
+func (c *Cluster) PullImages(images []string, opts PullOptions) (map[string][]ImagePull, error) {
+	if err := c.checkSelection(opts.Selector); err != nil {
+		return nil, err
+	}
+
+	limiter := newPullLimiter(opts.MaxConcurrentNodes, len(c.selectNodes(opts.Selector)), opts.Stagger)
+
+	var mu sync.Mutex
+	results := make(map[string][]ImagePull)
+
+	errs := c.runOnSelectedNodes(opts.Selector, pullImagesTimeout, func(n *Node, h *host.Host) error {
+		limiter.acquire()
+		defer limiter.release()
+
+		pulls := pullImages(h, images, n.platform(), opts)
+
+		mu.Lock()
+		results[n.MachineName] = pulls
+		mu.Unlock()
+
+		// report all pulls before returning the first error
+		var err error
+		for _, p := range pulls {
+			if p.Err != nil {
+				log.Errorf("Image '%s' pull failed on node '%s' after %d attempt(s): '%s'", p.Image, n.MachineName, p.Attempts, p.Err)
+				if err == nil {
+					err = p.Err
+				}
+				continue
+			}
+
+			log.Infof("Image '%s' pulled on node '%s' in %s (%d attempt(s))", p.Image, n.MachineName, p.Duration, p.Attempts)
+		}
+
+		return err
+	})
+
+	// copy the results to not race with the nodes still pulling after a timeout
+	mu.Lock()
+	defer mu.Unlock()
+
+	pulls := make(map[string][]ImagePull)
+	for machineName, p := range results {
+		pulls[machineName] = p
+	}
+
+	return pulls, fleetError("Images pull", errs)
+}
@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullLimiterConcurrency(t *testing.T) {
+	limiter := newPullLimiter(2, 6, 0)
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			limiter.acquire()
+			defer limiter.release()
+
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestPullLimiterUnlimited(t *testing.T) {
+	assert.Equal(t, 4, cap(newPullLimiter(0, 4, 0).slots))
+	assert.Equal(t, 4, cap(newPullLimiter(10, 4, 0).slots))
+}
+
+func TestPullLimiterStagger(t *testing.T) {
+	limiter := newPullLimiter(3, 3, 20*time.Millisecond)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		limiter.acquire()
+	}
+
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+}
+
+func TestIsPermanentPullError(t *testing.T) {
+	assert.True(t, isPermanentPullError("Error response from daemon: pull access denied for foo, repository does not exist"))
+	assert.True(t, isPermanentPullError("Error response from daemon: manifest for nginx:nope not found"))
+	assert.True(t, isPermanentPullError("unauthorized: authentication required"))
+	assert.False(t, isPermanentPullError("Error response from daemon: Get https://registry-1.docker.io/v2/: net/http: TLS handshake timeout"))
+	assert.False(t, isPermanentPullError("exit status 124"))
+}
+
+func TestGeneratePullCommand(t *testing.T) {
+	assert.Equal(t, "docker pull nginx:alpine", generatePullCommand("nginx:alpine", 0, ""))
+	assert.Equal(t, "timeout 120 docker pull nginx:alpine", generatePullCommand("nginx:alpine", 2*time.Minute, ""))
+	assert.Equal(t, "timeout 120 docker pull --platform=linux/arm64 nginx:alpine", generatePullCommand("nginx:alpine", 2*time.Minute, "linux/arm64"))
+}
+
+func TestPullOptionsRetryDelay(t *testing.T) {
+	opts := &PullOptions{}
+	assert.Equal(t, defaultPullBackoff, opts.retryDelay(1))
+
+	opts = &PullOptions{Backoff: time.Second}
+	assert.Equal(t, time.Second, opts.retryDelay(1))
+	assert.Equal(t, 4*time.Second, opts.retryDelay(3))
+}
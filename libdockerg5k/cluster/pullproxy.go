@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/registry"
+	"github.com/docker/machine/libmachine/host"
+)
+
+// engineProxyCommand returns the HTTP and HTTPS proxies used by the running Engine
+const engineProxyCommand = "docker info --format '{{.HTTPProxy}} {{.HTTPSProxy}}'"
+
+// pullNoProxy are the destinations reached by the Engine without the pull proxy (the local host, the registry mirror of the cluster and the Grid5000 services)
+var pullNoProxy = []string{"localhost", "127.0.0.1", registry.Hostname, ".grid5000.fr"}
+
+// validatePullProxy check the pull proxy is an HTTP(S) URL
+func validatePullProxy(proxy string) error {
+	u, err := url.Parse(proxy)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("The Engine pull proxy is not a valid HTTP(S) URL: '%s' (ex: http://proxy:3128)", proxy)
+	}
+
+	return nil
+}
+
+// redactedPullProxy returns the pull proxy URL without its password (for the logs and the inventory)
+func redactedPullProxy(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return ""
+	}
+
+	return u.Redacted()
+}
+
+// pullProxySettings returns the proxies settings of the Docker Engine configuration, only used by the Engine itself (ex: image pulls) and not by the containers nor the host
+func pullProxySettings(proxy string) map[string]interface{} {
+	return map[string]interface{}{
+		"proxies": map[string]string{
+			"http-proxy":  proxy,
+			"https-proxy": proxy,
+			"no-proxy":    strings.Join(pullNoProxy, ","),
+		},
+	}
+}
+
+// applyPullProxy set the pull proxy in the Engine configuration, restart the Engine and check it uses the proxy (if configured)
+func (n *Node) applyPullProxy(h *host.Host) error {
+	proxy := n.clusterConfig.EnginePullProxy
+	if proxy == "" {
+		return nil
+	}
+
+	// the proxies are only supported in the configuration file by the Docker Engine 23.0 and later
+	if err := updateDaemonConfig(h, pullProxySettings(proxy)); err != nil {
+		return err
+	}
+
+	n.logf("Engine pull proxy: %s", redactedPullProxy(proxy))
+	if err := n.restartEngine(h); err != nil {
+		return err
+	}
+
+	out, err := h.RunSSHCommand(engineProxyCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the Engine proxies: '%s'", err)
+	}
+
+	if strings.TrimSpace(out) == "" {
+		return fmt.Errorf("The Engine pull proxy is not used after the Engine restart (needs Docker Engine 23.0 or later)")
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// SiteQuota contain the resources usage of the user on a site and the remaining core-hours of its allowance
+type SiteQuota struct {
+	g5k.SiteUsage
+
+	// core-hours allowance of the site (from the cluster configuration, the Grid5000 API does not expose the allowances) and remaining core-hours
+	Quota     float64 `json:"quota,omitempty"`
+	Remaining float64 `json:"remaining,omitempty"`
+
+	// the usage of the site is not available (ex: the jobs API is not reachable), the error is kept
+	Error string `json:"error,omitempty"`
+}
+
+// HasQuota returns true if the usage and the allowance of the site are known, false otherwise
+func (s *SiteQuota) HasQuota() bool {
+	return s.Error == "" && s.Quota > 0
+}
+
+// QuotaReport contain the resources usage of the user by site
+type QuotaReport struct {
+	Sites map[string]*SiteQuota `json:"sites"`
+}
+
+// validateCoreHoursQuota check the core-hours allowances are positive
+func validateCoreHoursQuota(quota map[string]float64) error {
+	for site, hours := range quota {
+		if hours <= 0 {
+			return fmt.Errorf("The core-hours quota of site '%s' must be positive: '%v'", site, hours)
+		}
+	}
+
+	return nil
+}
+
+// newSiteQuota returns the quota of the site from its usage (or the error getting it) and its allowance (0 if unknown)
+func newSiteQuota(site string, usage *g5k.SiteUsage, err error, quota float64) *SiteQuota {
+	if err != nil {
+		return &SiteQuota{SiteUsage: g5k.SiteUsage{Site: site}, Quota: quota, Error: err.Error()}
+	}
+
+	s := &SiteQuota{SiteUsage: *usage, Quota: quota}
+	if quota > 0 {
+		s.Remaining = quota - usage.CoreHoursReserved
+	}
+
+	return s
+}
+
+// QuotaUsage returns the resources usage of the user (running and waiting jobs, core-hours) and the remaining core-hours allowance on the given sites
+// The sites whose usage is not available are reported with their error instead of failing the whole report
+func (c *GlobalConfig) QuotaUsage(sites []string) (*QuotaReport, error) {
+	g5kAPI, err := c.g5kAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(sites)
+	report := &QuotaReport{Sites: make(map[string]*SiteQuota)}
+	for _, site := range sites {
+		usage, err := g5kAPI.GetSiteUsage(site)
+		if err != nil {
+			log.Warnf("The resources usage of site '%s' is not available: '%s'", site, err)
+		}
+
+		report.Sites[site] = newSiteQuota(site, usage, err, c.CoreHoursQuota[site])
+	}
+
+	return report, nil
+}
+
+// CheckReservation check the planned reservation (number of nodes and walltime, format: hh:mm:ss) fits in the remaining core-hours of the site
+// The planned core-hours are estimated with the largest nodes of the site, the check is skipped (with a warning) if the usage or the allowance of the site is not known
+func (r *QuotaReport) CheckReservation(site string, nbNodes int, walltime string) error {
+	d, err := g5k.ParseWalltime(walltime)
+	if err != nil {
+		return err
+	}
+
+	s, ok := r.Sites[site]
+	if !ok || !s.HasQuota() || s.MaxNodeCores == 0 {
+		log.Warnf("No quota information for site '%s', the reservation of %d nodes is not checked", site, nbNodes)
+		return nil
+	}
+
+	planned := float64(nbNodes*s.MaxNodeCores) * d.Hours()
+	if planned > s.Remaining {
+		return fmt.Errorf("The reservation of %d nodes for %s on site '%s' needs up to %.1f core-hours, only %.1f remaining (quota: %.1f, reserved: %.1f)", nbNodes, walltime, site, planned, s.Remaining, s.Quota, s.CoreHoursReserved)
+	}
+
+	return nil
+}
+
+// SiteCoreHours contain the estimated core-hours of the reservation of a site
+type SiteCoreHours struct {
+	Nodes     int     `json:"nodes"`
+	NodeCores int     `json:"node_cores"` // cores of the largest node of the site (from its hardware description)
+	CoreHours float64 `json:"core_hours"`
+}
+
+// CoreHoursEstimate contain the estimated core-hours of a reservation (upper bound, the reserved nodes are not known yet)
+type CoreHoursEstimate struct {
+	Walltime  string                    `json:"walltime"`
+	Sites     map[string]*SiteCoreHours `json:"sites"`
+	CoreHours float64                   `json:"core_hours"`
+}
+
+// String returns the details of the estimate by site (sorted)
+func (e *CoreHoursEstimate) String() string {
+	sites := []string{}
+	for site := range e.Sites {
+		sites = append(sites, site)
+	}
+	sort.Strings(sites)
+
+	details := []string{}
+	for _, site := range sites {
+		s := e.Sites[site]
+		details = append(details, fmt.Sprintf("%s: %d nodes x %d cores", site, s.Nodes, s.NodeCores))
+	}
+
+	return fmt.Sprintf("%.1f core-hours for %s (%s)", e.CoreHours, e.Walltime, strings.Join(details, ", "))
+}
+
+// maxNodeCores returns the number of cores of the largest node from the hardware description of the Reference API nodes (0 if unknown)
+func maxNodeCores(nodes []g5k.ReferenceNode) int {
+	cores := 0
+	for i := range nodes {
+		if hw := newHardwareInfo(&nodes[i]); hw.Cores > cores {
+			cores = hw.Cores
+		}
+	}
+
+	return cores
+}
+
+// newCoreHoursEstimate returns the estimated core-hours of the reservation (number of nodes by site) for the walltime (format: hh:mm:ss) with the cores of the largest node of each site
+func newCoreHoursEstimate(reservations map[string]int, nodeCores map[string]int, walltime string) (*CoreHoursEstimate, error) {
+	d, err := g5k.ParseWalltime(walltime)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &CoreHoursEstimate{Walltime: walltime, Sites: make(map[string]*SiteCoreHours)}
+	for site, nb := range reservations {
+		cores, ok := nodeCores[site]
+		if !ok || cores == 0 {
+			return nil, fmt.Errorf("The number of cores of the nodes of site '%s' is not known, the core-hours of the reservation can't be estimated", site)
+		}
+
+		s := &SiteCoreHours{Nodes: nb, NodeCores: cores, CoreHours: float64(nb*cores) * d.Hours()}
+		e.Sites[site] = s
+		e.CoreHours += s.CoreHours
+	}
+
+	return e, nil
+}
+
+// checkCoreHoursEstimate check the estimated core-hours of the reservation does not exceed the maximum (only a warning if the overrun is allowed)
+func (c *GlobalConfig) checkCoreHoursEstimate(e *CoreHoursEstimate) error {
+	if c.MaxCoreHours <= 0 || e.CoreHours <= c.MaxCoreHours {
+		return nil
+	}
+
+	if c.AllowCoreHoursOverrun {
+		log.Warnf("The reservation needs up to %s, above the maximum of %.1f core-hours", e, c.MaxCoreHours)
+		return nil
+	}
+
+	return fmt.Errorf("The reservation needs up to %s, above the maximum of %.1f core-hours (the overrun needs to be allowed)", e, c.MaxCoreHours)
+}
+
+// CheckMaxCoreHours estimate the core-hours of the reservation (number of nodes by site) for the cluster walltime with the hardware description of the largest nodes of each site,
+// and check it does not exceed the maximum core-hours of the cluster before reserving (nothing is checked if no maximum is set, the estimate is nil)
+func (c *GlobalConfig) CheckMaxCoreHours(reservations map[string]int) (*CoreHoursEstimate, error) {
+	if c.MaxCoreHours <= 0 {
+		return nil, nil
+	}
+
+	g5kAPI, err := c.g5kAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	nodeCores := make(map[string]int)
+	for site := range reservations {
+		nodes, err := g5kAPI.GetSiteReferenceNodes(site)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to get the description of the nodes of site '%s': '%s'", site, err)
+		}
+
+		nodeCores[site] = maxNodeCores(nodes)
+	}
+
+	e, err := newCoreHoursEstimate(reservations, nodeCores, c.G5kWalltime)
+	if err != nil {
+		return nil, err
+	}
+
+	return e, c.checkCoreHoursEstimate(e)
+}
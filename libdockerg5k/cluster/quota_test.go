@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCoreHoursQuota(t *testing.T) {
+	assert.NoError(t, validateCoreHoursQuota(nil))
+	assert.NoError(t, validateCoreHoursQuota(map[string]float64{"rennes": 100}))
+	assert.Error(t, validateCoreHoursQuota(map[string]float64{"rennes": 0}))
+}
+
+func TestNewSiteQuota(t *testing.T) {
+	s := newSiteQuota("rennes", &g5k.SiteUsage{Site: "rennes", CoreHoursReserved: 40, MaxNodeCores: 16}, nil, 100)
+	assert.True(t, s.HasQuota())
+	assert.Equal(t, 60.0, s.Remaining)
+
+	s = newSiteQuota("rennes", &g5k.SiteUsage{Site: "rennes", CoreHoursReserved: 40}, nil, 0)
+	assert.False(t, s.HasQuota())
+
+	s = newSiteQuota("lille", nil, errors.New("unreachable"), 100)
+	assert.False(t, s.HasQuota())
+	assert.Equal(t, "lille", s.Site)
+	assert.Equal(t, "unreachable", s.Error)
+}
+
+func TestCheckReservation(t *testing.T) {
+	r := &QuotaReport{Sites: map[string]*SiteQuota{
+		"rennes": newSiteQuota("rennes", &g5k.SiteUsage{Site: "rennes", CoreHoursReserved: 40, MaxNodeCores: 16}, nil, 100),
+		"lille":  newSiteQuota("lille", nil, errors.New("unreachable"), 100),
+	}}
+
+	// 2 nodes * 16 cores * 1h = 32 core-hours (60 remaining)
+	assert.NoError(t, r.CheckReservation("rennes", 2, "1:00:00"))
+	assert.Error(t, r.CheckReservation("rennes", 4, "1:00:00"))
+	assert.Error(t, r.CheckReservation("rennes", 2, "invalid"))
+
+	// no quota information
+	assert.NoError(t, r.CheckReservation("lille", 100, "10:00:00"))
+	assert.NoError(t, r.CheckReservation("nancy", 100, "10:00:00"))
+}
+
+func TestMaxNodeCores(t *testing.T) {
+	nodes := []g5k.ReferenceNode{
+		{UID: "paravance-1", Architecture: g5k.ReferenceArchitecture{NbCores: 16}},
+		{UID: "parasilo-1", Architecture: g5k.ReferenceArchitecture{NbCores: 32}},
+	}
+
+	assert.Equal(t, 32, maxNodeCores(nodes))
+	assert.Equal(t, 0, maxNodeCores(nil))
+}
+
+func TestNewCoreHoursEstimate(t *testing.T) {
+	// 4 nodes * 16 cores * 2h + 2 nodes * 32 cores * 2h
+	e, err := newCoreHoursEstimate(map[string]int{"rennes": 4, "nancy": 2}, map[string]int{"rennes": 16, "nancy": 32}, "2:00:00")
+	assert.NoError(t, err)
+	assert.Equal(t, 256.0, e.CoreHours)
+	assert.Equal(t, &SiteCoreHours{Nodes: 4, NodeCores: 16, CoreHours: 128}, e.Sites["rennes"])
+	assert.Equal(t, "256.0 core-hours for 2:00:00 (nancy: 2 nodes x 32 cores, rennes: 4 nodes x 16 cores)", e.String())
+
+	_, err = newCoreHoursEstimate(map[string]int{"lille": 4}, map[string]int{"lille": 0}, "2:00:00")
+	assert.Error(t, err)
+	_, err = newCoreHoursEstimate(map[string]int{"rennes": 4}, map[string]int{"rennes": 16}, "invalid")
+	assert.Error(t, err)
+}
+
+func TestCheckCoreHoursEstimate(t *testing.T) {
+	e := &CoreHoursEstimate{Walltime: "2:00:00", Sites: map[string]*SiteCoreHours{"rennes": {Nodes: 4, NodeCores: 16, CoreHours: 128}}, CoreHours: 128}
+
+	assert.NoError(t, (&GlobalConfig{}).checkCoreHoursEstimate(e))
+	assert.NoError(t, (&GlobalConfig{MaxCoreHours: 200}).checkCoreHoursEstimate(e))
+
+	err := (&GlobalConfig{MaxCoreHours: 100}).checkCoreHoursEstimate(e)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "128.0 core-hours for 2:00:00 (rennes: 4 nodes x 16 cores)")
+
+	assert.NoError(t, (&GlobalConfig{MaxCoreHours: 100, AllowCoreHoursOverrun: true}).checkCoreHoursEstimate(e))
+}
+
+func TestCheckMaxCoreHoursDisabled(t *testing.T) {
+	e, err := (&GlobalConfig{}).CheckMaxCoreHours(map[string]int{"rennes": 1000})
+	assert.NoError(t, err)
+	assert.Nil(t, e)
+}
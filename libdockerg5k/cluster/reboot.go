@@ -0,0 +1,111 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// rebootTimeout is the maximum time allowed for a node to reboot (and its Engine/Swarm mode to be back)
+	rebootTimeout = 15 * time.Minute
+
+	// rebootCommand reboot the node in background (to let the SSH command return before the connection is closed)
+	rebootCommand = "nohup sh -c 'sleep 2; systemctl reboot' >/dev/null 2>&1 &"
+)
+
+// waitForSSHDown wait until the host stop responding to SSH commands or the timeout expire
+func waitForSSHDown(h *host.Host, timeout time.Duration) error {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(2 * time.Second) {
+		if _, err := h.RunSSHCommand("true"); err != nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("The node is still responding after %s", timeout)
+}
+
+// waitForSwarmModeActive wait until the Engine of the host is part of the Swarm mode cluster or the timeout expire
+func waitForSwarmModeActive(h *host.Host, timeout time.Duration) error {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(5 * time.Second) {
+		out, err := h.RunSSHCommand(swarmModeStatusCommand)
+		if err == nil && parseSwarmModeRole(out) != "" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("The node has not rejoined the Swarm mode cluster after %s", timeout)
+}
+
+// rebootProbes wait (within the given timeout) for the node to go down, for its Engine to be back and for it to rejoin the Swarm mode cluster (not checked if nil)
+type rebootProbes struct {
+	down   func(timeout time.Duration) error
+	up     func(timeout time.Duration) error
+	rejoin func(timeout time.Duration) error
+}
+
+// hostRebootProbes returns the reboot probes of the host (the Swarm mode cluster is only rejoined if swarmMode is set)
+func hostRebootProbes(h *host.Host, swarmMode bool) rebootProbes {
+	probes := rebootProbes{
+		down: func(timeout time.Duration) error { return waitForSSHDown(h, timeout) },
+		up:   func(timeout time.Duration) error { return waitForEngine(h, timeout) },
+	}
+	if swarmMode {
+		probes.rejoin = func(timeout time.Duration) error { return waitForSwarmModeActive(h, timeout) }
+	}
+
+	return probes
+}
+
+// waitForReboot run the reboot probes in sequence, sharing the timeout, and returns the observed downtime of the node
+func (n *Node) waitForReboot(probes rebootProbes, timeout time.Duration) (time.Duration, error) {
+	deadline := time.Now().Add(timeout)
+
+	// wait for the node to go down
+	if err := probes.down(time.Until(deadline)); err != nil {
+		return 0, fmt.Errorf("Node '%s' did not reboot: '%s'", n.MachineName, err)
+	}
+	downSince := time.Now()
+
+	// wait for the node SSH and Engine to be back
+	if err := probes.up(time.Until(deadline)); err != nil {
+		return 0, fmt.Errorf("Node '%s' is not back after the reboot: '%s'", n.MachineName, err)
+	}
+
+	// wait for the node to rejoin the Swarm mode cluster
+	if probes.rejoin != nil {
+		if err := probes.rejoin(time.Until(deadline)); err != nil {
+			return 0, fmt.Errorf("Node '%s' is not back after the reboot: '%s'", n.MachineName, err)
+		}
+	}
+
+	return time.Since(downSince), nil
+}
+
+// Reboot reboot the node and, if wait is true, wait until its Engine respond and it has rejoined the Swarm mode cluster (the observed downtime is logged)
+func (n *Node) Reboot(wait bool) error {
+	h, err := n.loadHost()
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Rebooting node '%s' ('%s')...", n.NodeName, n.MachineName)
+	if _, err := h.RunSSHCommand(rebootCommand); err != nil {
+		return fmt.Errorf("Failed to reboot node '%s': '%s'", n.MachineName, err)
+	}
+
+	if !wait {
+		return nil
+	}
+
+	downtime, err := n.waitForReboot(hostRebootProbes(h, n.clusterConfig.SwarmModeGlobalConfig != nil), rebootTimeout)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Node '%s' ('%s') is back after a downtime of %s", n.NodeName, n.MachineName, downtime.Round(time.Second))
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForReboot(t *testing.T) {
+	n := &Node{MachineName: "lille-0"}
+
+	calls := []string{}
+	probe := func(name string) func(timeout time.Duration) error {
+		return func(timeout time.Duration) error {
+			calls = append(calls, name)
+			if name == "up" {
+				time.Sleep(20 * time.Millisecond)
+			}
+			return nil
+		}
+	}
+
+	downtime, err := n.waitForReboot(rebootProbes{down: probe("down"), up: probe("up"), rejoin: probe("rejoin")}, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"down", "up", "rejoin"}, calls)
+	assert.True(t, downtime >= 20*time.Millisecond)
+}
+
+func TestWaitForRebootWithoutSwarmMode(t *testing.T) {
+	n := &Node{MachineName: "lille-0"}
+
+	calls := 0
+	probe := func(timeout time.Duration) error {
+		calls++
+		return nil
+	}
+
+	_, err := n.waitForReboot(rebootProbes{down: probe, up: probe}, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestWaitForRebootTimeout(t *testing.T) {
+	n := &Node{MachineName: "lille-0"}
+
+	// the node goes down late, leaving only the remaining time to come back
+	var upTimeout time.Duration
+	rejoined := false
+	_, err := n.waitForReboot(rebootProbes{
+		down: func(timeout time.Duration) error {
+			time.Sleep(30 * time.Millisecond)
+			return nil
+		},
+		up: func(timeout time.Duration) error {
+			upTimeout = timeout
+			time.Sleep(timeout)
+			return fmt.Errorf("The Docker Engine is not responding after %s", timeout)
+		},
+		rejoin: func(timeout time.Duration) error {
+			rejoined = true
+			return nil
+		},
+	}, 50*time.Millisecond)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Node 'lille-0' is not back after the reboot")
+	assert.True(t, upTimeout < 50*time.Millisecond)
+	assert.False(t, rejoined)
+}
+
+func TestWaitForRebootNotDown(t *testing.T) {
+	n := &Node{MachineName: "lille-0"}
+
+	up := false
+	_, err := n.waitForReboot(rebootProbes{
+		down: func(timeout time.Duration) error {
+			return fmt.Errorf("The node is still responding after %s", timeout)
+		},
+		up: func(timeout time.Duration) error {
+			up = true
+			return nil
+		},
+	}, time.Minute)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Node 'lille-0' did not reboot")
+	assert.False(t, up)
+}
+
+func TestHostRebootProbes(t *testing.T) {
+	h := &host.Host{Name: "lille-0"}
+	assert.Nil(t, hostRebootProbes(h, false).rejoin)
+	assert.NotNil(t, hostRebootProbes(h, true).rejoin)
+}
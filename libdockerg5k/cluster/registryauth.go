@@ -0,0 +1,132 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// dockerHubRegistry is the name of the Docker Hub registry, stored with its index address in the Docker client configuration
+	dockerHubRegistry     = "docker.io"
+	dockerHubIndexAddress = "https://index.docker.io/v1/"
+
+	// dockerClientConfigPath is the Docker client configuration file of the SSH user on the nodes
+	dockerClientConfigPath = "~/.docker/config.json"
+
+	// redactedSecret replace the credentials in the error messages
+	redactedSecret = "<redacted>"
+)
+
+var (
+	// regexRegistryHostname match a registry hostname with an optional port (ex: registry.example.com:5000)
+	regexRegistryHostname = regexp.MustCompile("^[[:alnum:]]([[:alnum:].-]*[[:alnum:]])?(:[[:digit:]]+)?$")
+)
+
+// RegistryAuth contain the credentials of a private registry (username/password for the basic authentication, or identity token)
+// the secrets are excluded from the configuration snapshots (json:"-")
+type RegistryAuth struct {
+	Username      string
+	Password      string `json:"-"`
+	IdentityToken string `json:"-"`
+}
+
+// Validate check the registry hostname and that the credentials use only one of the authentication methods
+func (a *RegistryAuth) Validate(registry string) error {
+	if !regexRegistryHostname.MatchString(registry) {
+		return fmt.Errorf("Invalid registry hostname: '%s' (format: hostname[:port], ex: registry.example.com:5000)", registry)
+	}
+
+	basic := a.Username != "" || a.Password != ""
+	switch {
+	case basic && a.IdentityToken != "":
+		return fmt.Errorf("The credentials of registry '%s' can't use both a username/password and an identity token", registry)
+	case basic && (a.Username == "" || a.Password == ""):
+		return fmt.Errorf("The credentials of registry '%s' need both a username and a password", registry)
+	case !basic && a.IdentityToken == "":
+		return fmt.Errorf("The credentials of registry '%s' need a username/password or an identity token", registry)
+	}
+
+	return nil
+}
+
+// dockerConfigAuth contain the credentials of a registry in the Docker client configuration file
+type dockerConfigAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// generateDockerConfig returns the Docker client configuration file (config.json) with the credentials of the registries
+func generateDockerConfig(auths map[string]RegistryAuth) ([]byte, error) {
+	entries := make(map[string]dockerConfigAuth)
+	for registry, a := range auths {
+		if registry == dockerHubRegistry {
+			registry = dockerHubIndexAddress
+		}
+
+		if a.IdentityToken != "" {
+			entries[registry] = dockerConfigAuth{IdentityToken: a.IdentityToken}
+		} else {
+			entries[registry] = dockerConfigAuth{Auth: base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + a.Password))}
+		}
+	}
+
+	return json.MarshalIndent(map[string]interface{}{"auths": entries}, "", "\t")
+}
+
+// generateDockerConfigCommand returns the command used to write the (base64 encoded) Docker client configuration file, only readable by its owner
+func generateDockerConfigCommand(encodedConfig string) string {
+	return fmt.Sprintf("mkdir -p ~/.docker && echo '%s' | base64 -d >%s && chmod 600 %s", encodedConfig, dockerClientConfigPath, dockerClientConfigPath)
+}
+
+// redactSecrets returns the message with the given secrets replaced
+func redactSecrets(msg string, secrets ...string) string {
+	for _, s := range secrets {
+		if s != "" {
+			msg = strings.Replace(msg, s, redactedSecret, -1)
+		}
+	}
+
+	return msg
+}
+
+// sortedRegistries returns the registries (sorted) with credentials
+func (c *GlobalConfig) sortedRegistries() []string {
+	registries := []string{}
+	for registry := range c.RegistryAuths {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+
+	return registries
+}
+
+// configureRegistryAuths write the registries credentials to the Docker client configuration of the node (used by the pulls on the node)
+// the credentials are never logged, they are redacted from the returned error
+func (n *Node) configureRegistryAuths(h *host.Host) error {
+	if len(n.clusterConfig.RegistryAuths) == 0 {
+		return nil
+	}
+
+	config, err := generateDockerConfig(n.clusterConfig.RegistryAuths)
+	if err != nil {
+		return fmt.Errorf("Unable to generate the Docker client configuration: '%s'", err)
+	}
+
+	encodedConfig := base64.StdEncoding.EncodeToString(config)
+	if _, err := h.RunSSHCommand(generateDockerConfigCommand(encodedConfig)); err != nil {
+		secrets := []string{encodedConfig}
+		for _, a := range n.clusterConfig.RegistryAuths {
+			secrets = append(secrets, a.Password, a.IdentityToken)
+		}
+
+		return fmt.Errorf("Failed to write the credentials of the registries %s: '%s'", strings.Join(n.clusterConfig.sortedRegistries(), ", "), redactSecrets(err.Error(), secrets...))
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryAuthValidate(t *testing.T) {
+	assert.NoError(t, (&RegistryAuth{Username: "user", Password: "pass"}).Validate("registry.example.com:5000"))
+	assert.NoError(t, (&RegistryAuth{IdentityToken: "token"}).Validate("docker.io"))
+}
+
+func TestRegistryAuthValidateIncorrect(t *testing.T) {
+	assert.Error(t, (&RegistryAuth{Username: "user", Password: "pass"}).Validate("https://registry.example.com"))
+	assert.Error(t, (&RegistryAuth{Username: "user", Password: "pass"}).Validate("registry.example.com:port"))
+	assert.Error(t, (&RegistryAuth{Username: "user", Password: "pass", IdentityToken: "token"}).Validate("docker.io"))
+	assert.Error(t, (&RegistryAuth{Username: "user"}).Validate("docker.io"))
+	assert.Error(t, (&RegistryAuth{}).Validate("docker.io"))
+}
+
+func TestGenerateDockerConfig(t *testing.T) {
+	data, err := generateDockerConfig(map[string]RegistryAuth{
+		"registry.example.com:5000": {Username: "user", Password: "pass"},
+		"docker.io":                 {IdentityToken: "token"},
+	})
+	assert.NoError(t, err)
+
+	var config map[string]map[string]map[string]string
+	assert.NoError(t, json.Unmarshal(data, &config))
+	assert.Equal(t, map[string]map[string]string{
+		"registry.example.com:5000":   {"auth": "dXNlcjpwYXNz"},
+		"https://index.docker.io/v1/": {"identitytoken": "token"},
+	}, config["auths"])
+}
+
+func TestRedactSecrets(t *testing.T) {
+	assert.Equal(t, "echo '<redacted>' failed: <redacted>", redactSecrets("echo 'c2VjcmV0' failed: secret", "c2VjcmV0", "secret", ""))
+}
@@ -0,0 +1,246 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// defaultRepairInterval is the default delay between two checks of the nodes jobs by the supervisor
+	defaultRepairInterval = 1 * time.Minute
+
+	// defaultMaxRepairs is the default maximum number of replacement attempts of the supervisor
+	defaultMaxRepairs = 3
+)
+
+// RepairEvent contain a node loss detected by the supervisor and the result of its replacement
+type RepairEvent struct {
+	Time        time.Time `json:"time"`
+	MachineName string    `json:"machine_name"` // lost node
+	Site        string    `json:"site"`
+	Role        string    `json:"role"`
+	JobID       int       `json:"job_id"`
+	JobState    string    `json:"job_state"`
+	Attempt     int       `json:"attempt,omitempty"`     // replacement attempt of the supervisor (not set if only detected)
+	Replacement string    `json:"replacement,omitempty"` // machine name of the replacement node (only set if it succeeded)
+	Error       string    `json:"error,omitempty"`
+}
+
+// repairOps contain the operations of the supervisor on the Grid5000 jobs and the nodes
+type repairOps struct {
+	// getJob returns the state of the Grid5000 job
+	getJob func(site string, jobID int) (*g5k.JobState, error)
+
+	// retire remove the lost node from the Swarm mode cluster and the machines storage
+	retire func(n *Node)
+
+	// replace reserve, provision and join a new node of the role on the site, and returns its machine name
+	replace func(site string, role string) (string, error)
+}
+
+// repairInterval returns the delay between two checks of the nodes jobs by the supervisor
+func (c *GlobalConfig) repairInterval() time.Duration {
+	if c.RepairInterval > 0 {
+		return c.RepairInterval
+	}
+
+	return defaultRepairInterval
+}
+
+// maxRepairs returns the maximum number of replacement attempts of the supervisor
+func (c *GlobalConfig) maxRepairs() int {
+	if c.MaxRepairs > 0 {
+		return c.MaxRepairs
+	}
+
+	return defaultMaxRepairs
+}
+
+// lostNodes returns the nodes (sorted by machine name) whose Grid5000 job is neither running nor suspended (ex: preempted besteffort job), and the state of their job
+// the jobs whose state can't be retrieved are not considered lost
+func lostNodes(jobs map[jobKey][]string, getJob func(site string, jobID int) (*g5k.JobState, error)) ([]string, map[string]string) {
+	lost := []string{}
+	states := make(map[string]string)
+	for k, machineNames := range jobs {
+		job, err := getJob(k.site, k.jobID)
+		if err != nil {
+			log.Warnf("Unable to get the job '%d' on site '%s' of the supervised nodes: '%s'", k.jobID, k.site, err)
+			continue
+		}
+
+		if job.IsRunning() || job.IsSuspended() {
+			continue
+		}
+
+		for _, machineName := range machineNames {
+			lost = append(lost, machineName)
+			states[machineName] = job.State
+		}
+	}
+	sort.Strings(lost)
+
+	return lost, states
+}
+
+// isReplaceable returns true if the lost node can be replaced by a new node, false otherwise (the registry, ingress and bastion nodes hold the cluster services)
+func (c *Cluster) isReplaceable(n *Node) bool {
+	return !(c.Config.DeployRegistry && n.MachineName == c.Config.RegistryNode) && !n.isIngressNode() && !n.isBastionNode()
+}
+
+// retireLostNode remove the lost node from the Swarm mode cluster (demoting it first if it is a manager) and its Docker Machine, on a best effort basis (the node is unreachable)
+func (c *Cluster) retireLostNode(n *Node) {
+	manager, err := c.swarmManager(n.MachineName)
+	if err != nil {
+		log.Warnf("Unable to remove the lost node '%s' from the Swarm mode cluster: '%s'", n.MachineName, err)
+	} else {
+		// the hostname of the node is its machine name
+		if n.isSwarmMaster() {
+			if _, err := manager.RunSSHCommand(fmt.Sprintf("docker node demote %s", n.MachineName)); err != nil {
+				log.Warnf("Unable to demote the lost Swarm manager '%s': '%s'", n.MachineName, err)
+			}
+		}
+
+		if _, err := manager.RunSSHCommand(fmt.Sprintf("docker node rm --force %s", n.MachineName)); err != nil {
+			log.Warnf("Unable to remove the lost node '%s' from the Swarm mode cluster: '%s'", n.MachineName, err)
+		}
+	}
+
+	// only the machine storage entry is deleted (the driver is not called), the job of the lost node is already terminated
+	if err := c.Config.LibMachineClient.Remove(n.MachineName); err != nil {
+		log.Warnf("Unable to remove the machine of the lost node '%s': '%s'", n.MachineName, err)
+	}
+}
+
+// replaceNode reserve and provision a new node with the role on the site, joining the Swarm mode cluster, and returns its machine name
+func (c *Cluster) replaceNode(g5kAPI *g5k.G5K, site string, role string) (string, error) {
+	manager, err := c.swarmManager()
+	if err != nil {
+		return "", err
+	}
+
+	managers, workers := 0, 1
+	if role == NodeRoleManager {
+		managers, workers = 1, 0
+	}
+
+	existing := make(map[string]bool)
+	for machineName := range c.Nodes {
+		existing[machineName] = true
+	}
+
+	if err := c.addNodes(g5kAPI, manager, site, managers, workers); err != nil {
+		return "", err
+	}
+
+	for machineName := range c.Nodes {
+		if !existing[machineName] {
+			return machineName, nil
+		}
+	}
+
+	return "", fmt.Errorf("The replacement node was not added to the cluster")
+}
+
+// checkLostNodes detect the lost nodes, remove them from the cluster and returns their loss event (the nodes which can't be replaced are kept and only reported once)
+func (c *Cluster) checkLostNodes(ops *repairOps, reported map[string]bool, report func(RepairEvent)) []RepairEvent {
+	machineNames, states := lostNodes(c.nodesByJob(), ops.getJob)
+
+	lost := []RepairEvent{}
+	for _, machineName := range machineNames {
+		n := c.Nodes[machineName]
+		e := RepairEvent{Time: time.Now(), MachineName: machineName, Site: n.G5kSite, Role: n.Role, JobID: n.G5kJobID, JobState: states[machineName]}
+
+		if !c.Config.AutoRepair || !c.isReplaceable(n) {
+			if !reported[machineName] {
+				reported[machineName] = true
+				if c.Config.AutoRepair {
+					e.Error = "The registry, ingress and bastion nodes can't be replaced"
+				}
+				log.Warnf("The node '%s' is lost (job '%d' on site '%s' is '%s')", machineName, e.JobID, e.Site, e.JobState)
+				report(e)
+			}
+			continue
+		}
+
+		log.Warnf("The node '%s' is lost (job '%d' on site '%s' is '%s'), replacing it...", machineName, e.JobID, e.Site, e.JobState)
+		ops.retire(n)
+		c.forgetNode(machineName)
+		lost = append(lost, e)
+	}
+
+	return lost
+}
+
+// supervise check the nodes jobs at each interval and replace the lost nodes until the context is done or the repair attempts are exhausted
+func (c *Cluster) supervise(ctx context.Context, ops *repairOps, report func(RepairEvent)) error {
+	pending := []RepairEvent{}
+	reported := make(map[string]bool)
+	attempts := 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(c.Config.repairInterval()):
+		}
+
+		pending = append(pending, c.checkLostNodes(ops, reported, report)...)
+
+		// the failed replacements are retried at the next check
+		remaining := []RepairEvent{}
+		for _, e := range pending {
+			if attempts >= c.Config.maxRepairs() {
+				return fmt.Errorf("The node '%s' can't be replaced: the %d repair attempts are exhausted", e.MachineName, c.Config.maxRepairs())
+			}
+			attempts++
+
+			e.Time = time.Now()
+			e.Attempt = attempts
+			e.Error = ""
+
+			replacement, err := ops.replace(e.Site, e.Role)
+			if err != nil {
+				e.Error = err.Error()
+				log.Errorf("Unable to replace the lost node '%s' (attempt %d/%d): '%s'", e.MachineName, attempts, c.Config.maxRepairs(), err)
+				remaining = append(remaining, e)
+			} else {
+				e.Replacement = replacement
+				log.Infof("The lost node '%s' is replaced by '%s'", e.MachineName, replacement)
+			}
+			report(e)
+		}
+		pending = remaining
+	}
+}
+
+// Supervise watch the Grid5000 jobs of the nodes and, with AutoRepair, replace the lost nodes (job preempted or terminated) by a new node of the same role
+// reserved on the same site, keeping the cluster size. Each detected loss and replacement attempt is given to the report function (if not nil).
+// It returns when the context is done, or with an error when the repair attempts (MaxRepairs) are exhausted. The cluster must not be modified while supervised.
+func (c *Cluster) Supervise(ctx context.Context, report func(RepairEvent)) error {
+	if c.Config.SwarmModeGlobalConfig == nil {
+		return fmt.Errorf("Supervising the cluster requires Swarm mode")
+	}
+
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		return err
+	}
+
+	if report == nil {
+		report = func(RepairEvent) {}
+	}
+
+	c.SyncNodeRoles()
+	return c.supervise(ctx, &repairOps{
+		getJob: g5kAPI.GetJob,
+		retire: c.retireLostNode,
+		replace: func(site string, role string) (string, error) {
+			return c.replaceNode(g5kAPI, site, role)
+		},
+	}, report)
+}
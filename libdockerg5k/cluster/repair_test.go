@@ -0,0 +1,128 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRepairCluster returns a cluster with a manager (job 1) and two workers (job 2) on site lille
+func newTestRepairCluster() *Cluster {
+	c := NewCluster(&GlobalConfig{SwarmMasterNode: []string{"lille-0"}, HostsLookupTable: map[string]string{"lille-1": "1.2.3.4"}, RepairInterval: time.Millisecond})
+	c.Nodes["lille-0"] = &Node{clusterConfig: c.Config, MachineName: "lille-0", G5kSite: "lille", G5kJobID: 1, Role: NodeRoleManager}
+	c.Nodes["lille-1"] = &Node{clusterConfig: c.Config, MachineName: "lille-1", G5kSite: "lille", G5kJobID: 2, Role: NodeRoleWorker}
+	c.Nodes["lille-2"] = &Node{clusterConfig: c.Config, MachineName: "lille-2", G5kSite: "lille", G5kJobID: 2, Role: NodeRoleWorker}
+
+	return c
+}
+
+// testJobStates returns the getJob operation returning the states of the jobs (the missing jobs fail)
+func testJobStates(states map[int]string) func(string, int) (*g5k.JobState, error) {
+	return func(site string, jobID int) (*g5k.JobState, error) {
+		state, ok := states[jobID]
+		if !ok {
+			return nil, fmt.Errorf("API error")
+		}
+		return &g5k.JobState{UID: jobID, State: state}, nil
+	}
+}
+
+func TestLostNodes(t *testing.T) {
+	c := newTestRepairCluster()
+
+	lost, states := lostNodes(c.nodesByJob(), testJobStates(map[int]string{1: "running", 2: "error"}))
+	assert.Equal(t, []string{"lille-1", "lille-2"}, lost)
+	assert.Equal(t, "error", states["lille-1"])
+
+	// suspended jobs and API errors are not losses
+	lost, _ = lostNodes(c.nodesByJob(), testJobStates(map[int]string{2: "suspended"}))
+	assert.Empty(t, lost)
+}
+
+func TestSuperviseReplaceLostNodes(t *testing.T) {
+	c := newTestRepairCluster()
+	c.Config.AutoRepair = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	retired := []string{}
+	events := []RepairEvent{}
+	ops := &repairOps{
+		getJob: testJobStates(map[int]string{1: "running", 2: "error"}),
+		retire: func(n *Node) { retired = append(retired, n.MachineName) },
+		replace: func(site string, role string) (string, error) {
+			machineName := c.nextMachineNames(site, 1)[0]
+			c.Nodes[machineName] = &Node{clusterConfig: c.Config, MachineName: machineName, G5kSite: site, G5kJobID: 3, Role: role}
+			return machineName, nil
+		},
+	}
+
+	err := c.supervise(ctx, ops, func(e RepairEvent) {
+		events = append(events, e)
+		if len(events) == 2 {
+			cancel()
+		}
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"lille-1", "lille-2"}, retired)
+	assert.Len(t, c.Nodes, 3)
+	assert.NotContains(t, c.Config.HostsLookupTable, "lille-1")
+
+	// the machine names of the removed nodes are reused
+	assert.Equal(t, 3, c.Nodes["lille-1"].G5kJobID)
+	assert.Equal(t, 3, c.Nodes["lille-2"].G5kJobID)
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, "lille-1", events[0].MachineName)
+	assert.Equal(t, NodeRoleWorker, events[0].Role)
+	assert.Equal(t, "error", events[0].JobState)
+	assert.Equal(t, 1, events[0].Attempt)
+	assert.Equal(t, "lille-1", events[0].Replacement)
+	assert.Equal(t, "lille-2", events[1].Replacement)
+}
+
+func TestSuperviseRepairsExhausted(t *testing.T) {
+	c := newTestRepairCluster()
+	c.Config.AutoRepair = true
+	c.Config.MaxRepairs = 2
+
+	events := []RepairEvent{}
+	ops := &repairOps{
+		getJob:  testJobStates(map[int]string{1: "running", 2: "error"}),
+		retire:  func(n *Node) {},
+		replace: func(site string, role string) (string, error) { return "", fmt.Errorf("no resources") },
+	}
+
+	err := c.supervise(context.Background(), ops, func(e RepairEvent) { events = append(events, e) })
+	assert.EqualError(t, err, "The node 'lille-1' can't be replaced: the 2 repair attempts are exhausted")
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, "no resources", events[1].Error)
+	assert.Equal(t, "", events[1].Replacement)
+}
+
+func TestSuperviseWithoutAutoRepair(t *testing.T) {
+	c := newTestRepairCluster()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	events := []RepairEvent{}
+	ops := &repairOps{
+		getJob:  testJobStates(map[int]string{1: "running", 2: "terminated"}),
+		retire:  func(n *Node) { t.Error("the lost node must not be retired") },
+		replace: func(site string, role string) (string, error) { return "", fmt.Errorf("must not be called") },
+	}
+
+	// the lost nodes are only reported once
+	assert.NoError(t, c.supervise(ctx, ops, func(e RepairEvent) { events = append(events, e) }))
+	assert.Len(t, events, 2)
+	assert.Equal(t, 0, events[0].Attempt)
+	assert.Len(t, c.Nodes, 3)
+}
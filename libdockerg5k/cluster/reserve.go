@@ -0,0 +1,158 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// JobReserver submit, wait for and cancel the Grid5000 jobs (implemented by the Grid5000 API client)
+type JobReserver interface {
+	SubmitResources(site string, resources string, resourceProperties string, queue string, jobType string) (int, error)
+	WaitUntilJobIsReady(site string, jobID int) error
+	CancelJob(site string, jobID int) error
+}
+
+// JobDeployer reserve and deploy the nodes of the Grid5000 jobs (implemented by the Grid5000 API client)
+type JobDeployer interface {
+	JobReserver
+	ResolveEnvironment(site string, image string) (string, error)
+	DeployNodes(site string, sshPublicKey string, jobID int, image string) ([]string, error)
+	GetJob(site string, jobID int) (*g5k.JobState, error)
+}
+
+// ReserveJob submit the job of the given type on the site and wait until it is running, the job is canceled if it does not start before the timeout
+// (the submitted job would hold the nodes once started)
+func ReserveJob(jobs JobReserver, site string, resources string, resourceProperties string, queue string, jobType string, timeout time.Duration) (int, error) {
+	// the job types permitted by the site/queue are checked by OAR at the submission
+	jobID, err := jobs.SubmitResources(site, resources, resourceProperties, queue, jobType)
+	if err != nil {
+		return 0, fmt.Errorf("Job reservation for site '%s' failed (job type '%s'): %w: %w", site, jobType, ErrReservation, err)
+	}
+
+	if err := WithTimeout(timeout, func() error { return jobs.WaitUntilJobIsReady(site, jobID) }); err != nil {
+		if cancelErr := jobs.CancelJob(site, jobID); cancelErr != nil {
+			log.Errorf("Unable to cancel the job '%d' on site '%s': '%s'", jobID, site, cancelErr)
+		}
+		return 0, fmt.Errorf("Job reservation for site '%s' failed: %w: %w", site, ErrReservation, err)
+	}
+
+	return jobID, nil
+}
+
+// reserveJob reserve (one job) the number of nodes on the site in the deployment mode, and returns the job ID and the environment to deploy (resolved before the reservation)
+func (c *GlobalConfig) reserveJob(g5kAPI JobDeployer, site string, mode string, nb int) (int, string, error) {
+	if c.SSHKeyPair == nil {
+		return 0, "", fmt.Errorf("The cluster SSH key pair is required to deploy new nodes")
+	}
+
+	resourceProperties := ""
+	if c.NetworkRequirement != nil {
+		resourceProperties = c.NetworkRequirement.OARProperties()
+	}
+
+	// resolve the environment to deploy before the reservation
+	image := ""
+	if mode == DeployModeDeploy {
+		var err error
+		image, err = g5kAPI.ResolveEnvironment(site, c.G5kImage)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
+	log.Infof("Reserving %d nodes on '%s' site...", nb, site)
+
+	// reserve nodes (the job is canceled if it does not start before the 'reserve' phase timeout)
+	jobID, err := ReserveJob(g5kAPI, site, g5k.GenerateResources(nb, c.G5kWalltime, "", 0), resourceProperties, c.OARQueue, DeployModeJobType(mode), c.PhaseTimeout(PhaseReserve))
+	if err != nil {
+		return 0, "", err
+	}
+
+	return jobID, image, nil
+}
+
+// deployJob deploy the nodes of the job in the deployment mode, and returns the nodes hostname (sorted)
+// an error is returned if the deployment does not return the requested number of nodes (the job is not released)
+func (c *GlobalConfig) deployJob(g5kAPI JobDeployer, site string, mode string, jobID int, image string, nb int) ([]string, error) {
+	deployedNodes, err := DeployJobNodes(g5kAPI, site, mode, string(c.SSHKeyPair.PublicKey), jobID, image)
+	if err == nil && len(deployedNodes) != nb {
+		err = fmt.Errorf("%d/%d nodes deployed", len(deployedNodes), nb)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Nodes deployment for site '%s' failed: %w: %w", site, ErrDeployment, err)
+	}
+	sort.Strings(deployedNodes)
+
+	return deployedNodes, nil
+}
+
+// reserveJobNodes reserve (one job) and deploy the number of nodes on the site in the deployment mode, and returns the job ID and the nodes hostname (sorted)
+// the job is released if the deployment fails or does not return the requested number of nodes
+func (c *GlobalConfig) reserveJobNodes(g5kAPI JobDeployer, site string, mode string, nb int) (int, []string, error) {
+	jobID, image, err := c.reserveJob(g5kAPI, site, mode, nb)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// deploy nodes (the job is released if the deployment fails)
+	deployedNodes, err := c.deployJob(g5kAPI, site, mode, jobID, image, nb)
+	if err != nil {
+		if cancelErr := g5kAPI.CancelJob(site, jobID); cancelErr != nil {
+			log.Errorf("Unable to cancel the job '%d' on site '%s': '%s'", jobID, site, cancelErr)
+		}
+		return 0, nil, err
+	}
+
+	return jobID, deployedNodes, nil
+}
+
+// ReserveAndDefine reserve (one job) and deploy the given number of Swarm managers and workers on the site, and returns their nodes ready to provision (sorted by hostname,
+// machine name format: {site}-{id}, the managers first). The IP addresses of the nodes are added to the static lookup table, the job is released if the reservation
+// can't be satisfied (the nodes are not deployed or their number does not match the requested counts)
+func (c *GlobalConfig) ReserveAndDefine(managers int, workers int, site string) ([]*Node, error) {
+	if managers < 0 || workers < 0 || managers+workers == 0 {
+		return nil, fmt.Errorf("Invalid number of nodes to reserve: %d managers and %d workers", managers, workers)
+	}
+
+	if err := validateDeployMode(c.DeployMode); err != nil {
+		return nil, err
+	}
+
+	if _, err := c.CheckMaxCoreHours(map[string]int{site: managers + workers}); err != nil {
+		return nil, err
+	}
+
+	g5kAPI, err := c.g5kAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	mode := (&Node{clusterConfig: c}).deployMode()
+	jobID, deployedNodes, err := c.reserveJobNodes(g5kAPI, site, mode, managers+workers)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := c.newJobNodes(site, jobID, deployedNodes)
+	for i, n := range nodes {
+		n.DeployMode = mode
+		n.OwnsJob = true
+		n.Role = NodeRoleWorker
+		if i < managers {
+			n.Role = NodeRoleManager
+		}
+	}
+
+	if err := c.lookupNodesIP(nodes); err != nil {
+		if cancelErr := g5kAPI.CancelJob(site, jobID); cancelErr != nil {
+			log.Errorf("Unable to cancel the job '%d' on site '%s': '%s'", jobID, site, cancelErr)
+		}
+		return nil, err
+	}
+
+	return nodes, nil
+}
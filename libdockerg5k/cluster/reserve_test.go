@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveAndDefineIncorrect(t *testing.T) {
+	c := &GlobalConfig{}
+
+	_, err := c.ReserveAndDefine(0, 0, "nancy")
+	assert.Error(t, err)
+
+	_, err = c.ReserveAndDefine(-1, 10, "nancy")
+	assert.Error(t, err)
+
+	c.DeployMode = "kadeploy"
+	_, err = c.ReserveAndDefine(3, 10, "nancy")
+	assert.Error(t, err)
+}
+
+// fakeJobReserver submit the jobs with increasing IDs, their wait takes the given time (or fails) and the canceled jobs are recorded
+type fakeJobReserver struct {
+	wait      time.Duration
+	waitErr   error
+	submitErr error
+	submitted int
+	canceled  chan int
+}
+
+func newFakeJobReserver(wait time.Duration) *fakeJobReserver {
+	return &fakeJobReserver{wait: wait, canceled: make(chan int, 10)}
+}
+
+func (f *fakeJobReserver) SubmitResources(site string, resources string, resourceProperties string, queue string, jobType string) (int, error) {
+	if f.submitErr != nil {
+		return 0, f.submitErr
+	}
+
+	f.submitted++
+	return 1000 + f.submitted, nil
+}
+
+func (f *fakeJobReserver) WaitUntilJobIsReady(site string, jobID int) error {
+	time.Sleep(f.wait)
+	return f.waitErr
+}
+
+func (f *fakeJobReserver) CancelJob(site string, jobID int) error {
+	f.canceled <- jobID
+	return nil
+}
+
+func TestReserveJob(t *testing.T) {
+	f := newFakeJobReserver(0)
+
+	jobID, err := ReserveJob(f, "nancy", "nodes=2,walltime=1:00:00", "", "", "deploy", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1001, jobID)
+	assert.Len(t, f.canceled, 0)
+}
+
+func TestReserveJobTimeout(t *testing.T) {
+	f := newFakeJobReserver(time.Minute)
+
+	// the submitted job is canceled when it does not start in time
+	_, err := ReserveJob(f, "nancy", "nodes=2,walltime=1:00:00", "", "", "deploy", 10*time.Millisecond)
+	assert.True(t, errors.Is(err, ErrReservation))
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.Equal(t, 1001, <-f.canceled)
+}
+
+func TestReserveJobWaitError(t *testing.T) {
+	f := newFakeJobReserver(0)
+	f.waitErr = fmt.Errorf("job error")
+
+	_, err := ReserveJob(f, "nancy", "nodes=2,walltime=1:00:00", "", "", "deploy", time.Minute)
+	assert.True(t, errors.Is(err, ErrReservation))
+	assert.Equal(t, 1001, <-f.canceled)
+}
+
+func TestReserveJobSubmitError(t *testing.T) {
+	f := newFakeJobReserver(0)
+	f.submitErr = fmt.Errorf("invalid job type")
+
+	// nothing to cancel
+	_, err := ReserveJob(f, "nancy", "nodes=2,walltime=1:00:00", "", "", "allow_classic_ssh", time.Minute)
+	assert.True(t, errors.Is(err, ErrReservation))
+	assert.Len(t, f.canceled, 0)
+}
@@ -0,0 +1,124 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// physicalResourcesCommand returns the number of CPUs and the total memory (in bytes) of the node
+	physicalResourcesCommand = "docker info --format '{{.NCPU}} {{.MemTotal}}'"
+)
+
+// AdvertisedResources contain the node resources advertised to the Swarm mode scheduler as generic resources ('cpu' in nano CPUs, 'memory' in bytes)
+type AdvertisedResources struct {
+	NanoCPUs    int64
+	MemoryBytes int64
+}
+
+// IsSet returns true if advertised resources are configured, false otherwise
+func (r *AdvertisedResources) IsSet() bool {
+	return r.NanoCPUs != 0 || r.MemoryBytes != 0
+}
+
+// parseMemorySize returns the number of bytes of a size with an optional unit (b, k, m or g)
+func parseMemorySize(size string) (int64, error) {
+	if !regexSize.MatchString(size) {
+		return 0, fmt.Errorf("Invalid memory size: '%s'", size)
+	}
+
+	multiplier := int64(1)
+	switch strings.ToLower(size[len(size)-1:]) {
+	case "k":
+		multiplier = 1 << 10
+	case "m":
+		multiplier = 1 << 20
+	case "g":
+		multiplier = 1 << 30
+	}
+
+	value, err := strconv.ParseInt(strings.TrimRight(size, "bkmgBKMG"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid memory size: '%s'", size)
+	}
+
+	return value * multiplier, nil
+}
+
+// Set set the advertised resource from its name ('cpus' as a number of CPUs, 'memory' as a size with an optional unit)
+func (r *AdvertisedResources) Set(name string, value string) error {
+	switch name {
+	case "cpus":
+		cpus, err := strconv.ParseFloat(value, 64)
+		if err != nil || cpus <= 0 {
+			return fmt.Errorf("Invalid number of advertised CPUs: '%s'", value)
+		}
+		r.NanoCPUs = int64(cpus * 1e9)
+	case "memory":
+		memory, err := parseMemorySize(value)
+		if err != nil || memory <= 0 {
+			return fmt.Errorf("Invalid advertised memory: '%s'", value)
+		}
+		r.MemoryBytes = memory
+	default:
+		return fmt.Errorf("Unknown advertised resource: '%s' (supported: 'cpus', 'memory')", name)
+	}
+
+	return nil
+}
+
+// engineFlags returns the Engine flags advertising the resources to the Swarm mode scheduler
+func (r *AdvertisedResources) engineFlags() []string {
+	flags := []string{}
+
+	if r.NanoCPUs != 0 {
+		flags = append(flags, fmt.Sprintf("node-generic-resource=cpu=%d", r.NanoCPUs))
+	}
+
+	if r.MemoryBytes != 0 {
+		flags = append(flags, fmt.Sprintf("node-generic-resource=memory=%d", r.MemoryBytes))
+	}
+
+	return flags
+}
+
+// validatePhysical check the advertised resources do not exceed the physical resources of the node
+func (r *AdvertisedResources) validatePhysical(ncpu int64, memTotal int64) error {
+	if r.NanoCPUs > ncpu*1e9 {
+		return fmt.Errorf("The advertised CPUs (%v) exceed the node CPUs (%d)", float64(r.NanoCPUs)/1e9, ncpu)
+	}
+
+	if r.MemoryBytes > memTotal {
+		return fmt.Errorf("The advertised memory (%d bytes) exceed the node memory (%d bytes)", r.MemoryBytes, memTotal)
+	}
+
+	return nil
+}
+
+// checkPhysicalResources check the advertised resources do not exceed the physical resources of the node's host
+func (r *AdvertisedResources) checkPhysicalResources(h *host.Host) error {
+	out, err := h.RunSSHCommand(physicalResourcesCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the node physical resources: '%s'", err)
+	}
+
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return fmt.Errorf("Unable to parse the node physical resources: '%s'", out)
+	}
+
+	ncpu, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the node number of CPUs: '%s'", err)
+	}
+
+	memTotal, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("Unable to parse the node total memory: '%s'", err)
+	}
+
+	return r.validatePhysical(ncpu, memTotal)
+}
@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMemorySize(t *testing.T) {
+	for size, expected := range map[string]int64{"512": 512, "512b": 512, "4k": 4096, "8m": 8 << 20, "2G": 2 << 30} {
+		v, err := parseMemorySize(size)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, v)
+	}
+
+	_, err := parseMemorySize("8gb")
+	assert.Error(t, err)
+}
+
+func TestAdvertisedResourcesSet(t *testing.T) {
+	r := &AdvertisedResources{}
+	assert.NoError(t, r.Set("cpus", "2.5"))
+	assert.NoError(t, r.Set("memory", "8g"))
+	assert.Equal(t, &AdvertisedResources{NanoCPUs: 2500000000, MemoryBytes: 8 << 30}, r)
+}
+
+func TestAdvertisedResourcesSetIncorrect(t *testing.T) {
+	r := &AdvertisedResources{}
+	assert.Error(t, r.Set("cpus", "-1"))
+	assert.Error(t, r.Set("memory", "lots"))
+	assert.Error(t, r.Set("gpus", "1"))
+	assert.False(t, r.IsSet())
+}
+
+func TestAdvertisedResourcesEngineFlags(t *testing.T) {
+	r := &AdvertisedResources{NanoCPUs: 2000000000, MemoryBytes: 1024}
+	assert.Equal(t, []string{"node-generic-resource=cpu=2000000000", "node-generic-resource=memory=1024"}, r.engineFlags())
+}
+
+func TestAdvertisedResourcesValidatePhysical(t *testing.T) {
+	r := &AdvertisedResources{NanoCPUs: 4000000000, MemoryBytes: 8 << 30}
+	assert.NoError(t, r.validatePhysical(4, 16<<30))
+	assert.Error(t, r.validatePhysical(2, 16<<30))
+	assert.Error(t, r.validatePhysical(4, 4<<30))
+}
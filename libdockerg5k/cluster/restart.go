@@ -0,0 +1,170 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// restartEnginesTimeout is the maximum time allowed to restart the Engine of a node (and wait for it to be ready)
+	restartEnginesTimeout = 5 * time.Minute
+
+	// engineRestartCommand restart the Docker Engine
+	engineRestartCommand = "systemctl restart docker.service"
+
+	// swarmNodeIDCommand returns the Swarm mode node ID of the Engine
+	swarmNodeIDCommand = "docker info --format '{{.Swarm.NodeID}}'"
+)
+
+// waitForEngine wait until the Engine of the host respond or the timeout expire
+func waitForEngine(h *host.Host, timeout time.Duration) error {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(5 * time.Second) {
+		if _, err := h.RunSSHCommand("docker info"); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("The Docker Engine is not responding after %s", timeout)
+}
+
+// waitForSwarmNodeReady wait until the Swarm mode node is ready (the manager host is used to get the node status)
+func waitForSwarmNodeReady(manager *host.Host, nodeID string, timeout time.Duration) error {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(5 * time.Second) {
+		out, err := manager.RunSSHCommand(fmt.Sprintf("docker node inspect --format '{{.Status.State}}' %s", nodeID))
+		if err == nil && strings.TrimSpace(out) == "ready" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("The Swarm node is not ready after %s", timeout)
+}
+
+// setSwarmNodeAvailability set the availability (active, pause or drain) of the Swarm mode node (the manager host is used to update the node)
+func setSwarmNodeAvailability(manager *host.Host, nodeID string, availability string) error {
+	if _, err := manager.RunSSHCommand(fmt.Sprintf("docker node update --availability %s %s", availability, nodeID)); err != nil {
+		return fmt.Errorf("Failed to set the Swarm node availability to '%s': '%s'", availability, err)
+	}
+
+	return nil
+}
+
+// restartEngine restart the Engine of the node and wait for it to respond
+func (n *Node) restartEngine(h *host.Host) error {
+	if _, err := h.RunSSHCommand(engineRestartCommand); err != nil {
+		return fmt.Errorf("Failed to restart the Docker Engine: '%s'", err)
+	}
+
+	return waitForEngine(h, restartEnginesTimeout)
+}
+
+// drainAndRestart drain the Swarm mode node, run the restart and wait for the node to be ready before making it active again
+// the node is made active again if the restart or the wait fails, to not remove its capacity from the cluster
+func drainAndRestart(setAvailability func(availability string) error, restart func() error, waitReady func() error) (err error) {
+	// drain the node tasks
+	if err := setAvailability("drain"); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			if restoreErr := setAvailability("active"); restoreErr != nil {
+				log.Errorf("Unable to make the drained Swarm node active again: '%s'", restoreErr)
+			}
+		}
+	}()
+
+	// restart the Engine
+	if err = restart(); err != nil {
+		return err
+	}
+
+	// wait for the node to rejoin the cluster
+	if err = waitReady(); err != nil {
+		return err
+	}
+
+	// schedule tasks on the node again
+	return setAvailability("active")
+}
+
+// rollingRestartEngine drain the Swarm mode tasks of the node, restart its Engine and wait for it to be ready before making it active again
+func (n *Node) rollingRestartEngine(h *host.Host, manager *host.Host) error {
+	// the node manage itself if it is a Swarm manager
+	if n.isSwarmMaster() {
+		manager = h
+	}
+
+	// get Swarm mode node ID
+	out, err := h.RunSSHCommand(swarmNodeIDCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the Swarm node ID: '%s'", err)
+	}
+	nodeID := strings.TrimSpace(out)
+
+	return drainAndRestart(func(availability string) error {
+		return setSwarmNodeAvailability(manager, nodeID, availability)
+	}, func() error {
+		return n.restartEngine(h)
+	}, func() error {
+		return waitForSwarmNodeReady(manager, nodeID, restartEnginesTimeout)
+	})
+}
+
+// RestartEngines restart the Docker Engine of the selected nodes (all nodes if the selector is empty), concurrently or one node at a time (draining its Swarm mode tasks before the restart) in rolling mode
+func (c *Cluster) RestartEngines(rolling bool, sel *NodeSelector) error {
+	if err := c.checkSelection(sel); err != nil {
+		return err
+	}
+
+	// restart all nodes concurrently
+	if !rolling {
+		errs := c.runOnSelectedNodes(sel, restartEnginesTimeout, func(n *Node, h *host.Host) error {
+			return n.restartEngine(h)
+		})
+
+		return fleetError("Engine restart", errs)
+	}
+
+	// a reachable Swarm mode manager is used to drain the worker nodes
+	var manager *host.Host
+	if c.Config.SwarmModeGlobalConfig != nil {
+		h, err := c.swarmManager()
+		if err != nil {
+			return err
+		}
+		manager = h
+	}
+
+	// restart nodes one at a time (sorted by machine name)
+	return c.restartOneAtATime(c.selectNodes(sel), func(n *Node) error {
+		h, err := n.loadHost()
+		if err != nil {
+			return err
+		}
+
+		if manager != nil {
+			return n.rollingRestartEngine(h, manager)
+		}
+		return n.restartEngine(h)
+	})
+}
+
+// restartOneAtATime run the restart function on the nodes one at a time (in the given order), stopping at the first failure to preserve the cluster availability
+func (c *Cluster) restartOneAtATime(machineNames []string, restart func(n *Node) error) error {
+	for _, machineName := range machineNames {
+		n := c.Nodes[machineName]
+		log.Infof("Restarting the Docker Engine of node '%s' ('%s')...", n.NodeName, n.MachineName)
+
+		if err := restart(n); err != nil {
+			return fleetError("Engine restart", map[string]error{machineName: err})
+		}
+
+		log.Infof("Docker Engine of node '%s' ('%s') restarted", n.NodeName, n.MachineName)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartOneAtATime(t *testing.T) {
+	c := newFleetCluster(12, 4)
+
+	restarted := []string{}
+	err := c.restartOneAtATime(c.selectNodes(&NodeSelector{}), func(n *Node) error {
+		restarted = append(restarted, n.MachineName)
+		return nil
+	})
+
+	// the nodes are restarted sorted by machine name
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lille-0", "lille-1", "lille-10", "lille-11", "lille-2", "lille-3", "lille-4", "lille-5", "lille-6", "lille-7", "lille-8", "lille-9"}, restarted)
+}
+
+func TestRestartOneAtATimeStopAtFirstFailure(t *testing.T) {
+	c := newFleetCluster(5, 4)
+
+	restarted := []string{}
+	err := c.restartOneAtATime(c.selectNodes(&NodeSelector{}), func(n *Node) error {
+		restarted = append(restarted, n.MachineName)
+		if n.MachineName == "lille-2" {
+			return fmt.Errorf("The Docker Engine is not responding after 5m0s")
+		}
+		return nil
+	})
+
+	// the nodes after the failed node are not restarted
+	assert.EqualError(t, err, "Engine restart failed on node(s): lille-2")
+	assert.Equal(t, []string{"lille-0", "lille-1", "lille-2"}, restarted)
+}
+
+func TestRestartOneAtATimeSelection(t *testing.T) {
+	c := newFleetCluster(5, 4)
+
+	restarted := []string{}
+	err := c.restartOneAtATime(c.selectNodes(&NodeSelector{Names: []string{"lille-3", "lille-1"}}), func(n *Node) error {
+		restarted = append(restarted, n.MachineName)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lille-1", "lille-3"}, restarted)
+}
+
+func TestDrainAndRestart(t *testing.T) {
+	calls := []string{}
+	err := drainAndRestart(func(availability string) error {
+		calls = append(calls, availability)
+		return nil
+	}, func() error {
+		calls = append(calls, "restart")
+		return nil
+	}, func() error {
+		calls = append(calls, "ready")
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"drain", "restart", "ready", "active"}, calls)
+}
+
+func TestDrainAndRestartFailure(t *testing.T) {
+	calls := []string{}
+	setAvailability := func(availability string) error {
+		calls = append(calls, availability)
+		return nil
+	}
+
+	// the drained node is made active again when its restart fails
+	err := drainAndRestart(setAvailability, func() error {
+		calls = append(calls, "restart")
+		return fmt.Errorf("Failed to restart the Docker Engine: 'exit status 1'")
+	}, func() error {
+		calls = append(calls, "ready")
+		return nil
+	})
+	assert.EqualError(t, err, "Failed to restart the Docker Engine: 'exit status 1'")
+	assert.Equal(t, []string{"drain", "restart", "active"}, calls)
+
+	// or when it does not rejoin the cluster
+	calls = []string{}
+	err = drainAndRestart(setAvailability, func() error {
+		calls = append(calls, "restart")
+		return nil
+	}, func() error {
+		calls = append(calls, "ready")
+		return fmt.Errorf("The Swarm node is not ready after 5m0s")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"drain", "restart", "ready", "active"}, calls)
+}
+
+func TestDrainAndRestartDrainFailure(t *testing.T) {
+	restarted := false
+	err := drainAndRestart(func(availability string) error {
+		return fmt.Errorf("Failed to set the Swarm node availability to '%s': 'node not found'", availability)
+	}, func() error {
+		restarted = true
+		return nil
+	}, func() error {
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.False(t, restarted)
+}
@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// Roles of a node in the cluster
+	NodeRoleManager = "Manager" // Swarm mode manager / Swarm standalone master
+	NodeRoleWorker  = "Worker"  // Swarm mode worker / Swarm standalone agent
+)
+
+// SyncNodeRoles synchronize the nodes role with the Swarm master/manager list (the nodes of the list are managers, the nodes without role are workers)
+// The managers not in the list are appended to it (sorted by machine name), the first node of the list stay the bootstrap manager
+func (c *Cluster) SyncNodeRoles() {
+	listed := make(map[string]bool)
+	for _, m := range c.Config.SwarmMasterNode {
+		listed[m] = true
+
+		if n, ok := c.Nodes[m]; ok {
+			n.Role = NodeRoleManager
+		}
+	}
+
+	// sort nodes by machine name
+	machineNames := []string{}
+	for machineName := range c.Nodes {
+		machineNames = append(machineNames, machineName)
+	}
+	sort.Strings(machineNames)
+
+	for _, machineName := range machineNames {
+		n := c.Nodes[machineName]
+		switch {
+		case n.Role == "":
+			n.Role = NodeRoleWorker
+		case n.Role == NodeRoleManager && !listed[machineName]:
+			c.Config.SwarmMasterNode = append(c.Config.SwarmMasterNode, machineName)
+		}
+	}
+}
+
+// validateNodeRoles check the role of the nodes and that the cluster has at least one manager if Swarm is used
+func (c *Cluster) validateNodeRoles() error {
+	managers := 0
+	for _, n := range c.Nodes {
+		switch n.Role {
+		case NodeRoleManager:
+			managers++
+		case NodeRoleWorker, "":
+		default:
+			return fmt.Errorf("Invalid role '%s' for node '%s' (supported: '%s', '%s')", n.Role, n.MachineName, NodeRoleManager, NodeRoleWorker)
+		}
+	}
+
+	if managers == 0 && (c.Config.SwarmStandaloneGlobalConfig != nil || c.Config.SwarmModeGlobalConfig != nil) {
+		return fmt.Errorf("At least one Swarm master/manager node is required")
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+)
+
+func newRolesCluster(masters []string, roles map[string]string) *Cluster {
+	c := NewCluster(&GlobalConfig{SwarmMasterNode: masters, SwarmModeGlobalConfig: &swarm.SwarmModeGlobalConfig{}})
+	for machineName, role := range roles {
+		c.Nodes[machineName] = &Node{clusterConfig: c.Config, MachineName: machineName, Role: role}
+	}
+
+	return c
+}
+
+func TestSyncNodeRolesFromList(t *testing.T) {
+	c := newRolesCluster([]string{"lille-1", "lille-0"}, map[string]string{"lille-0": "", "lille-1": "", "lille-2": ""})
+	c.SyncNodeRoles()
+
+	assert.Equal(t, NodeRoleManager, c.Nodes["lille-0"].Role)
+	assert.Equal(t, NodeRoleManager, c.Nodes["lille-1"].Role)
+	assert.Equal(t, NodeRoleWorker, c.Nodes["lille-2"].Role)
+	assert.Equal(t, []string{"lille-1", "lille-0"}, c.Config.SwarmMasterNode)
+}
+
+func TestSyncNodeRolesFromRoles(t *testing.T) {
+	c := newRolesCluster([]string{"lille-2"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleWorker, "lille-2": "", "nancy-0": NodeRoleManager})
+	c.SyncNodeRoles()
+
+	assert.Equal(t, []string{"lille-2", "lille-0", "nancy-0"}, c.Config.SwarmMasterNode)
+	assert.True(t, c.Nodes["lille-0"].isSwarmMaster())
+	assert.False(t, c.Nodes["lille-1"].isSwarmMaster())
+	assert.True(t, c.Nodes["lille-2"].isSwarmModeBootstrapNode())
+}
+
+func TestValidateNodeRoles(t *testing.T) {
+	c := newRolesCluster(nil, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleWorker})
+	assert.NoError(t, c.validateNodeRoles())
+
+	c = newRolesCluster(nil, map[string]string{"lille-0": NodeRoleWorker, "lille-1": NodeRoleWorker})
+	assert.Error(t, c.validateNodeRoles())
+
+	c = newRolesCluster(nil, map[string]string{"lille-0": NodeRoleManager, "lille-1": "master"})
+	assert.Error(t, c.validateNodeRoles())
+}
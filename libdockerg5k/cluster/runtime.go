@@ -0,0 +1,97 @@
+package cluster
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// defaultOCIRuntime is the OCI runtime used by the Docker Engine by default
+	defaultOCIRuntime = "runc"
+)
+
+var (
+	// regexRuntimeName match a valid OCI runtime name
+	regexRuntimeName = regexp.MustCompile("^[[:alnum:]][[:alnum:]_.-]*$")
+)
+
+// validateOCIRuntimes check the registered OCI runtimes and the default runtime
+func validateOCIRuntimes(defaultRuntime string, binaries map[string]string) error {
+	for name, binary := range binaries {
+		if !regexRuntimeName.MatchString(name) {
+			return fmt.Errorf("Invalid OCI runtime name: '%s' (only letters, digits, '_', '.' and '-' are allowed)", name)
+		}
+
+		// runc is registered by the Docker Engine
+		if name == defaultOCIRuntime {
+			return fmt.Errorf("The OCI runtime '%s' is registered by the Docker Engine and can't be redefined", defaultOCIRuntime)
+		}
+
+		if !path.IsAbs(binary) {
+			return fmt.Errorf("The binary of the OCI runtime '%s' must be an absolute path: '%s'", name, binary)
+		}
+	}
+
+	if defaultRuntime != "" && defaultRuntime != defaultOCIRuntime {
+		if _, ok := binaries[defaultRuntime]; !ok {
+			return fmt.Errorf("The OCI runtime '%s' is not registered (its binary must be given)", defaultRuntime)
+		}
+	}
+
+	return nil
+}
+
+// sortedRuntimes returns the names (sorted) of the registered OCI runtimes
+func (c *GlobalConfig) sortedRuntimes() []string {
+	names := []string{}
+	for name := range c.RuntimeBinaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// runtimeEngineFlags returns the Engine flags registering the OCI runtimes and selecting the default runtime
+func (c *GlobalConfig) runtimeEngineFlags() []string {
+	flags := []string{}
+	for _, name := range c.sortedRuntimes() {
+		flags = append(flags, fmt.Sprintf("add-runtime=%s=%s", name, c.RuntimeBinaries[name]))
+	}
+
+	if c.OCIRuntime != "" && c.OCIRuntime != defaultOCIRuntime {
+		flags = append(flags, fmt.Sprintf("default-runtime=%s", c.OCIRuntime))
+	}
+
+	return flags
+}
+
+// activeRuntime returns the default OCI runtime of the Engines
+func (c *GlobalConfig) activeRuntime() string {
+	if c.OCIRuntime == "" {
+		return defaultOCIRuntime
+	}
+
+	return c.OCIRuntime
+}
+
+// generateRuntimeInstallCommand returns the command used to install the OCI runtime package if its binary is not found
+func generateRuntimeInstallCommand(name string, binary string) string {
+	return fmt.Sprintf("test -x %[2]s || (apt-get update -q && DEBIAN_FRONTEND=noninteractive apt-get install -q -y %[1]s && test -x %[2]s)", name, binary)
+}
+
+// installRuntimes check the registered OCI runtimes binaries are installed on the node (the runtime package is installed otherwise)
+func (n *Node) installRuntimes(h *host.Host) error {
+	for _, name := range n.clusterConfig.sortedRuntimes() {
+		binary := n.clusterConfig.RuntimeBinaries[name]
+		if _, err := h.RunSSHCommand(generateRuntimeInstallCommand(name, binary)); err != nil {
+			return fmt.Errorf("The binary '%s' of the OCI runtime '%s' is not installed and the '%s' package can't be installed: '%s'", binary, name, name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOCIRuntimes(t *testing.T) {
+	assert.NoError(t, validateOCIRuntimes("", nil))
+	assert.NoError(t, validateOCIRuntimes("runc", nil))
+	assert.NoError(t, validateOCIRuntimes("crun", map[string]string{"crun": "/usr/bin/crun"}))
+	assert.Error(t, validateOCIRuntimes("crun", nil))
+	assert.Error(t, validateOCIRuntimes("", map[string]string{"runc": "/usr/bin/runc"}))
+	assert.Error(t, validateOCIRuntimes("", map[string]string{"crun": "crun"}))
+	assert.Error(t, validateOCIRuntimes("", map[string]string{"my runtime": "/usr/bin/crun"}))
+}
+
+func TestRuntimeEngineFlags(t *testing.T) {
+	c := &GlobalConfig{}
+	assert.Empty(t, c.runtimeEngineFlags())
+	assert.Equal(t, "runc", c.activeRuntime())
+
+	c = &GlobalConfig{OCIRuntime: "crun", RuntimeBinaries: map[string]string{"kata": "/usr/bin/kata-runtime", "crun": "/usr/bin/crun"}}
+	assert.Equal(t, []string{"add-runtime=crun=/usr/bin/crun", "add-runtime=kata=/usr/bin/kata-runtime", "default-runtime=crun"}, c.runtimeEngineFlags())
+	assert.Equal(t, "crun", c.activeRuntime())
+}
+
+func TestGenerateRuntimeInstallCommand(t *testing.T) {
+	assert.Equal(t, "test -x /usr/bin/crun || (apt-get update -q && DEBIAN_FRONTEND=noninteractive apt-get install -q -y crun && test -x /usr/bin/crun)", generateRuntimeInstallCommand("crun", "/usr/bin/crun"))
+}
@@ -0,0 +1,385 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/hostsmapping"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// scalePlan contain the nodes to add (by role) and remove (machine names) to reach the target size of the cluster
+type scalePlan struct {
+	addManagers    int
+	addWorkers     int
+	removeManagers []string
+	removeWorkers  []string
+}
+
+// isEmpty returns true if the cluster is already at the target size, false otherwise
+func (p *scalePlan) isEmpty() bool {
+	return p.addManagers == 0 && p.addWorkers == 0 && len(p.removeManagers) == 0 && len(p.removeWorkers) == 0
+}
+
+// isProtected returns true if the node can't be removed by a scaling (Swarm mode bootstrap manager, registry, ingress or bastion node), false otherwise
+func (c *Cluster) isProtected(n *Node) bool {
+	return n.isSwarmModeBootstrapNode() || (c.Config.DeployRegistry && n.MachineName == c.Config.RegistryNode) || n.isIngressNode() || n.isBastionNode()
+}
+
+// planScale returns the nodes to add and remove to reach the target number of managers and workers
+// the last nodes (by machine name) are removed first and the managers can't be scaled down below the quorum of the current managers
+func (c *Cluster) planScale(managers int, workers int) (*scalePlan, error) {
+	if managers < 1 {
+		return nil, fmt.Errorf("At least one Swarm manager is required")
+	}
+
+	if workers < 0 {
+		return nil, fmt.Errorf("Invalid number of Swarm workers: %d", workers)
+	}
+
+	// removable nodes by role (sorted by machine name, the last ones are removed first)
+	currentManagers, currentWorkers := 0, 0
+	removableManagers, removableWorkers := []string{}, []string{}
+	for machineName, n := range c.Nodes {
+		if n.isSwarmMaster() {
+			currentManagers++
+			if !c.isProtected(n) {
+				removableManagers = append(removableManagers, machineName)
+			}
+		} else {
+			currentWorkers++
+			if !c.isProtected(n) {
+				removableWorkers = append(removableWorkers, machineName)
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(removableManagers)))
+	sort.Sort(sort.Reverse(sort.StringSlice(removableWorkers)))
+
+	// the remaining managers must hold the quorum while the others are demoted
+	if quorum := (currentManagers / 2) + 1; managers < quorum {
+		return nil, fmt.Errorf("Scaling the Swarm managers from %d to %d would break the quorum (at least %d managers must stay, scale down in several steps)", currentManagers, managers, quorum)
+	}
+
+	p := &scalePlan{}
+	if managers > currentManagers {
+		p.addManagers = managers - currentManagers
+	} else if nb := currentManagers - managers; nb > 0 {
+		if nb > len(removableManagers) {
+			return nil, fmt.Errorf("Unable to remove %d Swarm managers: only %d can be removed", nb, len(removableManagers))
+		}
+		p.removeManagers = removableManagers[:nb]
+	}
+
+	if workers > currentWorkers {
+		p.addWorkers = workers - currentWorkers
+	} else if nb := currentWorkers - workers; nb > 0 {
+		if nb > len(removableWorkers) {
+			return nil, fmt.Errorf("Unable to remove %d Swarm workers: only %d can be removed", nb, len(removableWorkers))
+		}
+		p.removeWorkers = removableWorkers[:nb]
+	}
+
+	return p, nil
+}
+
+// nextMachineNames returns the given number of unused machine names (format: {site}-{id}) for the site, following the existing nodes
+func (c *Cluster) nextMachineNames(site string, count int) []string {
+	next := 0
+	for machineName := range c.Nodes {
+		if !strings.HasPrefix(machineName, site+"-") {
+			continue
+		}
+
+		if id, err := strconv.Atoi(strings.TrimPrefix(machineName, site+"-")); err == nil && id >= next {
+			next = id + 1
+		}
+	}
+
+	names := []string{}
+	for i := 0; i < count; i++ {
+		names = append(names, fmt.Sprintf("%s-%d", site, next+i))
+	}
+
+	return names
+}
+
+// removeSwarmNode remove the node from the Swarm mode cluster (demoting it first if it is a manager) and its Docker Machine
+func (c *Cluster) removeSwarmNode(manager *host.Host, n *Node) error {
+	h, err := n.loadHost()
+	if err != nil {
+		return err
+	}
+
+	// get Swarm mode node ID
+	out, err := h.RunSSHCommand(swarmNodeIDCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the Swarm node ID: '%s'", err)
+	}
+	nodeID := strings.TrimSpace(out)
+
+	// demote the manager (one at a time to keep the quorum)
+	if n.isSwarmMaster() {
+		if _, err := manager.RunSSHCommand(fmt.Sprintf("docker node demote %s", nodeID)); err != nil {
+			return fmt.Errorf("Failed to demote the Swarm manager: '%s'", err)
+		}
+	}
+
+	// reschedule the node tasks
+	if err := setSwarmNodeAvailability(manager, nodeID, "drain"); err != nil {
+		return err
+	}
+
+	// leave the cluster and remove the node from the managers list of nodes
+	if _, err := h.RunSSHCommand("docker swarm leave"); err != nil {
+		return fmt.Errorf("Failed to leave the Swarm mode cluster: '%s'", err)
+	}
+
+	if _, err := manager.RunSSHCommand(fmt.Sprintf("docker node rm --force %s", nodeID)); err != nil {
+		return fmt.Errorf("Failed to remove the Swarm node: '%s'", err)
+	}
+
+	// stop the remaining (standalone) containers
+	n.stopContainers(h)
+
+	if err := c.Config.LibMachineClient.Remove(n.MachineName); err != nil {
+		return fmt.Errorf("Unable to remove the machine: '%s'", err)
+	}
+
+	return nil
+}
+
+// forgetNode remove the node from the cluster nodes, the static lookup table and the Swarm master/manager list
+func (c *Cluster) forgetNode(machineName string) {
+	delete(c.Nodes, machineName)
+	delete(c.Config.HostsLookupTable, machineName)
+
+	masters := []string{}
+	for _, m := range c.Config.SwarmMasterNode {
+		if m != machineName {
+			masters = append(masters, m)
+		}
+	}
+	c.Config.SwarmMasterNode = masters
+}
+
+// removeNodes remove the nodes (sequentially) from the cluster and cancel the Grid5000 jobs reserved by docker-g5k whose nodes are all removed
+func (c *Cluster) removeNodes(g5kAPI *g5k.G5K, manager *host.Host, machineNames []string) error {
+	jobs := c.nodesByJob()
+	owned := make(map[jobKey]bool)
+	for k, jobNodes := range jobs {
+		owned[k] = c.ownsJob(jobNodes)
+	}
+
+	for _, machineName := range machineNames {
+		n := c.Nodes[machineName]
+		log.Infof("Removing node '%s' ('%s') from the cluster...", n.NodeName, n.MachineName)
+
+		if err := c.removeSwarmNode(manager, n); err != nil {
+			return fleetError("Node removal", map[string]error{machineName: err})
+		}
+
+		c.forgetNode(machineName)
+	}
+
+	// a job can only be released when all its nodes are removed
+	errs := make(map[string]error)
+	for k, jobNodes := range jobs {
+		kept := []string{}
+		for _, machineName := range jobNodes {
+			if _, ok := c.Nodes[machineName]; ok {
+				kept = append(kept, machineName)
+			}
+		}
+
+		switch {
+		case len(kept) == len(jobNodes):
+		case len(kept) > 0:
+			log.Warnf("The job '%d' on site '%s' is still used by node(s) %s, the removed nodes stay reserved until its end", k.jobID, k.site, strings.Join(kept, ", "))
+		case !owned[k]:
+			log.Infof("The job '%d' on site '%s' was not reserved by docker-g5k, it is kept running", k.jobID, k.site)
+		default:
+			if err := g5kAPI.CancelJob(k.site, k.jobID); err != nil {
+				for _, machineName := range jobNodes {
+					errs[machineName] = fmt.Errorf("Unable to cancel the job '%d' on site '%s': '%s'", k.jobID, k.site, err)
+				}
+			}
+		}
+	}
+
+	return fleetError("Release", errs)
+}
+
+// reserveNewNodes reserve (one job) and deploy new nodes on the site in the deployment mode of the site nodes, and returns them (the managers first) with their IP address by machine name
+// the job is canceled if it does not start before the 'reserve' phase timeout or if the deployment fails
+func (c *Cluster) reserveNewNodes(g5kAPI JobDeployer, site string, managers int, workers int) ([]*Node, map[string]string, error) {
+	// the new nodes use the deployment mode of the site nodes
+	mode, err := c.SiteDeployMode(site)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jobID, deployedNodes, err := c.Config.reserveJobNodes(g5kAPI, site, mode, managers+workers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// lookup IP address of the new nodes for static lookup table
+	newNodes := []*Node{}
+	newHosts := make(map[string]string)
+	for i, machineName := range c.nextMachineNames(site, len(deployedNodes)) {
+		n := &Node{
+			clusterConfig: c.Config,
+			MachineName:   machineName,
+			NodeName:      deployedNodes[i],
+			G5kSite:       site,
+			G5kJobID:      jobID,
+			OwnsJob:       true,
+			DeployMode:    mode,
+			Role:          NodeRoleWorker,
+		}
+		if i < managers {
+			n.Role = NodeRoleManager
+		}
+
+		ip, err := net.LookupIP(n.NodeName)
+		if err != nil || len(ip) < 1 {
+			return nil, nil, fmt.Errorf("Unable to lookup IP address for '%s' node: '%s'", n.NodeName, err)
+		}
+
+		newHosts[machineName] = ip[0].String()
+		newNodes = append(newNodes, n)
+	}
+
+	return newNodes, newHosts, nil
+}
+
+// addNodes reserve and deploy new nodes on the site and provision them as Swarm managers (sequentially) and workers (in parallel)
+func (c *Cluster) addNodes(g5kAPI *g5k.G5K, manager *host.Host, site string, managers int, workers int) error {
+	newNodes, newHosts, err := c.reserveNewNodes(g5kAPI, site, managers, workers)
+	if err != nil {
+		return err
+	}
+
+	// add the new nodes to the static lookup table of the existing nodes
+	errs := c.runOnNodes(c.Config.PhaseTimeout(PhaseMapping), func(n *Node, h *host.Host) error {
+		return hostsmapping.AddClusterHostsMapping(h, newHosts, nil)
+	})
+	if err := fleetError("Hosts mapping", errs); err != nil {
+		return err
+	}
+
+	for _, n := range newNodes {
+		c.Nodes[n.MachineName] = n
+		c.Config.HostsLookupTable[n.MachineName] = newHosts[n.MachineName]
+		if n.Role == NodeRoleManager {
+			c.Config.SwarmMasterNode = append(c.Config.SwarmMasterNode, n.MachineName)
+		}
+	}
+	c.syncClusterNodes()
+
+	// select the new nodes network interface satisfying the network requirement
+	if err := c.ResolveAdvertiseInterfaces(g5kAPI, site); err != nil {
+		return fmt.Errorf("Unable to select the network interface of the nodes for site '%s' : '%s'", site, err)
+	}
+
+	// the new nodes join the existing Swarm mode cluster
+	if err := c.Config.SwarmModeGlobalConfig.LoadJoinTokens(manager); err != nil {
+		return err
+	}
+
+	// provision the new Swarm managers (sequential)
+	for _, n := range newNodes[:managers] {
+		log.Infof("Provisionning Swarm manager node '%s' ('%s')...", n.NodeName, n.MachineName)
+		if err := n.Provision(); err != nil {
+			return fmt.Errorf("Error while provisionning Swarm manager node '%s': %w", n.NodeName, err)
+		}
+	}
+
+	// provision the new workers (parallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs = make(map[string]error)
+	for _, n := range newNodes[managers:] {
+		wg.Add(1)
+		go func(n *Node) {
+			defer wg.Done()
+			if err := n.Provision(); err != nil {
+				mu.Lock()
+				errs[n.MachineName] = err
+				mu.Unlock()
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	return fleetError("Provisioning", errs)
+}
+
+// Scale adds or removes Swarm mode nodes to reach the target number of managers and workers, and returns the resulting inventory
+// New nodes are reserved (one job) and deployed on the site of the bootstrap manager, the nodes are removed one at a time (the managers are demoted first)
+// and their Grid5000 job is released once all its nodes are removed. Scaling down the managers below the quorum of the current managers is refused.
+func (c *Cluster) Scale(managers int, workers int) (*Inventory, error) {
+	if c.Config.SwarmModeGlobalConfig == nil {
+		return nil, fmt.Errorf("Scaling the cluster requires Swarm mode")
+	}
+
+	c.SyncNodeRoles()
+	if len(c.Config.SwarmMasterNode) == 0 {
+		return nil, fmt.Errorf("At least one Swarm master/manager node is required")
+	}
+
+	p, err := c.planScale(managers, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isEmpty() {
+		return c.Inventory(), nil
+	}
+
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	// a reachable manager which is not removed is used to manage the Swarm mode nodes (the new nodes are added on the site of the bootstrap manager)
+	bootstrap := c.Nodes[c.Config.SwarmMasterNode[0]]
+	manager, err := c.swarmManager(p.removeManagers...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the managers quorum is required before changing the managers
+	changeManagers := p.addManagers > 0 || len(p.removeManagers) > 0
+	if changeManagers {
+		if err := c.waitForManagersQuorum(); err != nil {
+			return nil, err
+		}
+	}
+
+	// remove the workers first, then the managers
+	if err := c.removeNodes(g5kAPI, manager, append(p.removeWorkers, p.removeManagers...)); err != nil {
+		return nil, err
+	}
+
+	if p.addManagers+p.addWorkers > 0 {
+		if err := c.addNodes(g5kAPI, manager, bootstrap.G5kSite, p.addManagers, p.addWorkers); err != nil {
+			return nil, err
+		}
+	}
+
+	if changeManagers {
+		if err := c.waitForManagersQuorum(); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.Inventory(), nil
+}
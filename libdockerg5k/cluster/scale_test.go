@@ -0,0 +1,105 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/g5k"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanScaleUp(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleWorker})
+
+	p, err := c.planScale(3, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, &scalePlan{addManagers: 2, addWorkers: 3}, p)
+}
+
+func TestPlanScaleDown(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleManager, "lille-2": NodeRoleManager, "lille-3": NodeRoleWorker, "lille-4": NodeRoleWorker, "lille-5": NodeRoleWorker})
+
+	p, err := c.planScale(2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lille-2"}, p.removeManagers)
+	assert.Equal(t, []string{"lille-5", "lille-4"}, p.removeWorkers)
+
+	// nothing to do
+	p, err = c.planScale(3, 3)
+	assert.NoError(t, err)
+	assert.True(t, p.isEmpty())
+}
+
+func TestPlanScaleProtectedNodes(t *testing.T) {
+	c := newRolesCluster([]string{"lille-1"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleManager, "lille-2": NodeRoleWorker, "lille-3": NodeRoleWorker})
+	c.Config.DeployRegistry = true
+	c.Config.RegistryNode = "lille-3"
+
+	p, err := c.planScale(2, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lille-2"}, p.removeWorkers)
+
+	// the registry node can't be removed
+	_, err = c.planScale(2, 0)
+	assert.Error(t, err)
+}
+
+func TestPlanScaleQuorum(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleManager, "lille-2": NodeRoleManager, "lille-3": NodeRoleManager, "lille-4": NodeRoleManager})
+
+	_, err := c.planScale(3, 0)
+	assert.NoError(t, err)
+
+	_, err = c.planScale(2, 0)
+	assert.Error(t, err)
+
+	_, err = c.planScale(0, 0)
+	assert.Error(t, err)
+
+	_, err = c.planScale(5, -1)
+	assert.Error(t, err)
+}
+
+func TestNextMachineNames(t *testing.T) {
+	c := newRolesCluster(nil, map[string]string{"lille-0": "", "lille-3": "", "nancy-7": ""})
+
+	assert.Equal(t, []string{"lille-4", "lille-5"}, c.nextMachineNames("lille", 2))
+	assert.Equal(t, []string{"rennes-0"}, c.nextMachineNames("rennes", 1))
+}
+
+// fakeJobDeployer reserve the jobs like fakeJobReserver and records the deployments
+type fakeJobDeployer struct {
+	*fakeJobReserver
+	deployed []int
+}
+
+func (f *fakeJobDeployer) ResolveEnvironment(site string, image string) (string, error) {
+	return image, nil
+}
+
+func (f *fakeJobDeployer) DeployNodes(site string, sshPublicKey string, jobID int, image string) ([]string, error) {
+	f.deployed = append(f.deployed, jobID)
+	return nil, nil
+}
+
+func (f *fakeJobDeployer) GetJob(site string, jobID int) (*g5k.JobState, error) {
+	return &g5k.JobState{UID: jobID}, nil
+}
+
+func TestReserveNewNodesTimeout(t *testing.T) {
+	c := newRolesCluster([]string{"lille-0"}, map[string]string{"lille-0": NodeRoleManager, "lille-1": NodeRoleWorker})
+	c.Config.SSHKeyPair = &ssh.KeyPair{}
+	c.Config.PhaseTimeouts = map[string]time.Duration{PhaseReserve: 10 * time.Millisecond}
+
+	// the job of the new nodes does not start before the 'reserve' phase timeout
+	f := &fakeJobDeployer{fakeJobReserver: newFakeJobReserver(time.Minute)}
+	nodes, _, err := c.reserveNewNodes(f, "lille", 1, 2)
+
+	assert.True(t, errors.Is(err, ErrReservation))
+	assert.Nil(t, nodes)
+	assert.Equal(t, 1001, <-f.canceled)
+	assert.Empty(t, f.deployed)
+	assert.Len(t, c.Nodes, 2)
+}
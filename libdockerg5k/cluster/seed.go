@@ -0,0 +1,47 @@
+package cluster
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+// resolveSeed returns the seed of the nondeterministic choices, a time-based seed is generated (and logged to reproduce the run) if none is set
+func (c *GlobalConfig) resolveSeed() int64 {
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+		log.Infof("No provisioning seed set, using '%d' (set it to reproduce the nodes allocation)", c.Seed)
+	}
+
+	return c.Seed
+}
+
+// siteRand returns a random source for the site derived from the seed (independent of the sites processing order)
+func siteRand(seed int64, site string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(site))
+
+	return rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+}
+
+// shuffleNodes returns the nodes sorted by name then shuffled with the random source
+func shuffleNodes(nodes []string, r *rand.Rand) []string {
+	ordered := make([]string, len(nodes))
+	copy(ordered, nodes)
+	sort.Strings(ordered)
+
+	r.Shuffle(len(ordered), func(i, j int) {
+		ordered[i], ordered[j] = ordered[j], ordered[i]
+	})
+
+	return ordered
+}
+
+// OrderDeployedNodes returns the deployed nodes of the site in the order of their allocation to the machines ({site}-{index}), using the seed
+// The order returned by the Grid5000 API is not stable, so the same seed and deployed nodes always give the same allocation (and so the same bootstrap manager and addresses)
+func (c *Cluster) OrderDeployedNodes(site string, deployedNodes []string) []string {
+	return shuffleNodes(deployedNodes, siteRand(c.Config.resolveSeed(), site))
+}
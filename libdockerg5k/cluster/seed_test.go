@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuffleNodesReproducible(t *testing.T) {
+	nodes := []string{"paravance-3", "paravance-1", "paravance-2", "paravance-4", "paravance-5"}
+	reversed := []string{"paravance-5", "paravance-4", "paravance-3", "paravance-2", "paravance-1"}
+
+	ordered := shuffleNodes(nodes, siteRand(42, "rennes"))
+	assert.Equal(t, ordered, shuffleNodes(nodes, siteRand(42, "rennes")))
+	assert.Equal(t, ordered, shuffleNodes(reversed, siteRand(42, "rennes")))
+	assert.ElementsMatch(t, nodes, ordered)
+
+	// input is not modified
+	assert.Equal(t, "paravance-3", nodes[0])
+}
+
+func TestOrderDeployedNodes(t *testing.T) {
+	c := NewCluster(&GlobalConfig{Seed: 7})
+	nodes := []string{"a", "b", "c", "d"}
+	assert.Equal(t, c.OrderDeployedNodes("lyon", nodes), c.OrderDeployedNodes("lyon", []string{"d", "c", "b", "a"}))
+	assert.Equal(t, int64(7), c.Config.Seed)
+}
+
+func TestResolveSeedTimeBased(t *testing.T) {
+	c := &GlobalConfig{}
+	seed := c.resolveSeed()
+	assert.NotEqual(t, int64(0), seed)
+	assert.Equal(t, seed, c.resolveSeed())
+}
@@ -0,0 +1,195 @@
+package cluster
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// NodeSelector select a subset of the cluster nodes for the fleet operations
+// The values of a criteria are alternatives, a node must match all the set criteria (all nodes are selected if empty)
+type NodeSelector struct {
+	Roles  []string          // role of the node (Manager or Worker, case insensitive)
+	Sites  []string          // Grid'5000 site of the node
+	Names  []string          // glob pattern of the machine or node name (ex: nancy-*, graphene-1*)
+	Labels map[string]string // Engine labels of the node (a label without value match any value)
+}
+
+// ParseNodeSelector returns the selector of the comma separated list of criteria (ex: role=worker,site=nancy,name=nancy-*,label=disk=ssd)
+func ParseNodeSelector(s string) (*NodeSelector, error) {
+	sel := &NodeSelector{}
+	if strings.TrimSpace(s) == "" {
+		return sel, nil
+	}
+
+	for _, c := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(c), "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("Invalid node selector criteria '%s' (format: key=value)", c)
+		}
+
+		switch kv[0] {
+		case "role":
+			sel.Roles = append(sel.Roles, kv[1])
+		case "site":
+			sel.Sites = append(sel.Sites, kv[1])
+		case "name":
+			sel.Names = append(sel.Names, kv[1])
+		case "label":
+			if sel.Labels == nil {
+				sel.Labels = make(map[string]string)
+			}
+			l := strings.SplitN(kv[1], "=", 2)
+			if len(l) == 2 {
+				sel.Labels[l[0]] = l[1]
+			} else {
+				sel.Labels[l[0]] = ""
+			}
+		default:
+			return nil, fmt.Errorf("Unknown node selector criteria '%s' (supported: role, site, name, label)", kv[0])
+		}
+	}
+
+	if err := sel.Validate(); err != nil {
+		return nil, err
+	}
+
+	return sel, nil
+}
+
+// IsEmpty returns true if the selector select all the nodes
+func (s *NodeSelector) IsEmpty() bool {
+	return s == nil || (len(s.Roles) == 0 && len(s.Sites) == 0 && len(s.Names) == 0 && len(s.Labels) == 0)
+}
+
+// Validate check the roles and name patterns of the selector
+func (s *NodeSelector) Validate() error {
+	if s == nil {
+		return nil
+	}
+
+	for _, r := range s.Roles {
+		if !strings.EqualFold(r, NodeRoleManager) && !strings.EqualFold(r, NodeRoleWorker) {
+			return fmt.Errorf("Invalid node selector role '%s' (supported: '%s', '%s')", r, NodeRoleManager, NodeRoleWorker)
+		}
+	}
+
+	for _, p := range s.Names {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("Invalid node selector name pattern '%s': '%s'", p, err)
+		}
+	}
+
+	return nil
+}
+
+// matchRole returns true if the node role is one of the selected roles (the nodes without role are workers)
+func (s *NodeSelector) matchRole(n *Node) bool {
+	if len(s.Roles) == 0 {
+		return true
+	}
+
+	role := n.Role
+	if role == "" {
+		role = NodeRoleWorker
+	}
+
+	for _, r := range s.Roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchSite returns true if the node is on one of the selected sites
+func (s *NodeSelector) matchSite(n *Node) bool {
+	if len(s.Sites) == 0 {
+		return true
+	}
+
+	for _, site := range s.Sites {
+		if site == n.G5kSite {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchName returns true if the machine or node name match one of the selected patterns
+func (s *NodeSelector) matchName(n *Node) bool {
+	if len(s.Names) == 0 {
+		return true
+	}
+
+	for _, p := range s.Names {
+		if ok, _ := path.Match(p, n.MachineName); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, n.NodeName); ok && n.NodeName != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchLabels returns true if the node has all the selected Engine labels
+func (s *NodeSelector) matchLabels(n *Node) bool {
+	labels := make(map[string]string)
+	for _, l := range n.EngineLabel {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) == 2 {
+			labels[kv[0]] = kv[1]
+		} else {
+			labels[kv[0]] = ""
+		}
+	}
+
+	for k, v := range s.Labels {
+		value, ok := labels[k]
+		if !ok || (v != "" && v != value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Match returns true if the node is selected
+func (s *NodeSelector) Match(n *Node) bool {
+	if s.IsEmpty() {
+		return true
+	}
+
+	return s.matchRole(n) && s.matchSite(n) && s.matchName(n) && s.matchLabels(n)
+}
+
+// selectNodes returns the machine names of the nodes selected by the selector (sorted)
+func (c *Cluster) selectNodes(sel *NodeSelector) []string {
+	machineNames := []string{}
+	for machineName, n := range c.Nodes {
+		if sel.Match(n) {
+			machineNames = append(machineNames, machineName)
+		}
+	}
+	sort.Strings(machineNames)
+
+	return machineNames
+}
+
+// checkSelection check the selector is valid and select at least one node
+func (c *Cluster) checkSelection(sel *NodeSelector) error {
+	if err := sel.Validate(); err != nil {
+		return err
+	}
+
+	if len(c.selectNodes(sel)) == 0 {
+		return fmt.Errorf("No node of the cluster is selected by the node selector")
+	}
+
+	return nil
+}
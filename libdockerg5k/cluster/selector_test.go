@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSelectorTestCluster returns a cluster with managers and workers on two sites
+func newSelectorTestCluster() *Cluster {
+	return &Cluster{Nodes: map[string]*Node{
+		"nancy-0":  {MachineName: "nancy-0", NodeName: "graphene-1.nancy.grid5000.fr", G5kSite: "nancy", Role: NodeRoleManager},
+		"nancy-1":  {MachineName: "nancy-1", NodeName: "graphene-2.nancy.grid5000.fr", G5kSite: "nancy", Role: NodeRoleWorker, EngineLabel: []string{"disk=ssd"}},
+		"nancy-2":  {MachineName: "nancy-2", NodeName: "grisou-1.nancy.grid5000.fr", G5kSite: "nancy", EngineLabel: []string{"disk=hdd", "gpu"}},
+		"rennes-0": {MachineName: "rennes-0", NodeName: "paravance-1.rennes.grid5000.fr", G5kSite: "rennes", Role: NodeRoleWorker, EngineLabel: []string{"disk=ssd"}},
+	}}
+}
+
+func TestParseNodeSelector(t *testing.T) {
+	sel, err := ParseNodeSelector("role=worker, site=nancy,name=nancy-*,name=grisou-*,label=disk=ssd,label=gpu")
+	assert.NoError(t, err)
+	assert.Equal(t, &NodeSelector{
+		Roles:  []string{"worker"},
+		Sites:  []string{"nancy"},
+		Names:  []string{"nancy-*", "grisou-*"},
+		Labels: map[string]string{"disk": "ssd", "gpu": ""},
+	}, sel)
+
+	sel, err = ParseNodeSelector("")
+	assert.NoError(t, err)
+	assert.True(t, sel.IsEmpty())
+}
+
+func TestParseNodeSelectorIncorrect(t *testing.T) {
+	for _, s := range []string{"role", "role=", "role=master", "zone=a", "name=[a-"} {
+		_, err := ParseNodeSelector(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSelectNodes(t *testing.T) {
+	c := newSelectorTestCluster()
+
+	assert.Equal(t, []string{"nancy-0", "nancy-1", "nancy-2", "rennes-0"}, c.selectNodes(nil))
+	assert.Equal(t, []string{"nancy-1", "nancy-2"}, c.selectNodes(&NodeSelector{Roles: []string{"worker"}, Sites: []string{"nancy"}}))
+	assert.Equal(t, []string{"nancy-0"}, c.selectNodes(&NodeSelector{Roles: []string{"MANAGER"}}))
+	assert.Equal(t, []string{"nancy-0", "nancy-1"}, c.selectNodes(&NodeSelector{Names: []string{"graphene-*"}}))
+	assert.Equal(t, []string{"nancy-2", "rennes-0"}, c.selectNodes(&NodeSelector{Names: []string{"rennes-*", "grisou-*"}}))
+	assert.Equal(t, []string{"nancy-1", "rennes-0"}, c.selectNodes(&NodeSelector{Labels: map[string]string{"disk": "ssd"}}))
+	assert.Equal(t, []string{"nancy-2"}, c.selectNodes(&NodeSelector{Labels: map[string]string{"disk": "", "gpu": ""}}))
+	assert.Empty(t, c.selectNodes(&NodeSelector{Sites: []string{"lille"}}))
+}
+
+func TestCheckSelection(t *testing.T) {
+	c := newSelectorTestCluster()
+
+	assert.NoError(t, c.checkSelection(nil))
+	assert.NoError(t, c.checkSelection(&NodeSelector{Sites: []string{"rennes"}}))
+	assert.Error(t, c.checkSelection(&NodeSelector{Sites: []string{"lille"}}))
+	assert.Error(t, c.checkSelection(&NodeSelector{Roles: []string{"master"}}))
+}
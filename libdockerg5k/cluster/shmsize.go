@@ -0,0 +1,102 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// engineArgsCommand returns the command line of the running Docker Engine daemon
+	engineArgsCommand = "ps -o args= -C dockerd"
+)
+
+// validateDefaultShmSize check the default size of the containers '/dev/shm' (empty for the Docker default)
+func validateDefaultShmSize(size string) error {
+	if size == "" {
+		return nil
+	}
+
+	bytes, err := parseMemorySize(size)
+	if err != nil || bytes <= 0 {
+		return fmt.Errorf("Invalid default shm size: '%s'", size)
+	}
+
+	return nil
+}
+
+// defaultShmSize returns the default size of the containers '/dev/shm' on the node (the node 'default-shm-size' flag takes precedence, empty for the Docker default)
+func (n *Node) defaultShmSize() string {
+	if s := getEngineFlagValue(n.EngineOpt, "default-shm-size"); s != "" {
+		return s
+	}
+
+	return n.clusterConfig.DefaultShmSize
+}
+
+// shmSizeEngineFlags returns the default shm size Engine flag of the node (the node 'default-shm-size' flag is kept if set)
+func (n *Node) shmSizeEngineFlags() []string {
+	if n.clusterConfig.DefaultShmSize == "" || getEngineFlagValue(n.EngineOpt, "default-shm-size") != "" {
+		return []string{}
+	}
+
+	return []string{fmt.Sprintf("default-shm-size=%s", n.clusterConfig.DefaultShmSize)}
+}
+
+// parseEngineShmSize returns the default shm size set on the command line of the Engine daemon (empty if not set)
+func parseEngineShmSize(args string) string {
+	fields := strings.Fields(args)
+	for i, f := range fields {
+		if strings.HasPrefix(f, "--default-shm-size=") {
+			return strings.TrimPrefix(f, "--default-shm-size=")
+		}
+
+		if f == "--default-shm-size" && i+1 < len(fields) {
+			return fields[i+1]
+		}
+	}
+
+	return ""
+}
+
+// engineRunsWithShmSize returns true if the Engine of the host runs with the given default shm size
+func engineRunsWithShmSize(h *host.Host, size string) (bool, error) {
+	out, err := h.RunSSHCommand(engineArgsCommand)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get the Engine command line: '%s'", err)
+	}
+
+	expected, _ := parseMemorySize(size)
+	current, err := parseMemorySize(parseEngineShmSize(out))
+
+	return err == nil && current == expected, nil
+}
+
+// checkEngineShmSize check the Engine of the node runs with the default shm size (if set), restarting it if it still runs with a previous configuration
+func (n *Node) checkEngineShmSize(h *host.Host) error {
+	size := n.defaultShmSize()
+	if size == "" {
+		return nil
+	}
+
+	ok, err := engineRunsWithShmSize(h, size)
+	if err != nil || ok {
+		return err
+	}
+
+	log.Infof("Restarting the Docker Engine of node '%s' ('%s') to apply the default shm size...", n.NodeName, n.MachineName)
+	if err := n.restartEngine(h); err != nil {
+		return err
+	}
+
+	if ok, err = engineRunsWithShmSize(h, size); err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("The Docker Engine does not run with the default shm size '%s' after its restart", size)
+	}
+
+	return nil
+}
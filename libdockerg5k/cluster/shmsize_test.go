@@ -0,0 +1,39 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDefaultShmSize(t *testing.T) {
+	assert.NoError(t, validateDefaultShmSize(""))
+	assert.NoError(t, validateDefaultShmSize("1g"))
+	assert.NoError(t, validateDefaultShmSize("512M"))
+
+	assert.Error(t, validateDefaultShmSize("0"))
+	assert.Error(t, validateDefaultShmSize("1gb"))
+	assert.Error(t, validateDefaultShmSize("large"))
+}
+
+func TestShmSizeEngineFlags(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{}}
+	assert.Empty(t, n.shmSizeEngineFlags())
+	assert.Equal(t, "", n.defaultShmSize())
+
+	n = &Node{clusterConfig: &GlobalConfig{DefaultShmSize: "1g"}}
+	assert.Equal(t, []string{"default-shm-size=1g"}, n.shmSizeEngineFlags())
+	assert.Equal(t, "1g", n.defaultShmSize())
+
+	// the node flag takes precedence
+	n = &Node{clusterConfig: &GlobalConfig{DefaultShmSize: "1g"}, EngineOpt: []string{"default-shm-size=2g"}}
+	assert.Empty(t, n.shmSizeEngineFlags())
+	assert.Equal(t, "2g", n.defaultShmSize())
+}
+
+func TestParseEngineShmSize(t *testing.T) {
+	assert.Equal(t, "1g", parseEngineShmSize("/usr/bin/dockerd -H tcp://0.0.0.0:2376 --default-shm-size=1g --tlsverify\n"))
+	assert.Equal(t, "512m", parseEngineShmSize("/usr/bin/dockerd --default-shm-size 512m"))
+	assert.Equal(t, "", parseEngineShmSize("/usr/bin/dockerd -H tcp://0.0.0.0:2376"))
+	assert.Equal(t, "", parseEngineShmSize(""))
+}
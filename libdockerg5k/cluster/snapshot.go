@@ -0,0 +1,184 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// snapshotNodeStateFields are the node fields set by the reservation, excluded from the configuration snapshots
+	snapshotNodeStateFields = []string{"NodeName", "G5kJobID", "OwnsJob", "FailureDomain"}
+)
+
+// ConfigSnapshot contain the normalized intended configuration of the cluster (without secrets and reservation results) and its hash
+type ConfigSnapshot struct {
+	Config map[string]interface{}            `json:"config"`
+	Nodes  map[string]map[string]interface{} `json:"nodes"`
+	Hash   string                            `json:"hash"`
+}
+
+// normalizeValue returns the JSON value without the null values, empty strings and empty arrays/objects (nil if the value itself is empty)
+func normalizeValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{})
+		for k, e := range t {
+			if n := normalizeValue(e); n != nil {
+				m[k] = n
+			}
+		}
+
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	case []interface{}:
+		if len(t) == 0 {
+			return nil
+		}
+
+		a := []interface{}{}
+		for _, e := range t {
+			a = append(a, normalizeValue(e))
+		}
+		return a
+	case string:
+		if t == "" {
+			return nil
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// normalizedObject returns the normalized JSON object of the value
+func normalizedObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+
+	m, ok := normalizeValue(obj).(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}, nil
+	}
+
+	return m, nil
+}
+
+// hash returns the SHA-256 of the snapshot configuration and nodes (JSON objects keys are sorted, the hash does not depend on the maps order)
+func (s *ConfigSnapshot) hash() (string, error) {
+	data, err := json.Marshal(struct {
+		Config map[string]interface{}            `json:"config"`
+		Nodes  map[string]map[string]interface{} `json:"nodes"`
+	}{s.Config, s.Nodes})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Snapshot returns the normalized configuration snapshot of the cluster global configuration and nodes
+func (c *Cluster) Snapshot() (*ConfigSnapshot, error) {
+	config, err := normalizedObject(c.Config)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to snapshot the cluster configuration: '%s'", err)
+	}
+
+	s := &ConfigSnapshot{
+		Config: config,
+		Nodes:  make(map[string]map[string]interface{}),
+	}
+
+	for machineName, n := range c.Nodes {
+		node, err := normalizedObject(n)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to snapshot the configuration of node '%s': '%s'", machineName, err)
+		}
+
+		for _, f := range snapshotNodeStateFields {
+			delete(node, f)
+		}
+
+		s.Nodes[machineName] = node
+	}
+
+	if s.Hash, err = s.hash(); err != nil {
+		return nil, fmt.Errorf("Unable to hash the cluster configuration snapshot: '%s'", err)
+	}
+
+	return s, nil
+}
+
+// flattenValue add the scalar values (and arrays, as JSON) of the JSON value to the flat map by path
+func flattenValue(prefix string, v interface{}, flat map[string]string) {
+	if m, ok := v.(map[string]interface{}); ok {
+		for k, e := range m {
+			flattenValue(prefix+"."+k, e, flat)
+		}
+		return
+	}
+
+	data, _ := json.Marshal(v)
+	flat[prefix] = string(data)
+}
+
+// flatten returns the values of the snapshot by path (ex: config.EngineExperimental, nodes.lille-0.EngineOpt)
+func (s *ConfigSnapshot) flatten() map[string]string {
+	flat := make(map[string]string)
+	flattenValue("config", s.Config, flat)
+	for machineName, n := range s.Nodes {
+		flattenValue("nodes."+machineName, n, flat)
+	}
+
+	return flat
+}
+
+// CompareSnapshots returns the differences between the two snapshots, one line by path sorted (empty if the configurations are identical)
+func CompareSnapshots(a *ConfigSnapshot, b *ConfigSnapshot) string {
+	if a.Hash != "" && a.Hash == b.Hash {
+		return ""
+	}
+
+	fa, fb := a.flatten(), b.flatten()
+
+	paths := []string{}
+	for p := range fa {
+		paths = append(paths, p)
+	}
+	for p := range fb {
+		if _, ok := fa[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	lines := []string{}
+	for _, p := range paths {
+		va, okA := fa[p]
+		vb, okB := fb[p]
+
+		switch {
+		case !okB:
+			lines = append(lines, fmt.Sprintf("- %s: %s", p, va))
+		case !okA:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", p, vb))
+		case va != vb:
+			lines = append(lines, fmt.Sprintf("~ %s: %s => %s", p, va, vb))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
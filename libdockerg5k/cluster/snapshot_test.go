@@ -0,0 +1,84 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSnapshotCluster() *Cluster {
+	c := NewCluster(&GlobalConfig{
+		G5kUsername:            "jdoe",
+		G5kPassword:            "secret",
+		G5kImage:               "jessie-x64-min",
+		EngineSystemdOverrides: map[string]string{"Service.LimitNOFILE": "1048576", "Service.TasksMax": "infinity"},
+		PhaseTimeouts:          map[string]time.Duration{PhaseCreate: 30 * time.Minute},
+		HostsLookupTable:       map[string]string{"lille-0": "1.2.3.4"},
+		PreEngineHook:          func(h *host.Host) error { return nil },
+		SwarmModeGlobalConfig:  &swarm.SwarmModeGlobalConfig{WorkerToken: "SWMTKN-1-secret"},
+	})
+	c.Nodes["lille-0"] = &Node{clusterConfig: c.Config, MachineName: "lille-0", G5kSite: "lille", NodeName: "chimint-1.lille.grid5000.fr", G5kJobID: 42, EngineOpt: []string{"log-level=debug"}}
+	c.Nodes["lille-1"] = &Node{clusterConfig: c.Config, MachineName: "lille-1", G5kSite: "lille", EngineLabel: []string{}}
+
+	return c
+}
+
+func TestSnapshot(t *testing.T) {
+	s, err := newTestSnapshotCluster().Snapshot()
+	assert.NoError(t, err)
+
+	// secrets and live state are excluded
+	assert.Equal(t, "jdoe", s.Config["G5kUsername"])
+	assert.NotContains(t, s.Config, "G5kPassword")
+	assert.NotContains(t, s.Config, "HostsLookupTable")
+	assert.NotContains(t, s.Config["SwarmModeGlobalConfig"], "WorkerToken")
+	assert.NotContains(t, s.Nodes["lille-0"], "NodeName")
+	assert.NotContains(t, s.Nodes["lille-0"], "G5kJobID")
+
+	// empty values are normalized
+	assert.NotContains(t, s.Nodes["lille-1"], "EngineLabel")
+	assert.Len(t, s.Hash, 64)
+}
+
+func TestSnapshotHashStable(t *testing.T) {
+	a, err := newTestSnapshotCluster().Snapshot()
+	assert.NoError(t, err)
+
+	// other reservation results and secrets, nil instead of empty slices
+	c := newTestSnapshotCluster()
+	c.Config.G5kPassword = "other"
+	c.Nodes["lille-0"].NodeName = "chimint-2.lille.grid5000.fr"
+	c.Nodes["lille-0"].G5kJobID = 7
+	c.Nodes["lille-1"].EngineLabel = nil
+
+	b, err := c.Snapshot()
+	assert.NoError(t, err)
+	assert.Equal(t, a.Hash, b.Hash)
+	assert.Equal(t, "", CompareSnapshots(a, b))
+}
+
+func TestCompareSnapshots(t *testing.T) {
+	a, err := newTestSnapshotCluster().Snapshot()
+	assert.NoError(t, err)
+
+	c := newTestSnapshotCluster()
+	c.Config.EngineExperimental = true
+	c.Config.G5kImage = ""
+	c.Nodes["lille-0"].EngineOpt = []string{"log-level=info"}
+	c.Nodes["lille-2"] = &Node{clusterConfig: c.Config, MachineName: "lille-2", G5kSite: "lille"}
+
+	b, err := c.Snapshot()
+	assert.NoError(t, err)
+	assert.NotEqual(t, a.Hash, b.Hash)
+	assert.Equal(t, `~ config.EngineExperimental: false => true
+- config.G5kImage: "jessie-x64-min"
+~ nodes.lille-0.EngineOpt: ["log-level=debug"] => ["log-level=info"]
++ nodes.lille-2.AdvertisedResources.MemoryBytes: 0
++ nodes.lille-2.AdvertisedResources.NanoCPUs: 0
++ nodes.lille-2.EngineDebug: false
++ nodes.lille-2.G5kSite: "lille"
++ nodes.lille-2.MachineName: "lille-2"`, CompareSnapshots(a, b))
+}
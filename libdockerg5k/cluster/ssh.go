@@ -0,0 +1,82 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// sshPort is the SSH port of the nodes
+	sshPort = "22"
+
+	// delay between the first SSH checks of a node (doubled after each failed check)
+	sshCheckInitialDelay = 1 * time.Second
+	sshCheckMaxDelay     = 30 * time.Second
+
+	// sshDialTimeout is the maximum time allowed to connect to the SSH port of a node
+	sshDialTimeout = 5 * time.Second
+)
+
+// sshCheckDelay returns the delay after the given failed SSH check (starting at 1), doubled at each check and capped
+func sshCheckDelay(check int) time.Duration {
+	delay := sshCheckInitialDelay
+	for i := 1; i < check && delay < sshCheckMaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > sshCheckMaxDelay {
+		return sshCheckMaxDelay
+	}
+
+	return delay
+}
+
+// waitForSSHPort wait until the SSH port of the address accept connections or the deadline expire
+func waitForSSHPort(address string, deadline time.Time) error {
+	for check := 1; ; check++ {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(address, sshPort), sshDialTimeout)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		delay := sshCheckDelay(check)
+		if time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("SSH is not reachable: '%s'", err)
+		}
+
+		time.Sleep(delay)
+	}
+}
+
+// WaitForSSH wait (in parallel) until the SSH port of the nodes is reachable, returning an error listing the unreachable nodes after the timeout
+func (c *GlobalConfig) WaitForSSH(nodes []*Node, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	results := make(chan nodeResult, len(nodes))
+
+	for _, n := range nodes {
+		go func(n *Node) {
+			results <- nodeResult{n.MachineName, waitForSSHPort(n.NodeName, deadline)}
+		}(n)
+	}
+
+	errs := make(map[string]error)
+	for range nodes {
+		if r := <-results; r.err != nil {
+			errs[r.machineName] = r.err
+		}
+	}
+
+	return fleetError("SSH wait", errs)
+}
+
+// waitForNodesSSH wait until the SSH port of all nodes of the cluster is reachable
+func (c *Cluster) waitForNodesSSH() error {
+	nodes := []*Node{}
+	for _, n := range c.Nodes {
+		nodes = append(nodes, n)
+	}
+
+	return c.Config.WaitForSSH(nodes, c.Config.SSHWaitTimeout)
+}
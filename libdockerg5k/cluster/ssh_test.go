@@ -0,0 +1,16 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHCheckDelay(t *testing.T) {
+	assert.Equal(t, 1*time.Second, sshCheckDelay(1))
+	assert.Equal(t, 2*time.Second, sshCheckDelay(2))
+	assert.Equal(t, 16*time.Second, sshCheckDelay(5))
+	assert.Equal(t, 30*time.Second, sshCheckDelay(6))
+	assert.Equal(t, 30*time.Second, sshCheckDelay(100))
+}
@@ -0,0 +1,109 @@
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// SSHConnInfo contain the SSH connection parameters of a node (for the external tools: ssh, scp, rsync, ...)
+type SSHConnInfo struct {
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	User    string `json:"user"`
+	KeyPath string `json:"key_path"`
+	Bastion string `json:"bastion,omitempty"` // SSH bastion used to reach the node (format: user@host, direct if empty)
+}
+
+// SSHConnection returns the SSH connection parameters of the node from its machine (through the cluster SSH bastion if configured)
+func (n *Node) SSHConnection() (*SSHConnInfo, error) {
+	h, err := n.loadHost()
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := h.Driver.GetSSHHostname()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the SSH hostname of machine '%s': '%s'", n.MachineName, err)
+	}
+
+	port, err := h.Driver.GetSSHPort()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the SSH port of machine '%s': '%s'", n.MachineName, err)
+	}
+
+	info := &SSHConnInfo{
+		Host:    hostname,
+		Port:    port,
+		User:    h.Driver.GetSSHUsername(),
+		KeyPath: h.Driver.GetSSHKeyPath(),
+	}
+
+	// the bastion is reached with the Grid'5000 user
+	if n.clusterConfig.SSHBastion != "" {
+		user, _, err := n.clusterConfig.credentials()
+		if err != nil {
+			return nil, err
+		}
+
+		info.Bastion = fmt.Sprintf("%s@%s", user, n.clusterConfig.SSHBastion)
+	}
+
+	return info, nil
+}
+
+// renderSSHConfig returns the OpenSSH client configuration with a Host entry by machine name (sorted)
+// the host keys are not checked as they change each time the nodes are deployed
+func renderSSHConfig(conns map[string]*SSHConnInfo) string {
+	machineNames := []string{}
+	for machineName := range conns {
+		machineNames = append(machineNames, machineName)
+	}
+	sort.Strings(machineNames)
+
+	var b strings.Builder
+	for _, machineName := range machineNames {
+		info := conns[machineName]
+
+		fmt.Fprintf(&b, "Host %s\n", machineName)
+		fmt.Fprintf(&b, "  HostName %s\n", info.Host)
+		fmt.Fprintf(&b, "  Port %d\n", info.Port)
+		fmt.Fprintf(&b, "  User %s\n", info.User)
+		fmt.Fprintf(&b, "  IdentityFile %s\n", info.KeyPath)
+		b.WriteString("  IdentitiesOnly yes\n")
+		b.WriteString("  StrictHostKeyChecking no\n")
+		b.WriteString("  UserKnownHostsFile /dev/null\n")
+		if info.Bastion != "" {
+			fmt.Fprintf(&b, "  ProxyJump %s\n", info.Bastion)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// WriteSSHConfig write an OpenSSH client configuration fragment with a Host alias by machine name in the given file (ex: included in ~/.ssh/config to run 'ssh <machine name>')
+func (c *Cluster) WriteSSHConfig(path string) error {
+	conns := make(map[string]*SSHConnInfo)
+	errs := make(map[string]error)
+	for machineName, n := range c.Nodes {
+		info, err := n.SSHConnection()
+		if err != nil {
+			errs[machineName] = err
+			continue
+		}
+
+		conns[machineName] = info
+	}
+
+	if err := fleetError("SSH connection lookup", errs); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(path, []byte(renderSSHConfig(conns)), 0644); err != nil {
+		return fmt.Errorf("Unable to write the SSH configuration '%s': '%s'", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderSSHConfig(t *testing.T) {
+	conns := map[string]*SSHConnInfo{
+		"lille-1": {Host: "chifflet-2.lille.grid5000.fr", Port: 22, User: "root", KeyPath: "/home/jdoe/.docker/machine/machines/lille-1/id_rsa"},
+		"lille-0": {Host: "chifflet-1.lille.grid5000.fr", Port: 22, User: "root", KeyPath: "/home/jdoe/.docker/machine/machines/lille-0/id_rsa", Bastion: "jdoe@access.grid5000.fr"},
+	}
+
+	expected := `Host lille-0
+  HostName chifflet-1.lille.grid5000.fr
+  Port 22
+  User root
+  IdentityFile /home/jdoe/.docker/machine/machines/lille-0/id_rsa
+  IdentitiesOnly yes
+  StrictHostKeyChecking no
+  UserKnownHostsFile /dev/null
+  ProxyJump jdoe@access.grid5000.fr
+
+Host lille-1
+  HostName chifflet-2.lille.grid5000.fr
+  Port 22
+  User root
+  IdentityFile /home/jdoe/.docker/machine/machines/lille-1/id_rsa
+  IdentitiesOnly yes
+  StrictHostKeyChecking no
+  UserKnownHostsFile /dev/null
+
+`
+	assert.Equal(t, expected, renderSSHConfig(conns))
+}
+
+func TestRenderSSHConfigEmpty(t *testing.T) {
+	assert.Equal(t, "", renderSSHConfig(map[string]*SSHConnInfo{}))
+}
@@ -0,0 +1,228 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	g5kdriver "github.com/Spirals-Team/docker-machine-driver-g5k/driver"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/persist"
+)
+
+const (
+	// Swarm roles of a node
+	SwarmRoleManager = "manager" // Swarm mode manager
+	SwarmRoleWorker  = "worker"  // Swarm mode worker
+	SwarmRoleMaster  = "master"  // Swarm standalone master
+	SwarmRoleAgent   = "agent"   // Swarm standalone agent
+
+	// swarmModeStatusCommand returns the Swarm mode state of the node and if it is a manager
+	swarmModeStatusCommand = "docker info --format '{{.Swarm.LocalNodeState}} {{.Swarm.ControlAvailable}}'"
+
+	// engineLabelsCommand returns the labels of the Docker Engine (JSON format)
+	engineLabelsCommand = "docker info --format '{{json .Labels}}'"
+
+	// statusTimeout is the maximum time allowed to get the state of all nodes
+	statusTimeout = 2 * time.Minute
+)
+
+var (
+	// unmanagedEngineLabelKeys are the keys of the Engine labels added by Docker Machine (ex: provider=g5k), not described by the cluster configuration
+	unmanagedEngineLabelKeys = []string{"provider"}
+)
+
+// NodeStatus contain the state of a cluster node
+type NodeStatus struct {
+	MachineName  string   `json:"machine_name"`
+	SwarmRole    string   `json:"swarm_role,omitempty"`
+	EngineLabels []string `json:"engine_labels,omitempty"`
+}
+
+// parseSwarmModeRole returns the Swarm mode role from the output of the Swarm mode status command (empty if not part of a cluster)
+func parseSwarmModeRole(out string) string {
+	fields := strings.Fields(out)
+	if len(fields) != 2 || fields[0] != "active" {
+		return ""
+	}
+
+	if fields[1] == "true" {
+		return SwarmRoleManager
+	}
+
+	return SwarmRoleWorker
+}
+
+// parseEngineLabels returns the Engine labels from the output of the Engine labels command (without the labels added by Docker Machine)
+func parseEngineLabels(out string) ([]string, error) {
+	var labels []string
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &labels); err != nil {
+		return nil, fmt.Errorf("Unable to parse Engine labels: '%s'", err)
+	}
+
+	return withoutUnmanagedLabels(labels), nil
+}
+
+// withoutUnmanagedLabels returns the labels without the labels added by Docker Machine
+func withoutUnmanagedLabels(labels []string) []string {
+	managed := []string{}
+	for _, l := range labels {
+		unmanaged := false
+		for _, key := range unmanagedEngineLabelKeys {
+			if strings.HasPrefix(l, key+"=") {
+				unmanaged = true
+			}
+		}
+
+		if !unmanaged {
+			managed = append(managed, l)
+		}
+	}
+
+	return managed
+}
+
+// desiredStatus returns the state of the node described by the cluster configuration
+func (n *Node) desiredStatus() *NodeStatus {
+	ns := &NodeStatus{
+		MachineName:  n.MachineName,
+		EngineLabels: n.engineLabels(),
+	}
+
+	// Swarm role
+	if n.clusterConfig.SwarmModeGlobalConfig != nil {
+		ns.SwarmRole = SwarmRoleWorker
+		if n.isSwarmMaster() {
+			ns.SwarmRole = SwarmRoleManager
+		}
+	} else if n.clusterConfig.SwarmStandaloneGlobalConfig != nil {
+		ns.SwarmRole = SwarmRoleAgent
+		if n.isSwarmMaster() {
+			ns.SwarmRole = SwarmRoleMaster
+		}
+	}
+
+	return ns
+}
+
+// hostStatus returns the state of the given host
+func hostStatus(h *host.Host) (*NodeStatus, error) {
+	ns := &NodeStatus{MachineName: h.Name}
+
+	// Swarm role (standalone role is stored in the host options)
+	if h.HostOptions != nil && h.HostOptions.SwarmOptions != nil && h.HostOptions.SwarmOptions.IsSwarm {
+		ns.SwarmRole = SwarmRoleAgent
+		if h.HostOptions.SwarmOptions.Master {
+			ns.SwarmRole = SwarmRoleMaster
+		}
+	} else {
+		out, err := h.RunSSHCommand(swarmModeStatusCommand)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get Swarm mode status: '%s'", err)
+		}
+
+		ns.SwarmRole = parseSwarmModeRole(out)
+	}
+
+	// Engine labels
+	out, err := h.RunSSHCommand(engineLabelsCommand)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get Engine labels: '%s'", err)
+	}
+
+	labels, err := parseEngineLabels(out)
+	if err != nil {
+		return nil, err
+	}
+	ns.EngineLabels = labels
+
+	return ns, nil
+}
+
+// loadClusterHosts returns the hosts of the Docker Machine storage belonging to the cluster jobs
+func (c *Cluster) loadClusterHosts() ([]*host.Host, error) {
+	// get cluster jobs
+	jobs := make(map[int]bool)
+	for _, n := range c.Nodes {
+		if n.G5kJobID != 0 {
+			jobs[n.G5kJobID] = true
+		}
+	}
+
+	// load hosts from libmachine storage
+	lst, _, err := persist.LoadAllHosts(c.Config.LibMachineClient)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := []*host.Host{}
+	for _, h := range lst {
+		// only catch Grid'5000 nodes
+		if h.DriverName != "g5k" {
+			continue
+		}
+
+		// get machine driver configuration
+		var drv g5kdriver.Driver
+		if err := json.Unmarshal(h.RawDriver, &drv); err != nil {
+			continue
+		}
+
+		if jobs[drv.G5kJobID] {
+			hosts = append(hosts, h)
+		}
+	}
+
+	return hosts, nil
+}
+
+// Status returns the live state of the cluster nodes (machines of the cluster jobs) by machine name, the nodes are queried in parallel
+func (c *Cluster) Status() (map[string]*NodeStatus, error) {
+	hosts, err := c.loadClusterHosts()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load the cluster machines: '%s'", err)
+	}
+
+	// the machines of the cluster jobs may not be described by the cluster configuration
+	machines := NewCluster(c.Config)
+	for _, h := range hosts {
+		n, ok := c.Nodes[h.Name]
+		if !ok {
+			n = &Node{MachineName: h.Name, clusterConfig: c.Config}
+		}
+		machines.Nodes[h.Name] = n
+	}
+
+	var mu sync.Mutex
+	results := make(map[string]*NodeStatus)
+
+	errs := machines.runOnNodes(statusTimeout, func(n *Node, h *host.Host) error {
+		ns, err := hostStatus(h)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		results[n.MachineName] = ns
+		mu.Unlock()
+
+		return nil
+	})
+
+	if err := fleetError("Status", errs); err != nil {
+		return nil, err
+	}
+
+	// copy the results to not race with the nodes still running after a timeout
+	mu.Lock()
+	defer mu.Unlock()
+
+	status := make(map[string]*NodeStatus)
+	for machineName, ns := range results {
+		status[machineName] = ns
+	}
+
+	return status, nil
+}
@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// killedExitCode is the exit code of the containers killed (SIGKILL) at the end of the stop grace period
+	killedExitCode = "137"
+)
+
+// generateStopContainersCommand returns the command stopping all running containers with the grace period and printing their state once stopped
+func generateStopContainersCommand(grace time.Duration) string {
+	return fmt.Sprintf("ids=$(docker ps -q); [ -z \"$ids\" ] || { docker stop --time %d $ids >/dev/null; docker inspect --format '{{.Name}} {{.State.Running}} {{.State.ExitCode}}' $ids; }",
+		int(math.Ceil(grace.Seconds())))
+}
+
+// parseStoppedContainers returns the names of the containers not stopped in the grace period (killed or still running) from the stop command output
+func parseStoppedContainers(out string) []string {
+	forced := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		if fields[1] == "true" || fields[2] == killedExitCode {
+			forced = append(forced, strings.TrimPrefix(fields[0], "/"))
+		}
+	}
+
+	return forced
+}
+
+// StopHostContainers stop all running containers of the host with the grace period (the containers are killed at its end) and returns the names of the containers not stopped in time
+func StopHostContainers(h *host.Host, grace time.Duration) ([]string, error) {
+	out, err := h.RunSSHCommand(generateStopContainersCommand(grace))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to stop the containers: '%s'", err)
+	}
+
+	forced := parseStoppedContainers(out)
+	if len(forced) > 0 {
+		log.Warnf("The container(s) %s of machine '%s' didn't stop in %s", strings.Join(forced, ", "), h.Name, grace)
+	}
+
+	return forced, nil
+}
+
+// stopContainers stop the running containers of the node before its removal if a graceful stop is configured (failures are only logged to not block the removal)
+func (n *Node) stopContainers(h *host.Host) {
+	if n.clusterConfig.GracefulContainerStop == 0 {
+		return
+	}
+
+	log.Infof("Stopping the containers of node '%s' ('%s')...", n.NodeName, n.MachineName)
+	if _, err := StopHostContainers(h, n.clusterConfig.GracefulContainerStop); err != nil {
+		log.Warnf("The containers of node '%s' ('%s') were not stopped before its removal: %s", n.NodeName, n.MachineName, err)
+	}
+}
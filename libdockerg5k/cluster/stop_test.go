@@ -0,0 +1,20 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateStopContainersCommand(t *testing.T) {
+	assert.Contains(t, generateStopContainersCommand(30*time.Second), "docker stop --time 30 $ids")
+	assert.Contains(t, generateStopContainersCommand(1500*time.Millisecond), "docker stop --time 2 $ids")
+}
+
+func TestParseStoppedContainers(t *testing.T) {
+	out := "/db true 0\n/cache false 137\n/web false 0\n/worker false 1\n"
+	assert.Equal(t, []string{"db", "cache"}, parseStoppedContainers(out))
+
+	assert.Empty(t, parseStoppedContainers(""))
+}
@@ -0,0 +1,99 @@
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine"
+)
+
+// NewMachineStorageClient returns a libmachine client using the given Docker Machine storage directory (the Docker Machine default if empty)
+func NewMachineStorageClient(storagePath string) *libmachine.Client {
+	c := &GlobalConfig{MachineStoragePath: storagePath}
+	return libmachine.NewClient(c.machineStorageDir(), c.machineCertDir())
+}
+
+// machineStorageDir returns the base directory of the Docker Machine storage of the cluster
+func (c *GlobalConfig) machineStorageDir() string {
+	if c.MachineStoragePath != "" {
+		return filepath.Clean(c.MachineStoragePath)
+	}
+
+	return mcndirs.GetBaseDir()
+}
+
+// machineCertDir returns the directory of the certificates (CA and client) of the cluster
+func (c *GlobalConfig) machineCertDir() string {
+	return filepath.Join(c.machineStorageDir(), "certs")
+}
+
+// machinesDir returns the directory of the machines of the cluster
+func (c *GlobalConfig) machinesDir() string {
+	return filepath.Join(c.machineStorageDir(), "machines")
+}
+
+// machineDir returns the directory of the given machine (SSH key, server certificate and configuration)
+func (c *GlobalConfig) machineDir(machineName string) string {
+	return filepath.Join(c.machinesDir(), machineName)
+}
+
+// validateMachineStorage check the machine storage directory is absolute and used by the libmachine client (if set)
+func (c *GlobalConfig) validateMachineStorage() error {
+	if c.MachineStoragePath == "" {
+		return nil
+	}
+
+	if !filepath.IsAbs(c.MachineStoragePath) {
+		return fmt.Errorf("The machine storage directory must be an absolute path: '%s'", c.MachineStoragePath)
+	}
+
+	if c.LibMachineClient != nil && c.LibMachineClient.Filestore != nil && filepath.Clean(c.LibMachineClient.Filestore.Path) != c.machineStorageDir() {
+		return fmt.Errorf("The libmachine client storage '%s' is not the machine storage directory '%s' of the cluster", c.LibMachineClient.Filestore.Path, c.machineStorageDir())
+	}
+
+	return nil
+}
+
+// isWithinDir returns true if the path is the directory or one of its descendants
+func isWithinDir(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// prepareMachineStorage create the directories of the machine storage of the cluster (if set, the Docker Machine default storage is managed by libmachine)
+// the certificates and machines directories can't be symbolic links leading out of the storage: the CA and the machines would be shared with another storage (ex: ~/.docker/machine/certs)
+func (c *GlobalConfig) prepareMachineStorage() error {
+	if c.MachineStoragePath == "" {
+		return nil
+	}
+
+	base := c.machineStorageDir()
+	if err := os.MkdirAll(base, 0700); err != nil {
+		return fmt.Errorf("Failed to create the machine storage directory: '%s'", err)
+	}
+
+	resolvedBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve the machine storage directory: '%s'", err)
+	}
+
+	for _, dir := range []string{c.machineCertDir(), c.machinesDir()} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("Failed to create the machine storage directory: '%s'", err)
+		}
+
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return fmt.Errorf("Failed to resolve the machine storage directory: '%s'", err)
+		}
+
+		if !isWithinDir(resolved, resolvedBase) {
+			return fmt.Errorf("The machine storage directory '%s' is a symbolic link to '%s', outside of the storage '%s'", dir, resolved, base)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package cluster
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMachineStorageDirs(t *testing.T) {
+	c := &GlobalConfig{MachineStoragePath: "/tmp/exp1/machine/"}
+	assert.Equal(t, "/tmp/exp1/machine", c.machineStorageDir())
+	assert.Equal(t, "/tmp/exp1/machine/certs", c.machineCertDir())
+	assert.Equal(t, "/tmp/exp1/machine/machines/lille-0", c.machineDir("lille-0"))
+
+	assert.Equal(t, "/tmp/exp1/machine", NewMachineStorageClient("/tmp/exp1/machine").Filestore.Path)
+}
+
+func TestValidateMachineStorage(t *testing.T) {
+	assert.NoError(t, (&GlobalConfig{}).validateMachineStorage())
+	assert.NoError(t, (&GlobalConfig{MachineStoragePath: "/tmp/exp1", LibMachineClient: NewMachineStorageClient("/tmp/exp1/")}).validateMachineStorage())
+	assert.Error(t, (&GlobalConfig{MachineStoragePath: "exp1"}).validateMachineStorage())
+	assert.Error(t, (&GlobalConfig{MachineStoragePath: "/tmp/exp1", LibMachineClient: NewMachineStorageClient("/tmp/exp2")}).validateMachineStorage())
+}
+
+func TestCreateHostAuthOptionsStorage(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{MachineStoragePath: "/tmp/exp1"}, MachineName: "lille-0"}
+	opts := n.createHostAuthOptions()
+	assert.Equal(t, "/tmp/exp1/certs", opts.CertDir)
+	assert.Equal(t, "/tmp/exp1/certs/ca.pem", opts.CaCertPath)
+	assert.Equal(t, "/tmp/exp1/certs/key.pem", opts.ClientKeyPath)
+	assert.Equal(t, "/tmp/exp1/machines/lille-0/server.pem", opts.ServerCertPath)
+	assert.Equal(t, "/tmp/exp1/machines/lille-0", opts.StorePath)
+}
+
+func TestPrepareMachineStorageConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-g5k-storage")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// two clusters with the same machine names provisioned concurrently in distinct storages
+	bases := []string{filepath.Join(dir, "exp1"), filepath.Join(dir, "exp2")}
+	var wg sync.WaitGroup
+	errs := make([]error, len(bases))
+	for i, base := range bases {
+		wg.Add(1)
+		go func(i int, base string) {
+			defer wg.Done()
+
+			c := NewCluster(&GlobalConfig{MachineStoragePath: base, LibMachineClient: NewMachineStorageClient(base)})
+			c.CreateNodes(map[string]int{"lille": 2})
+			if errs[i] = c.Config.prepareMachineStorage(); errs[i] != nil {
+				return
+			}
+
+			for _, n := range c.Nodes {
+				opts := n.createHostAuthOptions()
+				if errs[i] = os.MkdirAll(opts.StorePath, 0700); errs[i] != nil {
+					return
+				}
+				if errs[i] = ioutil.WriteFile(opts.ServerCertPath, []byte(base), 0600); errs[i] != nil {
+					return
+				}
+			}
+			errs[i] = ioutil.WriteFile(filepath.Join(c.Config.machineCertDir(), "ca.pem"), []byte(base), 0600)
+		}(i, base)
+	}
+	wg.Wait()
+
+	for i, base := range bases {
+		assert.NoError(t, errs[i])
+
+		n := &Node{clusterConfig: &GlobalConfig{MachineStoragePath: base}, MachineName: "lille-1"}
+		opts := n.createHostAuthOptions()
+		for _, path := range []string{opts.CertDir, opts.CaCertPath, opts.CaPrivateKeyPath, opts.ClientCertPath, opts.ClientKeyPath, opts.ServerCertPath, opts.ServerKeyPath, opts.StorePath} {
+			assert.True(t, strings.HasPrefix(path, base+string(filepath.Separator)), path)
+		}
+
+		// each storage only contain the certificates of its cluster
+		for _, path := range []string{opts.CaCertPath, opts.ServerCertPath} {
+			data, err := ioutil.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, base, string(data))
+		}
+	}
+}
+
+func TestPrepareMachineStorageSymlink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "docker-g5k-storage")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, (&GlobalConfig{}).prepareMachineStorage())
+
+	// certificates directory shared with another storage
+	shared := filepath.Join(dir, "shared", "certs")
+	assert.NoError(t, os.MkdirAll(shared, 0700))
+	base := filepath.Join(dir, "exp1")
+	assert.NoError(t, os.MkdirAll(base, 0700))
+	assert.NoError(t, os.Symlink(shared, filepath.Join(base, "certs")))
+	assert.Error(t, (&GlobalConfig{MachineStoragePath: base}).prepareMachineStorage())
+
+	// symbolic link to the storage itself and within the storage are allowed
+	link := filepath.Join(dir, "exp2-link")
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "exp2", "data", "certs"), 0700))
+	assert.NoError(t, os.Symlink(filepath.Join(dir, "exp2"), link))
+	assert.NoError(t, os.Symlink(filepath.Join(link, "data", "certs"), filepath.Join(dir, "exp2", "certs")))
+	assert.NoError(t, (&GlobalConfig{MachineStoragePath: link}).prepareMachineStorage())
+	_, err = os.Stat(filepath.Join(dir, "exp2", "machines"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,214 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// resumeTimeout is the maximum time allowed for the jobs and the Engines of the nodes to be running again after a resume
+	resumeTimeout = 10 * time.Minute
+
+	// resumePollInterval is the delay between two checks of the jobs and the Swarm mode cluster state during a resume
+	resumePollInterval = 10 * time.Second
+
+	// swarmLocalStateCommand returns the Swarm mode state of the node (active, pending, inactive, error, locked)
+	swarmLocalStateCommand = "docker info --format '{{.Swarm.LocalNodeState}}'"
+)
+
+// sortedJobs returns the jobs sorted by site and job ID
+func sortedJobs(jobs map[jobKey][]string) []jobKey {
+	keys := []jobKey{}
+	for k := range jobs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].site != keys[j].site {
+			return keys[i].site < keys[j].site
+		}
+		return keys[i].jobID < keys[j].jobID
+	})
+
+	return keys
+}
+
+// suspendJobs suspend the jobs of the cluster (one at a time) using the given function, the already suspended jobs are resumed if a job can't be suspended
+func (c *Cluster) suspendJobs(suspend func(site string, jobID int) error, resume func(site string, jobID int) error) error {
+	suspended := []jobKey{}
+	for _, k := range sortedJobs(c.nodesByJob()) {
+		if err := suspend(k.site, k.jobID); err != nil {
+			// keep the cluster usable: a partially suspended cluster is of no use
+			for _, s := range suspended {
+				if rerr := resume(s.site, s.jobID); rerr != nil {
+					log.Errorf("Unable to resume the job '%d' on site '%s': '%s'", s.jobID, s.site, rerr)
+				}
+			}
+			return fmt.Errorf("Unable to suspend the job '%d' on site '%s': %w", k.jobID, k.site, err)
+		}
+
+		log.Infof("Job '%d' on site '%s' suspended", k.jobID, k.site)
+		suspended = append(suspended, k)
+	}
+
+	return nil
+}
+
+// SuspendCluster suspend the Grid5000 jobs of the cluster (the nodes keep their state and the walltime still elapses)
+// The g5k.ErrSuspendUnsupported error is returned if a site does not allow to suspend the running jobs, the cluster is then left running
+func (c *Cluster) SuspendCluster() error {
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		return err
+	}
+
+	if err := c.suspendJobs(g5kAPI.SuspendJob, g5kAPI.ResumeJob); err != nil {
+		return err
+	}
+
+	// the machines may change during the suspension, their hosts are loaded again
+	c.Config.hostCache.close()
+	return nil
+}
+
+// waitForJobsRunning wait until all jobs of the cluster are running again
+func (c *Cluster) waitForJobsRunning(deadline time.Time) error {
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		return err
+	}
+
+	for _, k := range sortedJobs(c.nodesByJob()) {
+		for {
+			job, err := g5kAPI.GetJob(k.site, k.jobID)
+			if err != nil {
+				return err
+			}
+			if job.IsRunning() {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("The job '%d' on site '%s' is not running after the resume (state: '%s')", k.jobID, k.site, job.State)
+			}
+			time.Sleep(resumePollInterval)
+		}
+	}
+
+	return nil
+}
+
+// inactiveSwarmNodes returns the machine names (sorted) of the nodes whose Swarm mode state is not active
+func inactiveSwarmNodes(states map[string]string) []string {
+	inactive := []string{}
+	for machineName, state := range states {
+		if state != "active" {
+			inactive = append(inactive, machineName)
+		}
+	}
+	sort.Strings(inactive)
+
+	return inactive
+}
+
+// checkSwarmRecovered wait until the Swarm mode cluster has a leader and all nodes are active again
+func (c *Cluster) checkSwarmRecovered(deadline time.Time) error {
+	for {
+		_, leaderErr := c.Leader()
+
+		var mu sync.Mutex
+		results := make(map[string]string)
+		errs := c.runOnNodes(time.Until(deadline), func(n *Node, h *host.Host) error {
+			out, err := h.RunSSHCommand(swarmLocalStateCommand)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			results[n.MachineName] = strings.TrimSpace(out)
+			mu.Unlock()
+			return nil
+		})
+
+		// copy the results to not race with the nodes still running after a timeout
+		mu.Lock()
+		states := make(map[string]string)
+		for machineName, state := range results {
+			states[machineName] = state
+		}
+		mu.Unlock()
+		for machineName, err := range errs {
+			states[machineName] = fmt.Sprintf("unknown (%s)", err)
+		}
+
+		inactive := inactiveSwarmNodes(states)
+		if leaderErr == nil && len(inactive) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			if leaderErr != nil {
+				return leaderErr
+			}
+			return fmt.Errorf("The Swarm mode node(s) %s are not active after the resume", strings.Join(inactive, ", "))
+		}
+		time.Sleep(resumePollInterval)
+	}
+}
+
+// ResumeCluster resume the suspended Grid5000 jobs of the cluster, wait for the Engines of the nodes and reconcile the cluster state (Weave peers, Swarm mode nodes)
+func (c *Cluster) ResumeCluster() error {
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(resumeTimeout)
+
+	// resume all jobs (even if one fails, to not leave more nodes suspended)
+	errs := make(map[string]error)
+	for k, machineNames := range c.nodesByJob() {
+		if err := g5kAPI.ResumeJob(k.site, k.jobID); err != nil {
+			for _, machineName := range machineNames {
+				errs[machineName] = fmt.Errorf("Unable to resume the job '%d' on site '%s': %w", k.jobID, k.site, err)
+			}
+		}
+	}
+	if err := fleetError("Resume", errs); err != nil {
+		return err
+	}
+
+	if err := c.waitForJobsRunning(deadline); err != nil {
+		return err
+	}
+
+	// load the hosts of the nodes again (the cached hosts may be stale)
+	c.Config.hostCache.close()
+	errs = c.runOnNodes(time.Until(deadline), func(n *Node, h *host.Host) error {
+		return waitForEngine(h, time.Until(deadline))
+	})
+	if err := fleetError("Resume", errs); err != nil {
+		return err
+	}
+
+	// re-peer Weave Net
+	if c.Config.SwarmStandaloneGlobalConfig != nil && c.Config.WeaveNetworkingEnabled {
+		if err := c.ReconfigureWeave(); err != nil {
+			return err
+		}
+	}
+
+	// check the Swarm mode cluster recovered
+	if c.Config.SwarmModeGlobalConfig != nil {
+		if err := c.checkSwarmRecovered(deadline); err != nil {
+			return err
+		}
+	}
+
+	log.Info("Cluster resumed")
+	return nil
+}
@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortedJobs(t *testing.T) {
+	jobs := map[jobKey][]string{{"nancy", 7}: nil, {"lille", 42}: nil, {"lille", 3}: nil}
+	assert.Equal(t, []jobKey{{"lille", 3}, {"lille", 42}, {"nancy", 7}}, sortedJobs(jobs))
+}
+
+func TestSuspendJobs(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.Nodes["lille-0"] = &Node{MachineName: "lille-0", G5kSite: "lille", G5kJobID: 42}
+	c.Nodes["nancy-0"] = &Node{MachineName: "nancy-0", G5kSite: "nancy", G5kJobID: 7}
+
+	suspended := []string{}
+	err := c.suspendJobs(func(site string, jobID int) error {
+		suspended = append(suspended, fmt.Sprintf("%s/%d", site, jobID))
+		return nil
+	}, func(site string, jobID int) error {
+		return fmt.Errorf("unexpected resume")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lille/42", "nancy/7"}, suspended)
+}
+
+func TestSuspendJobsRollback(t *testing.T) {
+	c := NewCluster(&GlobalConfig{})
+	c.Nodes["lille-0"] = &Node{MachineName: "lille-0", G5kSite: "lille", G5kJobID: 42}
+	c.Nodes["nancy-0"] = &Node{MachineName: "nancy-0", G5kSite: "nancy", G5kJobID: 7}
+
+	resumed := []string{}
+	err := c.suspendJobs(func(site string, jobID int) error {
+		if site == "nancy" {
+			return fmt.Errorf("not supported")
+		}
+		return nil
+	}, func(site string, jobID int) error {
+		resumed = append(resumed, fmt.Sprintf("%s/%d", site, jobID))
+		return nil
+	})
+	assert.EqualError(t, err, "Unable to suspend the job '7' on site 'nancy': not supported")
+	assert.Equal(t, []string{"lille/42"}, resumed)
+}
+
+func TestInactiveSwarmNodes(t *testing.T) {
+	assert.Equal(t, []string{"lille-1", "lille-2"}, inactiveSwarmNodes(map[string]string{"lille-0": "active", "lille-2": "pending", "lille-1": "unknown (timeout)"}))
+	assert.Empty(t, inactiveSwarmNodes(map[string]string{"lille-0": "active"}))
+}
@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// disableSwapCommand disable all swap devices and comment the swap entries of the fstab (to keep swap disabled after a reboot)
+	disableSwapCommand = "swapoff -a && sed -i '/^[^#].*[[:space:]]swap[[:space:]]/ s/^/#/' /etc/fstab"
+
+	// swapDevicesCommand returns the active swap devices (one per line, without header)
+	swapDevicesCommand = "tail -n +2 /proc/swaps"
+)
+
+// parseSwapDevices returns the active swap devices from the output of the swap devices command
+func parseSwapDevices(out string) []string {
+	devices := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			devices = append(devices, fields[0])
+		}
+	}
+
+	return devices
+}
+
+// disableSwap disable the swap on the node and check no swap device is still active
+func (n *Node) disableSwap(h *host.Host) error {
+	if _, err := h.RunSSHCommand(disableSwapCommand); err != nil {
+		return fmt.Errorf("Failed to disable the swap: '%s'", err)
+	}
+
+	out, err := h.RunSSHCommand(swapDevicesCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the active swap devices: '%s'", err)
+	}
+
+	if devices := parseSwapDevices(out); len(devices) > 0 {
+		return fmt.Errorf("The swap is still active on: %s", strings.Join(devices, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,12 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSwapDevices(t *testing.T) {
+	assert.Equal(t, []string{}, parseSwapDevices(""))
+	assert.Equal(t, []string{"/dev/sda3", "/swapfile"}, parseSwapDevices("/dev/sda3  partition  3998716  0  -2\n/swapfile  file  1048572  0  -3\n"))
+}
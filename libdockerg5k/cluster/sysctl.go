@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// sysctlConfigPath is the path of the sysctl configuration file written on the nodes (loaded at boot)
+	sysctlConfigPath = "/etc/sysctl.d/90-docker-g5k.conf"
+)
+
+var (
+	// regexSysctlKey match a kernel parameter name (ex: net.core.somaxconn, net.ipv4.conf.eth0.rp_filter)
+	regexSysctlKey = regexp.MustCompile(`^[a-z0-9_]+(\.[a-zA-Z0-9_\-]+)+$`)
+)
+
+// validateSysctl check the kernel parameter name and value
+func validateSysctl(key string, value string) error {
+	if !regexSysctlKey.MatchString(key) {
+		return fmt.Errorf("Invalid sysctl name: '%s' (ex: net.core.somaxconn)", key)
+	}
+
+	if strings.TrimSpace(value) == "" || strings.ContainsAny(value, "'\n\"") {
+		return fmt.Errorf("Invalid value for sysctl '%s': '%s'", key, value)
+	}
+
+	return nil
+}
+
+// validateSysctls check the kernel parameters names and values
+func validateSysctls(sysctls map[string]string) error {
+	for k, v := range sysctls {
+		if err := validateSysctl(k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sysctls returns the kernel parameters of the node (the node parameters take precedence over the Swarm mode data path and the common parameters)
+func (n *Node) sysctls() map[string]string {
+	sysctls := make(map[string]string)
+	for k, v := range n.clusterConfig.CommonSysctls {
+		sysctls[k] = v
+	}
+	if gc := n.clusterConfig.SwarmModeGlobalConfig; gc != nil {
+		for k, v := range gc.DataPathTuning.Sysctls {
+			sysctls[k] = v
+		}
+	}
+	for k, v := range n.Sysctls {
+		sysctls[k] = v
+	}
+
+	return sysctls
+}
+
+// sortedSysctlKeys returns the names of the kernel parameters (sorted)
+func sortedSysctlKeys(sysctls map[string]string) []string {
+	keys := []string{}
+	for k := range sysctls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// generateSysctlConfig returns the content of the sysctl configuration file
+func generateSysctlConfig(sysctls map[string]string) string {
+	var b strings.Builder
+	b.WriteString("# kernel parameters of the docker-g5k cluster\n")
+	for _, k := range sortedSysctlKeys(sysctls) {
+		fmt.Fprintf(&b, "%s = %s\n", k, sysctls[k])
+	}
+
+	return b.String()
+}
+
+// generateApplySysctlsCommand returns the command writing the sysctl configuration file then setting each parameter (the names of the parameters that can't be set are printed)
+func generateApplySysctlsCommand(sysctls map[string]string) string {
+	cmds := []string{fmt.Sprintf("echo '%s' | base64 -d >%s", base64.StdEncoding.EncodeToString([]byte(generateSysctlConfig(sysctls))), sysctlConfigPath)}
+	for _, k := range sortedSysctlKeys(sysctls) {
+		cmds = append(cmds, fmt.Sprintf("{ sysctl -q -w '%s=%s' >/dev/null 2>&1 || echo '%s'; }", k, sysctls[k], k))
+	}
+
+	return strings.Join(cmds, " && ")
+}
+
+// parseFailedSysctls returns the names of the parameters that can't be set from the apply command output
+func parseFailedSysctls(out string) []string {
+	failed := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if k := strings.TrimSpace(line); k != "" {
+			failed = append(failed, k)
+		}
+	}
+
+	return failed
+}
+
+// applySysctls write and load the kernel parameters of the node (nothing is done if the node has no parameters)
+func (n *Node) applySysctls(h *host.Host) error {
+	sysctls := n.sysctls()
+	if len(sysctls) == 0 {
+		return nil
+	}
+
+	out, err := h.RunSSHCommand(generateApplySysctlsCommand(sysctls))
+	if err != nil {
+		return fmt.Errorf("Failed to write the sysctl configuration: '%s'", err)
+	}
+
+	if failed := parseFailedSysctls(out); len(failed) > 0 {
+		return fmt.Errorf("The sysctl(s) %s can't be set (unknown or read-only parameters)", strings.Join(failed, ", "))
+	}
+
+	log.Infof("%d sysctl(s) applied on node '%s' ('%s')", len(sysctls), n.NodeName, n.MachineName)
+	n.appliedSysctls = sysctls
+	return nil
+}
@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSysctls(t *testing.T) {
+	assert.NoError(t, validateSysctls(map[string]string{"net.core.somaxconn": "4096", "net.ipv4.tcp_rmem": "4096 87380 6291456", "net.ipv4.conf.eth0.rp_filter": "0"}))
+
+	assert.Error(t, validateSysctls(map[string]string{"somaxconn": "4096"}))
+	assert.Error(t, validateSysctls(map[string]string{"net.core.somaxconn; reboot": "1"}))
+	assert.Error(t, validateSysctls(map[string]string{"net.core.somaxconn": ""}))
+	assert.Error(t, validateSysctls(map[string]string{"net.core.somaxconn": "1'; reboot; '"}))
+}
+
+func TestNodeSysctls(t *testing.T) {
+	n := &Node{
+		clusterConfig: &GlobalConfig{CommonSysctls: map[string]string{"net.core.somaxconn": "1024", "vm.swappiness": "10"}},
+		Sysctls:       map[string]string{"net.core.somaxconn": "4096"},
+	}
+	assert.Equal(t, map[string]string{"net.core.somaxconn": "4096", "vm.swappiness": "10"}, n.sysctls())
+
+	n = &Node{clusterConfig: &GlobalConfig{}}
+	assert.Empty(t, n.sysctls())
+}
+
+func TestNodeSysctlsDataPath(t *testing.T) {
+	gc := &swarm.SwarmModeGlobalConfig{DataPathTuning: swarm.DataPathTuning{Sysctls: map[string]string{"net.core.rmem_max": "26214400", "vm.swappiness": "1"}}}
+	n := &Node{
+		clusterConfig: &GlobalConfig{CommonSysctls: map[string]string{"vm.swappiness": "10"}, SwarmModeGlobalConfig: gc},
+		Sysctls:       map[string]string{"net.core.rmem_max": "8388608"},
+	}
+	assert.Equal(t, map[string]string{"net.core.rmem_max": "8388608", "vm.swappiness": "1"}, n.sysctls())
+}
+
+func TestGenerateSysctlConfig(t *testing.T) {
+	assert.Equal(t, "# kernel parameters of the docker-g5k cluster\nnet.core.somaxconn = 4096\nvm.swappiness = 10\n", generateSysctlConfig(map[string]string{"vm.swappiness": "10", "net.core.somaxconn": "4096"}))
+}
+
+func TestGenerateApplySysctlsCommand(t *testing.T) {
+	cmd := generateApplySysctlsCommand(map[string]string{"vm.swappiness": "10", "net.core.somaxconn": "4096"})
+	assert.Contains(t, cmd, "| base64 -d >/etc/sysctl.d/90-docker-g5k.conf && ")
+	assert.Contains(t, cmd, "{ sysctl -q -w 'net.core.somaxconn=4096' >/dev/null 2>&1 || echo 'net.core.somaxconn'; } && { sysctl -q -w 'vm.swappiness=10' >/dev/null 2>&1 || echo 'vm.swappiness'; }")
+}
+
+func TestParseFailedSysctls(t *testing.T) {
+	assert.Equal(t, []string{"net.ipv4.unknown", "kernel.version"}, parseFailedSysctls("net.ipv4.unknown\nkernel.version\n"))
+	assert.Empty(t, parseFailedSysctls(""))
+}
@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// systemdDropInDir is the drop-in directory of the Docker Engine service on the nodes
+	systemdDropInDir = "/etc/systemd/system/docker.service.d"
+
+	// systemdDropInPath is the location of the Docker Engine service overrides on the nodes
+	systemdDropInPath = systemdDropInDir + "/docker-g5k.conf"
+)
+
+var (
+	// regexSystemdOverride match a systemd unit setting (format: Section.Key)
+	regexSystemdOverride = regexp.MustCompile("^([A-Z][[:alnum:]]*)\\.([A-Z][[:alnum:]]*)$")
+)
+
+// validateSystemdOverrides check the format of the Docker Engine service overrides
+func validateSystemdOverrides(overrides map[string]string) error {
+	for setting, value := range overrides {
+		if !regexSystemdOverride.MatchString(setting) {
+			return fmt.Errorf("Invalid systemd setting: '%s' (format: Section.Key, ex: Service.LimitNOFILE)", setting)
+		}
+
+		if strings.ContainsAny(value, "\n\r") {
+			return fmt.Errorf("The value of the systemd setting '%s' must be on a single line", setting)
+		}
+	}
+
+	return nil
+}
+
+// generateSystemdDropIn returns the content of the drop-in file of the Docker Engine service (sections and keys are sorted)
+func generateSystemdDropIn(overrides map[string]string) string {
+	// group settings by section
+	sections := make(map[string][]string)
+	for setting, value := range overrides {
+		v := regexSystemdOverride.FindStringSubmatch(setting)
+		if v == nil {
+			continue
+		}
+
+		sections[v[1]] = append(sections[v[1]], fmt.Sprintf("%s=%s", v[2], value))
+	}
+
+	names := []string{}
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var dropIn strings.Builder
+	for _, name := range names {
+		sort.Strings(sections[name])
+		fmt.Fprintf(&dropIn, "[%s]\n%s\n", name, strings.Join(sections[name], "\n"))
+	}
+
+	return dropIn.String()
+}
+
+// applySystemdOverrides write the Docker Engine service drop-in and restart the service (if configured)
+func (n *Node) applySystemdOverrides(h *host.Host) error {
+	if len(n.clusterConfig.EngineSystemdOverrides) == 0 {
+		return nil
+	}
+
+	// write drop-in (base64 encoded to avoid quoting issues)
+	dropIn := base64.StdEncoding.EncodeToString([]byte(generateSystemdDropIn(n.clusterConfig.EngineSystemdOverrides)))
+	if _, err := h.RunSSHCommand(fmt.Sprintf("mkdir -p %s && echo '%s' | base64 -d >%s", systemdDropInDir, dropIn, systemdDropInPath)); err != nil {
+		return fmt.Errorf("Failed to write the Docker Engine service drop-in: '%s'", err)
+	}
+
+	// reload units and restart Docker Engine to apply the overrides
+	if _, err := h.RunSSHCommand("systemctl daemon-reload && systemctl restart docker.service"); err != nil {
+		return fmt.Errorf("Failed to restart the Docker Engine service: '%s'", err)
+	}
+
+	return nil
+}
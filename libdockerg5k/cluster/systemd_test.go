@@ -0,0 +1,29 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSystemdOverrides(t *testing.T) {
+	assert.NoError(t, validateSystemdOverrides(map[string]string{"Service.LimitNOFILE": "1048576", "Unit.After": "network-online.target"}))
+	assert.NoError(t, validateSystemdOverrides(map[string]string{}))
+}
+
+func TestValidateSystemdOverridesIncorrect(t *testing.T) {
+	assert.Error(t, validateSystemdOverrides(map[string]string{"LimitNOFILE": "1048576"}))
+	assert.Error(t, validateSystemdOverrides(map[string]string{"service.LimitNOFILE": "1048576"}))
+	assert.Error(t, validateSystemdOverrides(map[string]string{"Service.Limit-NOFILE": "1048576"}))
+	assert.Error(t, validateSystemdOverrides(map[string]string{"Service.ExecStart": "/bin/true\nExecStartPre=/bin/false"}))
+}
+
+func TestGenerateSystemdDropIn(t *testing.T) {
+	overrides := map[string]string{
+		"Service.TasksMax":    "infinity",
+		"Unit.After":          "network-online.target",
+		"Service.LimitNOFILE": "1048576",
+	}
+
+	assert.Equal(t, "[Service]\nLimitNOFILE=1048576\nTasksMax=infinity\n[Unit]\nAfter=network-online.target\n", generateSystemdDropIn(overrides))
+}
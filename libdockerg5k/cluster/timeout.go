@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// provisioning phases with a timeout
+	PhaseReserve   = "reserve"   // nodes reservation (wait for the job to be running)
+	PhaseCreate    = "create"    // Docker Machine creation (Engine installation and configuration)
+	PhaseMapping   = "mapping"   // static lookup table update
+	PhaseZookeeper = "zookeeper" // Zookeeper server start on a node, and the cluster-level wait for the ensemble leader
+	PhaseWeave     = "weave"     // Weave Net / Discovery start
+	PhaseSwarm     = "swarm"     // Swarm mode cluster initialization / join
+	PhasePlugins   = "plugins"   // provisioning plugins (all the plugins of a node or of the cluster)
+)
+
+var (
+	// defaultPhaseTimeouts contain the default timeout of each provisioning phase
+	defaultPhaseTimeouts = map[string]time.Duration{
+		PhaseReserve:   15 * time.Minute,
+		PhaseCreate:    20 * time.Minute,
+		PhaseMapping:   1 * time.Minute,
+		PhaseZookeeper: 5 * time.Minute,
+		PhaseWeave:     5 * time.Minute,
+		PhaseSwarm:     15 * time.Minute,
+		PhasePlugins:   10 * time.Minute,
+	}
+)
+
+// validatePhaseTimeouts check the phases exist and the timeouts are positive
+func validatePhaseTimeouts(timeouts map[string]time.Duration) error {
+	for phase, timeout := range timeouts {
+		if _, ok := defaultPhaseTimeouts[phase]; !ok {
+			return fmt.Errorf("Unknown provisioning phase: '%s' (supported: reserve, create, mapping, zookeeper, weave, swarm, plugins)", phase)
+		}
+
+		if timeout <= 0 {
+			return fmt.Errorf("The timeout of the provisioning phase '%s' must be positive: '%s'", phase, timeout)
+		}
+	}
+
+	return nil
+}
+
+// PhaseTimeout returns the timeout of the provisioning phase (the default timeout is used if not configured)
+func (c *GlobalConfig) PhaseTimeout(phase string) time.Duration {
+	if timeout, ok := c.PhaseTimeouts[phase]; ok {
+		return timeout
+	}
+
+	return defaultPhaseTimeouts[phase]
+}
+
+// WithTimeout run the function and returns its error, or an ErrTimeout error if it does not return before the timeout
+// (libmachine operations can't be canceled, the function keeps running in background after the timeout)
+func WithTimeout(timeout time.Duration, fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("%w after %s", ErrTimeout, timeout)
+	}
+}
+
+// runPhase run the provisioning phase of the node with its timeout (the phase duration is written to the node log file and to the provisioning metrics)
+func (n *Node) runPhase(phase string, fn func() error) error {
+	start := time.Now()
+	n.logf("Phase '%s' started", phase)
+
+	err := WithTimeout(n.clusterConfig.PhaseTimeout(phase), fn)
+	n.observePhase(phase, start, err)
+
+	if err != nil {
+		n.logf("Phase '%s' failed after %s: %s", phase, time.Since(start), err)
+		return err
+	}
+
+	n.logf("Phase '%s' done in %s", phase, time.Since(start))
+	return nil
+}
@@ -0,0 +1,35 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePhaseTimeouts(t *testing.T) {
+	assert.NoError(t, validatePhaseTimeouts(map[string]time.Duration{PhaseReserve: 10 * time.Minute, PhaseMapping: time.Second}))
+	assert.Error(t, validatePhaseTimeouts(map[string]time.Duration{"deploy": time.Minute}))
+	assert.Error(t, validatePhaseTimeouts(map[string]time.Duration{PhaseSwarm: 0}))
+}
+
+func TestPhaseTimeout(t *testing.T) {
+	c := &GlobalConfig{PhaseTimeouts: map[string]time.Duration{PhaseMapping: time.Second}}
+	assert.Equal(t, time.Second, c.PhaseTimeout(PhaseMapping))
+	assert.Equal(t, defaultPhaseTimeouts[PhaseCreate], c.PhaseTimeout(PhaseCreate))
+}
+
+func TestWithTimeoutReturnError(t *testing.T) {
+	assert.NoError(t, WithTimeout(time.Second, func() error { return nil }))
+	assert.EqualError(t, WithTimeout(time.Second, func() error { return fmt.Errorf("phase error") }), "phase error")
+}
+
+func TestWithTimeoutExpired(t *testing.T) {
+	err := WithTimeout(10*time.Millisecond, func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrTimeout))
+}
@@ -0,0 +1,110 @@
+package cluster
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// caCertificatesDir is the directory of the local CA certificates of the system trust store (Debian)
+	caCertificatesDir = "/usr/local/share/ca-certificates/docker-g5k"
+
+	// dockerCertsDir is the directory of the registries CA certificates of the Docker Engine
+	dockerCertsDir = "/etc/docker/certs.d"
+)
+
+// readCABundle returns the PEM encoded certificates of the CA bundle file (at least one certificate is required)
+func readCABundle(path string) ([][]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the CA bundle '%s': '%s'", path, err)
+	}
+
+	certs := [][]byte{}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("Invalid certificate in the CA bundle '%s': '%s'", path, err)
+		}
+
+		certs = append(certs, pem.EncodeToMemory(block))
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("No PEM certificate found in the CA bundle '%s'", path)
+	}
+
+	return certs, nil
+}
+
+// readCABundles returns the PEM encoded certificates of all the CA bundle files
+func readCABundles(paths []string) ([][]byte, error) {
+	certs := [][]byte{}
+	for _, path := range paths {
+		bundle, err := readCABundle(path)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, bundle...)
+	}
+
+	return certs, nil
+}
+
+// generateInstallCAsCommand returns the command used to install the certificates (one file by certificate) in the system trust store and,
+// as a bundle, in the Docker Engine certificates directory of the registries, then to update the trust store
+func generateInstallCAsCommand(certs [][]byte, registries []string) string {
+	cmds := []string{fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s", caCertificatesDir)}
+	for i, c := range certs {
+		cmds = append(cmds, fmt.Sprintf("echo '%s' | base64 -d >%s/ca-%d.crt", base64.StdEncoding.EncodeToString(c), caCertificatesDir, i))
+	}
+
+	bundle := base64.StdEncoding.EncodeToString(bytes.Join(certs, nil))
+	for _, registry := range registries {
+		dir := fmt.Sprintf("%s/%s", dockerCertsDir, registry)
+		cmds = append(cmds, fmt.Sprintf("mkdir -p %s && echo '%s' | base64 -d >%s/ca.crt", dir, bundle, dir))
+	}
+
+	return strings.Join(append(cmds, "update-ca-certificates"), " && ")
+}
+
+// trustedRegistries returns the registries (sorted) whose CA bundle is written in the Docker Engine certificates directory (the private registries, except the Docker Hub)
+func (c *GlobalConfig) trustedRegistries() []string {
+	registries := []string{}
+	for _, registry := range c.sortedRegistries() {
+		if registry != dockerHubRegistry {
+			registries = append(registries, registry)
+		}
+	}
+
+	return registries
+}
+
+// installTrustedCAs install the trusted CA bundles in the system trust store and the Docker Engine certificates directory of the node, then restart the Engine (if configured)
+func (n *Node) installTrustedCAs(h *host.Host) error {
+	if len(n.clusterConfig.TrustedCABundles) == 0 {
+		return nil
+	}
+
+	certs, err := readCABundles(n.clusterConfig.TrustedCABundles)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.RunSSHCommand(generateInstallCAsCommand(certs, n.clusterConfig.trustedRegistries())); err != nil {
+		return fmt.Errorf("Failed to install the trusted CA certificates: '%s'", err)
+	}
+
+	// the Engine load the system trust store at startup
+	return n.restartEngine(h)
+}
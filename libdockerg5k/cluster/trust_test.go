@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// generateTestCA returns a self-signed PEM encoded CA certificate
+func generateTestCA(t *testing.T, name string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestReadCABundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trust")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "bundle.pem")
+	assert.NoError(t, ioutil.WriteFile(bundle, append(generateTestCA(t, "ca-1"), generateTestCA(t, "ca-2")...), 0644))
+
+	certs, err := readCABundle(bundle)
+	assert.NoError(t, err)
+	assert.Len(t, certs, 2)
+
+	certs, err = readCABundles([]string{bundle, bundle})
+	assert.NoError(t, err)
+	assert.Len(t, certs, 4)
+}
+
+func TestReadCABundleIncorrect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trust")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = readCABundle(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+
+	empty := filepath.Join(dir, "empty.pem")
+	assert.NoError(t, ioutil.WriteFile(empty, []byte("not a certificate"), 0644))
+	_, err = readCABundle(empty)
+	assert.Error(t, err)
+
+	invalid := filepath.Join(dir, "invalid.pem")
+	assert.NoError(t, ioutil.WriteFile(invalid, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")}), 0644))
+	_, err = readCABundle(invalid)
+	assert.Error(t, err)
+}
+
+func TestGenerateInstallCAsCommand(t *testing.T) {
+	cmd := generateInstallCAsCommand([][]byte{[]byte("a"), []byte("b")}, []string{"registry.example.com:5000"})
+	assert.True(t, strings.HasPrefix(cmd, "rm -rf /usr/local/share/ca-certificates/docker-g5k && mkdir -p /usr/local/share/ca-certificates/docker-g5k && "))
+	assert.Contains(t, cmd, "echo 'YQ==' | base64 -d >/usr/local/share/ca-certificates/docker-g5k/ca-0.crt")
+	assert.Contains(t, cmd, "echo 'Yg==' | base64 -d >/usr/local/share/ca-certificates/docker-g5k/ca-1.crt")
+	assert.Contains(t, cmd, "mkdir -p /etc/docker/certs.d/registry.example.com:5000 && echo 'YWI=' | base64 -d >/etc/docker/certs.d/registry.example.com:5000/ca.crt")
+	assert.True(t, strings.HasSuffix(cmd, " && update-ca-certificates"))
+}
+
+func TestTrustedRegistries(t *testing.T) {
+	c := &GlobalConfig{RegistryAuths: map[string]RegistryAuth{"docker.io": {}, "registry.example.com": {}, "a.example.com:5000": {}}}
+	assert.Equal(t, []string{"a.example.com:5000", "registry.example.com"}, c.trustedRegistries())
+}
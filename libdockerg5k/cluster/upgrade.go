@@ -0,0 +1,160 @@
+package cluster
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// defaultEngineInstallURL is the Docker Engine install script used if none is given or configured
+	defaultEngineInstallURL = "https://get.docker.com"
+
+	// upgradeEngineTimeout is the maximum time allowed for the Engine of an upgraded node to respond and rejoin the Swarm mode cluster
+	upgradeEngineTimeout = 10 * time.Minute
+
+	// engineVersionCommand returns the version of the Docker Engine
+	engineVersionCommand = "docker version --format '{{.Server.Version}}'"
+)
+
+var (
+	// regexEngineVersion match a Docker Engine version (ex: 17.06, 17.06.0~ce-0~debian)
+	regexEngineVersion = regexp.MustCompile("^[[:digit:]][[:alnum:].~_-]*$")
+
+	// managersUpgrade allow only one Swarm manager to be upgraded at a time (the managers quorum is kept)
+	managersUpgrade sync.Mutex
+)
+
+// generateEngineUpgradeCommand returns the command used to re-run the install script (selecting the version, if given) and restart the Engine
+func generateEngineUpgradeCommand(installURL string, version string) string {
+	env := ""
+	if version != "" {
+		env = fmt.Sprintf("VERSION=%s ", version)
+	}
+
+	return fmt.Sprintf("curl -sSL %s | %ssh && %s", installURL, env, engineRestartCommand)
+}
+
+// engineVersion returns the version of the Docker Engine of the host
+func engineVersion(h *host.Host) (string, error) {
+	out, err := h.RunSSHCommand(engineVersionCommand)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get the Docker Engine version: '%s'", err)
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+// upgradeEngine re-run the install script on the node and wait for the Engine to respond with the expected version
+func (n *Node) upgradeEngine(h *host.Host, installURL string, version string) (string, error) {
+	if _, err := h.RunSSHCommand(generateEngineUpgradeCommand(installURL, version)); err != nil {
+		return "", fmt.Errorf("Failed to upgrade the Docker Engine: '%s'", err)
+	}
+
+	if err := waitForEngine(h, upgradeEngineTimeout); err != nil {
+		return "", err
+	}
+
+	after, err := engineVersion(h)
+	if err != nil {
+		return "", err
+	}
+
+	if version != "" && !strings.HasPrefix(after, version) {
+		return after, fmt.Errorf("The Docker Engine version is '%s' after the upgrade, not '%s'", after, version)
+	}
+
+	return after, nil
+}
+
+// UpgradeEngine upgrade the Docker Engine of the provisioned node by running the install script again (the configured or default install URL is used if empty)
+// with the given version (latest if empty). The Swarm mode tasks of the node are drained before the upgrade and the node must rejoin the cluster ready afterward.
+// The Swarm managers are upgraded one at a time, the Engine version before and after the upgrade is logged.
+func (n *Node) UpgradeEngine(installURL string, version string) error {
+	if version != "" && !regexEngineVersion.MatchString(version) {
+		return fmt.Errorf("Invalid Docker Engine version: '%s'", version)
+	}
+
+	if installURL == "" {
+		installURL = n.clusterConfig.EngineInstallURL
+	}
+	if installURL == "" {
+		installURL = defaultEngineInstallURL
+	}
+
+	if n.isSwarmMaster() {
+		managersUpgrade.Lock()
+		defer managersUpgrade.Unlock()
+	}
+
+	h, err := n.loadHost()
+	if err != nil {
+		return err
+	}
+
+	before, err := engineVersion(h)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Upgrading the Docker Engine of node '%s' ('%s') from version '%s'...", n.NodeName, n.MachineName, before)
+
+	// without Swarm mode, the Engine is only upgraded
+	if n.clusterConfig.SwarmModeGlobalConfig == nil {
+		after, err := n.upgradeEngine(h, installURL, version)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Docker Engine of node '%s' ('%s') upgraded from version '%s' to '%s'", n.NodeName, n.MachineName, before, after)
+		return nil
+	}
+
+	// the node manage itself if it is a Swarm manager, the bootstrap manager is used otherwise
+	manager := h
+	if !n.isSwarmMaster() {
+		if len(n.clusterConfig.SwarmMasterNode) == 0 {
+			return fmt.Errorf("At least one Swarm master/manager node is required")
+		}
+
+		bootstrap := &Node{clusterConfig: n.clusterConfig, MachineName: n.clusterConfig.SwarmMasterNode[0]}
+		if manager, err = bootstrap.loadHost(); err != nil {
+			return err
+		}
+	}
+
+	// get Swarm mode node ID
+	out, err := h.RunSSHCommand(swarmNodeIDCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the Swarm node ID: '%s'", err)
+	}
+	nodeID := strings.TrimSpace(out)
+
+	// drain the node tasks
+	if err := setSwarmNodeAvailability(manager, nodeID, "drain"); err != nil {
+		return err
+	}
+
+	after, err := n.upgradeEngine(h, installURL, version)
+	if err != nil {
+		return err
+	}
+
+	// wait for the node to rejoin the cluster
+	if err := waitForSwarmNodeReady(manager, nodeID, upgradeEngineTimeout); err != nil {
+		return err
+	}
+
+	// schedule tasks on the node again
+	if err := setSwarmNodeAvailability(manager, nodeID, "active"); err != nil {
+		return err
+	}
+
+	log.Infof("Docker Engine of node '%s' ('%s') upgraded from version '%s' to '%s'", n.NodeName, n.MachineName, before, after)
+	return nil
+}
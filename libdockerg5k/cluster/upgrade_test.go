@@ -0,0 +1,18 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateEngineUpgradeCommand(t *testing.T) {
+	assert.Equal(t, "curl -sSL https://get.docker.com | sh && systemctl restart docker.service", generateEngineUpgradeCommand("https://get.docker.com", ""))
+	assert.Equal(t, "curl -sSL https://get.docker.com | VERSION=17.06 sh && systemctl restart docker.service", generateEngineUpgradeCommand("https://get.docker.com", "17.06"))
+}
+
+func TestUpgradeEngineIncorrectVersion(t *testing.T) {
+	n := &Node{clusterConfig: &GlobalConfig{}, MachineName: "lille-0"}
+	assert.Error(t, n.UpgradeEngine("", "17.06; reboot"))
+	assert.Error(t, n.UpgradeEngine("", "latest"))
+}
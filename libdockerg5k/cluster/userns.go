@@ -0,0 +1,200 @@
+package cluster
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// UsernsRemapDefault is the user namespace remapping using the 'dockremap' user and group created by the Docker Engine
+	UsernsRemapDefault = "default"
+
+	// engineDaemonConfigPath is the location of the Docker Engine configuration file on the nodes
+	engineDaemonConfigPath = "/etc/docker/daemon.json"
+
+	// size of the subordinate IDs range allocated to the remapped user and group
+	usernsRangeSize = 65536
+
+	// engineUsernsCommand returns 'name=userns' in the security options of the Engine if the user namespaces remapping is enabled
+	engineUsernsCommand = "docker info --format '{{range .SecurityOptions}}{{println .}}{{end}}'"
+)
+
+var (
+	// regexUsernsRemap match a user namespace remapping spec (user or user:group, by name or ID)
+	regexUsernsRemap = regexp.MustCompile("^([a-z_][a-z0-9_-]*|[0-9]+)(:([a-z_][a-z0-9_-]*|[0-9]+))?$")
+)
+
+// validateUsernsRemap check the user namespace remapping spec ('default' or user[:group])
+func validateUsernsRemap(spec string) error {
+	if spec == UsernsRemapDefault || regexUsernsRemap.MatchString(spec) {
+		return nil
+	}
+
+	return fmt.Errorf("Invalid user namespace remapping: '%s' (format: default or user[:group])", spec)
+}
+
+// parseUsernsRemap returns the user and group of the user namespace remapping spec (the group is the user if not set)
+func parseUsernsRemap(spec string) (string, string) {
+	v := strings.SplitN(spec, ":", 2)
+	if len(v) == 1 {
+		return v[0], v[0]
+	}
+
+	return v[0], v[1]
+}
+
+// usernsWarnings returns the warnings about the features affected by the user namespace remapping
+func (c *GlobalConfig) usernsWarnings() []string {
+	warnings := []string{}
+
+	if c.UsernsRemap == "" {
+		return warnings
+	}
+
+	warnings = append(warnings, "The containers started with --privileged, --pid=host or --net=host need --userns=host with the user namespace remapping, and the bind mounts need to be accessible by the remapped user")
+
+	if c.WeaveNetworkingEnabled {
+		warnings = append(warnings, "The Weave Net router is a privileged container and may not start with the user namespace remapping")
+	}
+
+	if c.SwarmStandaloneGlobalConfig != nil && c.UseZookeeperClusterStorage {
+		warnings = append(warnings, "The Zookeeper cluster storage uses the host network and may not start with the user namespace remapping")
+	}
+
+	return warnings
+}
+
+// generateSubIDsCommand returns the command creating the user and group of the remapping (if needed) and their subordinate IDs ranges (after the existing ones)
+func generateSubIDsCommand(user string, group string) string {
+	cmds := []string{
+		fmt.Sprintf("(getent group %[1]s >/dev/null || groupadd -r %[1]s)", group),
+		fmt.Sprintf("(getent passwd %[1]s >/dev/null || useradd -r -g %[2]s -s /usr/sbin/nologin %[1]s)", user, group),
+	}
+
+	for _, e := range []struct{ name, file string }{{user, "/etc/subuid"}, {group, "/etc/subgid"}} {
+		cmds = append(cmds, fmt.Sprintf("touch %[2]s && (grep -q '^%[1]s:' %[2]s || echo \"%[1]s:$(awk -F: 'BEGIN {m = 100000} $2 + $3 > m {m = $2 + $3} END {print m}' %[2]s):%[3]d\" >>%[2]s)", e.name, e.file, usernsRangeSize))
+	}
+
+	return strings.Join(cmds, " && ")
+}
+
+// mergeDaemonConfig returns the Docker Engine configuration with the user namespace remapping (the other settings are kept)
+func mergeDaemonConfig(current string, spec string) ([]byte, error) {
+	return mergeDaemonConfigSettings(current, map[string]interface{}{"userns-remap": spec})
+}
+
+// mergeDaemonConfigSettings returns the Docker Engine configuration with the given settings (the other settings are kept)
+func mergeDaemonConfigSettings(current string, settings map[string]interface{}) ([]byte, error) {
+	config := make(map[string]interface{})
+	if strings.TrimSpace(current) != "" {
+		if err := json.Unmarshal([]byte(current), &config); err != nil {
+			return nil, fmt.Errorf("Failed to decode the Docker Engine configuration: '%s'", err)
+		}
+	}
+
+	for k, v := range settings {
+		config[k] = v
+	}
+
+	b, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+// updateDaemonConfig merge the settings in the Docker Engine configuration file of the host (the Engine needs to be restarted)
+func updateDaemonConfig(h *host.Host, settings map[string]interface{}) error {
+	current, err := h.RunSSHCommand(fmt.Sprintf("cat %s 2>/dev/null || true", engineDaemonConfigPath))
+	if err != nil {
+		return fmt.Errorf("Failed to read the Docker Engine configuration: '%s'", err)
+	}
+
+	config, err := mergeDaemonConfigSettings(current, settings)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.RunSSHCommand(fmt.Sprintf("echo '%s' | base64 -d >%s", base64.StdEncoding.EncodeToString(config), engineDaemonConfigPath)); err != nil {
+		return fmt.Errorf("Failed to write the Docker Engine configuration: '%s'", err)
+	}
+
+	return nil
+}
+
+// isUsernsEnabled returns true if the user namespace remapping is in the security options of the Engine
+func isUsernsEnabled(securityOptions string) bool {
+	for _, o := range strings.Split(securityOptions, "\n") {
+		if strings.TrimSpace(o) == "name=userns" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usernsRemap returns the user namespace remapping of the node (empty if disabled or set by a node Engine flag)
+func (n *Node) usernsRemap() string {
+	if getEngineFlagValue(n.EngineOpt, "userns-remap") != "" {
+		return ""
+	}
+
+	return n.clusterConfig.UsernsRemap
+}
+
+// usernsRemapSpec returns the user namespace remapping of the Engine of the node (node Engine flag or cluster configuration, empty if disabled)
+func (n *Node) usernsRemapSpec() string {
+	if spec := getEngineFlagValue(n.EngineOpt, "userns-remap"); spec != "" {
+		return spec
+	}
+
+	return n.clusterConfig.UsernsRemap
+}
+
+// applyUsernsRemap create the subordinate IDs of the remapped user, enable the user namespace remapping in the Engine configuration and restart the Engine (if configured)
+func (n *Node) applyUsernsRemap(h *host.Host) error {
+	if n.clusterConfig.UsernsRemap == "" {
+		return nil
+	}
+
+	// the Engine fails to start if the remapping is given both as flag and in the configuration file
+	spec := n.usernsRemap()
+	if spec == "" {
+		log.Warnf("The user namespace remapping is not applied on node '%s' ('%s') because it uses the '%s' node flag", n.NodeName, n.MachineName, getEngineFlagValue(n.EngineOpt, "userns-remap"))
+		return nil
+	}
+
+	// the 'dockremap' user and its subordinate IDs are created by the Engine
+	if spec != UsernsRemapDefault {
+		if _, err := h.RunSSHCommand(generateSubIDsCommand(parseUsernsRemap(spec))); err != nil {
+			return fmt.Errorf("Failed to create the subordinate IDs of the user namespace remapping: '%s'", err)
+		}
+	}
+
+	if err := updateDaemonConfig(h, map[string]interface{}{"userns-remap": spec}); err != nil {
+		return err
+	}
+
+	n.logf("User namespace remapping: %s", spec)
+	if err := n.restartEngine(h); err != nil {
+		return err
+	}
+
+	out, err := h.RunSSHCommand(engineUsernsCommand)
+	if err != nil {
+		return fmt.Errorf("Failed to get the Engine security options: '%s'", err)
+	}
+
+	if !isUsernsEnabled(out) {
+		return fmt.Errorf("The user namespace remapping is not enabled after the Engine restart")
+	}
+
+	return nil
+}
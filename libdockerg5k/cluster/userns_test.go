@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateUsernsRemap(t *testing.T) {
+	for _, spec := range []string{"default", "dockremap", "testuser:testgroup", "1000:1000"} {
+		assert.NoError(t, validateUsernsRemap(spec), spec)
+	}
+
+	for _, spec := range []string{"Default", "user:", ":group", "user:group:other", "user name"} {
+		assert.Error(t, validateUsernsRemap(spec), spec)
+	}
+}
+
+func TestParseUsernsRemap(t *testing.T) {
+	user, group := parseUsernsRemap("testuser:testgroup")
+	assert.Equal(t, "testuser", user)
+	assert.Equal(t, "testgroup", group)
+
+	user, group = parseUsernsRemap("testuser")
+	assert.Equal(t, "testuser", user)
+	assert.Equal(t, "testuser", group)
+}
+
+func TestGenerateSubIDsCommand(t *testing.T) {
+	assert.Equal(t, "(getent group testgroup >/dev/null || groupadd -r testgroup) && "+
+		"(getent passwd testuser >/dev/null || useradd -r -g testgroup -s /usr/sbin/nologin testuser) && "+
+		"touch /etc/subuid && (grep -q '^testuser:' /etc/subuid || echo \"testuser:$(awk -F: 'BEGIN {m = 100000} $2 + $3 > m {m = $2 + $3} END {print m}' /etc/subuid):65536\" >>/etc/subuid) && "+
+		"touch /etc/subgid && (grep -q '^testgroup:' /etc/subgid || echo \"testgroup:$(awk -F: 'BEGIN {m = 100000} $2 + $3 > m {m = $2 + $3} END {print m}' /etc/subgid):65536\" >>/etc/subgid)",
+		generateSubIDsCommand("testuser", "testgroup"))
+}
+
+func TestMergeDaemonConfig(t *testing.T) {
+	config, err := mergeDaemonConfig("", "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"userns-remap\": \"default\"\n}\n", string(config))
+
+	// the seccomp profile is kept
+	config, err = mergeDaemonConfig("{\"seccomp-profile\": \"/etc/docker/docker-g5k-seccomp.json\"}\n", "testuser")
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"seccomp-profile\": \"/etc/docker/docker-g5k-seccomp.json\",\n  \"userns-remap\": \"testuser\"\n}\n", string(config))
+
+	_, err = mergeDaemonConfig("{", "default")
+	assert.Error(t, err)
+}
+
+func TestMergeDaemonConfigSeccompProfile(t *testing.T) {
+	// the existing settings are kept
+	config, err := mergeDaemonConfigSettings("{\"log-level\": \"warn\"}\n", map[string]interface{}{"seccomp-profile": "/etc/docker/docker-g5k-seccomp.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"log-level\": \"warn\",\n  \"seccomp-profile\": \"/etc/docker/docker-g5k-seccomp.json\"\n}\n", string(config))
+}
+
+func TestIsUsernsEnabled(t *testing.T) {
+	assert.True(t, isUsernsEnabled("name=seccomp,profile=default\nname=userns\n"))
+	assert.False(t, isUsernsEnabled("name=seccomp,profile=default\n"))
+}
+
+func TestNodeUsernsRemap(t *testing.T) {
+	c := &GlobalConfig{UsernsRemap: "default"}
+	n := &Node{clusterConfig: c}
+	assert.Equal(t, "default", n.usernsRemap())
+	assert.Equal(t, "default", n.usernsRemapSpec())
+
+	// the node flag takes precedence
+	n.EngineOpt = []string{"userns-remap=testuser"}
+	assert.Equal(t, "", n.usernsRemap())
+	assert.Equal(t, "testuser", n.usernsRemapSpec())
+}
+
+func TestUsernsWarnings(t *testing.T) {
+	assert.Empty(t, (&GlobalConfig{WeaveNetworkingEnabled: true}).usernsWarnings())
+	assert.Len(t, (&GlobalConfig{UsernsRemap: "default"}).usernsWarnings(), 1)
+	assert.Len(t, (&GlobalConfig{UsernsRemap: "default", WeaveNetworkingEnabled: true}).usernsWarnings(), 2)
+}
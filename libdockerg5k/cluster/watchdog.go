@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+// createMachine run the Docker Machine creation of the node under the create phase timeout (watchdog)
+// libmachine can't cancel the creation, so when it is stuck (ex: the driver waiting on SSH) the half-created machine is removed from the storage and an ErrCreateStuck error is returned
+func (n *Node) createMachine(h *host.Host) error {
+	err := n.runPhase(PhaseCreate, func() error { return n.clusterConfig.LibMachineClient.Create(h) })
+	if err == nil || !errors.Is(err, ErrTimeout) {
+		return err
+	}
+
+	log.Warnf("The machine creation of node '%s' is stuck, removing the machine...", n.MachineName)
+	if exists, existsErr := n.clusterConfig.LibMachineClient.Exists(n.MachineName); existsErr == nil && exists {
+		if removeErr := n.clusterConfig.LibMachineClient.Remove(n.MachineName); removeErr != nil {
+			log.Errorf("Unable to remove the machine of node '%s': '%s'", n.MachineName, removeErr)
+		}
+	}
+
+	return fmt.Errorf("%w: %w", ErrCreateStuck, err)
+}
+
+// stuckJobs returns the jobs to release because the creation of one of their nodes is stuck and none of their nodes was provisioned, and the jobs kept because they have provisioned nodes
+func stuckJobs(jobs map[jobKey][]string, errs map[string]error) ([]jobKey, []jobKey) {
+	release := []jobKey{}
+	kept := []jobKey{}
+	for k, machineNames := range jobs {
+		stuck := false
+		provisioned := false
+		for _, machineName := range machineNames {
+			err, failed := errs[machineName]
+			switch {
+			case !failed:
+				provisioned = true
+			case errors.Is(err, ErrCreateStuck):
+				stuck = true
+			}
+		}
+
+		if !stuck {
+			continue
+		}
+
+		if provisioned {
+			kept = append(kept, k)
+		} else {
+			release = append(release, k)
+		}
+	}
+
+	return release, kept
+}
+
+// releaseStuckJobs cancel the Grid5000 jobs of the nodes whose creation is stuck, if the job has no provisioned node (a job shared with provisioned nodes is kept)
+func (c *Cluster) releaseStuckJobs(errs map[string]error) {
+	jobs := c.nodesByJob()
+	stuck, kept := stuckJobs(jobs, errs)
+	for _, k := range kept {
+		log.Warnf("The job '%d' on site '%s' of a stuck node is kept, it is used by provisioned nodes", k.jobID, k.site)
+	}
+
+	// the jobs not reserved by docker-g5k are kept
+	release := []jobKey{}
+	for _, k := range stuck {
+		if !c.ownsJob(jobs[k]) {
+			log.Warnf("The job '%d' on site '%s' of a stuck node is kept, it was not reserved by docker-g5k", k.jobID, k.site)
+			continue
+		}
+		release = append(release, k)
+	}
+
+	if len(release) == 0 {
+		return
+	}
+
+	g5kAPI, err := c.Config.g5kAPI()
+	if err != nil {
+		log.Errorf("Unable to release the jobs of the stuck nodes: '%s'", err)
+		return
+	}
+
+	for _, k := range release {
+		log.Warnf("Releasing the job '%d' on site '%s' of the stuck nodes...", k.jobID, k.site)
+		if err := g5kAPI.CancelJob(k.site, k.jobID); err != nil {
+			log.Errorf("Unable to cancel the job '%d' on site '%s': '%s'", k.jobID, k.site, err)
+		}
+	}
+}
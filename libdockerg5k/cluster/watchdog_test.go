@@ -0,0 +1,41 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStuckJobs(t *testing.T) {
+	n := &Node{MachineName: "lille-0"}
+	stuck := n.wrapError(ErrMachineCreate, fmt.Errorf("%w: %w", ErrCreateStuck, ErrTimeout))
+
+	jobs := map[jobKey][]string{
+		{"lille", 1}:  {"lille-0", "lille-1"},
+		{"lyon", 2}:   {"lyon-0", "lyon-1"},
+		{"nancy", 3}:  {"nancy-0"},
+		{"rennes", 4}: {"rennes-0"},
+	}
+	errs := map[string]error{
+		"lille-0":  stuck,
+		"lille-1":  errors.New("swarm join failed"),
+		"lyon-0":   stuck,
+		"nancy-0":  errors.New("swarm join failed"),
+		"rennes-0": stuck,
+	}
+
+	release, kept := stuckJobs(jobs, errs)
+	assert.ElementsMatch(t, []jobKey{{"lille", 1}, {"rennes", 4}}, release)
+	assert.Equal(t, []jobKey{{"lyon", 2}}, kept)
+}
+
+func TestCreateStuckErrorIs(t *testing.T) {
+	n := &Node{MachineName: "lille-0"}
+	err := n.wrapError(ErrMachineCreate, fmt.Errorf("%w: %w", ErrCreateStuck, fmt.Errorf("%w after 20m0s", ErrTimeout)))
+	assert.True(t, errors.Is(err, ErrMachineCreate))
+	assert.True(t, errors.Is(err, ErrCreateStuck))
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.EqualError(t, err, "node lille-0: machine creation: machine creation stuck: timeout after 20m0s")
+}
@@ -0,0 +1,151 @@
+package cluster
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/weave"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	// reconfigureWeaveTimeout is the maximum time allowed to reconfigure Weave on all nodes
+	reconfigureWeaveTimeout = 10 * time.Minute
+
+	// rotateWeavePasswordTimeout is the maximum time allowed to restart Weave with the new password on all nodes
+	rotateWeavePasswordTimeout = 5 * time.Minute
+)
+
+// reconfigureWeave restart Weave on the node's host with the current configuration (resetting Weave if its state is broken)
+func (n *Node) reconfigureWeave(h *host.Host) error {
+	if weave.IsWeaveNetRunning(h, n.clusterConfig.WeaveConfig) {
+		// stop Weave and keep its persisted data (IP allocations)
+		if err := weave.StopWeave(h, n.clusterConfig.WeaveConfig); err != nil {
+			return err
+		}
+	} else {
+		log.Warnf("Weave is not running properly on node '%s' ('%s'), resetting it...", n.NodeName, n.MachineName)
+
+		// reset Weave to start from a clean state
+		if err := weave.ResetWeave(h, n.clusterConfig.WeaveConfig); err != nil {
+			return err
+		}
+	}
+
+	return n.runWeave(h)
+}
+
+// ReconfigureWeave re-run Weave Net and Weave Discovery on all nodes of an existing cluster with the current configuration
+func (c *Cluster) ReconfigureWeave() error {
+	// check Weave networking is enabled
+	if c.Config.SwarmStandaloneGlobalConfig == nil || !c.Config.WeaveNetworkingEnabled {
+		return fmt.Errorf("Weave networking is not enabled for this cluster")
+	}
+
+	// check Weave connectors
+	if err := c.validateWeaveConnectors(); err != nil {
+		return err
+	}
+
+	errs := c.runOnNodes(reconfigureWeaveTimeout, func(n *Node, h *host.Host) error {
+		if err := n.reconfigureWeave(h); err != nil {
+			return err
+		}
+
+		log.Infof("Weave reconfigured on node '%s' ('%s')", n.NodeName, n.MachineName)
+		return nil
+	})
+
+	return fleetError("Weave reconfiguration", errs)
+}
+
+// generateWeavePassword returns a new random Weave encryption password
+func generateWeavePassword() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Unable to generate the Weave encryption password: '%s'", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// restartWeave restart Weave on the node's host with the given encryption password (the Weave persisted data are kept)
+func (n *Node) restartWeave(h *host.Host, password string) error {
+	if err := weave.StopWeave(h, n.clusterConfig.WeaveConfig); err != nil {
+		return err
+	}
+
+	return n.startWeave(h, password)
+}
+
+// RotateOverlayKey rotate the Weave encryption password of all nodes of the cluster, and returns the new password
+// Weave does not accept several passwords at the same time, so the routers are all restarted at once with the new password (the traffic is interrupted while they restart).
+// The new password is only kept in the cluster configuration: it needs to be given to the later operations on the cluster (ex: --weave-password), or the new routers won't join the network.
+// If any node fails, the rotated nodes are restarted with the previous password and the cluster keeps using it.
+func (c *Cluster) RotateOverlayKey() (string, error) {
+	// the Swarm mode overlay keys are managed by Swarm
+	if c.Config.SwarmModeGlobalConfig != nil {
+		return "", fmt.Errorf("The Swarm mode overlay encryption keys are rotated automatically by the managers and can't be rotated on demand")
+	}
+
+	// check Weave encryption is enabled
+	if c.Config.SwarmStandaloneGlobalConfig == nil || !c.Config.WeaveNetworkingEnabled {
+		return "", fmt.Errorf("Weave networking is not enabled for this cluster")
+	}
+	if c.Config.WeavePassword == "" {
+		return "", fmt.Errorf("Weave encryption is not enabled for this cluster")
+	}
+
+	// check Weave connectors
+	if err := c.validateWeaveConnectors(); err != nil {
+		return "", err
+	}
+
+	password, err := generateWeavePassword()
+	if err != nil {
+		return "", err
+	}
+
+	var mu sync.Mutex
+	rotated := []string{}
+
+	errs := c.runOnNodes(rotateWeavePasswordTimeout, func(n *Node, h *host.Host) error {
+		if err := n.restartWeave(h, password); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		rotated = append(rotated, n.MachineName)
+		mu.Unlock()
+
+		log.Infof("Weave encryption password rotated on node '%s' ('%s')", n.NodeName, n.MachineName)
+		return nil
+	})
+
+	if len(errs) == 0 {
+		c.Config.WeavePassword = password
+		return password, nil
+	}
+
+	// rollback the rotated nodes to the previous password
+	log.Warn("The Weave encryption password rotation failed, restoring the previous password...")
+
+	mu.Lock()
+	rollback := append([]string{}, rotated...)
+	mu.Unlock()
+
+	if len(rollback) > 0 {
+		rollbackErrs := c.runOnSelectedNodes(&NodeSelector{Names: rollback}, rotateWeavePasswordTimeout, func(n *Node, h *host.Host) error {
+			return n.restartWeave(h, c.Config.WeavePassword)
+		})
+		if rollbackErr := fleetError("Weave encryption password rollback", rollbackErrs); rollbackErr != nil {
+			return "", fmt.Errorf("%s (%s)", fleetError("Weave encryption password rotation", errs), rollbackErr)
+		}
+	}
+
+	return "", fleetError("Weave encryption password rotation", errs)
+}
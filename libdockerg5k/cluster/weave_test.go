@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/swarm"
+)
+
+func TestGenerateWeavePassword(t *testing.T) {
+	p1, err := generateWeavePassword()
+	assert.NoError(t, err)
+	assert.Len(t, p1, 64)
+
+	p2, err := generateWeavePassword()
+	assert.NoError(t, err)
+	assert.NotEqual(t, p1, p2)
+}
+
+func TestRotateOverlayKeyNotSupported(t *testing.T) {
+	// Swarm mode overlay keys
+	c := NewCluster(&GlobalConfig{SwarmModeGlobalConfig: &swarm.SwarmModeGlobalConfig{}})
+	_, err := c.RotateOverlayKey()
+	assert.Error(t, err)
+
+	// Weave without encryption
+	c = NewCluster(&GlobalConfig{SwarmStandaloneGlobalConfig: &swarm.SwarmStandaloneGlobalConfig{}, WeaveNetworkingEnabled: true})
+	_, err = c.RotateOverlayKey()
+	assert.EqualError(t, err, "Weave encryption is not enabled for this cluster")
+	assert.Equal(t, "", c.Config.WeavePassword)
+}
+
+func TestRotateOverlayKey(t *testing.T) {
+	c := newFleetCluster(3, 2)
+	c.Config.SwarmStandaloneGlobalConfig = &swarm.SwarmStandaloneGlobalConfig{}
+	c.Config.WeaveNetworkingEnabled = true
+	c.Config.WeavePassword = "previous"
+
+	// the new password is returned to be given to the later operations
+	password, err := c.RotateOverlayKey()
+	assert.NoError(t, err)
+	assert.NotEqual(t, "previous", password)
+	assert.Len(t, password, 64)
+	assert.Equal(t, password, c.Config.WeavePassword)
+}
@@ -0,0 +1,57 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// AllCapabilities is the capability name granting all the capabilities to a container
+	AllCapabilities = "ALL"
+)
+
+// linuxCapabilities are the Linux capabilities names (without the CAP_ prefix) accepted by Docker
+var linuxCapabilities = map[string]bool{
+	"AUDIT_CONTROL": true, "AUDIT_READ": true, "AUDIT_WRITE": true, "BLOCK_SUSPEND": true, "BPF": true,
+	"CHECKPOINT_RESTORE": true, "CHOWN": true, "DAC_OVERRIDE": true, "DAC_READ_SEARCH": true, "FOWNER": true,
+	"FSETID": true, "IPC_LOCK": true, "IPC_OWNER": true, "KILL": true, "LEASE": true,
+	"LINUX_IMMUTABLE": true, "MAC_ADMIN": true, "MAC_OVERRIDE": true, "MKNOD": true, "NET_ADMIN": true,
+	"NET_BIND_SERVICE": true, "NET_BROADCAST": true, "NET_RAW": true, "PERFMON": true, "SETFCAP": true,
+	"SETGID": true, "SETPCAP": true, "SETUID": true, "SYSLOG": true, "SYS_ADMIN": true,
+	"SYS_BOOT": true, "SYS_CHROOT": true, "SYS_MODULE": true, "SYS_NICE": true, "SYS_PACCT": true,
+	"SYS_PTRACE": true, "SYS_RAWIO": true, "SYS_RESOURCE": true, "SYS_TIME": true, "SYS_TTY_CONFIG": true,
+	"WAKE_ALARM": true,
+}
+
+// NormalizeCapability returns the capability name in the Docker format (upper case, without the CAP_ prefix)
+func NormalizeCapability(name string) (string, error) {
+	c := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "CAP_")
+	if c != AllCapabilities && !linuxCapabilities[c] {
+		return "", fmt.Errorf("Unknown Linux capability: '%s'", name)
+	}
+
+	return c, nil
+}
+
+// ValidateCapabilities check the capabilities names
+func ValidateCapabilities(caps []string) error {
+	for _, c := range caps {
+		if _, err := NormalizeCapability(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CapAddFlags returns the flags adding the (valid) capabilities to a container, each preceded by a space (for 'docker run' commands), empty if there is no capability
+func CapAddFlags(caps []string) string {
+	flags := ""
+	for _, c := range caps {
+		if name, err := NormalizeCapability(c); err == nil {
+			flags += fmt.Sprintf(" --cap-add=%s", name)
+		}
+	}
+
+	return flags
+}
@@ -0,0 +1,33 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCapability(t *testing.T) {
+	for _, name := range []string{"NET_ADMIN", "net_admin", "CAP_NET_ADMIN", " cap_net_admin "} {
+		c, err := NormalizeCapability(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "NET_ADMIN", c, name)
+	}
+
+	c, err := NormalizeCapability("all")
+	assert.NoError(t, err)
+	assert.Equal(t, AllCapabilities, c)
+
+	_, err = NormalizeCapability("NET_MAGIC")
+	assert.Error(t, err)
+}
+
+func TestValidateCapabilities(t *testing.T) {
+	assert.NoError(t, ValidateCapabilities(nil))
+	assert.NoError(t, ValidateCapabilities([]string{"NET_ADMIN", "sys_ptrace"}))
+	assert.Error(t, ValidateCapabilities([]string{"NET_ADMIN", ""}))
+}
+
+func TestCapAddFlags(t *testing.T) {
+	assert.Equal(t, "", CapAddFlags(nil))
+	assert.Equal(t, " --cap-add=NET_ADMIN --cap-add=SYS_PTRACE", CapAddFlags([]string{"cap_net_admin", "SYS_PTRACE"}))
+}
@@ -0,0 +1,30 @@
+package container
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// ManagedLabel is the label of the containers started by docker-g5k on the nodes
+	ManagedLabel = "managed-by=docker-g5k"
+
+	// LabelFlag is the flag adding the managed label to a container (for 'docker run' commands)
+	LabelFlag = "--label " + ManagedLabel
+)
+
+// generateRemoveCommand returns the command used to remove all the containers started by docker-g5k (running or not)
+// the containers are stopped first, with their stop signal and timeout, to let them shut down cleanly
+func generateRemoveCommand() string {
+	return fmt.Sprintf("ids=$(docker ps -aq --filter label=%s); [ -z \"$ids\" ] || { docker stop $ids >/dev/null; docker rm -f $ids; }", ManagedLabel)
+}
+
+// RemoveManagedContainers stop (with their stop signal and timeout) and remove the containers started by docker-g5k on the host
+func RemoveManagedContainers(h *host.Host) error {
+	if _, err := h.RunSSHCommand(generateRemoveCommand()); err != nil {
+		return fmt.Errorf("Failed to remove the docker-g5k containers: '%s'", err)
+	}
+
+	return nil
+}
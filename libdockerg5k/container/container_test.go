@@ -0,0 +1,11 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateRemoveCommand(t *testing.T) {
+	assert.Equal(t, "ids=$(docker ps -aq --filter label=managed-by=docker-g5k); [ -z \"$ids\" ] || { docker stop $ids >/dev/null; docker rm -f $ids; }", generateRemoveCommand())
+}
@@ -0,0 +1,127 @@
+package container
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+// ContainerSpec contain the configuration of a one-shot job container
+type ContainerSpec struct {
+	Image   string   // image of the container
+	Command []string // command and arguments (image default command if empty)
+	Env     []string // environment variables (format: KEY=value)
+	Mounts  []string // bind mounts or volumes (format: source:destination[:options])
+
+	// resource limits (no limit if zero)
+	CPUs     float64 // number of CPUs
+	MemoryMB int     // memory in MB
+}
+
+// JobResult contain the result of a job container
+type JobResult struct {
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// Validate check the job container configuration
+func (s *ContainerSpec) Validate() error {
+	// check image
+	if s.Image == "" {
+		return fmt.Errorf("An image is required for the job container")
+	}
+
+	// check resource limits
+	if s.CPUs < 0 || s.MemoryMB < 0 {
+		return fmt.Errorf("The job container resource limits can't be negative (CPUs: %g, memory: %dMB)", s.CPUs, s.MemoryMB)
+	}
+
+	// check mounts
+	for _, m := range s.Mounts {
+		if len(strings.Split(m, ":")) < 2 {
+			return fmt.Errorf("Invalid job container mount format: '%s' (expected source:destination[:options])", m)
+		}
+	}
+
+	return nil
+}
+
+// shellQuote returns the string quoted for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// generateCreateCommand returns the command used to create the job container
+func (s *ContainerSpec) generateCreateCommand(name string) string {
+	args := []string{"docker", "create", "--name", name, LabelFlag}
+
+	// resource limits
+	if s.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(s.CPUs, 'f', -1, 64))
+	}
+	if s.MemoryMB > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%dm", s.MemoryMB))
+	}
+
+	// environment variables
+	for _, e := range s.Env {
+		args = append(args, "--env", shellQuote(e))
+	}
+
+	// mounts
+	for _, m := range s.Mounts {
+		args = append(args, "--volume", shellQuote(m))
+	}
+
+	args = append(args, shellQuote(s.Image))
+	for _, a := range s.Command {
+		args = append(args, shellQuote(a))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// RunJob run the job container to completion on the host and returns its exit code and logs (the container is removed once finished)
+func RunJob(h *host.Host, name string, spec ContainerSpec) (*JobResult, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	// create container
+	if _, err := h.RunSSHCommand(spec.generateCreateCommand(name)); err != nil {
+		return nil, fmt.Errorf("Failed to create the job container '%s': '%s'", name, err)
+	}
+	defer h.RunSSHCommand(fmt.Sprintf("docker rm -f %s", name))
+
+	// start container
+	if _, err := h.RunSSHCommand(fmt.Sprintf("docker start %s", name)); err != nil {
+		return nil, fmt.Errorf("Failed to start the job container '%s': '%s'", name, err)
+	}
+
+	// wait for container exit
+	out, err := h.RunSSHCommand(fmt.Sprintf("docker wait %s", name))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to wait for the job container '%s': '%s'", name, err)
+	}
+
+	exitCode, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse the job container '%s' exit code: '%s'", name, err)
+	}
+
+	// capture logs (stdout and stderr separately)
+	stdout, err := h.RunSSHCommand(fmt.Sprintf("docker logs %s 2>/dev/null", name))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the job container '%s' stdout: '%s'", name, err)
+	}
+
+	stderr, err := h.RunSSHCommand(fmt.Sprintf("docker logs %s 2>&1 >/dev/null", name))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the job container '%s' stderr: '%s'", name, err)
+	}
+
+	return &JobResult{ExitCode: exitCode, Stdout: stdout, Stderr: stderr}, nil
+}
@@ -0,0 +1,29 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerSpecValidate(t *testing.T) {
+	assert.NoError(t, (&ContainerSpec{Image: "alpine", Mounts: []string{"/tmp/data:/data:ro"}}).Validate())
+	assert.Error(t, (&ContainerSpec{}).Validate())
+	assert.Error(t, (&ContainerSpec{Image: "alpine", CPUs: -1}).Validate())
+	assert.Error(t, (&ContainerSpec{Image: "alpine", Mounts: []string{"/data"}}).Validate())
+}
+
+func TestGenerateCreateCommand(t *testing.T) {
+	spec := ContainerSpec{Image: "alpine"}
+	assert.Equal(t, "docker create --name job-1 --label managed-by=docker-g5k 'alpine'", spec.generateCreateCommand("job-1"))
+
+	spec = ContainerSpec{
+		Image:    "alpine:3.6",
+		Command:  []string{"sh", "-c", "echo 'hello'"},
+		Env:      []string{"RUN=1"},
+		Mounts:   []string{"data:/data"},
+		CPUs:     1.5,
+		MemoryMB: 512,
+	}
+	assert.Equal(t, `docker create --name job-1 --label managed-by=docker-g5k --cpus 1.5 --memory 512m --env 'RUN=1' --volume 'data:/data' 'alpine:3.6' 'sh' '-c' 'echo '\''hello'\'''`, spec.generateCreateCommand("job-1"))
+}
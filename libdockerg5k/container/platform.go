@@ -0,0 +1,71 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+)
+
+// platformArchitectures are the architectures (GOARCH) of the Linux images and their variants
+var platformArchitectures = map[string][]string{
+	"386": {}, "amd64": {"v2", "v3", "v4"}, "arm": {"v5", "v6", "v7"}, "arm64": {"v8"},
+	"mips64le": {}, "ppc64le": {}, "riscv64": {}, "s390x": {},
+}
+
+// machineArchitectures are the architectures of the images matching the machine hardware names (uname -m)
+var machineArchitectures = map[string]string{
+	"x86_64": "amd64", "amd64": "amd64", "i386": "386", "i686": "386", "aarch64": "arm64", "arm64": "arm64",
+	"armv7l": "arm", "armv6l": "arm", "mips64": "mips64le", "ppc64le": "ppc64le", "riscv64": "riscv64", "s390x": "s390x",
+}
+
+// ValidatePlatform check the image platform (format: linux/arch[/variant], ex: linux/amd64, linux/arm64, linux/arm/v7)
+func ValidatePlatform(platform string) error {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] != "linux" {
+		return fmt.Errorf("Invalid platform: '%s' (format: linux/arch[/variant])", platform)
+	}
+
+	variants, ok := platformArchitectures[parts[1]]
+	if !ok {
+		return fmt.Errorf("Unknown platform architecture: '%s'", parts[1])
+	}
+
+	if len(parts) == 3 {
+		for _, v := range variants {
+			if parts[2] == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("Unknown variant '%s' of the platform architecture '%s'", parts[2], parts[1])
+	}
+
+	return nil
+}
+
+// PlatformArchitecture returns the architecture of the image platform (ex: arm64 for linux/arm64/v8), empty if not set
+func PlatformArchitecture(platform string) string {
+	parts := strings.Split(platform, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	return parts[1]
+}
+
+// MachineArchitecture returns the architecture of the images matching the machine hardware name (uname -m), the name itself if unknown
+func MachineArchitecture(machine string) string {
+	machine = strings.TrimSpace(machine)
+	if arch, ok := machineArchitectures[machine]; ok {
+		return arch
+	}
+
+	return machine
+}
+
+// PlatformFlag returns the flag selecting the image platform of a container, preceded by a space (for 'docker run' and 'docker pull' commands), empty if not set
+func PlatformFlag(platform string) string {
+	if platform == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" --platform=%s", platform)
+}
@@ -0,0 +1,31 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePlatform(t *testing.T) {
+	assert.NoError(t, ValidatePlatform("linux/amd64"))
+	assert.NoError(t, ValidatePlatform("linux/arm64"))
+	assert.NoError(t, ValidatePlatform("linux/arm/v7"))
+	assert.Error(t, ValidatePlatform("amd64"))
+	assert.Error(t, ValidatePlatform("windows/amd64"))
+	assert.Error(t, ValidatePlatform("linux/x86_64"))
+	assert.Error(t, ValidatePlatform("linux/arm/v9"))
+	assert.Error(t, ValidatePlatform("linux/amd64/v2/extra"))
+}
+
+func TestPlatformArchitecture(t *testing.T) {
+	assert.Equal(t, "arm64", PlatformArchitecture("linux/arm64/v8"))
+	assert.Equal(t, "", PlatformArchitecture(""))
+	assert.Equal(t, "amd64", MachineArchitecture("x86_64\n"))
+	assert.Equal(t, "arm64", MachineArchitecture("aarch64"))
+	assert.Equal(t, "sparc64", MachineArchitecture("sparc64"))
+}
+
+func TestPlatformFlag(t *testing.T) {
+	assert.Equal(t, "", PlatformFlag(""))
+	assert.Equal(t, " --platform=linux/arm64", PlatformFlag("linux/arm64"))
+}
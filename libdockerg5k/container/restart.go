@@ -0,0 +1,34 @@
+package container
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// DefaultRestartPolicy is the restart policy of the containers started by docker-g5k if not configured
+	DefaultRestartPolicy = "always"
+)
+
+var (
+	// regexRestartPolicy match a valid Docker restart policy (no, always, unless-stopped or on-failure[:max-retries])
+	regexRestartPolicy = regexp.MustCompile("^(no|always|unless-stopped|on-failure(:[[:digit:]]+)?)$")
+)
+
+// ValidateRestartPolicy check the Docker restart policy
+func ValidateRestartPolicy(policy string) error {
+	if !regexRestartPolicy.MatchString(policy) {
+		return fmt.Errorf("Invalid restart policy: '%s' (supported: no, always, unless-stopped, on-failure[:max-retries])", policy)
+	}
+
+	return nil
+}
+
+// RestartFlag returns the flag setting the restart policy of a container (for 'docker run' commands), the default policy is used if empty
+func RestartFlag(policy string) string {
+	if policy == "" {
+		policy = DefaultRestartPolicy
+	}
+
+	return fmt.Sprintf("--restart=%s", policy)
+}
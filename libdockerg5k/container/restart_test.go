@@ -0,0 +1,23 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRestartPolicy(t *testing.T) {
+	assert.NoError(t, ValidateRestartPolicy("no"))
+	assert.NoError(t, ValidateRestartPolicy("always"))
+	assert.NoError(t, ValidateRestartPolicy("unless-stopped"))
+	assert.NoError(t, ValidateRestartPolicy("on-failure"))
+	assert.NoError(t, ValidateRestartPolicy("on-failure:5"))
+	assert.Error(t, ValidateRestartPolicy(""))
+	assert.Error(t, ValidateRestartPolicy("on-failure:"))
+	assert.Error(t, ValidateRestartPolicy("sometimes"))
+}
+
+func TestRestartFlag(t *testing.T) {
+	assert.Equal(t, "--restart=always", RestartFlag(""))
+	assert.Equal(t, "--restart=on-failure:5", RestartFlag("on-failure:5"))
+}
@@ -0,0 +1,69 @@
+package container
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// linuxSignals are the Linux signals names (without the SIG prefix) accepted by Docker
+var linuxSignals = map[string]bool{
+	"ABRT": true, "ALRM": true, "BUS": true, "CHLD": true, "CLD": true, "CONT": true, "FPE": true, "HUP": true,
+	"ILL": true, "INT": true, "IO": true, "IOT": true, "KILL": true, "PIPE": true, "POLL": true, "PROF": true,
+	"PWR": true, "QUIT": true, "SEGV": true, "STKFLT": true, "STOP": true, "SYS": true, "TERM": true, "TRAP": true,
+	"TSTP": true, "TTIN": true, "TTOU": true, "URG": true, "USR1": true, "USR2": true, "VTALRM": true, "WINCH": true,
+	"XCPU": true, "XFSZ": true,
+}
+
+// StopConfig contain the signal and the grace period used to stop a container (the image or Docker default is used for the unset values)
+type StopConfig struct {
+	Signal  string        // signal name (ex: SIGTERM, TERM) or number
+	Timeout time.Duration // time to wait after the signal before killing the container (rounded up to the second)
+}
+
+// ValidateStopSignal check the stop signal name (with or without the SIG prefix, or the signal number)
+func ValidateStopSignal(signal string) error {
+	if n, err := strconv.Atoi(signal); err == nil {
+		if n < 1 || n > 64 {
+			return fmt.Errorf("Invalid stop signal number: '%s' (between 1 and 64)", signal)
+		}
+		return nil
+	}
+
+	name := strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	if linuxSignals[name] || strings.HasPrefix(name, "RTMIN") || strings.HasPrefix(name, "RTMAX") {
+		return nil
+	}
+
+	return fmt.Errorf("Unknown stop signal: '%s'", signal)
+}
+
+// Validate check the stop signal and timeout
+func (s StopConfig) Validate() error {
+	if s.Signal != "" {
+		if err := ValidateStopSignal(s.Signal); err != nil {
+			return err
+		}
+	}
+
+	if s.Timeout < 0 {
+		return fmt.Errorf("The stop timeout needs to be positive: '%s'", s.Timeout)
+	}
+
+	return nil
+}
+
+// Flags returns the flags setting the stop signal and timeout of a container, each preceded by a space (for 'docker run' commands), empty if not set
+func (s StopConfig) Flags() string {
+	flags := ""
+	if s.Signal != "" {
+		flags += fmt.Sprintf(" --stop-signal=%s", s.Signal)
+	}
+	if s.Timeout > 0 {
+		flags += fmt.Sprintf(" --stop-timeout=%d", int(math.Ceil(s.Timeout.Seconds())))
+	}
+
+	return flags
+}
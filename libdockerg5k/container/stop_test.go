@@ -0,0 +1,31 @@
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStopSignal(t *testing.T) {
+	assert.NoError(t, ValidateStopSignal("SIGTERM"))
+	assert.NoError(t, ValidateStopSignal("term"))
+	assert.NoError(t, ValidateStopSignal("SIGRTMIN+3"))
+	assert.NoError(t, ValidateStopSignal("15"))
+	assert.Error(t, ValidateStopSignal("SIGFOO"))
+	assert.Error(t, ValidateStopSignal("0"))
+	assert.Error(t, ValidateStopSignal(""))
+}
+
+func TestStopConfigValidate(t *testing.T) {
+	assert.NoError(t, StopConfig{}.Validate())
+	assert.NoError(t, StopConfig{Signal: "SIGINT", Timeout: 30 * time.Second}.Validate())
+	assert.Error(t, StopConfig{Signal: "SIGFOO"}.Validate())
+	assert.Error(t, StopConfig{Timeout: -time.Second}.Validate())
+}
+
+func TestStopConfigFlags(t *testing.T) {
+	assert.Equal(t, "", StopConfig{}.Flags())
+	assert.Equal(t, " --stop-signal=SIGINT", StopConfig{Signal: "SIGINT"}.Flags())
+	assert.Equal(t, " --stop-signal=SIGTERM --stop-timeout=3", StopConfig{Signal: "SIGTERM", Timeout: 2500 * time.Millisecond}.Flags())
+}
@@ -0,0 +1,90 @@
+package g5k
+
+import (
+	"fmt"
+)
+
+const (
+	// failure domains of the nodes
+	FailureDomainCluster = "cluster" // Grid5000 cluster of the node
+	FailureDomainSwitch  = "switch"  // network switch of the node
+)
+
+// ValidateFailureDomain check the failure domain is supported
+func ValidateFailureDomain(domain string) error {
+	if domain != FailureDomainCluster && domain != FailureDomainSwitch {
+		return fmt.Errorf("Unsupported failure domain: '%s' (supported: '%s', '%s')", domain, FailureDomainCluster, FailureDomainSwitch)
+	}
+
+	return nil
+}
+
+// referenceNodeSwitch returns the switch of the first enabled network adapter of the node
+func referenceNodeSwitch(node *ReferenceNode) string {
+	for _, adapter := range node.NetworkAdapters {
+		if adapter.Enabled && adapter.Mountable && adapter.Switch != "" {
+			return adapter.Switch
+		}
+	}
+
+	return ""
+}
+
+// referenceNodeDomain returns the failure domain of the node described by the Reference API
+func referenceNodeDomain(node *ReferenceNode, domain string) string {
+	if domain == FailureDomainSwitch {
+		return referenceNodeSwitch(node)
+	}
+
+	cluster, _, err := parseNodeHostname(node.UID)
+	if err != nil {
+		return ""
+	}
+
+	return cluster
+}
+
+// CountFailureDomains returns the number of distinct failure domains of the site nodes
+func (g *G5K) CountFailureDomains(site string, domain string) (int, error) {
+	nodes, err := g.GetSiteReferenceNodes(site)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to get the nodes description of site '%s': '%s'", site, err)
+	}
+
+	domains := make(map[string]bool)
+	for i := range nodes {
+		if d := referenceNodeDomain(&nodes[i], domain); d != "" {
+			domains[d] = true
+		}
+	}
+
+	return len(domains), nil
+}
+
+// GetNodesFailureDomain returns the failure domain of each node (hostname => domain)
+func (g *G5K) GetNodesFailureDomain(site string, nodes []string, domain string) (map[string]string, error) {
+	domains := make(map[string]string)
+
+	for _, n := range nodes {
+		// the cluster can be extracted from the node hostname
+		if domain == FailureDomainCluster {
+			cluster, _, err := parseNodeHostname(n)
+			if err != nil {
+				return nil, err
+			}
+
+			domains[n] = cluster
+			continue
+		}
+
+		// get node description from the Reference API
+		refNode, err := g.GetReferenceNode(site, n)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to get the description of node '%s': '%s'", n, err)
+		}
+
+		domains[n] = referenceNodeDomain(refNode, domain)
+	}
+
+	return domains, nil
+}
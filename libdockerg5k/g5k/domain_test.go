@@ -0,0 +1,26 @@
+package g5k
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateFailureDomain(t *testing.T) {
+	assert.NoError(t, ValidateFailureDomain("cluster"))
+	assert.NoError(t, ValidateFailureDomain("switch"))
+	assert.Error(t, ValidateFailureDomain("rack"))
+}
+
+func TestReferenceNodeDomain(t *testing.T) {
+	node := &ReferenceNode{
+		UID: "chifflet-1",
+		NetworkAdapters: []ReferenceNetworkAdapter{
+			{Device: "eth0", Enabled: false, Mountable: false, Switch: "gw"},
+			{Device: "eth1", Enabled: true, Mountable: true, Switch: "sw-2"},
+		},
+	}
+
+	assert.Equal(t, "chifflet", referenceNodeDomain(node, FailureDomainCluster))
+	assert.Equal(t, "sw-2", referenceNodeDomain(node, FailureDomainSwitch))
+}
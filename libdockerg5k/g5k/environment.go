@@ -0,0 +1,74 @@
+package g5k
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Environment contain the description of a deployable environment from the site environments list
+type Environment struct {
+	UID   string `json:"uid"`
+	Name  string `json:"name"`
+	Alias string `json:"alias"`
+}
+
+// IsEnvironmentPath returns true if the image is a path or an URL to an environment description (not resolved against the environments list), false otherwise
+func IsEnvironmentPath(image string) bool {
+	return strings.Contains(image, "/")
+}
+
+// matchEnvironment returns the name of the environment matching the given name (or alias), the error list the available environments names
+func matchEnvironment(environments []Environment, name string) (string, error) {
+	names := make(map[string]bool)
+	for _, e := range environments {
+		if e.Name == name || (e.Alias != "" && e.Alias == name) {
+			return e.Name, nil
+		}
+
+		names[e.Name] = true
+	}
+
+	available := []string{}
+	for n := range names {
+		available = append(available, n)
+	}
+	sort.Strings(available)
+
+	return "", fmt.Errorf("Unknown environment '%s' (available: %s)", name, strings.Join(available, ", "))
+}
+
+// GetSiteEnvironments returns the environments available on the site (the list is cached by site)
+func (g *G5K) GetSiteEnvironments(site string) ([]Environment, error) {
+	if environments, ok := g.sitesEnvironments[site]; ok {
+		return environments, nil
+	}
+
+	var environments []Environment
+	if err := g.getItems(fmt.Sprintf("sites/%s/environments", site), &environments); err != nil {
+		return nil, fmt.Errorf("Unable to get the environments list of site '%s': '%s'", site, err)
+	}
+
+	g.sitesEnvironments[site] = environments
+	return environments, nil
+}
+
+// ResolveEnvironment returns the name of the environment to deploy on the site for the given image (name or alias of an environment of the site)
+// A path or an URL to an environment description is returned as is
+func (g *G5K) ResolveEnvironment(site string, image string) (string, error) {
+	if IsEnvironmentPath(image) {
+		return image, nil
+	}
+
+	environments, err := g.GetSiteEnvironments(site)
+	if err != nil {
+		return "", err
+	}
+
+	name, err := matchEnvironment(environments, image)
+	if err != nil {
+		return "", fmt.Errorf("%s on site '%s'", err, site)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,32 @@
+package g5k
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsEnvironmentPath(t *testing.T) {
+	assert.False(t, IsEnvironmentPath("debian11-x64-std"))
+	assert.True(t, IsEnvironmentPath("/home/jdoe/envs/custom.dsc"))
+	assert.True(t, IsEnvironmentPath("http://public.lille.grid5000.fr/~jdoe/custom.dsc"))
+}
+
+func TestMatchEnvironment(t *testing.T) {
+	environments := []Environment{
+		{UID: "debian11-x64-std_2023062614", Name: "debian11-x64-std", Alias: "debian11-std"},
+		{UID: "debian11-x64-min_2023062614", Name: "debian11-x64-min"},
+		{UID: "debian11-x64-std_2022110115", Name: "debian11-x64-std", Alias: "debian11-std"},
+	}
+
+	name, err := matchEnvironment(environments, "debian11-x64-min")
+	assert.NoError(t, err)
+	assert.Equal(t, "debian11-x64-min", name)
+
+	name, err = matchEnvironment(environments, "debian11-std")
+	assert.NoError(t, err)
+	assert.Equal(t, "debian11-x64-std", name)
+
+	_, err = matchEnvironment(environments, "debian11-x64-sdt")
+	assert.EqualError(t, err, "Unknown environment 'debian11-x64-sdt' (available: debian11-x64-min, debian11-x64-std)")
+}
@@ -10,6 +10,9 @@ type G5K struct {
 	username string
 	password string
 	sitesAPI map[string]*api.Client
+
+	// environments list by site (cached)
+	sitesEnvironments map[string][]Environment
 }
 
 // Init initialize a new G5K struct with the given parameters
@@ -18,6 +21,8 @@ func Init(username string, password string) *G5K {
 		username: username,
 		password: password,
 		sitesAPI: map[string]*api.Client{},
+
+		sitesEnvironments: map[string][]Environment{},
 	}
 }
 
@@ -1,34 +1,225 @@
 package g5k
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/Spirals-Team/docker-machine-driver-g5k/api"
 )
 
-// ReserveNodes allocate a new job with the required number of nodes on the given site, and returns the Job ID
-func (g *G5K) ReserveNodes(site string, nbNodes int, resourceProperties string, walltime string) (int, error) {
-	// create a new job request with given parameters
-	jobReq := api.JobRequest{
-		Resources:  fmt.Sprintf("nodes=%v,walltime=%s", nbNodes, walltime),
-		Command:    "sleep 365d",
-		Properties: resourceProperties,
-		Types:      []string{"deploy"},
+const (
+	// JobTypeDeploy is the OAR job type of the nodes deployed with an environment image (kadeploy)
+	JobTypeDeploy = "deploy"
+
+	// JobTypeClassicSSH is the OAR job type of the nodes reached by SSH in their default environment (no deployment)
+	JobTypeClassicSSH = "allow_classic_ssh"
+)
+
+var (
+	// ErrSuspendUnsupported is returned when the site does not allow to suspend and resume the running jobs
+	ErrSuspendUnsupported = errors.New("suspending the running jobs is not supported")
+
+	// regexJobQueue match a valid OAR queue name
+	regexJobQueue = regexp.MustCompile("^[[:alnum:]_-]+$")
+
+	// regexWalltimeExtension match a walltime extension (format: [+]hours[:minutes[:seconds]])
+	regexWalltimeExtension = regexp.MustCompile("^\\+?[0-9]+(:[0-5][0-9]){0,2}$")
+)
+
+// ValidateJobQueue check the OAR queue name format
+func ValidateJobQueue(queue string) error {
+	if !regexJobQueue.MatchString(queue) {
+		return fmt.Errorf("Invalid OAR queue name: '%s' (only letters, digits, '_' and '-' are allowed)", queue)
 	}
 
-	// get site API client
-	siteAPI := g.getSiteAPI(site)
+	return nil
+}
 
-	// submit job request
-	jobID, err := siteAPI.SubmitJob(jobReq)
+// GenerateResources returns the OAR resources request for the number of nodes and walltime, spreading the given number of nodes on distinct failure domains ('cluster' or 'switch') if set
+func GenerateResources(nbNodes int, walltime string, spreadDomain string, nbSpread int) string {
+	if spreadDomain == "" || nbSpread < 2 {
+		return fmt.Sprintf("nodes=%v,walltime=%s", nbNodes, walltime)
+	}
+
+	// all nodes are spread
+	if nbNodes <= nbSpread {
+		return fmt.Sprintf("/%s=%v/nodes=1,walltime=%s", spreadDomain, nbNodes, walltime)
+	}
+
+	return fmt.Sprintf("/%s=%v/nodes=1+nodes=%v,walltime=%s", spreadDomain, nbSpread, nbNodes-nbSpread, walltime)
+}
+
+// ReserveNodes allocate a new job of the given type with the required number of nodes on the given site (in the given queue, or the default one if empty), and returns the Job ID
+func (g *G5K) ReserveNodes(site string, nbNodes int, resourceProperties string, walltime string, queue string, jobType string) (int, error) {
+	return g.ReserveResources(site, GenerateResources(nbNodes, walltime, "", 0), resourceProperties, queue, jobType)
+}
+
+// ReserveResources allocate a new job of the given type with the given OAR resources request on the given site (in the given queue, or the default one if empty), and returns the Job ID
+func (g *G5K) ReserveResources(site string, resources string, resourceProperties string, queue string, jobType string) (int, error) {
+	jobID, err := g.SubmitResources(site, resources, resourceProperties, queue, jobType)
 	if err != nil {
 		return 0, err
 	}
 
 	// wait until job reach 'ready' state
-	if err := siteAPI.WaitUntilJobIsReady(jobID); err != nil {
+	if err := g.WaitUntilJobIsReady(site, jobID); err != nil {
 		return 0, err
 	}
 
 	return jobID, nil
 }
+
+// SubmitResources submit a new job of the given type (deploy if empty) with the given OAR resources request on the given site (in the given queue, or the default one if empty)
+// without waiting for it to be running, and returns the Job ID
+func (g *G5K) SubmitResources(site string, resources string, resourceProperties string, queue string, jobType string) (int, error) {
+	if jobType == "" {
+		jobType = JobTypeDeploy
+	}
+
+	// create a new job request with given parameters
+	jobReq := api.JobRequest{
+		Resources:  resources,
+		Command:    "sleep 365d",
+		Properties: resourceProperties,
+		Types:      []string{jobType},
+		Queue:      queue,
+	}
+
+	// submit job request
+	return g.getSiteAPI(site).SubmitJob(jobReq)
+}
+
+// WaitUntilJobIsReady wait until the job of the site is running
+func (g *G5K) WaitUntilJobIsReady(site string, jobID int) error {
+	return g.getSiteAPI(site).WaitUntilJobIsReady(jobID)
+}
+
+// JobState contain the state of a job from the Grid5000 API
+type JobState struct {
+	UID         int      `json:"uid"`
+	User        string   `json:"user"`
+	State       string   `json:"state"`
+	Queue       string   `json:"queue"`
+	Walltime    int64    `json:"walltime"`     // in seconds
+	StartedAt   int64    `json:"started_at"`   // Unix timestamp (0 if not started)
+	ScheduledAt int64    `json:"scheduled_at"` // Unix timestamp (0 if not scheduled)
+	Nodes       []string `json:"assigned_nodes"`
+}
+
+// IsRunning returns true if the job is running, false otherwise
+func (j *JobState) IsRunning() bool {
+	return j.State == "running"
+}
+
+// IsSuspended returns true if the job is suspended, false otherwise
+func (j *JobState) IsSuspended() bool {
+	return j.State == "suspended"
+}
+
+// ValidateImport check the job can be used by the given user (the job must belong to the user and be running)
+func (j *JobState) ValidateImport(username string) error {
+	if j.User != username {
+		return fmt.Errorf("The job '%d' belongs to the user '%s', not '%s'", j.UID, j.User, username)
+	}
+
+	if !j.IsRunning() {
+		return fmt.Errorf("The job '%d' is not running (state: '%s')", j.UID, j.State)
+	}
+
+	if len(j.Nodes) == 0 {
+		return fmt.Errorf("The job '%d' has no assigned nodes", j.UID)
+	}
+
+	return nil
+}
+
+// EndTime returns the scheduled end time of the job (zero time if the job is not started or scheduled)
+func (j *JobState) EndTime() time.Time {
+	start := j.StartedAt
+	if start == 0 {
+		start = j.ScheduledAt
+	}
+
+	if start == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(start+j.Walltime, 0)
+}
+
+// GetJob returns the state of the job from the Grid5000 API
+func (g *G5K) GetJob(site string, jobID int) (*JobState, error) {
+	var job JobState
+	if err := g.getJSON(fmt.Sprintf("sites/%s/jobs/%d", site, jobID), &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// CancelJob kill the job (the nodes of the job are released)
+func (g *G5K) CancelJob(site string, jobID int) error {
+	return g.getSiteAPI(site).KillJob(jobID)
+}
+
+// ValidateWalltimeExtension check the walltime extension format and returns it in the OAR format (+hours[:minutes[:seconds]])
+func ValidateWalltimeExtension(additional string) (string, error) {
+	if !regexWalltimeExtension.MatchString(additional) {
+		return "", fmt.Errorf("Invalid walltime extension: '%s' (format: [+]hours[:minutes[:seconds]], ex: +1:30)", additional)
+	}
+
+	return "+" + strings.TrimPrefix(additional, "+"), nil
+}
+
+// ExtendJobWalltime request a walltime extension of the job (the request can be refused by the scheduler, depending on the site and queue policies)
+func (g *G5K) ExtendJobWalltime(site string, jobID int, additional string) error {
+	walltime, err := ValidateWalltimeExtension(additional)
+	if err != nil {
+		return err
+	}
+
+	return g.postJSON(fmt.Sprintf("sites/%s/jobs/%d/walltime", site, jobID), map[string]string{"walltime": walltime}, nil)
+}
+
+// isUnsupportedAction returns true if the API error means the job action is not available on the site
+func isUnsupportedAction(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusForbidden, http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+
+	return false
+}
+
+// SuspendJob suspend the running job (OAR hold of a running job), ErrSuspendUnsupported is returned if the site does not allow it
+func (g *G5K) SuspendJob(site string, jobID int) error {
+	if err := g.postJSON(fmt.Sprintf("sites/%s/jobs/%d/rholds", site, jobID), map[string]string{}, nil); err != nil {
+		if isUnsupportedAction(err) {
+			return fmt.Errorf("%w (site '%s'): %w", ErrSuspendUnsupported, site, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ResumeJob resume the suspended job, ErrSuspendUnsupported is returned if the site does not allow it
+func (g *G5K) ResumeJob(site string, jobID int) error {
+	if err := g.postJSON(fmt.Sprintf("sites/%s/jobs/%d/resumptions", site, jobID), map[string]string{}, nil); err != nil {
+		if isUnsupportedAction(err) {
+			return fmt.Errorf("%w (site '%s'): %w", ErrSuspendUnsupported, site, err)
+		}
+		return err
+	}
+
+	return nil
+}
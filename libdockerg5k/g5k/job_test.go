@@ -0,0 +1,78 @@
+package g5k
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateJobQueue(t *testing.T) {
+	assert.NoError(t, ValidateJobQueue("production"))
+	assert.NoError(t, ValidateJobQueue("besteffort"))
+	assert.NoError(t, ValidateJobQueue("my_group-queue2"))
+}
+
+func TestValidateJobQueueIncorrect(t *testing.T) {
+	assert.Error(t, ValidateJobQueue(""))
+	assert.Error(t, ValidateJobQueue("prod queue"))
+	assert.Error(t, ValidateJobQueue("prod;rm"))
+}
+
+func TestJobStateEndTime(t *testing.T) {
+	assert.Equal(t, time.Unix(1000+3600, 0), (&JobState{StartedAt: 1000, ScheduledAt: 900, Walltime: 3600}).EndTime())
+	assert.Equal(t, time.Unix(900+3600, 0), (&JobState{ScheduledAt: 900, Walltime: 3600}).EndTime())
+	assert.True(t, (&JobState{Walltime: 3600}).EndTime().IsZero())
+}
+
+func TestJobStateIsRunning(t *testing.T) {
+	assert.True(t, (&JobState{State: "running"}).IsRunning())
+	assert.False(t, (&JobState{State: "terminated"}).IsRunning())
+}
+
+func TestGenerateResources(t *testing.T) {
+	assert.Equal(t, "nodes=16,walltime=1:00:00", GenerateResources(16, "1:00:00", "", 0))
+	assert.Equal(t, "nodes=16,walltime=1:00:00", GenerateResources(16, "1:00:00", "cluster", 1))
+	assert.Equal(t, "/cluster=3/nodes=1+nodes=13,walltime=1:00:00", GenerateResources(16, "1:00:00", "cluster", 3))
+	assert.Equal(t, "/switch=3/nodes=1,walltime=1:00:00", GenerateResources(3, "1:00:00", "switch", 3))
+}
+
+func TestJobStateValidateImport(t *testing.T) {
+	job := &JobState{UID: 42, User: "jdoe", State: "running", Nodes: []string{"graphene-1.nancy.grid5000.fr"}}
+	assert.NoError(t, job.ValidateImport("jdoe"))
+	assert.Error(t, job.ValidateImport("other"))
+
+	job.State = "waiting"
+	assert.Error(t, job.ValidateImport("jdoe"))
+
+	job = &JobState{UID: 42, User: "jdoe", State: "running"}
+	assert.Error(t, job.ValidateImport("jdoe"))
+}
+
+func TestValidateWalltimeExtension(t *testing.T) {
+	for _, v := range [][2]string{{"1", "+1"}, {"+1:30", "+1:30"}, {"2:00:00", "+2:00:00"}} {
+		walltime, err := ValidateWalltimeExtension(v[0])
+		assert.NoError(t, err)
+		assert.Equal(t, v[1], walltime)
+	}
+}
+
+func TestValidateWalltimeExtensionIncorrect(t *testing.T) {
+	for _, v := range []string{"", "-1:00", "1:60", "1h", "1:00:00:00"} {
+		_, err := ValidateWalltimeExtension(v)
+		assert.Error(t, err)
+	}
+}
+
+func TestJobStateIsSuspended(t *testing.T) {
+	assert.True(t, (&JobState{State: "suspended"}).IsSuspended())
+	assert.False(t, (&JobState{State: "running"}).IsSuspended())
+}
+
+func TestIsUnsupportedAction(t *testing.T) {
+	assert.True(t, isUnsupportedAction(&APIError{Path: "sites/lille/jobs/1/rholds", StatusCode: 403, Status: "403 Forbidden"}))
+	assert.True(t, isUnsupportedAction(fmt.Errorf("wrapped: %w", &APIError{StatusCode: 404, Status: "404 Not Found"})))
+	assert.False(t, isUnsupportedAction(&APIError{StatusCode: 500, Status: "500 Internal Server Error"}))
+	assert.False(t, isUnsupportedAction(fmt.Errorf("connection refused")))
+}
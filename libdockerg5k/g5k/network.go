@@ -0,0 +1,94 @@
+package g5k
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// regexEthernetRequirement match an Ethernet network requirement with its minimum rate in Gbps (ex: eth25g)
+	regexEthernetRequirement = regexp.MustCompile("^eth([[:digit:]]+)g$")
+)
+
+// NetworkRequirement contain the network capability required on the nodes
+type NetworkRequirement struct {
+	Interface   string  // network adapter interface type (as in the Reference API)
+	MinRateGbps float64 // minimum rate of the network adapter
+}
+
+// ParseNetworkRequirement returns the network requirement from its name ('ib' for InfiniBand, 'eth{rate}g' for Ethernet with a minimum rate in Gbps)
+func ParseNetworkRequirement(name string) (*NetworkRequirement, error) {
+	if name == "ib" {
+		return &NetworkRequirement{Interface: "InfiniBand"}, nil
+	}
+
+	if m := regexEthernetRequirement.FindStringSubmatch(name); m != nil {
+		rate, err := strconv.Atoi(m[1])
+		if err != nil || rate < 1 {
+			return nil, fmt.Errorf("Invalid Ethernet rate in network requirement: '%s'", name)
+		}
+
+		return &NetworkRequirement{Interface: "Ethernet", MinRateGbps: float64(rate)}, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported network requirement: '%s' (supported: 'ib', 'eth{rate}g')", name)
+}
+
+// OARProperties returns the OAR resource properties (SQL format) selecting the nodes satisfying the requirement
+func (r *NetworkRequirement) OARProperties() string {
+	if r.Interface == "InfiniBand" {
+		return "ib<>'NO'"
+	}
+
+	return fmt.Sprintf("eth_rate>=%v", r.MinRateGbps)
+}
+
+// Matches returns true if the network adapter satisfies the requirement, false otherwise
+func (r *NetworkRequirement) Matches(adapter ReferenceNetworkAdapter) bool {
+	return adapter.Enabled && adapter.Mountable && adapter.Interface == r.Interface && adapter.Rate >= r.MinRateGbps*1e9
+}
+
+// MatchingDevice returns the name of the first network device of the node satisfying the requirement
+func (r *NetworkRequirement) MatchingDevice(node *ReferenceNode) (string, error) {
+	for _, adapter := range node.NetworkAdapters {
+		if r.Matches(adapter) {
+			return adapter.Device, nil
+		}
+	}
+
+	return "", fmt.Errorf("The node '%s' has no network adapter satisfying the network requirement", node.UID)
+}
+
+// CombineResourceProperties returns the resource properties (SQL format) requiring all the given properties
+func CombineResourceProperties(properties ...string) string {
+	combined := ""
+	for _, p := range properties {
+		if p == "" {
+			continue
+		}
+
+		if combined != "" {
+			combined += " and "
+		}
+		combined += fmt.Sprintf("(%s)", p)
+	}
+
+	return combined
+}
+
+// CheckNetworkRequirement check at least one node of the site satisfies the network requirement
+func (g *G5K) CheckNetworkRequirement(site string, r *NetworkRequirement) error {
+	nodes, err := g.GetSiteReferenceNodes(site)
+	if err != nil {
+		return fmt.Errorf("Unable to get the nodes description of site '%s': '%s'", site, err)
+	}
+
+	for i := range nodes {
+		if _, err := r.MatchingDevice(&nodes[i]); err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("No node of site '%s' satisfies the network requirement", site)
+}
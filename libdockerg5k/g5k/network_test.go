@@ -0,0 +1,63 @@
+package g5k
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNetworkRequirementInfiniBand(t *testing.T) {
+	r, err := ParseNetworkRequirement("ib")
+	assert.NoError(t, err)
+	assert.Equal(t, "ib<>'NO'", r.OARProperties())
+}
+
+func TestParseNetworkRequirementEthernet(t *testing.T) {
+	r, err := ParseNetworkRequirement("eth25g")
+	assert.NoError(t, err)
+	assert.Equal(t, "eth_rate>=25", r.OARProperties())
+}
+
+func TestParseNetworkRequirementIncorrect(t *testing.T) {
+	_, err := ParseNetworkRequirement("wifi")
+	assert.Error(t, err)
+
+	_, err = ParseNetworkRequirement("eth0g")
+	assert.Error(t, err)
+}
+
+func TestMatchingDevice(t *testing.T) {
+	node := &ReferenceNode{
+		UID: "chifflet-1",
+		NetworkAdapters: []ReferenceNetworkAdapter{
+			{Device: "eth0", Interface: "Ethernet", Rate: 10e9, Enabled: true, Mountable: true},
+			{Device: "eth1", Interface: "Ethernet", Rate: 25e9, Enabled: false, Mountable: false},
+			{Device: "eth2", Interface: "Ethernet", Rate: 25e9, Enabled: true, Mountable: true},
+		},
+	}
+
+	r, _ := ParseNetworkRequirement("eth25g")
+	device, err := r.MatchingDevice(node)
+	assert.NoError(t, err)
+	assert.Equal(t, "eth2", device)
+
+	r, _ = ParseNetworkRequirement("ib")
+	_, err = r.MatchingDevice(node)
+	assert.Error(t, err)
+}
+
+func TestCombineResourceProperties(t *testing.T) {
+	assert.Equal(t, "", CombineResourceProperties("", ""))
+	assert.Equal(t, "(ib<>'NO')", CombineResourceProperties("", "ib<>'NO'"))
+	assert.Equal(t, "(memnode > 8192) and (ib<>'NO')", CombineResourceProperties("memnode > 8192", "ib<>'NO'"))
+}
+
+func TestParseNodeHostname(t *testing.T) {
+	cluster, uid, err := parseNodeHostname("chifflet-1.lille.grid5000.fr")
+	assert.NoError(t, err)
+	assert.Equal(t, "chifflet", cluster)
+	assert.Equal(t, "chifflet-1", uid)
+
+	_, _, err = parseNodeHostname("frontend.lille.grid5000.fr")
+	assert.Error(t, err)
+}
@@ -0,0 +1,200 @@
+package g5k
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// g5kAPIURL is the base URL of the Grid5000 API
+	g5kAPIURL = "https://api.grid5000.fr/stable"
+
+	// g5kAPITimeout is the maximum time of a request to the Grid5000 API
+	g5kAPITimeout = time.Minute
+)
+
+// g5kAPIClient is the HTTP client of the Grid5000 API requests (a stalled request fails after the timeout)
+var g5kAPIClient = &http.Client{Timeout: g5kAPITimeout}
+
+// ReferenceNetworkAdapter contain the description of a node network adapter from the Reference API
+type ReferenceNetworkAdapter struct {
+	Device    string  `json:"device"`
+	Interface string  `json:"interface"`
+	Rate      float64 `json:"rate"`
+	Enabled   bool    `json:"enabled"`
+	Mountable bool    `json:"mountable"`
+	Switch    string  `json:"switch"`
+}
+
+// ReferenceProcessor contain the description of a node processor from the Reference API
+type ReferenceProcessor struct {
+	Model            string `json:"model"`
+	Version          string `json:"version"`
+	OtherDescription string `json:"other_description"`
+}
+
+// ReferenceArchitecture contain the number of processors/cores/threads of a node from the Reference API
+type ReferenceArchitecture struct {
+	NbProcs   int `json:"nb_procs"`
+	NbCores   int `json:"nb_cores"`
+	NbThreads int `json:"nb_threads"`
+}
+
+// ReferenceMainMemory contain the description of a node memory from the Reference API
+type ReferenceMainMemory struct {
+	RAMSize int64 `json:"ram_size"` // in bytes
+}
+
+// ReferenceGPUDevice contain the description of a node GPU from the Reference API
+type ReferenceGPUDevice struct {
+	Vendor string `json:"vendor"`
+	Model  string `json:"model"`
+}
+
+// ReferenceNode contain the description of a node from the Reference API
+type ReferenceNode struct {
+	UID             string                        `json:"uid"`
+	NetworkAdapters []ReferenceNetworkAdapter     `json:"network_adapters"`
+	Processor       ReferenceProcessor            `json:"processor"`
+	Architecture    ReferenceArchitecture         `json:"architecture"`
+	MainMemory      ReferenceMainMemory           `json:"main_memory"`
+	GPUDevices      map[string]ReferenceGPUDevice `json:"gpu_devices"`
+}
+
+// APIError is returned when the Grid5000 API respond with an error status
+type APIError struct {
+	Path       string
+	StatusCode int
+	Status     string
+}
+
+// Error returns the description of the API error
+func (e *APIError) Error() string {
+	return fmt.Sprintf("The Grid5000 API returned an error for '%s': '%s'", e.Path, e.Status)
+}
+
+// referenceItems contain the items of a Reference API collection
+type referenceItems struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+// requestJSON send a request with the given JSON body (if not nil) to the given path of the Grid5000 API and unmarshal the JSON response (if v is not nil)
+func (g *G5K) requestJSON(method string, path string, body interface{}, v interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/%s", g5kAPIURL, path), reqBody)
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(g.username, g.password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g5kAPIClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{Path: path, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// getJSON request the given path of the Grid5000 API and unmarshal the JSON response
+func (g *G5K) getJSON(path string, v interface{}) error {
+	return g.requestJSON("GET", path, nil, v)
+}
+
+// postJSON send the JSON body to the given path of the Grid5000 API and unmarshal the JSON response (if v is not nil)
+func (g *G5K) postJSON(path string, body interface{}, v interface{}) error {
+	return g.requestJSON("POST", path, body, v)
+}
+
+// getItems request the given Reference API collection and unmarshal its items
+func (g *G5K) getItems(path string, v interface{}) error {
+	var items referenceItems
+	if err := g.getJSON(path, &items); err != nil {
+		return err
+	}
+
+	// unmarshal items as a JSON array
+	data, err := json.Marshal(items.Items)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// GetSiteReferenceNodes returns the description of all nodes of the site from the Reference API
+func (g *G5K) GetSiteReferenceNodes(site string) ([]ReferenceNode, error) {
+	// get clusters of the site
+	var clusters []struct {
+		UID string `json:"uid"`
+	}
+	if err := g.getItems(fmt.Sprintf("sites/%s/clusters", site), &clusters); err != nil {
+		return nil, err
+	}
+
+	// get nodes of each cluster
+	nodes := []ReferenceNode{}
+	for _, c := range clusters {
+		var clusterNodes []ReferenceNode
+		if err := g.getItems(fmt.Sprintf("sites/%s/clusters/%s/nodes", site, c.UID), &clusterNodes); err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, clusterNodes...)
+	}
+
+	return nodes, nil
+}
+
+// parseNodeHostname returns the cluster and the node UID from a node hostname (format: {cluster}-{id}.{site}.grid5000.fr)
+func parseNodeHostname(nodeName string) (string, string, error) {
+	uid := strings.SplitN(nodeName, ".", 2)[0]
+
+	i := strings.LastIndex(uid, "-")
+	if i <= 0 {
+		return "", "", fmt.Errorf("Unable to extract the cluster name from the node hostname '%s'", nodeName)
+	}
+
+	return uid[:i], uid, nil
+}
+
+// GetReferenceNode returns the description of the node from the Reference API
+func (g *G5K) GetReferenceNode(site string, nodeName string) (*ReferenceNode, error) {
+	cluster, uid, err := parseNodeHostname(nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	var node ReferenceNode
+	if err := g.getJSON(fmt.Sprintf("sites/%s/clusters/%s/nodes/%s", site, cluster, uid), &node); err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
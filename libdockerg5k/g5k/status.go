@@ -0,0 +1,52 @@
+package g5k
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NodeStatus contain the state of a node from the Status API
+type NodeStatus struct {
+	Hard string `json:"hard"` // alive, absent, suspected or dead
+	Soft string `json:"soft"` // free, busy, besteffort or unknown
+}
+
+// IsFree returns true if the node is alive and not used by a job, false otherwise
+func (s *NodeStatus) IsFree() bool {
+	return s.Hard == "alive" && s.Soft == "free"
+}
+
+// siteStatus contain the status of the nodes of a site (by node hostname) from the Status API
+type siteStatus struct {
+	Nodes map[string]NodeStatus `json:"nodes"`
+}
+
+// nodesStatusByUID returns the status of the nodes by node UID (the hostnames format is {cluster}-{id}.{site}.grid5000.fr)
+func nodesStatusByUID(status map[string]NodeStatus) map[string]NodeStatus {
+	nodes := make(map[string]NodeStatus)
+	for hostname, s := range status {
+		nodes[strings.SplitN(hostname, ".", 2)[0]] = s
+	}
+
+	return nodes
+}
+
+// CheckSiteAccess request the description of the site, to check the Grid5000 API is reachable, the credentials are valid and the site exists
+// an API error (*APIError) is returned if the API responded with an error status
+func (g *G5K) CheckSiteAccess(site string) error {
+	var s struct {
+		UID string `json:"uid"`
+	}
+
+	return g.getJSON(fmt.Sprintf("sites/%s", site), &s)
+}
+
+// GetSiteNodesStatus returns the status of the nodes of the site (by node UID) from the Status API
+func (g *G5K) GetSiteNodesStatus(site string) (map[string]NodeStatus, error) {
+	var status siteStatus
+	if err := g.getJSON(fmt.Sprintf("sites/%s/status", site), &status); err != nil {
+		return nil, fmt.Errorf("Unable to get the nodes status of site '%s': '%s'", site, err)
+	}
+
+	return nodesStatusByUID(status.Nodes), nil
+}
@@ -0,0 +1,24 @@
+package g5k
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodesStatusByUID(t *testing.T) {
+	var status siteStatus
+	assert.NoError(t, json.Unmarshal([]byte(`{"nodes": {"chifflet-1.lille.grid5000.fr": {"hard": "alive", "soft": "free"}, "chifflet-2.lille.grid5000.fr": {"hard": "alive", "soft": "busy"}}}`), &status))
+
+	nodes := nodesStatusByUID(status.Nodes)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, NodeStatus{Hard: "alive", Soft: "free"}, nodes["chifflet-1"])
+	assert.Equal(t, NodeStatus{Hard: "alive", Soft: "busy"}, nodes["chifflet-2"])
+}
+
+func TestNodeStatusIsFree(t *testing.T) {
+	assert.True(t, (&NodeStatus{Hard: "alive", Soft: "free"}).IsFree())
+	assert.False(t, (&NodeStatus{Hard: "alive", Soft: "busy"}).IsFree())
+	assert.False(t, (&NodeStatus{Hard: "dead", Soft: "free"}).IsFree())
+}
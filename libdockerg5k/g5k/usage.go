@@ -0,0 +1,112 @@
+package g5k
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SiteUsage contain the resources used by the jobs of the user on a site
+type SiteUsage struct {
+	Site              string  `json:"site"`
+	ActiveJobs        int     `json:"active_jobs"`         // running and waiting jobs
+	ReservedNodes     int     `json:"reserved_nodes"`      // nodes assigned to the running jobs
+	CoreHoursUsed     float64 `json:"core_hours_used"`     // elapsed core-hours of the running jobs
+	CoreHoursReserved float64 `json:"core_hours_reserved"` // core-hours of the whole walltime of the running jobs
+	MaxNodeCores      int     `json:"max_node_cores"`      // maximum number of cores of a node of the site (0 if unknown)
+}
+
+// ParseWalltime returns the duration of a walltime (format: hh[:mm[:ss]])
+func ParseWalltime(walltime string) (time.Duration, error) {
+	parts := strings.Split(walltime, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("Invalid walltime: '%s' (format: hh:mm:ss)", walltime)
+	}
+
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 || (i > 0 && v > 59) {
+			return 0, fmt.Errorf("Invalid walltime: '%s' (format: hh:mm:ss)", walltime)
+		}
+		d += time.Duration(v) * units[i]
+	}
+
+	return d, nil
+}
+
+// siteUsage returns the resources used by the jobs on the site at the given time, using the number of cores of the nodes (by node UID)
+func siteUsage(site string, jobs []JobState, nodesCores map[string]int, now time.Time) *SiteUsage {
+	u := &SiteUsage{Site: site, ActiveJobs: len(jobs)}
+	for _, c := range nodesCores {
+		if c > u.MaxNodeCores {
+			u.MaxNodeCores = c
+		}
+	}
+
+	for _, j := range jobs {
+		if !j.IsRunning() {
+			continue
+		}
+
+		elapsed := now.Sub(time.Unix(j.StartedAt, 0)).Hours()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		walltime := float64(j.Walltime) / 3600
+
+		u.ReservedNodes += len(j.Nodes)
+		for _, n := range j.Nodes {
+			cores := float64(nodesCores[strings.SplitN(n, ".", 2)[0]])
+			u.CoreHoursUsed += cores * elapsed
+			u.CoreHoursReserved += cores * walltime
+		}
+	}
+
+	return u
+}
+
+// GetUserJobs returns the running and waiting jobs of the user on the site
+func (g *G5K) GetUserJobs(site string) ([]JobState, error) {
+	var jobs []JobState
+	if err := g.getItems(fmt.Sprintf("sites/%s/jobs?user=%s&state=running,waiting,launching", site, g.username), &jobs); err != nil {
+		return nil, fmt.Errorf("Unable to get the jobs of user '%s' on site '%s': '%s'", g.username, site, err)
+	}
+
+	// the jobs list does not contain the assigned nodes
+	for i, j := range jobs {
+		if !j.IsRunning() {
+			continue
+		}
+
+		job, err := g.GetJob(site, j.UID)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to get the job '%d' on site '%s': '%s'", j.UID, site, err)
+		}
+		jobs[i] = *job
+	}
+
+	return jobs, nil
+}
+
+// GetSiteUsage returns the resources used by the running and waiting jobs of the user on the site
+func (g *G5K) GetSiteUsage(site string) (*SiteUsage, error) {
+	jobs, err := g.GetUserJobs(site)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := g.GetSiteReferenceNodes(site)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the description of the nodes of site '%s': '%s'", site, err)
+	}
+
+	nodesCores := make(map[string]int)
+	for _, n := range nodes {
+		nodesCores[n.UID] = n.Architecture.NbCores
+	}
+
+	return siteUsage(site, jobs, nodesCores, time.Now()), nil
+}
@@ -0,0 +1,46 @@
+package g5k
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWalltime(t *testing.T) {
+	d, err := ParseWalltime("1:30:00")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, d)
+
+	d, err = ParseWalltime("2")
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, d)
+
+	d, err = ParseWalltime("0:45")
+	assert.NoError(t, err)
+	assert.Equal(t, 45*time.Minute, d)
+}
+
+func TestParseWalltimeIncorrect(t *testing.T) {
+	for _, w := range []string{"", "1:60:00", "1:00:00:00", "a:00", "-1"} {
+		_, err := ParseWalltime(w)
+		assert.Error(t, err, w)
+	}
+}
+
+func TestSiteUsage(t *testing.T) {
+	now := time.Unix(10000, 0)
+	jobs := []JobState{
+		{UID: 1, State: "running", StartedAt: 10000 - 3600, Walltime: 7200, Nodes: []string{"paravance-1.rennes.grid5000.fr", "parasilo-2.rennes.grid5000.fr"}},
+		{UID: 2, State: "waiting", Walltime: 3600},
+	}
+	cores := map[string]int{"paravance-1": 16, "parasilo-2": 8, "paravance-2": 16}
+
+	u := siteUsage("rennes", jobs, cores, now)
+	assert.Equal(t, "rennes", u.Site)
+	assert.Equal(t, 2, u.ActiveJobs)
+	assert.Equal(t, 2, u.ReservedNodes)
+	assert.Equal(t, 16, u.MaxNodeCores)
+	assert.Equal(t, 24.0, u.CoreHoursUsed)
+	assert.Equal(t, 48.0, u.CoreHoursReserved)
+}
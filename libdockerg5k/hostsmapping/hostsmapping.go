@@ -3,31 +3,94 @@ package hostsmapping
 import (
 	"bytes"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/docker/machine/libmachine/host"
 )
 
-// generateHostsEntries returns the entries as a single string
-func generateHostsEntries(hostsLookupTable map[string]string) string {
+// hostsHeader is the header of the cluster entries in the static lookup table, the entries end at the next empty line
+const hostsHeader = "# docker-g5k:"
+
+// removeHostsEntriesScript is the awk script printing the static lookup table without the cluster entries (and the empty lines around them)
+const removeHostsEntriesScript = `/^# docker-g5k:$/{skip=1; blank=0; next} skip && /^$/{skip=0; next} skip{next} /^$/{blank++; next} {for (; blank > 0; blank--) print ""; print}`
+
+// generateHostsEntries returns the entries (with the aliases of the hostnames) as a single string
+func generateHostsEntries(hostsLookupTable map[string]string, aliases map[string][]string) string {
 	var buffer bytes.Buffer
 
 	// append a header
-	buffer.WriteString("\n# docker-g5k:\n")
+	buffer.WriteString("\n" + hostsHeader + "\n")
 
-	// entry format: {ip}<tab>{hostname}
-	for hostname, ip := range hostsLookupTable {
-		buffer.WriteString(fmt.Sprintf("%s\t%s\n", ip, hostname))
+	// entry format: {ip}<tab>{hostname}[ {alias}...] (sorted by hostname)
+	hostnames := []string{}
+	for hostname := range hostsLookupTable {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	for _, hostname := range hostnames {
+		names := append([]string{hostname}, aliases[hostname]...)
+		buffer.WriteString(fmt.Sprintf("%s\t%s\n", hostsLookupTable[hostname], strings.Join(names, " ")))
 	}
 
 	return buffer.String()
 }
 
-// AddClusterHostsMapping add cluster nodes name ({site}-{id}) to the static lookup table (/etc/hosts) of the node
-func AddClusterHostsMapping(h *host.Host, hostsLookupTable map[string]string) error {
+// AddClusterHostsMapping add cluster nodes name ({site}-{id}) and their aliases (by node name, optional) to the static lookup table (/etc/hosts) of the node
+func AddClusterHostsMapping(h *host.Host, hostsLookupTable map[string]string, aliases map[string][]string) error {
 	// append entries at the end of the /etc/hosts file
-	if _, err := h.RunSSHCommand(fmt.Sprintf("echo '%s' >>/etc/hosts", generateHostsEntries(hostsLookupTable))); err != nil {
+	if _, err := h.RunSSHCommand(fmt.Sprintf("echo '%s' >>/etc/hosts", generateHostsEntries(hostsLookupTable, aliases))); err != nil {
 		return fmt.Errorf("Failed to append hosts to the static lookup table: '%s'", err)
 	}
 
 	return nil
 }
+
+// clusterHostsEntries returns the lines of the cluster entries found in the static lookup table, each block of entries is preceded by its header
+func clusterHostsEntries(hosts string) []string {
+	lines := []string{}
+	inBlock := false
+	for _, line := range strings.Split(hosts, "\n") {
+		switch {
+		case line == hostsHeader:
+			inBlock = true
+			lines = append(lines, line)
+		case inBlock && line == "":
+			inBlock = false
+		case inBlock:
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// IsClusterHostsMappingUpToDate returns true if the static lookup table (content of /etc/hosts) contain a single block of cluster entries matching the given ones
+func IsClusterHostsMappingUpToDate(hosts string, hostsLookupTable map[string]string, aliases map[string][]string) bool {
+	expected := strings.Split(strings.Trim(generateHostsEntries(hostsLookupTable, aliases), "\n"), "\n")
+	current := clusterHostsEntries(hosts)
+	if len(current) != len(expected) {
+		return false
+	}
+
+	for i := range expected {
+		if current[i] != expected[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ReplaceClusterHostsMapping replace the cluster entries of the static lookup table (/etc/hosts) of the node by the given ones, the other entries are kept
+// the file is rewritten in place (the containers bind mounting it keep the same file) and the result is the same if run again
+func ReplaceClusterHostsMapping(h *host.Host, hostsLookupTable map[string]string, aliases map[string][]string) error {
+	cmd := fmt.Sprintf("awk '%s' /etc/hosts >/etc/hosts.docker-g5k && echo '%s' >>/etc/hosts.docker-g5k && cat /etc/hosts.docker-g5k >/etc/hosts && rm -f /etc/hosts.docker-g5k",
+		removeHostsEntriesScript, generateHostsEntries(hostsLookupTable, aliases))
+	if _, err := h.RunSSHCommand(cmd); err != nil {
+		return fmt.Errorf("Failed to replace hosts in the static lookup table: '%s'", err)
+	}
+
+	return nil
+}
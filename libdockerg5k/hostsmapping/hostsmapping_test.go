@@ -10,12 +10,43 @@ import (
 
 func TestGenerateHostsEntriesSingleIpv4(t *testing.T) {
 	hostsLookupTable := map[string]string{"lille-0": "1.2.3.4"}
-	entries := generateHostsEntries(hostsLookupTable)
+	entries := generateHostsEntries(hostsLookupTable, nil)
 	assert.Equal(t, fmt.Sprintf("\n# docker-g5k:\n1.2.3.4\tlille-0\n"), entries)
 }
 
 func TestGenerateHostsEntriesSingleIpv6(t *testing.T) {
 	hostsLookupTable := map[string]string{"lille-0": "2001:db8:85a3::8a2e:370:7334"}
-	entries := generateHostsEntries(hostsLookupTable)
+	entries := generateHostsEntries(hostsLookupTable, nil)
 	assert.Equal(t, fmt.Sprintf("\n# docker-g5k:\n2001:db8:85a3::8a2e:370:7334\tlille-0\n"), entries)
 }
+
+func TestGenerateHostsEntriesAliases(t *testing.T) {
+	hostsLookupTable := map[string]string{"lille-0": "1.2.3.4"}
+	entries := generateHostsEntries(hostsLookupTable, map[string][]string{"lille-0": {"db0", "cache0"}, "lille-1": {"web0"}})
+	assert.Equal(t, fmt.Sprintf("\n# docker-g5k:\n1.2.3.4\tlille-0 db0 cache0\n"), entries)
+}
+
+func TestGenerateHostsEntriesSorted(t *testing.T) {
+	hostsLookupTable := map[string]string{"lille-1": "1.2.3.5", "lille-0": "1.2.3.4", "g5k-registry": "1.2.3.4"}
+	entries := generateHostsEntries(hostsLookupTable, nil)
+	assert.Equal(t, "\n# docker-g5k:\n1.2.3.4\tg5k-registry\n1.2.3.4\tlille-0\n1.2.3.5\tlille-1\n", entries)
+}
+
+func TestIsClusterHostsMappingUpToDate(t *testing.T) {
+	hostsLookupTable := map[string]string{"lille-0": "1.2.3.4", "lille-1": "1.2.3.5"}
+	aliases := map[string][]string{"lille-0": {"db0"}}
+	hosts := "127.0.0.1\tlocalhost\n\n# docker-g5k:\n1.2.3.4\tlille-0 db0\n1.2.3.5\tlille-1\n\n"
+	assert.True(t, IsClusterHostsMappingUpToDate(hosts, hostsLookupTable, aliases))
+
+	// address changed
+	assert.False(t, IsClusterHostsMappingUpToDate(hosts, map[string]string{"lille-0": "1.2.3.6", "lille-1": "1.2.3.5"}, aliases))
+
+	// alias removed
+	assert.False(t, IsClusterHostsMappingUpToDate(hosts, hostsLookupTable, nil))
+
+	// entries appended by another block (ex: external host)
+	assert.False(t, IsClusterHostsMappingUpToDate(hosts+"\n# docker-g5k:\n9.9.9.9\text-0\n\n", hostsLookupTable, aliases))
+
+	// no cluster entries
+	assert.False(t, IsClusterHostsMappingUpToDate("127.0.0.1\tlocalhost\n", hostsLookupTable, aliases))
+}
@@ -0,0 +1,100 @@
+package ingress
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// Hostname is the name of the ingress node in the cluster static lookup table
+	Hostname = "docker-g5k-ingress"
+
+	// Network is the attachable overlay network joined by the ingress controller and the routed Swarm mode services
+	Network = "docker-g5k-ingress"
+
+	// EnableLabel is the label of the containers/services routed by the ingress controller
+	EnableLabel = "traefik.enable=true"
+
+	// default ingress controller image and published port
+	defaultImage = "traefik:1.7"
+	defaultPort  = 80
+)
+
+// Spec contain the configuration of the ingress controller (Traefik)
+type Spec struct {
+	Node  string // machine name of the node running the ingress controller
+	Image string // Traefik image (default if empty)
+	Port  int    // port published on the ingress node (default if zero)
+}
+
+// Validate check the ingress configuration
+func (s *Spec) Validate() error {
+	if s.Node == "" {
+		return fmt.Errorf("The ingress node is required")
+	}
+
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("Invalid ingress port: %d (must be between 1 and 65535)", s.Port)
+	}
+
+	return nil
+}
+
+// image returns the ingress controller image
+func (s *Spec) image() string {
+	if s.Image == "" {
+		return defaultImage
+	}
+
+	return s.Image
+}
+
+// port returns the port published on the ingress node
+func (s *Spec) port() int {
+	if s.Port == 0 {
+		return defaultPort
+	}
+
+	return s.Port
+}
+
+// Endpoint returns the address (host:port) of the ingress on the given host
+func (s *Spec) Endpoint(hostname string) string {
+	return net.JoinHostPort(hostname, strconv.Itoa(s.port()))
+}
+
+// generateNetworkCommand returns the command used to create the attachable overlay network of the ingress (if it does not exist)
+func generateNetworkCommand() string {
+	return fmt.Sprintf("docker network inspect %[1]s >/dev/null 2>&1 || docker network create --driver overlay --attachable %[1]s", Network)
+}
+
+// generateRunCommand returns the command used to run the ingress controller with the given restart policy, added capabilities, stop signal/timeout and image platform (routing the Swarm mode services in swarm mode, the local containers otherwise)
+func (s *Spec) generateRunCommand(swarmMode bool, restartPolicy string, caps []string, stop container.StopConfig, platform string) string {
+	network := ""
+	provider := "--docker --docker.watch --docker.exposedbydefault=false"
+	if swarmMode {
+		network = fmt.Sprintf("--network %s ", Network)
+		provider += fmt.Sprintf(" --docker.swarmmode --docker.network=%s", Network)
+	}
+
+	return fmt.Sprintf("docker run -d %s --name docker-g5k-ingress %s %s-p %d:80 -v /var/run/docker.sock:/var/run/docker.sock %s %s", container.RestartFlag(restartPolicy)+container.CapAddFlags(caps)+stop.Flags()+container.PlatformFlag(platform), container.LabelFlag, network, s.port(), s.image(), provider)
+}
+
+// StartIngress start the ingress controller container on the given host with the added capabilities, stop signal/timeout and image platform (needs to be a Swarm manager in Swarm mode, the default restart policy is used if empty)
+func StartIngress(h *host.Host, s *Spec, swarmMode bool, restartPolicy string, caps []string, stop container.StopConfig, platform string) error {
+	if swarmMode {
+		if _, err := h.RunSSHCommand(generateNetworkCommand()); err != nil {
+			return fmt.Errorf("Ingress network creation failed: '%s'", err)
+		}
+	}
+
+	if _, err := h.RunSSHCommand(s.generateRunCommand(swarmMode, restartPolicy, caps, stop, platform)); err != nil {
+		return fmt.Errorf("Ingress run command failed: '%s'", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecValidate(t *testing.T) {
+	assert.NoError(t, (&Spec{Node: "lille-0"}).Validate())
+	assert.NoError(t, (&Spec{Node: "lille-0", Port: 8080}).Validate())
+	assert.Error(t, (&Spec{}).Validate())
+	assert.Error(t, (&Spec{Node: "lille-0", Port: 70000}).Validate())
+}
+
+func TestSpecEndpoint(t *testing.T) {
+	assert.Equal(t, "chetemi-1.lille.grid5000.fr:80", (&Spec{Node: "lille-0"}).Endpoint("chetemi-1.lille.grid5000.fr"))
+	assert.Equal(t, "docker-g5k-ingress:8080", (&Spec{Node: "lille-0", Port: 8080}).Endpoint(Hostname))
+}
+
+func TestGenerateRunCommand(t *testing.T) {
+	s := &Spec{Node: "lille-0"}
+	assert.Equal(t, "docker run -d --restart=always --name docker-g5k-ingress --label managed-by=docker-g5k -p 80:80 -v /var/run/docker.sock:/var/run/docker.sock traefik:1.7 --docker --docker.watch --docker.exposedbydefault=false", s.generateRunCommand(false, "", nil, container.StopConfig{}, ""))
+
+	s = &Spec{Node: "lille-0", Image: "traefik:1.6", Port: 8080}
+	assert.Equal(t, "docker run -d --restart=unless-stopped --name docker-g5k-ingress --label managed-by=docker-g5k --network docker-g5k-ingress -p 8080:80 -v /var/run/docker.sock:/var/run/docker.sock traefik:1.6 --docker --docker.watch --docker.exposedbydefault=false --docker.swarmmode --docker.network=docker-g5k-ingress", s.generateRunCommand(true, "unless-stopped", nil, container.StopConfig{}, ""))
+}
@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// Hostname is the name of the registry node in the cluster static lookup table
+	Hostname = "docker-g5k-registry"
+
+	// Port is the port used by the registry
+	Port = "5000"
+)
+
+// Address returns the address (host:port) of the registry
+func Address() string {
+	return net.JoinHostPort(Hostname, Port)
+}
+
+// MirrorURL returns the URL to use for the Docker Engine 'registry-mirror' parameter
+func MirrorURL() string {
+	return fmt.Sprintf("http://%s", Address())
+}
+
+// generateRunCommand returns the command used to run the registry (as a pull-through cache if a remote URL is given) with the given restart policy, added capabilities, stop signal/timeout and image platform
+func generateRunCommand(proxyRemoteURL string, restartPolicy string, caps []string, stop container.StopConfig, platform string) string {
+	env := ""
+	if proxyRemoteURL != "" {
+		env = fmt.Sprintf("-e \"REGISTRY_PROXY_REMOTEURL=%s\" ", proxyRemoteURL)
+	}
+
+	return fmt.Sprintf("docker run -d %s --name docker-g5k-registry %s -p %s:5000 %sregistry:2", container.RestartFlag(restartPolicy)+container.CapAddFlags(caps)+stop.Flags()+container.PlatformFlag(platform), container.LabelFlag, Port, env)
+}
+
+// StartRegistry start a registry container on the given host with the added capabilities, stop signal/timeout and image platform (the default restart policy is used if empty)
+func StartRegistry(h *host.Host, proxyRemoteURL string, restartPolicy string, caps []string, stop container.StopConfig, platform string) error {
+	if _, err := h.RunSSHCommand(generateRunCommand(proxyRemoteURL, restartPolicy, caps, stop, platform)); err != nil {
+		return fmt.Errorf("Registry run command failed: '%s'", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorURL(t *testing.T) {
+	assert.Equal(t, "http://docker-g5k-registry:5000", MirrorURL())
+}
+
+func TestGenerateRunCommandWithoutProxy(t *testing.T) {
+	assert.Equal(t, "docker run -d --restart=always --name docker-g5k-registry --label managed-by=docker-g5k -p 5000:5000 registry:2", generateRunCommand("", "", nil, container.StopConfig{}, ""))
+}
+
+func TestGenerateRunCommandWithProxy(t *testing.T) {
+	assert.Equal(t, "docker run -d --restart=always --name docker-g5k-registry --label managed-by=docker-g5k -p 5000:5000 -e \"REGISTRY_PROXY_REMOTEURL=https://registry-1.docker.io\" registry:2", generateRunCommand("https://registry-1.docker.io", "always", nil, container.StopConfig{}, ""))
+}
+
+func TestGenerateRunCommandWithCapabilities(t *testing.T) {
+	assert.Equal(t, "docker run -d --restart=no --cap-add=NET_ADMIN --name docker-g5k-registry --label managed-by=docker-g5k -p 5000:5000 registry:2", generateRunCommand("", "no", []string{"net_admin"}, container.StopConfig{}, ""))
+}
+
+func TestGenerateRunCommandWithStopConfig(t *testing.T) {
+	assert.Equal(t, "docker run -d --restart=always --stop-signal=SIGINT --stop-timeout=30 --name docker-g5k-registry --label managed-by=docker-g5k -p 5000:5000 registry:2", generateRunCommand("", "", nil, container.StopConfig{Signal: "SIGINT", Timeout: 30 * time.Second}, ""))
+}
+
+func TestGenerateRunCommandWithPlatform(t *testing.T) {
+	assert.Equal(t, "docker run -d --restart=always --platform=linux/arm64 --name docker-g5k-registry --label managed-by=docker-g5k -p 5000:5000 registry:2", generateRunCommand("", "", nil, container.StopConfig{}, "linux/arm64"))
+}
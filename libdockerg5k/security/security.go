@@ -0,0 +1,75 @@
+package security
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// SeccompProfilePath is the location of the seccomp profile on the nodes
+	SeccompProfilePath = "/etc/docker/docker-g5k-seccomp.json"
+
+	// appArmorProfilePath is the location of the AppArmor profile on the nodes
+	appArmorProfilePath = "/etc/apparmor.d/docker-g5k"
+)
+
+// ReadSeccompProfile read the seccomp profile file and check it is a valid JSON document
+func ReadSeccompProfile(path string) ([]byte, error) {
+	profile, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the seccomp profile '%s': '%s'", path, err)
+	}
+
+	if !json.Valid(profile) {
+		return nil, fmt.Errorf("The seccomp profile '%s' is not a valid JSON document", path)
+	}
+
+	return profile, nil
+}
+
+// ReadAppArmorProfile read the AppArmor profile file
+func ReadAppArmorProfile(path string) ([]byte, error) {
+	profile, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the AppArmor profile '%s': '%s'", path, err)
+	}
+
+	if len(profile) == 0 {
+		return nil, fmt.Errorf("The AppArmor profile '%s' is empty", path)
+	}
+
+	return profile, nil
+}
+
+// generateUploadCommand returns the command used to write the content in the destination file (base64 encoded to avoid quoting issues)
+func generateUploadCommand(content []byte, dest string) string {
+	return fmt.Sprintf("echo '%s' | base64 -d >%s", base64.StdEncoding.EncodeToString(content), dest)
+}
+
+// UploadSeccompProfile upload the seccomp profile on the host (SeccompProfilePath), it needs to be set in the Docker Engine configuration to be used
+func UploadSeccompProfile(h *host.Host, profile []byte) error {
+	if _, err := h.RunSSHCommand(generateUploadCommand(profile, SeccompProfilePath)); err != nil {
+		return fmt.Errorf("Failed to upload the seccomp profile: '%s'", err)
+	}
+
+	return nil
+}
+
+// LoadAppArmorProfile upload and load the AppArmor profile in the kernel
+func LoadAppArmorProfile(h *host.Host, profile []byte) error {
+	// upload profile
+	if _, err := h.RunSSHCommand(generateUploadCommand(profile, appArmorProfilePath)); err != nil {
+		return fmt.Errorf("Failed to upload the AppArmor profile: '%s'", err)
+	}
+
+	// load (or replace) profile
+	if _, err := h.RunSSHCommand(fmt.Sprintf("apparmor_parser -r -W %s", appArmorProfilePath)); err != nil {
+		return fmt.Errorf("Failed to load the AppArmor profile: '%s'", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package security
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTempProfile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "docker-g5k-security")
+	assert.NoError(t, err)
+
+	path := filepath.Join(dir, "profile")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	return path
+}
+
+func TestReadSeccompProfileValid(t *testing.T) {
+	path := writeTempProfile(t, `{"defaultAction": "SCMP_ACT_ALLOW"}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	profile, err := ReadSeccompProfile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"defaultAction": "SCMP_ACT_ALLOW"}`, string(profile))
+}
+
+func TestReadSeccompProfileInvalidJSON(t *testing.T) {
+	path := writeTempProfile(t, `{"defaultAction": `)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	_, err := ReadSeccompProfile(path)
+	assert.Error(t, err)
+}
+
+func TestReadSeccompProfileMissing(t *testing.T) {
+	_, err := ReadSeccompProfile("/nonexistent/seccomp.json")
+	assert.Error(t, err)
+}
+
+func TestReadAppArmorProfileEmpty(t *testing.T) {
+	path := writeTempProfile(t, "")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	_, err := ReadAppArmorProfile(path)
+	assert.Error(t, err)
+}
+
+func TestGenerateUploadCommand(t *testing.T) {
+	assert.Equal(t, "echo 'e30=' | base64 -d >/etc/docker/daemon.json", generateUploadCommand([]byte("{}"), "/etc/docker/daemon.json"))
+}
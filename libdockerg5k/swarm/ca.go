@@ -0,0 +1,169 @@
+package swarm
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// defaultCARotationTimeout is the default maximum time to wait for all the nodes to get their certificate of the new root CA
+	defaultCARotationTimeout = 10 * time.Minute
+
+	// caCertPath is the path (on the manager) of the root certificate of the external CA given to the rotation
+	caCertPath = "/tmp/docker-g5k-swarm-ca.pem"
+
+	// rootRotationCommand returns true while the root CA rotation is in progress
+	rootRotationCommand = "docker info --format '{{.Swarm.Cluster.RootRotationInProgress}}'"
+
+	// nodesTLSStatusCommand returns the hostname and TLS status of the Swarm mode nodes (one node per line)
+	nodesTLSStatusCommand = "docker node ls --format '{{.Hostname}} {{.TLSStatus}}'"
+)
+
+// CAOptions contain the options of the root CA rotation of the Swarm mode cluster
+type CAOptions struct {
+	// URL of the external CFSSL CA signing the nodes certificates and PEM root certificate of this CA (the Swarm internal CA is used if empty)
+	ExternalCAURL  string
+	ExternalCACert string `json:"-"`
+
+	// validity of the nodes certificates issued after the rotation (Docker default 90 days if not set)
+	CertExpiry time.Duration
+
+	// maximum time to wait for all the nodes to get their certificate of the new root CA (default if not set)
+	RotationTimeout time.Duration
+}
+
+// CARotationReport contain the TLS status of the nodes once the root CA rotation is done
+type CARotationReport struct {
+	Nodes    map[string]string `json:"nodes"` // node hostname => TLS status (Ready once the node has its new certificate)
+	Duration time.Duration     `json:"duration"`
+}
+
+// Validate check the external CA endpoint, its root certificate and the certificates expiry
+func (o *CAOptions) Validate() error {
+	if o.ExternalCAURL != "" {
+		u, err := url.Parse(o.ExternalCAURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("The Swarm mode external CA is not a valid HTTPS URL: '%s' (ex: https://ca.example.org:8888/api/v1/cfssl/sign)", o.ExternalCAURL)
+		}
+
+		// Docker needs the root certificate to rotate to an external CA
+		if !strings.Contains(o.ExternalCACert, "-----BEGIN CERTIFICATE-----") {
+			return fmt.Errorf("The Swarm mode external CA needs its PEM root certificate")
+		}
+	} else if o.ExternalCACert != "" {
+		return fmt.Errorf("The Swarm mode external CA root certificate needs an external CA URL")
+	}
+
+	if o.CertExpiry != 0 && o.CertExpiry < minNodeCertExpiry {
+		return fmt.Errorf("The Swarm mode nodes certificate expiry must be at least %s: '%s'", minNodeCertExpiry, o.CertExpiry)
+	}
+
+	if o.RotationTimeout < 0 {
+		return fmt.Errorf("The Swarm mode CA rotation timeout needs to be positive: '%s'", o.RotationTimeout)
+	}
+
+	return nil
+}
+
+// rotationTimeout returns the maximum time to wait for the end of the root CA rotation
+func (o *CAOptions) rotationTimeout() time.Duration {
+	if o.RotationTimeout > 0 {
+		return o.RotationTimeout
+	}
+
+	return defaultCARotationTimeout
+}
+
+// generateRotateCACommand returns the command starting the root CA rotation (in background, the external CA root certificate needs to be written at caCertPath)
+func (o *CAOptions) generateRotateCACommand() string {
+	cmd := "docker swarm ca --rotate --detach --quiet"
+	if o.CertExpiry != 0 {
+		cmd += fmt.Sprintf(" --cert-expiry %s", o.CertExpiry)
+	}
+	if o.ExternalCAURL != "" {
+		cmd += fmt.Sprintf(" --external-ca protocol=cfssl,url=%s --ca-cert %s", o.ExternalCAURL, caCertPath)
+	}
+
+	return cmd
+}
+
+// parseNodesTLSStatus returns the TLS status by hostname from the 'docker node ls' output (format: {hostname} {TLS status}, the status may contain spaces)
+func parseNodesTLSStatus(out string) map[string]string {
+	nodes := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		nodes[fields[0]] = fields[1]
+	}
+
+	return nodes
+}
+
+// pendingTLSNodes returns the nodes (sorted) not having their certificate of the new root CA yet
+func pendingTLSNodes(nodes map[string]string) []string {
+	pending := []string{}
+	for hostname, status := range nodes {
+		if status != "Ready" {
+			pending = append(pending, hostname)
+		}
+	}
+	sort.Strings(pending)
+
+	return pending
+}
+
+// RotateCA rotate the root CA of the Swarm mode cluster with the CA options (to the external CA if set) and wait for all the nodes to get a certificate of the new root CA (the host needs to be a manager)
+// the nodes whose certificate is not rotated before the timeout are listed in the returned error
+func (gc *SwarmModeGlobalConfig) RotateCA(h *host.Host) (*CARotationReport, error) {
+	opts := &gc.CAOptions
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	deadline := started.Add(opts.rotationTimeout())
+
+	if opts.ExternalCAURL != "" {
+		if _, err := h.RunSSHCommand(fmt.Sprintf("cat >%s <<'EOF'\n%s\nEOF", caCertPath, strings.TrimSpace(opts.ExternalCACert))); err != nil {
+			return nil, fmt.Errorf("Failed to write the external CA root certificate: '%s'", err)
+		}
+		defer h.RunSSHCommand(fmt.Sprintf("rm -f %s", caCertPath))
+	}
+
+	if _, err := h.RunSSHCommand(opts.generateRotateCACommand()); err != nil {
+		return nil, fmt.Errorf("Failed to start the Swarm mode root CA rotation: '%s'", err)
+	}
+
+	// wait for the end of the rotation, once all the nodes have a certificate of the new root CA
+	report := &CARotationReport{}
+	for ; ; time.Sleep(5 * time.Second) {
+		inProgress, err := h.RunSSHCommand(rootRotationCommand)
+		if err == nil && strings.TrimSpace(inProgress) == "false" {
+			if out, err := h.RunSSHCommand(nodesTLSStatusCommand); err == nil {
+				report.Nodes = parseNodesTLSStatus(out)
+				if len(report.Nodes) > 0 && len(pendingTLSNodes(report.Nodes)) == 0 {
+					break
+				}
+			}
+		}
+
+		if time.Now().After(deadline) {
+			report.Duration = time.Since(started)
+			if pending := pendingTLSNodes(report.Nodes); len(pending) > 0 {
+				return report, fmt.Errorf("The Swarm mode root CA rotation is not done before the timeout, node(s) without rotated certificate: %s", strings.Join(pending, ", "))
+			}
+			return report, fmt.Errorf("The Swarm mode root CA rotation is not done before the timeout")
+		}
+	}
+	report.Duration = time.Since(started)
+
+	return report, nil
+}
@@ -0,0 +1,43 @@
+package swarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCACert = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"
+
+func TestCAOptionsValidate(t *testing.T) {
+	assert.NoError(t, (&CAOptions{}).Validate())
+	assert.NoError(t, (&CAOptions{CertExpiry: 24 * time.Hour, RotationTimeout: time.Minute}).Validate())
+	assert.NoError(t, (&CAOptions{ExternalCAURL: "https://ca.example.org:8888/api/v1/cfssl/sign", ExternalCACert: testCACert}).Validate())
+}
+
+func TestCAOptionsValidateIncorrect(t *testing.T) {
+	for _, url := range []string{"ca.example.org", "http://ca.example.org/api/v1/cfssl/sign", "https://"} {
+		assert.Error(t, (&CAOptions{ExternalCAURL: url, ExternalCACert: testCACert}).Validate(), url)
+	}
+
+	assert.Error(t, (&CAOptions{ExternalCAURL: "https://ca.example.org/api/v1/cfssl/sign"}).Validate())
+	assert.Error(t, (&CAOptions{ExternalCACert: testCACert}).Validate())
+	assert.Error(t, (&CAOptions{CertExpiry: time.Minute}).Validate())
+	assert.Error(t, (&CAOptions{RotationTimeout: -time.Minute}).Validate())
+
+	assert.Error(t, (&SwarmModeGlobalConfig{CAOptions: CAOptions{CertExpiry: time.Minute}}).Validate())
+}
+
+func TestGenerateRotateCACommand(t *testing.T) {
+	assert.Equal(t, "docker swarm ca --rotate --detach --quiet", (&CAOptions{}).generateRotateCACommand())
+
+	opts := &CAOptions{ExternalCAURL: "https://ca.example.org/api/v1/cfssl/sign", ExternalCACert: testCACert, CertExpiry: 720 * time.Hour}
+	assert.Equal(t, "docker swarm ca --rotate --detach --quiet --cert-expiry 720h0m0s --external-ca protocol=cfssl,url=https://ca.example.org/api/v1/cfssl/sign --ca-cert /tmp/docker-g5k-swarm-ca.pem", opts.generateRotateCACommand())
+}
+
+func TestParseNodesTLSStatus(t *testing.T) {
+	nodes := parseNodesTLSStatus("chifflet-1 Ready\nchifflet-2 Needs Rotation\n\ninvalid\n")
+	assert.Equal(t, map[string]string{"chifflet-1": "Ready", "chifflet-2": "Needs Rotation"}, nodes)
+	assert.Equal(t, []string{"chifflet-2"}, pendingTLSNodes(nodes))
+	assert.Empty(t, pendingTLSNodes(map[string]string{"chifflet-1": "Ready"}))
+}
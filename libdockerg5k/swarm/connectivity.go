@@ -0,0 +1,247 @@
+package swarm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// connectivityServiceName is the name of the global service checking the overlay connectivity between the nodes
+	connectivityServiceName = "docker-g5k-connectivity"
+
+	// defaultConnectivityNetwork is the default name of the overlay network of the connectivity check
+	defaultConnectivityNetwork = "docker-g5k-connectivity"
+
+	// connectivityImage is the image of the connectivity check service (needs nslookup and ping)
+	connectivityImage = "busybox"
+
+	// defaultConnectivityTimeout is the default maximum time to wait for the results of all the replicas of the connectivity check
+	defaultConnectivityTimeout = 3 * time.Minute
+
+	// activeNodesCommand returns the status and availability of the Swarm mode nodes
+	activeNodesCommand = "docker node ls --format '{{.Status}} {{.Availability}}'"
+)
+
+// ConnectivityMatrix contain the reachability between the nodes (by hostname) on the overlay network
+type ConnectivityMatrix struct {
+	Nodes     []string                   `json:"nodes"`
+	Reachable map[string]map[string]bool `json:"reachable"` // source node => destination node => reachable
+
+	// nodes whose replica did not report its results (their links are unknown)
+	Missing []string `json:"missing,omitempty"`
+}
+
+// UnreachablePairs returns the links (source->destination, sorted) not working on the overlay network
+func (m *ConnectivityMatrix) UnreachablePairs() []string {
+	pairs := []string{}
+	for src, dsts := range m.Reachable {
+		for dst, ok := range dsts {
+			if !ok {
+				pairs = append(pairs, fmt.Sprintf("%s->%s", src, dst))
+			}
+		}
+	}
+	sort.Strings(pairs)
+
+	return pairs
+}
+
+// connectivityNetwork returns the overlay network of the connectivity check
+func (gc *SwarmModeGlobalConfig) connectivityNetwork() string {
+	if gc.ConnectivityTestNetwork != "" {
+		return gc.ConnectivityTestNetwork
+	}
+
+	return defaultConnectivityNetwork
+}
+
+// connectivityTimeout returns the maximum time to wait for the results of the connectivity check
+func (gc *SwarmModeGlobalConfig) connectivityTimeout() time.Duration {
+	if gc.ConnectivityTestTimeout > 0 {
+		return gc.ConnectivityTestTimeout
+	}
+
+	return defaultConnectivityTimeout
+}
+
+// countActiveNodes returns the number of ready and active nodes from the 'docker node ls' output (format: {status} {availability}), the nodes running a global service replica
+func countActiveNodes(out string) int {
+	count := 0
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "Ready" && fields[1] == "Active" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// generateConnectivityScript returns the script of a replica: wait until the addresses of the expected number of replicas are resolved, ping each of them and log the results
+// result line format: connectivity {source node} {destination address} ok|fail, the replica logs 'connectivity-done {source node}' once done and waits to be removed
+func generateConnectivityScript(expected int) string {
+	return fmt.Sprintf(`while true; do `+
+		`ips=$(nslookup tasks.%[1]s 2>/dev/null | sed -n "s/^Address[^:]*: *\([0-9.]*\).*/\1/p" | grep -v "^127\." | sort -u); `+
+		`[ $(echo "$ips" | grep -c .) -ge %[2]d ] && break; sleep 2; done; `+
+		`for ip in $ips; do if ping -c 3 -W 2 $ip >/dev/null 2>&1; then r=ok; else r=fail; fi; echo "connectivity $(hostname) $ip $r"; done; `+
+		`echo "connectivity-done $(hostname)"; sleep 86400`, connectivityServiceName, expected)
+}
+
+// parseConnectivityLogs returns the reachability between the nodes from the logs of the replicas (the destination addresses are resolved with the address of each node replica)
+// and the nodes whose replica reported its results
+func parseConnectivityLogs(out string, addressNodes map[string]string) (map[string]map[string]bool, map[string]bool) {
+	reachable := make(map[string]map[string]bool)
+	done := make(map[string]bool)
+
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 2 && fields[0] == "connectivity-done":
+			done[fields[1]] = true
+		case len(fields) == 4 && fields[0] == "connectivity":
+			dst, ok := addressNodes[fields[2]]
+			if !ok || dst == fields[1] {
+				continue
+			}
+
+			if _, ok := reachable[fields[1]]; !ok {
+				reachable[fields[1]] = make(map[string]bool)
+			}
+			reachable[fields[1]][dst] = fields[3] == "ok"
+		}
+	}
+
+	return reachable, done
+}
+
+// taskAddresses returns the node (by address on the overlay network) of the running tasks (task ID => node)
+func taskAddresses(h *host.Host, tasks map[string]string) (map[string]string, error) {
+	addressNodes := make(map[string]string)
+	for taskID, node := range tasks {
+		out, err := h.RunSSHCommand(fmt.Sprintf("docker inspect --format '{{range .NetworksAttachments}}{{range .Addresses}}{{.}} {{end}}{{end}}' %s", taskID))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get the address of the task '%s': '%s'", taskID, err)
+		}
+
+		addr := parseTaskAddress(out)
+		if addr == "" {
+			return nil, fmt.Errorf("The task '%s' running on node '%s' has no address on the overlay network", taskID, node)
+		}
+		addressNodes[addr] = node
+	}
+
+	return addressNodes, nil
+}
+
+// waitForConnectivityTasks wait until a replica of the connectivity check service is running on each active node and returns the tasks (task ID => node)
+func waitForConnectivityTasks(h *host.Host, expected int, deadline time.Time) (map[string]string, error) {
+	cmd := fmt.Sprintf("docker service ps --no-trunc --filter desired-state=running --format '{{.ID}} {{.Node}} {{.CurrentState}}' %s", connectivityServiceName)
+
+	for ; time.Now().Before(deadline); time.Sleep(5 * time.Second) {
+		out, err := h.RunSSHCommand(cmd)
+		if err != nil {
+			continue
+		}
+
+		if tasks, ok := parseServiceTasks(out, expected); ok {
+			return tasks, nil
+		}
+	}
+
+	return nil, fmt.Errorf("The connectivity check replicas were not running on the %d active nodes before the timeout", expected)
+}
+
+// VerifyOverlayConnectivity deploy a temporary global service on an overlay network, each replica pings the replicas of the other nodes, and returns the connectivity matrix between the nodes (the host needs to be a manager)
+// the service and the network are removed once done, an error listing the unreachable links is returned if any link does not work
+func (gc *SwarmModeGlobalConfig) VerifyOverlayConnectivity(h *host.Host) (*ConnectivityMatrix, error) {
+	deadline := time.Now().Add(gc.connectivityTimeout())
+
+	out, err := h.RunSSHCommand(activeNodesCommand)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the Swarm mode nodes: '%s'", err)
+	}
+	expected := countActiveNodes(out)
+	if expected == 0 {
+		return nil, fmt.Errorf("No active node in the Swarm mode cluster")
+	}
+
+	// create overlay network
+	network := gc.connectivityNetwork()
+	if err := gc.CreateOverlayNetwork(h, network); err != nil {
+		return nil, err
+	}
+	defer h.RunSSHCommand(fmt.Sprintf("docker network rm %s", network))
+
+	// create global service (the replicas are named by the hostname of their node)
+	if _, err := h.RunSSHCommand(fmt.Sprintf("docker service create --detach --name %s --container-label %s --network %s --mode global --hostname '{{.Node.Hostname}}' %s sh -c '%s'",
+		connectivityServiceName, container.ManagedLabel, network, connectivityImage, generateConnectivityScript(expected))); err != nil {
+		return nil, fmt.Errorf("Failed to create the connectivity check service: '%s'", err)
+	}
+	defer h.RunSSHCommand(fmt.Sprintf("docker service rm %s", connectivityServiceName))
+
+	tasks, err := waitForConnectivityTasks(h, expected, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	addressNodes, err := taskAddresses(h, tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	// wait for the results of all replicas
+	matrix := &ConnectivityMatrix{}
+	for _, node := range tasks {
+		matrix.Nodes = append(matrix.Nodes, node)
+	}
+	sort.Strings(matrix.Nodes)
+
+	var done map[string]bool
+	for ; ; time.Sleep(5 * time.Second) {
+		out, err := h.RunSSHCommand(fmt.Sprintf("docker service logs --raw --no-trunc %s", connectivityServiceName))
+		if err == nil {
+			matrix.Reachable, done = parseConnectivityLogs(out, addressNodes)
+			if len(done) >= len(matrix.Nodes) {
+				break
+			}
+		}
+
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+
+	for _, node := range matrix.Nodes {
+		if !done[node] {
+			matrix.Missing = append(matrix.Missing, node)
+		}
+	}
+	if matrix.Reachable == nil {
+		matrix.Reachable = make(map[string]map[string]bool)
+	}
+
+	// the destinations not reported by a replica are unreachable
+	for src := range done {
+		for _, dst := range matrix.Nodes {
+			if _, ok := matrix.Reachable[src][dst]; !ok && dst != src {
+				if _, ok := matrix.Reachable[src]; !ok {
+					matrix.Reachable[src] = make(map[string]bool)
+				}
+				matrix.Reachable[src][dst] = false
+			}
+		}
+	}
+
+	if pairs := matrix.UnreachablePairs(); len(pairs) > 0 {
+		return matrix, fmt.Errorf("The overlay connectivity check failed: %d unreachable link(s): %s", len(pairs), strings.Join(pairs, ", "))
+	}
+	if len(matrix.Missing) > 0 {
+		return matrix, fmt.Errorf("The overlay connectivity check failed: no result from the node(s) %s before the timeout", strings.Join(matrix.Missing, ", "))
+	}
+
+	return matrix, nil
+}
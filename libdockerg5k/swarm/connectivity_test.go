@@ -0,0 +1,58 @@
+package swarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectivityDefaults(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{}
+	assert.Equal(t, "docker-g5k-connectivity", gc.connectivityNetwork())
+	assert.Equal(t, 3*time.Minute, gc.connectivityTimeout())
+
+	gc = &SwarmModeGlobalConfig{ConnectivityTestNetwork: "check", ConnectivityTestTimeout: time.Minute}
+	assert.Equal(t, "check", gc.connectivityNetwork())
+	assert.Equal(t, time.Minute, gc.connectivityTimeout())
+
+	assert.Error(t, (&SwarmModeGlobalConfig{ConnectivityTestNetwork: "my check"}).Validate())
+}
+
+func TestCountActiveNodes(t *testing.T) {
+	assert.Equal(t, 2, countActiveNodes("Ready Active\nReady Drain\nDown Active\nReady Active\n"))
+	assert.Equal(t, 0, countActiveNodes(""))
+}
+
+func TestGenerateConnectivityScript(t *testing.T) {
+	script := generateConnectivityScript(3)
+	assert.Contains(t, script, "nslookup tasks.docker-g5k-connectivity")
+	assert.Contains(t, script, "-ge 3 ]")
+	assert.NotContains(t, script, "'")
+}
+
+func TestParseConnectivityLogs(t *testing.T) {
+	addressNodes := map[string]string{"10.0.1.2": "lille-0", "10.0.1.3": "lille-1", "10.0.1.4": "nancy-0"}
+	out := "connectivity lille-0 10.0.1.2 ok\n" +
+		"connectivity lille-0 10.0.1.3 ok\n" +
+		"connectivity lille-0 10.0.1.4 fail\n" +
+		"connectivity-done lille-0\n" +
+		"connectivity lille-1 10.0.1.2 ok\n" +
+		"connectivity lille-1 10.0.1.9 ok\n"
+
+	reachable, done := parseConnectivityLogs(out, addressNodes)
+	assert.Equal(t, map[string]map[string]bool{
+		"lille-0": {"lille-1": true, "nancy-0": false},
+		"lille-1": {"lille-0": true},
+	}, reachable)
+	assert.Equal(t, map[string]bool{"lille-0": true}, done)
+}
+
+func TestUnreachablePairs(t *testing.T) {
+	m := &ConnectivityMatrix{Reachable: map[string]map[string]bool{
+		"nancy-0": {"lille-0": false, "lille-1": true},
+		"lille-0": {"nancy-0": false, "lille-1": true},
+	}}
+
+	assert.Equal(t, []string{"lille-0->nancy-0", "nancy-0->lille-0"}, m.UnreachablePairs())
+}
@@ -0,0 +1,142 @@
+package swarm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+var (
+	// regexOffloadFeature match an offload feature name of ethtool (ex: gro, tx-udp_tnl-segmentation)
+	regexOffloadFeature = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+	// offloadFeatureAliases are the short names of the offload features accepted by ethtool, by their name in the features list
+	offloadFeatureAliases = map[string]string{
+		"rx":  "rx-checksumming",
+		"tx":  "tx-checksumming",
+		"sg":  "scatter-gather",
+		"tso": "tcp-segmentation-offload",
+		"gso": "generic-segmentation-offload",
+		"gro": "generic-receive-offload",
+		"lro": "large-receive-offload",
+	}
+)
+
+// DataPathTuning contain the network stack settings of the nodes for the VXLAN data path of the overlay networks (nothing is changed if empty)
+type DataPathTuning struct {
+	Offloads map[string]bool   // offload features of the data path interface set with ethtool (ex: gro, tx-udp_tnl-segmentation), enabled if true
+	Sysctls  map[string]string // kernel parameters of the UDP data path (ex: net.core.rmem_max=26214400), the parameters of a node take precedence
+}
+
+// IsSet returns true if data path settings are configured, false otherwise
+func (t *DataPathTuning) IsSet() bool {
+	return len(t.Offloads) > 0 || len(t.Sysctls) > 0
+}
+
+// Validate check the offload features names (the kernel parameters are checked with the sysctls of the cluster)
+func (t *DataPathTuning) Validate() error {
+	for f := range t.Offloads {
+		if !regexOffloadFeature.MatchString(f) {
+			return fmt.Errorf("Invalid data path offload feature: '%s' (ex: gro, tx-udp_tnl-segmentation)", f)
+		}
+	}
+
+	return nil
+}
+
+// offloadFeature is the state of an offload feature of an interface
+type offloadFeature struct {
+	enabled bool
+	fixed   bool // the state can't be changed on the interface
+}
+
+// parseOffloadFeatures returns the offload features by name from the 'ethtool -k' output (line format: {feature}: on|off [fixed])
+func parseOffloadFeatures(out string) map[string]offloadFeature {
+	features := make(map[string]offloadFeature)
+	for _, line := range strings.Split(out, "\n") {
+		s := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(s) != 2 {
+			continue
+		}
+
+		fields := strings.Fields(s[1])
+		if len(fields) == 0 || (fields[0] != "on" && fields[0] != "off") {
+			continue
+		}
+
+		features[s[0]] = offloadFeature{enabled: fields[0] == "on", fixed: strings.Contains(s[1], "[fixed]")}
+	}
+
+	return features
+}
+
+// sortedOffloads returns the names of the offload features (sorted)
+func (t *DataPathTuning) sortedOffloads() []string {
+	names := []string{}
+	for f := range t.Offloads {
+		names = append(names, f)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// checkOffloads check the offload features are supported by the interface and can be set to the requested state
+func (t *DataPathTuning) checkOffloads(features map[string]offloadFeature) error {
+	for _, f := range t.sortedOffloads() {
+		name := f
+		if alias, ok := offloadFeatureAliases[f]; ok {
+			name = alias
+		}
+
+		current, ok := features[name]
+		if !ok {
+			return fmt.Errorf("The offload feature '%s' is not supported by the data path interface", f)
+		}
+
+		if current.fixed && current.enabled != t.Offloads[f] {
+			return fmt.Errorf("The offload feature '%s' can't be changed on the data path interface (fixed)", f)
+		}
+	}
+
+	return nil
+}
+
+// generateOffloadsCommand returns the ethtool command setting the offload features of the interface
+func (t *DataPathTuning) generateOffloadsCommand(iface string) string {
+	cmd := fmt.Sprintf("ethtool -K %s", iface)
+	for _, f := range t.sortedOffloads() {
+		state := "off"
+		if t.Offloads[f] {
+			state = "on"
+		}
+		cmd = fmt.Sprintf("%s %s %s", cmd, f, state)
+	}
+
+	return cmd
+}
+
+// ApplyOffloads check the offload features can be set on the data path interface of the host and set them (nothing is done if no feature is configured)
+func (t *DataPathTuning) ApplyOffloads(h *host.Host, iface string) error {
+	if len(t.Offloads) == 0 {
+		return nil
+	}
+
+	out, err := h.RunSSHCommand(fmt.Sprintf("ethtool -k %s", iface))
+	if err != nil {
+		return fmt.Errorf("Failed to get the offload features of the interface '%s': '%s'", iface, err)
+	}
+
+	if err := t.checkOffloads(parseOffloadFeatures(out)); err != nil {
+		return fmt.Errorf("Interface '%s': %s", iface, err)
+	}
+
+	if _, err := h.RunSSHCommand(t.generateOffloadsCommand(iface)); err != nil {
+		return fmt.Errorf("Failed to set the offload features of the interface '%s': '%s'", iface, err)
+	}
+
+	return nil
+}
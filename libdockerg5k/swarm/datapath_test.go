@@ -0,0 +1,53 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ethtoolFeatures = `Features for eth0:
+rx-checksumming: on
+tx-checksumming: on
+	tx-checksum-ipv4: off [fixed]
+generic-segmentation-offload: on
+generic-receive-offload: on
+large-receive-offload: off [fixed]
+tx-udp_tnl-segmentation: on
+rx-gro-list: off
+`
+
+func TestDataPathTuningValidate(t *testing.T) {
+	assert.NoError(t, (&DataPathTuning{}).Validate())
+	assert.NoError(t, (&DataPathTuning{Offloads: map[string]bool{"gro": true, "tx-udp_tnl-segmentation": false}}).Validate())
+
+	assert.Error(t, (&DataPathTuning{Offloads: map[string]bool{"gro on": true}}).Validate())
+	assert.Error(t, (&DataPathTuning{Offloads: map[string]bool{"-gro": true}}).Validate())
+}
+
+func TestParseOffloadFeatures(t *testing.T) {
+	features := parseOffloadFeatures(ethtoolFeatures)
+
+	assert.Len(t, features, 8)
+	assert.Equal(t, offloadFeature{enabled: true}, features["generic-receive-offload"])
+	assert.Equal(t, offloadFeature{enabled: false, fixed: true}, features["tx-checksum-ipv4"])
+	assert.NotContains(t, features, "Features for eth0")
+}
+
+func TestCheckOffloads(t *testing.T) {
+	features := parseOffloadFeatures(ethtoolFeatures)
+
+	assert.NoError(t, (&DataPathTuning{Offloads: map[string]bool{"gro": false, "rx-gro-list": true, "tx-udp_tnl-segmentation": false}}).checkOffloads(features))
+
+	// a fixed feature can only be set to its current state
+	assert.NoError(t, (&DataPathTuning{Offloads: map[string]bool{"lro": false}}).checkOffloads(features))
+	assert.Error(t, (&DataPathTuning{Offloads: map[string]bool{"lro": true}}).checkOffloads(features))
+
+	// unsupported feature
+	assert.Error(t, (&DataPathTuning{Offloads: map[string]bool{"rx-udp-gro-forwarding": true}}).checkOffloads(features))
+}
+
+func TestGenerateOffloadsCommand(t *testing.T) {
+	tuning := DataPathTuning{Offloads: map[string]bool{"tx-udp_tnl-segmentation": false, "gro": true}}
+	assert.Equal(t, "ethtool -K eth1 gro on tx-udp_tnl-segmentation off", tuning.generateOffloadsCommand("eth1"))
+}
@@ -0,0 +1,92 @@
+package swarm
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// registratorName is the name of the container connecting the standalone containers of a node to the service discovery network
+	registratorName = "docker-g5k-registrator"
+
+	// registratorImage is the image of the registrator (Docker client)
+	registratorImage = "docker:cli"
+)
+
+// regexNetworkName match a Docker network name
+var regexNetworkName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// validateServiceDiscovery check the name and subnet of the service discovery network (disabled if the name is empty)
+func (gc *SwarmModeGlobalConfig) validateServiceDiscovery() error {
+	if gc.ServiceDiscoveryNetwork == "" {
+		if gc.ServiceDiscoverySubnet != "" {
+			return fmt.Errorf("The service discovery subnet needs a service discovery network")
+		}
+		return nil
+	}
+
+	if !regexNetworkName.MatchString(gc.ServiceDiscoveryNetwork) {
+		return fmt.Errorf("Invalid service discovery network name: '%s'", gc.ServiceDiscoveryNetwork)
+	}
+
+	if gc.ServiceDiscoverySubnet != "" {
+		ip, _, err := net.ParseCIDR(gc.ServiceDiscoverySubnet)
+		if err != nil || ip.To4() == nil {
+			return fmt.Errorf("The service discovery subnet is not a valid IPv4 CIDR: '%s'", gc.ServiceDiscoverySubnet)
+		}
+	}
+
+	return nil
+}
+
+// generateServiceDiscoveryNetworkCommand returns the command creating the attachable and encrypted overlay network of the service discovery (if it does not exist)
+func (gc *SwarmModeGlobalConfig) generateServiceDiscoveryNetworkCommand() string {
+	subnet := ""
+	if gc.ServiceDiscoverySubnet != "" {
+		subnet = fmt.Sprintf(" --subnet %s", gc.ServiceDiscoverySubnet)
+	}
+
+	return fmt.Sprintf("docker network inspect %[1]s >/dev/null 2>&1 || docker network create --driver overlay --attachable --opt encrypted%[2]s %[1]s", gc.ServiceDiscoveryNetwork, subnet)
+}
+
+// generateRegistratorCommand returns the command running the registrator of the node with the restart policy, stop signal/timeout and image platform
+// the registrator connects the running and started containers to the service discovery network, except the Swarm mode tasks and the docker-g5k containers
+func (gc *SwarmModeGlobalConfig) generateRegistratorCommand(restartPolicy string, stop container.StopConfig, platform string) string {
+	script := fmt.Sprintf(`c() { docker inspect --format "{{.Config.Labels}}" $1 | grep -qE "com.docker.swarm.task.id|managed-by:docker-g5k" || docker network connect %[1]s $1 2>/dev/null; }; `+
+		`for id in $(docker ps -q); do c $id; done; `+
+		`docker events --filter type=container --filter event=start --format "{{.ID}}" | while read id; do c $id; done`, gc.ServiceDiscoveryNetwork)
+
+	return fmt.Sprintf("docker rm -f %[1]s >/dev/null 2>&1; docker run -d %[2]s --name %[1]s %[3]s -v /var/run/docker.sock:/var/run/docker.sock %[4]s sh -c '%[5]s'",
+		registratorName, container.RestartFlag(restartPolicy)+stop.Flags()+container.PlatformFlag(platform), container.LabelFlag, registratorImage, script)
+}
+
+// CreateServiceDiscoveryNetwork create the service discovery network of the cluster (the host needs to be a manager, nothing is done if disabled)
+// the containers attached to the network resolve each other by name with the embedded DNS of the Engines
+func (gc *SwarmModeGlobalConfig) CreateServiceDiscoveryNetwork(h *host.Host) error {
+	if gc.ServiceDiscoveryNetwork == "" {
+		return nil
+	}
+
+	if _, err := h.RunSSHCommand(gc.generateServiceDiscoveryNetworkCommand()); err != nil {
+		return fmt.Errorf("Failed to create the service discovery network '%s': '%s'", gc.ServiceDiscoveryNetwork, err)
+	}
+
+	return nil
+}
+
+// StartRegistrator run the registrator connecting the standalone containers of the host to the service discovery network (nothing is done if disabled)
+func (gc *SwarmModeGlobalConfig) StartRegistrator(h *host.Host, restartPolicy string, stop container.StopConfig, platform string) error {
+	if gc.ServiceDiscoveryNetwork == "" {
+		return nil
+	}
+
+	if _, err := h.RunSSHCommand(gc.generateRegistratorCommand(restartPolicy, stop, platform)); err != nil {
+		return fmt.Errorf("Failed to start the service discovery registrator: '%s'", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateServiceDiscovery(t *testing.T) {
+	assert.NoError(t, (&SwarmModeGlobalConfig{}).validateServiceDiscovery())
+	assert.NoError(t, (&SwarmModeGlobalConfig{ServiceDiscoveryNetwork: "discovery"}).validateServiceDiscovery())
+	assert.NoError(t, (&SwarmModeGlobalConfig{ServiceDiscoveryNetwork: "discovery", ServiceDiscoverySubnet: "10.20.0.0/16"}).validateServiceDiscovery())
+
+	assert.Error(t, (&SwarmModeGlobalConfig{ServiceDiscoverySubnet: "10.20.0.0/16"}).validateServiceDiscovery())
+	assert.Error(t, (&SwarmModeGlobalConfig{ServiceDiscoveryNetwork: "my network"}).validateServiceDiscovery())
+	assert.Error(t, (&SwarmModeGlobalConfig{ServiceDiscoveryNetwork: "discovery", ServiceDiscoverySubnet: "10.20.0.0"}).validateServiceDiscovery())
+	assert.Error(t, (&SwarmModeGlobalConfig{ServiceDiscoveryNetwork: "discovery", ServiceDiscoverySubnet: "fd00::/64"}).validateServiceDiscovery())
+}
+
+func TestGenerateServiceDiscoveryNetworkCommand(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{ServiceDiscoveryNetwork: "discovery"}
+	assert.Equal(t, "docker network inspect discovery >/dev/null 2>&1 || docker network create --driver overlay --attachable --opt encrypted discovery", gc.generateServiceDiscoveryNetworkCommand())
+
+	gc.ServiceDiscoverySubnet = "10.20.0.0/16"
+	assert.Equal(t, "docker network inspect discovery >/dev/null 2>&1 || docker network create --driver overlay --attachable --opt encrypted --subnet 10.20.0.0/16 discovery", gc.generateServiceDiscoveryNetworkCommand())
+}
+
+func TestGenerateRegistratorCommand(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{ServiceDiscoveryNetwork: "discovery"}
+	cmd := gc.generateRegistratorCommand("", container.StopConfig{}, "")
+
+	assert.Contains(t, cmd, "docker run -d --restart=always --name docker-g5k-registrator --label managed-by=docker-g5k -v /var/run/docker.sock:/var/run/docker.sock docker:cli sh -c '")
+	assert.Contains(t, cmd, "docker network connect discovery $1")
+	assert.Contains(t, cmd, "docker events --filter type=container --filter event=start")
+}
@@ -0,0 +1,96 @@
+package swarm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultJoinRetries is the default number of retries of a failed join
+	defaultJoinRetries = 5
+
+	// defaultJoinBackoff is the default delay before the first retry of a failed join (doubled at each retry)
+	defaultJoinBackoff = 2 * time.Second
+
+	// maxJoinBackoff is the maximum delay between two join attempts
+	maxJoinBackoff = 1 * time.Minute
+)
+
+var (
+	// permanentJoinErrors are the messages of the join errors that will not be fixed by a retry (invalid token or address)
+	permanentJoinErrors = []string{"join token", "invalid token", "token is invalid", "invalid remote address", "certificate signed by unknown authority"}
+
+	// alreadyJoinedError is the message of the error returned when the node is already part of a Swarm mode cluster
+	alreadyJoinedError = "already part of a swarm"
+)
+
+// isPermanentJoinError returns true if the join error will not be fixed by a retry (ex: wrong token), false otherwise (ex: manager not ready yet)
+func isPermanentJoinError(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, e := range permanentJoinErrors {
+		if strings.Contains(msg, e) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// joinRetries returns the number of retries of a failed join
+func (gc *SwarmModeGlobalConfig) joinRetries() int {
+	switch {
+	case gc.JoinRetries < 0:
+		return 0
+	case gc.JoinRetries == 0:
+		return defaultJoinRetries
+	}
+
+	return gc.JoinRetries
+}
+
+// joinRetryDelay returns the delay before the given retry (starting at 1), doubled at each retry and capped
+func (gc *SwarmModeGlobalConfig) joinRetryDelay(retry int) time.Duration {
+	delay := gc.JoinBackoff
+	if delay <= 0 {
+		delay = defaultJoinBackoff
+	}
+
+	for i := 1; i < retry && delay < maxJoinBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxJoinBackoff {
+		return maxJoinBackoff
+	}
+
+	return delay
+}
+
+// retryJoin run the join until it succeeds, fails with a permanent error or the retries are exhausted, returning the last error
+func (gc *SwarmModeGlobalConfig) retryJoin(join func() (string, error)) error {
+	retries := gc.joinRetries()
+
+	for attempt := 1; ; attempt++ {
+		out, err := join()
+		if err == nil {
+			return nil
+		}
+		msg := err.Error() + out
+
+		// a previous attempt may have joined the cluster before failing (ex: timeout waiting for the manager answer)
+		if attempt > 1 && strings.Contains(strings.ToLower(msg), alreadyJoinedError) {
+			return nil
+		}
+
+		if isPermanentJoinError(msg) {
+			return fmt.Errorf("Failed to join the Swarm mode cluster: '%s'", err)
+		}
+
+		if attempt > retries {
+			return fmt.Errorf("Failed to join the Swarm mode cluster after %d attempt(s): '%s'", attempt, err)
+		}
+
+		time.Sleep(gc.joinRetryDelay(attempt))
+	}
+}
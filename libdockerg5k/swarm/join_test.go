@@ -0,0 +1,92 @@
+package swarm
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// unreachableManager returns a join failing with a connection error the given number of times before succeeding, and the number of attempts
+func unreachableManager(failures int) (func() (string, error), *int) {
+	attempts := 0
+	return func() (string, error) {
+		attempts++
+		if attempts <= failures {
+			return "Error response from daemon: rpc error: code = Unavailable desc = connection error: dial tcp 172.16.20.1:2377: connect: connection refused", fmt.Errorf("exit status 1")
+		}
+		return "This node joined a swarm as a worker.", nil
+	}, &attempts
+}
+
+func TestIsPermanentJoinError(t *testing.T) {
+	assert.True(t, isPermanentJoinError("Error response from daemon: rpc error: code = InvalidArgument desc = A valid join token is necessary to join this cluster"))
+	assert.True(t, isPermanentJoinError("Error response from daemon: invalid join token"))
+	assert.False(t, isPermanentJoinError("Error response from daemon: rpc error: code = Unavailable desc = connection error: connect: connection refused"))
+	assert.False(t, isPermanentJoinError("Error response from daemon: Timeout was reached before node joined."))
+}
+
+func TestJoinRetryDefaults(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{}
+	assert.Equal(t, defaultJoinRetries, gc.joinRetries())
+	assert.Equal(t, defaultJoinBackoff, gc.joinRetryDelay(1))
+	assert.Equal(t, 2*defaultJoinBackoff, gc.joinRetryDelay(2))
+	assert.Equal(t, maxJoinBackoff, gc.joinRetryDelay(20))
+
+	assert.Equal(t, 0, (&SwarmModeGlobalConfig{JoinRetries: -1}).joinRetries())
+	assert.Equal(t, 8, (&SwarmModeGlobalConfig{JoinRetries: 8}).joinRetries())
+
+	assert.Error(t, (&SwarmModeGlobalConfig{JoinBackoff: -time.Second}).Validate())
+}
+
+func TestRetryJoinTemporarilyUnreachableManager(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{JoinRetries: 3, JoinBackoff: time.Millisecond}
+
+	join, attempts := unreachableManager(2)
+	assert.NoError(t, gc.retryJoin(join))
+	assert.Equal(t, 3, *attempts)
+}
+
+func TestRetryJoinExhausted(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{JoinRetries: 2, JoinBackoff: time.Millisecond}
+
+	join, attempts := unreachableManager(10)
+	err := gc.retryJoin(join)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "after 3 attempt(s)")
+	assert.Equal(t, 3, *attempts)
+
+	// no retry if disabled
+	gc.JoinRetries = -1
+	join, attempts = unreachableManager(1)
+	assert.Error(t, gc.retryJoin(join))
+	assert.Equal(t, 1, *attempts)
+}
+
+func TestRetryJoinWrongToken(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{JoinRetries: 3, JoinBackoff: time.Millisecond}
+
+	attempts := 0
+	err := gc.retryJoin(func() (string, error) {
+		attempts++
+		return "Error response from daemon: rpc error: code = InvalidArgument desc = A valid join token is necessary to join this cluster", fmt.Errorf("exit status 1")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetryJoinAlreadyJoined(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{JoinRetries: 3, JoinBackoff: time.Millisecond}
+
+	// the first attempt timed out after joining the cluster
+	attempts := 0
+	assert.NoError(t, gc.retryJoin(func() (string, error) {
+		attempts++
+		if attempts == 1 {
+			return "Error response from daemon: Timeout was reached before node joined.", fmt.Errorf("exit status 1")
+		}
+		return "Error response from daemon: This node is already part of a swarm.", fmt.Errorf("exit status 1")
+	}))
+	assert.Equal(t, 2, attempts)
+}
@@ -0,0 +1,59 @@
+package swarm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// managersStatusCommand returns the hostname, leadership and reachability of the Swarm mode managers (one manager per line)
+	managersStatusCommand = "docker node inspect --format '{{.Description.Hostname}} {{.ManagerStatus.Leader}} {{.ManagerStatus.Reachability}}' $(docker node ls -q --filter role=manager)"
+)
+
+// ManagerStatus contain the Raft status of a Swarm mode manager
+type ManagerStatus struct {
+	Hostname     string
+	Leader       bool
+	Reachability string // reachable, unreachable or unknown
+}
+
+// IsReachable returns true if the manager is reachable (the leader is always reachable), false otherwise
+func (m *ManagerStatus) IsReachable() bool {
+	return m.Leader || m.Reachability == "reachable"
+}
+
+// parseManagersStatus returns the managers status from the 'docker node inspect' output (format: {hostname} {leader} {reachability})
+func parseManagersStatus(out string) []ManagerStatus {
+	managers := []ManagerStatus{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		managers = append(managers, ManagerStatus{
+			Hostname:     fields[0],
+			Leader:       fields[1] == "true",
+			Reachability: fields[2],
+		})
+	}
+
+	return managers
+}
+
+// ManagersStatus returns the Raft status of the Swarm mode managers (the host needs to be a manager of a cluster with a leader)
+func (gc *SwarmModeGlobalConfig) ManagersStatus(h *host.Host) ([]ManagerStatus, error) {
+	out, err := h.RunSSHCommand(managersStatusCommand)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the Swarm managers status: '%s'", err)
+	}
+
+	managers := parseManagersStatus(out)
+	if len(managers) == 0 {
+		return nil, fmt.Errorf("No Swarm manager found in the managers status: '%s'", strings.TrimSpace(out))
+	}
+
+	return managers, nil
+}
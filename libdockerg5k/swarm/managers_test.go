@@ -0,0 +1,23 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseManagersStatus(t *testing.T) {
+	out := "lille-0 true reachable\nlille-1 false reachable\n\nlille-2 false unreachable\n"
+
+	managers := parseManagersStatus(out)
+	assert.Len(t, managers, 3)
+	assert.Equal(t, ManagerStatus{Hostname: "lille-0", Leader: true, Reachability: "reachable"}, managers[0])
+	assert.True(t, managers[0].IsReachable())
+	assert.True(t, managers[1].IsReachable())
+	assert.False(t, managers[2].IsReachable())
+}
+
+func TestParseManagersStatusIncorrect(t *testing.T) {
+	assert.Empty(t, parseManagersStatus(""))
+	assert.Empty(t, parseManagersStatus("Error: This node is not a swarm manager."))
+}
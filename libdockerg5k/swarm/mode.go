@@ -3,17 +3,177 @@ package swarm
 import (
 	"fmt"
 	"net"
+	"sync"
+	"time"
 
 	"strings"
 
 	"github.com/docker/machine/libmachine/host"
 )
 
+const (
+	// defaultInitWaitTimeout is the default maximum time a node wait for the cluster initialization before joining
+	defaultInitWaitTimeout = 10 * time.Minute
+)
+
 // SwarmModeGlobalConfig contain Swarm Mode global configuration
 type SwarmModeGlobalConfig struct {
-	ManagerToken        string
-	BootstrapManagerURL string
-	WorkerToken         string
+	ManagerToken        string `json:"-"`
+	BootstrapManagerURL string `json:"-"`
+	WorkerToken         string `json:"-"`
+
+	// maximum time a node wait for the cluster initialization before joining (default if not set)
+	InitWaitTimeout time.Duration
+
+	// number of retries of a join failing while the manager is not ready (default if not set, disabled if negative) and delay before the first retry, doubled at each retry (default if not set)
+	JoinRetries int
+	JoinBackoff time.Duration
+
+	// default options of the overlay networks
+	OverlayDefaults OverlayDefaults
+
+	// network stack settings of the nodes for the overlay networks data path (applied at the provisioning)
+	DataPathTuning DataPathTuning
+
+	// attachable and encrypted overlay network connecting the standalone containers of the nodes for the name resolution (disabled if empty), and its subnet (allocated by Docker if empty)
+	ServiceDiscoveryNetwork string
+	ServiceDiscoverySubnet  string
+
+	// orchestration options set at the cluster initialization (updated by UpdateSwarmConfig)
+	OrchestrationOpts OrchestrationOpts
+
+	// root CA rotation options (used by RotateCA)
+	CAOptions CAOptions
+
+	// site of the bootstrap manager ('auto' to select the site with the lowest latency to the other sites, site of the first manager if empty)
+	BootstrapSite string
+
+	// address advertised by the bootstrap manager and used by the nodes to join the cluster (detected if not set)
+	AdvertiseAddr string
+
+	// smoke test service image and number of replicas (default if not set)
+	SmokeTestImage    string
+	SmokeTestReplicas int
+
+	// placement preferences of the replicated services created on the cluster, ex: spread=node.labels.nic (spread=node.id if not set)
+	// the node labels need to be derived from the hardware description, the Engine labels (spread=engine.labels.{key}) to be set on the nodes
+	DefaultPlacementPreferences []string
+
+	// overlay network and maximum time of the connectivity check between the nodes (default if not set)
+	ConnectivityTestNetwork string
+	ConnectivityTestTimeout time.Duration
+
+	// closed when the cluster initialization is done (successfully or not)
+	readyOnce sync.Once
+	ready     chan struct{}
+	doneOnce  sync.Once
+	initErr   error
+}
+
+// readiness returns the channel closed when the cluster initialization is done
+func (gc *SwarmModeGlobalConfig) readiness() chan struct{} {
+	gc.readyOnce.Do(func() {
+		gc.ready = make(chan struct{})
+	})
+
+	return gc.ready
+}
+
+// setInitResult store the result of the cluster initialization and wake up the nodes waiting to join
+func (gc *SwarmModeGlobalConfig) setInitResult(err error) {
+	gc.doneOnce.Do(func() {
+		gc.initErr = err
+		close(gc.readiness())
+	})
+}
+
+// waitForInit block until the cluster initialization is done or the timeout expire
+func (gc *SwarmModeGlobalConfig) waitForInit() error {
+	// use default timeout if none is given
+	timeout := gc.InitWaitTimeout
+	if timeout <= 0 {
+		timeout = defaultInitWaitTimeout
+	}
+
+	select {
+	case <-gc.readiness():
+		if gc.initErr != nil {
+			return fmt.Errorf("The Swarm mode cluster initialization failed: '%s'", gc.initErr)
+		}
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("The Swarm mode cluster was not initialized after %s", timeout)
+	}
+}
+
+// Validate check the Swarm mode configuration
+func (gc *SwarmModeGlobalConfig) Validate() error {
+	if gc.AdvertiseAddr != "" && net.ParseIP(gc.AdvertiseAddr) == nil {
+		return fmt.Errorf("The Swarm mode advertise address is not a valid IP address: '%s'", gc.AdvertiseAddr)
+	}
+
+	if gc.JoinBackoff < 0 {
+		return fmt.Errorf("The Swarm mode join backoff needs to be positive: '%s'", gc.JoinBackoff)
+	}
+
+	if gc.ConnectivityTestNetwork != "" && !regexNetworkName.MatchString(gc.ConnectivityTestNetwork) {
+		return fmt.Errorf("Invalid connectivity check network name: '%s'", gc.ConnectivityTestNetwork)
+	}
+
+	if err := gc.OrchestrationOpts.Validate(); err != nil {
+		return err
+	}
+
+	if err := gc.CAOptions.Validate(); err != nil {
+		return err
+	}
+
+	if err := gc.DataPathTuning.Validate(); err != nil {
+		return err
+	}
+
+	if err := gc.validateServiceDiscovery(); err != nil {
+		return err
+	}
+
+	if err := gc.validatePlacementPreferences(); err != nil {
+		return err
+	}
+
+	return gc.OverlayDefaults.Validate()
+}
+
+// isAddressAssigned returns true if the address is assigned to an interface in the 'ip -o addr show' output, false otherwise
+func isAddressAssigned(addr string, ipAddrOutput string) bool {
+	ip := net.ParseIP(addr)
+
+	for _, line := range strings.Split(ipAddrOutput, "\n") {
+		// line format: {index}: {interface} inet|inet6 {address}/{prefix} ...
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+
+		if a, _, err := net.ParseCIDR(fields[3]); err == nil && a.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAdvertiseAddr check the advertise address is assigned to the host
+func (gc *SwarmModeGlobalConfig) checkAdvertiseAddr(h *host.Host) error {
+	out, err := h.RunSSHCommand("ip -o addr show")
+	if err != nil {
+		return fmt.Errorf("Failed to get the node addresses: '%s'", err)
+	}
+
+	if !isAddressAssigned(gc.AdvertiseAddr, out) {
+		return fmt.Errorf("The Swarm mode advertise address '%s' is not assigned to the bootstrap manager", gc.AdvertiseAddr)
+	}
+
+	return nil
 }
 
 // IsSwarmModeClusterInitialized returns true if Swarm mode cluster is initialized (Manager/Worker tokens set), and false otherwise
@@ -21,15 +181,45 @@ func (gc *SwarmModeGlobalConfig) IsSwarmModeClusterInitialized() bool {
 	return (gc.ManagerToken != "") && (gc.WorkerToken != "")
 }
 
-// InitSwarmModeCluster initialize a new Swarm mode cluster on the given host and returns the Manager/Worker join tokens
-func (gc *SwarmModeGlobalConfig) InitSwarmModeCluster(h *host.Host) error {
+// advertiseAddrFlag returns the 'advertise-addr' flag for the Swarm init/join commands (empty if no interface is given)
+func advertiseAddrFlag(advertiseInterface string) string {
+	if advertiseInterface == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" --advertise-addr %s", advertiseInterface)
+}
+
+// InitSwarmModeCluster initialize a new Swarm mode cluster on the given host (advertising the given interface if set) and returns the Manager/Worker join tokens
+func (gc *SwarmModeGlobalConfig) InitSwarmModeCluster(h *host.Host, advertiseInterface string) error {
 	// check if Swarm mode cluster is already initialized
 	if gc.IsSwarmModeClusterInitialized() {
 		return fmt.Errorf("The Swarm Mode cluster is already initialized")
 	}
 
+	// wake up the nodes waiting to join
+	var err error
+	defer func() {
+		gc.setInitResult(err)
+	}()
+
+	err = gc.initSwarmModeCluster(h, advertiseInterface)
+	return err
+}
+
+// initSwarmModeCluster run the Swarm mode cluster initialization on the given host and store the join tokens
+func (gc *SwarmModeGlobalConfig) initSwarmModeCluster(h *host.Host, advertiseInterface string) error {
+	// the advertise address takes precedence over the advertised interface
+	if gc.AdvertiseAddr != "" {
+		if err := gc.checkAdvertiseAddr(h); err != nil {
+			return err
+		}
+
+		advertiseInterface = gc.AdvertiseAddr
+	}
+
 	// init Swarm mode cluster
-	_, err := h.RunSSHCommand("docker swarm init")
+	_, err := h.RunSSHCommand(fmt.Sprintf("docker swarm init%s%s%s", advertiseAddrFlag(advertiseInterface), gc.OverlayDefaults.dataPathPortFlag(), gc.OrchestrationOpts.flags()))
 	if err != nil {
 		return err
 	}
@@ -52,6 +242,18 @@ func (gc *SwarmModeGlobalConfig) InitSwarmModeCluster(h *host.Host) error {
 		return err
 	}
 
+	// use the address of the advertised interface if set
+	if gc.AdvertiseAddr != "" {
+		ip = gc.AdvertiseAddr
+	} else if advertiseInterface != "" {
+		nodeAddr, err := h.RunSSHCommand("docker info --format '{{.Swarm.NodeAddr}}'")
+		if err != nil {
+			return err
+		}
+
+		ip = strings.TrimSpace(nodeAddr)
+	}
+
 	// remove spaces/new lines at the begining/end of the tokens
 	gc.ManagerToken = strings.TrimSpace(managerToken)
 	gc.WorkerToken = strings.TrimSpace(workerToken)
@@ -62,8 +264,46 @@ func (gc *SwarmModeGlobalConfig) InitSwarmModeCluster(h *host.Host) error {
 	return nil
 }
 
-// JoinSwarmModeCluster makes the host join a Swarm mode cluster as Manager or Worker
-func (gc *SwarmModeGlobalConfig) JoinSwarmModeCluster(host *host.Host, isManager bool) error {
+// LoadJoinTokens get the Manager/Worker join tokens and the manager address from a manager of an existing Swarm mode cluster (ex: to add nodes after the provisioning)
+func (gc *SwarmModeGlobalConfig) LoadJoinTokens(h *host.Host) error {
+	// already initialized by this configuration
+	if gc.IsSwarmModeClusterInitialized() {
+		return nil
+	}
+
+	managerToken, err := h.RunSSHCommand("docker swarm join-token -q manager")
+	if err != nil {
+		return fmt.Errorf("Unable to get the Swarm manager join token: '%s'", err)
+	}
+
+	workerToken, err := h.RunSSHCommand("docker swarm join-token -q worker")
+	if err != nil {
+		return fmt.Errorf("Unable to get the Swarm worker join token: '%s'", err)
+	}
+
+	nodeAddr, err := h.RunSSHCommand("docker info --format '{{.Swarm.NodeAddr}}'")
+	if err != nil {
+		return fmt.Errorf("Unable to get the Swarm manager address: '%s'", err)
+	}
+
+	gc.ManagerToken = strings.TrimSpace(managerToken)
+	gc.WorkerToken = strings.TrimSpace(workerToken)
+	gc.BootstrapManagerURL = net.JoinHostPort(strings.TrimSpace(nodeAddr), "2377")
+
+	// the joining nodes don't wait for an initialization
+	gc.setInitResult(nil)
+
+	return nil
+}
+
+// JoinSwarmModeCluster makes the host join a Swarm mode cluster as Manager or Worker (advertising the given interface if set), waiting for the cluster initialization if needed
+// The join is retried while the manager is not ready (the permanent errors, ex: wrong token, are not retried)
+func (gc *SwarmModeGlobalConfig) JoinSwarmModeCluster(host *host.Host, isManager bool, advertiseInterface string) error {
+	// wait for the bootstrap manager to initialize the cluster
+	if err := gc.waitForInit(); err != nil {
+		return err
+	}
+
 	// by default, join as Worker
 	token := gc.WorkerToken
 
@@ -73,9 +313,40 @@ func (gc *SwarmModeGlobalConfig) JoinSwarmModeCluster(host *host.Host, isManager
 	}
 
 	// run swarm join command
-	if _, err := host.RunSSHCommand(fmt.Sprintf("docker swarm join --token %s %s", token, gc.BootstrapManagerURL)); err != nil {
-		return err
+	return gc.retryJoin(func() (string, error) {
+		return host.RunSSHCommand(fmt.Sprintf("docker swarm join%s --token %s %s", advertiseAddrFlag(advertiseInterface), token, gc.BootstrapManagerURL))
+	})
+}
+
+// countReachableManagers returns the number of reachable managers from the 'docker node inspect' output (one reachability status per line)
+func countReachableManagers(reachability string) int {
+	count := 0
+	for _, status := range strings.Split(reachability, "\n") {
+		if strings.TrimSpace(status) == "reachable" {
+			count++
+		}
 	}
 
-	return nil
+	return count
+}
+
+// WaitForManagersQuorum wait until a majority of the given number of managers are reachable in the Swarm mode cluster (the host needs to be a manager)
+func (gc *SwarmModeGlobalConfig) WaitForManagersQuorum(h *host.Host, nbManagers int, timeout time.Duration) error {
+	quorum := (nbManagers / 2) + 1
+	reachable := 0
+
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(5 * time.Second) {
+		// get managers reachability
+		out, err := h.RunSSHCommand("docker node inspect --format '{{.ManagerStatus.Reachability}}' $(docker node ls -q --filter role=manager)")
+		if err != nil {
+			continue
+		}
+
+		// check if the quorum is reached
+		if reachable = countReachableManagers(out); reachable >= quorum {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("The Swarm managers quorum was not reached after %s (%d/%d reachable managers, %d needed)", timeout, reachable, nbManagers, quorum)
 }
@@ -0,0 +1,72 @@
+package swarm
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountReachableManagersEmpty(t *testing.T) {
+	assert.Equal(t, 0, countReachableManagers(""))
+}
+
+func TestCountReachableManagersMixed(t *testing.T) {
+	assert.Equal(t, 2, countReachableManagers("reachable\nunreachable\nreachable\n"))
+}
+
+func TestAdvertiseAddrFlag(t *testing.T) {
+	assert.Equal(t, "", advertiseAddrFlag(""))
+	assert.Equal(t, " --advertise-addr ib0", advertiseAddrFlag("ib0"))
+}
+
+func TestSwarmModeValidateAdvertiseAddr(t *testing.T) {
+	assert.NoError(t, (&SwarmModeGlobalConfig{}).Validate())
+	assert.NoError(t, (&SwarmModeGlobalConfig{AdvertiseAddr: "172.18.20.5"}).Validate())
+	assert.Error(t, (&SwarmModeGlobalConfig{AdvertiseAddr: "ib0"}).Validate())
+}
+
+func TestIsAddressAssigned(t *testing.T) {
+	out := "1: lo    inet 127.0.0.1/8 scope host lo\n" +
+		"2: eth0    inet 172.16.20.5/20 brd 172.16.31.255 scope global eth0\n" +
+		"3: ib0    inet 172.18.20.5/20 brd 172.18.31.255 scope global ib0\n"
+
+	assert.True(t, isAddressAssigned("172.18.20.5", out))
+	assert.False(t, isAddressAssigned("172.18.20.6", out))
+}
+
+func TestWaitForInitSuccess(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{InitWaitTimeout: time.Second}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		gc.ManagerToken = "manager"
+		gc.WorkerToken = "worker"
+		gc.setInitResult(nil)
+	}()
+
+	assert.NoError(t, gc.waitForInit())
+	assert.True(t, gc.IsSwarmModeClusterInitialized())
+}
+
+func TestWaitForInitFailure(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{InitWaitTimeout: time.Second}
+	gc.setInitResult(fmt.Errorf("init error"))
+
+	assert.Error(t, gc.waitForInit())
+}
+
+func TestWaitForInitTimeout(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{InitWaitTimeout: 10 * time.Millisecond}
+
+	assert.Error(t, gc.waitForInit())
+}
+
+func TestSetInitResultTwice(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{}
+	gc.setInitResult(nil)
+	gc.setInitResult(fmt.Errorf("init error"))
+
+	assert.NoError(t, gc.waitForInit())
+}
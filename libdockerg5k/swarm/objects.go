@@ -0,0 +1,107 @@
+package swarm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// kinds of the Swarm mode objects holding data for the services
+	objectSecret = "secret"
+	objectConfig = "config"
+
+	// maximum length of the name and size of the data of a secret or config allowed by the Docker Engine
+	maxObjectNameLength = 64
+	maxObjectDataSize   = 500 * 1024
+
+	// redactedData replace the secret data in the error messages
+	redactedData = "<redacted>"
+)
+
+var regexObjectName = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9_.-]*[a-zA-Z0-9])?$`)
+
+// validateObject check the name and data of a secret or config
+func validateObject(kind string, name string, data []byte) error {
+	if len(name) > maxObjectNameLength || !regexObjectName.MatchString(name) {
+		return fmt.Errorf("Invalid %s name: '%s'", kind, name)
+	}
+
+	if len(data) == 0 {
+		return fmt.Errorf("The data of the %s '%s' is empty", kind, name)
+	}
+
+	if len(data) > maxObjectDataSize {
+		return fmt.Errorf("The data of the %s '%s' exceeds the maximum size of %d bytes", kind, name, maxObjectDataSize)
+	}
+
+	return nil
+}
+
+// generateInspectCommand returns the command printing the ID of the secret or config (nothing if it does not exist)
+func generateInspectCommand(kind string, name string) string {
+	return fmt.Sprintf("docker %s inspect --format '{{.ID}}' %s 2>/dev/null || true", kind, name)
+}
+
+// generateCreateCommand returns the command creating the secret or config from its base64 encoded data (passed on the standard input of the Docker client)
+func generateCreateCommand(kind string, name string, encoded string) string {
+	return fmt.Sprintf("echo '%s' | base64 -d | docker %s create --label %s %s -", encoded, kind, container.ManagedLabel, name)
+}
+
+// redact returns the message with the encoded data replaced
+func redact(msg string, encoded string) string {
+	return strings.Replace(msg, encoded, redactedData, -1)
+}
+
+// runCreateCommand run the create command without the libmachine debug log of the SSH commands (containing the data), the data is redacted from the returned error
+func runCreateCommand(h *host.Host, command string, encoded string) (string, error) {
+	client, err := h.CreateSSHClient()
+	if err != nil {
+		return "", fmt.Errorf("Unable to create the SSH client: '%s'", err)
+	}
+
+	out, err := client.Output(command)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", redact(err.Error(), encoded), redact(strings.TrimSpace(out), encoded))
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+// createObject create the secret or config if it does not already exist (the host needs to be a manager) and returns its ID
+// the Swarm mode objects are immutable, an existing object is kept as is (new data needs a new name)
+func createObject(h *host.Host, kind string, name string, data []byte) (string, error) {
+	if err := validateObject(kind, name, data); err != nil {
+		return "", err
+	}
+
+	out, err := h.RunSSHCommand(generateInspectCommand(kind, name))
+	if err != nil {
+		return "", fmt.Errorf("Failed to inspect the %s '%s': '%s'", kind, name, err)
+	}
+	if id := strings.TrimSpace(out); id != "" {
+		return id, nil
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	id, err := runCreateCommand(h, generateCreateCommand(kind, name, encoded), encoded)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create the %s '%s': '%s'", kind, name, err)
+	}
+
+	return id, nil
+}
+
+// CreateSecret create the secret on the Swarm mode cluster if it does not already exist (the host needs to be a manager) and returns its ID, the data is never logged
+func (gc *SwarmModeGlobalConfig) CreateSecret(h *host.Host, name string, data []byte) (string, error) {
+	return createObject(h, objectSecret, name, data)
+}
+
+// CreateConfig create the config on the Swarm mode cluster if it does not already exist (the host needs to be a manager) and returns its ID
+func (gc *SwarmModeGlobalConfig) CreateConfig(h *host.Host, name string, data []byte) (string, error) {
+	return createObject(h, objectConfig, name, data)
+}
@@ -0,0 +1,32 @@
+package swarm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateObject(t *testing.T) {
+	assert.NoError(t, validateObject(objectSecret, "db-password", []byte("secret")))
+	assert.NoError(t, validateObject(objectConfig, "nginx.conf", []byte("server {}")))
+}
+
+func TestValidateObjectIncorrect(t *testing.T) {
+	assert.Error(t, validateObject(objectSecret, "", []byte("secret")))
+	assert.Error(t, validateObject(objectSecret, "-password", []byte("secret")))
+	assert.Error(t, validateObject(objectSecret, "db password", []byte("secret")))
+	assert.Error(t, validateObject(objectSecret, strings.Repeat("a", 65), []byte("secret")))
+	assert.Error(t, validateObject(objectSecret, "db-password", nil))
+	assert.Error(t, validateObject(objectConfig, "nginx.conf", make([]byte, maxObjectDataSize+1)))
+}
+
+func TestGenerateObjectCommands(t *testing.T) {
+	assert.Equal(t, "docker secret inspect --format '{{.ID}}' db-password 2>/dev/null || true", generateInspectCommand(objectSecret, "db-password"))
+	assert.Equal(t, "echo 'c2VjcmV0' | base64 -d | docker config create --label managed-by=docker-g5k app.conf -", generateCreateCommand(objectConfig, "app.conf", "c2VjcmV0"))
+}
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "Process exited with status 1: echo '<redacted>' | base64 -d", redact("Process exited with status 1: echo 'c2VjcmV0' | base64 -d", "c2VjcmV0"))
+	assert.Equal(t, "Error response from daemon: rpc error", redact("Error response from daemon: rpc error", "c2VjcmV0"))
+}
@@ -0,0 +1,92 @@
+package swarm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// minimum values of the orchestration options accepted by the Swarm managers
+	minDispatcherHeartbeat = 1 * time.Second
+	minNodeCertExpiry      = 1 * time.Hour
+)
+
+// OrchestrationOpts contain the orchestration options of the Swarm mode cluster (Docker default is used for the unset options)
+type OrchestrationOpts struct {
+	TaskHistoryLimit    int           // number of terminated tasks kept by service slot (Docker default 5 if not set)
+	DispatcherHeartbeat time.Duration // period of the nodes heartbeat to the managers (Docker default 5s if not set)
+	NodeCertExpiry      time.Duration // validity of the nodes certificates (Docker default 90 days if not set)
+}
+
+// IsSet returns true if an orchestration option is set, false otherwise
+func (o *OrchestrationOpts) IsSet() bool {
+	return o.TaskHistoryLimit != 0 || o.DispatcherHeartbeat != 0 || o.NodeCertExpiry != 0
+}
+
+// Validate check the orchestration options
+func (o *OrchestrationOpts) Validate() error {
+	if o.TaskHistoryLimit < 0 {
+		return fmt.Errorf("The Swarm mode task history limit can't be negative: '%d'", o.TaskHistoryLimit)
+	}
+
+	if o.DispatcherHeartbeat != 0 && o.DispatcherHeartbeat < minDispatcherHeartbeat {
+		return fmt.Errorf("The Swarm mode dispatcher heartbeat period must be at least %s: '%s'", minDispatcherHeartbeat, o.DispatcherHeartbeat)
+	}
+
+	if o.NodeCertExpiry != 0 && o.NodeCertExpiry < minNodeCertExpiry {
+		return fmt.Errorf("The Swarm mode nodes certificate expiry must be at least %s: '%s'", minNodeCertExpiry, o.NodeCertExpiry)
+	}
+
+	return nil
+}
+
+// flags returns the orchestration flags for the Swarm init/update commands (empty if no option is set)
+func (o *OrchestrationOpts) flags() string {
+	flags := ""
+	if o.TaskHistoryLimit != 0 {
+		flags += fmt.Sprintf(" --task-history-limit %d", o.TaskHistoryLimit)
+	}
+	if o.DispatcherHeartbeat != 0 {
+		flags += fmt.Sprintf(" --dispatcher-heartbeat %s", o.DispatcherHeartbeat)
+	}
+	if o.NodeCertExpiry != 0 {
+		flags += fmt.Sprintf(" --cert-expiry %s", o.NodeCertExpiry)
+	}
+
+	return flags
+}
+
+// UpdateSwarmConfig apply the orchestration options to the live Swarm mode cluster (the host needs to be a manager), the unset options are not modified
+func (gc *SwarmModeGlobalConfig) UpdateSwarmConfig(h *host.Host, opts OrchestrationOpts) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	if !opts.IsSet() {
+		return fmt.Errorf("No Swarm mode orchestration option to update")
+	}
+
+	if _, err := h.RunSSHCommand(fmt.Sprintf("docker swarm update%s", opts.flags())); err != nil {
+		return fmt.Errorf("Failed to update the Swarm mode cluster configuration: '%s'", err)
+	}
+
+	// keep the applied options
+	if opts.TaskHistoryLimit != 0 {
+		gc.OrchestrationOpts.TaskHistoryLimit = opts.TaskHistoryLimit
+	}
+	if opts.DispatcherHeartbeat != 0 {
+		gc.OrchestrationOpts.DispatcherHeartbeat = opts.DispatcherHeartbeat
+	}
+	if opts.NodeCertExpiry != 0 {
+		gc.OrchestrationOpts.NodeCertExpiry = opts.NodeCertExpiry
+	}
+
+	return nil
+}
+
+// String returns the set orchestration options (same format as the Swarm init/update flags)
+func (o OrchestrationOpts) String() string {
+	return o.flags()
+}
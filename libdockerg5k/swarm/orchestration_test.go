@@ -0,0 +1,29 @@
+package swarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrchestrationOptsValidate(t *testing.T) {
+	assert.NoError(t, (&OrchestrationOpts{}).Validate())
+	assert.NoError(t, (&OrchestrationOpts{TaskHistoryLimit: 1, DispatcherHeartbeat: 2 * time.Second, NodeCertExpiry: 24 * time.Hour}).Validate())
+}
+
+func TestOrchestrationOptsValidateIncorrect(t *testing.T) {
+	assert.Error(t, (&OrchestrationOpts{TaskHistoryLimit: -1}).Validate())
+	assert.Error(t, (&OrchestrationOpts{DispatcherHeartbeat: 500 * time.Millisecond}).Validate())
+	assert.Error(t, (&OrchestrationOpts{NodeCertExpiry: 30 * time.Minute}).Validate())
+}
+
+func TestOrchestrationOptsFlags(t *testing.T) {
+	assert.False(t, (&OrchestrationOpts{}).IsSet())
+	assert.Equal(t, "", (&OrchestrationOpts{}).flags())
+
+	o := &OrchestrationOpts{TaskHistoryLimit: 10, DispatcherHeartbeat: 2 * time.Second, NodeCertExpiry: 48 * time.Hour}
+	assert.True(t, o.IsSet())
+	assert.Equal(t, " --task-history-limit 10 --dispatcher-heartbeat 2s --cert-expiry 48h0m0s", o.flags())
+	assert.Equal(t, " --dispatcher-heartbeat 2s", (&OrchestrationOpts{DispatcherHeartbeat: 2 * time.Second}).flags())
+}
@@ -0,0 +1,64 @@
+package swarm
+
+import (
+	"fmt"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// range of the VXLAN data path port allowed by the Docker Engine
+	minDataPathPort = 1024
+	maxDataPathPort = 49151
+)
+
+// OverlayDefaults contain the default options of the overlay networks of the Swarm mode cluster
+type OverlayDefaults struct {
+	Encrypted bool // encrypt the application data on the overlay networks
+	VXLANPort int  // UDP port of the VXLAN data path (Docker default 4789 if not set)
+}
+
+// Validate check the overlay networks default options
+func (o *OverlayDefaults) Validate() error {
+	if o.VXLANPort == 0 {
+		return nil
+	}
+
+	if o.VXLANPort < minDataPathPort || o.VXLANPort > maxDataPathPort {
+		return fmt.Errorf("The VXLAN data path port must be between %d and %d: '%d'", minDataPathPort, maxDataPathPort, o.VXLANPort)
+	}
+
+	// Swarm mode cluster management and node communication ports
+	if o.VXLANPort == 2377 || o.VXLANPort == 7946 {
+		return fmt.Errorf("The VXLAN data path port conflicts with the Swarm mode ports (2377/tcp and 7946): '%d'", o.VXLANPort)
+	}
+
+	return nil
+}
+
+// dataPathPortFlag returns the 'data-path-port' flag for the Swarm init command (empty if the default port is used)
+func (o *OverlayDefaults) dataPathPortFlag() string {
+	if o.VXLANPort == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" --data-path-port %d", o.VXLANPort)
+}
+
+// networkCreateOptions returns the options for the overlay network create command (empty if no option is set)
+func (o *OverlayDefaults) networkCreateOptions() string {
+	if !o.Encrypted {
+		return ""
+	}
+
+	return " --opt encrypted"
+}
+
+// CreateOverlayNetwork create an attachable overlay network using the default options of the cluster (the host needs to be a manager)
+func (gc *SwarmModeGlobalConfig) CreateOverlayNetwork(h *host.Host, name string) error {
+	if _, err := h.RunSSHCommand(fmt.Sprintf("docker network create --driver overlay --attachable%s %s", gc.OverlayDefaults.networkCreateOptions(), name)); err != nil {
+		return fmt.Errorf("Failed to create the overlay network '%s': '%s'", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlayDefaultsValidate(t *testing.T) {
+	assert.NoError(t, (&OverlayDefaults{}).Validate())
+	assert.NoError(t, (&OverlayDefaults{Encrypted: true, VXLANPort: 4790}).Validate())
+}
+
+func TestOverlayDefaultsValidateIncorrect(t *testing.T) {
+	assert.Error(t, (&OverlayDefaults{VXLANPort: 443}).Validate())
+	assert.Error(t, (&OverlayDefaults{VXLANPort: 50000}).Validate())
+	assert.Error(t, (&OverlayDefaults{VXLANPort: 7946}).Validate())
+}
+
+func TestOverlayDefaultsFlags(t *testing.T) {
+	assert.Equal(t, "", (&OverlayDefaults{}).dataPathPortFlag())
+	assert.Equal(t, "", (&OverlayDefaults{}).networkCreateOptions())
+
+	o := &OverlayDefaults{Encrypted: true, VXLANPort: 4790}
+	assert.Equal(t, " --data-path-port 4790", o.dataPathPortFlag())
+	assert.Equal(t, " --opt encrypted", o.networkCreateOptions())
+}
@@ -0,0 +1,62 @@
+package swarm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// regexPlacementPref match a spread placement preference (format: spread=node.id, spread=node.labels.{key} or spread=engine.labels.{key})
+	regexPlacementPref = regexp.MustCompile(`^spread=(node\.id|(node|engine)\.labels\.[a-zA-Z0-9][a-zA-Z0-9_.-]*)$`)
+
+	// defaultPlacementPreferences are the placement preferences used if none is configured (a replica per node if possible)
+	defaultPlacementPreferences = []string{"spread=node.id"}
+)
+
+// validatePlacementPreferences check the format of the default placement preferences
+func (gc *SwarmModeGlobalConfig) validatePlacementPreferences() error {
+	for _, p := range gc.DefaultPlacementPreferences {
+		if !regexPlacementPref.MatchString(p) {
+			return fmt.Errorf("Invalid placement preference: '%s' (format: spread=node.id, spread=node.labels.key or spread=engine.labels.key)", p)
+		}
+	}
+
+	return nil
+}
+
+// PlacementPreferences returns the placement preferences of the replicated services created on the cluster (default if not set)
+func (gc *SwarmModeGlobalConfig) PlacementPreferences() []string {
+	if len(gc.DefaultPlacementPreferences) > 0 {
+		return gc.DefaultPlacementPreferences
+	}
+
+	return defaultPlacementPreferences
+}
+
+// PlacementPrefFlags returns the 'docker service create' flags of the placement preferences (ex: to create the user services with the same placement)
+func (gc *SwarmModeGlobalConfig) PlacementPrefFlags() string {
+	flags := ""
+	for _, p := range gc.PlacementPreferences() {
+		flags = fmt.Sprintf("%s --placement-pref %s", flags, p)
+	}
+
+	return strings.TrimSpace(flags)
+}
+
+// PlacementLabels returns the keys of the Swarm node labels and of the Engine labels referenced by the default placement preferences
+func (gc *SwarmModeGlobalConfig) PlacementLabels() ([]string, []string) {
+	nodeLabels := []string{}
+	engineLabels := []string{}
+	for _, p := range gc.DefaultPlacementPreferences {
+		descriptor := strings.TrimPrefix(p, "spread=")
+		switch {
+		case strings.HasPrefix(descriptor, "node.labels."):
+			nodeLabels = append(nodeLabels, strings.TrimPrefix(descriptor, "node.labels."))
+		case strings.HasPrefix(descriptor, "engine.labels."):
+			engineLabels = append(engineLabels, strings.TrimPrefix(descriptor, "engine.labels."))
+		}
+	}
+
+	return nodeLabels, engineLabels
+}
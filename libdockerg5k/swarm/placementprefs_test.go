@@ -0,0 +1,33 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePlacementPreferences(t *testing.T) {
+	assert.NoError(t, (&SwarmModeGlobalConfig{}).validatePlacementPreferences())
+	assert.NoError(t, (&SwarmModeGlobalConfig{DefaultPlacementPreferences: []string{"spread=node.labels.nic", "spread=engine.labels.g5k.site", "spread=node.id"}}).validatePlacementPreferences())
+
+	assert.Error(t, (&SwarmModeGlobalConfig{DefaultPlacementPreferences: []string{"node.labels.nic"}}).validatePlacementPreferences())
+	assert.Error(t, (&SwarmModeGlobalConfig{DefaultPlacementPreferences: []string{"pack=node.labels.nic"}}).validatePlacementPreferences())
+	assert.Error(t, (&SwarmModeGlobalConfig{DefaultPlacementPreferences: []string{"spread=node.labels."}}).validatePlacementPreferences())
+	assert.Error(t, (&SwarmModeGlobalConfig{DefaultPlacementPreferences: []string{"spread=node.labels.nic; reboot"}}).validatePlacementPreferences())
+}
+
+func TestPlacementPrefFlags(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{}
+	assert.Equal(t, "--placement-pref spread=node.id", gc.PlacementPrefFlags())
+
+	gc.DefaultPlacementPreferences = []string{"spread=engine.labels.g5k.site", "spread=node.labels.nic"}
+	assert.Equal(t, "--placement-pref spread=engine.labels.g5k.site --placement-pref spread=node.labels.nic", gc.PlacementPrefFlags())
+}
+
+func TestPlacementLabels(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{DefaultPlacementPreferences: []string{"spread=engine.labels.g5k.site", "spread=node.labels.nic", "spread=node.id"}}
+
+	nodeLabels, engineLabels := gc.PlacementLabels()
+	assert.Equal(t, []string{"nic"}, nodeLabels)
+	assert.Equal(t, []string{"g5k.site"}, engineLabels)
+}
@@ -0,0 +1,178 @@
+package swarm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// smokeTestName is the name of the smoke test service and overlay network
+	smokeTestName = "docker-g5k-smoketest"
+
+	// defaultSmokeTestImage is the default image of the smoke test service (needs to serve HTTP on port 80 and provide wget)
+	defaultSmokeTestImage = "nginx:alpine"
+
+	// defaultSmokeTestReplicas is the default number of replicas of the smoke test service
+	defaultSmokeTestReplicas = 3
+
+	// smokeTestTimeout is the maximum time to wait for the smoke test service replicas to be running
+	smokeTestTimeout = 5 * time.Minute
+)
+
+// TaskPlacement contain the placement and reachability of a smoke test service task
+type TaskPlacement struct {
+	TaskID    string `json:"task_id"`
+	Node      string `json:"node"`
+	Address   string `json:"address"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SmokeTestResult contain the result of the smoke test
+type SmokeTestResult struct {
+	Placements []TaskPlacement `json:"placements"`
+}
+
+// smokeTestImage returns the image of the smoke test service
+func (gc *SwarmModeGlobalConfig) smokeTestImage() string {
+	if gc.SmokeTestImage != "" {
+		return gc.SmokeTestImage
+	}
+
+	return defaultSmokeTestImage
+}
+
+// smokeTestReplicas returns the number of replicas of the smoke test service
+func (gc *SwarmModeGlobalConfig) smokeTestReplicas() int {
+	if gc.SmokeTestReplicas > 0 {
+		return gc.SmokeTestReplicas
+	}
+
+	return defaultSmokeTestReplicas
+}
+
+// parseServiceTasks returns the running tasks (task ID => node) from the 'docker service ps' output (format: {id} {node} {current state}), and if all replicas are running
+func parseServiceTasks(out string, replicas int) (map[string]string, bool) {
+	tasks := make(map[string]string)
+	running := 0
+
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+
+		if fields[2] == "Running" {
+			tasks[fields[0]] = fields[1]
+			running++
+		}
+	}
+
+	return tasks, running == replicas
+}
+
+// parseTaskAddress returns the IP address of the task on the overlay network (without the prefix length)
+func parseTaskAddress(out string) string {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return strings.SplitN(fields[0], "/", 2)[0]
+}
+
+// waitForServiceTasks wait until all replicas of the smoke test service are running and returns the tasks (task ID => node)
+func (gc *SwarmModeGlobalConfig) waitForServiceTasks(h *host.Host) (map[string]string, error) {
+	cmd := fmt.Sprintf("docker service ps --no-trunc --filter desired-state=running --format '{{.ID}} {{.Node}} {{.CurrentState}}' %s", smokeTestName)
+
+	for deadline := time.Now().Add(smokeTestTimeout); time.Now().Before(deadline); time.Sleep(5 * time.Second) {
+		out, err := h.RunSSHCommand(cmd)
+		if err != nil {
+			continue
+		}
+
+		if tasks, ok := parseServiceTasks(out, gc.smokeTestReplicas()); ok {
+			return tasks, nil
+		}
+	}
+
+	return nil, fmt.Errorf("The smoke test service replicas were not running after %s", smokeTestTimeout)
+}
+
+// checkTask returns the placement of the task and check it is reachable on the overlay network
+func (gc *SwarmModeGlobalConfig) checkTask(h *host.Host, taskID string, node string) TaskPlacement {
+	p := TaskPlacement{TaskID: taskID, Node: node}
+
+	// get task address on the overlay network
+	out, err := h.RunSSHCommand(fmt.Sprintf("docker inspect --format '{{range .NetworksAttachments}}{{range .Addresses}}{{.}} {{end}}{{end}}' %s", taskID))
+	if err != nil {
+		p.Error = fmt.Sprintf("Failed to get task address: '%s'", err)
+		return p
+	}
+
+	if p.Address = parseTaskAddress(out); p.Address == "" {
+		p.Error = "The task has no address on the overlay network"
+		return p
+	}
+
+	// request the task from a container attached to the overlay network
+	if _, err := h.RunSSHCommand(fmt.Sprintf("docker run --rm %s --network %s %s wget -q -T 5 -O /dev/null http://%s", container.LabelFlag, smokeTestName, gc.smokeTestImage(), p.Address)); err != nil {
+		p.Error = fmt.Sprintf("The task is not reachable on the overlay network: '%s'", err)
+		return p
+	}
+
+	p.Reachable = true
+	return p
+}
+
+// SmokeTest deploy a replicated service on the cluster, check all replicas are running and reachable on an overlay network, then remove it (the host needs to be a manager)
+func (gc *SwarmModeGlobalConfig) SmokeTest(h *host.Host) (*SmokeTestResult, error) {
+	// create overlay network
+	if err := gc.CreateOverlayNetwork(h, smokeTestName); err != nil {
+		return nil, err
+	}
+	defer h.RunSSHCommand(fmt.Sprintf("docker network rm %s", smokeTestName))
+
+	// create service (spread the replicas using the placement preferences)
+	if _, err := h.RunSSHCommand(fmt.Sprintf("docker service create --detach --name %[1]s --container-label %[4]s --network %[1]s --replicas %[2]d %[5]s %[3]s", smokeTestName, gc.smokeTestReplicas(), gc.smokeTestImage(), container.ManagedLabel, gc.PlacementPrefFlags())); err != nil {
+		return nil, fmt.Errorf("Failed to create the smoke test service: '%s'", err)
+	}
+	defer h.RunSSHCommand(fmt.Sprintf("docker service rm %s", smokeTestName))
+
+	// wait for the replicas to be running
+	tasks, err := gc.waitForServiceTasks(h)
+	if err != nil {
+		return nil, err
+	}
+
+	// check the tasks are reachable
+	result := &SmokeTestResult{}
+	failed := 0
+	for taskID, node := range tasks {
+		p := gc.checkTask(h, taskID, node)
+		if !p.Reachable {
+			failed++
+		}
+
+		result.Placements = append(result.Placements, p)
+	}
+
+	// sort placements by node for a stable output
+	sort.Slice(result.Placements, func(i, j int) bool {
+		if result.Placements[i].Node != result.Placements[j].Node {
+			return result.Placements[i].Node < result.Placements[j].Node
+		}
+		return result.Placements[i].TaskID < result.Placements[j].TaskID
+	})
+
+	if failed > 0 {
+		return result, fmt.Errorf("The smoke test failed: %d/%d task(s) not reachable on the overlay network", failed, len(tasks))
+	}
+
+	return result, nil
+}
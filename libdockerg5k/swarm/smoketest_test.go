@@ -0,0 +1,34 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseServiceTasksAllRunning(t *testing.T) {
+	tasks, ok := parseServiceTasks("a1 lille-0 Running 2 seconds ago\nb2 lille-1 Running 3 seconds ago\n", 2)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"a1": "lille-0", "b2": "lille-1"}, tasks)
+}
+
+func TestParseServiceTasksPending(t *testing.T) {
+	tasks, ok := parseServiceTasks("a1 lille-0 Running 2 seconds ago\nb2 lille-1 Preparing 3 seconds ago\n", 2)
+	assert.False(t, ok)
+	assert.Equal(t, map[string]string{"a1": "lille-0"}, tasks)
+}
+
+func TestParseTaskAddress(t *testing.T) {
+	assert.Equal(t, "10.0.0.3", parseTaskAddress("10.0.0.3/24 \n"))
+	assert.Equal(t, "", parseTaskAddress("\n"))
+}
+
+func TestSmokeTestDefaults(t *testing.T) {
+	gc := &SwarmModeGlobalConfig{}
+	assert.Equal(t, defaultSmokeTestImage, gc.smokeTestImage())
+	assert.Equal(t, defaultSmokeTestReplicas, gc.smokeTestReplicas())
+
+	gc = &SwarmModeGlobalConfig{SmokeTestImage: "httpd:alpine", SmokeTestReplicas: 8}
+	assert.Equal(t, "httpd:alpine", gc.smokeTestImage())
+	assert.Equal(t, 8, gc.smokeTestReplicas())
+}
@@ -1,16 +1,159 @@
 package swarm
 
 import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
 	"github.com/docker/machine/libmachine/swarm"
 )
 
+// DiscoveryBackend is a Swarm standalone discovery backend
+type DiscoveryBackend string
+
+const (
+	// DiscoveryBackendToken use the Docker Hub hosted discovery service (token://<token>)
+	DiscoveryBackendToken DiscoveryBackend = "token"
+	// DiscoveryBackendZookeeper use a Zookeeper k/v store (zk://<ip1>,<ip2>/<path>), deployed on the master nodes if no address is given
+	DiscoveryBackendZookeeper DiscoveryBackend = "zk"
+	// DiscoveryBackendConsul use a Consul k/v store (consul://<ip>:<port>/<path>)
+	DiscoveryBackendConsul DiscoveryBackend = "consul"
+	// DiscoveryBackendEtcd use an Etcd k/v store (etcd://<ip1>,<ip2>/<path>)
+	DiscoveryBackendEtcd DiscoveryBackend = "etcd"
+	// DiscoveryBackendNodes use a static list of nodes (nodes://<ip1>:<port>,<ip2>:<port>), generated from the cluster nodes if no address is given
+	DiscoveryBackendNodes DiscoveryBackend = "nodes"
+)
+
+var (
+	// discoveryBackends contain the supported discovery backends
+	discoveryBackends = []DiscoveryBackend{DiscoveryBackendToken, DiscoveryBackendZookeeper, DiscoveryBackendConsul, DiscoveryBackendEtcd, DiscoveryBackendNodes}
+
+	// externalDiscoveryBackends are the k/v store backends that can be used as external discovery (and Engines cluster store)
+	externalDiscoveryBackends = []DiscoveryBackend{DiscoveryBackendZookeeper, DiscoveryBackendConsul, DiscoveryBackendEtcd}
+)
+
+// ParseDiscoveryBackend returns the discovery backend matching the given name
+func ParseDiscoveryBackend(name string) (DiscoveryBackend, error) {
+	for _, b := range discoveryBackends {
+		if string(b) == name {
+			return b, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unsupported Swarm discovery backend: '%s'", name)
+}
+
 // SwarmStandaloneGlobalConfig contain Swarm standalone global configuration
 type SwarmStandaloneGlobalConfig struct {
-	Image       string
-	Discovery   string
-	Strategy    string
-	MasterFlags []string
-	JoinFlags   []string
+	Image            string
+	DiscoveryBackend DiscoveryBackend
+	Discovery        string
+	Strategy         string
+	MasterFlags      []string
+	JoinFlags        []string
+
+	// external k/v store used as discovery and Engines cluster store, never deployed by docker-g5k (ex: etcd://10.0.0.1:2379/swarm)
+	ExternalDiscoveryURL string
+}
+
+// parseExternalDiscoveryURL returns the k/v store backend of the external discovery URL (zk, consul or etcd scheme followed by the store address)
+func parseExternalDiscoveryURL(url string) (DiscoveryBackend, error) {
+	s := strings.SplitN(url, "://", 2)
+	if len(s) != 2 || s[1] == "" || strings.ContainsAny(s[1], " \t") {
+		return "", fmt.Errorf("Invalid external Swarm discovery URL: '%s'", url)
+	}
+
+	for _, b := range externalDiscoveryBackends {
+		if s[0] == string(b) {
+			return b, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unsupported external Swarm discovery URL scheme: '%s' (supported: zk, consul, etcd)", s[0])
+}
+
+// GetDiscoveryBackend returns the discovery backend to use (explicitly set, or inferred from the discovery URL scheme)
+func (gc *SwarmStandaloneGlobalConfig) GetDiscoveryBackend() (DiscoveryBackend, error) {
+	// external k/v store (the explicitly set backend must match its scheme)
+	if gc.ExternalDiscoveryURL != "" {
+		backend, err := parseExternalDiscoveryURL(gc.ExternalDiscoveryURL)
+		if err != nil {
+			return "", err
+		}
+
+		if gc.DiscoveryBackend != "" && gc.DiscoveryBackend != backend {
+			return "", fmt.Errorf("The external Swarm discovery URL '%s' does not match the '%s' discovery backend", gc.ExternalDiscoveryURL, gc.DiscoveryBackend)
+		}
+
+		return backend, nil
+	}
+
+	// explicitly set backend
+	if gc.DiscoveryBackend != "" {
+		return ParseDiscoveryBackend(string(gc.DiscoveryBackend))
+	}
+
+	// no discovery given, a Zookeeper k/v store will be deployed
+	if gc.Discovery == "" {
+		return DiscoveryBackendZookeeper, nil
+	}
+
+	// infer backend from discovery URL scheme
+	s := strings.SplitN(gc.Discovery, "://", 2)
+	if len(s) != 2 {
+		return "", fmt.Errorf("Unable to infer the Swarm discovery backend from '%s', please select a discovery backend", gc.Discovery)
+	}
+
+	return ParseDiscoveryBackend(s[0])
+}
+
+// GenerateNodesDiscoveryURL returns a static discovery URL listing the given nodes Docker Engine (format=nodes://ip1:2376,ip2:2376...)
+func GenerateNodesDiscoveryURL(nodes []string, hostsLookupTable map[string]string) string {
+	// sort nodes by name to always generate the same URL
+	nodesName := append([]string{}, nodes...)
+	sort.Strings(nodesName)
+
+	nodesAddr := []string{}
+	for _, n := range nodesName {
+		nodesAddr = append(nodesAddr, net.JoinHostPort(hostsLookupTable[n], "2376"))
+	}
+
+	return fmt.Sprintf("nodes://%s", strings.Join(nodesAddr, ","))
+}
+
+// ConfigureDiscovery generate the discovery URL for the discovery backend and check it is consistent
+func (gc *SwarmStandaloneGlobalConfig) ConfigureDiscovery() error {
+	backend, err := gc.GetDiscoveryBackend()
+	if err != nil {
+		return err
+	}
+
+	// the external k/v store is used directly as discovery
+	if gc.ExternalDiscoveryURL != "" {
+		if gc.Discovery != "" && gc.Discovery != gc.ExternalDiscoveryURL {
+			return fmt.Errorf("The Swarm discovery and the external Swarm discovery can't be both set")
+		}
+		gc.Discovery = gc.ExternalDiscoveryURL
+	}
+
+	// an address is needed for the backends not deployed by docker-g5k
+	if gc.Discovery == "" {
+		return fmt.Errorf("The Swarm discovery backend '%s' needs a discovery address", backend)
+	}
+
+	// add backend scheme if only the address is given
+	if !strings.Contains(gc.Discovery, "://") {
+		gc.Discovery = fmt.Sprintf("%s://%s", backend, gc.Discovery)
+	}
+
+	// check the discovery URL scheme matches the backend
+	if !strings.HasPrefix(gc.Discovery, fmt.Sprintf("%s://", backend)) {
+		return fmt.Errorf("The Swarm discovery URL '%s' does not match the '%s' discovery backend", gc.Discovery, backend)
+	}
+
+	gc.DiscoveryBackend = backend
+	return nil
 }
 
 // CreateNodeConfig returns a configured SwarmOptions for HostOptions struct
@@ -0,0 +1,69 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDiscoveryBackendDefault(t *testing.T) {
+	gc := SwarmStandaloneGlobalConfig{}
+	b, err := gc.GetDiscoveryBackend()
+	assert.NoError(t, err)
+	assert.Equal(t, DiscoveryBackendZookeeper, b)
+}
+
+func TestGetDiscoveryBackendInferred(t *testing.T) {
+	gc := SwarmStandaloneGlobalConfig{Discovery: "consul://10.0.0.1:8500/swarm"}
+	b, err := gc.GetDiscoveryBackend()
+	assert.NoError(t, err)
+	assert.Equal(t, DiscoveryBackendConsul, b)
+}
+
+func TestGetDiscoveryBackendUnknownScheme(t *testing.T) {
+	gc := SwarmStandaloneGlobalConfig{Discovery: "file:///tmp/cluster"}
+	_, err := gc.GetDiscoveryBackend()
+	assert.Error(t, err)
+}
+
+func TestConfigureDiscoveryAddScheme(t *testing.T) {
+	gc := SwarmStandaloneGlobalConfig{DiscoveryBackend: DiscoveryBackendEtcd, Discovery: "10.0.0.1:2379/swarm"}
+	assert.NoError(t, gc.ConfigureDiscovery())
+	assert.Equal(t, "etcd://10.0.0.1:2379/swarm", gc.Discovery)
+}
+
+func TestConfigureDiscoveryMismatchingScheme(t *testing.T) {
+	gc := SwarmStandaloneGlobalConfig{DiscoveryBackend: DiscoveryBackendEtcd, Discovery: "consul://10.0.0.1:8500"}
+	assert.Error(t, gc.ConfigureDiscovery())
+}
+
+func TestConfigureDiscoveryMissingAddress(t *testing.T) {
+	gc := SwarmStandaloneGlobalConfig{DiscoveryBackend: DiscoveryBackendToken}
+	assert.Error(t, gc.ConfigureDiscovery())
+}
+
+func TestConfigureDiscoveryExternal(t *testing.T) {
+	gc := SwarmStandaloneGlobalConfig{ExternalDiscoveryURL: "etcd://10.0.0.1:2379,10.0.0.2:2379/swarm"}
+	assert.NoError(t, gc.ConfigureDiscovery())
+	assert.Equal(t, "etcd://10.0.0.1:2379,10.0.0.2:2379/swarm", gc.Discovery)
+	assert.Equal(t, DiscoveryBackendEtcd, gc.DiscoveryBackend)
+}
+
+func TestConfigureDiscoveryExternalIncorrect(t *testing.T) {
+	for _, url := range []string{"10.0.0.1:2379", "etcd://", "token://abcd", "nodes://10.0.0.1:2376", "consul://10.0.0.1 :8500"} {
+		gc := SwarmStandaloneGlobalConfig{ExternalDiscoveryURL: url}
+		assert.Error(t, gc.ConfigureDiscovery(), url)
+	}
+
+	// mismatching backend and discovery
+	gc := SwarmStandaloneGlobalConfig{DiscoveryBackend: DiscoveryBackendConsul, ExternalDiscoveryURL: "etcd://10.0.0.1:2379"}
+	assert.Error(t, gc.ConfigureDiscovery())
+
+	gc = SwarmStandaloneGlobalConfig{Discovery: "consul://10.0.0.1:8500", ExternalDiscoveryURL: "etcd://10.0.0.1:2379"}
+	assert.Error(t, gc.ConfigureDiscovery())
+}
+
+func TestGenerateNodesDiscoveryURL(t *testing.T) {
+	hostsLookup := map[string]string{"lille-1": "10.0.0.1", "lille-0": "10.0.0.0", "alias": "10.0.0.0"}
+	assert.Equal(t, "nodes://10.0.0.0:2376,10.0.0.1:2376", GenerateNodesDiscoveryURL([]string{"lille-1", "lille-0"}, hostsLookup))
+}
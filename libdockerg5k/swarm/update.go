@@ -0,0 +1,280 @@
+package swarm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+const (
+	// defaultServiceUpdateTimeout is the default maximum time to wait for a service update to converge
+	defaultServiceUpdateTimeout = 10 * time.Minute
+
+	// serviceUpdatePollInterval is the delay between the checks of a service update
+	serviceUpdatePollInterval = 2 * time.Second
+
+	// separator of the fields of the 'docker service ps' output (the task error can contain spaces)
+	serviceTaskSeparator = "|"
+)
+
+// actions on an update failure accepted by Docker
+var serviceUpdateFailureActions = map[string]bool{"pause": true, "continue": true, "rollback": true}
+
+// final states of a service update
+var serviceUpdateFinalStates = map[string]bool{"completed": true, "paused": true, "rollback_completed": true, "rollback_paused": true}
+
+// UpdateOpts contain the options of a rolling service update (the Docker default is used for the unset options)
+type UpdateOpts struct {
+	// number of tasks updated simultaneously
+	Parallelism int
+
+	// delay between the updates of two groups of tasks
+	Delay time.Duration
+
+	// action on update failure ('pause', 'continue' or 'rollback')
+	FailureAction string
+
+	// duration after each task update to monitor for failure
+	Monitor time.Duration
+
+	// maximum time to wait for the update to converge (default if not set)
+	Timeout time.Duration
+}
+
+// UpdateEvent contain a state change of a task observed during a service update
+type UpdateEvent struct {
+	Time         time.Time `json:"time"`
+	TaskID       string    `json:"task_id"`
+	Node         string    `json:"node"`
+	Image        string    `json:"image"`
+	DesiredState string    `json:"desired_state"`
+	State        string    `json:"state"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// UpdateResult contain the final state and the observed timeline of a service update
+type UpdateResult struct {
+	Service  string        `json:"service"`
+	Image    string        `json:"image"`
+	State    string        `json:"state"`
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Events   []UpdateEvent `json:"events"`
+}
+
+// IsRolledBack returns true if the update was rolled back
+func (r *UpdateResult) IsRolledBack() bool {
+	return strings.HasPrefix(r.State, "rollback_")
+}
+
+// Validate check the service update options
+func (o *UpdateOpts) Validate() error {
+	if o.Parallelism < 0 {
+		return fmt.Errorf("The service update parallelism needs to be positive: '%d'", o.Parallelism)
+	}
+
+	if o.Delay < 0 || o.Monitor < 0 || o.Timeout < 0 {
+		return fmt.Errorf("The service update delay, monitor and timeout durations need to be positive")
+	}
+
+	if o.FailureAction != "" && !serviceUpdateFailureActions[o.FailureAction] {
+		return fmt.Errorf("Invalid service update failure action (pause, continue or rollback): '%s'", o.FailureAction)
+	}
+
+	return nil
+}
+
+// flags returns the 'docker service update' flags of the set options
+func (o *UpdateOpts) flags() string {
+	flags := ""
+	if o.Parallelism > 0 {
+		flags += fmt.Sprintf(" --update-parallelism %d", o.Parallelism)
+	}
+	if o.Delay > 0 {
+		flags += fmt.Sprintf(" --update-delay %s", o.Delay)
+	}
+	if o.FailureAction != "" {
+		flags += fmt.Sprintf(" --update-failure-action %s", o.FailureAction)
+	}
+	if o.Monitor > 0 {
+		flags += fmt.Sprintf(" --update-monitor %s", o.Monitor)
+	}
+
+	return flags
+}
+
+// timeout returns the maximum time to wait for the update to converge
+func (o *UpdateOpts) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+
+	return defaultServiceUpdateTimeout
+}
+
+// parseServiceTaskStates returns the tasks (task ID => event without time) from the 'docker service ps' output (format: {id}|{node}|{image}|{desired state}|{current state}|{error})
+func parseServiceTaskStates(out string) map[string]UpdateEvent {
+	tasks := make(map[string]UpdateEvent)
+
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), serviceTaskSeparator, 6)
+		if len(fields) < 5 || fields[0] == "" {
+			continue
+		}
+
+		// the current state is followed by its age (ex: 'Running 2 seconds ago')
+		state := ""
+		if f := strings.Fields(fields[4]); len(f) > 0 {
+			state = f[0]
+		}
+
+		e := UpdateEvent{TaskID: fields[0], Node: fields[1], Image: fields[2], DesiredState: fields[3], State: state}
+		if len(fields) == 6 {
+			e.Error = strings.TrimSpace(fields[5])
+		}
+
+		tasks[e.TaskID] = e
+	}
+
+	return tasks
+}
+
+// taskStateChanges returns the events of the tasks whose state changed since the previous observation (sorted by task ID for a stable timeline)
+func taskStateChanges(previous map[string]UpdateEvent, current map[string]UpdateEvent, now time.Time) []UpdateEvent {
+	events := []UpdateEvent{}
+	for _, id := range sortedTaskIDs(current) {
+		e := current[id]
+		if p, ok := previous[id]; ok && p.State == e.State && p.DesiredState == e.DesiredState && p.Error == e.Error {
+			continue
+		}
+
+		e.Time = now
+		events = append(events, e)
+	}
+
+	return events
+}
+
+// sortedTaskIDs returns the sorted IDs of the tasks
+func sortedTaskIDs(tasks map[string]UpdateEvent) []string {
+	ids := make([]string, 0, len(tasks))
+	for id := range tasks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// serviceTaskStates returns the current tasks of the service
+func serviceTaskStates(h *host.Host, name string) (map[string]UpdateEvent, error) {
+	format := strings.Join([]string{"{{.ID}}", "{{.Node}}", "{{.Image}}", "{{.DesiredState}}", "{{.CurrentState}}", "{{.Error}}"}, serviceTaskSeparator)
+	out, err := h.RunSSHCommand(fmt.Sprintf("docker service ps --no-trunc --format '%s' %s", format, name))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the tasks of the service '%s': '%s'", name, err)
+	}
+
+	return parseServiceTaskStates(out), nil
+}
+
+// serviceStatus contain the image and the last update status of a service
+type serviceStatus struct {
+	image         string
+	updateState   string
+	updateStarted string
+}
+
+// parseServiceStatus returns the service status from the 'docker service inspect' output (format: {image} {update state} {update start}, without the update fields if the service was never updated)
+func parseServiceStatus(out string) serviceStatus {
+	fields := strings.Fields(out)
+
+	s := serviceStatus{}
+	if len(fields) > 0 {
+		// the image is pinned to its digest by the manager
+		s.image = strings.SplitN(fields[0], "@", 2)[0]
+	}
+	if len(fields) > 1 {
+		s.updateState = fields[1]
+	}
+	if len(fields) > 2 {
+		s.updateStarted = strings.Join(fields[2:], " ")
+	}
+
+	return s
+}
+
+// inspectService returns the image and the last update status of the service
+func inspectService(h *host.Host, name string) (serviceStatus, error) {
+	out, err := h.RunSSHCommand(fmt.Sprintf("docker service inspect --format '{{.Spec.TaskTemplate.ContainerSpec.Image}}{{if .UpdateStatus}} {{.UpdateStatus.State}} {{.UpdateStatus.StartedAt}}{{end}}' %s", name))
+	if err != nil {
+		return serviceStatus{}, fmt.Errorf("Failed to inspect the service '%s': '%s'", name, err)
+	}
+
+	return parseServiceStatus(out), nil
+}
+
+// UpdateService run a rolling update of the service image and wait until it converges or is rolled back, returning the observed tasks timeline (the host needs to be a manager)
+func (gc *SwarmModeGlobalConfig) UpdateService(h *host.Host, name string, image string, opts UpdateOpts) (*UpdateResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	if name == "" || image == "" {
+		return nil, fmt.Errorf("The service name and image are required")
+	}
+
+	// the status of the previous update is kept until the new one starts
+	initial, err := inspectService(h, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if initial.image == image {
+		return nil, fmt.Errorf("The service '%s' already runs the image '%s'", name, image)
+	}
+
+	// initial state of the tasks
+	tasks, err := serviceTaskStates(h, name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UpdateResult{Service: name, Image: image, Started: time.Now()}
+	if _, err := h.RunSSHCommand(fmt.Sprintf("docker service update --detach%s --image %s %s", opts.flags(), image, name)); err != nil {
+		return nil, fmt.Errorf("Failed to update the service '%s': '%s'", name, err)
+	}
+
+	for deadline := time.Now().Add(opts.timeout()); ; time.Sleep(serviceUpdatePollInterval) {
+		// the tasks update is observed on a best effort basis (a failed check is retried at the next poll)
+		if current, err := serviceTaskStates(h, name); err == nil {
+			result.Events = append(result.Events, taskStateChanges(tasks, current, time.Now())...)
+			tasks = current
+		}
+
+		if status, err := inspectService(h, name); err == nil && status.updateStarted != initial.updateStarted {
+			result.State = status.updateState
+			if serviceUpdateFinalStates[status.updateState] {
+				break
+			}
+		}
+
+		if time.Now().After(deadline) {
+			result.Finished = time.Now()
+			return result, fmt.Errorf("The update of the service '%s' did not converge after %s (state: '%s')", name, opts.timeout(), result.State)
+		}
+	}
+
+	result.Finished = time.Now()
+
+	switch {
+	case result.IsRolledBack():
+		return result, fmt.Errorf("The update of the service '%s' was rolled back (state: '%s')", name, result.State)
+	case result.State == "paused":
+		return result, fmt.Errorf("The update of the service '%s' was paused after a task failure", name)
+	}
+
+	return result, nil
+}
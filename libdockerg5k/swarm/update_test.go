@@ -0,0 +1,60 @@
+package swarm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateOptsValidate(t *testing.T) {
+	assert.NoError(t, (&UpdateOpts{}).Validate())
+	assert.NoError(t, (&UpdateOpts{Parallelism: 2, Delay: 10 * time.Second, FailureAction: "rollback"}).Validate())
+
+	assert.Error(t, (&UpdateOpts{Parallelism: -1}).Validate())
+	assert.Error(t, (&UpdateOpts{Delay: -time.Second}).Validate())
+	assert.Error(t, (&UpdateOpts{FailureAction: "abort"}).Validate())
+}
+
+func TestUpdateOptsFlags(t *testing.T) {
+	assert.Equal(t, "", (&UpdateOpts{}).flags())
+
+	opts := &UpdateOpts{Parallelism: 2, Delay: 10 * time.Second, FailureAction: "rollback", Monitor: 30 * time.Second}
+	assert.Equal(t, " --update-parallelism 2 --update-delay 10s --update-failure-action rollback --update-monitor 30s", opts.flags())
+
+	assert.Equal(t, defaultServiceUpdateTimeout, opts.timeout())
+	assert.Equal(t, time.Minute, (&UpdateOpts{Timeout: time.Minute}).timeout())
+}
+
+func TestParseServiceTaskStates(t *testing.T) {
+	out := "a1|lille-0|nginx:1.19|Running|Running 2 minutes ago|\n" +
+		"b2|lille-1|nginx:1.20|Shutdown|Failed 5 seconds ago|task: non-zero exit (1)\n" +
+		"\n"
+
+	assert.Equal(t, map[string]UpdateEvent{
+		"a1": {TaskID: "a1", Node: "lille-0", Image: "nginx:1.19", DesiredState: "Running", State: "Running"},
+		"b2": {TaskID: "b2", Node: "lille-1", Image: "nginx:1.20", DesiredState: "Shutdown", State: "Failed", Error: "task: non-zero exit (1)"},
+	}, parseServiceTaskStates(out))
+}
+
+func TestTaskStateChanges(t *testing.T) {
+	now := time.Now()
+	previous := parseServiceTaskStates("a1|lille-0|nginx:1.19|Running|Running 2 minutes ago|\nb2|lille-1|nginx:1.19|Running|Running 2 minutes ago|\n")
+	current := parseServiceTaskStates("a1|lille-0|nginx:1.19|Running|Running 3 minutes ago|\nb2|lille-1|nginx:1.19|Shutdown|Shutdown 1 second ago|\nc3|lille-1|nginx:1.20|Running|Preparing 1 second ago|\n")
+
+	events := taskStateChanges(previous, current, now)
+	assert.Equal(t, []UpdateEvent{
+		{Time: now, TaskID: "b2", Node: "lille-1", Image: "nginx:1.19", DesiredState: "Shutdown", State: "Shutdown"},
+		{Time: now, TaskID: "c3", Node: "lille-1", Image: "nginx:1.20", DesiredState: "Running", State: "Preparing"},
+	}, events)
+
+	assert.Empty(t, taskStateChanges(current, current, now))
+}
+
+func TestParseServiceStatus(t *testing.T) {
+	assert.Equal(t, serviceStatus{image: "nginx:1.19"}, parseServiceStatus("nginx:1.19@sha256:abcd\n"))
+	assert.Equal(t, serviceStatus{image: "nginx:1.20", updateState: "rollback_completed", updateStarted: "2020-05-04 10:00:00 +0000 UTC"}, parseServiceStatus("nginx:1.20 rollback_completed 2020-05-04 10:00:00 +0000 UTC\n"))
+
+	assert.True(t, (&UpdateResult{State: "rollback_completed"}).IsRolledBack())
+	assert.False(t, (&UpdateResult{State: "completed"}).IsRolledBack())
+}
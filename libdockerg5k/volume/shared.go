@@ -0,0 +1,84 @@
+package volume
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+// SharedMount contain the configuration of a NFS export mounted on the node (and optionally exposed to the containers as a Docker volume)
+type SharedMount struct {
+	Server     string // NFS server hostname
+	Export     string // exported path on the NFS server
+	Path       string // mount point on the node
+	VolumeName string // Docker volume bound to the mount point (optional)
+}
+
+// Validate check the shared mount configuration
+func (s *SharedMount) Validate() error {
+	// check NFS server
+	if s.Server == "" {
+		return fmt.Errorf("A NFS server is required for the shared mount on '%s'", s.Path)
+	}
+
+	// check NFS export
+	if !filepath.IsAbs(s.Export) {
+		return fmt.Errorf("The NFS export must be an absolute path: '%s'", s.Export)
+	}
+
+	// check mount path
+	if !filepath.IsAbs(s.Path) || filepath.Clean(s.Path) == "/" {
+		return fmt.Errorf("The shared mount path must be an absolute path (and not the root directory): '%s'", s.Path)
+	}
+
+	return nil
+}
+
+// String returns the description of the shared mount
+func (s *SharedMount) String() string {
+	return fmt.Sprintf("%s:%s on %s", s.Server, s.Export, s.Path)
+}
+
+// generateCheckExportCommand returns the command used to check the export is available on the NFS server
+func (s *SharedMount) generateCheckExportCommand() string {
+	return fmt.Sprintf("showmount --no-headers -e %s | awk '{print $1}' | grep -qx %s", s.Server, s.Export)
+}
+
+// generateMountCommand returns the command used to mount the NFS export (if not already mounted)
+func (s *SharedMount) generateMountCommand() string {
+	return fmt.Sprintf("mountpoint -q %[3]s || mount -t nfs %[1]s:%[2]s %[3]s", s.Server, s.Export, s.Path)
+}
+
+// MountSharedDirectory mount the NFS export on the node and create the Docker volume bound to it if requested
+func MountSharedDirectory(h *host.Host, s SharedMount) error {
+	// install NFS client if needed
+	if _, err := h.RunSSHCommand("which mount.nfs || (apt-get update && apt-get install -y nfs-common)"); err != nil {
+		return fmt.Errorf("Failed to install the NFS client: '%s'", err)
+	}
+
+	// check NFS server export
+	if _, err := h.RunSSHCommand(s.generateCheckExportCommand()); err != nil {
+		return fmt.Errorf("The NFS export '%s:%s' is not reachable: '%s'", s.Server, s.Export, err)
+	}
+
+	// create mount point
+	if _, err := h.RunSSHCommand(fmt.Sprintf("mkdir -p %s", s.Path)); err != nil {
+		return fmt.Errorf("Failed to create the shared mount directory '%s': '%s'", s.Path, err)
+	}
+
+	// mount NFS export
+	if _, err := h.RunSSHCommand(s.generateMountCommand()); err != nil {
+		return fmt.Errorf("Failed to mount '%s': '%s'", s.String(), err)
+	}
+
+	// expose the mount point to the containers
+	if s.VolumeName != "" {
+		v := VolumeMount{Name: s.VolumeName, Path: s.Path}
+		if _, err := h.RunSSHCommand(v.generateVolumeCreateCommand()); err != nil {
+			return fmt.Errorf("Failed to create shared volume '%s': '%s'", s.VolumeName, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package volume
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedMountValidate(t *testing.T) {
+	assert.NoError(t, (&SharedMount{Server: "nfs", Export: "/export/home/user", Path: "/home/user"}).Validate())
+	assert.Error(t, (&SharedMount{Export: "/export/home/user", Path: "/home/user"}).Validate())
+	assert.Error(t, (&SharedMount{Server: "nfs", Export: "export/home/user", Path: "/home/user"}).Validate())
+	assert.Error(t, (&SharedMount{Server: "nfs", Export: "/export/home/user", Path: "/"}).Validate())
+}
+
+func TestSharedMountGenerateMountCommand(t *testing.T) {
+	s := SharedMount{Server: "nfs", Export: "/export/home/user", Path: "/home/user"}
+	assert.Equal(t, "mountpoint -q /home/user || mount -t nfs nfs:/export/home/user /home/user", s.generateMountCommand())
+}
+
+func TestSharedMountGenerateCheckExportCommand(t *testing.T) {
+	s := SharedMount{Server: "nfs", Export: "/export/home/user", Path: "/home/user"}
+	assert.Equal(t, "showmount --no-headers -e nfs | awk '{print $1}' | grep -qx /export/home/user", s.generateCheckExportCommand())
+}
@@ -0,0 +1,118 @@
+package volume
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/host"
+)
+
+// VolumeMount contain the configuration of a Docker volume backed by a node local disk
+type VolumeMount struct {
+	Name           string // Docker volume name
+	Path           string // mount point of the local disk on the node
+	Device         string // block device to format/mount on Path (optional, Path is used as-is if empty)
+	MinFreeSpaceMB int    // minimum free space required on Path (optional)
+}
+
+// Validate check the volume mount configuration
+func (v *VolumeMount) Validate() error {
+	// check volume name
+	if v.Name == "" {
+		return fmt.Errorf("A name is required for the local volume")
+	}
+
+	// check mount path
+	if !filepath.IsAbs(v.Path) || filepath.Clean(v.Path) == "/" {
+		return fmt.Errorf("The local volume '%s' path must be an absolute path (and not the root directory): '%s'", v.Name, v.Path)
+	}
+
+	// check device
+	if v.Device != "" && !strings.HasPrefix(v.Device, "/dev/") {
+		return fmt.Errorf("The local volume '%s' device must be a block device in '/dev': '%s'", v.Name, v.Device)
+	}
+
+	return nil
+}
+
+// shellQuote returns the string quoted for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// prepareDisk format (if no filesystem is found) and mount the device on the volume path
+func (v *VolumeMount) prepareDisk(h *host.Host) error {
+	// create mount point
+	if _, err := h.RunSSHCommand(fmt.Sprintf("mkdir -p %s", shellQuote(v.Path))); err != nil {
+		return fmt.Errorf("Failed to create the local volume directory '%s': '%s'", v.Path, err)
+	}
+
+	// nothing more to do if no device is given
+	if v.Device == "" {
+		return nil
+	}
+
+	// create a filesystem on the device if it does not have one
+	if _, err := h.RunSSHCommand(fmt.Sprintf("blkid -o value -s TYPE %[1]s || mkfs.ext4 -q -F %[1]s", shellQuote(v.Device))); err != nil {
+		return fmt.Errorf("Failed to create a filesystem on device '%s': '%s'", v.Device, err)
+	}
+
+	// mount the device (if not already mounted)
+	if _, err := h.RunSSHCommand(fmt.Sprintf("mountpoint -q %[2]s || mount %[1]s %[2]s", shellQuote(v.Device), shellQuote(v.Path))); err != nil {
+		return fmt.Errorf("Failed to mount device '%s' on '%s': '%s'", v.Device, v.Path, err)
+	}
+
+	return nil
+}
+
+// checkFreeSpace check the available space on the volume path
+func (v *VolumeMount) checkFreeSpace(h *host.Host) error {
+	// skip check if no minimum is required
+	if v.MinFreeSpaceMB <= 0 {
+		return nil
+	}
+
+	// get available space of the filesystem (in MB)
+	out, err := h.RunSSHCommand(fmt.Sprintf("df -Pm %s | awk 'NR==2 {print $4}'", shellQuote(v.Path)))
+	if err != nil {
+		return fmt.Errorf("Failed to get available space on '%s': '%s'", v.Path, err)
+	}
+
+	free, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return fmt.Errorf("Unable to parse available space on '%s': '%s'", v.Path, err)
+	}
+
+	if free < v.MinFreeSpaceMB {
+		return fmt.Errorf("Not enough space on '%s' for local volume '%s' (%dMB available, %dMB required)", v.Path, v.Name, free, v.MinFreeSpaceMB)
+	}
+
+	return nil
+}
+
+// generateVolumeCreateCommand returns the command used to create a Docker volume bound to the volume path (the path and name are quoted for the remote shell)
+func (v *VolumeMount) generateVolumeCreateCommand() string {
+	return fmt.Sprintf("docker volume create --driver local --opt type=none --opt o=bind --opt device=%s %s", shellQuote(v.Path), shellQuote(v.Name))
+}
+
+// CreateLocalVolume prepare the node local disk and create the Docker volume backed by it
+func CreateLocalVolume(h *host.Host, v VolumeMount) error {
+	// prepare local disk
+	if err := v.prepareDisk(h); err != nil {
+		return err
+	}
+
+	// check available space
+	if err := v.checkFreeSpace(h); err != nil {
+		return err
+	}
+
+	// create Docker volume
+	if _, err := h.RunSSHCommand(v.generateVolumeCreateCommand()); err != nil {
+		return fmt.Errorf("Failed to create local volume '%s': '%s'", v.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package volume
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCorrect(t *testing.T) {
+	v := VolumeMount{Name: "data", Path: "/tmp/data", Device: "/dev/sdb"}
+	assert.NoError(t, v.Validate())
+}
+
+func TestValidateRootPath(t *testing.T) {
+	v := VolumeMount{Name: "data", Path: "/"}
+	assert.Error(t, v.Validate())
+}
+
+func TestValidateIncorrectDevice(t *testing.T) {
+	v := VolumeMount{Name: "data", Path: "/tmp/data", Device: "sdb"}
+	assert.Error(t, v.Validate())
+}
+
+func TestGenerateVolumeCreateCommand(t *testing.T) {
+	v := VolumeMount{Name: "data", Path: "/tmp/data"}
+	assert.Equal(t, "docker volume create --driver local --opt type=none --opt o=bind --opt device='/tmp/data' 'data'", v.generateVolumeCreateCommand())
+}
+
+func TestGenerateVolumeCreateCommandQuoted(t *testing.T) {
+	v := VolumeMount{Name: "data", Path: "/tmp/it's data; rm -rf /"}
+	assert.Equal(t, `docker volume create --driver local --opt type=none --opt o=bind --opt device='/tmp/it'\''s data; rm -rf /' 'data'`, v.generateVolumeCreateCommand())
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "'/dev/sdb'", shellQuote("/dev/sdb"))
+	assert.Equal(t, `'/tmp/$(reboot)'`, shellQuote("/tmp/$(reboot)"))
+	assert.Equal(t, `'/tmp/it'\''s'`, shellQuote("/tmp/it's"))
+}
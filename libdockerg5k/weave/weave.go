@@ -14,27 +14,245 @@ docker run --net=weave -h barbar.weave.local --dns=172.17.0.1 --dns-search=weave
 */
 
 import (
+	"crypto/sha1"
+	"encoding/base64"
 	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"unicode"
 
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
 	"github.com/docker/machine/libmachine/host"
 )
 
-// RunWeaveNet run Weave Net on given host
-func RunWeaveNet(h *host.Host) error {
-	// Run Weave Net router with Docker plugin
-	if _, err := h.RunSSHCommand("docker run --rm -v /var/run/docker.sock:/var/run/docker.sock -v /proc:/hostproc -e PROCFS=/hostproc --privileged --net=host weaveworks/weaveexec --local launch-router --plugin"); err != nil {
-		return fmt.Errorf("Weave Net run command failed: '%s'", err)
+const (
+	// weaveExecCommand is the command used to run the Weave script on the host (followed by the script image)
+	weaveExecCommand = "docker run --rm -v /var/run/docker.sock:/var/run/docker.sock -v /proc:/hostproc -e PROCFS=/hostproc --privileged --net=host"
+
+	// upstream namespace and name of the Weave Net router and script images
+	defaultNamespace = "weaveworks"
+	routerImageName  = "weave"
+	execImageName    = "weaveexec"
+
+	// removeWeaveDiscoveryCommand stop (with its stop signal and timeout) and remove the Weave Discovery container (if any)
+	removeWeaveDiscoveryCommand = "docker stop weavediscovery >/dev/null 2>&1; docker rm -f weavediscovery || true"
+)
+
+var regexImageTag = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+
+// Config contain the Weave Net images launched on the hosts (the upstream images are used if not set)
+// the Weave script derives the router image from its own namespace and version, so the script image (weaveexec) is taken from the same namespace and version than the router image
+type Config struct {
+	Image   string // Weave Net router image repository (ex: myregistry:5000/weave, the upstream weaveworks/weave if empty)
+	Version string // tag of the Weave Net router and script images (ex: 2.8.1, latest if empty)
+}
+
+// IsSet returns true if a custom image or version is set, false otherwise
+func (c *Config) IsSet() bool {
+	return c.Image != "" || c.Version != ""
+}
+
+// Validate check the Weave Net router image (its name must be 'weave', in any namespace) and version
+func (c *Config) Validate() error {
+	if c.Image != "" {
+		// the tag is not part of the image, it is set by the version
+		if strings.ContainsAny(c.Image, " @") || (c.Image != routerImageName && !strings.HasSuffix(c.Image, "/"+routerImageName)) {
+			return fmt.Errorf("Invalid Weave Net image: '%s' (the image name must be '%s' without tag, ex: myregistry:5000/%s)", c.Image, routerImageName, routerImageName)
+		}
+	}
+
+	if c.Version != "" && !regexImageTag.MatchString(c.Version) {
+		return fmt.Errorf("Invalid Weave Net version: '%s'", c.Version)
+	}
+
+	return nil
+}
+
+// namespace returns the namespace of the Weave images (the upstream namespace if not set)
+func (c *Config) namespace() string {
+	if c.Image == "" || c.Image == routerImageName {
+		return defaultNamespace
+	}
+
+	return strings.TrimSuffix(c.Image, "/"+routerImageName)
+}
+
+// version returns the tag of the Weave images (latest if not set)
+func (c *Config) version() string {
+	if c.Version == "" {
+		return "latest"
+	}
+
+	return c.Version
+}
+
+// RouterImage returns the Weave Net router image launched on the hosts
+func (c *Config) RouterImage() string {
+	return fmt.Sprintf("%s/%s:%s", c.namespace(), routerImageName, c.version())
+}
+
+// execImage returns the Weave script image
+func (c *Config) execImage() string {
+	return fmt.Sprintf("%s/%s:%s", c.namespace(), execImageName, c.version())
+}
+
+// execCommand returns the command used to run the Weave script on the host, with the namespace and version of the router image if set (the upstream script image otherwise)
+// the given environment variables of the host are passed to the script
+func (c *Config) execCommand(env ...string) string {
+	cmd := weaveExecCommand
+	for _, e := range env {
+		cmd = fmt.Sprintf("%s -e %s", cmd, e)
+	}
+
+	if !c.IsSet() {
+		return fmt.Sprintf("%s %s/%s --local", cmd, defaultNamespace, execImageName)
+	}
+
+	return fmt.Sprintf("%s -e DOCKERHUB_USER=%s -e WEAVE_VERSION=%s %s --local", cmd, c.namespace(), c.version(), c.execImage())
+}
+
+// pullImages pull the Weave Net router and script images on the host, to check they are available before launching the router
+func (c *Config) pullImages(h *host.Host) error {
+	for _, image := range []string{c.execImage(), c.RouterImage()} {
+		if _, err := h.RunSSHCommand(fmt.Sprintf("docker pull %s", image)); err != nil {
+			return fmt.Errorf("The Weave image '%s' can't be pulled: '%s'", image, err)
+		}
+	}
+
+	return nil
+}
+
+// Identity contain the stable identity of a Weave Net router, kept across restarts
+type Identity struct {
+	Name     string   // peer name (MAC address format)
+	Nickname string   // human readable peer name
+	IPAMSeed []string // peer names of the IPAM seed (needs to be the same on all routers)
+}
+
+// PeerName returns a stable Weave peer name (MAC address format) derived from the given name
+func PeerName(name string) string {
+	sum := sha1.Sum([]byte(name))
+
+	// locally administered unicast address
+	sum[0] = (sum[0] | 0x02) &^ 0x01
+
+	return net.HardwareAddr(sum[:6]).String()
+}
+
+// ValidatePassword check the Weave encryption password can be passed to the Weave script (empty if not encrypted)
+func ValidatePassword(password string) error {
+	for _, r := range password {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("Invalid Weave encryption password: it can't contain control characters")
+		}
+	}
+
+	return nil
+}
+
+// encodePassword returns the encryption password encoded in base64 (only shell-safe characters)
+func encodePassword(password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(password))
+}
+
+// redactPassword returns the message with the encryption password (and its encoded form) replaced
+func redactPassword(msg string, password string) string {
+	if password == "" {
+		return msg
+	}
+
+	msg = strings.Replace(msg, encodePassword(password), "<redacted>", -1)
+	return strings.Replace(msg, password, "<redacted>", -1)
+}
+
+// generateLaunchRouterCommand returns the command used to launch the Weave Net router with the given identity (random if nil) and encryption password (not encrypted if empty),
+// peering only with the given peers if any
+func generateLaunchRouterCommand(cfg Config, peers []string, id *Identity, password string) string {
+	cmd := fmt.Sprintf("%s launch-router --plugin", cfg.execCommand())
+
+	// encryption of the traffic between the routers: the password is decoded on the host and given to the script through its environment (never on the command line)
+	if password != "" {
+		cmd = fmt.Sprintf("WEAVE_PASSWORD=\"$(echo %s | base64 -d)\" %s launch-router --plugin", encodePassword(password), cfg.execCommand("WEAVE_PASSWORD"))
+	}
+
+	// stable peer name and IPAM seed
+	if id != nil {
+		cmd = fmt.Sprintf("%s --name %s --nickname %s", cmd, id.Name, id.Nickname)
+
+		if len(id.IPAMSeed) > 0 {
+			cmd = fmt.Sprintf("%s --ipalloc-init seed=%s", cmd, strings.Join(id.IPAMSeed, ","))
+		}
+	}
+
+	// disable peers auto-discovery and only connect to the given peers
+	if len(peers) > 0 {
+		cmd = fmt.Sprintf("%s --no-discovery %s", cmd, strings.Join(peers, " "))
+	}
+
+	return cmd
+}
+
+// RunWeaveNet run Weave Net on given host with the images of the configuration, the given identity (random if nil) and encryption password (not encrypted if empty)
+// If peers are given, the router will only connect to them instead of using a full mesh
+func RunWeaveNet(h *host.Host, cfg Config, peers []string, id *Identity, password string) error {
+	// check the custom images are available
+	if cfg.IsSet() {
+		if err := cfg.pullImages(h); err != nil {
+			return err
+		}
+	}
+
+	// Run Weave Net router with Docker plugin (the password is redacted from the error, which contain the command)
+	if _, err := h.RunSSHCommand(generateLaunchRouterCommand(cfg, peers, id, password)); err != nil {
+		return fmt.Errorf("Weave Net run command failed: '%s'", redactPassword(err.Error(), password))
 	}
 
 	return nil
 }
 
-// RunWeaveDiscovery run Weave Discovery on a host using the given Swarm Discovery method
-func RunWeaveDiscovery(h *host.Host, swarmDiscovery string) error {
+// RunWeaveDiscovery run Weave Discovery on a host using the given Swarm Discovery method with the added capabilities, stop signal/timeout and image platform (the default restart policy is used if empty)
+func RunWeaveDiscovery(h *host.Host, swarmDiscovery string, restartPolicy string, caps []string, stop container.StopConfig, platform string) error {
 	// Run Weave Discovery
-	if _, err := h.RunSSHCommand(fmt.Sprintf("docker run -d --name weavediscovery --net=host weaveworks/weavediscovery %s", swarmDiscovery)); err != nil {
+	if _, err := h.RunSSHCommand(fmt.Sprintf("docker run -d %s --name weavediscovery %s --net=host weaveworks/weavediscovery %s", container.RestartFlag(restartPolicy)+container.CapAddFlags(caps)+stop.Flags()+container.PlatformFlag(platform), container.LabelFlag, swarmDiscovery)); err != nil {
 		return fmt.Errorf("Weave Discovery run command failed: '%s'", err)
 	}
 
 	return nil
 }
+
+// IsWeaveNetRunning returns true if the Weave Net router is running and responding on the host, false otherwise
+func IsWeaveNetRunning(h *host.Host, cfg Config) bool {
+	_, err := h.RunSSHCommand(fmt.Sprintf("%s status", cfg.execCommand()))
+	return err == nil
+}
+
+// StopWeave stop Weave Net (with the script of the configuration) and Weave Discovery on the host (the Weave persisted data are kept)
+func StopWeave(h *host.Host, cfg Config) error {
+	// remove Weave Discovery container (if any)
+	if _, err := h.RunSSHCommand(removeWeaveDiscoveryCommand); err != nil {
+		return fmt.Errorf("Weave Discovery remove command failed: '%s'", err)
+	}
+
+	// stop Weave Net router
+	if _, err := h.RunSSHCommand(fmt.Sprintf("%s stop", cfg.execCommand())); err != nil {
+		return fmt.Errorf("Weave Net stop command failed: '%s'", err)
+	}
+
+	return nil
+}
+
+// ResetWeave stop Weave Net (with the script of the configuration) and Weave Discovery on the host and remove all Weave persisted data
+func ResetWeave(h *host.Host, cfg Config) error {
+	// remove Weave Discovery container (if any)
+	if _, err := h.RunSSHCommand(removeWeaveDiscoveryCommand); err != nil {
+		return fmt.Errorf("Weave Discovery remove command failed: '%s'", err)
+	}
+
+	// reset Weave Net router
+	if _, err := h.RunSSHCommand(fmt.Sprintf("%s reset --force", cfg.execCommand())); err != nil {
+		return fmt.Errorf("Weave Net reset command failed: '%s'", err)
+	}
+
+	return nil
+}
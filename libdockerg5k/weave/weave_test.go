@@ -0,0 +1,88 @@
+package weave
+
+import (
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateLaunchRouterCommandFullMesh(t *testing.T) {
+	cmd := generateLaunchRouterCommand(Config{}, nil, nil, "")
+	assert.NotContains(t, cmd, "--no-discovery")
+	assert.NotContains(t, cmd, "--password")
+}
+
+func TestGenerateLaunchRouterCommandPassword(t *testing.T) {
+	// the password is given through the environment of the script, encoded with only shell-safe characters
+	cmd := generateLaunchRouterCommand(Config{}, []string{"10.0.0.1"}, nil, "s3cret; reboot")
+	assert.Equal(t, "WEAVE_PASSWORD=\"$(echo czNjcmV0OyByZWJvb3Q= | base64 -d)\" "+weaveExecCommand+" -e WEAVE_PASSWORD weaveworks/weaveexec --local launch-router --plugin --no-discovery 10.0.0.1", cmd)
+	assert.NotContains(t, cmd, "s3cret")
+	assert.NotContains(t, cmd, "--password")
+}
+
+func TestValidatePassword(t *testing.T) {
+	assert.NoError(t, ValidatePassword(""))
+	assert.NoError(t, ValidatePassword("s3cret with spaces & $ymbols'\""))
+	assert.Error(t, ValidatePassword("s3cret\nreboot"))
+}
+
+func TestRedactPassword(t *testing.T) {
+	err := "exit status 1: WEAVE_PASSWORD=\"$(echo czNjcmV0 | base64 -d)\" weave launch-router (s3cret)"
+	assert.Equal(t, "exit status 1: WEAVE_PASSWORD=\"$(echo <redacted> | base64 -d)\" weave launch-router (<redacted>)", redactPassword(err, "s3cret"))
+	assert.Equal(t, err, redactPassword(err, ""))
+}
+
+func TestGenerateLaunchRouterCommandConnectors(t *testing.T) {
+	cmd := generateLaunchRouterCommand(Config{}, []string{"10.0.0.1", "10.0.0.2"}, nil, "")
+	assert.Contains(t, cmd, "launch-router --plugin --no-discovery 10.0.0.1 10.0.0.2")
+}
+
+func TestGenerateLaunchRouterCommandIdentity(t *testing.T) {
+	id := &Identity{Name: "02:00:00:00:00:01", Nickname: "lille-1", IPAMSeed: []string{"02:00:00:00:00:00", "02:00:00:00:00:01"}}
+	cmd := generateLaunchRouterCommand(Config{}, []string{"10.0.0.1"}, id, "")
+	assert.Contains(t, cmd, "launch-router --plugin --name 02:00:00:00:00:01 --nickname lille-1 --ipalloc-init seed=02:00:00:00:00:00,02:00:00:00:00:01 --no-discovery 10.0.0.1")
+}
+
+func TestPeerName(t *testing.T) {
+	name := PeerName("lille-1")
+	assert.True(t, regexp.MustCompile("^([0-9a-f]{2}:){5}[0-9a-f]{2}$").MatchString(name))
+	assert.Equal(t, name, PeerName("lille-1"))
+	assert.NotEqual(t, name, PeerName("lille-2"))
+
+	// locally administered unicast address
+	mac, err := net.ParseMAC(name)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x02), mac[0]&0x03)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+	assert.NoError(t, (&Config{Image: "myregistry:5000/weave", Version: "2.8.1"}).Validate())
+	assert.NoError(t, (&Config{Version: "2.6.5"}).Validate())
+}
+
+func TestConfigValidateIncorrect(t *testing.T) {
+	assert.Error(t, (&Config{Image: "myregistry:5000/weave-patched"}).Validate())
+	assert.Error(t, (&Config{Image: "weaveworks/weave:2.8.1"}).Validate())
+	assert.Error(t, (&Config{Image: "weaveworks/weave@sha256:abc"}).Validate())
+	assert.Error(t, (&Config{Version: "2.8.1 --privileged"}).Validate())
+}
+
+func TestConfigImages(t *testing.T) {
+	assert.Equal(t, "weaveworks/weave:latest", (&Config{}).RouterImage())
+
+	c := &Config{Image: "myregistry:5000/weave", Version: "2.8.1"}
+	assert.Equal(t, "myregistry:5000/weave:2.8.1", c.RouterImage())
+	assert.Equal(t, "myregistry:5000/weaveexec:2.8.1", c.execImage())
+	assert.Equal(t, "weaveworks/weaveexec:2.6.5", (&Config{Version: "2.6.5"}).execImage())
+}
+
+func TestGenerateLaunchRouterCommandImages(t *testing.T) {
+	// the upstream script image is used if not set
+	assert.Equal(t, "docker run --rm -v /var/run/docker.sock:/var/run/docker.sock -v /proc:/hostproc -e PROCFS=/hostproc --privileged --net=host weaveworks/weaveexec --local launch-router --plugin", generateLaunchRouterCommand(Config{}, nil, nil, ""))
+
+	cmd := generateLaunchRouterCommand(Config{Image: "myregistry:5000/weave", Version: "2.8.1"}, nil, nil, "")
+	assert.Equal(t, "docker run --rm -v /var/run/docker.sock:/var/run/docker.sock -v /proc:/hostproc -e PROCFS=/hostproc --privileged --net=host -e DOCKERHUB_USER=myregistry:5000 -e WEAVE_VERSION=2.8.1 myregistry:5000/weaveexec:2.8.1 --local launch-router --plugin", cmd)
+}
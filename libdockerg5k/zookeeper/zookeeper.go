@@ -3,10 +3,20 @@ package zookeeper
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
 	"github.com/docker/machine/libmachine/host"
 )
 
+const (
+	// containerName is the name of the Zookeeper container
+	containerName = "docker-g5k-zookeeper"
+
+	// statusCommand print the status of the Zookeeper server of the node (its mode once the ensemble has a leader)
+	statusCommand = "docker exec " + containerName + " zkServer.sh status 2>&1"
+)
+
 // GenerateClusterStorageURL returns a string used for Docker Engine/Swarm cluster-store parameter (format=zk://node1,node2,nodeN...)
 func GenerateClusterStorageURL(zookeeperMasterNodes []string, hostsLookupTable map[string]string) string {
 	// get the master nodes IP address from the hosts lookup table
@@ -30,8 +40,8 @@ func generateServerList(nodes []string) string {
 	return strings.Join(zkServers, " ")
 }
 
-// StartClusterStorage start a zookeeper k/vcontainer on the Swarm master nodes for cluster k/v storage
-func StartClusterStorage(host *host.Host, zookeeperMasterNodes []string) error {
+// StartClusterStorage start a zookeeper k/vcontainer on the Swarm master nodes for cluster k/v storage with the added capabilities, stop signal/timeout and image platform (the default restart policy is used if empty)
+func StartClusterStorage(host *host.Host, zookeeperMasterNodes []string, restartPolicy string, caps []string, stop container.StopConfig, platform string) error {
 	// search current host in Swarm master nodes list
 	for i, nodeName := range zookeeperMasterNodes {
 		// host found in Swarm master nodes list
@@ -41,8 +51,8 @@ func StartClusterStorage(host *host.Host, zookeeperMasterNodes []string) error {
 			envServers := fmt.Sprintf("ZOO_SERVERS=%s", generateServerList(zookeeperMasterNodes))
 
 			// start zookeeper container
-			if _, err := host.RunSSHCommand(fmt.Sprintf("docker run -td --restart=always --net=host --name docker-g5k-zookeeper -e \"%s\" -e \"%s\" zookeeper", envID, envServers)); err != nil {
-				return err
+			if _, err := host.RunSSHCommand(fmt.Sprintf("docker run -td %s --net=host --name %s %s -e \"%s\" -e \"%s\" zookeeper", container.RestartFlag(restartPolicy)+container.CapAddFlags(caps)+stop.Flags()+container.PlatformFlag(platform), containerName, container.LabelFlag, envID, envServers)); err != nil {
+				return fmt.Errorf("Failed to start the Zookeeper container: '%s'", err)
 			}
 
 			return nil
@@ -52,3 +62,30 @@ func StartClusterStorage(host *host.Host, zookeeperMasterNodes []string) error {
 	// host not found in Swarm master nodes list
 	return fmt.Errorf("This host is not in the given Zookeeper master nodes list")
 }
+
+// parseServerMode returns the mode of the Zookeeper server (leader, follower or standalone) from the status command output, empty if the server is not serving
+func parseServerMode(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "Mode:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Mode:"))
+		}
+	}
+
+	return ""
+}
+
+// WaitForLeader wait until the Zookeeper server of the host is part of an ensemble with an elected leader (or is a standalone server)
+func WaitForLeader(h *host.Host, timeout time.Duration) error {
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(5 * time.Second) {
+		out, err := h.RunSSHCommand(statusCommand)
+		if err != nil {
+			continue
+		}
+
+		if parseServerMode(out) != "" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("The Zookeeper ensemble has no leader after %s", timeout)
+}
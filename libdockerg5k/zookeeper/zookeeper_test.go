@@ -3,6 +3,8 @@ package zookeeper
 import (
 	"testing"
 
+	"github.com/Spirals-Team/docker-g5k/libdockerg5k/container"
+	"github.com/docker/machine/libmachine/host"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,3 +33,21 @@ func TestGenerateServerListMultiMaster(t *testing.T) {
 	srvList := generateServerList(masters)
 	assert.Equal(t, "server.0=lille-0:2888:3888 server.1=sophia-1:2888:3888 server.2=lyon-2:2888:3888", srvList)
 }
+
+func TestStartClusterStorageNotMaster(t *testing.T) {
+	h := &host.Host{Name: "lyon-2"}
+	err := StartClusterStorage(h, []string{"lille-0", "sophia-1"}, "", nil, container.StopConfig{}, "")
+	assert.Error(t, err)
+}
+
+func TestParseServerMode(t *testing.T) {
+	assert.Equal(t, "follower", parseServerMode("ZooKeeper JMX enabled by default\nUsing config: /conf/zoo.cfg\nClient port found: 2181. Client address: localhost.\nMode: follower\n"))
+	assert.Equal(t, "standalone", parseServerMode("Mode: standalone"))
+	assert.Equal(t, "", parseServerMode("Using config: /conf/zoo.cfg\nError contacting service. It is probably not running.\n"))
+	assert.Equal(t, "", parseServerMode(""))
+}
+
+func TestWaitForLeaderTimeout(t *testing.T) {
+	// no status check is possible within the timeout
+	assert.Error(t, WaitForLeader(&host.Host{Name: "lille-0"}, 0))
+}